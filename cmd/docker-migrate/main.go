@@ -1,23 +1,39 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/audit"
 	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/events"
+	"github.com/artemis/docker-migrate/internal/janitor"
 	"github.com/artemis/docker-migrate/internal/master"
 	"github.com/artemis/docker-migrate/internal/migration"
+	"github.com/artemis/docker-migrate/internal/objectstore"
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/artemis/docker-migrate/internal/peer"
 	"github.com/artemis/docker-migrate/internal/server"
 	"github.com/artemis/docker-migrate/internal/worker"
+	pb "github.com/artemis/docker-migrate/proto"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -63,6 +79,34 @@ including containers, images, volumes, and networks between hosts.`,
 				logger.Warn("failed to set log level, using default", zap.Error(err))
 			}
 		}
+
+		// Module overrides and sampling can also be changed later via /api/logging
+		if spec, _ := cmd.Flags().GetString("log-modules"); spec != "" {
+			modules, err := observability.ParseModuleLevels(spec)
+			if err != nil {
+				logger.Error("invalid --log-modules", zap.Error(err))
+				os.Exit(1)
+			}
+			cfg.LogModules = modules
+		}
+		if err := logger.Control().SetModuleLevels(cfg.LogModules); err != nil {
+			logger.Warn("ignoring invalid log_modules", zap.Error(err))
+		}
+		if cfg.LogSampling != nil {
+			if err := logger.Control().SetSampling(cfg.LogSampling.Initial, cfg.LogSampling.Thereafter); err != nil {
+				logger.Warn("ignoring invalid log_sampling", zap.Error(err))
+			}
+		}
+
+		// Hidden testing flag overrides any configured fault injection
+		if spec, _ := cmd.Flags().GetString("inject-faults"); spec != "" {
+			faults, err := config.ParseFaultInjection(spec)
+			if err != nil {
+				logger.Error("invalid --inject-faults", zap.Error(err))
+				os.Exit(1)
+			}
+			cfg.FaultInjection = faults
+		}
 	},
 }
 
@@ -89,10 +133,16 @@ func runUIServer(cmd *cobra.Command, args []string) error {
 	}
 	defer dockerClient.Close()
 
+	freezeMode, err := docker.ParseFreezeMode(cfg.ExportFreeze)
+	if err != nil {
+		return fmt.Errorf("invalid export_freeze: %w", err)
+	}
+	dockerClient.SetExportFreeze(freezeMode)
+	dockerClient.SetExportThrottle(cfg.ExportRateLimit, cfg.ExportIOIdle)
+
 	// Initialize health checker
 	healthChecker := observability.NewHealthChecker()
-	healthChecker.RegisterCheck("docker", observability.DockerHealthCheck(dockerClient.Ping))
-	go healthChecker.StartPeriodicChecks(ctx, 10*time.Second)
+	healthChecker.RegisterReadinessCheck("docker", observability.DockerHealthCheck(dockerClient.Ping))
 
 	// Initialize metrics
 	metrics := observability.NewMetrics()
@@ -103,8 +153,19 @@ func runUIServer(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create crypto manager: %w", err)
 	}
 
+	if cfg.TOFU {
+		cryptoManager.EnableTOFU()
+	}
+
+	// Security-relevant actions go to the tamper-evident audit log
+	auditLog, err := audit.Open(cfg.DataDir, cfg.AuditKeyFile, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
 	// Initialize pairing manager
-	pairingManager := peer.NewPairingManager(cfg, cryptoManager, logger)
+	pairingManager := peer.NewPairingManager(ctx, cfg, cryptoManager, logger)
+	pairingManager.SetAuditLog(auditLog)
 
 	// Initialize transfer manager
 	transferManager, err := peer.NewTransferManager(cfg, logger)
@@ -113,10 +174,18 @@ func runUIServer(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize peer discovery
-	peerDiscovery := peer.NewPeerDiscovery(cfg, pairingManager, cryptoManager, logger)
+	peerDiscovery := peer.NewPeerDiscovery(ctx, cfg, pairingManager, cryptoManager, logger)
+
+	// Trust peers declared in config or DNS-SD without interactive pairing
+	if err := peerDiscovery.LoadStaticPeers(ctx); err != nil {
+		return fmt.Errorf("failed to load static peers: %w", err)
+	}
+	go peerDiscovery.StartDNSSDRefresh(ctx, peer.DNSSDRefreshInterval)
+	go peerDiscovery.StartMDNS(ctx)
 
 	// Initialize migration engine (expects *zap.Logger)
 	migrationEngine := migration.NewEngine(
+		ctx,
 		dockerClient,
 		peerDiscovery,
 		transferManager,
@@ -124,9 +193,36 @@ func runUIServer(cmd *cobra.Command, args []string) error {
 		logger.Logger, // Access embedded *zap.Logger
 		metrics,
 	)
+	// Typed events shared by the engine and the UI hub
+	eventBus := events.NewBus(logger.Logger)
+	migrationEngine.SetEventBus(eventBus)
+	// Integrity reports are signed with the same key peers know us by
+	migrationEngine.SetSigner(cryptoManager)
+
+	// Peers that pair with one of our codes arrive over gRPC. The UI shows
+	// the symbols, and the peer is registered once the user confirms them.
+	pairingManager.OnPaired(func(trusted *peer.TrustedPeer) {
+		eventBus.Publish(events.Event{
+			Type: events.PeerUpdated,
+			Data: map[string]interface{}{
+				"peer_id":      trusted.ID,
+				"name":         trusted.Name,
+				"paired":       true,
+				"verification": pairingManager.Verification(trusted.Fingerprint),
+			},
+		})
+	})
+
+	go migrationEngine.StartRetentionLoop(ctx, migration.RetentionPolicy{
+		MaxAge:   cfg.JobRetention,
+		MaxCount: cfg.JobRetentionCount,
+	}, time.Hour)
+
+	go migrationEngine.StartScheduleLoop(ctx, migration.ScheduleInterval)
 
 	// Initialize gRPC server (expects *observability.Logger)
-	// In master mode, don't require client certificates (auth via enrollment token)
+	// In master mode, workers connect without a trusted certificate and
+	// authenticate with their enrollment token; peer services still check it
 	var grpcOpts []peer.GRPCServerOption
 	if cfg.IsMaster() {
 		grpcOpts = append(grpcOpts, peer.WithNoClientVerify())
@@ -149,22 +245,40 @@ func runUIServer(cmd *cobra.Command, args []string) error {
 	var masterNode *master.Master
 	if cfg.IsMaster() {
 		var err error
-		masterNode, err = master.New(cfg, dockerClient, cryptoManager, transferManager, logger)
+		masterNode, err = master.New(ctx, cfg, dockerClient, cryptoManager, transferManager, logger)
 		if err != nil {
 			return fmt.Errorf("failed to create master node: %w", err)
 		}
 
+		masterNode.SetAuditLog(auditLog)
+
 		// Register MasterService on the existing gRPC server (before it starts)
 		masterNode.RegisterGRPCService(grpcServer.GetServer())
 
 		// Start registry cleanup
 		go masterNode.StartBackgroundTasks(ctx)
 
+		// Workers pin this with --master-fingerprint
 		logger.Info("master mode enabled",
 			zap.String("enrollment_token", cfg.Master.EnrollmentToken),
+			zap.String("master_fingerprint", cryptoManager.GetFingerprint()),
 		)
 	}
 
+	// Peers behind NAT reach this node through the rendezvous master
+	peerDiscovery.SetInboundHandler(grpcServer.ServeConn)
+
+	// Clear out what crashes and abandoned transfers leave behind
+	janitorTasks := janitor.New(logger)
+	janitorTasks.Register("checkpoints", cfg.CheckpointRetention, migrationEngine.SweepCheckpoints)
+	janitorTasks.Register("job_state", cfg.CheckpointRetention, migrationEngine.SweepStaleState)
+	janitorTasks.Register("spool", cfg.TempFileRetention, grpcServer.SweepSpool)
+	janitorTasks.Register("temp_files", cfg.TempFileRetention, migrationEngine.SweepTempFiles)
+	go janitorTasks.Start(ctx, cfg.JanitorInterval)
+
+	healthChecker.RegisterReadinessCheck("grpc", observability.ServingHealthCheck("gRPC server", grpcServer.Serving))
+	go healthChecker.StartPeriodicChecks(ctx, 10*time.Second)
+
 	// Start background services
 	go peerDiscovery.Start(ctx)
 	go func() {
@@ -182,9 +296,19 @@ func runUIServer(cmd *cobra.Command, args []string) error {
 		peerDiscovery,
 		healthChecker,
 		metrics,
+		eventBus,
 		logger,
 	)
 
+	httpServer.SetAuditLog(auditLog)
+
+	if cfg.OIDC != nil {
+		if err := httpServer.EnableOIDC(); err != nil {
+			return err
+		}
+		logger.Info("web UI sign-in enabled", zap.String("issuer", cfg.OIDC.Issuer))
+	}
+
 	// Register master routes with HTTP server if in master mode
 	if masterNode != nil {
 		httpServer.SetMaster(masterNode)
@@ -210,7 +334,7 @@ func runUIServer(cmd *cobra.Command, args []string) error {
 		zap.String("grpc_addr", cfg.GRPCAddr),
 	)
 
-	if err := httpServer.Start(); err != nil {
+	if err := httpServer.Start(ctx); err != nil {
 		return fmt.Errorf("HTTP server error: %w", err)
 	}
 
@@ -334,173 +458,1526 @@ var migrateCmd = &cobra.Command{
 	Long:  "Migrate Docker resources to a peer",
 }
 
-var masterCmd = &cobra.Command{
-	Use:   "master",
-	Short: "Run as master node with web UI",
-	Long:  "Start docker-migrate as a master node that manages worker connections and orchestrates migrations",
+var benchmarkCmd = &cobra.Command{
+	Use:   "benchmark",
+	Short: "Benchmark the transfer pipeline",
+	Long:  "Stream synthetic data through the chunk/checksum/compression pipeline (loopback) or to a paired peer and report throughput",
 	Run: func(cmd *cobra.Command, args []string) {
-		// Set role to master
-		cfg.Role = config.RoleMaster
-
-		// Get or generate enrollment token (persisted across restarts)
-		enrollmentToken, _ := cmd.Flags().GetString("enrollment-token")
-		if enrollmentToken == "" {
-			enrollmentToken = loadOrGenerateEnrollmentToken()
+		if err := runBenchmark(cmd); err != nil {
+			logger.Error("benchmark failed", zap.Error(err))
+			os.Exit(1)
 		}
+	},
+}
 
-		if cfg.Master == nil {
-			cfg.Master = config.DefaultMasterConfig()
+var pruneCmd = &cobra.Command{
+	Use:   "prune [images|volumes|build-cache]",
+	Short: "Reclaim disk space on this host",
+	Long:  "Remove dangling images, unreferenced volumes or unused build cache; use --dry-run to see what would be reclaimed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+
+		dockerClient, err := docker.NewClient(logger, cfg.DockerHost)
+		if err != nil {
+			logger.Error("failed to create docker client", zap.Error(err))
+			os.Exit(1)
 		}
-		cfg.Master.EnrollmentToken = enrollmentToken
+		defer dockerClient.Close()
 
-		logger.Info("enrollment token for workers", zap.String("token", enrollmentToken))
+		var prune func(ctx context.Context, all, dryRun bool) (*docker.PruneReport, error)
+		switch args[0] {
+		case "images", "i":
+			prune = dockerClient.PruneImages
+		case "volumes", "v":
+			prune = dockerClient.PruneVolumes
+		case "build-cache", "b":
+			prune = dockerClient.PruneBuildCache
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown prune type: %s\n", args[0])
+			os.Exit(1)
+		}
 
-		// Run the UI server (which will detect master mode)
-		if err := runUIServer(cmd, args); err != nil {
-			logger.Error("failed to start master", zap.Error(err))
+		report, err := prune(ctx, all, dryRun)
+		if err != nil {
+			logger.Error("prune failed", zap.Error(err))
 			os.Exit(1)
 		}
+
+		verb := "Removed"
+		if report.DryRun {
+			verb = "Would remove"
+		}
+		fmt.Printf("%s %d %s, reclaiming %.2f MB\n", verb, len(report.Deleted), args[0], float64(report.SpaceReclaimed)/(1024*1024))
+		for _, id := range report.Deleted {
+			fmt.Printf("  - %s\n", id)
+		}
 	},
 }
 
-var workerCmd = &cobra.Command{
-	Use:   "worker",
-	Short: "Run as worker node",
-	Long:  "Start docker-migrate as a worker node that connects to a master for coordinated migrations",
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <job-id>",
+	Short: "Roll back a failed migration",
+	Long:  "Restore the pre-migration state recorded in a job's persisted rollback snapshot, e.g. after a crash",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		masterURL, _ := cmd.Flags().GetString("master-url")
-		token, _ := cmd.Flags().GetString("token")
-		workerName, _ := cmd.Flags().GetString("name")
+		jobID := args[0]
+		force, _ := cmd.Flags().GetBool("force")
 
-		if masterURL == "" {
-			fmt.Fprintln(os.Stderr, "Error: --master-url is required")
+		if !force && rollbackJobCompleted(jobID) {
+			logger.Error("refusing to roll back", zap.String("job_id", jobID), zap.Error(migration.ErrRollbackCompleted))
+			fmt.Fprintln(os.Stderr, "Rerun with --force to delete the migrated resources from the target and restore the source.")
 			os.Exit(1)
 		}
-		if token == "" {
-			fmt.Fprintln(os.Stderr, "Error: --token is required")
+
+		dockerClient, err := docker.NewClient(logger, cfg.DockerHost)
+		if err != nil {
+			logger.Error("failed to create docker client", zap.Error(err))
 			os.Exit(1)
 		}
+		defer dockerClient.Close()
 
-		cfg.Role = config.RoleWorker
-		if cfg.Worker == nil {
-			cfg.Worker = config.DefaultWorkerConfig()
+		ctx := context.Background()
+		rollback := migration.NewRollbackManager(dockerClient, cfg.DataDir, logger.Logger)
+
+		// Reach the target to remove what the migration created there
+		if peers, transfer, err := rollbackPeers(ctx); err != nil {
+			logger.Warn("cannot reach peers, restoring this host only", zap.Error(err))
+		} else {
+			rollback.SetPeers(peers, transfer)
 		}
-		cfg.Worker.MasterURL = masterURL
-		cfg.Worker.Name = workerName
 
-		// Worker needs to connect to master and run its own gRPC server
-		if err := runWorker(cmd, args, token); err != nil {
-			logger.Error("failed to start worker", zap.Error(err))
+		if err := rollback.Rollback(ctx, jobID); err != nil {
+			logger.Error("rollback failed", zap.String("job_id", jobID), zap.Error(err))
 			os.Exit(1)
 		}
+		if err := rollback.DeleteSnapshot(jobID); err != nil {
+			logger.Warn("failed to remove snapshot", zap.String("job_id", jobID), zap.Error(err))
+		}
+
+		fmt.Printf("Rolled back migration %s\n", jobID)
 	},
 }
 
-var (
-	migrateTo         string
-	migrateContainers []string
-	migrateVolumes    []string
-	migrateImages     []string
-	migrateNetworks   []string
-	migrateMode       string
-	migrateStrategy   string
-	migrateDryRun     bool
-)
+// rollbackJobCompleted reports whether the job's persisted record or its
+// history entry shows it completed
+func rollbackJobCompleted(jobID string) bool {
+	if store, err := migration.NewJobStore(cfg.DataDir, logger.Logger); err == nil {
+		if jobs, err := store.LoadAll(); err == nil {
+			for _, job := range jobs {
+				if job.ID == jobID {
+					return job.Status == migration.StatusComplete
+				}
+			}
+		}
+	}
+	history, err := migration.NewHistoryStore(cfg.DataDir, logger.Logger)
+	if err != nil {
+		return false
+	}
+	defer history.Close()
+	job, err := history.Get(jobID)
+	return err == nil && job.Status == migration.StatusComplete
+}
 
-func generateEnrollmentToken() string {
-	b := make([]byte, 16)
-	if _, err := rand.Read(b); err != nil {
-		return fmt.Sprintf("%d", time.Now().UnixNano())
+// rollbackPeers loads the paired peers so a rollback can connect to them
+func rollbackPeers(ctx context.Context) (*peer.PeerDiscovery, *peer.TransferManager, error) {
+	cryptoManager, err := peer.NewCryptoManager(logger, cfg.DataDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create crypto manager: %w", err)
 	}
-	return fmt.Sprintf("%x", b)
+	transferManager, err := peer.NewTransferManager(cfg, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create transfer manager: %w", err)
+	}
+	pairingManager := peer.NewPairingManager(ctx, cfg, cryptoManager, logger)
+	peers := peer.NewPeerDiscovery(ctx, cfg, pairingManager, cryptoManager, logger)
+	if err := peers.LoadStaticPeers(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to load static peers: %w", err)
+	}
+	return peers, transferManager, nil
 }
 
-const enrollmentTokenFile = ".docker-migrate-token"
+var historyCmd = &cobra.Command{
+	Use:   "history [job-id]",
+	Short: "Show finished migrations",
+	Long:  "List finished migrations from the history database, or show the full record of one",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		history, err := migration.NewHistoryStore(cfg.DataDir, logger.Logger)
+		if err != nil {
+			logger.Error("failed to open migration history", zap.Error(err))
+			os.Exit(1)
+		}
+		defer history.Close()
 
-func loadOrGenerateEnrollmentToken() string {
-	// Try to load existing token from file
-	data, err := os.ReadFile(enrollmentTokenFile)
-	if err == nil {
-		token := string(data)
-		if len(token) >= 16 {
-			return token
+		if len(args) == 1 {
+			job, err := history.Get(args[0])
+			if err != nil {
+				logger.Error("failed to read migration", zap.String("job_id", args[0]), zap.Error(err))
+				os.Exit(1)
+			}
+			data, _ := json.MarshalIndent(job, "", "  ")
+			fmt.Println(string(data))
+			return
 		}
-	}
 
-	// Generate new token and persist it
-	token := generateEnrollmentToken()
-	if err := os.WriteFile(enrollmentTokenFile, []byte(token), 0600); err != nil {
-		// Log warning but continue - token will work for this session
-		fmt.Fprintf(os.Stderr, "Warning: could not persist enrollment token: %v\n", err)
-	}
-	return token
+		status, _ := cmd.Flags().GetString("status")
+		peerID, _ := cmd.Flags().GetString("peer")
+		strategy, _ := cmd.Flags().GetString("strategy")
+		limit, _ := cmd.Flags().GetInt("limit")
+		filter := migration.HistoryFilter{
+			Status:   migration.MigrationStatus(status),
+			PeerID:   peerID,
+			Strategy: migration.MigrationStrategy(strategy),
+			Limit:    limit,
+		}
+		now := time.Now()
+		if since, _ := cmd.Flags().GetString("since"); since != "" {
+			if filter.Since, err = migration.ParseHistoryTime(since, now); err != nil {
+				logger.Error("invalid --since", zap.Error(err))
+				os.Exit(1)
+			}
+		}
+		if until, _ := cmd.Flags().GetString("until"); until != "" {
+			if filter.Until, err = migration.ParseHistoryTime(until, now); err != nil {
+				logger.Error("invalid --until", zap.Error(err))
+				os.Exit(1)
+			}
+		}
+
+		entries, err := history.List(filter)
+		if err != nil {
+			logger.Error("failed to list migration history", zap.Error(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("%-36s %-10s %-8s %-20s %-25s %-10s %10s %s\n",
+			"ID", "STATUS", "STRATEGY", "PEER", "FINISHED", "DURATION", "MB", "ERROR")
+		for _, e := range entries {
+			fmt.Printf("%-36s %-10s %-8s %-20s %-25s %-10s %10.1f %s\n",
+				e.ID, e.Status, e.Strategy, e.PeerID, e.EndTime.Format(time.RFC3339),
+				e.Duration.Round(time.Second), float64(e.BytesDone)/(1024*1024), e.LastError)
+		}
+	},
 }
 
-func runWorker(cmd *cobra.Command, args []string, enrollmentToken string) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Export and import migration bundles",
+	Long:  "Upload resources to the S3-compatible bucket in object_store as a migration bundle, or recreate a bundle on this host",
+}
 
-	// Get worker name
-	workerName := cfg.Worker.Name
-	if workerName == "" {
-		hostname, err := os.Hostname()
+var bundleExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export resources to a bundle",
+	Long:  "Upload containers, images, volumes and networks to the bucket; containers bring their images, named volumes and networks",
+	Run: func(cmd *cobra.Command, args []string) {
+		req := migration.BundleExportRequest{}
+		req.ID, _ = cmd.Flags().GetString("id")
+		req.Containers, _ = cmd.Flags().GetStringSlice("containers")
+		req.Images, _ = cmd.Flags().GetStringSlice("images")
+		req.Volumes, _ = cmd.Flags().GetStringSlice("volumes")
+		req.Networks, _ = cmd.Flags().GetStringSlice("networks")
+
+		bundles, closeDocker := newBundleManager()
+		defer closeDocker()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		manifest, err := bundles.Export(ctx, req)
 		if err != nil {
-			workerName = "worker-" + generateEnrollmentToken()[:8]
-		} else {
-			workerName = hostname
+			logger.Error("bundle export failed", zap.Error(err))
+			os.Exit(1)
 		}
-		cfg.Worker.Name = workerName
-	}
+		fmt.Printf("Exported bundle %s: %d containers, %d images, %d volumes, %d networks\n",
+			manifest.ID, len(manifest.Containers), len(manifest.Images), len(manifest.Volumes), len(manifest.Networks))
+	},
+}
 
-	// Parse labels from flags
-	labelStrs, _ := cmd.Flags().GetStringSlice("labels")
-	for _, l := range labelStrs {
-		parts := strings.SplitN(l, "=", 2)
-		if len(parts) == 2 {
-			cfg.Worker.Labels[parts[0]] = parts[1]
+var bundleImportCmd = &cobra.Command{
+	Use:   "import <bundle-id>",
+	Short: "Recreate a bundle on this host",
+	Long:  "Download a bundle and recreate its networks, volumes, images and containers; existing volumes and containers are never overwritten",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		start, _ := cmd.Flags().GetBool("start")
+		allowDefaultRuntime, _ := cmd.Flags().GetBool("allow-default-runtime")
+
+		bundles, closeDocker := newBundleManager()
+		defer closeDocker()
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		manifest, err := bundles.Import(ctx, args[0], migration.BundleImportOptions{Start: start, AllowDefaultRuntime: allowDefaultRuntime})
+		if err != nil {
+			logger.Error("bundle import failed", zap.String("bundle_id", args[0]), zap.Error(err))
+			os.Exit(1)
 		}
-	}
+		fmt.Printf("Imported bundle %s from %s: %d containers, %d images, %d volumes, %d networks\n",
+			manifest.ID, manifest.Host, len(manifest.Containers), len(manifest.Images), len(manifest.Volumes), len(manifest.Networks))
+	},
+}
 
-	// Initialize Docker client
-	dockerClient, err := docker.NewClient(logger, cfg.DockerHost)
-	if err != nil {
-		return fmt.Errorf("failed to create docker client: %w", err)
-	}
-	defer dockerClient.Close()
+var bundleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List bundles in the bucket",
+	Run: func(cmd *cobra.Command, args []string) {
+		bundles, closeDocker := newBundleManager()
+		defer closeDocker()
 
-	// Initialize crypto manager
-	cryptoManager, err := peer.NewCryptoManager(logger, cfg.DataDir)
-	if err != nil {
-		return fmt.Errorf("failed to create crypto manager: %w", err)
-	}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
 
-	// Initialize transfer manager
-	transferManager, err := peer.NewTransferManager(cfg, logger)
+		ids, err := bundles.List(ctx)
+		if err != nil {
+			logger.Error("failed to list bundles", zap.Error(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("%-32s %-25s %-20s %s\n", "ID", "CREATED", "HOST", "RESOURCES")
+		for _, id := range ids {
+			manifest, err := bundles.Manifest(ctx, id)
+			if err != nil {
+				fmt.Printf("%-32s %s\n", id, "(incomplete)")
+				continue
+			}
+			resources := len(manifest.Containers) + len(manifest.Images) + len(manifest.Volumes) + len(manifest.Networks)
+			fmt.Printf("%-32s %-25s %-20s %d\n", id, manifest.CreatedAt.Format(time.RFC3339), manifest.Host, resources)
+		}
+	},
+}
+
+// newBundleManager connects to Docker and the configured bucket; the returned
+// func closes the Docker client
+func newBundleManager() (*migration.BundleManager, func()) {
+	if cfg.ObjectStore == nil {
+		logger.Error("no object_store in the config")
+		os.Exit(1)
+	}
+	store, err := objectstore.New(cfg.ObjectStore)
 	if err != nil {
-		return fmt.Errorf("failed to create transfer manager: %w", err)
+		logger.Error("invalid object_store", zap.Error(err))
+		os.Exit(1)
 	}
 
-	// Create worker instance
-	w, err := worker.New(cfg, dockerClient, cryptoManager, transferManager, logger)
+	dockerClient, err := docker.NewClient(logger, cfg.DockerHost)
 	if err != nil {
-		return fmt.Errorf("failed to create worker: %w", err)
+		logger.Error("failed to create docker client", zap.Error(err))
+		os.Exit(1)
 	}
 
-	// Handle graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	bundles := migration.NewBundleManager(dockerClient, store, cfg.ObjectStore.Prefix, logger.Logger)
+	return bundles, func() { dockerClient.Close() }
+}
 
-	go func() {
-		<-sigChan
-		logger.Info("received shutdown signal")
-		cancel()
-		w.Stop()
-	}()
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose common setup problems",
+	Long:  "Check Docker connectivity, the node certificate, listen ports, the data directory, the clock and paired peers, printing a fix for each problem found. Exits non-zero if any check fails.",
+	Run: func(cmd *cobra.Command, args []string) {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		skipPeers, _ := cmd.Flags().GetBool("skip-peers")
 
-	logger.Info("starting worker",
-		zap.String("name", workerName),
-		zap.String("master_url", cfg.Worker.MasterURL),
-	)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var report doctorReport
+		doctorDocker(ctx, &report, timeout)
+		cert := doctorCertificate(&report)
+		doctorPorts(&report, timeout)
+		doctorDataDir(&report)
+		if cfg.Worker != nil && cfg.Worker.MasterURL != "" {
+			doctorMaster(&report, timeout)
+		}
+		clockChecked := false
+		if !skipPeers {
+			clockChecked = doctorPeers(ctx, &report, cert, timeout)
+		}
+		if !clockChecked {
+			doctorClock(ctx, &report, timeout)
+		}
+
+		fmt.Printf("\n%d passed, %d warned, %d failed\n", report.passed, report.warnings, report.failed)
+		if report.failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// doctorReport prints check results as they come and counts them
+type doctorReport struct {
+	passed, warnings, failed int
+}
+
+func (r *doctorReport) ok(check, format string, args ...interface{}) {
+	r.passed++
+	fmt.Printf("[OK]   %-12s %s\n", check, fmt.Sprintf(format, args...))
+}
+
+func (r *doctorReport) warn(check, fix, format string, args ...interface{}) {
+	r.warnings++
+	fmt.Printf("[WARN] %-12s %s\n", check, fmt.Sprintf(format, args...))
+	fmt.Printf("       %-12s fix: %s\n", "", fix)
+}
+
+func (r *doctorReport) fail(check, fix, format string, args ...interface{}) {
+	r.failed++
+	fmt.Printf("[FAIL] %-12s %s\n", check, fmt.Sprintf(format, args...))
+	fmt.Printf("       %-12s fix: %s\n", "", fix)
+}
+
+// doctorDocker checks that the Docker daemon answers
+func doctorDocker(ctx context.Context, r *doctorReport, timeout time.Duration) {
+	host := cfg.DockerHost
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = "the default socket"
+	}
+
+	dockerClient, err := docker.NewClient(logger, cfg.DockerHost)
+	if err != nil {
+		r.fail("docker", "start the Docker daemon, check docker_host or DOCKER_HOST, and make sure this user may use the socket (e.g. is in the docker group)",
+			"cannot reach Docker at %s: %v", host, err)
+		return
+	}
+	defer dockerClient.Close()
+
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := dockerClient.Ping(pingCtx); err != nil {
+		r.fail("docker", "check that the Docker daemon is healthy with 'docker info'",
+			"Docker at %s does not answer: %v", host, err)
+		return
+	}
+	r.ok("docker", "daemon at %s is reachable", host)
+}
+
+// doctorCertificate checks the node certificate, returning it when there is one
+func doctorCertificate(r *doctorReport) *x509.Certificate {
+	cert, err := peer.ReadCertificate(cfg.DataDir)
+	if errors.Is(err, os.ErrNotExist) {
+		r.ok("certificate", "none yet; one is generated on first start")
+		return nil
+	}
+	if err != nil {
+		r.fail("certificate", "move the certs directory in the data directory aside so a new certificate is generated, then pair peers again",
+			"%v", err)
+		return nil
+	}
+
+	now := time.Now()
+	switch {
+	case now.Before(cert.NotBefore):
+		r.fail("certificate", "set the system clock correctly (enable NTP, e.g. 'timedatectl set-ntp true')",
+			"not valid until %s: this host's clock is behind", cert.NotBefore.Format(time.RFC3339))
+	case now.After(cert.NotAfter):
+		r.fail("certificate", "restart docker-migrate to generate a new certificate, then pair peers again since the fingerprint changes",
+			"expired %s", cert.NotAfter.Format(time.RFC3339))
+	case cert.NotAfter.Sub(now) < 30*24*time.Hour:
+		r.warn("certificate", "plan to re-pair peers: a new certificate with a new fingerprint is generated once this one expires",
+			"expires %s (in %d days)", cert.NotAfter.Format(time.RFC3339), int(cert.NotAfter.Sub(now).Hours()/24))
+	default:
+		r.ok("certificate", "valid until %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	return cert
+}
+
+// doctorPorts checks that the configured listen addresses are free. An
+// address that is taken but answers is most likely docker-migrate itself.
+func doctorPorts(r *doctorReport, timeout time.Duration) {
+	addrs := []struct{ name, addr, key string }{
+		{"http", cfg.HTTPAddr, "http_addr"},
+		{"grpc", cfg.GRPCAddr, "grpc_addr"},
+	}
+	if cfg.Worker != nil && cfg.Worker.HealthAddr != "" {
+		addrs = append(addrs, struct{ name, addr, key string }{"health", cfg.Worker.HealthAddr, "worker.health_addr"})
+	}
+
+	for _, a := range addrs {
+		if a.addr == "" {
+			continue
+		}
+		ln, err := net.Listen("tcp", a.addr)
+		if err == nil {
+			ln.Close()
+			r.ok(a.name, "%s is free", a.addr)
+			continue
+		}
+
+		if conn, dialErr := net.DialTimeout("tcp", dialAddr(a.addr), timeout); dialErr == nil {
+			conn.Close()
+			r.warn(a.name, fmt.Sprintf("ignore this if docker-migrate is already running here; otherwise stop what holds the port or change %s", a.key),
+				"%s is in use", a.addr)
+			continue
+		}
+		r.fail(a.name, fmt.Sprintf("change %s, or use a port above 1024 when not running as root", a.key),
+			"cannot listen on %s: %v", a.addr, err)
+	}
+}
+
+// dialAddr turns a listen address such as ":8080" into one that can be dialled
+func dialAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// doctorDataDir checks that the data directory is writable and has room
+// for received transfers
+func doctorDataDir(r *doctorReport) {
+	dataDir, err := config.ResolveDataDir(cfg.DataDir)
+	if err != nil {
+		r.fail("data dir", "set HOME or data_dir", "%v", err)
+		return
+	}
+
+	dir := dataDir
+	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
+		// Created on first start; check where it will be created instead
+		dir = filepath.Dir(dataDir)
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		r.fail("data dir", fmt.Sprintf("make %s writable by this user or set data_dir elsewhere", dir),
+			"cannot write to %s: %v", dir, err)
+		return
+	}
+	f.Close()
+	os.Remove(f.Name())
+	if dir == dataDir {
+		r.ok("data dir", "%s is writable", dataDir)
+	} else {
+		r.ok("data dir", "%s will be created on first start", dataDir)
+	}
+
+	_, avail, err := docker.DiskSpace(dir)
+	if err != nil {
+		r.warn("disk space", "check free space on the data directory's filesystem by hand", "%v", err)
+		return
+	}
+	const mb = 1024 * 1024
+	switch {
+	case avail < peer.SpoolReserve:
+		r.fail("disk space", "free space on the filesystem or set data_dir to a larger one",
+			"%d MB free in %s; incoming transfers need more than %d MB", avail/mb, dir, peer.SpoolReserve/mb)
+	case avail < 4*peer.SpoolReserve:
+		r.warn("disk space", "free space before receiving large volumes or images",
+			"only %d MB free in %s", avail/mb, dir)
+	default:
+		r.ok("disk space", "%d MB free in %s", avail/mb, dir)
+	}
+}
+
+// doctorMaster checks that a worker can open a connection to its master
+func doctorMaster(r *doctorReport, timeout time.Duration) {
+	addr := cfg.Worker.MasterURL
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		addr = u.Host
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		r.fail("master", "check worker.master_url, that the master is running, and that firewalls allow the connection",
+			"cannot reach %s: %v", addr, err)
+		return
+	}
+	conn.Close()
+	r.ok("master", "%s is reachable", addr)
+}
+
+// doctorPeers pings every paired peer and compares its clock with ours,
+// reporting whether any peer's clock was compared. Peers are not contacted
+// without a valid certificate, since loading an expired one replaces it.
+func doctorPeers(ctx context.Context, r *doctorReport, cert *x509.Certificate, timeout time.Duration) bool {
+	trusted := cfg.ListTrustedPeers()
+	if len(trusted) == 0 && len(cfg.StaticPeers) == 0 {
+		r.ok("peers", "none paired")
+		return false
+	}
+	if cert == nil {
+		r.warn("peers", "start docker-migrate once so a certificate exists, then run doctor again",
+			"not checked without a certificate")
+		return false
+	}
+	if now := time.Now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		r.warn("peers", "fix the certificate problem above, then run doctor again",
+			"not checked: the certificate is not valid now")
+		return false
+	}
+
+	peers, transfer, err := rollbackPeers(ctx)
+	if err != nil {
+		r.fail("peers", "check the trusted and static peers in the config file", "%v", err)
+		return false
+	}
+
+	compared := false
+	for _, p := range peers.GetAllPeers() {
+		check := "peer " + p.Name
+		connectCtx, cancel := context.WithTimeout(ctx, timeout)
+		client, err := peers.Connect(connectCtx, p.ID, transfer)
+		cancel()
+		if err != nil {
+			r.fail(check, fmt.Sprintf("check that docker-migrate runs on %s and that its gRPC port is open; re-pair if either node's certificate changed", p.Address),
+				"unreachable: %v", err)
+			continue
+		}
+
+		skew, known := client.ClockSkew()
+		client.Close()
+		compared = compared || known
+		switch {
+		case !known:
+			r.ok(check, "reachable at %s (clock not reported)", p.Address)
+		case peer.ClockSkewExceeded(skew):
+			r.warn(check, "enable NTP on both hosts (e.g. 'timedatectl set-ntp true')",
+				"reachable at %s, but clocks differ by %s", p.Address, skew.Round(time.Millisecond))
+		default:
+			r.ok(check, "reachable at %s, clock within %s", p.Address, peer.ClockSkewWarnThreshold)
+		}
+	}
+	return compared
+}
+
+// doctorClock checks that NTP keeps this host's clock in sync, for when no
+// peer's clock could be compared with ours
+func doctorClock(ctx context.Context, r *doctorReport, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "timedatectl", "show", "--property=NTPSynchronized", "--value").Output()
+	if err != nil {
+		r.warn("clock", "make sure NTP (systemd-timesyncd, chrony or ntpd) keeps this host's clock in sync",
+			"cannot read the NTP status: %v", err)
+		return
+	}
+	if strings.TrimSpace(string(out)) != "yes" {
+		r.warn("clock", "enable NTP (e.g. 'timedatectl set-ntp true')",
+			"not synchronized by NTP; certificates, tokens and schedules depend on the clock")
+		return
+	}
+	r.ok("clock", "synchronized by NTP")
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the security audit log",
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "Check the audit log's hash chain",
+	Long:  "Verify the hash chain of an exported audit log, or of audit.jsonl in the data directory when no file is given, and each entry's MAC with the audit key. Without the key only the chain is checked. Exits non-zero if the chain is broken.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := ""
+		if len(args) == 1 {
+			path = args[0]
+		} else {
+			dataDir, err := config.ResolveDataDir(cfg.DataDir)
+			if err != nil {
+				logger.Error("failed to resolve data directory", zap.Error(err))
+				os.Exit(1)
+			}
+			path = filepath.Join(dataDir, audit.FileName)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			logger.Error("failed to open audit log", zap.String("path", path), zap.Error(err))
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		keyFile, _ := cmd.Flags().GetString("key")
+		if keyFile == "" {
+			keyFile = cfg.AuditKeyFile
+		}
+		var key *audit.Key
+		if keyFile, err = audit.ResolveKeyFile(keyFile); err == nil {
+			key, err = audit.ReadKey(keyFile)
+		}
+		if err != nil {
+			fmt.Printf("MACs not checked: %v\n", err)
+		}
+
+		result, err := audit.VerifyReader(f, key)
+		if err != nil {
+			logger.Error("failed to verify audit log", zap.Error(err))
+			os.Exit(1)
+		}
+		if !result.Valid {
+			fmt.Printf("BROKEN at line %d: %s\n", result.BrokenAt, result.Problem)
+			fmt.Printf("%d entries verified before the break\n", result.Entries)
+			os.Exit(1)
+		}
+		fmt.Printf("OK: %d entries\n", result.Entries)
+		if result.Keyed && result.Unkeyed > 0 {
+			fmt.Printf("%d entries predate the audit key, as its key file records, and are checked by the chain only\n", result.Unkeyed)
+		}
+		if result.Head != "" {
+			fmt.Printf("Head: %s\n", result.Head)
+		}
+	},
+}
+
+var composeCmd = &cobra.Command{
+	Use:   "compose",
+	Short: "Work with compose bundles",
+}
+
+var composeDecryptCmd = &cobra.Command{
+	Use:   "decrypt <file>...",
+	Short: "Open env and secret files sealed in a compose bundle",
+	Long:  "Decrypt files from a compose bundle exported with the encrypt env policy, in place. The bundle's session key is opened with this node's private key, which it was sealed for when the bundle was sent here. Env files have their sealed values opened; a sealed secret file <name>.enc is written back to <name>.",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyPath, _ := cmd.Flags().GetString("bundle-key")
+		keyData, err := os.ReadFile(keyPath)
+		if err != nil {
+			logger.Error("failed to read the bundle's sealed key", zap.String("path", keyPath), zap.Error(err))
+			os.Exit(1)
+		}
+		var sealed peer.SealedBundleKey
+		if err := json.Unmarshal(keyData, &sealed); err != nil {
+			logger.Error("failed to parse the bundle's sealed key", zap.String("path", keyPath), zap.Error(err))
+			os.Exit(1)
+		}
+		sessionKey, err := peer.OpenBundleKey(cfg.DataDir, &sealed)
+		if err != nil {
+			logger.Error("failed to open the bundle's session key", zap.Error(err))
+			os.Exit(1)
+		}
+
+		for _, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				logger.Error("failed to read file", zap.String("path", path), zap.Error(err))
+				os.Exit(1)
+			}
+
+			target := path
+			var plain []byte
+			if strings.HasSuffix(path, ".enc") {
+				target = strings.TrimSuffix(path, ".enc")
+				plain, err = docker.DecryptSecretFile(data, sessionKey)
+			} else {
+				plain, err = docker.DecryptEnvFile(data, sessionKey)
+			}
+			if err != nil {
+				logger.Error("failed to decrypt file", zap.String("path", path), zap.Error(err))
+				os.Exit(1)
+			}
+
+			if err := os.WriteFile(target, plain, 0600); err != nil {
+				logger.Error("failed to write file", zap.String("path", target), zap.Error(err))
+				os.Exit(1)
+			}
+			if target != path {
+				os.Remove(path)
+			}
+			fmt.Printf("Decrypted %s\n", target)
+		}
+	},
+}
+
+var templateCmd = &cobra.Command{
+	Use:     "template",
+	Aliases: []string{"plan"},
+	Short:   "Manage migration templates and saved plans",
+	Long:    "Save a migration configuration (resources, selectors, strategy, naming and image options) under a name and run it against any peer; saved with --to, it is a plan that re-runs against that peer",
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List migration templates",
+	Run: func(cmd *cobra.Command, args []string) {
+		templates := openTemplateStore()
+		list, err := templates.List()
+		if err != nil {
+			logger.Error("failed to list templates", zap.Error(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("%-24s %-6s %-9s %-20s %-25s %s\n", "NAME", "MODE", "STRATEGY", "PEER", "UPDATED", "DESCRIPTION")
+		for _, t := range list {
+			peerID := t.PeerID
+			if peerID == "" {
+				peerID = "-"
+			}
+			fmt.Printf("%-24s %-6s %-9s %-20s %-25s %s\n",
+				t.Name, t.Mode, t.Strategy, peerID, t.UpdatedAt.Format(time.RFC3339), t.Description)
+		}
+	},
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a migration template as JSON",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		templates := openTemplateStore()
+		t, err := templates.Get(args[0])
+		if err != nil {
+			logger.Error("failed to read template", zap.String("template", args[0]), zap.Error(err))
+			os.Exit(1)
+		}
+		data, _ := json.MarshalIndent(t, "", "  ")
+		fmt.Println(string(data))
+	},
+}
+
+var templateSaveCmd = &cobra.Command{
+	Use:   "save <name>",
+	Short: "Save a migration template",
+	Long:  "Create or replace a template from a JSON file holding the body of POST /api/migrate without peer_id and dry_run; --to saves it as a plan for that peer",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		description, _ := cmd.Flags().GetString("description")
+		peerID, _ := cmd.Flags().GetString("to")
+
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			logger.Error("failed to read template spec", zap.String("file", file), zap.Error(err))
+			os.Exit(1)
+		}
+
+		t := &migration.MigrationTemplate{Name: args[0]}
+		if err := json.Unmarshal(data, &t.MigrationSpec); err != nil {
+			logger.Error("invalid template spec", zap.String("file", file), zap.Error(err))
+			os.Exit(1)
+		}
+		t.Description = description
+		t.PeerID = peerID
+
+		templates := openTemplateStore()
+		if err := templates.Save(t); err != nil {
+			logger.Error("failed to save template", zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("Saved template %s\n", t.Name)
+	},
+}
+
+var templateDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a migration template",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		templates := openTemplateStore()
+		if err := templates.Delete(args[0]); err != nil {
+			logger.Error("failed to delete template", zap.String("template", args[0]), zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted template %s\n", args[0])
+	},
+}
+
+var templateRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a migration template against a peer",
+	Long:  "Ask the running docker-migrate server to start a migration from the template; the server reads the template from its own data directory. Without --to, the template's saved target peer is used.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		peerID, _ := cmd.Flags().GetString("to")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		server, _ := cmd.Flags().GetString("server")
+		token, _ := cmd.Flags().GetString("token")
+
+		if server == "" {
+			host, port, err := net.SplitHostPort(cfg.HTTPAddr)
+			if err != nil {
+				logger.Error("cannot derive server URL from http_addr, pass --server", zap.Error(err))
+				os.Exit(1)
+			}
+			if host == "" || host == "0.0.0.0" || host == "::" {
+				host = "localhost"
+			}
+			server = "http://" + net.JoinHostPort(host, port)
+		}
+
+		body, _ := json.Marshal(map[string]interface{}{
+			"peer_id": peerID,
+			"dry_run": dryRun,
+		})
+		endpoint := strings.TrimSuffix(server, "/") + "/api/templates/" + url.PathEscape(args[0]) + "/run"
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			logger.Error("invalid --server", zap.Error(err))
+			os.Exit(1)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		client := &http.Client{Timeout: 3 * time.Minute}
+		resp, err := client.Do(req)
+		if err != nil {
+			logger.Error("failed to reach server", zap.String("server", server), zap.Error(err))
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode/100 != 2 {
+			logger.Error("template run failed", zap.Int("status", resp.StatusCode), zap.String("response", strings.TrimSpace(string(data))))
+			os.Exit(1)
+		}
+
+		var out bytes.Buffer
+		if json.Indent(&out, data, "", "  ") == nil {
+			data = out.Bytes()
+		}
+		fmt.Println(string(data))
+	},
+}
+
+// openTemplateStore opens the migration templates in the data directory
+func openTemplateStore() *migration.TemplateStore {
+	templates, err := migration.NewTemplateStore(cfg.DataDir, logger.Logger)
+	if err != nil {
+		logger.Error("failed to open migration templates", zap.Error(err))
+		os.Exit(1)
+	}
+	return templates
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Manage scheduled migrations",
+	Long:  "Schedule a migration to run once at a set time or repeatedly on a cron expression; the running server starts it when due",
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List migration schedules",
+	Run: func(cmd *cobra.Command, args []string) {
+		schedules := openScheduleStore()
+		list, err := schedules.List()
+		if err != nil {
+			logger.Error("failed to list schedules", zap.Error(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("%-26s %-20s %-16s %-25s %-26s %s\n", "ID", "PEER", "WHEN", "NEXT RUN", "LAST JOB", "DESCRIPTION")
+		for _, s := range list {
+			when := s.Cron
+			if when == "" && s.RunAt != nil {
+				when = "once"
+			}
+			next := "-"
+			if s.NextRun != nil {
+				next = s.NextRun.Format(time.RFC3339)
+			}
+			last := s.LastJobID
+			if s.LastError != "" {
+				last = "error: " + s.LastError
+			}
+			fmt.Printf("%-26s %-20s %-16s %-25s %-26s %s\n", s.ID, s.PeerID, when, next, last, s.Description)
+		}
+	},
+}
+
+var scheduleShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Print a migration schedule as JSON",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		schedules := openScheduleStore()
+		s, err := schedules.Get(args[0])
+		if err != nil {
+			logger.Error("failed to read schedule", zap.String("schedule", args[0]), zap.Error(err))
+			os.Exit(1)
+		}
+		data, _ := json.MarshalIndent(s, "", "  ")
+		fmt.Println(string(data))
+	},
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Schedule a migration",
+	Long:  "Schedule the migration in a JSON file holding the body of POST /api/migrate without peer_id and dry_run, once with --at or repeatedly with --cron",
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		peerID, _ := cmd.Flags().GetString("to")
+		cron, _ := cmd.Flags().GetString("cron")
+		at, _ := cmd.Flags().GetString("at")
+		description, _ := cmd.Flags().GetString("description")
+
+		var data []byte
+		var err error
+		if file == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(file)
+		}
+		if err != nil {
+			logger.Error("failed to read migration spec", zap.String("file", file), zap.Error(err))
+			os.Exit(1)
+		}
+
+		s := &migration.MigrationSchedule{
+			PeerID:      peerID,
+			Cron:        cron,
+			Description: description,
+		}
+		if err := json.Unmarshal(data, &s.MigrationSpec); err != nil {
+			logger.Error("invalid migration spec", zap.String("file", file), zap.Error(err))
+			os.Exit(1)
+		}
+		if at != "" {
+			runAt, err := time.Parse(time.RFC3339, at)
+			if err != nil {
+				logger.Error("invalid --at, expected an RFC 3339 time", zap.String("at", at), zap.Error(err))
+				os.Exit(1)
+			}
+			s.RunAt = &runAt
+		}
+
+		schedules := openScheduleStore()
+		if err := schedules.Add(s); err != nil {
+			logger.Error("failed to schedule migration", zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("Scheduled %s, next run %s\n", s.ID, s.NextRun.Format(time.RFC3339))
+	},
+}
+
+var scheduleDeleteCmd = &cobra.Command{
+	Use:   "delete <id>",
+	Short: "Delete a migration schedule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		schedules := openScheduleStore()
+		if err := schedules.Delete(args[0]); err != nil {
+			logger.Error("failed to delete schedule", zap.String("schedule", args[0]), zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted schedule %s\n", args[0])
+	},
+}
+
+// openScheduleStore opens the migration schedules in the data directory
+func openScheduleStore() *migration.ScheduleStore {
+	schedules, err := migration.NewScheduleStore(cfg.DataDir, logger.Logger)
+	if err != nil {
+		logger.Error("failed to open migration schedules", zap.Error(err))
+		os.Exit(1)
+	}
+	return schedules
+}
+
+var masterCmd = &cobra.Command{
+	Use:   "master",
+	Short: "Run as master node with web UI",
+	Long:  "Start docker-migrate as a master node that manages worker connections and orchestrates migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		// Set role to master
+		cfg.Role = config.RoleMaster
+
+		// Get or generate enrollment token (persisted across restarts)
+		enrollmentToken, _ := cmd.Flags().GetString("enrollment-token")
+		if enrollmentToken == "" {
+			enrollmentToken = loadOrGenerateEnrollmentToken()
+		}
+
+		if cfg.Master == nil {
+			cfg.Master = config.DefaultMasterConfig()
+		}
+		cfg.Master.EnrollmentToken = enrollmentToken
+		if requireToken, _ := cmd.Flags().GetBool("require-api-token"); requireToken {
+			cfg.Master.RequireAPIToken = true
+		}
+		if requireApproval, _ := cmd.Flags().GetBool("require-approval"); requireApproval {
+			cfg.Master.RequireApproval = true
+		}
+
+		logger.Info("enrollment token for workers", zap.String("token", enrollmentToken))
+
+		// Run the UI server (which will detect master mode)
+		if err := runUIServer(cmd, args); err != nil {
+			logger.Error("failed to start master", zap.Error(err))
+			os.Exit(1)
+		}
+	},
+}
+
+var masterTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens for the master HTTP endpoints",
+}
+
+var masterTokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Issue an API token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ttl, _ := cmd.Flags().GetDuration("ttl")
+		roleName, _ := cmd.Flags().GetString("role")
+		role, err := master.ParseRole(roleName)
+		if err != nil {
+			logger.Error("invalid --role", zap.Error(err))
+			os.Exit(1)
+		}
+
+		namespace, _ := cmd.Flags().GetString("namespace")
+		if namespace != "" && !openNamespaceStore().Exists(namespace) {
+			logger.Error("invalid --namespace", zap.String("namespace", namespace))
+			os.Exit(1)
+		}
+
+		tokens := openTokenStore()
+		plaintext, token, err := tokens.Issue(args[0], role, namespace, ttl)
+		if err != nil {
+			logger.Error("failed to issue token", zap.Error(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Issued %s token %s (%s)\n", token.Role, token.ID, token.Name)
+		if token.Namespace != "" {
+			fmt.Printf("Namespace: %s\n", token.Namespace)
+		}
+		if !token.ExpiresAt.IsZero() {
+			fmt.Printf("Expires: %s\n", token.ExpiresAt.Format(time.RFC3339))
+		}
+		fmt.Printf("\n  %s\n\nStore it now; it cannot be shown again.\n", plaintext)
+	},
+}
+
+var masterTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		tokens := openTokenStore()
+
+		now := time.Now()
+		fmt.Printf("%-14s %-20s %-9s %-16s %-10s %-25s %s\n", "ID", "NAME", "ROLE", "NAMESPACE", "STATE", "EXPIRES", "LAST USED")
+		for _, t := range tokens.List() {
+			state := "active"
+			if t.Revoked() {
+				state = "revoked"
+			} else if t.Expired(now) {
+				state = "expired"
+			}
+			expires, lastUsed := "never", "-"
+			if !t.ExpiresAt.IsZero() {
+				expires = t.ExpiresAt.Format(time.RFC3339)
+			}
+			if !t.LastUsedAt.IsZero() {
+				lastUsed = t.LastUsedAt.Format(time.RFC3339)
+			}
+			role := t.Role
+			if role == "" {
+				role = master.RoleAdmin
+			}
+			namespace := t.Namespace
+			if namespace == "" {
+				namespace = "*"
+			}
+			fmt.Printf("%-14s %-20s %-9s %-16s %-10s %-25s %s\n", t.ID, t.Name, role, namespace, state, expires, lastUsed)
+		}
+	},
+}
+
+var masterTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <token-id>",
+	Short: "Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := openTokenStore().Revoke(args[0]); err != nil {
+			logger.Error("failed to revoke token", zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("Revoked token %s\n", args[0])
+	},
+}
+
+var masterTOTPCmd = &cobra.Command{
+	Use:   "totp [enroll|disable]",
+	Short: "Manage the TOTP second factor for destructive operations",
+	Long:  "Once enrolled, worker removal, migration start/approval and token management require a code in the X-TOTP-Code header",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tokens := openTokenStore()
+
+		switch args[0] {
+		case "enroll":
+			secret, uri, err := tokens.EnrollTOTP("master")
+			if err != nil {
+				logger.Error("failed to enroll totp", zap.Error(err))
+				os.Exit(1)
+			}
+			fmt.Printf("Secret: %s\n", secret)
+			fmt.Printf("URI:    %s\n", uri)
+			fmt.Println("Add it to an authenticator app; any previous secret no longer works.")
+		case "disable":
+			if err := tokens.DisableTOTP(); err != nil {
+				logger.Error("failed to disable totp", zap.Error(err))
+				os.Exit(1)
+			}
+			fmt.Println("TOTP second factor disabled")
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown totp action: %s\n", args[0])
+			os.Exit(1)
+		}
+	},
+}
+
+// openTokenStore opens the master's API token store or exits
+func openTokenStore() *master.TokenStore {
+	tokens, err := master.NewTokenStore(cfg.DataDir, logger)
+	if err != nil {
+		logger.Error("failed to open token store", zap.Error(err))
+		os.Exit(1)
+	}
+	return tokens
+}
+
+var masterNamespaceCmd = &cobra.Command{
+	Use:   "namespace",
+	Short: "Manage namespaces that split workers and jobs between teams",
+}
+
+var masterNamespaceCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a namespace and print its enrollment token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		description, _ := cmd.Flags().GetString("description")
+
+		token, ns, err := openNamespaceStore().Create(args[0], description)
+		if err != nil {
+			logger.Error("failed to create namespace", zap.Error(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Created namespace %s\n", ns.Name)
+		fmt.Printf("\nEnrollment token:\n\n  %s\n\nWorkers enrolled with it join the namespace. Store it now; it cannot be shown again.\n", token)
+	},
+}
+
+var masterNamespaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List namespaces",
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Printf("%-24s %-25s %s\n", "NAME", "CREATED", "DESCRIPTION")
+		for _, ns := range openNamespaceStore().List() {
+			fmt.Printf("%-24s %-25s %s\n", ns.Name, ns.CreatedAt.Format(time.RFC3339), ns.Description)
+		}
+	},
+}
+
+var masterNamespaceDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a namespace; its workers and jobs stay visible to unscoped callers",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := openNamespaceStore().Delete(args[0]); err != nil {
+			logger.Error("failed to delete namespace", zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted namespace %s\n", args[0])
+	},
+}
+
+var masterNamespaceRotateTokenCmd = &cobra.Command{
+	Use:   "rotate-token <name>",
+	Short: "Replace a namespace's enrollment token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := openNamespaceStore().RotateToken(args[0])
+		if err != nil {
+			logger.Error("failed to rotate enrollment token", zap.Error(err))
+			os.Exit(1)
+		}
+		fmt.Printf("New enrollment token for %s:\n\n  %s\n\nEnrolled workers are unaffected.\n", args[0], token)
+	},
+}
+
+// openNamespaceStore opens the master's namespace store or exits
+func openNamespaceStore() *master.NamespaceStore {
+	namespaces, err := master.NewNamespaceStore(cfg.DataDir, logger)
+	if err != nil {
+		logger.Error("failed to open namespace store", zap.Error(err))
+		os.Exit(1)
+	}
+	return namespaces
+}
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run as worker node",
+	Long:  "Start docker-migrate as a worker node that connects to a master for coordinated migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		masterURL, _ := cmd.Flags().GetString("master-url")
+		token, _ := cmd.Flags().GetString("token")
+		workerName, _ := cmd.Flags().GetString("name")
+
+		if masterURL == "" {
+			fmt.Fprintln(os.Stderr, "Error: --master-url is required")
+			os.Exit(1)
+		}
+		if token == "" {
+			fmt.Fprintln(os.Stderr, "Error: --token is required")
+			os.Exit(1)
+		}
+
+		cfg.Role = config.RoleWorker
+		if cfg.Worker == nil {
+			cfg.Worker = config.DefaultWorkerConfig()
+		}
+		cfg.Worker.MasterURL = masterURL
+		cfg.Worker.Name = workerName
+		if tunnel, _ := cmd.Flags().GetString("tunnel"); tunnel != "" {
+			cfg.Worker.Tunnel = tunnel
+		}
+		if tunnelURL, _ := cmd.Flags().GetString("tunnel-url"); tunnelURL != "" {
+			cfg.Worker.TunnelURL = tunnelURL
+		}
+		if proxyURL, _ := cmd.Flags().GetString("proxy-url"); proxyURL != "" {
+			cfg.Worker.ProxyURL = proxyURL
+		}
+		if outboundOnly, _ := cmd.Flags().GetBool("outbound-only"); outboundOnly {
+			cfg.Worker.OutboundOnly = true
+		}
+		if fingerprint, _ := cmd.Flags().GetString("master-fingerprint"); fingerprint != "" {
+			cfg.Worker.MasterFingerprint = fingerprint
+		}
+		if healthAddr, _ := cmd.Flags().GetString("health-addr"); healthAddr != "" {
+			cfg.Worker.HealthAddr = healthAddr
+		}
+
+		// Worker needs to connect to master and run its own gRPC server
+		if err := runWorker(cmd, args, token); err != nil {
+			logger.Error("failed to start worker", zap.Error(err))
+			os.Exit(1)
+		}
+	},
+}
+
+var workerRequestCmd = &cobra.Command{
+	Use:   "request",
+	Short: "Ask the master to migrate resources off this worker",
+	Long:  "Submit a migration from the worker running on this host to another worker; it starts once an admin approves it on the master",
+	Run: func(cmd *cobra.Command, args []string) {
+		target, _ := cmd.Flags().GetString("to")
+		containers, _ := cmd.Flags().GetStringSlice("containers")
+		images, _ := cmd.Flags().GetStringSlice("images")
+		volumes, _ := cmd.Flags().GetStringSlice("volumes")
+		networks, _ := cmd.Flags().GetStringSlice("networks")
+		modeStr, _ := cmd.Flags().GetString("mode")
+		strategyStr, _ := cmd.Flags().GetString("strategy")
+		note, _ := cmd.Flags().GetString("note")
+
+		if len(containers) == 0 && len(images) == 0 && len(volumes) == 0 && len(networks) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: at least one of --containers, --images, --volumes or --networks is required")
+			os.Exit(1)
+		}
+
+		mode := pb.MigrationMode_MIGRATION_MODE_COLD
+		switch modeStr {
+		case "warm":
+			mode = pb.MigrationMode_MIGRATION_MODE_WARM
+		case "live":
+			mode = pb.MigrationMode_MIGRATION_MODE_LIVE
+		}
+
+		strategy := pb.MigrationStrategy_MIGRATION_STRATEGY_FULL
+		switch strategyStr {
+		case "incremental":
+			strategy = pb.MigrationStrategy_MIGRATION_STRATEGY_INCREMENTAL
+		case "snapshot":
+			strategy = pb.MigrationStrategy_MIGRATION_STRATEGY_SNAPSHOT
+		}
+
+		session, err := worker.LoadSession(cfg.DataDir)
+		if err != nil {
+			logger.Error("failed to load worker session", zap.Error(err))
+			os.Exit(1)
+		}
+
+		cryptoManager, err := peer.NewCryptoManager(logger, cfg.DataDir)
+		if err != nil {
+			logger.Error("failed to initialize crypto", zap.Error(err))
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		migrationID, err := worker.RequestMigration(ctx, session, cryptoManager, &pb.WorkerMigrationRequest{
+			TargetWorker: target,
+			ContainerIds: containers,
+			ImageIds:     images,
+			VolumeNames:  volumes,
+			NetworkIds:   networks,
+			Mode:         mode,
+			Strategy:     strategy,
+			Note:         note,
+		})
+		if err != nil {
+			logger.Error("failed to request migration", zap.Error(err))
+			os.Exit(1)
+		}
+
+		fmt.Printf("Migration %s requested; waiting for approval on the master\n", migrationID)
+	},
+}
+
+var (
+	migrateTo         string
+	migrateContainers []string
+	migrateVolumes    []string
+	migrateImages     []string
+	migrateNetworks   []string
+	migrateMode       string
+	migrateStrategy   string
+	migrateDryRun     bool
+)
+
+func generateEnrollmentToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%x", b)
+}
+
+const enrollmentTokenFile = ".docker-migrate-token"
+
+func loadOrGenerateEnrollmentToken() string {
+	// Try to load existing token from file
+	data, err := os.ReadFile(enrollmentTokenFile)
+	if err == nil {
+		token := string(data)
+		if len(token) >= 16 {
+			return token
+		}
+	}
+
+	// Generate new token and persist it
+	token := generateEnrollmentToken()
+	if err := os.WriteFile(enrollmentTokenFile, []byte(token), 0600); err != nil {
+		// Log warning but continue - token will work for this session
+		fmt.Fprintf(os.Stderr, "Warning: could not persist enrollment token: %v\n", err)
+	}
+	return token
+}
+
+func runWorker(cmd *cobra.Command, args []string, enrollmentToken string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Get worker name
+	workerName := cfg.Worker.Name
+	if workerName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			workerName = "worker-" + generateEnrollmentToken()[:8]
+		} else {
+			workerName = hostname
+		}
+		cfg.Worker.Name = workerName
+	}
+
+	// Parse labels from flags
+	labelStrs, _ := cmd.Flags().GetStringSlice("labels")
+	for _, l := range labelStrs {
+		parts := strings.SplitN(l, "=", 2)
+		if len(parts) == 2 {
+			cfg.Worker.Labels[parts[0]] = parts[1]
+		}
+	}
+
+	// Initialize Docker client
+	dockerClient, err := docker.NewClient(logger, cfg.DockerHost)
+	if err != nil {
+		return fmt.Errorf("failed to create docker client: %w", err)
+	}
+	defer dockerClient.Close()
+
+	freezeMode, err := docker.ParseFreezeMode(cfg.ExportFreeze)
+	if err != nil {
+		return fmt.Errorf("invalid export_freeze: %w", err)
+	}
+	dockerClient.SetExportFreeze(freezeMode)
+	dockerClient.SetExportThrottle(cfg.ExportRateLimit, cfg.ExportIOIdle)
+
+	// Initialize crypto manager
+	cryptoManager, err := peer.NewCryptoManager(logger, cfg.DataDir)
+	if err != nil {
+		return fmt.Errorf("failed to create crypto manager: %w", err)
+	}
+
+	// Initialize transfer manager
+	transferManager, err := peer.NewTransferManager(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer manager: %w", err)
+	}
+
+	// Create worker instance
+	w, err := worker.New(cfg, dockerClient, cryptoManager, transferManager, logger)
+	if err != nil {
+		return fmt.Errorf("failed to create worker: %w", err)
+	}
+
+	// Workers keep transfer checkpoints too
+	janitorTasks := janitor.New(logger)
+	janitorTasks.Register("checkpoints", cfg.CheckpointRetention, func(cutoff time.Time) int {
+		return transferManager.SweepCheckpoints(cutoff, nil)
+	})
+	go janitorTasks.Start(ctx, cfg.JanitorInterval)
+
+	// Orchestrators probe the worker on a small HTTP listener of its own
+	if cfg.Worker.HealthAddr != "" {
+		healthChecker := observability.NewHealthChecker()
+		w.RegisterHealthChecks(healthChecker)
+		go healthChecker.StartPeriodicChecks(ctx, 10*time.Second)
+		go func() {
+			if err := healthChecker.Serve(ctx, cfg.Worker.HealthAddr); err != nil {
+				logger.Error("health endpoint error", zap.Error(err))
+			}
+		}()
+		logger.Info("serving health endpoints", zap.String("addr", cfg.Worker.HealthAddr))
+	}
+
+	// Handle graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		logger.Info("received shutdown signal")
+		cancel()
+		w.Stop()
+	}()
+
+	logger.Info("starting worker",
+		zap.String("name", workerName),
+		zap.String("master_url", cfg.Worker.MasterURL),
+	)
 
 	// Start worker (blocks until context is cancelled)
 	return w.Start(ctx, enrollmentToken)
@@ -508,6 +1985,9 @@ func runWorker(cmd *cobra.Command, args []string, enrollmentToken string) error
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ~/.docker-migrate/config.json)")
+	rootCmd.PersistentFlags().String("log-modules", "", "Per-module log levels, e.g. peer.transfer=warn,server=info")
+	rootCmd.PersistentFlags().String("inject-faults", "", "Inject transfer faults for testing, e.g. corrupt=0.01,disconnect=0.001,latency=50ms,jitter=10ms,seed=42")
+	rootCmd.PersistentFlags().MarkHidden("inject-faults")
 
 	// Add subcommands
 	rootCmd.AddCommand(uiCmd)
@@ -517,11 +1997,84 @@ func init() {
 	rootCmd.AddCommand(migrateCmd)
 	rootCmd.AddCommand(masterCmd)
 	rootCmd.AddCommand(workerCmd)
+	rootCmd.AddCommand(benchmarkCmd)
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(bundleCmd)
+	rootCmd.AddCommand(composeCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(templateCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	rootCmd.AddCommand(doctorCmd)
 
 	// Pair subcommands
 	pairCmd.AddCommand(pairGenerateCmd)
 	pairCmd.AddCommand(pairConnectCmd)
 
+	// Prune flags
+	pruneCmd.Flags().Bool("all", false, "Also remove tagged unused images, named volumes or shared build cache")
+	pruneCmd.Flags().Bool("dry-run", false, "Report reclaimable space without deleting anything")
+
+	// Rollback flags
+	rollbackCmd.Flags().Bool("force", false, "Roll back a completed migration, deleting what it created on the target")
+
+	// History flags
+	historyCmd.Flags().String("status", "", "Only show migrations with this status: complete or failed")
+	historyCmd.Flags().String("peer", "", "Only show migrations to this peer ID")
+	historyCmd.Flags().String("strategy", "", "Only show migrations using this strategy")
+	historyCmd.Flags().String("since", "", "Only show migrations finished since this RFC 3339 time or duration ago, e.g. 168h")
+	historyCmd.Flags().String("until", "", "Only show migrations finished before this RFC 3339 time or duration ago")
+	historyCmd.Flags().Int("limit", migration.DefaultHistoryLimit, "Maximum number of migrations to show")
+
+	// Bundle subcommands
+	bundleCmd.AddCommand(bundleExportCmd)
+	bundleCmd.AddCommand(bundleImportCmd)
+	bundleCmd.AddCommand(bundleListCmd)
+	bundleExportCmd.Flags().String("id", "", "Bundle ID (default: bundle-<UTC timestamp>)")
+	bundleExportCmd.Flags().StringSlice("containers", nil, "Containers to export")
+	bundleExportCmd.Flags().StringSlice("images", nil, "Images to export")
+	bundleExportCmd.Flags().StringSlice("volumes", nil, "Volumes to export")
+	bundleExportCmd.Flags().StringSlice("networks", nil, "Networks to export")
+	bundleImportCmd.Flags().Bool("start", false, "Start the imported containers")
+	bundleImportCmd.Flags().Bool("allow-default-runtime", false, "Use the default runtime for containers whose runtime is not installed")
+
+	// Compose subcommands
+	composeCmd.AddCommand(composeDecryptCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+	auditVerifyCmd.Flags().String("key", "", "Audit key file (default: audit_key_file, or ~/.config/docker-migrate/audit.key)")
+	doctorCmd.Flags().Duration("timeout", 5*time.Second, "How long to wait for Docker, ports and each peer")
+	doctorCmd.Flags().Bool("skip-peers", false, "Do not contact paired peers")
+	composeDecryptCmd.Flags().String("bundle-key", docker.BundleKeyFile, "The bundle's sealed session key")
+
+	// Template subcommands
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateShowCmd)
+	templateCmd.AddCommand(templateSaveCmd)
+	templateCmd.AddCommand(templateDeleteCmd)
+	templateCmd.AddCommand(templateRunCmd)
+	templateSaveCmd.Flags().String("file", "", "JSON file with the migration spec, or - for stdin (required)")
+	templateSaveCmd.Flags().String("description", "", "What the template is for")
+	templateSaveCmd.Flags().String("to", "", "Target peer ID to run against when a run names none")
+	templateSaveCmd.MarkFlagRequired("file")
+	templateRunCmd.Flags().String("to", "", "Target peer ID (default: the template's own)")
+	templateRunCmd.Flags().Bool("dry-run", false, "Plan the migration without running it")
+	templateRunCmd.Flags().String("server", "", "URL of the docker-migrate server (default: this host's http_addr)")
+	templateRunCmd.Flags().String("token", "", "API token, when the server requires one")
+
+	// Schedule subcommands
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleShowCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleDeleteCmd)
+	scheduleAddCmd.Flags().String("file", "", "JSON file with the migration spec, or - for stdin (required)")
+	scheduleAddCmd.Flags().String("to", "", "Target peer ID (required)")
+	scheduleAddCmd.Flags().String("cron", "", "Cron expression to run on, e.g. \"0 2 * * 6\" for Saturdays at 02:00")
+	scheduleAddCmd.Flags().String("at", "", "RFC 3339 time to run once, e.g. 2026-11-07T02:00:00+01:00")
+	scheduleAddCmd.Flags().String("description", "", "What the schedule is for")
+	scheduleAddCmd.MarkFlagRequired("file")
+	scheduleAddCmd.MarkFlagRequired("to")
+
 	// Migrate flags
 	migrateCmd.Flags().StringVar(&migrateTo, "to", "", "Target peer ID (required)")
 	migrateCmd.Flags().StringSliceVar(&migrateContainers, "containers", nil, "Container IDs to migrate")
@@ -545,12 +2098,168 @@ func init() {
 		fmt.Printf("  Dry run: %v\n", migrateDryRun)
 	}
 
+	// Benchmark flags
+	benchmarkCmd.Flags().String("peer", "", "Paired peer ID to stream to (default: in-process loopback)")
+	benchmarkCmd.Flags().Int64("size", 256, "Data size per run in MB")
+	benchmarkCmd.Flags().StringSlice("chunk-sizes", []string{"256KB", "1MB", "2MB", "4MB"}, "Chunk sizes to compare, 256KB-4MB (loopback only)")
+	benchmarkCmd.Flags().Int("compression", 0, "Compression level 1-9 (0 = off, loopback only)")
+	benchmarkCmd.Flags().Int("compression-workers", 0, "Concurrent compression workers (default: compression_workers from config)")
+	benchmarkCmd.Flags().Int("compression-cpu", 0, "Aggregate compression CPU cap in percent of one core (default: compression_cpu_percent from config)")
+	benchmarkCmd.Flags().Float64("compressible", 0.5, "Fraction of synthetic data that is compressible (0-1)")
+
 	// Master flags
 	masterCmd.Flags().String("enrollment-token", "", "Token for worker enrollment (auto-generated if empty)")
+	masterCmd.Flags().Bool("require-api-token", false, "Reject HTTP API requests without a valid API token")
+	masterCmd.Flags().Bool("require-approval", false, "Let operators submit migrations for an admin to approve")
+	masterCmd.AddCommand(masterTokenCmd)
+	masterCmd.AddCommand(masterTOTPCmd)
+	masterTokenCmd.AddCommand(masterTokenCreateCmd)
+	masterTokenCmd.AddCommand(masterTokenListCmd)
+	masterTokenCmd.AddCommand(masterTokenRevokeCmd)
+	masterTokenCreateCmd.Flags().Duration("ttl", 0, "Token lifetime, e.g. 720h (default: never expires)")
+	masterTokenCreateCmd.Flags().String("role", string(master.RoleAdmin), "Token role: viewer, operator or admin")
+	masterTokenCreateCmd.Flags().String("namespace", "", "Confine the token to a namespace (default: sees all)")
+	masterCmd.AddCommand(masterNamespaceCmd)
+	masterNamespaceCmd.AddCommand(masterNamespaceCreateCmd)
+	masterNamespaceCmd.AddCommand(masterNamespaceListCmd)
+	masterNamespaceCmd.AddCommand(masterNamespaceDeleteCmd)
+	masterNamespaceCmd.AddCommand(masterNamespaceRotateTokenCmd)
+	masterNamespaceCreateCmd.Flags().String("description", "", "What the namespace is for")
 
 	// Worker flags
 	workerCmd.Flags().String("master-url", "", "Master gRPC URL (required)")
 	workerCmd.Flags().String("token", "", "Enrollment token from master (required)")
 	workerCmd.Flags().String("name", "", "Worker name (defaults to hostname)")
 	workerCmd.Flags().StringSlice("labels", nil, "Worker labels as key=value pairs")
+	workerCmd.Flags().String("tunnel", "", "Tunnel master connections: websocket or connect")
+	workerCmd.Flags().String("tunnel-url", "", "Master WebSocket tunnel URL (e.g. https://master:8080/api/tunnel)")
+	workerCmd.Flags().String("proxy-url", "", "Outbound HTTP proxy (defaults to HTTPS_PROXY)")
+	workerCmd.Flags().Bool("outbound-only", false, "Never listen for gRPC; route all transfers through the master proxy")
+	workerCmd.Flags().String("health-addr", "", "Serve /healthz and /readyz on this address (e.g. :8081)")
+	workerCmd.Flags().String("master-fingerprint", "", "SHA-256 fingerprint of the master's certificate (required on first registration)")
+
+	// Worker request flags
+	workerCmd.AddCommand(workerRequestCmd)
+	workerRequestCmd.Flags().String("to", "", "Target worker ID or name (required)")
+	workerRequestCmd.Flags().StringSlice("containers", nil, "Container IDs to migrate")
+	workerRequestCmd.Flags().StringSlice("images", nil, "Image IDs to migrate")
+	workerRequestCmd.Flags().StringSlice("volumes", nil, "Volume names to migrate")
+	workerRequestCmd.Flags().StringSlice("networks", nil, "Network IDs to migrate")
+	workerRequestCmd.Flags().String("mode", "cold", "Migration mode: cold, warm, or live")
+	workerRequestCmd.Flags().String("strategy", "full", "Migration strategy: full, incremental, or snapshot")
+	workerRequestCmd.Flags().String("note", "", "Note for the approving admin")
+	workerRequestCmd.MarkFlagRequired("to")
+}
+
+// runBenchmark runs the benchmark command
+func runBenchmark(cmd *cobra.Command) error {
+	peerID, _ := cmd.Flags().GetString("peer")
+	sizeMB, _ := cmd.Flags().GetInt64("size")
+	chunkSizes, _ := cmd.Flags().GetStringSlice("chunk-sizes")
+	compression, _ := cmd.Flags().GetInt("compression")
+	workers, _ := cmd.Flags().GetInt("compression-workers")
+	cpuPercent, _ := cmd.Flags().GetInt("compression-cpu")
+	compressible, _ := cmd.Flags().GetFloat64("compressible")
+
+	size := sizeMB * 1024 * 1024
+	if size <= 0 {
+		return fmt.Errorf("size must be positive")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Printf("%-10s %10s %8s %12s %14s %8s\n", "CHUNK", "DATA", "CHUNKS", "DURATION", "THROUGHPUT", "RATIO")
+	printResult := func(r *peer.BenchmarkResult) {
+		fmt.Printf("%-10s %10s %8d %12s %11.1f MB/s %7.2fx\n",
+			formatMB(int64(r.ChunkSize)), formatMB(r.Bytes), r.Chunks,
+			r.Duration.Round(time.Millisecond), r.ThroughputMBps, r.CompressionRatio)
+	}
+
+	if peerID != "" {
+		trusted, ok := cfg.TrustedPeers[peerID]
+		if !ok {
+			return fmt.Errorf("peer %s is not paired", peerID)
+		}
+
+		crypto, err := peer.NewCryptoManager(logger, cfg.DataDir)
+		if err != nil {
+			return fmt.Errorf("failed to initialize crypto: %w", err)
+		}
+		transfer, err := peer.NewTransferManager(cfg, logger)
+		if err != nil {
+			return fmt.Errorf("failed to create transfer manager: %w", err)
+		}
+
+		tunnel := trusted.SSHTunnel
+		if tunnel == nil {
+			tunnel = cfg.SSHTunnel
+		}
+		client, err := peer.NewGRPCClient(trusted.Address, trusted.Fingerprint, tunnel, transfer, crypto, logger)
+		if err != nil {
+			return fmt.Errorf("failed to connect to peer: %w", err)
+		}
+		defer client.Close()
+
+		result, err := client.RunPeerBenchmark(ctx, size, compressible)
+		if err != nil {
+			return err
+		}
+		printResult(result)
+		return nil
+	}
+
+	var pool *peer.CompressionPool
+	if compression > 0 {
+		if workers == 0 {
+			workers = cfg.CompressionWorkers
+		}
+		if cpuPercent == 0 {
+			cpuPercent = cfg.CompressionCPUPercent
+		}
+		var err error
+		if pool, err = peer.NewCompressionPool(compression, workers, cpuPercent); err != nil {
+			return err
+		}
+	}
+
+	for _, cs := range chunkSizes {
+		chunkSize, err := parseMB(cs)
+		if err != nil {
+			return err
+		}
+
+		result, err := peer.RunLoopbackBenchmark(ctx, size, int(chunkSize), pool, compressible)
+		if err != nil {
+			return err
+		}
+		printResult(result)
+	}
+
+	return nil
+}
+
+// parseMB parses sizes like "4MB", "512KB" or a plain byte count
+func parseMB(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(upper, "MB"):
+		multiplier = 1024 * 1024
+		upper = strings.TrimSuffix(upper, "MB")
+	case strings.HasSuffix(upper, "KB"):
+		multiplier = 1024
+		upper = strings.TrimSuffix(upper, "KB")
+	}
+
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return n * multiplier, nil
+}
+
+// formatMB renders a byte count in MB
+func formatMB(n int64) string {
+	return fmt.Sprintf("%.2fMB", float64(n)/(1024*1024))
 }
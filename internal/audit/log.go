@@ -0,0 +1,437 @@
+// Package audit keeps a tamper-evident record of security-relevant actions:
+// pairing and trust changes, worker enrollment, migrations started and
+// cancelled, and deletions.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	"go.uber.org/zap"
+)
+
+// FileName is the log's file in the data directory
+const FileName = "audit.jsonl"
+
+// ActionKeyed is the entry written when the log's key is generated. The key
+// file records its sequence number and hash, so a log replaced or deleted
+// after that point no longer verifies.
+const ActionKeyed = "audit.keyed"
+
+// Outcomes of an audited action
+const (
+	OutcomeOK     = "ok"
+	OutcomeDenied = "denied" // Refused for lack of authentication or role
+	OutcomeFailed = "failed"
+)
+
+// Entry is one audited action. Each entry's hash covers its own fields and
+// the hash of the entry before it, so editing, removing or reordering
+// entries breaks the chain from that point on. The MAC keys the hash with a
+// key kept outside the data directory, so the chain cannot be rewritten
+// and rehashed from scratch without it.
+type Entry struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`           // e.g. peer.removed, migration.start
+	Actor   string    `json:"actor"`            // Who did it, as in the command audit log
+	Target  string    `json:"target,omitempty"` // What it was done to
+	Detail  string    `json:"detail,omitempty"` // Never secrets
+	Outcome string    `json:"outcome"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+	MAC      string `json:"mac,omitempty"` // HMAC-SHA256 of Hash; missing on entries written before the log was keyed
+}
+
+// computeHash returns the hex SHA-256 of the entry with its Hash and MAC
+// unset
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	e.MAC = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// computeMAC returns the hex HMAC-SHA256 of the entry's hash under key
+func (e Entry) computeMAC(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(e.Hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verification is the result of checking the chain
+type Verification struct {
+	Valid   bool   `json:"valid"`
+	Entries uint64 `json:"entries"`
+	// Head is the last entry's hash. Keeping a copy elsewhere also catches
+	// entries cut from the end, which the chain alone cannot show.
+	Head string `json:"head,omitempty"`
+	// BrokenAt is the line where the chain first fails to check
+	BrokenAt uint64 `json:"broken_at,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+	// Keyed is set when the entries' MACs were checked against the key
+	Keyed bool `json:"keyed"`
+	// Unkeyed counts the entries written before the log was keyed, which
+	// only the chain vouches for. It is fixed by the key file and never
+	// grows.
+	Unkeyed uint64 `json:"unkeyed,omitempty"`
+}
+
+// Key is the log's MAC key and the entry written when it was generated.
+// Entries before FirstSeq predate the key; every entry from it on must be
+// MACed, and the one at FirstSeq must hash to Marker.
+type Key struct {
+	Secret   []byte
+	FirstSeq uint64
+	Marker   string // Hash of the ActionKeyed entry; empty in key files that predate it
+}
+
+// Log is an append-only, hash-chained log of security-relevant actions, kept
+// as JSON lines under the data directory. A nil Log records nothing.
+type Log struct {
+	path   string
+	key    *Key
+	logger *observability.Logger
+
+	mu       sync.Mutex
+	lastSeq  uint64
+	lastHash string
+}
+
+// Open opens the audit log in dataDir (default ~/.docker-migrate), picking
+// up its chain where the last run left it. Entries are MACed with the key in
+// keyFile (see ResolveKeyFile), which is generated on first use and must lie
+// outside dataDir. A key belongs to one log, since it records where that
+// log's keyed entries begin.
+func Open(dataDir, keyFile string, logger *observability.Logger) (*Log, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	keyFile, err = ResolveKeyFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if within(dataDir, keyFile) {
+		return nil, fmt.Errorf("audit key file %s must be outside the data directory %s", keyFile, dataDir)
+	}
+
+	l := &Log{
+		path:   filepath.Join(dataDir, FileName),
+		logger: logger,
+	}
+	if err := l.recover(); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(keyFile); !os.IsNotExist(err) {
+		if l.key, err = ReadKey(keyFile); err != nil {
+			return nil, err
+		}
+		if l.lastSeq < l.key.FirstSeq {
+			logger.Warn("audit log ends before its first keyed entry; it was replaced or deleted and will not verify",
+				zap.String("path", l.path),
+				zap.Uint64("first_keyed", l.key.FirstSeq),
+				zap.Uint64("entries", l.lastSeq),
+			)
+		}
+		return l, nil
+	}
+	if err := l.createKey(keyFile); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// ResolveKeyFile returns keyFile, or ~/.config/docker-migrate/audit.key
+// (the user's config directory) when it is empty
+func ResolveKeyFile(keyFile string) (string, error) {
+	if keyFile != "" {
+		return filepath.Abs(keyFile)
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the config directory for the audit key: %w", err)
+	}
+	return filepath.Join(configDir, "docker-migrate", "audit.key"), nil
+}
+
+// ReadKey reads the key the log's entries are MACed with. The file holds
+// the hex key, then the sequence number and hash of the ActionKeyed entry,
+// one per line. A file with the key alone has every entry keyed.
+func ReadKey(keyFile string) (*Key, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit key: %w", err)
+	}
+	lines := strings.Fields(string(data))
+	if len(lines) != 1 && len(lines) != 3 {
+		return nil, fmt.Errorf("audit key file %s is not a key and its first keyed entry", keyFile)
+	}
+	secret, err := hex.DecodeString(lines[0])
+	if err != nil || len(secret) < 32 {
+		return nil, fmt.Errorf("audit key file %s does not hold a hex key of at least 32 bytes", keyFile)
+	}
+	key := &Key{Secret: secret, FirstSeq: 1}
+	if len(lines) == 3 {
+		if key.FirstSeq, err = strconv.ParseUint(lines[1], 10, 64); err != nil || key.FirstSeq == 0 {
+			return nil, fmt.Errorf("audit key file %s has an invalid first keyed entry %q", keyFile, lines[1])
+		}
+		key.Marker = lines[2]
+	}
+	return key, nil
+}
+
+// createKey generates the log's key and writes the ActionKeyed entry with
+// it, then saves both to keyFile. The entry goes to the log first: if the
+// key file is never written, the next start keys the log again after it.
+func (l *Log) createKey(keyFile string) error {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return fmt.Errorf("failed to generate audit key: %w", err)
+	}
+	l.key = &Key{Secret: secret, FirstSeq: l.lastSeq + 1}
+
+	marker, err := l.append(Entry{
+		Action: ActionKeyed,
+		Actor:  "system",
+		Target: keyFile,
+		Detail: fmt.Sprintf("entries from %d on are MACed", l.lastSeq+1),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record audit key: %w", err)
+	}
+	l.key.Marker = marker.Hash
+
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return fmt.Errorf("failed to create audit key directory: %w", err)
+	}
+	data := fmt.Sprintf("%s\n%d\n%s\n", hex.EncodeToString(secret), l.key.FirstSeq, l.key.Marker)
+	if err := os.WriteFile(keyFile, []byte(data), 0400); err != nil {
+		return fmt.Errorf("failed to write audit key: %w", err)
+	}
+	l.logger.Info("generated audit log key",
+		zap.String("path", keyFile),
+		zap.Uint64("first_keyed", l.key.FirstSeq),
+	)
+	return nil
+}
+
+// within reports whether path lies inside dir
+func within(dir, path string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// recover finds the last complete entry. A line torn by a crash mid-write
+// is cut off so later entries chain from the last one that was written.
+func (l *Log) recover() error {
+	data, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+		l.logger.Warn("dropping torn final audit log entry", zap.Int("bytes", len(data)-end))
+		if err := os.Truncate(l.path, int64(end)); err != nil {
+			return fmt.Errorf("failed to repair audit log: %w", err)
+		}
+		data = data[:end]
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	last := lines[len(lines)-1]
+	if len(last) == 0 {
+		return nil
+	}
+	var entry Entry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return fmt.Errorf("failed to read last audit log entry: %w", err)
+	}
+	l.lastSeq, l.lastHash = entry.Seq, entry.Hash
+	return nil
+}
+
+// Record appends an entry, filling in its sequence number, time and hashes.
+// Failures are logged, not returned: the action has already happened.
+func (l *Log) Record(entry Entry) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.append(entry); err != nil {
+		l.logger.Warn("failed to record audit log entry", zap.Error(err))
+	}
+}
+
+// append fills in the entry's sequence number, time and hashes and writes
+// it to the end of the log
+func (l *Log) append(entry Entry) (Entry, error) {
+	entry.Seq = l.lastSeq + 1
+	entry.Time = time.Now().UTC()
+	entry.PrevHash = l.lastHash
+	if entry.Outcome == "" {
+		entry.Outcome = OutcomeOK
+	}
+	hash, err := entry.computeHash()
+	if err != nil {
+		return entry, fmt.Errorf("failed to hash entry: %w", err)
+	}
+	entry.Hash = hash
+	entry.MAC = entry.computeMAC(l.key.Secret)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return entry, fmt.Errorf("failed to encode entry: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return entry, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return entry, fmt.Errorf("failed to write entry: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		l.logger.Warn("failed to sync audit log", zap.Error(err))
+	}
+	l.lastSeq, l.lastHash = entry.Seq, entry.Hash
+	return entry, nil
+}
+
+// Export writes the whole log, as stored, to w and returns the head hash
+func (l *Log) Export(w io.Writer) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(w, f); err != nil {
+		return "", fmt.Errorf("failed to export audit log: %w", err)
+	}
+	return l.lastHash, nil
+}
+
+// Verify checks the log on disk
+func (l *Log) Verify() (*Verification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// A missing log is checked as an empty one, which fails once the log
+	// has been keyed
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return VerifyReader(strings.NewReader(""), l.key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	return VerifyReader(f, l.key)
+}
+
+// VerifyReader checks an exported log: every entry must parse, follow the
+// one before it in sequence and hash, and hash to what it records. With a
+// key, every entry from the key's first keyed one on must carry a matching
+// MAC, that entry must be the one the key file records, and the log must
+// reach it.
+func VerifyReader(r io.Reader, key *Key) (*Verification, error) {
+	result := &Verification{Valid: true, Keyed: key != nil}
+	broken := func(line uint64, format string, args ...interface{}) (*Verification, error) {
+		result.Valid = false
+		result.BrokenAt = line
+		result.Problem = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+
+	var line uint64
+	var prevHash string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line++
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return broken(line, "entry does not parse: %v", err)
+		}
+		if entry.Seq != line {
+			return broken(line, "sequence number %d, expected %d", entry.Seq, line)
+		}
+		if entry.PrevHash != prevHash {
+			return broken(line, "previous hash does not match the entry before it")
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return nil, err
+		}
+		if hash != entry.Hash {
+			return broken(line, "entry hash does not match its contents")
+		}
+		if key != nil {
+			switch {
+			case line < key.FirstSeq:
+				result.Unkeyed = line
+			case entry.MAC == "":
+				return broken(line, "entry has no MAC, but the log is keyed from entry %d", key.FirstSeq)
+			case !hmac.Equal([]byte(entry.MAC), []byte(entry.computeMAC(key.Secret))):
+				return broken(line, "entry MAC does not match the audit key")
+			case line == key.FirstSeq && key.Marker != "" && entry.Hash != key.Marker:
+				return broken(line, "entry is not the one the audit key was created with")
+			}
+		}
+		prevHash = entry.Hash
+		result.Entries = line
+		result.Head = entry.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if key != nil && line < key.FirstSeq {
+		return broken(line+1, "log ends before entry %d, where the audit key was created", key.FirstSeq)
+	}
+	return result, nil
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +26,11 @@ type Config struct {
 	HTTPAddr string `json:"http_addr"`
 	GRPCAddr string `json:"grpc_addr"`
 
+	// TrustedProxies are the addresses or CIDRs of reverse proxies whose
+	// X-Forwarded-For is believed; requests from anywhere else are known by
+	// their own address
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+
 	// Docker configuration
 	DockerHost string `json:"docker_host"`
 
@@ -39,20 +46,134 @@ type Config struct {
 	VerifyChecksums  bool          `json:"verify_checksums"`
 	CompressionLevel int           `json:"compression_level"`
 
+	// ReceiveBuffer bounds received volume data queued for disk in bytes (0 = 64MB)
+	ReceiveBuffer int64 `json:"receive_buffer,omitempty"`
+
+	// ReceiveFsyncBytes is how much received data is written between fsyncs (0 = 32MB)
+	ReceiveFsyncBytes int64 `json:"receive_fsync_bytes,omitempty"`
+
+	// SpoolDir holds in-flight received transfer files (default DataDir/spool)
+	SpoolDir string `json:"spool_dir,omitempty"`
+
+	// StallTimeout fails a transfer after this long without an acknowledged chunk (0 = 60s)
+	StallTimeout time.Duration `json:"stall_timeout,omitempty"`
+
+	// CheckpointBytes is how much transfer progress may go unsaved before a checkpoint is written (0 = 64MB)
+	CheckpointBytes int64 `json:"checkpoint_bytes,omitempty"`
+
+	// CheckpointInterval is the longest a progressing transfer goes between checkpoint writes (0 = 10s)
+	CheckpointInterval time.Duration `json:"checkpoint_interval,omitempty"`
+
+	// HealthWindow is how long migrated containers have to become healthy on the target (0 = 60s)
+	HealthWindow time.Duration `json:"health_window,omitempty"`
+
+	// ProgressInterval is the longest gap between progress reports of a running migration (0 = 1s)
+	ProgressInterval time.Duration `json:"progress_interval,omitempty"`
+
+	// ProgressBytes also reports progress each time this much more has moved (0 = by time only)
+	ProgressBytes int64 `json:"progress_bytes,omitempty"`
+
+	// CompressionWorkers caps concurrent compression goroutines (0 = half the CPUs)
+	CompressionWorkers int `json:"compression_workers,omitempty"`
+
+	// CompressionCPUPercent caps aggregate compression CPU in percent of one core (0 = unlimited)
+	CompressionCPUPercent int `json:"compression_cpu_percent,omitempty"`
+
+	// ExportFreeze quiesces the volume filesystem during export: "", "fsfreeze" or "flock"
+	ExportFreeze string `json:"export_freeze,omitempty"`
+
+	// TransferBindAddress is the local IP outbound transfer connections originate from
+	TransferBindAddress string `json:"transfer_bind_address,omitempty"`
+
+	// TransferInterface binds outbound transfers to this NIC's address when no bind address is set
+	TransferInterface string `json:"transfer_interface,omitempty"`
+
+	// ExportRateLimit caps disk reads of volume and image exports in bytes/sec (0 = unlimited)
+	ExportRateLimit int64 `json:"export_rate_limit,omitempty"`
+
+	// ExportIOIdle runs volume exports in the idle I/O class (Linux, like ionice -c3)
+	ExportIOIdle bool `json:"export_io_idle,omitempty"`
+
+	// FaultInjection deliberately degrades the transfer path for resilience testing.
+	// Intentionally undocumented; never enable outside tests and staging.
+	FaultInjection *FaultInjectionConfig `json:"fault_injection,omitempty"`
+
 	// Retry configuration
 	MaxRetries      int           `json:"max_retries"`
 	RetryBackoff    time.Duration `json:"retry_backoff"`
 	RetryMaxBackoff time.Duration `json:"retry_max_backoff"`
 
+	// Job retention: finished jobs older than JobRetention, or beyond the newest
+	// JobRetentionCount, are purged along with their rollback snapshots.
+	// 0 uses the default; a negative value removes that limit.
+	JobRetention      time.Duration `json:"job_retention"`
+	JobRetentionCount int           `json:"job_retention_count"`
+
+	// Janitor: every JanitorInterval, transfer checkpoints and rollback
+	// snapshots of vanished jobs untouched for CheckpointRetention, and spool
+	// and temporary files untouched for TempFileRetention, are removed.
+	// A negative retention keeps them.
+	JanitorInterval     time.Duration `json:"janitor_interval"`
+	CheckpointRetention time.Duration `json:"checkpoint_retention"`
+	TempFileRetention   time.Duration `json:"temp_file_retention"`
+
 	// Logging configuration
 	LogLevel string `json:"log_level"`
 
-	// Data directory for certificates and state
+	// LogModules overrides the level per module, e.g. {"peer.transfer": "warn"}
+	LogModules map[string]string `json:"log_modules,omitempty"`
+
+	// LogSampling limits repeated debug/info lines (nil = first 100 per second, then every 100th)
+	LogSampling *LogSampling `json:"log_sampling,omitempty"`
+
+	// Data directory for certificates, checkpoints, spool and other state (default ~/.docker-migrate)
 	DataDir string `json:"data_dir"`
 
+	// AuditKeyFile holds the key the security audit log's entries are MACed
+	// with (default ~/.config/docker-migrate/audit.key). It must lie outside
+	// DataDir, so whoever can rewrite the log cannot also re-key it.
+	AuditKeyFile string `json:"audit_key_file,omitempty"`
+
 	// Trusted peers
 	TrustedPeers map[string]*TrustedPeer `json:"trusted_peers"`
 
+	// StaticPeers are declared peers trusted by fingerprint without interactive pairing
+	StaticPeers []*StaticPeer `json:"static_peers,omitempty"`
+
+	// PeerDNSSD is a DNS-SD service name (e.g. _docker-migrate._tcp.example.com) whose
+	// SRV records list peers; each target needs a TXT record "fingerprint=<sha256>"
+	PeerDNSSD string `json:"peer_dnssd,omitempty"`
+
+	// MDNS advertises this node as a _docker-migrate._tcp service on the local
+	// network and lists the nodes it finds there for pairing. Finding a node
+	// does not trust it.
+	MDNS bool `json:"mdns,omitempty"`
+
+	// TOFU (trust on first use) records unknown peers that connect so they can be
+	// confirmed in the UI without pairing. Only for lab networks: whoever connects
+	// first gets offered for trust.
+	TOFU bool `json:"tofu,omitempty"`
+
+	// OIDC signs web UI users in through an OpenID Connect provider (nil = no UI login)
+	OIDC *OIDCConfig `json:"oidc,omitempty"`
+
+	// ImageRegistry is a private registry both sides of a migration can reach,
+	// used by jobs with image_mode "registry" (nil = mode unavailable)
+	ImageRegistry *RegistryConfig `json:"image_registry,omitempty"`
+
+	// ObjectStore is the S3-compatible bucket migration bundles are exported
+	// to and imported from (nil = bundles unavailable)
+	ObjectStore *ObjectStoreConfig `json:"object_store,omitempty"`
+
+	// SSHTunnel carries peer connections through an SSH server for peers
+	// without their own ssh_tunnel (nil = dial peers directly)
+	SSHTunnel *SSHTunnelConfig `json:"ssh_tunnel,omitempty"`
+
+	// NATTraversal reaches peers no address of which answers by punching a
+	// UDP hole through NAT, introduced by a master, or relaying through that
+	// master when no hole can be punched (nil = direct addresses only)
+	NATTraversal *NATTraversalConfig `json:"nat_traversal,omitempty"`
+
 	// Role configuration (master, worker, or empty for P2P mode)
 	Role   string        `json:"role,omitempty"`
 	Master *MasterConfig `json:"master,omitempty"`
@@ -77,6 +198,131 @@ type MasterConfig struct {
 
 	// MaxWorkers is the maximum number of workers allowed (0 = unlimited)
 	MaxWorkers int `json:"max_workers"`
+
+	// RequireAPIToken rejects HTTP API requests without a valid, unrevoked API token
+	RequireAPIToken bool `json:"require_api_token,omitempty"`
+
+	// RequireApproval lets operators submit migrations, which wait until an
+	// admin approves them (false = only admins may start migrations)
+	RequireApproval bool `json:"require_approval,omitempty"`
+
+	// AuthTokenRotation is how often each worker's auth token is replaced (0 = 24h)
+	AuthTokenRotation time.Duration `json:"auth_token_rotation,omitempty"`
+
+	// AuthTokenOverlap is how long a replaced token keeps working for a worker
+	// that has not switched yet (0 = 5m)
+	AuthTokenOverlap time.Duration `json:"auth_token_overlap,omitempty"`
+
+	// RelayToken lets P2P nodes use this master for NAT traversal and relay
+	// (empty = rendezvous disabled)
+	RelayToken string `json:"relay_token,omitempty"`
+
+	// IPAMPool is carved into subnets for bridge networks recreated on
+	// workers, so stacks landing on one target do not collide (nil = Docker
+	// picks each subnet)
+	IPAMPool *IPAMPoolConfig `json:"ipam_pool,omitempty"`
+}
+
+// IPAMPoolConfig is the site-wide address range the master allocates
+// network subnets from
+type IPAMPoolConfig struct {
+	// CIDR is the whole range, e.g. 10.200.0.0/16
+	CIDR string `json:"cidr"`
+
+	// PrefixLength is the size of each allocated subnet (0 = 24)
+	PrefixLength int `json:"prefix_length,omitempty"`
+}
+
+// RegistryConfig is an intermediary registry images are pushed to by the
+// source and pulled from by the target
+type RegistryConfig struct {
+	// Address is the registry and optional repository prefix, e.g. registry.internal:5000/migrate
+	Address string `json:"address"`
+
+	// Username and Password log in to the registry; empty for an open registry
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// NATTraversalConfig names the master that introduces this node to peers
+// behind NAT and relays for them
+type NATTraversalConfig struct {
+	// MasterAddr is the master's gRPC address, e.g. master.example.com:9090
+	MasterAddr string `json:"master_addr"`
+
+	// MasterFingerprint pins the master's certificate
+	MasterFingerprint string `json:"master_fingerprint"`
+
+	// RelayToken is the master's relay_token
+	RelayToken string `json:"relay_token"`
+
+	// STUNServers discover this node's public UDP address (default stun.l.google.com:19302)
+	STUNServers []string `json:"stun_servers,omitempty"`
+}
+
+// SSHTunnelConfig reaches a peer's gRPC port through an SSH server. The peer
+// address is dialed from that server, so it is often 127.0.0.1:<port> when
+// sshd runs on the peer itself.
+type SSHTunnelConfig struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"` // 0 = 22
+	User string `json:"user"`
+
+	// KeyFile is an unencrypted private key (empty = keys from the ssh-agent at SSH_AUTH_SOCK)
+	KeyFile string `json:"key_file,omitempty"`
+
+	// KnownHostsFile verifies the server's host key (empty = ~/.ssh/known_hosts)
+	KnownHostsFile string `json:"known_hosts_file,omitempty"`
+}
+
+// ObjectStoreConfig is an S3-compatible bucket, such as AWS S3 or MinIO
+type ObjectStoreConfig struct {
+	// Endpoint is the service URL, e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Endpoint string `json:"endpoint"`
+
+	// Region signs requests; MinIO accepts the default (empty = us-east-1)
+	Region string `json:"region,omitempty"`
+
+	// Bucket holds the bundles, each under Prefix followed by its ID
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix,omitempty"`
+
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+// OIDCConfig configures single sign-on for the web UI
+type OIDCConfig struct {
+	// Issuer is the provider's issuer URL, e.g. https://accounts.example.com
+	Issuer string `json:"issuer"`
+
+	// ClientID and ClientSecret identify this server to the provider
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+
+	// RedirectURL is this server's callback, e.g. https://migrate.example.com/auth/callback
+	RedirectURL string `json:"redirect_url"`
+
+	// Scopes are requested besides openid (default profile, email and groups)
+	Scopes []string `json:"scopes,omitempty"`
+
+	// GroupsClaim names the ID token claim listing the user's groups (default "groups")
+	GroupsClaim string `json:"groups_claim,omitempty"`
+
+	// GroupRoles maps provider groups to viewer, operator or admin; a user gets
+	// the highest role among their groups
+	GroupRoles map[string]string `json:"group_roles,omitempty"`
+
+	// DefaultRole is given to users in no mapped group; empty refuses them
+	DefaultRole string `json:"default_role,omitempty"`
+
+	// GroupNamespaces confines users in a provider group to a master
+	// namespace; users in no mapped group see every namespace, and users
+	// whose groups map to different namespaces are refused
+	GroupNamespaces map[string]string `json:"group_namespaces,omitempty"`
+
+	// SessionTTL is how long a UI session lasts (0 = 12h)
+	SessionTTL time.Duration `json:"session_ttl,omitempty"`
 }
 
 // WorkerConfig holds worker-specific configuration
@@ -101,6 +347,31 @@ type WorkerConfig struct {
 
 	// MaxReconnectInterval is the maximum backoff for reconnection attempts
 	MaxReconnectInterval time.Duration `json:"max_reconnect_interval"`
+
+	// OutboundOnly runs without a gRPC listener; transfers are forced through the master proxy
+	OutboundOnly bool `json:"outbound_only,omitempty"`
+
+	// Tunnel carries master connections over "websocket" or HTTP "connect" instead of direct TCP
+	Tunnel string `json:"tunnel,omitempty"`
+
+	// TunnelURL is the master's WebSocket tunnel endpoint, e.g. https://master:8080/api/tunnel
+	TunnelURL string `json:"tunnel_url,omitempty"`
+
+	// ProxyURL is the outbound HTTP proxy; defaults to HTTPS_PROXY from the environment
+	ProxyURL string `json:"proxy_url,omitempty"`
+
+	// MasterFingerprint is the SHA-256 fingerprint of the master's certificate. It is
+	// required until a registration has pinned it in the worker session.
+	MasterFingerprint string `json:"master_fingerprint,omitempty"`
+
+	// AllowedOperations limits what the master may have this worker do, e.g.
+	// ["send"] for a source-only worker or ["send", "receive_volumes"]. Empty
+	// allows everything.
+	AllowedOperations []string `json:"allowed_operations,omitempty"`
+
+	// HealthAddr serves /healthz and /readyz for orchestrators, e.g. ":8081";
+	// empty serves none
+	HealthAddr string `json:"health_addr,omitempty"`
 }
 
 // DefaultMasterConfig returns default master configuration
@@ -127,37 +398,133 @@ func DefaultWorkerConfig() *WorkerConfig {
 	}
 }
 
+// FaultInjectionConfig controls faults injected into chunk transfers
+type FaultInjectionConfig struct {
+	// CorruptRate is the probability a sent chunk has a byte flipped after checksumming
+	CorruptRate float64 `json:"corrupt_rate"`
+
+	// DisconnectRate is the probability the stream is dropped before sending a chunk
+	DisconnectRate float64 `json:"disconnect_rate"`
+
+	// Latency is added before every chunk send and ack
+	Latency time.Duration `json:"latency"`
+
+	// LatencyJitter adds up to this much random extra latency
+	LatencyJitter time.Duration `json:"latency_jitter"`
+
+	// Seed makes the injected faults reproducible (0 = time-based)
+	Seed int64 `json:"seed"`
+}
+
+// ParseFaultInjection parses a spec like "corrupt=0.01,disconnect=0.001,latency=50ms,jitter=10ms,seed=42"
+func ParseFaultInjection(spec string) (*FaultInjectionConfig, error) {
+	fi := &FaultInjectionConfig{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid fault spec %q: expected key=value", part)
+		}
+
+		var err error
+		switch key {
+		case "corrupt":
+			fi.CorruptRate, err = strconv.ParseFloat(value, 64)
+		case "disconnect":
+			fi.DisconnectRate, err = strconv.ParseFloat(value, 64)
+		case "latency":
+			fi.Latency, err = time.ParseDuration(value)
+		case "jitter":
+			fi.LatencyJitter, err = time.ParseDuration(value)
+		case "seed":
+			fi.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return nil, fmt.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+
+	if fi.CorruptRate < 0 || fi.CorruptRate > 1 || fi.DisconnectRate < 0 || fi.DisconnectRate > 1 {
+		return nil, fmt.Errorf("fault rates must be between 0 and 1")
+	}
+
+	return fi, nil
+}
+
 // TrustedPeer represents a peer that has been paired
 type TrustedPeer struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Fingerprint string    `json:"fingerprint"`
-	Address     string    `json:"address"`
-	AddedAt     time.Time `json:"added_at"`
-	LastSeen    time.Time `json:"last_seen"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint"`
+	Address     string `json:"address"`
+	// Addresses lists fallback addresses (e.g. LAN, VPN, public) tried in order after Address
+	Addresses []string  `json:"addresses,omitempty"`
+	AddedAt   time.Time `json:"added_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	// SSHTunnel overrides the global ssh_tunnel for this peer
+	SSHTunnel *SSHTunnelConfig `json:"ssh_tunnel,omitempty"`
+}
+
+// StaticPeer declares a peer in configuration instead of pairing with it
+type StaticPeer struct {
+	Name        string   `json:"name"`
+	Address     string   `json:"address"`
+	Addresses   []string `json:"addresses,omitempty"`
+	Fingerprint string   `json:"fingerprint"` // Pinned SHA-256 certificate fingerprint (hex)
+	// SSHTunnel overrides the global ssh_tunnel for this peer
+	SSHTunnel *SSHTunnelConfig `json:"ssh_tunnel,omitempty"`
+}
+
+// LogSampling limits how often one debug or info message is logged per second
+type LogSampling struct {
+	Initial    int `json:"initial"`    // Lines logged per message each second before sampling (0 = no sampling)
+	Thereafter int `json:"thereafter"` // After that, log every Nth line (0 = drop the rest)
 }
 
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
-		HTTPAddr:         ":8080",
-		GRPCAddr:         ":9090",
-		DockerHost:       "", // Use default Docker socket
-		TLSEnabled:       true,
-		ChunkSize:        1024 * 1024 * 4, // 4MB chunks
-		MaxConcurrent:    4,
-		TransferTimeout:  time.Hour,
-		VerifyChecksums:  true,
-		CompressionLevel: 3, // zstd default
-		MaxRetries:       5,
-		RetryBackoff:     time.Second,
-		RetryMaxBackoff:  time.Minute,
-		LogLevel:         "info",
-		DataDir:          "",  // Will use ~/.docker-migrate by default
-		TrustedPeers:     make(map[string]*TrustedPeer),
+		HTTPAddr:            ":8080",
+		GRPCAddr:            ":9090",
+		DockerHost:          "", // Use default Docker socket
+		TLSEnabled:          true,
+		ChunkSize:           1024 * 1024 * 4, // 4MB chunks
+		MaxConcurrent:       4,
+		TransferTimeout:     time.Hour,
+		VerifyChecksums:     true,
+		CompressionLevel:    3, // reserved: transfers are sent uncompressed
+		MaxRetries:          5,
+		RetryBackoff:        time.Second,
+		RetryMaxBackoff:     time.Minute,
+		JobRetention:        7 * 24 * time.Hour,
+		JobRetentionCount:   100,
+		JanitorInterval:     time.Hour,
+		CheckpointRetention: 72 * time.Hour,
+		TempFileRetention:   24 * time.Hour,
+		LogLevel:            "info",
+		DataDir:             "", // Will use ~/.docker-migrate by default
+		TrustedPeers:        make(map[string]*TrustedPeer),
 	}
 }
 
+// ResolveDataDir returns dataDir, or ~/.docker-migrate when it is empty.
+// Everything the tool persists lives under this directory.
+func ResolveDataDir(dataDir string) (string, error) {
+	if dataDir != "" {
+		return dataDir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".docker-migrate"), nil
+}
+
 // LoadConfig loads configuration from a file or returns default config
 func LoadConfig(path string) (*Config, error) {
 	if path == "" {
@@ -277,21 +644,75 @@ func (c *Config) Redact() map[string]interface{} {
 	defer c.mu.RUnlock()
 
 	return map[string]interface{}{
-		"http_addr":         c.HTTPAddr,
-		"grpc_addr":         c.GRPCAddr,
-		"docker_host":       observability.RedactString(c.DockerHost),
-		"tls_enabled":       c.TLSEnabled,
-		"cert_file":         c.CertFile,
-		"key_file":          "***REDACTED***",
-		"chunk_size":        c.ChunkSize,
-		"max_concurrent":    c.MaxConcurrent,
-		"transfer_timeout":  c.TransferTimeout,
-		"verify_checksums":  c.VerifyChecksums,
-		"compression_level": c.CompressionLevel,
-		"max_retries":       c.MaxRetries,
-		"log_level":         c.LogLevel,
-		"trusted_peers":     len(c.TrustedPeers),
+		"http_addr":               c.HTTPAddr,
+		"grpc_addr":               c.GRPCAddr,
+		"docker_host":             observability.RedactString(c.DockerHost),
+		"tls_enabled":             c.TLSEnabled,
+		"cert_file":               c.CertFile,
+		"key_file":                "***REDACTED***",
+		"chunk_size":              c.ChunkSize,
+		"max_concurrent":          c.MaxConcurrent,
+		"transfer_timeout":        c.TransferTimeout,
+		"verify_checksums":        c.VerifyChecksums,
+		"compression_level":       c.CompressionLevel,
+		"compression_workers":     c.CompressionWorkers,
+		"compression_cpu_percent": c.CompressionCPUPercent,
+		"export_freeze":           c.ExportFreeze,
+		"transfer_bind_address":   c.TransferBindAddress,
+		"transfer_interface":      c.TransferInterface,
+		"export_rate_limit":       c.ExportRateLimit,
+		"export_io_idle":          c.ExportIOIdle,
+		"max_retries":             c.MaxRetries,
+		"job_retention":           c.JobRetention,
+		"job_retention_count":     c.JobRetentionCount,
+		"janitor_interval":        c.JanitorInterval,
+		"checkpoint_retention":    c.CheckpointRetention,
+		"temp_file_retention":     c.TempFileRetention,
+		"log_level":               c.LogLevel,
+		"log_modules":             c.LogModules,
+		"trusted_peers":           len(c.TrustedPeers),
+		"static_peers":            len(c.StaticPeers),
+		"peer_dnssd":              c.PeerDNSSD,
+		"mdns":                    c.MDNS,
+		"tofu":                    c.TOFU,
+		"oidc_enabled":            c.OIDC != nil,
+		"image_registry":          c.imageRegistryAddress(),
+		"object_store":            c.objectStoreLocation(),
+		"ssh_tunnel":              c.sshTunnelHost(),
+		"nat_traversal":           c.natTraversalMaster(),
+	}
+}
+
+// natTraversalMaster returns the rendezvous master without its relay token
+func (c *Config) natTraversalMaster() string {
+	if c.NATTraversal == nil {
+		return ""
 	}
+	return c.NATTraversal.MasterAddr
+}
+
+// sshTunnelHost returns the default SSH tunnel server
+func (c *Config) sshTunnelHost() string {
+	if c.SSHTunnel == nil {
+		return ""
+	}
+	return c.SSHTunnel.Host
+}
+
+// objectStoreLocation returns the bundle bucket without its credentials
+func (c *Config) objectStoreLocation() string {
+	if c.ObjectStore == nil {
+		return ""
+	}
+	return c.ObjectStore.Endpoint + "/" + c.ObjectStore.Bucket
+}
+
+// imageRegistryAddress returns the intermediary registry without its credentials
+func (c *Config) imageRegistryAddress() string {
+	if c.ImageRegistry == nil {
+		return ""
+	}
+	return c.ImageRegistry.Address
 }
 
 func applyDefaults(cfg *Config) {
@@ -324,6 +745,21 @@ func applyDefaults(cfg *Config) {
 	if cfg.RetryMaxBackoff == 0 {
 		cfg.RetryMaxBackoff = defaults.RetryMaxBackoff
 	}
+	if cfg.JobRetention == 0 {
+		cfg.JobRetention = defaults.JobRetention
+	}
+	if cfg.JobRetentionCount == 0 {
+		cfg.JobRetentionCount = defaults.JobRetentionCount
+	}
+	if cfg.JanitorInterval <= 0 {
+		cfg.JanitorInterval = defaults.JanitorInterval
+	}
+	if cfg.CheckpointRetention == 0 {
+		cfg.CheckpointRetention = defaults.CheckpointRetention
+	}
+	if cfg.TempFileRetention == 0 {
+		cfg.TempFileRetention = defaults.TempFileRetention
+	}
 	if cfg.LogLevel == "" {
 		cfg.LogLevel = defaults.LogLevel
 	}
@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// BaseImage is a Docker Hub official image that provides the bottom layers
+// of another image, so a target can pull those layers from Docker Hub
+type BaseImage struct {
+	Reference string `json:"reference"` // Digest reference, e.g. "nginx@sha256:..."
+	Layers    int    `json:"layers"`    // Bottom layers of the image it provides
+	Whole     bool   `json:"whole"`     // The image is itself the official image
+}
+
+// IsOfficialImage reports whether a reference names a Docker Hub official
+// image, such as "nginx:1.25", "library/nginx" or "docker.io/library/nginx".
+// A bare name can also be a locally built image; pair this with a registry
+// digest to know the image came from Docker Hub.
+func IsOfficialImage(ref string) bool {
+	repo, _ := splitImageRef(ref)
+	for _, registry := range []string{"docker.io/", "index.docker.io/", "registry-1.docker.io/"} {
+		repo = strings.TrimPrefix(repo, registry)
+	}
+	repo = strings.TrimPrefix(repo, "library/")
+	return repo != "" && !strings.ContainsAny(repo, "/:")
+}
+
+// officialDigest returns an image's Docker Hub official digest reference
+func officialDigest(repoDigests []string) (string, bool) {
+	for _, digest := range repoDigests {
+		if IsOfficialImage(digest) {
+			return digest, true
+		}
+	}
+	return "", false
+}
+
+// FindPublicBaseImage finds the official image an image was built on, by
+// looking for the local official image whose layers are the longest prefix
+// of its own. It returns nil if there is none.
+func (c *Client) FindPublicBaseImage(ctx context.Context, imageID string) (*BaseImage, error) {
+	inspect, err := c.InspectImage(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+	layers := inspect.RootFS.Layers
+
+	if ref, ok := officialDigest(inspect.RepoDigests); ok {
+		return &BaseImage{Reference: ref, Layers: len(layers), Whole: true}, nil
+	}
+
+	images, err := c.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	var best *BaseImage
+	for _, img := range images {
+		if img.ID == inspect.ID {
+			continue
+		}
+		ref, ok := officialDigest(img.RepoDigests)
+		if !ok {
+			continue
+		}
+		candidate, err := c.InspectImage(ctx, img.ID)
+		if err != nil {
+			continue
+		}
+		base := candidate.RootFS.Layers
+		if len(base) == 0 || len(base) >= len(layers) || (best != nil && len(base) <= best.Layers) {
+			continue
+		}
+		if layersArePrefix(base, layers) {
+			best = &BaseImage{Reference: ref, Layers: len(base)}
+		}
+	}
+
+	return best, nil
+}
+
+// layersArePrefix reports whether base is the bottom of layers
+func layersArePrefix(base, layers []string) bool {
+	for i := range base {
+		if base[i] != layers[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/docker/docker/api/types/checkpoint"
+	"github.com/docker/docker/api/types/container"
+	"go.uber.org/zap"
+)
+
+// CheckpointSupported reports why this daemon cannot checkpoint containers,
+// or nil if it can. Docker only offers checkpoint/restore on Linux with
+// experimental features enabled, and needs CRIU installed on the host; a
+// missing CRIU only shows up when a checkpoint is attempted.
+func (c *Client) CheckpointSupported(ctx context.Context) error {
+	info, err := c.daemonInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if info.OSType != "linux" {
+		return fmt.Errorf("checkpoint/restore requires a Linux daemon, this one is %s", info.OSType)
+	}
+	if !info.ExperimentalBuild {
+		return fmt.Errorf("checkpoint/restore requires the daemon's experimental features")
+	}
+	return nil
+}
+
+// CreateCheckpoint saves a running container's process state with CRIU.
+// With exit set the container stops once the checkpoint is taken; otherwise
+// it keeps running. The checkpoint is written under dir on the daemon's
+// host, or the container's own state directory if dir is empty.
+func (c *Client) CreateCheckpoint(ctx context.Context, containerID, checkpointID, dir string, exit bool) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	err := cli.CheckpointCreate(ctx, containerID, checkpoint.CreateOptions{
+		CheckpointID:  checkpointID,
+		CheckpointDir: dir,
+		Exit:          exit,
+	})
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("checkpoint_create").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("checkpoint_create", "error").Inc()
+		return fmt.Errorf("failed to checkpoint container %s: %w", containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("checkpoint_create", "success").Inc()
+	c.logger.Info("container checkpointed",
+		zap.String("container_id", containerID),
+		zap.String("checkpoint", checkpointID),
+		zap.Bool("exit", exit),
+		zap.Duration("duration", duration),
+	)
+	return nil
+}
+
+// RemoveCheckpoint deletes a container's checkpoint from dir, or from the
+// container's state directory if dir is empty
+func (c *Client) RemoveCheckpoint(ctx context.Context, containerID, checkpointID, dir string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	err := cli.CheckpointDelete(ctx, containerID, checkpoint.DeleteOptions{
+		CheckpointID:  checkpointID,
+		CheckpointDir: dir,
+	})
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("checkpoint_delete").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("checkpoint_delete", "error").Inc()
+		return fmt.Errorf("failed to remove checkpoint %s of container %s: %w", checkpointID, containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("checkpoint_delete", "success").Inc()
+	return nil
+}
+
+// RestoreCheckpoint starts a stopped container from a checkpoint under dir,
+// or the container's state directory if dir is empty, resuming its
+// processes where they were saved
+func (c *Client) RestoreCheckpoint(ctx context.Context, containerID, checkpointID, dir string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	err := cli.ContainerStart(ctx, containerID, container.StartOptions{
+		CheckpointID:  checkpointID,
+		CheckpointDir: dir,
+	})
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("checkpoint_restore").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("checkpoint_restore", "error").Inc()
+		return fmt.Errorf("failed to restore container %s from checkpoint %s: %w", containerID, checkpointID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("checkpoint_restore", "success").Inc()
+	c.logger.Info("container restored from checkpoint",
+		zap.String("container_id", containerID),
+		zap.String("checkpoint", checkpointID),
+		zap.Duration("duration", duration),
+	)
+	return nil
+}
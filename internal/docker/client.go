@@ -8,6 +8,7 @@ import (
 
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"go.uber.org/zap"
 )
 
@@ -17,6 +18,15 @@ type Client struct {
 	logger *observability.Logger
 	mu     sync.RWMutex
 	closed bool
+
+	// exportFreeze protects volume mountpoints from writers during export
+	exportFreeze FreezeMode
+
+	// exportLimiter caps the aggregate read rate of exports; nil = unlimited
+	exportLimiter *RateLimiter
+
+	// exportIOIdle runs volume exports in the idle I/O scheduling class
+	exportIOIdle bool
 }
 
 // NewClient creates a new Docker client with connection validation
@@ -198,3 +208,9 @@ func containsAny(s, substr string) bool {
 	}
 	return false
 }
+
+// IsNotFound reports whether err means the container, volume, network or
+// image asked for does not exist
+func IsNotFound(err error) bool {
+	return errdefs.IsNotFound(err)
+}
@@ -0,0 +1,181 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/system"
+)
+
+// CompatibilityReport lists what a recreated container lost on this host:
+// settings the daemon dropped or changed, and limits it accepted but cannot
+// enforce. An empty report means the container matches its source.
+type CompatibilityReport struct {
+	Container   string         `json:"container"`
+	ContainerID string         `json:"container_id,omitempty"`
+	Skipped     []SkippedField `json:"skipped,omitempty"`
+	Warnings    []string       `json:"warnings,omitempty"` // Limit checks and daemon warnings
+}
+
+// SkippedField is a container setting that was not applied as requested
+type SkippedField struct {
+	Field     string `json:"field"` // e.g. "HostConfig.Sysctls"
+	Requested string `json:"requested"`
+	Applied   string `json:"applied,omitempty"`
+	Reason    string `json:"reason"`
+}
+
+// Clean reports whether every setting was applied as requested
+func (r *CompatibilityReport) Clean() bool {
+	return r == nil || (len(r.Skipped) == 0 && len(r.Warnings) == 0)
+}
+
+func (r *CompatibilityReport) skip(field string, requested, applied interface{}, reason string) {
+	entry := SkippedField{
+		Field:     field,
+		Requested: fmt.Sprintf("%v", requested),
+		Reason:    reason,
+	}
+	if !isUnset(applied) {
+		entry.Applied = fmt.Sprintf("%v", applied)
+	}
+	r.Skipped = append(r.Skipped, entry)
+}
+
+// hostConfigField reads one HostConfig setting for comparison
+type hostConfigField struct {
+	name string
+	get  func(*container.HostConfig) interface{}
+}
+
+// checkedHostConfigFields are the settings an older or differently built
+// daemon may drop without failing the create
+var checkedHostConfigFields = []hostConfigField{
+	{"Runtime", func(h *container.HostConfig) interface{} { return h.Runtime }},
+	{"Sysctls", func(h *container.HostConfig) interface{} { return h.Sysctls }},
+	{"DeviceCgroupRules", func(h *container.HostConfig) interface{} { return h.DeviceCgroupRules }},
+	{"Devices", func(h *container.HostConfig) interface{} { return h.Devices }},
+	{"DeviceRequests", func(h *container.HostConfig) interface{} { return h.DeviceRequests }},
+	{"CapAdd", func(h *container.HostConfig) interface{} { return []string(h.CapAdd) }},
+	{"CapDrop", func(h *container.HostConfig) interface{} { return []string(h.CapDrop) }},
+	{"SecurityOpt", func(h *container.HostConfig) interface{} { return h.SecurityOpt }},
+	{"Ulimits", func(h *container.HostConfig) interface{} { return h.Ulimits }},
+	{"Tmpfs", func(h *container.HostConfig) interface{} { return h.Tmpfs }},
+	{"Init", func(h *container.HostConfig) interface{} { return h.Init }},
+	{"CgroupnsMode", func(h *container.HostConfig) interface{} { return h.CgroupnsMode }},
+	{"IpcMode", func(h *container.HostConfig) interface{} { return h.IpcMode }},
+	{"PidMode", func(h *container.HostConfig) interface{} { return h.PidMode }},
+	{"UsernsMode", func(h *container.HostConfig) interface{} { return h.UsernsMode }},
+	{"ShmSize", func(h *container.HostConfig) interface{} { return h.ShmSize }},
+	{"OomScoreAdj", func(h *container.HostConfig) interface{} { return h.OomScoreAdj }},
+	{"GroupAdd", func(h *container.HostConfig) interface{} { return h.GroupAdd }},
+	{"StorageOpt", func(h *container.HostConfig) interface{} { return h.StorageOpt }},
+	{"Annotations", func(h *container.HostConfig) interface{} { return h.Annotations }},
+	{"MaskedPaths", func(h *container.HostConfig) interface{} { return h.MaskedPaths }},
+	{"ReadonlyPaths", func(h *container.HostConfig) interface{} { return h.ReadonlyPaths }},
+	{"Memory", func(h *container.HostConfig) interface{} { return h.Memory }},
+	{"MemoryReservation", func(h *container.HostConfig) interface{} { return h.MemoryReservation }},
+	{"MemorySwap", func(h *container.HostConfig) interface{} { return h.MemorySwap }},
+	{"MemorySwappiness", func(h *container.HostConfig) interface{} { return h.MemorySwappiness }},
+	{"NanoCPUs", func(h *container.HostConfig) interface{} { return h.NanoCPUs }},
+	{"CPUShares", func(h *container.HostConfig) interface{} { return h.CPUShares }},
+	{"CPUPeriod", func(h *container.HostConfig) interface{} { return h.CPUPeriod }},
+	{"CPUQuota", func(h *container.HostConfig) interface{} { return h.CPUQuota }},
+	{"CPURealtimeRuntime", func(h *container.HostConfig) interface{} { return h.CPURealtimeRuntime }},
+	{"CpusetCpus", func(h *container.HostConfig) interface{} { return h.CpusetCpus }},
+	{"CpusetMems", func(h *container.HostConfig) interface{} { return h.CpusetMems }},
+	{"PidsLimit", func(h *container.HostConfig) interface{} { return h.PidsLimit }},
+	{"BlkioWeight", func(h *container.HostConfig) interface{} { return h.BlkioWeight }},
+	{"KernelMemoryTCP", func(h *container.HostConfig) interface{} { return h.KernelMemoryTCP }},
+	{"OomKillDisable", func(h *container.HostConfig) interface{} { return h.OomKillDisable }},
+}
+
+// ErrRuntimeUnavailable is returned when a container asks for a runtime this
+// daemon does not have and the default runtime was not allowed instead
+var ErrRuntimeUnavailable = errors.New("container runtime is not installed")
+
+// checkRuntime handles a runtime this daemon does not have, which would
+// otherwise fail the create. A container isolated by a runtime such as gVisor
+// or Kata must not quietly run under runc, so it is an error unless
+// allowDefault is set; then the runtime is cleared and recorded in the report.
+func (r *CompatibilityReport) checkRuntime(info system.Info, hostConfig *container.HostConfig, allowDefault bool) error {
+	if hostConfig.Runtime == "" {
+		return nil
+	}
+	if _, ok := info.Runtimes[hostConfig.Runtime]; ok {
+		return nil
+	}
+
+	available := make([]string, 0, len(info.Runtimes))
+	for name := range info.Runtimes {
+		available = append(available, name)
+	}
+	sort.Strings(available)
+
+	if !allowDefault {
+		return fmt.Errorf("%w: %s (available: %v)", ErrRuntimeUnavailable, hostConfig.Runtime, available)
+	}
+	r.skip("HostConfig.Runtime", hostConfig.Runtime, info.DefaultRuntime,
+		fmt.Sprintf("runtime is not installed on this host (available: %v)", available))
+	hostConfig.Runtime = ""
+	return nil
+}
+
+// compareHostConfig records every setting that was requested but reads back
+// differently. Settings left unset are skipped, since the daemon fills in
+// its own defaults for them.
+func (r *CompatibilityReport) compareHostConfig(requested, applied *container.HostConfig) {
+	if requested == nil {
+		return
+	}
+	if applied == nil {
+		applied = &container.HostConfig{}
+	}
+
+	for _, field := range checkedHostConfigFields {
+		want := field.get(requested)
+		if isUnset(want) {
+			continue
+		}
+		got := field.get(applied)
+		if reflect.DeepEqual(deref(want), deref(got)) {
+			continue
+		}
+		reason := "changed by the target daemon"
+		if isUnset(got) {
+			reason = "not supported by the target daemon"
+		}
+		r.skip("HostConfig."+field.name, deref(want), deref(got), reason)
+	}
+}
+
+// isUnset reports whether a setting is nil, zero or empty
+func isUnset(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		return rv.IsNil()
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+// deref unwraps pointer settings such as PidsLimit so they print and compare
+// by value
+func deref(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		return rv.Elem().Interface()
+	}
+	return v
+}
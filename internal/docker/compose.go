@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/compose-spec/compose-go/v2/loader"
 	composetypes "github.com/compose-spec/compose-go/v2/types"
@@ -226,11 +227,31 @@ func (c *Client) ExportComposeResources(ctx context.Context, project *ComposePro
 	return resources, nil
 }
 
-// DetectComposeStacks finds all running compose projects on the system
+// Tools that deploy stacks, as reported in ComposeStack.ManagedBy
+const (
+	StackManagerCompose   = "compose"
+	StackManagerPortainer = "portainer"
+	StackManagerKomodo    = "komodo"
+	StackManagerSwarm     = "swarm"
+)
+
+// Labels identifying a stack and its services
+const (
+	composeProjectLabel     = "com.docker.compose.project"
+	composeServiceLabel     = "com.docker.compose.service"
+	composeWorkingDirLabel  = "com.docker.compose.project.working_dir"
+	composeConfigFilesLabel = "com.docker.compose.project.config_files"
+	swarmStackLabel         = "com.docker.stack.namespace"
+	swarmServiceLabel       = "com.docker.swarm.service.name"
+)
+
+// DetectComposeStacks finds all running compose projects on the system,
+// including those deployed by Portainer or Komodo and the local tasks of
+// Swarm stacks
 func (c *Client) DetectComposeStacks(ctx context.Context) ([]*ComposeStack, error) {
 	c.logger.Info("detecting compose stacks")
 
-	// Get all containers and group by compose.project label
+	// Get all containers and group by project or stack label
 	containers, err := c.ListContainers(ctx, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list containers: %w", err)
@@ -238,7 +259,7 @@ func (c *Client) DetectComposeStacks(ctx context.Context) ([]*ComposeStack, erro
 
 	projectContainers := make(map[string][]types.Container)
 	for _, container := range containers {
-		if project, ok := container.Labels["com.docker.compose.project"]; ok {
+		if project := StackName(container.Labels); project != "" {
 			projectContainers[project] = append(projectContainers[project], container)
 		}
 	}
@@ -246,21 +267,26 @@ func (c *Client) DetectComposeStacks(ctx context.Context) ([]*ComposeStack, erro
 	stacks := make([]*ComposeStack, 0, len(projectContainers))
 	for projectName, containers := range projectContainers {
 		stack := &ComposeStack{
-			Name: projectName,
+			Name:      projectName,
+			ManagedBy: StackManager(containers[0].Labels),
 		}
 
-		// Try to find compose file from first container
-		if len(containers) > 0 {
-			if dir, ok := containers[0].Labels["com.docker.compose.project.working_dir"]; ok {
-				stack.Directory = dir
-				stack.ConfigPath = filepath.Join(dir, "docker-compose.yml")
+		// Try to find compose file from first container. Swarm keeps no
+		// compose file on the nodes, and Portainer's lives in its own volume,
+		// so either may be missing.
+		labels := containers[0].Labels
+		if dir, ok := labels[composeWorkingDirLabel]; ok {
+			stack.Directory = dir
+			stack.ConfigPath = filepath.Join(dir, "docker-compose.yml")
+			if files := labels[composeConfigFilesLabel]; files != "" {
+				stack.ConfigPath = strings.Split(files, ",")[0]
 			}
 		}
 
 		// Build service list from containers
 		for _, container := range containers {
 			service := ComposeService{
-				Name:        container.Labels["com.docker.compose.service"],
+				Name:        serviceName(projectName, container.Labels),
 				Image:       container.Image,
 				ContainerID: container.ID,
 				Status:      container.State,
@@ -275,14 +301,61 @@ func (c *Client) DetectComposeStacks(ctx context.Context) ([]*ComposeStack, erro
 	return stacks, nil
 }
 
+// StackName returns the compose project or Swarm stack a resource belongs
+// to, or "" for a standalone one
+func StackName(labels map[string]string) string {
+	if project := labels[composeProjectLabel]; project != "" {
+		return project
+	}
+	return labels[swarmStackLabel]
+}
+
+// serviceName returns a container's service within its stack. Swarm names
+// services "<stack>_<service>".
+func serviceName(stack string, labels map[string]string) string {
+	if service := labels[composeServiceLabel]; service != "" {
+		return service
+	}
+	return strings.TrimPrefix(labels[swarmServiceLabel], stack+"_")
+}
+
+// StackManager works out which tool deployed a stack from its labels.
+// Portainer and Komodo run docker compose themselves, so their stacks carry
+// the compose labels too, plus their own or a working directory under
+// their data directories.
+func StackManager(labels map[string]string) string {
+	if labels[swarmStackLabel] != "" {
+		return StackManagerSwarm
+	}
+	for key := range labels {
+		switch {
+		case strings.HasPrefix(key, "io.portainer."):
+			return StackManagerPortainer
+		case strings.HasPrefix(key, "komodo."):
+			return StackManagerKomodo
+		}
+	}
+	dir := labels[composeWorkingDirLabel]
+	switch {
+	case strings.HasPrefix(dir, "/data/compose/"):
+		return StackManagerPortainer
+	case strings.Contains(dir, "/komodo/stacks/"):
+		return StackManagerKomodo
+	}
+	return StackManagerCompose
+}
+
 // ComposeStack represents a detected compose stack
 type ComposeStack struct {
 	Name       string
 	Directory  string
 	ConfigPath string
-	Services   []ComposeService
-	Volumes    []string
-	Networks   []string
+	// ManagedBy is the tool that deployed the stack: compose, portainer,
+	// komodo or swarm
+	ManagedBy string
+	Services  []ComposeService
+	Volumes   []string
+	Networks  []string
 }
 
 // ComposeService represents a service in a compose stack
@@ -294,39 +367,154 @@ type ComposeService struct {
 	Replicas    int
 }
 
-// ExportComposeBundle creates a tarball of compose project with all files
-func (c *Client) ExportComposeBundle(stack *ComposeStack) (io.Reader, error) {
-	c.logger.Info("exporting compose bundle", zap.String("stack", stack.Name))
+// ExportComposeBundle creates a tarball of compose project with all files.
+// The policy decides how .env, the services' env_file entries and
+// file-based secrets are bundled. The encrypt mode seals them with
+// sessionKey, which the bundle carries only as sealedKey, in BundleKeyFile,
+// sealed for the host it is sent to.
+func (c *Client) ExportComposeBundle(ctx context.Context, stack *ComposeStack, policy EnvPolicy, sessionKey, sealedKey []byte) (io.Reader, error) {
+	c.logger.Info("exporting compose bundle",
+		zap.String("stack", stack.Name),
+		zap.String("env_policy", string(policy.Mode)),
+	)
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if policy.Mode == EnvEncrypt && (sessionKey == nil || sealedKey == nil) {
+		return nil, fmt.Errorf("the encrypt env policy needs a session key sealed for the target")
+	}
+
+	// env_file entries and secrets are only known once the file is parsed;
+	// a stack whose file no longer parses still gets its .env bundled
+	envFiles := []string{filepath.Join(stack.Directory, ".env")}
+	var secretFiles []string
+	if project, err := c.LoadComposeFile(ctx, stack.ConfigPath); err == nil {
+		for _, service := range project.Services {
+			for _, envFile := range service.EnvFiles {
+				envFiles = append(envFiles, envFile.Path)
+			}
+		}
+		for _, secret := range project.Secrets {
+			if secret.File != "" {
+				secretFiles = append(secretFiles, secret.File)
+			}
+		}
+	} else {
+		c.logger.Warn("failed to load compose file, bundling .env only",
+			zap.String("path", stack.ConfigPath),
+			zap.Error(err),
+		)
+	}
 
 	pr, pw := io.Pipe()
 	tw := tar.NewWriter(pw)
 
 	go func() {
-		defer pw.Close()
-		defer tw.Close()
-
-		// Add main compose file
-		if err := addFileToTar(tw, stack.ConfigPath, "docker-compose.yml"); err != nil {
-			c.logger.Error("failed to add compose file to tar", zap.Error(err))
+		err := c.writeComposeBundle(tw, stack, policy, sessionKey, sealedKey, envFiles, secretFiles)
+		if closeErr := tw.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			c.logger.Error("failed to export compose bundle", zap.String("stack", stack.Name), zap.Error(err))
+			pw.CloseWithError(err)
 			return
 		}
+		pw.Close()
+		c.logger.Info("compose bundle exported", zap.String("stack", stack.Name))
+	}()
 
-		// Add override file if exists
-		overridePath := filepath.Join(stack.Directory, "docker-compose.override.yml")
-		if _, err := os.Stat(overridePath); err == nil {
-			addFileToTar(tw, overridePath, "docker-compose.override.yml")
+	return pr, nil
+}
+
+func (c *Client) writeComposeBundle(tw *tar.Writer, stack *ComposeStack, policy EnvPolicy, sessionKey, sealedKey []byte, envFiles, secretFiles []string) error {
+	if policy.Mode == EnvEncrypt {
+		if err := addBytesToTar(tw, BundleKeyFile, sealedKey); err != nil {
+			return err
 		}
+	}
 
-		// Add .env file if exists
-		envPath := filepath.Join(stack.Directory, ".env")
-		if _, err := os.Stat(envPath); err == nil {
-			addFileToTar(tw, envPath, ".env")
+	// Add main compose file
+	if err := addFileToTar(tw, stack.ConfigPath, "docker-compose.yml"); err != nil {
+		return fmt.Errorf("failed to add compose file: %w", err)
+	}
+
+	// Add override file if exists
+	overridePath := filepath.Join(stack.Directory, "docker-compose.override.yml")
+	if _, err := os.Stat(overridePath); err == nil {
+		if err := addFileToTar(tw, overridePath, "docker-compose.override.yml"); err != nil {
+			return fmt.Errorf("failed to add override file: %w", err)
 		}
+	}
 
-		c.logger.Info("compose bundle exported", zap.String("stack", stack.Name))
-	}()
+	added := make(map[string]bool)
+	for _, envPath := range envFiles {
+		name, ok := c.bundleName(stack, envPath)
+		if !ok || added[name] {
+			continue
+		}
+		data, err := os.ReadFile(envPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		added[name] = true
 
-	return pr, nil
+		data, err = policy.ApplyEnvFile(data, sessionKey)
+		if err != nil {
+			return fmt.Errorf("failed to apply env policy to %s: %w", name, err)
+		}
+		if data == nil {
+			continue
+		}
+		if err := addBytesToTar(tw, name, data); err != nil {
+			return err
+		}
+	}
+
+	for _, secretPath := range secretFiles {
+		name, ok := c.bundleName(stack, secretPath)
+		if !ok || added[name] {
+			continue
+		}
+		data, err := os.ReadFile(secretPath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		added[name] = true
+
+		name, data, err = policy.ApplySecretFile(name, data, sessionKey)
+		if err != nil {
+			return fmt.Errorf("failed to apply env policy to %s: %w", name, err)
+		}
+		if data == nil {
+			continue
+		}
+		if err := addBytesToTar(tw, name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bundleName returns a file's path in the bundle, relative to the stack's
+// directory. Files outside it are not bundled, since the target would
+// have nowhere to put them.
+func (c *Client) bundleName(stack *ComposeStack, filePath string) (string, bool) {
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(stack.Directory, filePath)
+	}
+	rel, err := filepath.Rel(stack.Directory, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		c.logger.Warn("file outside the compose directory not bundled", zap.String("path", filePath))
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
 }
 
 // parseEnvFile parses .env file content into a map
@@ -375,3 +563,23 @@ func addFileToTar(tw *tar.Writer, filePath, nameInTar string) error {
 
 	return nil
 }
+
+// addBytesToTar adds in-memory content to a tar archive as a private file
+func addBytesToTar(tw *tar.Writer, nameInTar string, data []byte) error {
+	header := &tar.Header{
+		Name:    nameInTar,
+		Size:    int64(len(data)),
+		Mode:    0600,
+		ModTime: time.Now(),
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write tar header: %w", err)
+	}
+
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write file to tar: %w", err)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/artemis/docker-migrate/internal/observability"
@@ -11,6 +12,7 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
 	"go.uber.org/zap"
 )
 
@@ -27,6 +29,7 @@ type ContainerState struct {
 	State           *types.ContainerState       `json:"state"`
 	Image           string                      `json:"image"`
 	ImageID         string                      `json:"image_id"`
+	Usage           *ResourceUsage              `json:"usage,omitempty"` // Snapshot at export time
 }
 
 // ListContainers returns all containers with full inspect data
@@ -84,6 +87,36 @@ func (c *Client) InspectContainer(ctx context.Context, containerID string) (type
 	return inspect, nil
 }
 
+// ContainerDiffSize returns the size of a container's writable layer, the
+// data a migration carries beyond its image
+func (c *Client) ContainerDiffSize(ctx context.Context, containerID string) (int64, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return 0, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	inspect, _, err := cli.ContainerInspectWithRaw(ctx, containerID, true)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("container_inspect").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_inspect", "error").Inc()
+		return 0, fmt.Errorf("failed to inspect container %s: %w", containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("container_inspect", "success").Inc()
+
+	if inspect.SizeRw == nil {
+		return 0, nil
+	}
+	return *inspect.SizeRw, nil
+}
+
 // ExportContainerState exports the complete state of a container
 // This captures everything needed to recreate the container identically
 func (c *Client) ExportContainerState(ctx context.Context, containerID string) (*ContainerState, error) {
@@ -144,6 +177,7 @@ func (c *Client) ExportContainerState(ctx context.Context, containerID string) (
 		State:           inspect.State,
 		Image:           inspect.Config.Image,
 		ImageID:         inspect.Image,
+		Usage:           c.containerUsage(ctx, inspect),
 	}
 
 	// Validate state completeness
@@ -162,19 +196,22 @@ func (c *Client) ExportContainerState(ctx context.Context, containerID string) (
 	return state, nil
 }
 
-// CreateContainer creates a container from exported state
-func (c *Client) CreateContainer(ctx context.Context, state *ContainerState, newName string) (string, error) {
+// CreateContainer creates a container from exported state. The report lists
+// settings this daemon could not apply; the container is still created
+// without them. A runtime this daemon lacks fails the create with
+// ErrRuntimeUnavailable unless allowDefaultRuntime is set.
+func (c *Client) CreateContainer(ctx context.Context, state *ContainerState, newName string, allowDefaultRuntime bool) (string, *CompatibilityReport, error) {
 	c.mu.RLock()
 	if c.closed {
 		c.mu.RUnlock()
-		return "", fmt.Errorf("client is closed")
+		return "", nil, fmt.Errorf("client is closed")
 	}
 	cli := c.cli
 	c.mu.RUnlock()
 
 	// Validate state before attempting creation
 	if err := validateContainerState(state); err != nil {
-		return "", fmt.Errorf("invalid container state: %w", err)
+		return "", nil, fmt.Errorf("invalid container state: %w", err)
 	}
 
 	// Use provided name or original name
@@ -188,10 +225,32 @@ func (c *Client) CreateContainer(ctx context.Context, state *ContainerState, new
 		zap.String("image", state.Image),
 	)
 
+	report := &CompatibilityReport{Container: name}
+
 	// Clear runtime-specific fields that shouldn't be set on creation
 	config := *state.Config
 	hostConfig := *state.HostConfig
 
+	// Docker may accept settings it cannot apply, so check them before creating
+	info, err := c.daemonInfo(ctx)
+	if err != nil {
+		c.logger.Warn("could not check target capabilities", zap.String("name", name), zap.Error(err))
+	} else {
+		if err := report.checkRuntime(info, &hostConfig, allowDefaultRuntime); err != nil {
+			return "", nil, err
+		}
+		if state.Usage != nil {
+			report.Warnings = append(report.Warnings, resourceLimitWarnings(info, state.Usage.Limits)...)
+		}
+	}
+	if state.Usage != nil && (state.Usage.OOMKilled || state.Usage.RestartCount > 0) {
+		c.logger.Info("recreating container with a history of restarts",
+			zap.String("name", name),
+			zap.Int("restart_count", state.Usage.RestartCount),
+			zap.Bool("oom_killed", state.Usage.OOMKilled),
+		)
+	}
+
 	// Apply mounts to host config
 	hostConfig.Mounts = state.Mounts
 
@@ -214,18 +273,13 @@ func (c *Client) CreateContainer(ctx context.Context, state *ContainerState, new
 			zap.String("name", name),
 			zap.Error(err),
 		)
-		return "", fmt.Errorf("failed to create container: %w", err)
+		return "", nil, fmt.Errorf("failed to create container: %w", err)
 	}
 
 	observability.DockerOperations.WithLabelValues("container_create", "success").Inc()
 
-	// Log any warnings from Docker
-	for _, warning := range resp.Warnings {
-		c.logger.Warn("container creation warning",
-			zap.String("container_id", resp.ID),
-			zap.String("warning", warning),
-		)
-	}
+	report.ContainerID = resp.ID
+	report.Warnings = append(report.Warnings, resp.Warnings...)
 
 	c.logger.Info("container created successfully",
 		zap.String("container_id", resp.ID),
@@ -233,13 +287,28 @@ func (c *Client) CreateContainer(ctx context.Context, state *ContainerState, new
 	)
 
 	// Verify container was created with correct configuration
-	if err := c.verifyContainerCreation(ctx, resp.ID, state); err != nil {
+	if err := c.verifyContainerCreation(ctx, resp.ID, state, &hostConfig, report); err != nil {
 		// Attempt cleanup on verification failure
 		_ = c.RemoveContainer(ctx, resp.ID, true)
-		return "", fmt.Errorf("container verification failed: %w", err)
+		return "", nil, fmt.Errorf("container verification failed: %w", err)
 	}
 
-	return resp.ID, nil
+	for _, field := range report.Skipped {
+		c.logger.Warn("container setting not applied on this host",
+			zap.String("container_id", resp.ID),
+			zap.String("field", field.Field),
+			zap.String("requested", field.Requested),
+			zap.String("reason", field.Reason),
+		)
+	}
+	for _, warning := range report.Warnings {
+		c.logger.Warn("container creation warning",
+			zap.String("container_id", resp.ID),
+			zap.String("warning", warning),
+		)
+	}
+
+	return resp.ID, report, nil
 }
 
 // RemoveContainer removes a container with force option
@@ -295,7 +364,8 @@ func validateContainerState(state *ContainerState) error {
 }
 
 // verifyContainerCreation verifies the created container matches expected state
-func (c *Client) verifyContainerCreation(ctx context.Context, containerID string, expectedState *ContainerState) error {
+// and records host settings that did not read back as requested
+func (c *Client) verifyContainerCreation(ctx context.Context, containerID string, expectedState *ContainerState, requested *container.HostConfig, report *CompatibilityReport) error {
 	inspect, err := c.InspectContainer(ctx, containerID)
 	if err != nil {
 		return err
@@ -310,6 +380,8 @@ func (c *Client) verifyContainerCreation(ctx context.Context, containerID string
 		return fmt.Errorf("mount count mismatch: got %d, expected %d", len(inspect.Mounts), len(expectedState.Mounts))
 	}
 
+	report.compareHostConfig(requested, inspect.HostConfig)
+
 	c.logger.Info("container creation verified",
 		zap.String("container_id", containerID),
 	)
@@ -376,6 +448,121 @@ func (c *Client) StopContainer(ctx context.Context, containerID string, timeout
 	return nil
 }
 
+// PauseContainer freezes all processes in a running container
+func (c *Client) PauseContainer(ctx context.Context, containerID string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	err := cli.ContainerPause(ctx, containerID)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("container_pause").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_pause", "error").Inc()
+		return fmt.Errorf("failed to pause container %s: %w", containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("container_pause", "success").Inc()
+	c.logger.Info("container paused", zap.String("container_id", containerID))
+	return nil
+}
+
+// UnpauseContainer resumes a paused container
+func (c *Client) UnpauseContainer(ctx context.Context, containerID string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	err := cli.ContainerUnpause(ctx, containerID)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("container_unpause").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_unpause", "error").Inc()
+		return fmt.Errorf("failed to unpause container %s: %w", containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("container_unpause", "success").Inc()
+	c.logger.Info("container unpaused", zap.String("container_id", containerID))
+	return nil
+}
+
+// RenameContainer gives a container a new name
+func (c *Client) RenameContainer(ctx context.Context, containerID, newName string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	err := cli.ContainerRename(ctx, containerID, newName)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("container_rename").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_rename", "error").Inc()
+		return fmt.Errorf("failed to rename container %s: %w", containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("container_rename", "success").Inc()
+	c.logger.Info("container renamed",
+		zap.String("container_id", containerID),
+		zap.String("name", newName),
+	)
+	return nil
+}
+
+// UpdateRestartPolicy sets a container's restart policy, e.g. "unless-stopped"
+func (c *Client) UpdateRestartPolicy(ctx context.Context, containerID, name string, maxRetries int) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	_, err := cli.ContainerUpdate(ctx, containerID, container.UpdateConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name:              container.RestartPolicyMode(name),
+			MaximumRetryCount: maxRetries,
+		},
+	})
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("container_update").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_update", "error").Inc()
+		return fmt.Errorf("failed to update restart policy of container %s: %w", containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("container_update", "success").Inc()
+	c.logger.Info("container restart policy updated",
+		zap.String("container_id", containerID),
+		zap.String("restart_policy", name),
+	)
+	return nil
+}
+
 // RestartContainer restarts a container
 func (c *Client) RestartContainer(ctx context.Context, containerID string, timeout *int) error {
 	c.mu.RLock()
@@ -430,3 +617,57 @@ func (c *Client) GetContainerLogs(ctx context.Context, containerID string, tail
 	c.logger.Info("container logs stream opened", zap.String("container_id", containerID), zap.Bool("follow", follow))
 	return reader, nil
 }
+
+// ExecInContainer runs a command inside a running container and returns its combined output.
+// A non-zero exit code is returned as an error.
+func (c *Client) ExecInContainer(ctx context.Context, containerID string, cmd []string) (string, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return "", fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	exec, err := cli.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_exec", "error").Inc()
+		return "", fmt.Errorf("failed to create exec in container %s: %w", containerID, err)
+	}
+
+	hijacked, err := cli.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{})
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_exec", "error").Inc()
+		return "", fmt.Errorf("failed to attach exec in container %s: %w", containerID, err)
+	}
+	defer hijacked.Close()
+
+	var output strings.Builder
+	if _, err := stdcopy.StdCopy(&output, &output, hijacked.Reader); err != nil {
+		observability.DockerOperations.WithLabelValues("container_exec", "error").Inc()
+		return "", fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("container_exec").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_exec", "error").Inc()
+		return "", fmt.Errorf("failed to inspect exec in container %s: %w", containerID, err)
+	}
+
+	if inspect.ExitCode != 0 {
+		observability.DockerOperations.WithLabelValues("container_exec", "error").Inc()
+		return output.String(), fmt.Errorf("command exited with code %d: %s", inspect.ExitCode, strings.TrimSpace(output.String()))
+	}
+
+	observability.DockerOperations.WithLabelValues("container_exec", "success").Inc()
+	return output.String(), nil
+}
@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"go.uber.org/zap"
+)
+
+// DiskUsageCategory summarises one kind of Docker object, like a row of `docker system df`
+type DiskUsageCategory struct {
+	Count       int   `json:"count"`
+	Active      int   `json:"active"`
+	Size        int64 `json:"size"`
+	Reclaimable int64 `json:"reclaimable"`
+}
+
+// DiskUsageSummary is the daemon's disk usage plus free space on its data root
+type DiskUsageSummary struct {
+	Images        DiskUsageCategory `json:"images"`
+	Containers    DiskUsageCategory `json:"containers"`
+	Volumes       DiskUsageCategory `json:"volumes"`
+	BuildCache    DiskUsageCategory `json:"build_cache"`
+	RootDir       string            `json:"root_dir,omitempty"`
+	DiskTotal     int64             `json:"disk_total"`
+	DiskAvailable int64             `json:"disk_available"`
+
+	// Per-object sizes, used for migration estimates rather than reported
+	VolumeSizes    map[string]int64 `json:"-"`
+	ContainerSizes map[string]int64 `json:"-"`
+}
+
+// SystemDF aggregates disk usage by object type. Free space is best-effort:
+// it is zero when the daemon's data root is not visible from this process.
+func (c *Client) SystemDF(ctx context.Context) (*DiskUsageSummary, error) {
+	usage, err := c.DiskUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &DiskUsageSummary{
+		VolumeSizes:    make(map[string]int64),
+		ContainerSizes: make(map[string]int64),
+	}
+
+	summary.Images.Size = usage.LayersSize
+	for _, img := range usage.Images {
+		summary.Images.Count++
+		if img.Containers > 0 {
+			summary.Images.Active++
+			continue
+		}
+		if img.SharedSize >= 0 && img.Size > img.SharedSize {
+			summary.Images.Reclaimable += img.Size - img.SharedSize
+		} else if img.SharedSize < 0 {
+			summary.Images.Reclaimable += img.Size
+		}
+	}
+
+	for _, ctr := range usage.Containers {
+		summary.Containers.Count++
+		summary.Containers.Size += ctr.SizeRw
+		summary.ContainerSizes[ctr.ID] = ctr.SizeRw
+		if ctr.State == "running" {
+			summary.Containers.Active++
+		} else {
+			summary.Containers.Reclaimable += ctr.SizeRw
+		}
+	}
+
+	for _, vol := range usage.Volumes {
+		summary.Volumes.Count++
+		if vol.UsageData == nil {
+			continue
+		}
+		if vol.UsageData.Size > 0 {
+			summary.Volumes.Size += vol.UsageData.Size
+			summary.VolumeSizes[vol.Name] = vol.UsageData.Size
+		}
+		if vol.UsageData.RefCount > 0 {
+			summary.Volumes.Active++
+		} else if vol.UsageData.Size > 0 {
+			summary.Volumes.Reclaimable += vol.UsageData.Size
+		}
+	}
+
+	for _, cache := range usage.BuildCache {
+		summary.BuildCache.Count++
+		if cache.InUse {
+			summary.BuildCache.Active++
+		}
+		if !cache.Shared {
+			summary.BuildCache.Size += cache.Size
+			if !cache.InUse {
+				summary.BuildCache.Reclaimable += cache.Size
+			}
+		}
+	}
+
+	if rootDir, err := c.dockerRootDir(ctx); err != nil {
+		c.logger.Debug("failed to get docker root dir", zap.Error(err))
+	} else {
+		summary.RootDir = rootDir
+		if total, avail, err := DiskSpace(rootDir); err == nil {
+			summary.DiskTotal = total
+			summary.DiskAvailable = avail
+		}
+	}
+
+	return summary, nil
+}
+
+// dockerRootDir returns the daemon's data root (e.g. /var/lib/docker)
+func (c *Client) dockerRootDir(ctx context.Context) (string, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return "", fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	info, err := cli.Info(ctx)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("info").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("info", "error").Inc()
+		return "", fmt.Errorf("failed to get docker info: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("info", "success").Inc()
+	return info.DockerRootDir, nil
+}
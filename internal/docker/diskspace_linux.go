@@ -0,0 +1,17 @@
+//go:build linux
+
+package docker
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// DiskSpace returns total and available bytes on the filesystem holding path
+func DiskSpace(path string) (int64, int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, fmt.Errorf("statfs %s: %w", path, err)
+	}
+	return int64(st.Blocks) * st.Bsize, int64(st.Bavail) * st.Bsize, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package docker
+
+import (
+	"fmt"
+)
+
+// DiskSpace is only supported on Linux
+func DiskSpace(path string) (int64, int64, error) {
+	return 0, 0, fmt.Errorf("disk space reporting is not supported on this platform")
+}
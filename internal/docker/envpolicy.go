@@ -0,0 +1,194 @@
+package docker
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// EnvFileMode decides how .env, env_file and file-based secret files travel
+// in a compose bundle
+type EnvFileMode string
+
+const (
+	EnvInclude EnvFileMode = "include" // Copy as they are
+	EnvExclude EnvFileMode = "exclude" // Leave out; the target supplies its own
+	EnvEncrypt EnvFileMode = "encrypt" // Seal values with the bundle's session key
+	EnvRedact  EnvFileMode = "redact"  // Blank values, keeping the keys
+)
+
+// encryptedValuePrefix marks an env value sealed with a bundle session key
+const encryptedValuePrefix = "enc:v1:"
+
+// EnvPolicy controls how env and secret files are bundled. Keys limits
+// encrypt and redact to env keys matching one of its glob patterns, such as
+// "*_PASSWORD"; without it every value is affected. Secret files have no
+// keys, so they are sealed or left out whole.
+type EnvPolicy struct {
+	Mode EnvFileMode `json:"mode"`
+	Keys []string    `json:"keys,omitempty"`
+}
+
+// Validate rejects unknown modes and malformed key patterns
+func (p EnvPolicy) Validate() error {
+	switch p.Mode {
+	case "", EnvInclude, EnvExclude, EnvEncrypt, EnvRedact:
+	default:
+		return fmt.Errorf("unknown env policy mode %q (expected include, exclude, encrypt or redact)", p.Mode)
+	}
+	for _, pattern := range p.Keys {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid env key pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the policy applies to an env key
+func (p EnvPolicy) matches(key string) bool {
+	if len(p.Keys) == 0 {
+		return true
+	}
+	for _, pattern := range p.Keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// BundleKeyFile holds, in a bundle, its session key sealed for the host
+// it was sent to
+const BundleKeyFile = ".bundle-key"
+
+// NewSessionKey generates a random AES-256 key for sealing one bundle
+func NewSessionKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	return key, nil
+}
+
+// ApplyEnvFile applies the policy to an env file. It returns nil when the
+// file should be left out. Comments, blank lines and key order are kept.
+func (p EnvPolicy) ApplyEnvFile(data, sessionKey []byte) ([]byte, error) {
+	switch p.Mode {
+	case "", EnvInclude:
+		return data, nil
+	case EnvExclude:
+		return nil, nil
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !p.matches(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(key), "export "))) {
+			continue
+		}
+
+		switch p.Mode {
+		case EnvRedact:
+			lines[i] = key + "="
+		case EnvEncrypt:
+			sealed, err := seal(sessionKey, []byte(value))
+			if err != nil {
+				return nil, err
+			}
+			lines[i] = key + "=" + encryptedValuePrefix + sealed
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// ApplySecretFile applies the policy to a secret file, returning its new
+// name and content, or nil when it should be left out. A redacted secret
+// is left out, since it has no keys to keep.
+func (p EnvPolicy) ApplySecretFile(name string, data, sessionKey []byte) (string, []byte, error) {
+	switch p.Mode {
+	case "", EnvInclude:
+		return name, data, nil
+	case EnvEncrypt:
+		sealed, err := seal(sessionKey, data)
+		if err != nil {
+			return "", nil, err
+		}
+		return name + ".enc", []byte(encryptedValuePrefix + sealed + "\n"), nil
+	default:
+		return "", nil, nil
+	}
+}
+
+// DecryptEnvFile opens the values of an env file sealed with sessionKey
+func DecryptEnvFile(data, sessionKey []byte) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !strings.HasPrefix(value, encryptedValuePrefix) {
+			continue
+		}
+		plain, err := unseal(sessionKey, strings.TrimPrefix(value, encryptedValuePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s: %w", strings.TrimSpace(key), err)
+		}
+		lines[i] = key + "=" + string(plain)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// DecryptSecretFile opens a secret file sealed with sessionKey
+func DecryptSecretFile(data, sessionKey []byte) ([]byte, error) {
+	sealed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(sealed, encryptedValuePrefix) {
+		return nil, fmt.Errorf("not an encrypted secret file")
+	}
+	return unseal(sessionKey, strings.TrimPrefix(sealed, encryptedValuePrefix))
+}
+
+// seal encrypts with AES-256-GCM, returning base64 of the nonce and ciphertext
+func seal(key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+func unseal(key []byte, sealed string) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encoding: %w", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong session key or corrupted value")
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,317 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// FileMeta describes one entry of a volume's file index
+type FileMeta struct {
+	Path    string      `json:"path"`
+	Mode    os.FileMode `json:"mode"`
+	Size    int64       `json:"size"`
+	ModTime int64       `json:"mod_time"` // Unix nanoseconds
+	Hash    string      `json:"hash,omitempty"`
+	Dir     bool        `json:"dir,omitempty"`
+	// Ranges holds the SHA-256 of each piece of the file, when it was
+	// indexed with a range size. An empty file has one empty range.
+	Ranges []string `json:"ranges,omitempty"`
+}
+
+// FileIndex maps volume-relative paths to their metadata. Only directories
+// and regular files are indexed, matching what ImportVolume restores.
+type FileIndex map[string]FileMeta
+
+// IndexVolume builds the file index of a volume from its export. Files whose
+// size and mtime match previous keep their hash instead of being hashed
+// again; pass nil to hash everything. A non-zero rangeSize also hashes each
+// file in ranges of that many bytes.
+func (c *Client) IndexVolume(ctx context.Context, volumeName string, previous FileIndex, rangeSize int64) (FileIndex, error) {
+	reader, err := c.ExportVolume(ctx, volumeName)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	index, err := IndexVolumeTar(reader, previous, rangeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index volume %s: %w", volumeName, err)
+	}
+
+	c.logger.Debug("indexed volume",
+		zap.String("volume", volumeName),
+		zap.Int("files", len(index)),
+	)
+	return index, nil
+}
+
+// SampleVolume indexes a volume's files without hashing them, except the
+// given paths, which are hashed afresh. Unhashed files have an empty Hash.
+func (c *Client) SampleVolume(ctx context.Context, volumeName string, paths []string) (FileIndex, error) {
+	reader, err := c.ExportVolume(ctx, volumeName)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	wanted := make(map[string]bool, len(paths))
+	for _, name := range paths {
+		wanted[name] = true
+	}
+	index, err := indexVolumeTar(reader, nil, 0, func(name string) bool { return wanted[name] })
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample volume %s: %w", volumeName, err)
+	}
+	return index, nil
+}
+
+// IndexVolumeTar builds a file index from a volume tar stream, with range
+// hashes if rangeSize is not zero
+func IndexVolumeTar(r io.Reader, previous FileIndex, rangeSize int64) (FileIndex, error) {
+	return indexVolumeTar(r, previous, rangeSize, nil)
+}
+
+// indexVolumeTar builds a file index from a volume tar stream, hashing the
+// files hash accepts, or all of them when it is nil
+func indexVolumeTar(r io.Reader, previous FileIndex, rangeSize int64, hash func(name string) bool) (FileIndex, error) {
+	index := make(FileIndex)
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		name := tarEntryPath(header.Name)
+		if name == "" {
+			continue
+		}
+
+		meta := FileMeta{
+			Path:    name,
+			Mode:    header.FileInfo().Mode().Perm(),
+			ModTime: header.ModTime.UnixNano(),
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			meta.Dir = true
+		case tar.TypeReg:
+			meta.Size = header.Size
+			if hash != nil && !hash(name) {
+				break
+			}
+			if prev, ok := previous[name]; ok && !prev.Dir && prev.Size == meta.Size && prev.ModTime == meta.ModTime &&
+				(rangeSize == 0 || len(prev.Ranges) == rangeCount(meta.Size, rangeSize)) {
+				meta.Hash = prev.Hash
+				meta.Ranges = prev.Ranges
+				break
+			}
+			sum := sha256.New()
+			var dst io.Writer = sum
+			var ranges *rangeHasher
+			if rangeSize > 0 {
+				ranges = &rangeHasher{size: rangeSize}
+				dst = io.MultiWriter(sum, ranges)
+			}
+			if _, err := io.Copy(dst, tr); err != nil {
+				return nil, fmt.Errorf("failed to hash %s: %w", name, err)
+			}
+			meta.Hash = fmt.Sprintf("%x", sum.Sum(nil))
+			if ranges != nil {
+				meta.Ranges = ranges.Sums()
+			}
+		default:
+			continue
+		}
+
+		index[name] = meta
+	}
+
+	return index, nil
+}
+
+// DiffIndex compares a source index against the target's and returns the
+// paths to send and the paths to remove from the target first, both sorted.
+// A path that changed between file and directory is in both lists. Files
+// are compared by size and hash; permission changes alone are not synced,
+// since the import applies them through the umask.
+func DiffIndex(source, target FileIndex) (changed, deleted []string) {
+	for name, src := range source {
+		dst, ok := target[name]
+		switch {
+		case !ok:
+			changed = append(changed, name)
+		case src.Dir != dst.Dir:
+			deleted = append(deleted, name)
+			changed = append(changed, name)
+		case src.Size != dst.Size || src.Hash != dst.Hash:
+			changed = append(changed, name)
+		}
+	}
+	for name := range target {
+		if _, ok := source[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+
+	sort.Strings(changed)
+	sort.Strings(deleted)
+	return changed, deleted
+}
+
+// Digest is a SHA-256 over every path in the index with its hash, in path
+// order, so two copies of a volume with the same content have the same digest
+func (idx FileIndex) Digest() string {
+	paths := make([]string, 0, len(idx))
+	for p := range idx {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, p := range paths {
+		meta := idx[p]
+		kind := "f"
+		if meta.Dir {
+			kind = "d"
+		}
+		fmt.Fprintf(hash, "%s\x00%s\x00%s\n", kind, meta.Path, meta.Hash)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// TarSize estimates the size of a tar holding the given paths, for progress
+// and spool space checks
+func (idx FileIndex) TarSize(paths []string) int64 {
+	size := int64(1024) // End-of-archive blocks
+	for _, name := range paths {
+		size += 512 + (idx[name].Size+511)/512*512
+	}
+	return size
+}
+
+// ExportVolumeFiles exports only the given paths of a volume as a tar stream.
+// The returned reader must be closed by the caller.
+func (c *Client) ExportVolumeFiles(ctx context.Context, volumeName string, paths []string) (io.ReadCloser, error) {
+	source, err := c.ExportVolume(ctx, volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return SelectTarFiles(source, paths), nil
+}
+
+// SelectTarFiles streams the entries of a volume tar, such as a saved
+// export, whose path is one of paths. It closes source when done, and the
+// returned reader must be closed by the caller.
+func SelectTarFiles(source io.ReadCloser, paths []string) io.ReadCloser {
+	wanted := make(map[string]bool, len(paths))
+	for _, name := range paths {
+		wanted[name] = true
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer source.Close()
+		pw.CloseWithError(filterVolumeTar(source, pw, wanted))
+	}()
+	return pr
+}
+
+// filterVolumeTar copies the entries of r whose path is wanted to w
+func filterVolumeTar(r io.Reader, w io.Writer, wanted map[string]bool) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		name := tarEntryPath(header.Name)
+		if !wanted[name] {
+			continue
+		}
+
+		header.Name = name
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := io.Copy(tw, tr); err != nil {
+				return fmt.Errorf("failed to copy %s: %w", name, err)
+			}
+		}
+	}
+
+	return tw.Close()
+}
+
+// RemoveVolumePaths deletes files and directories from a volume. Paths are
+// relative to the volume root; missing paths are ignored.
+func (c *Client) RemoveVolumePaths(ctx context.Context, volumeName string, paths []string) error {
+	for _, name := range paths {
+		if !validVolumePath(name) {
+			return fmt.Errorf("invalid volume path: %q", name)
+		}
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	vol, err := c.InspectVolume(ctx, volumeName)
+	if err != nil {
+		return fmt.Errorf("volume verification failed: %w", err)
+	}
+
+	if IsSharedStorageVolume(vol) || !c.canAccessMountpoint(vol.Mountpoint) {
+		var list strings.Builder
+		for _, name := range paths {
+			list.WriteString(path.Join(helperMountPath, name))
+			list.WriteByte(0)
+		}
+		return c.runHelperWithInput(ctx, volumeName,
+			[]string{"xargs", "-0", "rm", "-rf", "--"}, strings.NewReader(list.String()))
+	}
+
+	for _, name := range paths {
+		if err := os.RemoveAll(filepath.Join(vol.Mountpoint, filepath.FromSlash(name))); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// tarEntryPath normalizes a tar entry name to a volume-relative path, with
+// "" for the volume root. Direct and helper exports differ in their "./".
+func tarEntryPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// validVolumePath reports whether name stays inside the volume root
+func validVolumePath(name string) bool {
+	if name == "" || path.IsAbs(name) {
+		return false
+	}
+	clean := path.Clean(name)
+	return clean != "." && clean != ".." && !strings.HasPrefix(clean, "../")
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"fmt"
+)
+
+// FreezeMode selects how a volume is protected from writers during export
+type FreezeMode string
+
+const (
+	// FreezeNone exports without any protection
+	FreezeNone FreezeMode = ""
+
+	// FreezeFS suspends writes to the volume's filesystem with fsfreeze (FIFREEZE).
+	// Only allowed when the mountpoint is the root of its own filesystem.
+	FreezeFS FreezeMode = "fsfreeze"
+
+	// FreezeFlock takes an exclusive advisory lock on the mountpoint directory.
+	// Only cooperating writers that also flock the directory are held off.
+	FreezeFlock FreezeMode = "flock"
+)
+
+// ParseFreezeMode validates a freeze mode string
+func ParseFreezeMode(s string) (FreezeMode, error) {
+	switch FreezeMode(s) {
+	case FreezeNone, FreezeFS, FreezeFlock:
+		return FreezeMode(s), nil
+	default:
+		return FreezeNone, fmt.Errorf("unknown export freeze mode %q (expected fsfreeze or flock)", s)
+	}
+}
+
+// SetExportFreeze sets the freeze mode used around volume exports
+func (c *Client) SetExportFreeze(mode FreezeMode) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exportFreeze = mode
+}
+
+// freezeMountpoint applies the configured freeze mode and returns a function that releases it
+func (c *Client) freezeMountpoint(mountpoint string) (func() error, error) {
+	c.mu.RLock()
+	mode := c.exportFreeze
+	c.mu.RUnlock()
+
+	switch mode {
+	case FreezeFS:
+		return fsfreeze(mountpoint)
+	case FreezeFlock:
+		return flockDir(mountpoint)
+	default:
+		return func() error { return nil }, nil
+	}
+}
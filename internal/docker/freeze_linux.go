@@ -0,0 +1,66 @@
+//go:build linux
+
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const (
+	ioctlFIFREEZE = 0xC0045877
+	ioctlFITHAW   = 0xC0045878
+)
+
+// fsfreeze freezes the filesystem mounted at mountpoint until the returned thaw is called
+func fsfreeze(mountpoint string) (func() error, error) {
+	// Refuse to freeze a shared filesystem such as / or /var/lib/docker
+	var self, parent syscall.Stat_t
+	if err := syscall.Stat(mountpoint, &self); err != nil {
+		return nil, fmt.Errorf("failed to stat mountpoint: %w", err)
+	}
+	if err := syscall.Stat(filepath.Dir(mountpoint), &parent); err != nil {
+		return nil, fmt.Errorf("failed to stat mountpoint parent: %w", err)
+	}
+	if self.Dev == parent.Dev {
+		return nil, fmt.Errorf("fsfreeze requires %s to be a dedicated filesystem", mountpoint)
+	}
+
+	dir, err := os.Open(mountpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mountpoint: %w", err)
+	}
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(), ioctlFIFREEZE, 0); errno != 0 {
+		dir.Close()
+		return nil, fmt.Errorf("fsfreeze failed: %w", errno)
+	}
+
+	return func() error {
+		defer dir.Close()
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dir.Fd(), ioctlFITHAW, 0); errno != 0 {
+			return fmt.Errorf("fsthaw failed: %w", errno)
+		}
+		return nil
+	}, nil
+}
+
+// flockDir takes an exclusive advisory lock on a directory until the returned unlock is called
+func flockDir(path string) (func() error, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open directory: %w", err)
+	}
+
+	if err := syscall.Flock(int(dir.Fd()), syscall.LOCK_EX); err != nil {
+		dir.Close()
+		return nil, fmt.Errorf("flock failed: %w", err)
+	}
+
+	return func() error {
+		defer dir.Close()
+		return syscall.Flock(int(dir.Fd()), syscall.LOCK_UN)
+	}, nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package docker
+
+import (
+	"fmt"
+)
+
+// fsfreeze is only supported on Linux
+func fsfreeze(mountpoint string) (func() error, error) {
+	return nil, fmt.Errorf("fsfreeze is not supported on this platform")
+}
+
+// flockDir is only supported on Linux
+func flockDir(path string) (func() error, error) {
+	return nil, fmt.Errorf("flock is not supported on this platform")
+}
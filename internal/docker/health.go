@@ -0,0 +1,9 @@
+package docker
+
+// Container health as reported by its Docker healthcheck
+const (
+	HealthNone      = ""         // No healthcheck configured
+	HealthStarting  = "starting" // Within the healthcheck's start period
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
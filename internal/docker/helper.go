@@ -0,0 +1,238 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/pkg/stdcopy"
+	"go.uber.org/zap"
+)
+
+const (
+	// HelperImage is the utility image used to read and write volume data through the daemon
+	HelperImage = "busybox:latest"
+
+	// helperMountPath is where the volume is mounted inside the helper container
+	helperMountPath = "/data"
+
+	// helperLabel marks helper containers so they can be identified and cleaned up
+	helperLabel = "com.docker-migrate.helper"
+)
+
+// canAccessMountpoint reports whether a volume's data can be read directly from this host
+func (c *Client) canAccessMountpoint(mountpoint string) bool {
+	if mountpoint == "" {
+		return false
+	}
+	info, err := os.Stat(mountpoint)
+	return err == nil && info.IsDir()
+}
+
+// ensureHelperImage pulls the helper image if it is not present on the daemon
+func (c *Client) ensureHelperImage(ctx context.Context) error {
+	if _, err := c.InspectImage(ctx, HelperImage); err == nil {
+		return nil
+	}
+	return c.PullImage(ctx, HelperImage)
+}
+
+// createHelperContainer creates a short-lived container with the volume mounted at /data
+func (c *Client) createHelperContainer(ctx context.Context, volumeName string, readOnly bool, cmd []string) (string, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return "", fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	if err := c.ensureHelperImage(ctx); err != nil {
+		return "", fmt.Errorf("failed to prepare helper image: %w", err)
+	}
+
+	config := &container.Config{
+		Image:        HelperImage,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+		AttachStdin:  !readOnly,
+		OpenStdin:    !readOnly,
+		StdinOnce:    !readOnly,
+		Labels:       map[string]string{helperLabel: volumeName},
+	}
+
+	hostConfig := &container.HostConfig{
+		Mounts: []mount.Mount{
+			{
+				Type:     mount.TypeVolume,
+				Source:   volumeName,
+				Target:   helperMountPath,
+				ReadOnly: readOnly,
+			},
+		},
+		NetworkMode: "none",
+	}
+
+	start := time.Now()
+	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("helper_create").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("helper_create", "error").Inc()
+		return "", fmt.Errorf("failed to create helper container: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("helper_create", "success").Inc()
+	return resp.ID, nil
+}
+
+// removeHelperContainer force-removes a helper container, ignoring errors
+func (c *Client) removeHelperContainer(containerID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := c.cli.ContainerRemove(ctx, containerID, container.RemoveOptions{Force: true}); err != nil {
+		c.logger.Warn("failed to remove helper container",
+			zap.String("container_id", containerID),
+			zap.Error(err),
+		)
+	}
+}
+
+// waitHelperContainer waits for a helper container to exit and checks its exit code
+func (c *Client) waitHelperContainer(ctx context.Context, containerID string, stderr *strings.Builder) error {
+	statusCh, errCh := c.cli.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed waiting for helper container: %w", err)
+	case status := <-statusCh:
+		if status.StatusCode != 0 {
+			return fmt.Errorf("helper container exited with code %d: %s", status.StatusCode, strings.TrimSpace(stderr.String()))
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// ExportVolumeViaHelper streams a volume as a tar archive through a helper container.
+// Works for any volume driver and for remote daemons where the mountpoint is not local.
+// The returned reader must be closed by the caller
+func (c *Client) ExportVolumeViaHelper(ctx context.Context, volumeName string) (io.ReadCloser, error) {
+	c.logger.Info("exporting volume via helper container", zap.String("volume", volumeName))
+
+	containerID, err := c.createHelperContainer(ctx, volumeName, true,
+		[]string{"tar", "-C", helperMountPath, "-cf", "-", "."})
+	if err != nil {
+		return nil, err
+	}
+
+	hijacked, err := c.cli.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stream: true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		c.removeHelperContainer(containerID)
+		return nil, fmt.Errorf("failed to attach to helper container: %w", err)
+	}
+
+	if err := c.cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		hijacked.Close()
+		c.removeHelperContainer(containerID)
+		return nil, fmt.Errorf("failed to start helper container: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer hijacked.Close()
+		defer c.removeHelperContainer(containerID)
+
+		// Demultiplex the attach stream: stdout is the tar, stderr is diagnostics
+		var stderr strings.Builder
+		if _, err := stdcopy.StdCopy(pw, &stderr, hijacked.Reader); err != nil {
+			pw.CloseWithError(fmt.Errorf("failed to read helper output: %w", err))
+			return
+		}
+
+		if err := c.waitHelperContainer(ctx, containerID, &stderr); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		pw.Close()
+	}()
+
+	return &volumeReader{
+		ReadCloser: c.throttleReadCloser(ctx, pr),
+		volumeName: volumeName,
+		logger:     c.logger,
+		startTime:  time.Now(),
+	}, nil
+}
+
+// ImportVolumeViaHelper extracts a tar stream into a volume through a helper container
+func (c *Client) ImportVolumeViaHelper(ctx context.Context, volumeName string, reader io.Reader) error {
+	c.logger.Info("importing volume via helper container", zap.String("volume", volumeName))
+
+	if err := c.runHelperWithInput(ctx, volumeName,
+		[]string{"tar", "-C", helperMountPath, "-xf", "-"}, reader); err != nil {
+		return err
+	}
+
+	c.logger.Info("volume imported via helper container", zap.String("volume", volumeName))
+	return nil
+}
+
+// runHelperWithInput runs cmd in a helper container with the volume mounted
+// writable at /data, streaming reader to its stdin
+func (c *Client) runHelperWithInput(ctx context.Context, volumeName string, cmd []string, reader io.Reader) error {
+	containerID, err := c.createHelperContainer(ctx, volumeName, false, cmd)
+	if err != nil {
+		return err
+	}
+	defer c.removeHelperContainer(containerID)
+
+	hijacked, err := c.cli.ContainerAttach(ctx, containerID, container.AttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attach to helper container: %w", err)
+	}
+	defer hijacked.Close()
+
+	if err := c.cli.ContainerStart(ctx, containerID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start helper container: %w", err)
+	}
+
+	// Collect stderr for error reporting while stdin is streamed
+	var stderr strings.Builder
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		stdcopy.StdCopy(io.Discard, &stderr, hijacked.Reader)
+	}()
+
+	if _, err := io.Copy(hijacked.Conn, reader); err != nil {
+		return fmt.Errorf("failed to stream volume data to helper: %w", err)
+	}
+	if err := hijacked.CloseWrite(); err != nil {
+		return fmt.Errorf("failed to close helper stdin: %w", err)
+	}
+
+	<-outputDone
+	return c.waitHelperContainer(ctx, containerID, &stderr)
+}
@@ -155,7 +155,7 @@ func (c *Client) ExportImage(ctx context.Context, imageID string) (io.ReadCloser
 
 	// Wrap reader to track metrics on close
 	return &metricReader{
-		ReadCloser: reader,
+		ReadCloser: c.throttleReadCloser(ctx, reader),
 		imageID:    imageID,
 		logger:     c.logger,
 	}, nil
@@ -193,6 +193,11 @@ func (c *Client) ImportImage(ctx context.Context, reader io.Reader) error {
 		c.logger.Warn("failed to read image load response", zap.Error(err))
 	} else {
 		c.logger.Info("image import response", zap.ByteString("output", output))
+		// The daemon reports a failed load, such as a missing layer, in
+		// the response body rather than the status code
+		if err := streamError(output); err != nil {
+			return fmt.Errorf("failed to import image: %w", err)
+		}
 	}
 
 	c.logger.Info("image imported successfully")
@@ -201,6 +206,12 @@ func (c *Client) ImportImage(ctx context.Context, reader io.Reader) error {
 
 // PullImage pulls an image from a registry
 func (c *Client) PullImage(ctx context.Context, refStr string) error {
+	return c.PullImageWithAuth(ctx, refStr, "")
+}
+
+// PullImageWithAuth pulls an image from a registry needing credentials; auth
+// comes from EncodeRegistryAuth
+func (c *Client) PullImageWithAuth(ctx context.Context, refStr, auth string) error {
 	c.mu.RLock()
 	if c.closed {
 		c.mu.RUnlock()
@@ -212,7 +223,7 @@ func (c *Client) PullImage(ctx context.Context, refStr string) error {
 	c.logger.Info("pulling image", zap.String("ref", refStr))
 
 	start := time.Now()
-	reader, err := cli.ImagePull(ctx, refStr, types.ImagePullOptions{})
+	reader, err := cli.ImagePull(ctx, refStr, types.ImagePullOptions{RegistryAuth: auth})
 	duration := time.Since(start)
 
 	observability.DockerOperationDuration.WithLabelValues("image_pull").Observe(duration.Seconds())
@@ -225,10 +236,15 @@ func (c *Client) PullImage(ctx context.Context, refStr string) error {
 
 	observability.DockerOperations.WithLabelValues("image_pull", "success").Inc()
 
-	// Read pull output to ensure completion
-	if _, err := io.Copy(io.Discard, reader); err != nil {
+	// Read pull output to ensure completion; failures such as a refused
+	// login only show up in it
+	output, err := io.ReadAll(reader)
+	if err != nil {
 		return fmt.Errorf("failed to read pull output: %w", err)
 	}
+	if err := streamError(output); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", refStr, err)
+	}
 
 	c.logger.Info("image pulled successfully", zap.String("ref", refStr))
 	return nil
@@ -0,0 +1,30 @@
+//go:build linux
+
+package docker
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// lowerIOPriority moves the calling goroutine's OS thread into the idle I/O class,
+// so its disk reads only proceed when no other process needs the disk.
+// The goroutine stays locked to the thread; when it exits the thread is discarded,
+// so the lowered priority never leaks to unrelated goroutines.
+func lowerIOPriority() error {
+	runtime.LockOSThread()
+
+	// who=0 selects the calling thread
+	prio := uintptr(ioprioClassIdle << ioprioClassShift)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, prio); errno != 0 {
+		return fmt.Errorf("ioprio_set failed: %w", errno)
+	}
+	return nil
+}
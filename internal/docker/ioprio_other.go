@@ -0,0 +1,12 @@
+//go:build !linux
+
+package docker
+
+import (
+	"fmt"
+)
+
+// lowerIOPriority is only supported on Linux
+func lowerIOPriority() error {
+	return fmt.Errorf("I/O priority is not supported on this platform")
+}
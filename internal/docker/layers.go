@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SharedLayers returns the longest prefix of layers, given as diff IDs base
+// layer first, that a local image already has. Docker identifies a layer by
+// its whole chain, so a matching diff ID above a layer that differs does not
+// count.
+func (c *Client) SharedLayers(ctx context.Context, layers []string) ([]string, error) {
+	if len(layers) == 0 {
+		return nil, nil
+	}
+
+	images, err := c.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	shared := 0
+	for _, img := range images {
+		if shared == len(layers) {
+			break
+		}
+		inspect, err := c.InspectImage(ctx, img.ID)
+		if err != nil {
+			continue
+		}
+		if n := commonPrefix(inspect.RootFS.Layers, layers); n > shared {
+			shared = n
+		}
+	}
+
+	return layers[:shared], nil
+}
+
+// commonPrefix returns how many leading layers a and b have in common
+func commonPrefix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// streamError returns the first error in a docker load, pull or push
+// response, which is a stream of JSON messages
+func streamError(output []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var msg struct {
+			Error       string `json:"error"`
+			ErrorDetail *struct {
+				Message string `json:"message"`
+			} `json:"errorDetail"`
+		}
+		// Stop at the end, or at text that is not JSON from an old daemon
+		if err := dec.Decode(&msg); err != nil {
+			return nil
+		}
+		if msg.ErrorDetail != nil && msg.ErrorDetail.Message != "" {
+			return errors.New(msg.ErrorDetail.Message)
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+	}
+}
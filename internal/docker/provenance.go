@@ -0,0 +1,53 @@
+package docker
+
+import "time"
+
+// Labels a migration puts on the resources it recreates, so they can be told
+// apart from the target's own and traced back to where they came from
+const (
+	LabelMigratedSource       = "docker-migrate.source"        // Host or worker the resource came from
+	LabelMigratedJob          = "docker-migrate.job"           // Migration job or bundle ID
+	LabelMigratedOriginalName = "docker-migrate.original-name" // Name on the source
+	LabelMigratedAt           = "docker-migrate.migrated-at"   // RFC 3339
+)
+
+// Provenance is where a migrated resource came from
+type Provenance struct {
+	Source       string    `json:"source"`
+	JobID        string    `json:"job_id"`
+	OriginalName string    `json:"original_name,omitempty"`
+	MigratedAt   time.Time `json:"migrated_at"`
+}
+
+// WithLabels returns a copy of labels carrying the provenance. A resource
+// migrated again keeps only its latest hop.
+func (p Provenance) WithLabels(labels map[string]string) map[string]string {
+	out := make(map[string]string, len(labels)+4)
+	for k, v := range labels {
+		out[k] = v
+	}
+	out[LabelMigratedSource] = p.Source
+	out[LabelMigratedJob] = p.JobID
+	out[LabelMigratedAt] = p.MigratedAt.UTC().Format(time.RFC3339)
+	delete(out, LabelMigratedOriginalName)
+	if p.OriginalName != "" {
+		out[LabelMigratedOriginalName] = p.OriginalName
+	}
+	return out
+}
+
+// ProvenanceFromLabels reads a resource's provenance, or returns nil for a
+// resource no migration created
+func ProvenanceFromLabels(labels map[string]string) *Provenance {
+	source, ok := labels[LabelMigratedSource]
+	if !ok {
+		return nil
+	}
+	p := &Provenance{
+		Source:       source,
+		JobID:        labels[LabelMigratedJob],
+		OriginalName: labels[LabelMigratedOriginalName],
+	}
+	p.MigratedAt, _ = time.Parse(time.RFC3339, labels[LabelMigratedAt])
+	return p
+}
@@ -0,0 +1,242 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"go.uber.org/zap"
+)
+
+// PruneReport describes what a prune removed or, in dry-run mode, would remove
+type PruneReport struct {
+	Deleted        []string `json:"deleted"`
+	SpaceReclaimed uint64   `json:"space_reclaimed"`
+	DryRun         bool     `json:"dry_run"`
+}
+
+// DiskUsage returns the daemon's disk usage for the given object types (all if none)
+func (c *Client) DiskUsage(ctx context.Context, objects ...types.DiskUsageObject) (types.DiskUsage, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return types.DiskUsage{}, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	usage, err := cli.DiskUsage(ctx, types.DiskUsageOptions{Types: objects})
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("disk_usage").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("disk_usage", "error").Inc()
+		return types.DiskUsage{}, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("disk_usage", "success").Inc()
+	return usage, nil
+}
+
+// PruneImages removes dangling images, or every image without containers when all is set
+func (c *Client) PruneImages(ctx context.Context, all, dryRun bool) (*PruneReport, error) {
+	if dryRun {
+		usage, err := c.DiskUsage(ctx, types.ImageObject)
+		if err != nil {
+			return nil, err
+		}
+
+		report := &PruneReport{Deleted: make([]string, 0), DryRun: true}
+		for _, img := range usage.Images {
+			dangling := len(img.RepoTags) == 0 || (len(img.RepoTags) == 1 && img.RepoTags[0] == "<none>:<none>")
+			if img.Containers > 0 || (!all && !dangling) {
+				continue
+			}
+			report.Deleted = append(report.Deleted, img.ID)
+			if reclaim := img.Size - img.SharedSize; img.SharedSize >= 0 && reclaim > 0 {
+				report.SpaceReclaimed += uint64(reclaim)
+			} else if img.SharedSize < 0 {
+				report.SpaceReclaimed += uint64(img.Size)
+			}
+		}
+		return report, nil
+	}
+
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return nil, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	dangling := "true"
+	if all {
+		dangling = "false"
+	}
+
+	start := time.Now()
+	result, err := cli.ImagesPrune(ctx, filters.NewArgs(filters.Arg("dangling", dangling)))
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("image_prune").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("image_prune", "error").Inc()
+		return nil, fmt.Errorf("failed to prune images: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("image_prune", "success").Inc()
+
+	report := &PruneReport{Deleted: make([]string, 0, len(result.ImagesDeleted)), SpaceReclaimed: result.SpaceReclaimed}
+	for _, deleted := range result.ImagesDeleted {
+		if deleted.Deleted != "" {
+			report.Deleted = append(report.Deleted, deleted.Deleted)
+		}
+	}
+
+	c.logger.Info("images pruned",
+		zap.Int("deleted", len(report.Deleted)),
+		zap.Uint64("space_reclaimed", report.SpaceReclaimed),
+	)
+	return report, nil
+}
+
+// PruneVolumes removes volumes no container references. Docker only prunes
+// anonymous volumes unless all is set, in which case named volumes go too.
+func (c *Client) PruneVolumes(ctx context.Context, all, dryRun bool) (*PruneReport, error) {
+	if dryRun {
+		usage, err := c.DiskUsage(ctx, types.VolumeObject)
+		if err != nil {
+			return nil, err
+		}
+
+		report := &PruneReport{Deleted: make([]string, 0), DryRun: true}
+		for _, vol := range usage.Volumes {
+			if vol.UsageData == nil || vol.UsageData.RefCount != 0 {
+				continue
+			}
+			if !all && !isAnonymousVolume(vol.Name, vol.Labels) {
+				continue
+			}
+			report.Deleted = append(report.Deleted, vol.Name)
+			if vol.UsageData.Size > 0 {
+				report.SpaceReclaimed += uint64(vol.UsageData.Size)
+			}
+		}
+		return report, nil
+	}
+
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return nil, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	args := filters.NewArgs()
+	if all {
+		args.Add("all", "true")
+	}
+
+	start := time.Now()
+	result, err := cli.VolumesPrune(ctx, args)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("volume_prune").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("volume_prune", "error").Inc()
+		return nil, fmt.Errorf("failed to prune volumes: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("volume_prune", "success").Inc()
+
+	report := &PruneReport{Deleted: result.VolumesDeleted, SpaceReclaimed: result.SpaceReclaimed}
+	if report.Deleted == nil {
+		report.Deleted = make([]string, 0)
+	}
+
+	c.logger.Info("volumes pruned",
+		zap.Int("deleted", len(report.Deleted)),
+		zap.Uint64("space_reclaimed", report.SpaceReclaimed),
+	)
+	return report, nil
+}
+
+// PruneBuildCache removes unused build cache; all also drops shared and internal records
+func (c *Client) PruneBuildCache(ctx context.Context, all, dryRun bool) (*PruneReport, error) {
+	if dryRun {
+		usage, err := c.DiskUsage(ctx, types.BuildCacheObject)
+		if err != nil {
+			return nil, err
+		}
+
+		report := &PruneReport{Deleted: make([]string, 0), DryRun: true}
+		for _, cache := range usage.BuildCache {
+			if cache.InUse || (!all && cache.Shared) {
+				continue
+			}
+			report.Deleted = append(report.Deleted, cache.ID)
+			if cache.Size > 0 {
+				report.SpaceReclaimed += uint64(cache.Size)
+			}
+		}
+		return report, nil
+	}
+
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return nil, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	result, err := cli.BuildCachePrune(ctx, types.BuildCachePruneOptions{All: all})
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("build_cache_prune").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("build_cache_prune", "error").Inc()
+		return nil, fmt.Errorf("failed to prune build cache: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("build_cache_prune", "success").Inc()
+
+	report := &PruneReport{Deleted: result.CachesDeleted, SpaceReclaimed: result.SpaceReclaimed}
+	if report.Deleted == nil {
+		report.Deleted = make([]string, 0)
+	}
+
+	c.logger.Info("build cache pruned",
+		zap.Int("deleted", len(report.Deleted)),
+		zap.Uint64("space_reclaimed", report.SpaceReclaimed),
+	)
+	return report, nil
+}
+
+// isAnonymousVolume reports whether Docker created the volume without a name
+func isAnonymousVolume(name string, labels map[string]string) bool {
+	if _, ok := labels["com.docker.volume.anonymous"]; ok {
+		return true
+	}
+	return len(name) == 64 && isHex(name)
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
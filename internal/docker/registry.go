@@ -0,0 +1,130 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/registry"
+	"go.uber.org/zap"
+)
+
+// EncodeRegistryAuth encodes registry credentials the way the Docker API
+// expects them. Empty credentials still encode, as push requires the header.
+func EncodeRegistryAuth(server, username, password string) (string, error) {
+	auth, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		ServerAddress: server,
+		Username:      username,
+		Password:      password,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode registry credentials: %w", err)
+	}
+	return auth, nil
+}
+
+// IntermediaryReference names the copy of an image pushed to an intermediary
+// registry such as "registry.internal:5000/migrate". The repository keeps the
+// image's own path without its registry host, and its tag if it has one;
+// untagged images are named after their ID.
+func IntermediaryReference(registryAddr, reference, imageID string) string {
+	registryAddr = strings.TrimSuffix(registryAddr, "/")
+	id := strings.TrimPrefix(imageID, "sha256:")
+	if len(id) > 12 {
+		id = id[:12]
+	}
+
+	if strings.HasPrefix(reference, "sha256:") {
+		reference = ""
+	}
+	repo, tag := splitImageRef(reference)
+	if first, rest, ok := strings.Cut(repo, "/"); ok &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+		repo = rest
+	}
+	if repo == "" {
+		return fmt.Sprintf("%s/image-%s:latest", registryAddr, id)
+	}
+	// A digest cannot be pushed to as a tag
+	if tag, ok := strings.CutPrefix(tag, ":"); ok {
+		return fmt.Sprintf("%s/%s:%s", registryAddr, repo, tag)
+	}
+	return fmt.Sprintf("%s/%s:%s", registryAddr, repo, id)
+}
+
+// TagImage adds the reference target to the image source
+func (c *Client) TagImage(ctx context.Context, source, target string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	if err := cli.ImageTag(ctx, source, target); err != nil {
+		observability.DockerOperations.WithLabelValues("image_tag", "error").Inc()
+		return fmt.Errorf("failed to tag image %s as %s: %w", source, target, err)
+	}
+	observability.DockerOperations.WithLabelValues("image_tag", "success").Inc()
+	return nil
+}
+
+// UntagImage removes a reference. Only call it on images that keep another
+// tag: removing an image's last tag removes the image.
+func (c *Client) UntagImage(ctx context.Context, reference string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	if _, err := cli.ImageRemove(ctx, reference, types.ImageRemoveOptions{}); err != nil {
+		return fmt.Errorf("failed to untag %s: %w", reference, err)
+	}
+	return nil
+}
+
+// PushImage pushes a tagged image to its registry. auth comes from
+// EncodeRegistryAuth.
+func (c *Client) PushImage(ctx context.Context, reference, auth string) error {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	c.logger.Info("pushing image", zap.String("ref", reference))
+
+	start := time.Now()
+	reader, err := cli.ImagePush(ctx, reference, types.ImagePushOptions{RegistryAuth: auth})
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("image_push", "error").Inc()
+		return fmt.Errorf("failed to push image %s: %w", reference, err)
+	}
+	defer reader.Close()
+
+	// Push failures, such as a refused login, only show up in the output
+	output, err := io.ReadAll(reader)
+	observability.DockerOperationDuration.WithLabelValues("image_push").Observe(time.Since(start).Seconds())
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("image_push", "error").Inc()
+		return fmt.Errorf("failed to read push output: %w", err)
+	}
+	if err := streamError(output); err != nil {
+		observability.DockerOperations.WithLabelValues("image_push", "error").Inc()
+		return fmt.Errorf("failed to push image %s: %w", reference, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("image_push", "success").Inc()
+	c.logger.Info("image pushed", zap.String("ref", reference))
+	return nil
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ImageRewriteRule rewrites image references when registries differ between
+// sites. From matches a reference as written, e.g. "registry.old.local/*";
+// a trailing "*" matches by prefix and carries the rest over to To. Without
+// one, From matches a repository and its tag or digest is kept.
+type ImageRewriteRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ImageRewriteRules are tried in order; the first matching rule applies
+type ImageRewriteRules []ImageRewriteRule
+
+// ImageRewrite records one reference that was rewritten
+type ImageRewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Validate checks each rule has a source and target and uses "*" only as a
+// trailing wildcard on both sides
+func (rules ImageRewriteRules) Validate() error {
+	for i, rule := range rules {
+		if rule.From == "" || rule.To == "" {
+			return fmt.Errorf("image rewrite rule %d needs both from and to", i+1)
+		}
+		fromWild := strings.HasSuffix(rule.From, "*")
+		toWild := strings.HasSuffix(rule.To, "*")
+		if strings.Contains(strings.TrimSuffix(rule.From, "*"), "*") ||
+			strings.Contains(strings.TrimSuffix(rule.To, "*"), "*") {
+			return fmt.Errorf("image rewrite rule %q: \"*\" is only allowed at the end", rule.From)
+		}
+		if toWild && !fromWild {
+			return fmt.Errorf("image rewrite rule %q: to ends in \"*\" but from does not", rule.From)
+		}
+	}
+	return nil
+}
+
+// Rewrite returns the reference the first matching rule gives, and whether
+// any rule matched
+func (rules ImageRewriteRules) Rewrite(ref string) (string, bool) {
+	for _, rule := range rules {
+		if prefix, ok := strings.CutSuffix(rule.From, "*"); ok {
+			if !strings.HasPrefix(ref, prefix) {
+				continue
+			}
+			if to, ok := strings.CutSuffix(rule.To, "*"); ok {
+				return to + strings.TrimPrefix(ref, prefix), true
+			}
+			return rule.To, true
+		}
+
+		if ref == rule.From {
+			return rule.To, true
+		}
+		if repo, suffix := splitImageRef(ref); repo == rule.From {
+			// Keep the tag or digest unless the rule names its own
+			if to, _ := splitImageRef(rule.To); to == rule.To {
+				return rule.To + suffix, true
+			}
+			return rule.To, true
+		}
+	}
+	return ref, false
+}
+
+// splitImageRef splits a reference into its repository and its ":tag" or
+// "@digest" suffix
+func splitImageRef(ref string) (string, string) {
+	if i := strings.IndexByte(ref, '@'); i >= 0 {
+		return ref[:i], ref[i:]
+	}
+	// A colon before the last slash is a registry port, not a tag
+	if i := strings.LastIndexByte(ref, ':'); i > strings.LastIndexByte(ref, '/') {
+		return ref[:i], ref[i:]
+	}
+	return ref, ""
+}
+
+// composeImageLine matches an "image:" key in a compose file, capturing the
+// indentation and key, any quote, and the reference
+var composeImageLine = regexp.MustCompile(`^(\s*image:\s*)(["']?)([^"'\s#]+)(["']?)(.*)$`)
+
+// RewriteComposeFile applies rules to the "image:" lines of a compose file.
+// It edits the text in place, so comments and formatting survive, and does
+// not interpolate variables: a reference such as "${REGISTRY}/app" only
+// matches a rule written the same way.
+func RewriteComposeFile(data []byte, rules ImageRewriteRules) ([]byte, []ImageRewrite) {
+	var rewrites []ImageRewrite
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		m := composeImageLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if to, ok := rules.Rewrite(m[3]); ok && to != m[3] {
+			rewrites = append(rewrites, ImageRewrite{From: m[3], To: to})
+			lines[i] = m[1] + m[2] + to + m[4] + m[5]
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n")), rewrites
+}
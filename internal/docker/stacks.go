@@ -0,0 +1,80 @@
+package docker
+
+import (
+	"sort"
+	"strings"
+)
+
+// StackResources names the resources of one compose project or Swarm stack,
+// so a whole stack can be selected at once
+type StackResources struct {
+	Name       string   `json:"name"`
+	ManagedBy  string   `json:"managed_by"`
+	Containers []string `json:"containers,omitempty"` // IDs
+	Volumes    []string `json:"volumes,omitempty"`    // Names
+	Networks   []string `json:"networks,omitempty"`   // IDs
+}
+
+// StackIndex groups resources under the stack their labels name
+type StackIndex struct {
+	stacks map[string]*StackResources
+}
+
+// NewStackIndex returns an empty index
+func NewStackIndex() *StackIndex {
+	return &StackIndex{stacks: make(map[string]*StackResources)}
+}
+
+func (x *StackIndex) stack(labels map[string]string) *StackResources {
+	name := StackName(labels)
+	if name == "" {
+		return nil
+	}
+	s, ok := x.stacks[name]
+	if !ok {
+		s = &StackResources{Name: name, ManagedBy: StackManager(labels)}
+		x.stacks[name] = s
+	}
+	return s
+}
+
+// AddContainer records a container under its stack, if it has one
+func (x *StackIndex) AddContainer(id string, labels map[string]string) {
+	if s := x.stack(labels); s != nil {
+		s.Containers = append(s.Containers, id)
+	}
+}
+
+// AddVolume records a volume under its stack, if it has one
+func (x *StackIndex) AddVolume(name string, labels map[string]string) {
+	if s := x.stack(labels); s != nil {
+		s.Volumes = append(s.Volumes, name)
+	}
+}
+
+// AddNetwork records a network under its stack. Without labels, as workers
+// report networks, a name such as "shop_default" places it in a stack
+// already known from its containers or volumes, so add networks last.
+func (x *StackIndex) AddNetwork(id, name string, labels map[string]string) {
+	if labels != nil {
+		if s := x.stack(labels); s != nil {
+			s.Networks = append(s.Networks, id)
+		}
+		return
+	}
+	if i := strings.LastIndex(name, "_"); i > 0 {
+		if s, ok := x.stacks[name[:i]]; ok {
+			s.Networks = append(s.Networks, id)
+		}
+	}
+}
+
+// List returns the stacks sorted by name
+func (x *StackIndex) List() []*StackResources {
+	list := make([]*StackResources, 0, len(x.stacks))
+	for _, s := range x.stacks {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].Name < list[k].Name })
+	return list
+}
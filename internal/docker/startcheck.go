@@ -0,0 +1,219 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/docker/docker/api/types"
+)
+
+// StartCheck is what a container needs free on this host to start: its
+// name, the host ports it publishes and the volumes and paths it mounts
+type StartCheck struct {
+	Name      string
+	Ports     []HostPort
+	Volumes   []string // Named volumes
+	BindPaths []string // Host paths
+
+	// SharedWith names containers that may mount the same volumes, such as
+	// others moved by the same migration
+	SharedWith []string
+}
+
+// HostPort is a port published on the host; an empty IP means all addresses
+type HostPort struct {
+	IP       string
+	Port     int
+	Protocol string // tcp or udp; empty means tcp
+}
+
+// StartConflict is one thing that would stop a container starting
+type StartConflict struct {
+	Kind     string `json:"kind"` // name, port, volume or bind
+	Resource string `json:"resource"`
+	Detail   string `json:"detail"`
+}
+
+// Kinds of start conflict
+const (
+	ConflictName   = "name"
+	ConflictPort   = "port"
+	ConflictVolume = "volume"
+	ConflictBind   = "bind"
+)
+
+// CheckStartConflicts reports what is in the way of starting the container
+// described by check: another container with its name, a host port already
+// published by a running container or held by another process, a named
+// volume mounted by a running container outside SharedWith, or a bind-mount source missing from
+// this host. An empty result means it can start.
+func (c *Client) CheckStartConflicts(ctx context.Context, check StartCheck) ([]StartConflict, error) {
+	containers, err := c.ListContainers(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []StartConflict
+	if check.Name != "" {
+		for _, ctr := range containers {
+			if hasContainerName(ctr, check.Name) {
+				conflicts = append(conflicts, StartConflict{
+					Kind:     ConflictName,
+					Resource: check.Name,
+					Detail:   fmt.Sprintf("a container named %s already exists (%s, %s)", check.Name, shortID(ctr.ID), ctr.State),
+				})
+				break
+			}
+		}
+	}
+
+	for _, port := range check.Ports {
+		if conflict, ok := portConflict(containers, port); ok {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	for _, name := range check.Volumes {
+		for _, ctr := range containers {
+			if ctr.State != "running" || !mountsVolume(ctr, name) || sharesWith(ctr, check.SharedWith) {
+				continue
+			}
+			conflicts = append(conflicts, StartConflict{
+				Kind:     ConflictVolume,
+				Resource: name,
+				Detail:   fmt.Sprintf("volume %s is mounted by running container %s", name, containerName(ctr)),
+			})
+			break
+		}
+	}
+
+	for _, path := range check.BindPaths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			conflicts = append(conflicts, StartConflict{
+				Kind:     ConflictBind,
+				Resource: path,
+				Detail:   fmt.Sprintf("bind-mount source %s does not exist on this host", path),
+			})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// portConflict reports whether a host port is published by a running
+// container or, failing that, cannot be bound here. The bind test only sees
+// other processes when this daemon shares the host's network namespace.
+func portConflict(containers []types.Container, port HostPort) (StartConflict, bool) {
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	resource := net.JoinHostPort(port.IP, strconv.Itoa(port.Port)) + "/" + protocol
+
+	for _, ctr := range containers {
+		if ctr.State != "running" {
+			continue
+		}
+		for _, p := range ctr.Ports {
+			if int(p.PublicPort) != port.Port || !strings.EqualFold(p.Type, protocol) || !addressesOverlap(p.IP, port.IP) {
+				continue
+			}
+			return StartConflict{
+				Kind:     ConflictPort,
+				Resource: resource,
+				Detail:   fmt.Sprintf("port %s is published by running container %s", resource, containerName(ctr)),
+			}, true
+		}
+	}
+
+	if portInUse(port.IP, port.Port, protocol) {
+		return StartConflict{
+			Kind:     ConflictPort,
+			Resource: resource,
+			Detail:   fmt.Sprintf("port %s is in use by another process", resource),
+		}, true
+	}
+	return StartConflict{}, false
+}
+
+// portInUse tries to bind a port and reports whether it is already taken
+func portInUse(ip string, port int, protocol string) bool {
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	var err error
+	if protocol == "udp" {
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket("udp", addr); err == nil {
+			conn.Close()
+		}
+	} else {
+		var l net.Listener
+		if l, err = net.Listen("tcp", addr); err == nil {
+			l.Close()
+		}
+	}
+	return errors.Is(err, syscall.EADDRINUSE)
+}
+
+// addressesOverlap reports whether two host IPs a port is published on
+// clash; an empty or unspecified address covers every other one
+func addressesOverlap(a, b string) bool {
+	if isWildcard(a) || isWildcard(b) {
+		return true
+	}
+	return net.ParseIP(a).Equal(net.ParseIP(b))
+}
+
+func isWildcard(ip string) bool {
+	if ip == "" {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.IsUnspecified()
+}
+
+func hasContainerName(ctr types.Container, name string) bool {
+	for _, n := range ctr.Names {
+		if strings.TrimPrefix(n, "/") == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sharesWith(ctr types.Container, names []string) bool {
+	for _, name := range names {
+		if hasContainerName(ctr, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func mountsVolume(ctr types.Container, name string) bool {
+	for _, m := range ctr.Mounts {
+		if m.Type == "volume" && m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containerName(ctr types.Container) string {
+	if len(ctr.Names) > 0 {
+		return strings.TrimPrefix(ctr.Names[0], "/")
+	}
+	return shortID(ctr.ID)
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
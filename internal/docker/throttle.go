@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by all exports on a client, so concurrent
+// exports together stay under the configured disk read rate
+type RateLimiter struct {
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// NewRateLimiter creates a limiter allowing bytesPerSec with a one-second burst
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(bytesPerSec),
+		burst:  float64(bytesPerSec),
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// WaitN blocks until n bytes may be read
+func (rl *RateLimiter) WaitN(ctx context.Context, n int) error {
+	rl.mu.Lock()
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.burst {
+		rl.tokens = rl.burst
+	}
+	rl.last = now
+
+	// Reserve now and sleep off any deficit outside the lock
+	rl.tokens -= float64(n)
+	var wait time.Duration
+	if rl.tokens < 0 {
+		wait = time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+	}
+	rl.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledReader limits reads from r through a shared RateLimiter
+type throttledReader struct {
+	r       io.Reader
+	ctx     context.Context
+	limiter *RateLimiter
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	// Keep each read within the burst so the limiter stays smooth
+	if max := int(tr.limiter.burst); max > 0 && len(p) > max {
+		p = p[:max]
+	}
+	n, err := tr.r.Read(p)
+	if n > 0 {
+		if werr := tr.limiter.WaitN(tr.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// throttledReadCloser adds Close to throttledReader
+type throttledReadCloser struct {
+	throttledReader
+	io.Closer
+}
+
+// SetExportThrottle limits the aggregate disk read rate of exports (0 = unlimited)
+// and optionally runs volume exports in the idle I/O scheduling class
+func (c *Client) SetExportThrottle(bytesPerSec int64, ioIdle bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.exportLimiter = nil
+	if bytesPerSec > 0 {
+		c.exportLimiter = NewRateLimiter(bytesPerSec)
+	}
+	c.exportIOIdle = ioIdle
+}
+
+// throttle wraps r with the export rate limit, if configured
+func (c *Client) throttle(ctx context.Context, r io.Reader) io.Reader {
+	c.mu.RLock()
+	limiter := c.exportLimiter
+	c.mu.RUnlock()
+
+	if limiter == nil {
+		return r
+	}
+	return &throttledReader{r: r, ctx: ctx, limiter: limiter}
+}
+
+// throttleReadCloser wraps rc with the export rate limit, if configured
+func (c *Client) throttleReadCloser(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	c.mu.RLock()
+	limiter := c.exportLimiter
+	c.mu.RUnlock()
+
+	if limiter == nil {
+		return rc
+	}
+	return &throttledReadCloser{
+		throttledReader: throttledReader{r: rc, ctx: ctx, limiter: limiter},
+		Closer:          rc,
+	}
+}
@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/system"
+	"go.uber.org/zap"
+)
+
+// ResourceUsage is a snapshot of how a container has been running and the
+// cgroup limits it runs under, taken when its state is exported
+type ResourceUsage struct {
+	RestartCount    int            `json:"restart_count"`
+	OOMKilled       bool           `json:"oom_killed"`                  // Last exit was an out-of-memory kill
+	MemoryFailCount uint64         `json:"memory_fail_count,omitempty"` // Times the memory limit was hit (cgroup v1 only)
+	MemoryUsage     uint64         `json:"memory_usage,omitempty"`      // Bytes in use, if running
+	MemoryMaxUsage  uint64         `json:"memory_max_usage,omitempty"`  // Peak bytes in use (cgroup v1 only)
+	PidsCurrent     uint64         `json:"pids_current,omitempty"`
+	Limits          ResourceLimits `json:"limits"`
+	CollectedAt     time.Time      `json:"collected_at"`
+}
+
+// ResourceLimits are the cgroup limits a container was created with. Zero
+// values mean unlimited.
+type ResourceLimits struct {
+	Memory            int64  `json:"memory,omitempty"`
+	MemoryReservation int64  `json:"memory_reservation,omitempty"`
+	MemorySwap        int64  `json:"memory_swap,omitempty"`
+	NanoCPUs          int64  `json:"nano_cpus,omitempty"`
+	CPUShares         int64  `json:"cpu_shares,omitempty"`
+	CPUPeriod         int64  `json:"cpu_period,omitempty"`
+	CPUQuota          int64  `json:"cpu_quota,omitempty"`
+	CpusetCpus        string `json:"cpuset_cpus,omitempty"`
+	CpusetMems        string `json:"cpuset_mems,omitempty"`
+	PidsLimit         int64  `json:"pids_limit,omitempty"`
+	BlkioWeight       uint16 `json:"blkio_weight,omitempty"`
+	OomKillDisable    bool   `json:"oom_kill_disable,omitempty"`
+}
+
+// containerUsage snapshots a container's restart count, OOM history and
+// limits. Live figures come from a one-shot stats read and are skipped if
+// the container is not running or stats are unavailable.
+func (c *Client) containerUsage(ctx context.Context, inspect types.ContainerJSON) *ResourceUsage {
+	usage := &ResourceUsage{
+		RestartCount: inspect.RestartCount,
+		CollectedAt:  time.Now(),
+	}
+	if inspect.State != nil {
+		usage.OOMKilled = inspect.State.OOMKilled
+	}
+	if inspect.HostConfig != nil {
+		res := inspect.HostConfig.Resources
+		usage.Limits = ResourceLimits{
+			Memory:            res.Memory,
+			MemoryReservation: res.MemoryReservation,
+			MemorySwap:        res.MemorySwap,
+			NanoCPUs:          res.NanoCPUs,
+			CPUShares:         res.CPUShares,
+			CPUPeriod:         res.CPUPeriod,
+			CPUQuota:          res.CPUQuota,
+			CpusetCpus:        res.CpusetCpus,
+			CpusetMems:        res.CpusetMems,
+			BlkioWeight:       res.BlkioWeight,
+		}
+		if res.PidsLimit != nil && *res.PidsLimit > 0 {
+			usage.Limits.PidsLimit = *res.PidsLimit
+		}
+		if res.OomKillDisable != nil {
+			usage.Limits.OomKillDisable = *res.OomKillDisable
+		}
+	}
+
+	if inspect.State == nil || !inspect.State.Running {
+		return usage
+	}
+
+	stats, err := c.containerStats(ctx, inspect.ID)
+	if err != nil {
+		c.logger.Debug("container stats unavailable for usage snapshot",
+			zap.String("container_id", inspect.ID),
+			zap.Error(err),
+		)
+		return usage
+	}
+	usage.MemoryFailCount = stats.MemoryStats.Failcnt
+	usage.MemoryUsage = stats.MemoryStats.Usage
+	usage.MemoryMaxUsage = stats.MemoryStats.MaxUsage
+	usage.PidsCurrent = stats.PidsStats.Current
+
+	return usage
+}
+
+// containerStats reads a single stats sample for a container
+func (c *Client) containerStats(ctx context.Context, containerID string) (*types.StatsJSON, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return nil, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	resp, err := cli.ContainerStatsOneShot(ctx, containerID)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("container_stats").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("container_stats", "error").Inc()
+		return nil, fmt.Errorf("failed to get stats for container %s: %w", containerID, err)
+	}
+	defer resp.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		observability.DockerOperations.WithLabelValues("container_stats", "error").Inc()
+		return nil, fmt.Errorf("failed to decode stats for container %s: %w", containerID, err)
+	}
+
+	observability.DockerOperations.WithLabelValues("container_stats", "success").Inc()
+	return &stats, nil
+}
+
+// CheckResourceLimits reports limits this daemon cannot honor: ones larger
+// than the host, or ones its cgroup setup does not enforce. An empty result
+// means the container can be recreated with the same limits.
+func (c *Client) CheckResourceLimits(ctx context.Context, limits ResourceLimits) ([]string, error) {
+	info, err := c.daemonInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resourceLimitWarnings(info, limits), nil
+}
+
+// daemonInfo returns the daemon's capabilities and host resources
+func (c *Client) daemonInfo(ctx context.Context) (system.Info, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return system.Info{}, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	info, err := cli.Info(ctx)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("system_info").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("system_info", "error").Inc()
+		return system.Info{}, fmt.Errorf("failed to get docker info: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("system_info", "success").Inc()
+	return info, nil
+}
+
+// resourceLimitWarnings compares limits with what a daemon reports it can do
+func resourceLimitWarnings(info system.Info, limits ResourceLimits) []string {
+	var warnings []string
+
+	if limits.Memory > 0 {
+		if !info.MemoryLimit {
+			warnings = append(warnings, "memory limit is not enforced on this host")
+		} else if info.MemTotal > 0 && limits.Memory > info.MemTotal {
+			warnings = append(warnings, fmt.Sprintf("memory limit of %d bytes exceeds this host's %d bytes", limits.Memory, info.MemTotal))
+		}
+	}
+	if limits.MemorySwap > 0 && !info.SwapLimit {
+		warnings = append(warnings, "swap limit is not enforced on this host")
+	}
+
+	cpus := float64(limits.NanoCPUs) / 1e9
+	if limits.CPUQuota > 0 {
+		if !info.CPUCfsQuota {
+			warnings = append(warnings, "CPU quota is not enforced on this host")
+		}
+		period := limits.CPUPeriod
+		if period == 0 {
+			period = 100000 // Kernel default CFS period in microseconds
+		}
+		cpus = float64(limits.CPUQuota) / float64(period)
+	}
+	if cpus > 0 && info.NCPU > 0 && cpus > float64(info.NCPU) {
+		warnings = append(warnings, fmt.Sprintf("CPU limit of %.2f exceeds this host's %d CPUs", cpus, info.NCPU))
+	}
+	if limits.CPUShares > 0 && !info.CPUShares {
+		warnings = append(warnings, "CPU shares are not enforced on this host")
+	}
+
+	if limits.CpusetCpus != "" {
+		if !info.CPUSet {
+			warnings = append(warnings, "cpuset is not supported on this host")
+		} else if highest, ok := highestCPU(limits.CpusetCpus); ok && info.NCPU > 0 && highest >= info.NCPU {
+			warnings = append(warnings, fmt.Sprintf("cpuset %q names CPU %d but this host has %d CPUs", limits.CpusetCpus, highest, info.NCPU))
+		}
+	}
+
+	if limits.PidsLimit > 0 && !info.PidsLimit {
+		warnings = append(warnings, "PIDs limit is not enforced on this host")
+	}
+	if limits.OomKillDisable && !info.OomKillDisable {
+		warnings = append(warnings, "disabling the OOM killer is not supported on this host")
+	}
+
+	return warnings
+}
+
+// highestCPU returns the largest CPU number in a cpuset list such as "0-3,6"
+func highestCPU(cpuset string) (int, bool) {
+	highest, found := -1, false
+	for _, part := range strings.Split(cpuset, ",") {
+		part = strings.TrimSpace(part)
+		if i := strings.IndexByte(part, '-'); i >= 0 {
+			part = part[i+1:]
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			continue
+		}
+		if n > highest {
+			highest, found = n, true
+		}
+	}
+	return highest, found
+}
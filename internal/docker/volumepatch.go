@@ -0,0 +1,374 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// A volume patch is a tar of directories and of byte ranges of files, to
+// write in place into an existing copy of a volume. Each range entry holds
+// the range's data and, in PAX records, where it goes and how long the whole
+// file is, so a file that shrank is truncated and one that is missing is
+// created.
+const (
+	patchOffsetRecord = "DOCKERMIGRATE.offset"
+	patchSizeRecord   = "DOCKERMIGRATE.size"
+)
+
+// VolumeRanges selects parts of a volume by path: the indices of the ranges
+// of a file, or nil for a directory or a file's metadata alone
+type VolumeRanges map[string][]int
+
+// rangeCount is the number of ranges a file of size bytes is hashed in. An
+// empty file has one empty range, so it still has a leaf to compare.
+func rangeCount(size, rangeSize int64) int {
+	if size == 0 {
+		return 1
+	}
+	return int((size + rangeSize - 1) / rangeSize)
+}
+
+// rangeHasher hashes what is written to it in ranges of size bytes
+type rangeHasher struct {
+	size int64
+	n    int64 // Bytes in the current range
+	cur  hash.Hash
+	sums []string
+}
+
+func (h *rangeHasher) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		if h.cur == nil {
+			h.cur = sha256.New()
+		}
+		part := p
+		if rest := h.size - h.n; int64(len(part)) > rest {
+			part = part[:rest]
+		}
+		h.cur.Write(part)
+		h.n += int64(len(part))
+		p = p[len(part):]
+		if h.n == h.size {
+			h.finish()
+		}
+	}
+	return written, nil
+}
+
+func (h *rangeHasher) finish() {
+	if h.cur == nil {
+		h.cur = sha256.New()
+	}
+	h.sums = append(h.sums, fmt.Sprintf("%x", h.cur.Sum(nil)))
+	h.cur = nil
+	h.n = 0
+}
+
+// Sums returns the hash of every range, including a trailing short one
+func (h *rangeHasher) Sums() []string {
+	if h.cur != nil || len(h.sums) == 0 {
+		h.finish()
+	}
+	return h.sums
+}
+
+// PatchSize estimates the size of a patch holding the given ranges, for
+// progress and spool space checks
+func (idx FileIndex) PatchSize(ranges VolumeRanges, rangeSize int64) int64 {
+	size := int64(1024) // End-of-archive blocks
+	for name, wanted := range ranges {
+		meta := idx[name]
+		if meta.Dir || len(wanted) == 0 {
+			size += 512
+			continue
+		}
+		for _, i := range wanted {
+			n := meta.Size - int64(i)*rangeSize
+			if n > rangeSize {
+				n = rangeSize
+			}
+			if n < 0 {
+				n = 0
+			}
+			// A PAX header and its records precede each range
+			size += 1536 + (n+511)/512*512
+		}
+	}
+	return size
+}
+
+// ExportVolumeRanges exports a patch of the given ranges of a volume. The
+// returned reader must be closed by the caller.
+func (c *Client) ExportVolumeRanges(ctx context.Context, volumeName string, ranges VolumeRanges, rangeSize int64) (io.ReadCloser, error) {
+	source, err := c.ExportVolume(ctx, volumeName)
+	if err != nil {
+		return nil, err
+	}
+
+	return SelectTarRanges(source, ranges, rangeSize), nil
+}
+
+// SelectTarRanges streams a patch of the given ranges of the files in a
+// volume tar, such as a saved export, and of the selected directories. It
+// closes source when done, and the returned reader must be closed by the
+// caller.
+func SelectTarRanges(source io.ReadCloser, ranges VolumeRanges, rangeSize int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer source.Close()
+		pw.CloseWithError(patchVolumeTar(source, pw, ranges, rangeSize))
+	}()
+	return pr
+}
+
+// patchVolumeTar copies the wanted directories and ranges of r to w as a
+// patch
+func patchVolumeTar(r io.Reader, w io.Writer, ranges VolumeRanges, rangeSize int64) error {
+	if rangeSize <= 0 {
+		return fmt.Errorf("invalid range size %d", rangeSize)
+	}
+
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	buf := make([]byte, rangeSize)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		name := tarEntryPath(header.Name)
+		indices, ok := ranges[name]
+		if !ok {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			header.Name = name
+			if err := tw.WriteHeader(header); err != nil {
+				return fmt.Errorf("failed to write tar header: %w", err)
+			}
+		case tar.TypeReg:
+			wanted := make(map[int]bool, len(indices))
+			for _, i := range indices {
+				wanted[i] = true
+			}
+			for i := 0; i < rangeCount(header.Size, rangeSize); i++ {
+				offset := int64(i) * rangeSize
+				n := header.Size - offset
+				if n > rangeSize {
+					n = rangeSize
+				}
+				if !wanted[i] {
+					if _, err := io.CopyN(io.Discard, tr, n); err != nil {
+						return fmt.Errorf("failed to read %s: %w", name, err)
+					}
+					continue
+				}
+				if _, err := io.ReadFull(tr, buf[:n]); err != nil {
+					return fmt.Errorf("failed to read %s: %w", name, err)
+				}
+				if err := tw.WriteHeader(&tar.Header{
+					Typeflag: tar.TypeReg,
+					Name:     name,
+					Mode:     header.Mode,
+					Size:     n,
+					ModTime:  header.ModTime,
+					Format:   tar.FormatPAX,
+					PAXRecords: map[string]string{
+						patchOffsetRecord: strconv.FormatInt(offset, 10),
+						patchSizeRecord:   strconv.FormatInt(header.Size, 10),
+					},
+				}); err != nil {
+					return fmt.Errorf("failed to write tar header: %w", err)
+				}
+				if _, err := tw.Write(buf[:n]); err != nil {
+					return fmt.Errorf("failed to write %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	return tw.Close()
+}
+
+// PatchVolume writes a patch into a volume. Volumes this host cannot reach
+// directly have the patched files copied out through a helper container,
+// patched, and imported again.
+func (c *Client) PatchVolume(ctx context.Context, volumeName string, patch io.ReadSeeker) error {
+	vol, err := c.InspectVolume(ctx, volumeName)
+	if err != nil {
+		return fmt.Errorf("volume verification failed: %w", err)
+	}
+
+	if !IsSharedStorageVolume(vol) && c.canAccessMountpoint(vol.Mountpoint) {
+		if err := c.applyVolumePatch(ctx, vol.Mountpoint, patch); err != nil {
+			return fmt.Errorf("failed to patch volume: %w", err)
+		}
+		return nil
+	}
+
+	paths, err := patchPaths(patch)
+	if err != nil {
+		return err
+	}
+	if _, err := patch.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind patch: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "volume-patch-")
+	if err != nil {
+		return fmt.Errorf("failed to create patch directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	current, err := c.ExportVolumeFiles(ctx, volumeName, paths)
+	if err != nil {
+		return err
+	}
+	err = c.extractVolumeTar(ctx, dir, current)
+	current.Close()
+	if err != nil {
+		return fmt.Errorf("failed to copy out patched files: %w", err)
+	}
+
+	if err := c.applyVolumePatch(ctx, dir, patch); err != nil {
+		return fmt.Errorf("failed to patch volume: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.createVolumeTar(ctx, dir, pw))
+	}()
+	patched := SelectTarFiles(pr, paths)
+	defer patched.Close()
+
+	c.logger.Debug("patching volume via helper container",
+		zap.String("volume", volumeName),
+		zap.Int("paths", len(paths)),
+	)
+	return c.ImportVolumeViaHelper(ctx, volumeName, patched)
+}
+
+// patchPaths lists the paths a patch writes to, sorted
+func patchPaths(r io.Reader) ([]string, error) {
+	seen := make(map[string]bool)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar header: %w", err)
+		}
+		if name := tarEntryPath(header.Name); name != "" {
+			seen[name] = true
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for name := range seen {
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// applyVolumePatch writes a patch into the volume mounted at mountpoint
+func (c *Client) applyVolumePatch(ctx context.Context, mountpoint string, r io.Reader) error {
+	tr := tar.NewReader(r)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
+		target := filepath.Join(mountpoint, header.Name)
+
+		// Security check: prevent path traversal
+		if !filepath.HasPrefix(target, filepath.Clean(mountpoint)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid tar path: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
+				return fmt.Errorf("failed to create directory: %w", err)
+			}
+
+		case tar.TypeReg:
+			offset, err := strconv.ParseInt(header.PAXRecords[patchOffsetRecord], 10, 64)
+			if err != nil || offset < 0 {
+				return fmt.Errorf("invalid patch offset for %s", header.Name)
+			}
+			size, err := strconv.ParseInt(header.PAXRecords[patchSizeRecord], 10, 64)
+			if err != nil || size < offset+header.Size {
+				return fmt.Errorf("invalid patch size for %s", header.Name)
+			}
+			if err := writeFileRange(target, os.FileMode(header.Mode), offset, size, tr); err != nil {
+				return fmt.Errorf("failed to patch %s: %w", header.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeFileRange writes r into a file at offset, creating the file if it is
+// missing and sizing it to size
+func writeFileRange(target string, mode os.FileMode, offset, size int64, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() != size {
+		if err := file.Truncate(size); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.Copy(io.NewOffsetWriter(file, offset), r); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/artemis/docker-migrate/internal/observability"
@@ -23,6 +24,34 @@ type VolumeInfo struct {
 	Options    map[string]string `json:"options"`
 	Scope      string            `json:"scope"`
 	Size       int64             `json:"size"`
+	// SharedStorage is true when the data lives on storage reachable from other hosts
+	SharedStorage bool `json:"shared_storage"`
+}
+
+// LocalVolumeDriver is the built-in Docker volume driver
+const LocalVolumeDriver = "local"
+
+// sharedFilesystemTypes are local driver "type" options backed by network storage
+var sharedFilesystemTypes = map[string]bool{
+	"nfs":       true,
+	"nfs4":      true,
+	"cifs":      true,
+	"smb3":      true,
+	"glusterfs": true,
+	"ceph":      true,
+}
+
+// IsSharedStorageVolume reports whether a volume's data lives outside the local host.
+// Such volumes have no usable mountpoint to walk; they are either re-attached on the
+// target or copied through a helper container.
+func IsSharedStorageVolume(vol *volume.Volume) bool {
+	if vol == nil {
+		return false
+	}
+	if vol.Driver != "" && vol.Driver != LocalVolumeDriver {
+		return true
+	}
+	return sharedFilesystemTypes[strings.ToLower(vol.Options["type"])]
 }
 
 // ListVolumes returns all volumes
@@ -86,24 +115,29 @@ func (c *Client) GetVolumeInfo(ctx context.Context, volumeName string) (*VolumeI
 		return nil, err
 	}
 
-	// Calculate volume size
-	size, err := c.calculateVolumeSize(ctx, vol.Mountpoint)
-	if err != nil {
-		c.logger.Warn("failed to calculate volume size",
-			zap.String("volume", volumeName),
-			zap.Error(err),
-		)
-		size = 0
+	// Calculate volume size; shared storage has no local data to walk
+	shared := IsSharedStorageVolume(vol)
+	var size int64
+	if !shared {
+		size, err = c.calculateVolumeSize(ctx, vol.Mountpoint)
+		if err != nil {
+			c.logger.Warn("failed to calculate volume size",
+				zap.String("volume", volumeName),
+				zap.Error(err),
+			)
+			size = 0
+		}
 	}
 
 	info := &VolumeInfo{
-		Name:       vol.Name,
-		Driver:     vol.Driver,
-		Mountpoint: vol.Mountpoint,
-		Labels:     vol.Labels,
-		Options:    vol.Options,
-		Scope:      vol.Scope,
-		Size:       size,
+		Name:          vol.Name,
+		Driver:        vol.Driver,
+		Mountpoint:    vol.Mountpoint,
+		Labels:        vol.Labels,
+		Options:       vol.Options,
+		Scope:         vol.Scope,
+		Size:          size,
+		SharedStorage: shared,
 	}
 
 	observability.VolumeSize.WithLabelValues(volumeName).Observe(float64(size))
@@ -124,6 +158,10 @@ func (c *Client) GetVolumeSize(ctx context.Context, volumeName string) (int64, e
 		return 0, err
 	}
 
+	if IsSharedStorageVolume(vol) {
+		return 0, nil
+	}
+
 	return c.calculateVolumeSize(ctx, vol.Mountpoint)
 }
 
@@ -175,12 +213,48 @@ func (c *Client) ExportVolume(ctx context.Context, volumeName string) (io.ReadCl
 		return nil, fmt.Errorf("volume verification failed: %w", err)
 	}
 
+	// Non-local drivers and remote daemons have no mountpoint we can walk
+	if IsSharedStorageVolume(vol) || !c.canAccessMountpoint(vol.Mountpoint) {
+		return c.ExportVolumeViaHelper(ctx, volumeName)
+	}
+
 	// Create pipe for streaming
 	pr, pw := io.Pipe()
 
 	go func() {
 		defer pw.Close()
 
+		c.mu.RLock()
+		ioIdle := c.exportIOIdle
+		c.mu.RUnlock()
+		if ioIdle {
+			if err := lowerIOPriority(); err != nil {
+				c.logger.Warn("failed to lower export I/O priority",
+					zap.String("volume", volumeName),
+					zap.Error(err),
+				)
+			}
+		}
+
+		// Hold writers off for a crash-consistent archive
+		release, err := c.freezeMountpoint(vol.Mountpoint)
+		if err != nil {
+			c.logger.Error("failed to freeze volume for export",
+				zap.String("volume", volumeName),
+				zap.Error(err),
+			)
+			pw.CloseWithError(err)
+			return
+		}
+		defer func() {
+			if err := release(); err != nil {
+				c.logger.Error("failed to release volume freeze",
+					zap.String("volume", volumeName),
+					zap.Error(err),
+				)
+			}
+		}()
+
 		if err := c.createVolumeTar(ctx, vol.Mountpoint, pw); err != nil {
 			c.logger.Error("failed to create volume tar",
 				zap.String("volume", volumeName),
@@ -256,7 +330,7 @@ func (c *Client) createVolumeTar(ctx context.Context, mountpoint string, w io.Wr
 			}
 			defer file.Close()
 
-			if _, err := io.Copy(tw, file); err != nil {
+			if _, err := io.Copy(tw, c.throttle(ctx, file)); err != nil {
 				return fmt.Errorf("failed to write file contents: %w", err)
 			}
 		}
@@ -286,6 +360,11 @@ func (c *Client) ImportVolume(ctx context.Context, volumeName string, reader io.
 		}
 	}
 
+	// Non-local drivers and remote daemons have no mountpoint we can write to
+	if IsSharedStorageVolume(vol) || !c.canAccessMountpoint(vol.Mountpoint) {
+		return c.ImportVolumeViaHelper(ctx, volumeName, reader)
+	}
+
 	// Extract tar to volume mountpoint
 	if err := c.extractVolumeTar(ctx, vol.Mountpoint, reader); err != nil {
 		return fmt.Errorf("failed to extract volume tar: %w", err)
@@ -368,6 +447,11 @@ func (c *Client) extractVolumeTar(ctx context.Context, mountpoint string, r io.R
 
 // CreateVolume creates a new volume
 func (c *Client) CreateVolume(ctx context.Context, name string, labels, options map[string]string) (*volume.Volume, error) {
+	return c.CreateVolumeWithDriver(ctx, name, LocalVolumeDriver, labels, options)
+}
+
+// CreateVolumeWithDriver creates a new volume using the given driver and driver options
+func (c *Client) CreateVolumeWithDriver(ctx context.Context, name, driver string, labels, options map[string]string) (*volume.Volume, error) {
 	c.mu.RLock()
 	if c.closed {
 		c.mu.RUnlock()
@@ -380,7 +464,7 @@ func (c *Client) CreateVolume(ctx context.Context, name string, labels, options
 	vol, err := cli.VolumeCreate(ctx, volume.CreateOptions{
 		Name:   name,
 		Labels: labels,
-		Driver: "local",
+		Driver: driver,
 		DriverOpts: options,
 	})
 	duration := time.Since(start)
@@ -393,11 +477,36 @@ func (c *Client) CreateVolume(ctx context.Context, name string, labels, options
 	}
 
 	observability.DockerOperations.WithLabelValues("volume_create", "success").Inc()
-	c.logger.Info("volume created", zap.String("volume", name))
+	c.logger.Info("volume created", zap.String("volume", name), zap.String("driver", driver))
 
 	return &vol, nil
 }
 
+// ListVolumeDrivers returns the volume drivers available on the daemon
+func (c *Client) ListVolumeDrivers(ctx context.Context) ([]string, error) {
+	c.mu.RLock()
+	if c.closed {
+		c.mu.RUnlock()
+		return nil, fmt.Errorf("client is closed")
+	}
+	cli := c.cli
+	c.mu.RUnlock()
+
+	start := time.Now()
+	info, err := cli.Info(ctx)
+	duration := time.Since(start)
+
+	observability.DockerOperationDuration.WithLabelValues("system_info").Observe(duration.Seconds())
+
+	if err != nil {
+		observability.DockerOperations.WithLabelValues("system_info", "error").Inc()
+		return nil, fmt.Errorf("failed to get docker info: %w", err)
+	}
+
+	observability.DockerOperations.WithLabelValues("system_info", "success").Inc()
+	return info.Plugins.Volume, nil
+}
+
 // RemoveVolume removes a volume
 func (c *Client) RemoveVolume(ctx context.Context, volumeName string, force bool) error {
 	c.mu.RLock()
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Type identifies an event; its value is the "type" field clients receive
+type Type string
+
+const (
+	ResourceUpdated  Type = "resource_update"  // A Docker resource collection changed
+	MigrationUpdated Type = "migration_update" // A migration job changed status or phase
+	PeerUpdated      Type = "peer_update"      // A peer was paired, removed or changed state
+)
+
+// Event is a typed notification published on the bus. Data must be safe to
+// marshal after publishing; pass snapshots, never live structs.
+type Event struct {
+	Type      Type        `json:"type"`
+	Namespace string      `json:"namespace,omitempty"` // Also shown to callers confined to it; empty is for unscoped callers only
+	Resource  string      `json:"resource,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// ResourceChanged builds a ResourceUpdated event for a resource collection
+// ("containers", "images", "volumes" or "networks")
+func ResourceChanged(resource string) Event {
+	return Event{Type: ResourceUpdated, Resource: resource}
+}
+
+// MigrationState is the payload of MigrationUpdated events
+type MigrationState struct {
+	JobID  string `json:"job_id"`
+	PeerID string `json:"peer_id"`
+	Status string `json:"status"`
+	Phase  string `json:"phase"`
+}
+
+// DefaultSubscriberBuffer is the queue depth of a subscription
+const DefaultSubscriberBuffer = 256
+
+// Bus fans events out to subscribers. Publishing never blocks: a subscriber
+// that falls behind loses events rather than stalling the publisher.
+type Bus struct {
+	subs   map[int]*subscription
+	nextID int
+	logger *zap.Logger
+	mu     sync.RWMutex
+}
+
+type subscription struct {
+	ch    chan Event
+	types map[Type]bool // Empty means every type
+}
+
+// NewBus creates an event bus
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		subs:   make(map[int]*subscription),
+		logger: logger,
+	}
+}
+
+// Publish delivers an event to every interested subscriber
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, sub := range b.subs {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.logger.Warn("event subscriber is full, dropping event",
+				zap.String("type", string(event.Type)),
+			)
+		}
+	}
+}
+
+// Subscribe returns a channel receiving events of the given types (all types
+// if none are given) and a function that ends the subscription
+func (b *Bus) Subscribe(buffer int, types ...Type) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = DefaultSubscriberBuffer
+	}
+
+	sub := &subscription{
+		ch:    make(chan Event, buffer),
+		types: make(map[Type]bool, len(types)),
+	}
+	for _, t := range types {
+		sub.types[t] = true
+	}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(sub.ch)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// Package janitor removes state that crashes and abandoned transfers leave
+// behind: transfer checkpoints, spool and temporary files, and recovery state
+// of jobs that no longer exist.
+package janitor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"go.uber.org/zap"
+)
+
+// DefaultInterval is how often sweepers run when no interval is given
+const DefaultInterval = time.Hour
+
+// Sweeper removes what has not been touched since cutoff, returning how many
+// items it removed
+type Sweeper func(cutoff time.Time) int
+
+// task is a sweeper and how long what it removes is kept
+type task struct {
+	name      string
+	retention time.Duration
+	sweep     Sweeper
+}
+
+// Janitor runs its sweepers in the background
+type Janitor struct {
+	logger *observability.Logger
+
+	mu    sync.Mutex
+	tasks []task
+}
+
+// New creates a janitor with no sweepers
+func New(logger *observability.Logger) *Janitor {
+	return &Janitor{logger: logger}
+}
+
+// Register adds a sweeper that removes what has gone untouched for longer
+// than retention. A retention of zero or less disables it.
+func (j *Janitor) Register(name string, retention time.Duration, sweep Sweeper) {
+	if retention <= 0 {
+		j.logger.Info("janitor task disabled", zap.String("task", name))
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.tasks = append(j.tasks, task{name: name, retention: retention, sweep: sweep})
+}
+
+// Sweep runs every sweeper once, returning how many items each removed
+func (j *Janitor) Sweep() map[string]int {
+	j.mu.Lock()
+	tasks := append([]task(nil), j.tasks...)
+	j.mu.Unlock()
+
+	now := time.Now()
+	removed := make(map[string]int, len(tasks))
+	for _, t := range tasks {
+		count := t.sweep(now.Add(-t.retention))
+		removed[t.name] = count
+		if count > 0 {
+			j.logger.Info("janitor removed stale items",
+				zap.String("task", t.name),
+				zap.Int("count", count),
+				zap.Duration("retention", t.retention),
+			)
+		}
+	}
+	return removed
+}
+
+// Start sweeps now and then every interval until ctx is cancelled
+func (j *Janitor) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	j.Sweep()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.Sweep()
+		}
+	}
+}
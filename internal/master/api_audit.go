@@ -0,0 +1,47 @@
+package master
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/migration"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAuditRoutes registers the command audit log query route
+func (m *Master) RegisterAuditRoutes(rg *gin.RouterGroup) {
+	rg.GET("/audit/commands", m.RequireUnscoped(), m.listCommandAudit)
+}
+
+func (m *Master) listCommandAudit(c *gin.Context) {
+	filter := CommandFilter{
+		WorkerID:    c.Query("worker"),
+		MigrationID: c.Query("migration"),
+		Type:        c.Query("type"),
+		Issuer:      c.Query("issuer"),
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := migration.ParseHistoryTime(since, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filter.Since = t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	records, err := m.audit.List(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"commands": records})
+}
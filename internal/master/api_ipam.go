@@ -0,0 +1,51 @@
+package master
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterIPAMRoutes registers the subnet pool routes
+func (m *Master) RegisterIPAMRoutes(rg *gin.RouterGroup) {
+	unscoped := m.RequireUnscoped()
+	rg.GET("/ipam", unscoped, m.getIPAMPool)
+	rg.DELETE("/ipam/allocations", m.RequireRole(RoleAdmin), unscoped, m.RequireTOTP(), m.releaseSubnet)
+}
+
+func (m *Master) getIPAMPool(c *gin.Context) {
+	pool := m.orchestrator.ipam
+	if pool == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+
+	cidr, prefix := pool.CIDR()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":       true,
+		"cidr":          cidr,
+		"prefix_length": prefix,
+		"allocations":   pool.Allocations(),
+	})
+}
+
+// releaseSubnet frees the subnet in the subnet query parameter, e.g. after
+// its network was removed from the worker
+func (m *Master) releaseSubnet(c *gin.Context) {
+	pool := m.orchestrator.ipam
+	if pool == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no ipam_pool configured"})
+		return
+	}
+
+	subnet := c.Query("subnet")
+	if subnet == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "subnet is required"})
+		return
+	}
+	if err := pool.Release(subnet); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "subnet released"})
+}
@@ -1,9 +1,13 @@
 package master
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/audit"
 	"github.com/gin-gonic/gin"
 	pb "github.com/artemis/docker-migrate/proto"
 )
@@ -26,6 +30,19 @@ type MigrationResponse struct {
 	VolumeNames      []string   `json:"volume_names,omitempty"`
 	NetworkIDs       []string   `json:"network_ids,omitempty"`
 	TransferMode     string     `json:"transfer_mode,omitempty"`
+	TransferFallback string     `json:"transfer_fallback,omitempty"`
+	ImageMode        string     `json:"image_mode,omitempty"`
+	RequestedBy      string     `json:"requested_by,omitempty"`
+	ReviewedBy       string     `json:"reviewed_by,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewed_at,omitempty"`
+	IssuedBy         string     `json:"issued_by,omitempty"`
+	Namespace        string     `json:"namespace,omitempty"`
+	Note             string     `json:"note,omitempty"`
+
+	Attempt            int        `json:"attempt,omitempty"`
+	MaxAttempts        int        `json:"max_attempts,omitempty"`
+	NextRetryAt        *time.Time `json:"next_retry_at,omitempty"`
+	CompletedResources []string   `json:"completed_resources,omitempty"`
 }
 
 // StartMigrationRequest is the request body for starting a migration
@@ -39,14 +56,69 @@ type StartMigrationRequest struct {
 	Mode           string   `json:"mode"`          // cold, warm, live
 	Strategy       string   `json:"strategy"`      // full, incremental, snapshot
 	TransferMode   string   `json:"transfer_mode"` // direct, proxy, auto
+	ImageMode      string   `json:"image_mode"`    // stream, registry
+
+	// Retry re-dispatches the migration if it fails
+	Retry *RetryRequest `json:"retry,omitempty"`
+
+	// Note tells the approving admin what an operator's migration is for
+	Note string `json:"note"`
+
+	// AllowRemigration migrates containers even when the target already runs
+	// a live copy of them, or cannot be checked for one
+	AllowRemigration bool `json:"allow_remigration"`
+}
+
+// RetryRequest is the retry policy of a migration
+type RetryRequest struct {
+	MaxAttempts int    `json:"max_attempts" binding:"required,min=1"` // Including the first run
+	Backoff     string `json:"backoff"`                                // Delay before the first retry, doubling after each (default 30s)
+}
+
+// EstimateMigrationRequest is the request body for sizing a migration
+type EstimateMigrationRequest struct {
+	SourceWorkerID string   `json:"source_worker_id" binding:"required"`
+	ContainerIDs   []string `json:"container_ids"`
+	ImageIDs       []string `json:"image_ids"`
+	VolumeNames    []string `json:"volume_names"`
+	NetworkIDs     []string `json:"network_ids"`
 }
 
 // RegisterMigrationRoutes registers migration API routes
 func (m *Master) RegisterMigrationRoutes(rg *gin.RouterGroup) {
-	rg.POST("/migrations", m.startMigration)
+	operator := m.RequireRole(RoleOperator)
+	admin := m.RequireRole(RoleAdmin)
+	rg.POST("/migrations", m.requireStartRole(), m.startMigration)
+	rg.POST("/migrations/estimate", operator, m.estimateMigration)
 	rg.GET("/migrations", m.listMigrations)
 	rg.GET("/migrations/:id", m.getMigration)
-	rg.POST("/migrations/:id/cancel", m.cancelMigration)
+	rg.POST("/migrations/:id/cancel", operator, m.cancelMigration)
+	rg.POST("/migrations/:id/approve", admin, m.RequireTOTP(), m.approveMigration)
+	rg.POST("/migrations/:id/reject", admin, m.rejectMigration)
+
+	// The same jobs under a prefix that cannot be mistaken for the peer-mode
+	// /api/migrate routes
+	jobs := rg.Group("/master")
+	jobs.GET("/migrations", m.listMigrations)
+	jobs.GET("/migrations/:id", m.getMigration)
+	jobs.POST("/migrations/:id/cancel", operator, m.cancelMigration)
+}
+
+// requireStartRole lets admins start migrations, with a TOTP code once one
+// is enrolled. With require_approval, operators may also submit them; theirs
+// wait for an admin's approval, which asks for the code instead.
+func (m *Master) requireStartRole() gin.HandlerFunc {
+	totp := m.RequireTOTP()
+	return func(c *gin.Context) {
+		if m.config.Master.RequireApproval && CallerRole(c) == RoleOperator {
+			c.Next()
+			return
+		}
+		if !CheckRole(c, m.logger, RoleAdmin) {
+			return
+		}
+		totp(c)
+	}
 }
 
 func (m *Master) startMigration(c *gin.Context) {
@@ -56,11 +128,41 @@ func (m *Master) startMigration(c *gin.Context) {
 		return
 	}
 
+	migrationReq, err := req.migrationRequest()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	migrationReq.Issuer = CallerIdentity(c)
+	migrationReq.Namespace = CallerNamespace(c)
+
+	// Only admins get past requireStartRole otherwise
+	if CallerRole(c) != RoleAdmin {
+		job, err := m.orchestrator.RequestMigration(migrationReq, CallerIdentity(c), req.Note)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, migrationToResponse(job))
+		return
+	}
+
+	job, err := m.orchestrator.StartMigration(c.Request.Context(), migrationReq)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, migrationToResponse(job))
+}
+
+// migrationRequest validates the request and converts it for the
+// orchestrator, leaving the issuer and namespace to the caller
+func (req *StartMigrationRequest) migrationRequest() (*MigrationRequest, error) {
 	// Validate at least one resource type is specified
 	if len(req.ContainerIDs) == 0 && len(req.ImageIDs) == 0 &&
 		len(req.VolumeNames) == 0 && len(req.NetworkIDs) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one resource must be specified"})
-		return
+		return nil, fmt.Errorf("at least one resource must be specified")
 	}
 
 	// Parse mode and strategy
@@ -90,7 +192,19 @@ func (m *Master) startMigration(c *gin.Context) {
 		transferMode = pb.TransferMode_TRANSFER_MODE_DIRECT
 	}
 
-	job, err := m.orchestrator.StartMigration(c.Request.Context(), &MigrationRequest{
+	var retry *RetryPolicy
+	if req.Retry != nil {
+		retry = &RetryPolicy{MaxAttempts: req.Retry.MaxAttempts}
+		if req.Retry.Backoff != "" {
+			backoff, err := time.ParseDuration(req.Retry.Backoff)
+			if err != nil || backoff < 0 {
+				return nil, fmt.Errorf("invalid retry backoff %q", req.Retry.Backoff)
+			}
+			retry.Backoff = backoff
+		}
+	}
+
+	return &MigrationRequest{
 		SourceWorkerID: req.SourceWorkerID,
 		TargetWorkerID: req.TargetWorkerID,
 		ContainerIDs:   req.ContainerIDs,
@@ -100,32 +214,100 @@ func (m *Master) startMigration(c *gin.Context) {
 		Mode:           mode,
 		Strategy:       strategy,
 		TransferMode:   transferMode,
+		ImageMode:      req.ImageMode,
+		Retry:          retry,
+
+		AllowRemigration: req.AllowRemigration,
+	}, nil
+}
+
+func (m *Master) estimateMigration(c *gin.Context) {
+	var req EstimateMigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := m.visibleWorker(c, req.SourceWorkerID); !ok {
+		return
+	}
+
+	estimate, err := m.orchestrator.EstimateMigration(&MigrationRequest{
+		SourceWorkerID: req.SourceWorkerID,
+		ContainerIDs:   req.ContainerIDs,
+		ImageIDs:       req.ImageIDs,
+		VolumeNames:    req.VolumeNames,
+		NetworkIDs:     req.NetworkIDs,
 	})
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, migrationToResponse(job))
+	c.JSON(http.StatusOK, estimate)
 }
 
+// listMigrations lists migrations, newest first. The status query parameter
+// takes a comma-separated list of statuses; worker matches either side.
 func (m *Master) listMigrations(c *gin.Context) {
-	jobs := m.orchestrator.ListMigrations()
+	statuses := make(map[string]bool)
+	if param := c.Query("status"); param != "" {
+		for _, status := range strings.Split(param, ",") {
+			status = strings.TrimSpace(status)
+			if !validMigrationStatus(status) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown status %q", status)})
+				return
+			}
+			statuses[status] = true
+		}
+	}
+	worker := c.Query("worker")
 
-	response := make([]MigrationResponse, 0, len(jobs))
-	for _, j := range jobs {
-		response = append(response, migrationToResponse(j))
+	response := make([]MigrationResponse, 0)
+	for _, j := range m.orchestrator.ListMigrations() {
+		if !CanSee(c, j.Namespace) {
+			continue
+		}
+		resp := migrationToResponse(j)
+		if len(statuses) > 0 && !statuses[resp.Status] {
+			continue
+		}
+		if worker != "" && resp.SourceWorkerID != worker && resp.TargetWorkerID != worker {
+			continue
+		}
+		response = append(response, resp)
 	}
+	sort.Slice(response, func(i, k int) bool {
+		return response[i].StartedAt.After(response[k].StartedAt)
+	})
 
 	c.JSON(http.StatusOK, gin.H{"migrations": response})
 }
 
+func validMigrationStatus(status string) bool {
+	switch MigrationJobStatus(status) {
+	case MigrationStatusPending, MigrationStatusRunning, MigrationStatusCompleted,
+		MigrationStatusFailed, MigrationStatusCancelled, MigrationStatusAwaiting,
+		MigrationStatusRejected, MigrationStatusRetrying:
+		return true
+	}
+	return false
+}
+
+// visibleMigration returns a job the caller may see, writing a 404 otherwise
+func (m *Master) visibleMigration(c *gin.Context, migrationID string) (*MigrationJob, bool) {
+	job, ok := m.orchestrator.GetMigration(migrationID)
+	if !ok || !CanSee(c, job.Namespace) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "migration not found"})
+		return nil, false
+	}
+	return job, true
+}
+
 func (m *Master) getMigration(c *gin.Context) {
 	migrationID := c.Param("id")
 
-	job, ok := m.orchestrator.GetMigration(migrationID)
+	job, ok := m.visibleMigration(c, migrationID)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "migration not found"})
 		return
 	}
 
@@ -134,6 +316,9 @@ func (m *Master) getMigration(c *gin.Context) {
 
 func (m *Master) cancelMigration(c *gin.Context) {
 	migrationID := c.Param("id")
+	if _, ok := m.visibleMigration(c, migrationID); !ok {
+		return
+	}
 
 	var req struct {
 		Reason string `json:"reason"`
@@ -144,7 +329,7 @@ func (m *Master) cancelMigration(c *gin.Context) {
 		req.Reason = "cancelled by user"
 	}
 
-	if err := m.orchestrator.CancelMigration(migrationID, req.Reason); err != nil {
+	if err := m.orchestrator.CancelMigration(migrationID, req.Reason, CallerIdentity(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -152,6 +337,78 @@ func (m *Master) cancelMigration(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "migration cancelled"})
 }
 
+func (m *Master) approveMigration(c *gin.Context) {
+	migrationID := c.Param("id")
+	requested, ok := m.visibleMigration(c, migrationID)
+	if !ok {
+		return
+	}
+
+	job, err := m.orchestrator.ApproveMigration(migrationID, CallerIdentity(c))
+	m.recordReview(c, "migration.approved", requested, "", err)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, migrationToResponse(job))
+}
+
+func (m *Master) rejectMigration(c *gin.Context) {
+	migrationID := c.Param("id")
+	requested, ok := m.visibleMigration(c, migrationID)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	if req.Reason == "" {
+		req.Reason = "rejected by admin"
+	}
+
+	err := m.orchestrator.RejectMigration(migrationID, req.Reason, CallerIdentity(c))
+	m.recordReview(c, "migration.rejected", requested, req.Reason, err)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "migration rejected"})
+}
+
+// AuditRecordedKey is set once a handler has recorded the request in the
+// security audit log itself, so the server's audit middleware does not
+// record it again
+const AuditRecordedKey = "audit_recorded"
+
+// recordReview records an approval or rejection in the security audit log
+// with who requested the migration and, for a rejection, why
+func (m *Master) recordReview(c *gin.Context, action string, job *MigrationJob, reason string, err error) {
+	job.mu.RLock()
+	detail := fmt.Sprintf("requested_by=%s namespace=%s", job.RequestedBy, job.Namespace)
+	job.mu.RUnlock()
+	if reason != "" {
+		detail += fmt.Sprintf(" reason=%q", reason)
+	}
+
+	entry := audit.Entry{
+		Action: action,
+		Actor:  CallerIdentity(c),
+		Target: job.ID,
+		Detail: detail,
+	}
+	if err != nil {
+		entry.Outcome = audit.OutcomeFailed
+		entry.Detail += ": " + err.Error()
+	}
+	m.securityLog.Record(entry)
+	c.Set(AuditRecordedKey, true)
+}
+
 func migrationToResponse(j *MigrationJob) MigrationResponse {
 	j.mu.RLock()
 	defer j.mu.RUnlock()
@@ -172,11 +429,29 @@ func migrationToResponse(j *MigrationJob) MigrationResponse {
 		VolumeNames:      j.VolumeNames,
 		NetworkIDs:       j.NetworkIDs,
 		TransferMode:     transferModeToString(j.TransferMode),
+		TransferFallback: j.TransferFallback,
+		ImageMode:        j.ImageMode,
+		RequestedBy:      j.RequestedBy,
+		IssuedBy:         j.IssuedBy,
+		Namespace:        j.Namespace,
+		Note:             j.Note,
+
+		Attempt:            j.Attempt,
+		CompletedResources: j.CompletedResources,
 	}
 
 	if !j.CompletedAt.IsZero() {
 		resp.CompletedAt = &j.CompletedAt
 	}
+	if !j.ReviewedAt.IsZero() {
+		resp.ReviewedAt = &j.ReviewedAt
+	}
+	if j.Retry != nil {
+		resp.MaxAttempts = j.Retry.MaxAttempts
+	}
+	if !j.NextRetryAt.IsZero() {
+		resp.NextRetryAt = &j.NextRetryAt
+	}
 
 	return resp
 }
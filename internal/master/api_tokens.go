@@ -0,0 +1,198 @@
+package master
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TOTPHeader carries the second factor for destructive operations
+const TOTPHeader = "X-TOTP-Code"
+
+// RegisterTokenRoutes registers API token and TOTP management routes
+func (m *Master) RegisterTokenRoutes(rg *gin.RouterGroup) {
+	admin := m.RequireRole(RoleAdmin)
+	unscoped := m.RequireUnscoped()
+	rg.GET("/tokens", admin, unscoped, m.listTokens)
+	rg.POST("/tokens", admin, unscoped, m.RequireTOTP(), m.issueToken)
+	rg.DELETE("/tokens/:id", admin, unscoped, m.RequireTOTP(), m.revokeToken)
+	rg.GET("/totp", m.getTOTPStatus)
+	rg.POST("/totp/enroll", admin, unscoped, m.RequireTOTP(), m.enrollTOTP)
+	rg.DELETE("/totp", admin, unscoped, m.RequireTOTP(), m.disableTOTP)
+}
+
+// Authenticate enforces API tokens on master HTTP endpoints when
+// require_api_token is set. Browsers cannot set headers on WebSockets, so
+// the token may also be passed as ?access_token=. Reads need the viewer
+// role and other methods operator; routes needing admin say so themselves.
+func (m *Master) Authenticate(c *gin.Context) {
+	if m.config.Master == nil || !m.config.Master.RequireAPIToken {
+		c.Next()
+		return
+	}
+
+	presented := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if presented == "" {
+		presented = c.Query("access_token")
+	}
+	if presented == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api token required"})
+		return
+	}
+
+	token, err := m.tokens.Validate(presented)
+	if err != nil {
+		m.logger.Warn("rejected api token",
+			zap.String("remote", c.ClientIP()),
+			zap.String("path", c.Request.URL.Path),
+			zap.Error(err),
+		)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api token: " + err.Error()})
+		return
+	}
+
+	role, err := ParseRole(string(token.Role))
+	if err != nil {
+		// A hand-edited store should not grant more than it names
+		role = RoleViewer
+	}
+	c.Set("api_token_id", token.ID)
+	SetCallerRole(c, role)
+	SetCallerNamespace(c, token.Namespace)
+	if !CheckRole(c, m.logger, MethodRole(c.Request.Method)) {
+		return
+	}
+	c.Next()
+}
+
+// RequireTOTP guards a destructive route with the TOTP second factor, once enrolled
+func (m *Master) RequireTOTP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.tokens.TOTPEnabled() {
+			c.Next()
+			return
+		}
+
+		err := m.tokens.VerifyTOTP(totpCaller(c), c.GetHeader(TOTPHeader))
+		if errors.Is(err, ErrTOTPRateLimited) {
+			m.logger.Warn("rate limited totp code",
+				zap.String("caller", CallerIdentity(c)),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":         err.Error(),
+				"totp_required": true,
+			})
+			return
+		}
+		if err != nil {
+			m.logger.Warn("rejected totp code",
+				zap.String("remote", c.ClientIP()),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":         "valid TOTP code required in " + TOTPHeader,
+				"totp_required": true,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// totpCaller names who a TOTP code is checked for: the signed-in user or API
+// token, or the client address when there is neither
+func totpCaller(c *gin.Context) string {
+	if user := c.GetString(UserContextKey); user != "" {
+		return "user:" + user
+	}
+	if tokenID := c.GetString("api_token_id"); tokenID != "" {
+		return "token:" + tokenID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func (m *Master) listTokens(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"tokens": m.tokens.List()})
+}
+
+func (m *Master) issueToken(c *gin.Context) {
+	var req struct {
+		Name      string `json:"name" binding:"required"`
+		Role      string `json:"role"`      // viewer, operator or admin; empty is admin
+		Namespace string `json:"namespace"` // Confines the token to a namespace; empty sees all
+		TTL       string `json:"ttl"`       // e.g. "720h"; empty never expires
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := ParseRole(req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Namespace != "" && !m.namespaces.Exists(req.Namespace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "namespace not found: " + req.Namespace})
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ttl"})
+			return
+		}
+	}
+
+	plaintext, token, err := m.tokens.Issue(req.Name, role, req.Namespace, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	token.Hash = ""
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   plaintext,
+		"details": token,
+	})
+}
+
+func (m *Master) revokeToken(c *gin.Context) {
+	if err := m.tokens.Revoke(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "token revoked"})
+}
+
+func (m *Master) getTOTPStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"enabled": m.tokens.TOTPEnabled()})
+}
+
+func (m *Master) enrollTOTP(c *gin.Context) {
+	secret, uri, err := m.tokens.EnrollTOTP("master")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
+
+func (m *Master) disableTOTP(c *gin.Context) {
+	if err := m.tokens.DisableTOTP(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "totp disabled"})
+}
@@ -1,9 +1,12 @@
 package master
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/peer"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,12 +16,17 @@ type WorkerResponse struct {
 	Name           string            `json:"name"`
 	Hostname       string            `json:"hostname"`
 	GRPCAddress    string            `json:"grpc_address"`
+	OutboundOnly   bool              `json:"outbound_only"`
 	Labels         map[string]string `json:"labels"`
 	Version        string            `json:"version"`
+	Protocol       int32             `json:"protocol_version"`
+	Namespace      string            `json:"namespace,omitempty"`
 	Status         string            `json:"status"`
 	Online         bool              `json:"online"`
 	RegisteredAt   time.Time         `json:"registered_at"`
 	LastHeartbeat  time.Time         `json:"last_heartbeat"`
+	ClockSkewMs    *int64            `json:"clock_skew_ms,omitempty"`
+	ClockSkewWarn  bool              `json:"clock_skew_warning,omitempty"`
 	ContainerCount int               `json:"container_count"`
 	ImageCount     int               `json:"image_count"`
 	VolumeCount    int               `json:"volume_count"`
@@ -27,12 +35,27 @@ type WorkerResponse struct {
 
 // RegisterWorkerRoutes registers worker management routes
 func (m *Master) RegisterWorkerRoutes(rg *gin.RouterGroup) {
+	admin := m.RequireRole(RoleAdmin)
+	unscoped := m.RequireUnscoped()
 	rg.GET("/workers", m.listWorkers)
 	rg.GET("/workers/:id", m.getWorker)
 	rg.GET("/workers/:id/resources", m.getWorkerResources)
-	rg.DELETE("/workers/:id", m.removeWorker)
-	rg.GET("/enrollment-token", m.getEnrollmentToken)
-	rg.POST("/enrollment-token/regenerate", m.regenerateEnrollmentToken)
+	rg.GET("/workers/:id/df", m.getWorkerDiskUsage)
+	rg.DELETE("/workers/:id", admin, m.RequireTOTP(), m.removeWorker)
+	rg.POST("/workers/:id/rotate-token", admin, m.RequireTOTP(), m.rotateWorkerToken)
+	rg.GET("/enrollment-token", admin, unscoped, m.getEnrollmentToken)
+	rg.POST("/enrollment-token/regenerate", admin, unscoped, m.RequireTOTP(), m.regenerateEnrollmentToken)
+}
+
+// visibleWorker returns a worker the caller may see, writing a 404 otherwise.
+// Workers in other namespaces are reported as missing, not forbidden.
+func (m *Master) visibleWorker(c *gin.Context, workerID string) (*WorkerInfo, bool) {
+	w, ok := m.registry.Get(workerID)
+	if !ok || !CanSee(c, w.Namespace) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "worker not found"})
+		return nil, false
+	}
+	return w, true
 }
 
 func (m *Master) listWorkers(c *gin.Context) {
@@ -40,6 +63,9 @@ func (m *Master) listWorkers(c *gin.Context) {
 
 	response := make([]WorkerResponse, 0, len(workers))
 	for _, w := range workers {
+		if !CanSee(c, w.Namespace) {
+			continue
+		}
 		response = append(response, workerToResponse(w, m.registry.IsOnline(w.ID)))
 	}
 
@@ -49,9 +75,8 @@ func (m *Master) listWorkers(c *gin.Context) {
 func (m *Master) getWorker(c *gin.Context) {
 	workerID := c.Param("id")
 
-	w, ok := m.registry.Get(workerID)
+	w, ok := m.visibleWorker(c, workerID)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "worker not found"})
 		return
 	}
 
@@ -61,27 +86,83 @@ func (m *Master) getWorker(c *gin.Context) {
 func (m *Master) getWorkerResources(c *gin.Context) {
 	workerID := c.Param("id")
 
-	w, ok := m.registry.Get(workerID)
+	w, ok := m.visibleWorker(c, workerID)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "worker not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"worker_id":  workerID,
 		"containers": w.Containers,
 		"images":     w.Images,
 		"volumes":    w.Volumes,
 		"networks":   w.Networks,
 		"updated_at": w.LastInventory,
+	}
+
+	// group_by=stack adds which of the resources belong to each compose
+	// project or Swarm stack
+	switch c.Query("group_by") {
+	case "":
+	case "stack":
+		stacks := docker.NewStackIndex()
+		for _, container := range w.Containers {
+			stacks.AddContainer(container.Id, container.Labels)
+		}
+		for _, volume := range w.Volumes {
+			stacks.AddVolume(volume.Name, volume.Labels)
+		}
+		// Workers report networks without labels
+		for _, network := range w.Networks {
+			stacks.AddNetwork(network.Id, network.Name, nil)
+		}
+		response["stacks"] = stacks.List()
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown group_by %q (expected stack)", c.Query("group_by"))})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (m *Master) getWorkerDiskUsage(c *gin.Context) {
+	workerID := c.Param("id")
+	if _, ok := m.visibleWorker(c, workerID); !ok {
+		return
+	}
+
+	df, updatedAt, err := m.WorkerDiskUsage(workerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"worker_id":  workerID,
+		"disk_usage": df,
+		"updated_at": updatedAt,
 	})
 }
 
+// WorkerDiskUsage returns the disk usage a worker last reported with its inventory
+func (m *Master) WorkerDiskUsage(workerID string) (*docker.DiskUsageSummary, time.Time, error) {
+	w, ok := m.registry.Get(workerID)
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf("worker not found")
+	}
+	if !peer.SupportsFeature(w.Protocol, peer.FeatureDiskUsage) {
+		return nil, time.Time{}, fmt.Errorf("worker speaks protocol v%d, which does not report disk usage; upgrade it", w.Protocol)
+	}
+	if w.DiskUsage == nil {
+		return nil, time.Time{}, fmt.Errorf("worker has not reported disk usage yet")
+	}
+	return peer.DiskUsageFromProto(w.DiskUsage), w.LastInventory, nil
+}
+
 func (m *Master) removeWorker(c *gin.Context) {
 	workerID := c.Param("id")
 
-	if _, ok := m.registry.Get(workerID); !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "worker not found"})
+	if _, ok := m.visibleWorker(c, workerID); !ok {
 		return
 	}
 
@@ -89,6 +170,21 @@ func (m *Master) removeWorker(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "worker removed"})
 }
 
+func (m *Master) rotateWorkerToken(c *gin.Context) {
+	workerID := c.Param("id")
+
+	if _, ok := m.visibleWorker(c, workerID); !ok {
+		return
+	}
+
+	_, overlap := m.authTokenRotation()
+	if err := m.RotateWorkerToken(workerID, overlap, CallerIdentity(c)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "worker auth token rotated"})
+}
+
 func (m *Master) getEnrollmentToken(c *gin.Context) {
 	if m.config.Master == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "master config not set"})
@@ -96,7 +192,8 @@ func (m *Master) getEnrollmentToken(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"enrollment_token": m.config.Master.EnrollmentToken,
+		"enrollment_token":   m.config.Master.EnrollmentToken,
+		"master_fingerprint": m.Fingerprint(),
 	})
 }
 
@@ -117,17 +214,28 @@ func (m *Master) regenerateEnrollmentToken(c *gin.Context) {
 }
 
 func workerToResponse(w *WorkerInfo, online bool) WorkerResponse {
+	var skewMs *int64
+	if w.ClockSkewKnown {
+		ms := w.ClockSkew.Milliseconds()
+		skewMs = &ms
+	}
+
 	return WorkerResponse{
 		ID:             w.ID,
 		Name:           w.Name,
 		Hostname:       w.Hostname,
 		GRPCAddress:    w.GRPCAddress,
+		OutboundOnly:   w.OutboundOnly,
 		Labels:         w.Labels,
 		Version:        w.Version,
+		Protocol:       w.Protocol,
+		Namespace:      w.Namespace,
 		Status:         w.Status.String(),
 		Online:         online,
 		RegisteredAt:   w.RegisteredAt,
 		LastHeartbeat:  w.LastHeartbeat,
+		ClockSkewMs:    skewMs,
+		ClockSkewWarn:  peer.ClockSkewExceeded(w.ClockSkew),
 		ContainerCount: len(w.Containers),
 		ImageCount:     len(w.Images),
 		VolumeCount:    len(w.Volumes),
@@ -0,0 +1,273 @@
+package master
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+)
+
+// IssuerSystem is the issuer of commands the master sends on its own, such as
+// scheduled token rotations
+const IssuerSystem = "system"
+
+// DefaultCommandAuditLimit caps how many records a query returns by default
+const DefaultCommandAuditLimit = 100
+
+const (
+	commandAuditMaxSize = 16 << 20 // The log is rotated once it reaches this size
+	commandAuditBackups = 3        // Rotated files kept, as command-audit.jsonl.1 (newest) to .3
+)
+
+// Command delivery outcomes, and the results workers report for them
+const (
+	CommandSent      = "sent"
+	CommandFailed    = "failed"
+	CommandSucceeded = "succeeded"
+)
+
+// CommandRecord is one command the master sent, or tried to send, to a worker
+type CommandRecord struct {
+	Time        time.Time `json:"time"`
+	CommandID   string    `json:"command_id"`
+	Type        string    `json:"type"`
+	WorkerID    string    `json:"worker_id"`
+	WorkerName  string    `json:"worker_name,omitempty"`
+	Issuer      string    `json:"issuer"`
+	MigrationID string    `json:"migration_id,omitempty"`
+	Detail      string    `json:"detail,omitempty"` // Role, reason or settings; never secrets
+	Outcome     string    `json:"outcome"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// CommandFilter narrows a command audit query; zero fields match everything
+type CommandFilter struct {
+	WorkerID    string
+	MigrationID string
+	Type        string
+	Issuer      string
+	Since       time.Time
+	Limit       int
+}
+
+func (f CommandFilter) matches(r *CommandRecord) bool {
+	return (f.WorkerID == "" || r.WorkerID == f.WorkerID) &&
+		(f.MigrationID == "" || r.MigrationID == f.MigrationID) &&
+		(f.Type == "" || r.Type == f.Type) &&
+		(f.Issuer == "" || r.Issuer == f.Issuer) &&
+		(f.Since.IsZero() || !r.Time.Before(f.Since))
+}
+
+// CommandAudit is an append-only log of the commands sent to workers and the
+// results they report, kept as JSON lines under the data directory for
+// incident forensics. It is rotated by size, keeping a few older files.
+type CommandAudit struct {
+	path   string
+	logger *observability.Logger
+	mu     sync.Mutex
+}
+
+// NewCommandAudit opens the command audit log in dataDir
+func NewCommandAudit(dataDir string, logger *observability.Logger) (*CommandAudit, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &CommandAudit{
+		path:   filepath.Join(dataDir, "command-audit.jsonl"),
+		logger: logger,
+	}, nil
+}
+
+// Record appends a record. Failures are logged, not returned: a full disk
+// must not stop the master from cancelling a migration.
+func (a *CommandAudit) Record(rec *CommandRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		a.logger.Warn("failed to encode command audit record", zap.Error(err))
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if info, err := os.Stat(a.path); err == nil && info.Size()+int64(len(line)) >= commandAuditMaxSize {
+		a.rotateLocked()
+	}
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		a.logger.Warn("failed to open command audit log", zap.Error(err))
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		a.logger.Warn("failed to write command audit record", zap.Error(err))
+	}
+}
+
+// rotateLocked shifts the log to command-audit.jsonl.1, dropping the oldest
+// rotated file
+func (a *CommandAudit) rotateLocked() {
+	for i := commandAuditBackups - 1; i >= 1; i-- {
+		os.Rename(a.backupPath(i), a.backupPath(i+1))
+	}
+	if err := os.Rename(a.path, a.backupPath(1)); err != nil {
+		a.logger.Warn("failed to rotate command audit log", zap.Error(err))
+		return
+	}
+	a.logger.Info("rotated command audit log", zap.String("path", a.backupPath(1)))
+}
+
+// backupPath is the path of the i-th newest rotated file
+func (a *CommandAudit) backupPath(i int) string {
+	return fmt.Sprintf("%s.%d", a.path, i)
+}
+
+// List returns matching records, newest first, from the log and its
+// rotated files
+func (a *CommandAudit) List(filter CommandFilter) ([]*CommandRecord, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultCommandAuditLimit
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Keep only the newest limit matches while scanning oldest to newest
+	var matched []*CommandRecord
+	for i := commandAuditBackups; i >= 0; i-- {
+		path := a.path
+		if i > 0 {
+			path = a.backupPath(i)
+		}
+		var err error
+		if matched, err = scanCommandAudit(path, filter, limit, matched); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]*CommandRecord, len(matched))
+	for i, rec := range matched {
+		records[len(matched)-1-i] = rec
+	}
+	return records, nil
+}
+
+// scanCommandAudit appends the records in path that match filter to matched,
+// keeping at most the newest limit
+func scanCommandAudit(path string, filter CommandFilter, limit int, matched []*CommandRecord) ([]*CommandRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return matched, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open command audit log: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec CommandRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue // A torn final line from a crash
+		}
+		if !filter.matches(&rec) {
+			continue
+		}
+		matched = append(matched, &rec)
+		if len(matched) > limit {
+			matched = matched[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read command audit log: %w", err)
+	}
+	return matched, nil
+}
+
+// newResultRecord records the result a worker reported for a migration it
+// was commanded to run
+func newResultRecord(worker *WorkerInfo, complete *pb.MigrationComplete) *CommandRecord {
+	rec := &CommandRecord{
+		Time:        time.Now(),
+		Type:        "migration_result",
+		WorkerID:    worker.ID,
+		WorkerName:  worker.Name,
+		Issuer:      "worker:" + worker.ID,
+		MigrationID: complete.MigrationId,
+		Detail: fmt.Sprintf("bytes=%d duration_ms=%d resources=%d",
+			complete.BytesTransferred, complete.DurationMs, len(complete.ResourcesMigrated)),
+		Outcome: CommandSucceeded,
+	}
+	if !complete.Success {
+		rec.Outcome = CommandFailed
+		rec.Error = complete.Error
+	}
+	return rec
+}
+
+// newCommandRecord describes cmd without any secrets it carries
+func newCommandRecord(worker *WorkerInfo, workerID string, cmd *pb.MasterCommand, issuer string) *CommandRecord {
+	rec := &CommandRecord{
+		Time:      time.Now(),
+		CommandID: cmd.CommandId,
+		WorkerID:  workerID,
+		Issuer:    issuer,
+	}
+	if worker != nil {
+		rec.WorkerName = worker.Name
+	}
+
+	switch payload := cmd.Payload.(type) {
+	case *pb.MasterCommand_StartMigration:
+		rec.Type = "start_migration"
+		start := payload.StartMigration
+		rec.Detail = fmt.Sprintf("role=%s transfer=%s", start.Role, start.TransferMode)
+		if start.Request != nil {
+			rec.MigrationID = start.Request.MigrationId
+			if start.Request.ImageRegistry != nil {
+				rec.Detail += " images=registry:" + start.Request.ImageRegistry.Address
+			}
+		}
+		if start.AcceptRequest != nil {
+			rec.MigrationID = start.AcceptRequest.MigrationId
+		}
+	case *pb.MasterCommand_CancelMigration:
+		rec.Type = "cancel_migration"
+		rec.MigrationID = payload.CancelMigration.MigrationId
+		rec.Detail = payload.CancelMigration.Reason
+	case *pb.MasterCommand_UpdateConfig:
+		rec.Type = "update_config"
+		update := payload.UpdateConfig
+		rec.Detail = fmt.Sprintf("heartbeat_interval_ms=%d inventory_interval_ms=%d labels=%d",
+			update.HeartbeatIntervalMs, update.InventoryIntervalMs, len(update.Labels))
+	case *pb.MasterCommand_Shutdown:
+		rec.Type = "shutdown"
+		rec.Detail = payload.Shutdown.Reason
+		if payload.Shutdown.Force {
+			rec.Detail += " (forced)"
+		}
+	case *pb.MasterCommand_RotateAuthToken:
+		rec.Type = "rotate_auth_token"
+	case *pb.MasterCommand_HeartbeatAck:
+		rec.Type = "heartbeat_ack"
+	default:
+		rec.Type = "unknown"
+	}
+	return rec
+}
@@ -0,0 +1,77 @@
+package master
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/migration"
+)
+
+// EstimateMigration sizes the requested resources from the source worker's
+// last inventory: image size, volume data and container writable layers.
+// Resources missing from the inventory are reported with an error.
+func (o *Orchestrator) EstimateMigration(req *MigrationRequest) (*migration.SizeEstimate, error) {
+	source, ok := o.registry.Get(req.SourceWorkerID)
+	if !ok {
+		return nil, fmt.Errorf("source worker not found: %s", req.SourceWorkerID)
+	}
+
+	sizes := make([]migration.ResourceSize, 0,
+		len(req.ContainerIDs)+len(req.ImageIDs)+len(req.VolumeNames)+len(req.NetworkIDs))
+
+	for _, id := range req.ContainerIDs {
+		size := migration.ResourceSize{Type: "container", ID: id, Name: id, Error: "not in worker inventory"}
+		for _, c := range source.Containers {
+			if c.Id == id || strings.HasPrefix(c.Id, id) || strings.TrimPrefix(c.Name, "/") == id {
+				size.Name = strings.TrimPrefix(c.Name, "/")
+				size.SizeBytes = c.SizeRw
+				size.Error = ""
+				break
+			}
+		}
+		sizes = append(sizes, size)
+	}
+
+	for _, id := range req.ImageIDs {
+		size := migration.ResourceSize{Type: "image", ID: id, Name: id, Error: "not in worker inventory"}
+		for _, img := range source.Images {
+			if img.Id == id || containsString(img.Tags, id) {
+				if len(img.Tags) > 0 {
+					size.Name = img.Tags[0]
+				}
+				size.SizeBytes = img.Size
+				size.Error = ""
+				break
+			}
+		}
+		sizes = append(sizes, size)
+	}
+
+	for _, name := range req.VolumeNames {
+		size := migration.ResourceSize{Type: "volume", ID: name, Name: name, Error: "not in worker inventory"}
+		for _, v := range source.Volumes {
+			if v.Name == name {
+				size.SizeBytes = v.Size
+				size.Error = ""
+				break
+			}
+		}
+		sizes = append(sizes, size)
+	}
+
+	// Networks carry no data
+	for _, id := range req.NetworkIDs {
+		sizes = append(sizes, migration.ResourceSize{Type: "network", ID: id, Name: id})
+	}
+
+	return migration.NewSizeEstimate(sizes, 0), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
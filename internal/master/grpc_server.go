@@ -6,12 +6,14 @@ import (
 	"io"
 	"net"
 
+	"github.com/artemis/docker-migrate/internal/audit"
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/artemis/docker-migrate/internal/peer"
 	pb "github.com/artemis/docker-migrate/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	grpcpeer "google.golang.org/grpc/peer"
 )
 
 // GRPCServer implements the MasterService
@@ -23,6 +25,7 @@ type GRPCServer struct {
 	logger        *observability.Logger
 	server        *grpc.Server
 	proxyManager  *ProxyManager
+	rendezvous    *Rendezvous
 }
 
 // NewGRPCServer creates a new gRPC server for master
@@ -31,7 +34,8 @@ func NewGRPCServer(master *Master, cryptoManager *peer.CryptoManager, logger *ob
 		master:        master,
 		cryptoManager: cryptoManager,
 		logger:        logger,
-		proxyManager:  NewProxyManager(master.registry, logger),
+		proxyManager:  NewProxyManager(master.ctx, master.registry, master.proxyNonces, master.transferManager, logger),
+		rendezvous:    NewRendezvous(master.config.Master.RelayToken, logger),
 	}, nil
 }
 
@@ -39,6 +43,7 @@ func NewGRPCServer(master *Master, cryptoManager *peer.CryptoManager, logger *ob
 func (s *GRPCServer) RegisterOn(server *grpc.Server) {
 	pb.RegisterMasterServiceServer(server, s)
 	pb.RegisterProxyServiceServer(server, s.proxyManager)
+	pb.RegisterRendezvousServiceServer(server, s.rendezvous)
 	s.server = server
 	s.logger.Info("master service registered on existing gRPC server")
 }
@@ -63,6 +68,7 @@ func (s *GRPCServer) Start(addr string) error {
 	s.server = grpc.NewServer(opts...)
 	pb.RegisterMasterServiceServer(s.server, s)
 	pb.RegisterProxyServiceServer(s.server, s.proxyManager)
+	pb.RegisterRendezvousServiceServer(s.server, s.rendezvous)
 
 	s.logger.Info("master gRPC server starting", zap.String("addr", addr))
 
@@ -83,28 +89,105 @@ func (s *GRPCServer) RegisterWorker(ctx context.Context, reg *pb.WorkerRegistrat
 	)
 
 	// Validate enrollment token
-	if !s.master.ValidateEnrollmentToken(reg.EnrollmentToken) {
+	namespace, ok := s.master.ValidateEnrollmentToken(reg.EnrollmentToken)
+	if !ok {
 		s.logger.Warn("invalid enrollment token",
 			zap.String("name", reg.WorkerName),
 		)
+		s.master.securityLog.Record(audit.Entry{
+			Action:  "worker.enrolled",
+			Actor:   "host:" + reg.Hostname,
+			Detail:  "name=" + reg.WorkerName,
+			Outcome: audit.OutcomeDenied,
+		})
 		return &pb.RegistrationResponse{
 			Success: false,
 			Error:   "invalid enrollment token",
 		}, nil
 	}
 
+	// Agree on a protocol version; older workers are driven at their version
+	// and only refused when we can no longer speak it at all
+	protocol, err := peer.NegotiateProtocol(reg.ProtocolVersion, reg.MinProtocolVersion)
+	if err != nil {
+		s.logger.Warn("audit: worker registration refused, incompatible protocol",
+			zap.String("name", reg.WorkerName),
+			zap.String("hostname", reg.Hostname),
+			zap.String("worker_version", reg.Version),
+			zap.Int32("worker_protocol", peer.EffectiveProtocolVersion(reg.ProtocolVersion)),
+			zap.Int32("master_protocol", peer.ProtocolVersion),
+			zap.Error(err),
+		)
+		return &pb.RegistrationResponse{
+			Success:         false,
+			Error:           fmt.Sprintf("incompatible protocol: %v", err),
+			ProtocolVersion: peer.ProtocolVersion,
+		}, nil
+	}
+	if protocol < peer.ProtocolVersion {
+		s.logger.Info("audit: driving worker at older protocol",
+			zap.String("name", reg.WorkerName),
+			zap.String("worker_version", reg.Version),
+			zap.Int32("protocol", protocol),
+			zap.Int32("master_protocol", peer.ProtocolVersion),
+		)
+	}
+
+	// The worker is known by the certificate it proved it holds in the
+	// handshake, never by the fingerprint it reports
+	fingerprint, err := clientFingerprint(ctx)
+	if err == nil && reg.TlsFingerprint != "" && reg.TlsFingerprint != fingerprint {
+		err = fmt.Errorf("reported fingerprint %s does not match the client certificate", reg.TlsFingerprint)
+	}
+	if err != nil {
+		s.logger.Warn("worker registration refused",
+			zap.String("name", reg.WorkerName),
+			zap.String("hostname", reg.Hostname),
+			zap.Error(err),
+		)
+		s.master.securityLog.Record(audit.Entry{
+			Action:  "worker.enrolled",
+			Actor:   "host:" + reg.Hostname,
+			Detail:  fmt.Sprintf("name=%s: %v", reg.WorkerName, err),
+			Outcome: audit.OutcomeDenied,
+		})
+		return &pb.RegistrationResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
 	// Generate auth token for this worker
 	authToken := s.master.GenerateWorkerAuthToken()
 
 	// Register worker
-	worker, err := s.master.registry.Register(reg, authToken)
+	worker, err := s.master.registry.Register(reg, fingerprint, authToken, protocol, namespace)
 	if err != nil {
+		s.logger.Warn("worker registration refused",
+			zap.String("name", reg.WorkerName),
+			zap.String("fingerprint", fingerprint),
+			zap.Error(err),
+		)
+		s.master.securityLog.Record(audit.Entry{
+			Action:  "worker.enrolled",
+			Actor:   "host:" + reg.Hostname,
+			Detail:  fmt.Sprintf("name=%s namespace=%s: %v", reg.WorkerName, namespace, err),
+			Outcome: audit.OutcomeDenied,
+		})
 		return &pb.RegistrationResponse{
 			Success: false,
 			Error:   err.Error(),
 		}, nil
 	}
 
+	s.master.registry.UpdateClockSkew(worker.ID, reg.TimestampMs)
+	s.master.securityLog.Record(audit.Entry{
+		Action: "worker.enrolled",
+		Actor:  "host:" + reg.Hostname,
+		Target: worker.ID,
+		Detail: fmt.Sprintf("name=%s namespace=%s", reg.WorkerName, namespace),
+	})
+
 	masterCfg := s.master.GetConfig().Master
 
 	return &pb.RegistrationResponse{
@@ -113,6 +196,8 @@ func (s *GRPCServer) RegisterWorker(ctx context.Context, reg *pb.WorkerRegistrat
 		AuthToken:           authToken,
 		HeartbeatIntervalMs: int64(masterCfg.HeartbeatInterval.Milliseconds()),
 		InventoryIntervalMs: int64(masterCfg.InventoryInterval.Milliseconds()),
+		ProtocolVersion:     protocol,
+		MasterFingerprint:   s.cryptoManager.GetFingerprint(),
 	}, nil
 }
 
@@ -152,10 +237,16 @@ func (s *GRPCServer) WorkerStream(stream pb.MasterService_WorkerStreamServer) er
 			s.handleHeartbeat(workerID, payload.Heartbeat, stream)
 
 		case *pb.WorkerMessage_MigrationProgress:
-			s.master.orchestrator.UpdateProgress(payload.MigrationProgress.MigrationId, payload.MigrationProgress)
+			reportedAt := s.master.registry.NormalizeTime(workerID, payload.MigrationProgress.TimestampMs)
+			s.master.orchestrator.UpdateProgress(payload.MigrationProgress.MigrationId, payload.MigrationProgress, reportedAt)
 
 		case *pb.WorkerMessage_MigrationComplete:
-			s.master.orchestrator.CompleteMigration(payload.MigrationComplete.MigrationId, payload.MigrationComplete)
+			s.master.audit.Record(newResultRecord(worker, payload.MigrationComplete))
+			reportedAt := s.master.registry.NormalizeTime(workerID, payload.MigrationComplete.TimestampMs)
+			s.master.orchestrator.CompleteMigration(payload.MigrationComplete.MigrationId, payload.MigrationComplete, reportedAt)
+
+		case *pb.WorkerMessage_TransferFallback:
+			s.handleTransferFallback(workerID, payload.TransferFallback, stream)
 
 		case *pb.WorkerMessage_WorkerError:
 			s.logger.Error("worker error",
@@ -167,8 +258,46 @@ func (s *GRPCServer) WorkerStream(stream pb.MasterService_WorkerStreamServer) er
 	}
 }
 
+// clientFingerprint returns the fingerprint of the certificate the caller
+// presented in the TLS handshake
+func clientFingerprint(ctx context.Context) (string, error) {
+	p, ok := grpcpeer.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no peer info")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return "", fmt.Errorf("a client certificate is required")
+	}
+	return peer.ComputeFingerprint(tlsInfo.State.PeerCertificates[0]), nil
+}
+
+// handleTransferFallback relays a migration whose source could not reach its
+// target, or tells the source why it cannot be relayed
+func (s *GRPCServer) handleTransferFallback(workerID string, fb *pb.TransferFallback, stream pb.MasterService_WorkerStreamServer) {
+	err := s.master.orchestrator.FallBackToProxy(workerID, fb.MigrationId, fb.Reason)
+	if err == nil {
+		return
+	}
+	s.logger.Warn("cannot relay migration through the master",
+		zap.String("worker_id", workerID),
+		zap.String("migration_id", fb.MigrationId),
+		zap.Error(err),
+	)
+	stream.Send(&pb.MasterCommand{
+		CommandId: fmt.Sprintf("fallback-source-%s", fb.MigrationId),
+		Payload: &pb.MasterCommand_ProxyFallback{
+			ProxyFallback: &pb.ProxyFallbackCommand{
+				MigrationId: fb.MigrationId,
+				Error:       err.Error(),
+			},
+		},
+	})
+}
+
 func (s *GRPCServer) handleHeartbeat(workerID string, hb *pb.Heartbeat, stream pb.MasterService_WorkerStreamServer) {
 	s.master.registry.UpdateHeartbeat(workerID, hb.Status, hb.SystemResources)
+	s.master.registry.UpdateClockSkew(workerID, hb.Timestamp)
 
 	// Send ack
 	ack := &pb.MasterCommand{
@@ -208,3 +337,61 @@ func (s *GRPCServer) ReportResources(ctx context.Context, inv *pb.ResourceInvent
 
 	return &pb.AckResponse{Success: true}, nil
 }
+
+// RequestMigration records a worker-initiated migration for admin approval
+func (s *GRPCServer) RequestMigration(ctx context.Context, req *pb.WorkerMigrationRequest) (*pb.WorkerMigrationRequestResponse, error) {
+	worker, ok := s.master.registry.GetByAuthToken(req.AuthToken)
+	if !ok {
+		return &pb.WorkerMigrationRequestResponse{
+			Success: false,
+			Error:   "invalid auth token",
+		}, nil
+	}
+
+	if len(req.ContainerIds) == 0 && len(req.ImageIds) == 0 &&
+		len(req.VolumeNames) == 0 && len(req.NetworkIds) == 0 {
+		return &pb.WorkerMigrationRequestResponse{
+			Success: false,
+			Error:   "at least one resource must be specified",
+		}, nil
+	}
+
+	target, ok := s.master.registry.Resolve(req.TargetWorker)
+	if !ok {
+		return &pb.WorkerMigrationRequestResponse{
+			Success: false,
+			Error:   fmt.Sprintf("target worker not found: %s", req.TargetWorker),
+		}, nil
+	}
+	if target.ID == worker.ID {
+		return &pb.WorkerMigrationRequestResponse{
+			Success: false,
+			Error:   "target worker must differ from the requesting worker",
+		}, nil
+	}
+
+	job, err := s.master.orchestrator.RequestMigration(&MigrationRequest{
+		SourceWorkerID: worker.ID,
+		TargetWorkerID: target.ID,
+		ContainerIDs:   req.ContainerIds,
+		ImageIDs:       req.ImageIds,
+		VolumeNames:    req.VolumeNames,
+		NetworkIDs:     req.NetworkIds,
+		Mode:           req.Mode,
+		Strategy:       req.Strategy,
+		TransferMode:   pb.TransferMode_TRANSFER_MODE_DIRECT,
+		Issuer:         "worker:" + worker.ID,
+		Namespace:      worker.Namespace,
+	}, worker.ID, req.Note)
+	if err != nil {
+		return &pb.WorkerMigrationRequestResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &pb.WorkerMigrationRequestResponse{
+		Success:     true,
+		MigrationId: job.ID,
+	}, nil
+}
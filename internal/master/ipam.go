@@ -0,0 +1,299 @@
+package master
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+)
+
+// defaultSubnetPrefix is the size of each subnet carved from the pool
+const defaultSubnetPrefix = 24
+
+// SubnetAllocation is a pool subnet handed to a network on a worker
+type SubnetAllocation struct {
+	Subnet      string    `json:"subnet"`
+	Gateway     string    `json:"gateway"`
+	WorkerID    string    `json:"worker_id"`
+	Network     string    `json:"network"`
+	MigrationID string    `json:"migration_id,omitempty"`
+	AllocatedAt time.Time `json:"allocated_at"`
+}
+
+// IPAMPool hands out non-overlapping subnets from one site-wide range to the
+// networks migrations recreate on workers. Allocations are kept in
+// ipam-pool.json in the data directory, so a restarted master does not hand
+// a subnet out twice.
+type IPAMPool struct {
+	base   *net.IPNet
+	prefix int
+	path   string
+	logger *observability.Logger
+
+	mu          sync.Mutex
+	allocations []*SubnetAllocation
+}
+
+// NewIPAMPool opens the pool described by cfg, or returns nil if cfg is nil
+func NewIPAMPool(dataDir string, cfg *config.IPAMPoolConfig, logger *observability.Logger) (*IPAMPool, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	_, base, err := net.ParseCIDR(cfg.CIDR)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ipam_pool cidr %q: %w", cfg.CIDR, err)
+	}
+	if base.IP.To4() == nil {
+		return nil, fmt.Errorf("ipam_pool cidr %s is not IPv4", cfg.CIDR)
+	}
+	baseOnes, _ := base.Mask.Size()
+	prefix := cfg.PrefixLength
+	if prefix == 0 {
+		prefix = defaultSubnetPrefix
+	}
+	if prefix < baseOnes || prefix > 30 {
+		return nil, fmt.Errorf("ipam_pool prefix_length %d must be between %d and 30", prefix, baseOnes)
+	}
+
+	dataDir, err = config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	p := &IPAMPool{
+		base:   base,
+		prefix: prefix,
+		path:   filepath.Join(dataDir, "ipam-pool.json"),
+		logger: logger,
+	}
+
+	data, err := os.ReadFile(p.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read ipam pool: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &p.allocations); err != nil {
+			return nil, fmt.Errorf("failed to parse ipam pool: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// CIDR returns the pool's range and the size of its subnets
+func (p *IPAMPool) CIDR() (string, int) {
+	return p.base.String(), p.prefix
+}
+
+// Allocate returns the subnet for network on workerID, allocating one that
+// overlaps neither an earlier allocation nor any subnet in inUse. A network
+// that already has one keeps it, so a retried migration asks for the same
+// subnet it was given before.
+func (p *IPAMPool) Allocate(workerID, network, migrationID string, inUse []*net.IPNet) (*SubnetAllocation, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, a := range p.allocations {
+		if a.WorkerID == workerID && a.Network == network {
+			copied := *a
+			return &copied, nil
+		}
+	}
+
+	taken := make([]*net.IPNet, 0, len(p.allocations)+len(inUse))
+	for _, a := range p.allocations {
+		if _, subnet, err := net.ParseCIDR(a.Subnet); err == nil {
+			taken = append(taken, subnet)
+		}
+	}
+	taken = append(taken, inUse...)
+
+	baseOnes, _ := p.base.Mask.Size()
+	start := binary.BigEndian.Uint32(p.base.IP.To4())
+	step := uint32(1) << (32 - p.prefix)
+	count := uint32(1) << (p.prefix - baseOnes)
+	mask := net.CIDRMask(p.prefix, 32)
+
+	for i := uint32(0); i < count; i++ {
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, start+i*step)
+		candidate := &net.IPNet{IP: ip, Mask: mask}
+		if overlapsAny(candidate, taken) {
+			continue
+		}
+
+		gateway := make(net.IP, 4)
+		binary.BigEndian.PutUint32(gateway, start+i*step+1)
+		allocation := &SubnetAllocation{
+			Subnet:      candidate.String(),
+			Gateway:     gateway.String(),
+			WorkerID:    workerID,
+			Network:     network,
+			MigrationID: migrationID,
+			AllocatedAt: time.Now(),
+		}
+		p.allocations = append(p.allocations, allocation)
+		if err := p.saveLocked(); err != nil {
+			p.allocations = p.allocations[:len(p.allocations)-1]
+			return nil, err
+		}
+
+		p.logger.Info("allocated network subnet",
+			zap.String("subnet", allocation.Subnet),
+			zap.String("worker_id", workerID),
+			zap.String("network", network),
+			zap.String("migration_id", migrationID),
+		)
+		copied := *allocation
+		return &copied, nil
+	}
+
+	return nil, fmt.Errorf("ipam pool %s has no free /%d subnet left", p.base, p.prefix)
+}
+
+// Release returns a subnet to the pool
+func (p *IPAMPool) Release(subnet string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, a := range p.allocations {
+		if a.Subnet != subnet {
+			continue
+		}
+		p.allocations = append(p.allocations[:i], p.allocations[i+1:]...)
+		if err := p.saveLocked(); err != nil {
+			return err
+		}
+		p.logger.Info("released network subnet",
+			zap.String("subnet", subnet),
+			zap.String("worker_id", a.WorkerID),
+			zap.String("network", a.Network),
+		)
+		return nil
+	}
+	return fmt.Errorf("subnet not allocated: %s", subnet)
+}
+
+// Allocations returns copies of every allocation, by subnet address
+func (p *IPAMPool) Allocations() []SubnetAllocation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := make([]SubnetAllocation, 0, len(p.allocations))
+	for _, a := range p.allocations {
+		result = append(result, *a)
+	}
+	sort.Slice(result, func(i, k int) bool {
+		return subnetKey(result[i].Subnet) < subnetKey(result[k].Subnet)
+	})
+	return result
+}
+
+func (p *IPAMPool) saveLocked() error {
+	data, err := json.MarshalIndent(p.allocations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ipam pool: %w", err)
+	}
+
+	tmpPath := p.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write ipam pool: %w", err)
+	}
+	if err := os.Rename(tmpPath, p.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save ipam pool: %w", err)
+	}
+	return nil
+}
+
+// overlapsAny reports whether subnet shares an address with any of others
+func overlapsAny(subnet *net.IPNet, others []*net.IPNet) bool {
+	for _, other := range others {
+		if subnet.Contains(other.IP) || other.Contains(subnet.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// subnetKey orders IPv4 subnets by address
+func subnetKey(subnet string) uint32 {
+	ip, _, err := net.ParseCIDR(subnet)
+	if err != nil || ip.To4() == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(ip.To4())
+}
+
+// networkSpecs plans the networks job recreates on its target from the
+// source's inventory. Bridge networks get a pool subnet when a pool is
+// configured; other drivers keep the source subnet, which usually belongs to
+// the underlying network rather than to Docker.
+func (o *Orchestrator) networkSpecs(job *MigrationJob, source *WorkerInfo) ([]*pb.NetworkSpec, error) {
+	specs := make([]*pb.NetworkSpec, 0, len(job.NetworkIDs))
+	for _, id := range job.NetworkIDs {
+		var network *pb.NetworkResource
+		for _, n := range source.Networks {
+			if n.Id == id || strings.HasPrefix(n.Id, id) || n.Name == id {
+				network = n
+				break
+			}
+		}
+		if network == nil {
+			return nil, fmt.Errorf("network not in source inventory: %s", id)
+		}
+		switch network.Name {
+		case "bridge", "host", "none":
+			return nil, fmt.Errorf("cannot recreate built-in network: %s", network.Name)
+		}
+
+		spec := &pb.NetworkSpec{
+			SourceId: network.Id,
+			Name:     network.Name,
+			Driver:   network.Driver,
+			Internal: network.Internal,
+		}
+		switch {
+		case network.Driver == "bridge" && o.ipam != nil:
+			allocation, err := o.ipam.Allocate(job.TargetWorkerID, network.Name, job.ID, o.siteSubnets())
+			if err != nil {
+				return nil, err
+			}
+			spec.Subnet = allocation.Subnet
+			spec.Gateway = allocation.Gateway
+		case network.Driver != "bridge" && len(network.Subnets) > 0:
+			spec.Subnet = network.Subnets[0]
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// siteSubnets returns every subnet the workers report in use
+func (o *Orchestrator) siteSubnets() []*net.IPNet {
+	var subnets []*net.IPNet
+	for _, w := range o.registry.List() {
+		for _, n := range w.Networks {
+			for _, cidr := range n.Subnets {
+				if _, subnet, err := net.ParseCIDR(cidr); err == nil {
+					subnets = append(subnets, subnet)
+				}
+			}
+		}
+	}
+	return subnets
+}
@@ -0,0 +1,106 @@
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+)
+
+// interruptedError is recorded on jobs that were in flight when the master stopped
+const interruptedError = "interrupted by master restart"
+
+// JobStore keeps the orchestrator's migration jobs in the data directory so
+// the job list survives a master restart
+type JobStore struct {
+	path   string
+	logger *observability.Logger
+	mu     sync.Mutex
+}
+
+// NewJobStore opens the job store in dataDir
+func NewJobStore(dataDir string, logger *observability.Logger) (*JobStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &JobStore{
+		path:   filepath.Join(dataDir, "master-migrations.json"),
+		logger: logger,
+	}, nil
+}
+
+// Load returns the stored jobs. Jobs that were pending, running or waiting to
+// retry when the master stopped are returned as failed: their workers have
+// long since given up on them.
+func (s *JobStore) Load() ([]*MigrationJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job store: %w", err)
+	}
+
+	var jobs []*MigrationJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse job store: %w", err)
+	}
+
+	for _, job := range jobs {
+		if job.Status == MigrationStatusPending || job.Status == MigrationStatusRunning ||
+			job.Status == MigrationStatusRetrying {
+			job.markInterrupted()
+		}
+	}
+	return jobs, nil
+}
+
+// Save replaces the stored jobs with jobs
+func (s *JobStore) Save(jobs []*MigrationJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Each job is encoded under its own lock, so the file always holds the
+	// state the last caller saw
+	records := make([]json.RawMessage, 0, len(jobs))
+	for _, job := range jobs {
+		job.mu.RLock()
+		record, err := json.Marshal(job)
+		job.mu.RUnlock()
+		if err != nil {
+			return fmt.Errorf("failed to marshal job %s: %w", job.ID, err)
+		}
+		records = append(records, record)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job store: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write job store: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save job store: %w", err)
+	}
+	return nil
+}
+
+// markInterrupted fails a job that the master lost track of
+func (j *MigrationJob) markInterrupted() {
+	j.finishFailed(interruptedError)
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/audit"
 	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/docker"
 	"github.com/artemis/docker-migrate/internal/observability"
@@ -27,21 +28,29 @@ type Master struct {
 	registry     *Registry
 	orchestrator *Orchestrator
 	grpcServer   *GRPCServer
+	tokens       *TokenStore
+	namespaces   *NamespaceStore
+	proxyNonces  *ProxyNonces
+	audit        *CommandAudit
+	schedules    *ScheduleStore
+	securityLog  *audit.Log // Records worker enrollment; nil records nothing
 
 	mu     sync.RWMutex
 	ctx    context.Context
 	cancel context.CancelFunc
 }
 
-// New creates a new master node
+// New creates a new master node. Its background work stops when ctx is
+// cancelled or Stop is called.
 func New(
+	ctx context.Context,
 	cfg *config.Config,
 	dockerClient *docker.Client,
 	cryptoManager *peer.CryptoManager,
 	transferManager *peer.TransferManager,
 	logger *observability.Logger,
 ) (*Master, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 
 	m := &Master{
 		config:          cfg,
@@ -53,14 +62,59 @@ func New(
 		cancel:          cancel,
 	}
 
-	// Initialize registry
-	m.registry = NewRegistry(logger, cfg.Master.WorkerTimeout)
+	// Initialize registry, recording every command it sends to workers
+	audit, err := NewCommandAudit(cfg.DataDir, logger)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open command audit log: %w", err)
+	}
+	m.audit = audit
+	m.registry, err = NewRegistry(cfg.DataDir, logger, cfg.Master.WorkerTimeout, audit)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load worker registry: %w", err)
+	}
 
 	// Initialize orchestrator with the gRPC address for proxy mode
-	m.orchestrator = NewOrchestrator(m.registry, logger, cfg.GRPCAddr)
+	// and the jobs of earlier runs
+	m.proxyNonces = NewProxyNonces()
+	jobs, err := NewJobStore(cfg.DataDir, logger)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open job store: %w", err)
+	}
+	m.orchestrator, err = NewOrchestrator(ctx, m.registry, m.proxyNonces, jobs, logger, cfg.GRPCAddr)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to restore migration jobs: %w", err)
+	}
+	m.orchestrator.imageRegistry = cfg.ImageRegistry
+	m.orchestrator.ipam, err = NewIPAMPool(cfg.DataDir, cfg.Master.IPAMPool, logger)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open ipam pool: %w", err)
+	}
+
+	// Load API tokens guarding the HTTP endpoints
+	m.tokens, err = NewTokenStore(cfg.DataDir, logger)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load api tokens: %w", err)
+	}
+
+	m.namespaces, err = NewNamespaceStore(cfg.DataDir, logger)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load namespaces: %w", err)
+	}
+
+	m.schedules, err = NewScheduleStore(cfg.DataDir, logger)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open schedule store: %w", err)
+	}
 
 	// Initialize gRPC server
-	var err error
 	m.grpcServer, err = NewGRPCServer(m, cryptoManager, logger)
 	if err != nil {
 		cancel()
@@ -70,6 +124,11 @@ func New(
 	return m, nil
 }
 
+// SetAuditLog records worker enrollments in log; call before workers connect
+func (m *Master) SetAuditLog(log *audit.Log) {
+	m.securityLog = log
+}
+
 // RegisterGRPCService registers the MasterService on an existing gRPC server
 func (m *Master) RegisterGRPCService(server *grpc.Server) {
 	m.grpcServer.RegisterOn(server)
@@ -78,6 +137,8 @@ func (m *Master) RegisterGRPCService(server *grpc.Server) {
 
 // StartBackgroundTasks starts background tasks like registry cleanup
 func (m *Master) StartBackgroundTasks(ctx context.Context) {
+	go m.rotateAuthTokens(ctx)
+	go m.runSchedules(ctx)
 	m.registry.StartCleanup(ctx, m.config.Master.WorkerTimeout/2)
 }
 
@@ -87,8 +148,10 @@ func (m *Master) Start(ctx context.Context) error {
 		zap.String("grpc_addr", m.config.GRPCAddr),
 	)
 
-	// Start registry cleanup goroutine
+	// Start registry cleanup, token rotation and schedule goroutines
 	go m.registry.StartCleanup(ctx, m.config.Master.WorkerTimeout/2)
+	go m.rotateAuthTokens(ctx)
+	go m.runSchedules(ctx)
 
 	// Start gRPC server
 	if err := m.grpcServer.Start(m.config.GRPCAddr); err != nil {
@@ -120,9 +183,19 @@ func (m *Master) GetConfig() *config.Config {
 	return m.config
 }
 
-// ValidateEnrollmentToken checks if the token is valid
-func (m *Master) ValidateEnrollmentToken(token string) bool {
-	return m.config.Master != nil && m.config.Master.EnrollmentToken == token
+// Fingerprint returns the fingerprint of the master's certificate, which
+// workers must be given to enroll
+func (m *Master) Fingerprint() string {
+	return m.cryptoManager.GetFingerprint()
+}
+
+// ValidateEnrollmentToken checks if the token is valid and returns the
+// namespace it enrolls workers into: none for the master's own token
+func (m *Master) ValidateEnrollmentToken(token string) (string, bool) {
+	if m.config.Master != nil && m.config.Master.EnrollmentToken == token {
+		return "", true
+	}
+	return m.namespaces.MatchEnrollmentToken(token)
 }
 
 // GenerateWorkerAuthToken generates a new auth token for a worker
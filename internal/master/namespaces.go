@@ -0,0 +1,394 @@
+package master
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// namespaceEnrollmentPrefix marks enrollment tokens that place a worker in a
+// namespace
+const namespaceEnrollmentPrefix = "dmn_"
+
+// namespaceContextKey is where authentication leaves the caller's namespace
+const namespaceContextKey = "api_namespace"
+
+// namespaceNamePattern keeps names usable in URLs, labels and log fields
+var namespaceNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Namespace groups the workers and jobs of one team on a shared master.
+// Workers join one by enrolling with its token, and API tokens and UI users
+// bound to it see nothing outside it.
+type Namespace struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	TokenHash   string    `json:"token_hash,omitempty"` // Only a hash of the enrollment token is stored
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ValidateNamespaceName rejects names that are not DNS labels
+func ValidateNamespaceName(name string) error {
+	if !namespaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid namespace name %q: use up to 63 lowercase letters, digits and '-'", name)
+	}
+	return nil
+}
+
+// NamespaceStore keeps namespaces in namespaces.json in the data directory.
+// The file is shared with the CLI, so it is re-read whenever it changes on
+// disk.
+type NamespaceStore struct {
+	path       string
+	modTime    time.Time
+	namespaces []*Namespace
+	logger     *observability.Logger
+	mu         sync.Mutex
+}
+
+// NewNamespaceStore loads the namespace store from dataDir
+func NewNamespaceStore(dataDir string, logger *observability.Logger) (*NamespaceStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	ns := &NamespaceStore{
+		path:   filepath.Join(dataDir, "namespaces.json"),
+		logger: logger,
+	}
+	if err := ns.reloadLocked(); err != nil {
+		return nil, err
+	}
+	return ns, nil
+}
+
+// reloadLocked re-reads the store if the file changed since it was last read
+func (ns *NamespaceStore) reloadLocked() error {
+	info, err := os.Stat(ns.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat namespace store: %w", err)
+	}
+	if info.ModTime().Equal(ns.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(ns.path)
+	if err != nil {
+		return fmt.Errorf("failed to read namespace store: %w", err)
+	}
+	var namespaces []*Namespace
+	if err := json.Unmarshal(data, &namespaces); err != nil {
+		return fmt.Errorf("failed to parse namespace store: %w", err)
+	}
+
+	ns.namespaces = namespaces
+	ns.modTime = info.ModTime()
+	return nil
+}
+
+func (ns *NamespaceStore) saveLocked() error {
+	data, err := json.MarshalIndent(ns.namespaces, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal namespace store: %w", err)
+	}
+
+	tmpPath := ns.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write namespace store: %w", err)
+	}
+	if err := os.Rename(tmpPath, ns.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save namespace store: %w", err)
+	}
+
+	if info, err := os.Stat(ns.path); err == nil {
+		ns.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// findLocked returns the namespace called name, or nil
+func (ns *NamespaceStore) findLocked(name string) *Namespace {
+	for _, n := range ns.namespaces {
+		if n.Name == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// Create adds a namespace and returns the plaintext of its enrollment token
+func (ns *NamespaceStore) Create(name, description string) (string, *Namespace, error) {
+	if err := ValidateNamespaceName(name); err != nil {
+		return "", nil, err
+	}
+	token, err := newEnrollmentToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if err := ns.reloadLocked(); err != nil {
+		return "", nil, err
+	}
+	if ns.findLocked(name) != nil {
+		return "", nil, fmt.Errorf("namespace already exists: %s", name)
+	}
+
+	namespace := &Namespace{
+		Name:        name,
+		Description: description,
+		TokenHash:   hashAPIToken(token),
+		CreatedAt:   time.Now(),
+	}
+	ns.namespaces = append(ns.namespaces, namespace)
+	if err := ns.saveLocked(); err != nil {
+		return "", nil, err
+	}
+
+	ns.logger.Info("namespace created", zap.String("namespace", name))
+
+	copied := *namespace
+	return token, &copied, nil
+}
+
+// RotateToken replaces a namespace's enrollment token and returns the new
+// plaintext. Enrolled workers stay enrolled; only new enrollments need it.
+func (ns *NamespaceStore) RotateToken(name string) (string, error) {
+	token, err := newEnrollmentToken()
+	if err != nil {
+		return "", err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if err := ns.reloadLocked(); err != nil {
+		return "", err
+	}
+	namespace := ns.findLocked(name)
+	if namespace == nil {
+		return "", fmt.Errorf("namespace not found: %s", name)
+	}
+	namespace.TokenHash = hashAPIToken(token)
+	if err := ns.saveLocked(); err != nil {
+		return "", err
+	}
+
+	ns.logger.Info("namespace enrollment token rotated", zap.String("namespace", name))
+	return token, nil
+}
+
+// Delete removes a namespace. Its workers and jobs keep their namespace and
+// stay visible to unscoped callers.
+func (ns *NamespaceStore) Delete(name string) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if err := ns.reloadLocked(); err != nil {
+		return err
+	}
+	for i, n := range ns.namespaces {
+		if n.Name != name {
+			continue
+		}
+		ns.namespaces = append(ns.namespaces[:i], ns.namespaces[i+1:]...)
+		if err := ns.saveLocked(); err != nil {
+			return err
+		}
+		ns.logger.Info("namespace deleted", zap.String("namespace", name))
+		return nil
+	}
+	return fmt.Errorf("namespace not found: %s", name)
+}
+
+// List returns copies of all namespaces, by name, without their token hashes
+func (ns *NamespaceStore) List() []Namespace {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if err := ns.reloadLocked(); err != nil {
+		ns.logger.Warn("failed to reload namespace store", zap.Error(err))
+	}
+
+	namespaces := make([]Namespace, 0, len(ns.namespaces))
+	for _, n := range ns.namespaces {
+		copied := *n
+		copied.TokenHash = ""
+		namespaces = append(namespaces, copied)
+	}
+	sort.Slice(namespaces, func(i, k int) bool {
+		return namespaces[i].Name < namespaces[k].Name
+	})
+	return namespaces
+}
+
+// Exists reports whether a namespace called name exists
+func (ns *NamespaceStore) Exists(name string) bool {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if err := ns.reloadLocked(); err != nil {
+		ns.logger.Warn("failed to reload namespace store", zap.Error(err))
+	}
+	return ns.findLocked(name) != nil
+}
+
+// MatchEnrollmentToken returns the namespace whose enrollment token is token
+func (ns *NamespaceStore) MatchEnrollmentToken(token string) (string, bool) {
+	if !strings.HasPrefix(token, namespaceEnrollmentPrefix) {
+		return "", false
+	}
+	hash := hashAPIToken(token)
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if err := ns.reloadLocked(); err != nil {
+		ns.logger.Warn("failed to reload namespace store", zap.Error(err))
+	}
+	for _, n := range ns.namespaces {
+		if subtle.ConstantTimeCompare([]byte(n.TokenHash), []byte(hash)) == 1 {
+			return n.Name, true
+		}
+	}
+	return "", false
+}
+
+func newEnrollmentToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate enrollment token: %w", err)
+	}
+	return namespaceEnrollmentPrefix + hex.EncodeToString(secret), nil
+}
+
+// SetCallerNamespace confines an authenticated caller to a namespace
+func SetCallerNamespace(c *gin.Context, namespace string) {
+	if namespace != "" {
+		c.Set(namespaceContextKey, namespace)
+	}
+}
+
+// CallerNamespace returns the namespace the caller is confined to, or ""
+// for callers that see every namespace
+func CallerNamespace(c *gin.Context) string {
+	return c.GetString(namespaceContextKey)
+}
+
+// CanSee reports whether the caller may see a worker or job in namespace
+func CanSee(c *gin.Context, namespace string) bool {
+	scope := CallerNamespace(c)
+	return scope == "" || scope == namespace
+}
+
+// RequireUnscoped guards a route that spans namespaces, such as managing
+// credentials, from callers confined to one
+func (m *Master) RequireUnscoped() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scope := CallerNamespace(c); scope != "" {
+			m.logger.Warn("rejected namespaced caller on unscoped route",
+				zap.String("namespace", scope),
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":     "not available to callers confined to a namespace",
+				"namespace": scope,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterNamespaceRoutes registers namespace management routes
+func (m *Master) RegisterNamespaceRoutes(rg *gin.RouterGroup) {
+	admin := m.RequireRole(RoleAdmin)
+	unscoped := m.RequireUnscoped()
+	rg.GET("/namespaces", m.listNamespaces)
+	rg.POST("/namespaces", admin, unscoped, m.RequireTOTP(), m.createNamespace)
+	rg.DELETE("/namespaces/:name", admin, unscoped, m.RequireTOTP(), m.deleteNamespace)
+	rg.POST("/namespaces/:name/enrollment-token/regenerate", admin, m.RequireTOTP(), m.regenerateNamespaceToken)
+}
+
+// listNamespaces lists every namespace, or only the caller's own
+func (m *Master) listNamespaces(c *gin.Context) {
+	response := make([]Namespace, 0)
+	for _, n := range m.namespaces.List() {
+		if CanSee(c, n.Name) {
+			response = append(response, n)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"namespaces": response})
+}
+
+func (m *Master) createNamespace(c *gin.Context) {
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, namespace, err := m.namespaces.Create(req.Name, req.Description)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	namespace.TokenHash = ""
+
+	c.JSON(http.StatusCreated, gin.H{
+		"enrollment_token": token,
+		"namespace":        namespace,
+	})
+}
+
+func (m *Master) deleteNamespace(c *gin.Context) {
+	if err := m.namespaces.Delete(c.Param("name")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "namespace deleted"})
+}
+
+// regenerateNamespaceToken replaces a namespace's enrollment token; admins
+// confined to the namespace may replace its token too
+func (m *Master) regenerateNamespaceToken(c *gin.Context) {
+	name := c.Param("name")
+	if !CanSee(c, name) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "namespace not found: " + name})
+		return
+	}
+	token, err := m.namespaces.RotateToken(name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enrollment_token": token})
+}
@@ -3,38 +3,86 @@ package master
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/docker"
 	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/artemis/docker-migrate/internal/peer"
 	pb "github.com/artemis/docker-migrate/proto"
 	"go.uber.org/zap"
 )
 
 // MigrationJob represents a migration between two workers
 type MigrationJob struct {
-	ID             string
-	SourceWorkerID string
-	TargetWorkerID string
-
-	ContainerIDs []string
-	ImageIDs     []string
-	VolumeNames  []string
-	NetworkIDs   []string
-
-	Mode         pb.MigrationMode
-	Strategy     pb.MigrationStrategy
-	TransferMode pb.TransferMode
-
-	Status           MigrationJobStatus
-	Phase            pb.MigrationPhase
-	Progress         float32
-	BytesTransferred int64
-	TotalBytes       int64
-
-	StartedAt   time.Time
-	CompletedAt time.Time
-	Error       string
+	ID             string `json:"id"`
+	SourceWorkerID string `json:"source_worker_id"`
+	TargetWorkerID string `json:"target_worker_id"`
+
+	ContainerIDs []string `json:"container_ids,omitempty"`
+	ImageIDs     []string `json:"image_ids,omitempty"`
+	VolumeNames  []string `json:"volume_names,omitempty"`
+	NetworkIDs   []string `json:"network_ids,omitempty"`
+
+	Mode         pb.MigrationMode     `json:"mode"`
+	Strategy     pb.MigrationStrategy `json:"strategy"`
+	TransferMode pb.TransferMode      `json:"transfer_mode"`
+	ImageMode    string               `json:"image_mode,omitempty"` // stream or registry
+
+	// TransferFallback is why an auto-mode job's direct connection failed
+	// and its transfer moved to the master's proxy
+	TransferFallback string `json:"transfer_fallback,omitempty"`
+
+	// Namespace is shared by both workers; jobs between namespaces have none
+	// and are visible only to unscoped callers
+	Namespace string `json:"namespace,omitempty"`
+
+	Status           MigrationJobStatus `json:"status"`
+	Phase            pb.MigrationPhase  `json:"phase"`
+	Progress         float32            `json:"progress"`
+	BytesTransferred int64              `json:"bytes_transferred"`
+	TotalBytes       int64              `json:"total_bytes"`
+
+	// Set when a worker or operator requested the migration and an admin
+	// must approve it
+	RequestedBy string `json:"requested_by,omitempty"`
+	Note        string `json:"note,omitempty"`
+
+	// ReviewedBy approved or rejected a requested migration, at ReviewedAt
+	ReviewedBy string    `json:"reviewed_by,omitempty"`
+	ReviewedAt time.Time `json:"reviewed_at,omitempty"`
+
+	// IssuedBy started or approved the migration; its commands are audited
+	// under this name
+	IssuedBy string `json:"issued_by,omitempty"`
+
+	// Retry re-dispatches the job when it fails; nil never retries. Attempt
+	// counts dispatches so far.
+	Retry       *RetryPolicy `json:"retry,omitempty"`
+	Attempt     int          `json:"attempt"`
+	NextRetryAt time.Time    `json:"next_retry_at,omitempty"`
+
+	// CompletedResources the target has confirmed, as "volume:<name>" or
+	// "image:<id>"; retries skip them
+	CompletedResources []string `json:"completed_resources,omitempty"`
+
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	Error       string    `json:"error,omitempty"`
+
+	// LastProgressAt is when a worker last reported progress. Worker
+	// timestamps are converted to the master's clock.
+	LastProgressAt time.Time `json:"last_progress_at,omitempty"`
+
+	// EstimatedEnd projects when the transfer will finish from the rate of
+	// the worker's reports, on the master's clock
+	EstimatedEnd time.Time `json:"estimated_end,omitempty"`
+
+	// ClockSkewMs is how far the source's clock was ahead of the target's
+	// when the job was dispatched, if both workers report their time
+	ClockSkewMs *int64 `json:"clock_skew_ms,omitempty"`
 
 	mu sync.RWMutex
 }
@@ -48,48 +96,268 @@ const (
 	MigrationStatusCompleted MigrationJobStatus = "completed"
 	MigrationStatusFailed    MigrationJobStatus = "failed"
 	MigrationStatusCancelled MigrationJobStatus = "cancelled"
+	MigrationStatusAwaiting  MigrationJobStatus = "awaiting_approval"
+	MigrationStatusRejected  MigrationJobStatus = "rejected"
+	MigrationStatusRetrying  MigrationJobStatus = "retrying"
+)
+
+// How images reach the target
+const (
+	ImageModeStream   = "stream"   // Streamed over the transfer connection
+	ImageModeRegistry = "registry" // Pushed to the intermediary registry and pulled by the target
 )
 
 // Orchestrator coordinates migrations between workers
 type Orchestrator struct {
 	registry *Registry
+	nonces   *ProxyNonces
 	logger   *observability.Logger
-	grpcAddr string // Master's gRPC address for proxy mode
+	grpcAddr string          // Master's gRPC address for proxy mode
+	ctx      context.Context // Migrations run under this, not the request starting them
 
 	migrations map[string]*MigrationJob
 	mu         sync.RWMutex
+
+	// store persists jobs when their status changes; nil keeps them in memory
+	store  *JobStore
+	saveMu sync.Mutex
+
+	// imageRegistry is the intermediary registry for image_mode "registry"
+	imageRegistry *config.RegistryConfig
+
+	// ipam allocates subnets for recreated bridge networks; nil lets the
+	// target's Docker pick them
+	ipam *IPAMPool
 }
 
-// NewOrchestrator creates a new migration orchestrator
-func NewOrchestrator(registry *Registry, logger *observability.Logger, grpcAddr string) *Orchestrator {
-	return &Orchestrator{
+// NewOrchestrator creates a new migration orchestrator, restoring the jobs in
+// store if it is not nil
+func NewOrchestrator(ctx context.Context, registry *Registry, nonces *ProxyNonces, store *JobStore, logger *observability.Logger, grpcAddr string) (*Orchestrator, error) {
+	o := &Orchestrator{
 		registry:   registry,
+		nonces:     nonces,
 		logger:     logger,
 		grpcAddr:   grpcAddr,
+		ctx:        ctx,
 		migrations: make(map[string]*MigrationJob),
+		store:      store,
+	}
+
+	if store != nil {
+		jobs, err := store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range jobs {
+			o.migrations[job.ID] = job
+		}
+		if len(jobs) > 0 {
+			o.logger.Info("restored migration jobs", zap.Int("count", len(jobs)))
+		}
+	}
+
+	return o, nil
+}
+
+// persist saves every job. Progress is not saved on its own, so a restart
+// loses only the progress since the last status change.
+func (o *Orchestrator) persist() {
+	if o.store == nil {
+		return
+	}
+
+	// Serialised so an older job list never overwrites a newer one
+	o.saveMu.Lock()
+	defer o.saveMu.Unlock()
+
+	if err := o.store.Save(o.ListMigrations()); err != nil {
+		o.logger.Warn("failed to save migration jobs", zap.Error(err))
 	}
 }
 
 // StartMigration initiates a migration between two workers
 func (o *Orchestrator) StartMigration(ctx context.Context, req *MigrationRequest) (*MigrationJob, error) {
-	// Validate workers exist and are online
-	source, ok := o.registry.Get(req.SourceWorkerID)
+	job, source, target, err := o.newJob(req)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	o.migrations[job.ID] = job
+	o.mu.Unlock()
+	o.persist()
+
+	o.logger.Info("starting migration",
+		zap.String("migration_id", job.ID),
+		zap.String("source", source.Name),
+		zap.String("target", target.Name),
+	)
+
+	// Start migration in background; the request's context ends with the HTTP call
+	go o.executeMigration(o.ctx, job, source, target)
+
+	return job, nil
+}
+
+// RequestMigration records a migration a worker or operator requested, which
+// does not run until an admin approves it
+func (o *Orchestrator) RequestMigration(req *MigrationRequest, requestedBy, note string) (*MigrationJob, error) {
+	job, source, target, err := o.newJob(req)
+	if err != nil {
+		return nil, err
+	}
+	job.Status = MigrationStatusAwaiting
+	job.RequestedBy = requestedBy
+	job.Note = note
+
+	o.mu.Lock()
+	o.migrations[job.ID] = job
+	o.mu.Unlock()
+	o.persist()
+
+	o.logger.Info("migration requested, awaiting approval",
+		zap.String("migration_id", job.ID),
+		zap.String("requested_by", requestedBy),
+		zap.String("source", source.Name),
+		zap.String("target", target.Name),
+	)
+
+	return job, nil
+}
+
+// ApproveMigration starts a requested migration; issuer is the approver
+func (o *Orchestrator) ApproveMigration(migrationID, issuer string) (*MigrationJob, error) {
+	o.mu.RLock()
+	job, ok := o.migrations[migrationID]
+	o.mu.RUnlock()
+
 	if !ok {
-		return nil, fmt.Errorf("source worker not found: %s", req.SourceWorkerID)
+		return nil, fmt.Errorf("migration not found: %s", migrationID)
+	}
+
+	job.mu.Lock()
+	if job.Status != MigrationStatusAwaiting {
+		job.mu.Unlock()
+		return nil, fmt.Errorf("migration is not awaiting approval: %s", job.Status)
 	}
-	if !o.registry.IsOnline(req.SourceWorkerID) {
-		return nil, fmt.Errorf("source worker is offline: %s", req.SourceWorkerID)
+
+	// Workers may have gone away while the request waited
+	source, target, err := o.onlineWorkers(job.SourceWorkerID, job.TargetWorkerID)
+	if err != nil {
+		job.mu.Unlock()
+		return nil, err
 	}
 
-	target, ok := o.registry.Get(req.TargetWorkerID)
+	job.Status = MigrationStatusPending
+	job.StartedAt = time.Now()
+	job.IssuedBy = issuer
+	job.ReviewedBy = issuer
+	job.ReviewedAt = job.StartedAt
+	job.mu.Unlock()
+	o.persist()
+
+	o.logger.Info("migration approved",
+		zap.String("migration_id", job.ID),
+		zap.String("approved_by", issuer),
+		zap.String("source", source.Name),
+		zap.String("target", target.Name),
+	)
+
+	// The approving request's context ends with the HTTP call, so don't inherit it
+	go o.executeMigration(o.ctx, job, source, target)
+
+	return job, nil
+}
+
+// RejectMigration declines a requested migration; issuer is the reviewer
+func (o *Orchestrator) RejectMigration(migrationID, reason, issuer string) error {
+	o.mu.RLock()
+	job, ok := o.migrations[migrationID]
+	o.mu.RUnlock()
+
 	if !ok {
-		return nil, fmt.Errorf("target worker not found: %s", req.TargetWorkerID)
+		return fmt.Errorf("migration not found: %s", migrationID)
+	}
+
+	job.mu.Lock()
+	if job.Status != MigrationStatusAwaiting {
+		job.mu.Unlock()
+		return fmt.Errorf("migration is not awaiting approval: %s", job.Status)
+	}
+	job.Status = MigrationStatusRejected
+	job.Error = reason
+	job.CompletedAt = time.Now()
+	job.ReviewedBy = issuer
+	job.ReviewedAt = job.CompletedAt
+	job.mu.Unlock()
+	o.persist()
+
+	o.logger.Info("migration rejected",
+		zap.String("migration_id", job.ID),
+		zap.String("rejected_by", issuer),
+		zap.String("reason", reason),
+	)
+
+	return nil
+}
+
+// newJob validates a request and builds its job without registering it
+func (o *Orchestrator) newJob(req *MigrationRequest) (*MigrationJob, *WorkerInfo, *WorkerInfo, error) {
+	source, target, err := o.onlineWorkers(req.SourceWorkerID, req.TargetWorkerID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// Workers outside the caller's namespace are as good as missing
+	if req.Namespace != "" {
+		if source.Namespace != req.Namespace {
+			return nil, nil, nil, fmt.Errorf("source worker not found: %s", req.SourceWorkerID)
+		}
+		if target.Namespace != req.Namespace {
+			return nil, nil, nil, fmt.Errorf("target worker not found: %s", req.TargetWorkerID)
+		}
 	}
-	if !o.registry.IsOnline(req.TargetWorkerID) {
-		return nil, fmt.Errorf("target worker is offline: %s", req.TargetWorkerID)
+	var namespace string
+	if source.Namespace == target.Namespace {
+		namespace = source.Namespace
+	}
+
+	// Outbound-only workers cannot accept connections, so relay through the master
+	transferMode := req.TransferMode
+	if (source.OutboundOnly || target.OutboundOnly) && transferMode != pb.TransferMode_TRANSFER_MODE_PROXY {
+		if transferMode == pb.TransferMode_TRANSFER_MODE_DIRECT {
+			o.logger.Info("forcing proxy transfer for outbound-only worker",
+				zap.String("source", source.Name),
+				zap.String("target", target.Name),
+			)
+		}
+		transferMode = pb.TransferMode_TRANSFER_MODE_PROXY
+	}
+	// Auto mode relies on both workers switching to the proxy together;
+	// older ones just connect directly
+	if transferMode == pb.TransferMode_TRANSFER_MODE_AUTO &&
+		(!peer.SupportsFeature(source.Protocol, peer.FeatureTransferFallback) ||
+			!peer.SupportsFeature(target.Protocol, peer.FeatureTransferFallback)) {
+		o.logger.Info("workers cannot fall back to the proxy; transferring directly",
+			zap.String("source", source.Name),
+			zap.String("target", target.Name),
+		)
+		transferMode = pb.TransferMode_TRANSFER_MODE_DIRECT
+	}
+
+	imageMode, err := o.imageMode(req.ImageMode, transferMode)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(req.NetworkIDs) > 0 && !peer.SupportsFeature(target.Protocol, peer.FeatureNetworkRecreation) {
+		return nil, nil, nil, fmt.Errorf("target worker speaks protocol v%d, which cannot recreate networks; upgrade it", target.Protocol)
+	}
+	if req.Retry != nil && req.Retry.MaxAttempts < 1 {
+		return nil, nil, nil, fmt.Errorf("retry max_attempts must be at least 1")
+	}
+	if err := o.checkMigratedCopies(req, source, target); err != nil {
+		return nil, nil, nil, err
 	}
 
-	// Create migration job
 	job := &MigrationJob{
 		ID:             generateMigrationID(),
 		SourceWorkerID: req.SourceWorkerID,
@@ -100,32 +368,112 @@ func (o *Orchestrator) StartMigration(ctx context.Context, req *MigrationRequest
 		NetworkIDs:     req.NetworkIDs,
 		Mode:           req.Mode,
 		Strategy:       req.Strategy,
-		TransferMode:   req.TransferMode,
+		TransferMode:   transferMode,
+		ImageMode:      imageMode,
+		Namespace:      namespace,
+		Retry:          req.Retry,
 		Status:         MigrationStatusPending,
 		Phase:          pb.MigrationPhase_MIGRATION_PHASE_INITIALIZING,
 		StartedAt:      time.Now(),
+		IssuedBy:       req.Issuer,
 	}
 
-	o.mu.Lock()
-	o.migrations[job.ID] = job
-	o.mu.Unlock()
+	// Sized up front from inventory so progress has a total before the
+	// source reports one
+	if estimate, err := o.EstimateMigration(req); err == nil {
+		job.TotalBytes = estimate.TotalBytes
+	}
 
-	o.logger.Info("starting migration",
-		zap.String("migration_id", job.ID),
-		zap.String("source", source.Name),
-		zap.String("target", target.Name),
-	)
+	return job, source, target, nil
+}
 
-	// Start migration in background
-	go o.executeMigration(ctx, job, source, target)
+// imageMode validates a requested image mode, defaulting to stream
+func (o *Orchestrator) imageMode(mode string, transferMode pb.TransferMode) (string, error) {
+	switch mode {
+	case "", ImageModeStream:
+		return ImageModeStream, nil
+	case ImageModeRegistry:
+		if o.imageRegistry == nil || o.imageRegistry.Address == "" {
+			return "", fmt.Errorf("image mode registry needs image_registry in the master config")
+		}
+		// The source asks the target to pull over their direct connection
+		if transferMode == pb.TransferMode_TRANSFER_MODE_PROXY {
+			return "", fmt.Errorf("image mode registry needs a direct transfer between the workers")
+		}
+		return ImageModeRegistry, nil
+	default:
+		return "", fmt.Errorf("unknown image mode %q (expected stream or registry)", mode)
+	}
+}
 
-	return job, nil
+// imageRegistryForWorkers returns the intermediary registry as sent to the
+// source worker
+func (o *Orchestrator) imageRegistryForWorkers() (*pb.ImageRegistry, error) {
+	reg := o.imageRegistry
+	server, _, _ := strings.Cut(reg.Address, "/")
+	auth, err := docker.EncodeRegistryAuth(server, reg.Username, reg.Password)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ImageRegistry{Address: reg.Address, RegistryAuth: auth}, nil
+}
+
+// workerClockSkew returns how far the job's source clock is ahead of its
+// target's, warning when the difference is large enough to make their logs
+// hard to line up. It returns nil unless both skews are known.
+func (o *Orchestrator) workerClockSkew(job *MigrationJob) *int64 {
+	sourceSkew, ok := o.registry.ClockSkew(job.SourceWorkerID)
+	if !ok {
+		return nil
+	}
+	targetSkew, ok := o.registry.ClockSkew(job.TargetWorkerID)
+	if !ok {
+		return nil
+	}
+
+	skew := sourceSkew - targetSkew
+	if peer.ClockSkewExceeded(skew) {
+		o.logger.Warn("source and target clocks differ; compare their logs with care",
+			zap.String("migration_id", job.ID),
+			zap.Duration("clock_skew", skew),
+		)
+	}
+	skewMs := skew.Milliseconds()
+	return &skewMs
+}
+
+// onlineWorkers validates that both workers exist and are online
+func (o *Orchestrator) onlineWorkers(sourceID, targetID string) (*WorkerInfo, *WorkerInfo, error) {
+	source, ok := o.registry.Get(sourceID)
+	if !ok {
+		return nil, nil, fmt.Errorf("source worker not found: %s", sourceID)
+	}
+	if !o.registry.IsOnline(sourceID) {
+		return nil, nil, fmt.Errorf("source worker is offline: %s", sourceID)
+	}
+
+	target, ok := o.registry.Get(targetID)
+	if !ok {
+		return nil, nil, fmt.Errorf("target worker not found: %s", targetID)
+	}
+	if !o.registry.IsOnline(targetID) {
+		return nil, nil, fmt.Errorf("target worker is offline: %s", targetID)
+	}
+
+	return source, target, nil
 }
 
 func (o *Orchestrator) executeMigration(ctx context.Context, job *MigrationJob, source, target *WorkerInfo) {
 	job.mu.Lock()
 	job.Status = MigrationStatusRunning
+	job.Attempt++
+	job.Error = ""
+	job.ClockSkewMs = o.workerClockSkew(job)
+	// A retry only sends what the target has not confirmed
+	volumeNames := job.remaining("volume", job.VolumeNames)
+	imageIDs := job.remaining("image", job.ImageIDs)
 	job.mu.Unlock()
+	o.persist()
 
 	// Determine transfer mode
 	transferMode := job.TransferMode
@@ -133,10 +481,21 @@ func (o *Orchestrator) executeMigration(ctx context.Context, job *MigrationJob,
 		transferMode = pb.TransferMode_TRANSFER_MODE_DIRECT
 	}
 
-	// Get proxy address for proxy mode
-	proxyAddr := ""
+	// Get proxy address for proxy mode, and the one-time nonces each
+	// worker's handshake must present
+	proxyAddr, sourceNonce, targetNonce := "", "", ""
 	if transferMode == pb.TransferMode_TRANSFER_MODE_PROXY {
 		proxyAddr = o.getProxyAddress()
+		sourceNonce = o.nonces.Issue(job.ID, job.SourceWorkerID, pb.ProxyRole_PROXY_ROLE_SOURCE)
+		targetNonce = o.nonces.Issue(job.ID, job.TargetWorkerID, pb.ProxyRole_PROXY_ROLE_TARGET)
+	}
+
+	// Networks are planned before the target hears of the job, so it can
+	// create them before any data arrives
+	networks, err := o.networkSpecs(job, source)
+	if err != nil {
+		o.failMigration(job, err)
+		return
 	}
 
 	// Step 1: Tell target to prepare for incoming migration
@@ -152,21 +511,32 @@ func (o *Orchestrator) executeMigration(ctx context.Context, job *MigrationJob,
 					SourceAddress:     source.GRPCAddress,
 					SourceFingerprint: source.TLSFingerprint,
 					ContainerIds:      job.ContainerIDs,
-					ImageIds:          job.ImageIDs,
-					VolumeNames:       job.VolumeNames,
+					ImageIds:          imageIDs,
+					VolumeNames:       volumeNames,
 					NetworkIds:        job.NetworkIDs,
 					TransferMode:      transferMode,
 					ProxyAddress:      proxyAddr,
+					ProxyNonce:        targetNonce,
+					Networks:          networks,
 				},
 			},
 		},
 	}
 
-	if err := o.registry.SendCommand(job.TargetWorkerID, acceptCmd); err != nil {
+	if err := o.registry.SendCommand(job.TargetWorkerID, acceptCmd, job.IssuedBy); err != nil {
 		o.failMigration(job, fmt.Errorf("failed to notify target: %w", err))
 		return
 	}
 
+	var imageRegistry *pb.ImageRegistry
+	if job.ImageMode == ImageModeRegistry {
+		var err error
+		if imageRegistry, err = o.imageRegistryForWorkers(); err != nil {
+			o.failMigration(job, err)
+			return
+		}
+	}
+
 	// Step 2: Tell source to start sending
 	startCmd := &pb.MasterCommand{
 		CommandId: fmt.Sprintf("start-%s", job.ID),
@@ -180,19 +550,21 @@ func (o *Orchestrator) executeMigration(ctx context.Context, job *MigrationJob,
 					TargetAddress:     target.GRPCAddress,
 					TargetFingerprint: target.TLSFingerprint,
 					ContainerIds:      job.ContainerIDs,
-					ImageIds:          job.ImageIDs,
-					VolumeNames:       job.VolumeNames,
+					ImageIds:          imageIDs,
+					VolumeNames:       volumeNames,
 					NetworkIds:        job.NetworkIDs,
 					Mode:              job.Mode,
 					Strategy:          job.Strategy,
 					TransferMode:      transferMode,
 					ProxyAddress:      proxyAddr,
+					ProxyNonce:        sourceNonce,
+					ImageRegistry:     imageRegistry,
 				},
 			},
 		},
 	}
 
-	if err := o.registry.SendCommand(job.SourceWorkerID, startCmd); err != nil {
+	if err := o.registry.SendCommand(job.SourceWorkerID, startCmd, job.IssuedBy); err != nil {
 		o.failMigration(job, fmt.Errorf("failed to notify source: %w", err))
 		return
 	}
@@ -207,22 +579,98 @@ func (o *Orchestrator) getProxyAddress() string {
 	return o.grpcAddr
 }
 
-func (o *Orchestrator) failMigration(job *MigrationJob, err error) {
+// FallBackToProxy moves a running auto-mode migration onto the master's
+// proxy after its source, workerID, failed to reach the target directly.
+// Both workers are sent fresh proxy nonces; the target is told first so it
+// is listening by the time the source resends. The job stays in proxy mode
+// for any retries.
+func (o *Orchestrator) FallBackToProxy(workerID, migrationID, reason string) error {
+	o.mu.RLock()
+	job, ok := o.migrations[migrationID]
+	o.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("migration not found: %s", migrationID)
+	}
+
 	job.mu.Lock()
-	job.Status = MigrationStatusFailed
-	job.Phase = pb.MigrationPhase_MIGRATION_PHASE_FAILED
-	job.Error = err.Error()
-	job.CompletedAt = time.Now()
+	switch {
+	case job.SourceWorkerID != workerID:
+		job.mu.Unlock()
+		return fmt.Errorf("worker %s is not the source of migration %s", workerID, migrationID)
+	case job.Status != MigrationStatusRunning:
+		status := job.Status
+		job.mu.Unlock()
+		return fmt.Errorf("migration is %s", status)
+	case job.TransferMode != pb.TransferMode_TRANSFER_MODE_AUTO:
+		job.mu.Unlock()
+		return fmt.Errorf("migration was started with transfer mode %s", transferModeToString(job.TransferMode))
+	case job.ImageMode == ImageModeRegistry:
+		job.mu.Unlock()
+		return fmt.Errorf("image mode registry needs a direct transfer between the workers")
+	}
+	job.TransferMode = pb.TransferMode_TRANSFER_MODE_PROXY
+	job.TransferFallback = reason
 	job.mu.Unlock()
+	o.persist()
 
-	o.logger.Error("migration failed",
-		zap.String("migration_id", job.ID),
-		zap.Error(err),
+	o.logger.Warn("direct transfer failed, relaying through the master",
+		zap.String("migration_id", migrationID),
+		zap.String("reason", reason),
 	)
+
+	proxyAddr := o.getProxyAddress()
+	targetCmd := &pb.MasterCommand{
+		CommandId: fmt.Sprintf("fallback-target-%s", migrationID),
+		Payload: &pb.MasterCommand_ProxyFallback{
+			ProxyFallback: &pb.ProxyFallbackCommand{
+				MigrationId:  migrationID,
+				ProxyAddress: proxyAddr,
+				ProxyNonce:   o.nonces.Issue(migrationID, job.TargetWorkerID, pb.ProxyRole_PROXY_ROLE_TARGET),
+			},
+		},
+	}
+	if err := o.registry.SendCommand(job.TargetWorkerID, targetCmd, job.IssuedBy); err != nil {
+		o.nonces.Revoke(migrationID)
+		return fmt.Errorf("failed to notify target: %w", err)
+	}
+
+	sourceCmd := &pb.MasterCommand{
+		CommandId: fmt.Sprintf("fallback-source-%s", migrationID),
+		Payload: &pb.MasterCommand_ProxyFallback{
+			ProxyFallback: &pb.ProxyFallbackCommand{
+				MigrationId:  migrationID,
+				ProxyAddress: proxyAddr,
+				ProxyNonce:   o.nonces.Issue(migrationID, job.SourceWorkerID, pb.ProxyRole_PROXY_ROLE_SOURCE),
+			},
+		},
+	}
+	if err := o.registry.SendCommand(job.SourceWorkerID, sourceCmd, job.IssuedBy); err != nil {
+		o.nonces.Revoke(migrationID)
+		return fmt.Errorf("failed to notify source: %w", err)
+	}
+	return nil
+}
+
+func (o *Orchestrator) failMigration(job *MigrationJob, err error) {
+	job.mu.Lock()
+	retrying := o.scheduleRetry(job, err.Error())
+	if !retrying {
+		job.finishFailed(err.Error())
+	}
+	job.mu.Unlock()
+	o.nonces.Revoke(job.ID)
+	o.persist()
+
+	if !retrying {
+		o.logger.Error("migration failed",
+			zap.String("migration_id", job.ID),
+			zap.Error(err),
+		)
+	}
 }
 
 // UpdateProgress updates migration progress from worker reports
-func (o *Orchestrator) UpdateProgress(migrationID string, progress *pb.MigrationProgress) {
+func (o *Orchestrator) UpdateProgress(migrationID string, progress *pb.MigrationProgress, reportedAt time.Time) {
 	o.mu.RLock()
 	job, ok := o.migrations[migrationID]
 	o.mu.RUnlock()
@@ -235,12 +683,17 @@ func (o *Orchestrator) UpdateProgress(migrationID string, progress *pb.Migration
 	job.Phase = progress.Phase
 	job.Progress = progress.Progress
 	job.BytesTransferred = progress.BytesTransferred
-	job.TotalBytes = progress.TotalBytes
+	if progress.TotalBytes > 0 {
+		job.TotalBytes = progress.TotalBytes
+	}
+	job.LastProgressAt = reportedAt
+	job.EstimatedEnd = peer.EstimateEnd(job.StartedAt, reportedAt, job.BytesTransferred, job.TotalBytes)
 	job.mu.Unlock()
 }
 
-// CompleteMigration marks a migration as complete
-func (o *Orchestrator) CompleteMigration(migrationID string, complete *pb.MigrationComplete) {
+// CompleteMigration marks a migration as complete at reportedAt, the
+// worker's report time on the master's clock
+func (o *Orchestrator) CompleteMigration(migrationID string, complete *pb.MigrationComplete, reportedAt time.Time) {
 	o.mu.RLock()
 	job, ok := o.migrations[migrationID]
 	o.mu.RUnlock()
@@ -250,26 +703,36 @@ func (o *Orchestrator) CompleteMigration(migrationID string, complete *pb.Migrat
 	}
 
 	job.mu.Lock()
+	// A second report for the same attempt, such as the source failing
+	// after the target refused, changes nothing
+	if job.Status != MigrationStatusRunning && job.Status != MigrationStatusPending {
+		job.mu.Unlock()
+		return
+	}
+	job.recordCompleted(complete.ResourcesMigrated)
+	job.BytesTransferred = complete.BytesTransferred
+	retrying := false
 	if complete.Success {
 		job.Status = MigrationStatusCompleted
 		job.Phase = pb.MigrationPhase_MIGRATION_PHASE_COMPLETE
-	} else {
-		job.Status = MigrationStatusFailed
-		job.Phase = pb.MigrationPhase_MIGRATION_PHASE_FAILED
-		job.Error = complete.Error
+		job.CompletedAt = reportedAt
+	} else if retrying = o.scheduleRetry(job, complete.Error); !retrying {
+		job.finishFailed(complete.Error)
 	}
-	job.CompletedAt = time.Now()
-	job.BytesTransferred = complete.BytesTransferred
 	job.mu.Unlock()
-
-	o.logger.Info("migration completed",
-		zap.String("migration_id", migrationID),
-		zap.Bool("success", complete.Success),
-	)
+	o.nonces.Revoke(migrationID)
+	o.persist()
+
+	if !retrying {
+		o.logger.Info("migration completed",
+			zap.String("migration_id", migrationID),
+			zap.Bool("success", complete.Success),
+		)
+	}
 }
 
-// CancelMigration cancels a running migration
-func (o *Orchestrator) CancelMigration(migrationID string, reason string) error {
+// CancelMigration cancels a running migration on behalf of issuer
+func (o *Orchestrator) CancelMigration(migrationID, reason, issuer string) error {
 	o.mu.RLock()
 	job, ok := o.migrations[migrationID]
 	o.mu.RUnlock()
@@ -279,15 +742,19 @@ func (o *Orchestrator) CancelMigration(migrationID string, reason string) error
 	}
 
 	job.mu.Lock()
-	if job.Status != MigrationStatusRunning && job.Status != MigrationStatusPending {
+	if job.Status != MigrationStatusRunning && job.Status != MigrationStatusPending &&
+		job.Status != MigrationStatusRetrying {
 		job.mu.Unlock()
 		return fmt.Errorf("migration cannot be cancelled in state: %s", job.Status)
 	}
 	job.Status = MigrationStatusCancelled
+	job.NextRetryAt = time.Time{}
 	job.Phase = pb.MigrationPhase_MIGRATION_PHASE_CANCELLED
 	job.Error = reason
 	job.CompletedAt = time.Now()
 	job.mu.Unlock()
+	o.nonces.Revoke(migrationID)
+	o.persist()
 
 	// Send cancel commands to both workers
 	cancelCmd := &pb.MasterCommand{
@@ -301,8 +768,8 @@ func (o *Orchestrator) CancelMigration(migrationID string, reason string) error
 	}
 
 	// Best effort - don't fail if we can't reach workers
-	_ = o.registry.SendCommand(job.SourceWorkerID, cancelCmd)
-	_ = o.registry.SendCommand(job.TargetWorkerID, cancelCmd)
+	_ = o.registry.SendCommand(job.SourceWorkerID, cancelCmd, issuer)
+	_ = o.registry.SendCommand(job.TargetWorkerID, cancelCmd, issuer)
 
 	return nil
 }
@@ -338,6 +805,14 @@ type MigrationRequest struct {
 	Mode           pb.MigrationMode
 	Strategy       pb.MigrationStrategy
 	TransferMode   pb.TransferMode
+	ImageMode      string // stream (default) or registry
+	Retry          *RetryPolicy
+	Issuer         string // Who asked, for the command audit log
+	Namespace      string // Confines both workers to the caller's namespace; empty allows any
+
+	// AllowRemigration starts the job even if the target runs a live copy of
+	// one of its containers, or cannot be checked for one
+	AllowRemigration bool
 }
 
 func generateMigrationID() string {
@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 
 	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/artemis/docker-migrate/internal/peer"
 	pb "github.com/artemis/docker-migrate/proto"
 	"go.uber.org/zap"
 )
@@ -17,7 +18,10 @@ type ProxyManager struct {
 	pb.UnimplementedProxyServiceServer
 
 	registry *Registry
+	nonces   *ProxyNonces
+	transfer *peer.TransferManager // Supplies the progress reporting granularity
 	logger   *observability.Logger
+	ctx      context.Context          // Parent of every channel's context
 	channels map[string]*ProxyChannel // migration_id -> channel
 	mu       sync.RWMutex
 }
@@ -36,10 +40,13 @@ type ProxyChannel struct {
 }
 
 // NewProxyManager creates a new ProxyManager
-func NewProxyManager(registry *Registry, logger *observability.Logger) *ProxyManager {
+func NewProxyManager(ctx context.Context, registry *Registry, nonces *ProxyNonces, transfer *peer.TransferManager, logger *observability.Logger) *ProxyManager {
 	return &ProxyManager{
 		registry: registry,
+		nonces:   nonces,
+		transfer: transfer,
 		logger:   logger,
+		ctx:      ctx,
 		channels: make(map[string]*ProxyChannel),
 	}
 }
@@ -91,6 +98,18 @@ func (pm *ProxyManager) OpenProxyChannel(stream pb.ProxyService_OpenProxyChannel
 		return fmt.Errorf("worker ID mismatch")
 	}
 
+	// The auth token alone would let a captured handshake be replayed; the
+	// nonce ties it to this migration and role, once
+	if err := pm.nonces.Consume(handshake.Nonce, migrationID, workerID, role); err != nil {
+		pm.logger.Warn("rejected proxy handshake",
+			zap.String("migration_id", migrationID),
+			zap.String("worker_id", workerID),
+			zap.String("role", role.String()),
+			zap.Error(err),
+		)
+		return fmt.Errorf("proxy handshake rejected: %w", err)
+	}
+
 	// 2. Get or create ProxyChannel for migration_id
 	channel := pm.getOrCreateChannel(migrationID)
 
@@ -181,7 +200,7 @@ func (pm *ProxyManager) getOrCreateChannel(migrationID string) *ProxyChannel {
 		return channel
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(pm.ctx)
 	channel := &ProxyChannel{
 		MigrationID: migrationID,
 		SourceReady: make(chan struct{}),
@@ -249,6 +268,7 @@ func (pm *ProxyManager) relayLoop(channel *ProxyChannel) {
 
 // relaySourceToTarget relays data from source stream to target stream
 func (pm *ProxyManager) relaySourceToTarget(channel *ProxyChannel) error {
+	throttle := pm.transfer.NewProgressThrottle()
 	for {
 		select {
 		case <-channel.ctx.Done():
@@ -314,12 +334,14 @@ func (pm *ProxyManager) relaySourceToTarget(channel *ProxyChannel) error {
 		}
 
 		if dataSize > 0 {
-			atomic.AddInt64(&channel.BytesRelayed, int64(dataSize))
-			pm.logger.Debug("relayed data source->target",
-				zap.String("migration_id", channel.MigrationID),
-				zap.Int("bytes", dataSize),
-				zap.String("type", msg.Type.String()),
-			)
+			relayed := atomic.AddInt64(&channel.BytesRelayed, int64(dataSize))
+			if throttle.Allow(relayed) {
+				pm.logger.Debug("relayed data source->target",
+					zap.String("migration_id", channel.MigrationID),
+					zap.Int64("bytes_relayed", relayed),
+					zap.String("type", msg.Type.String()),
+				)
+			}
 		}
 	}
 }
@@ -0,0 +1,107 @@
+package master
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	pb "github.com/artemis/docker-migrate/proto"
+)
+
+// ProxyNonceTTL is how long a worker has to open its proxy channel after the
+// master tells it to
+const ProxyNonceTTL = 5 * time.Minute
+
+var (
+	// ErrProxyNonceInvalid means the nonce was never issued, or issued for
+	// another migration, worker or role
+	ErrProxyNonceInvalid = errors.New("invalid proxy nonce")
+	// ErrProxyNonceUsed means the handshake is a replay
+	ErrProxyNonceUsed = errors.New("proxy nonce already used")
+	// ErrProxyNonceExpired means the worker connected too late
+	ErrProxyNonceExpired = errors.New("proxy nonce expired")
+)
+
+// proxyNonce binds a proxy handshake to one migration, worker and role
+type proxyNonce struct {
+	migrationID string
+	workerID    string
+	role        pb.ProxyRole
+	expiresAt   time.Time
+	used        bool
+}
+
+// ProxyNonces issues the one-time nonces that proxy handshakes must present,
+// so a captured handshake cannot be replayed to join another relay
+type ProxyNonces struct {
+	mu     sync.Mutex
+	nonces map[string]*proxyNonce // nonce -> binding
+}
+
+// NewProxyNonces creates an empty nonce store
+func NewProxyNonces() *ProxyNonces {
+	return &ProxyNonces{nonces: make(map[string]*proxyNonce)}
+}
+
+// Issue returns a fresh nonce for workerID to open migrationID's proxy
+// channel in role
+func (n *ProxyNonces) Issue(migrationID, workerID string, role pb.ProxyRole) string {
+	nonce := generateToken(32)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pruneLocked(time.Now())
+	n.nonces[nonce] = &proxyNonce{
+		migrationID: migrationID,
+		workerID:    workerID,
+		role:        role,
+		expiresAt:   time.Now().Add(ProxyNonceTTL),
+	}
+	return nonce
+}
+
+// Consume checks a handshake's nonce and marks it used. Used nonces are kept
+// until they expire so a replay is reported as one.
+func (n *ProxyNonces) Consume(nonce, migrationID, workerID string, role pb.ProxyRole) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	entry, ok := n.nonces[nonce]
+	if !ok || nonce == "" {
+		return ErrProxyNonceInvalid
+	}
+	if entry.migrationID != migrationID || entry.workerID != workerID || entry.role != role {
+		return ErrProxyNonceInvalid
+	}
+	if entry.used {
+		return ErrProxyNonceUsed
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(n.nonces, nonce)
+		return ErrProxyNonceExpired
+	}
+
+	entry.used = true
+	return nil
+}
+
+// Revoke invalidates the unused nonces of a finished or failed migration
+func (n *ProxyNonces) Revoke(migrationID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for nonce, entry := range n.nonces {
+		if entry.migrationID == migrationID && !entry.used {
+			delete(n.nonces, nonce)
+		}
+	}
+}
+
+func (n *ProxyNonces) pruneLocked(now time.Time) {
+	for nonce, entry := range n.nonces {
+		if now.After(entry.expiresAt) {
+			delete(n.nonces, nonce)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package master
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/artemis/docker-migrate/internal/peer"
 	pb "github.com/artemis/docker-migrate/proto"
 	"go.uber.org/zap"
 )
@@ -20,19 +22,42 @@ type WorkerInfo struct {
 	TLSFingerprint string
 	Labels         map[string]string
 	Version        string
+	OutboundOnly   bool  // No gRPC listener; reachable only through the master proxy
+	Protocol       int32 // Negotiated master-worker protocol version
 
-	Status    pb.WorkerStatus
-	AuthToken string
+	// Namespace is the one whose enrollment token the worker used; empty for
+	// the master's own token
+	Namespace string
+
+	Status pb.WorkerStatus
+
+	// AuthTokenHash is the SHA-256 of the worker's auth token; the
+	// plaintext is only handed to the worker
+	AuthTokenHash string
+
+	// PreviousTokenHash still authenticates after a rotation, until
+	// PreviousTokenExpiry, so messages already in flight are not rejected
+	PreviousTokenHash   string
+	PreviousTokenExpiry time.Time
+	TokenIssuedAt       time.Time
 
 	RegisteredAt  time.Time
 	LastHeartbeat time.Time
 	LastInventory time.Time
 
+	// ClockSkew is how far the worker's clock is ahead of the master's,
+	// measured at registration and refreshed by heartbeats. It includes the
+	// one-way network delay. ClockSkewKnown is false until a worker that
+	// stamps its registration registers.
+	ClockSkew      time.Duration
+	ClockSkewKnown bool
+
 	// Resource inventory
 	Containers []*pb.ContainerResource
 	Images     []*pb.ImageResource
 	Volumes    []*pb.VolumeResource
 	Networks   []*pb.NetworkResource
+	DiskUsage  *pb.DiskUsageReport
 
 	// System resources
 	SystemResources *pb.SystemResources
@@ -48,35 +73,63 @@ type Registry struct {
 	mu      sync.RWMutex
 	logger  *observability.Logger
 	timeout time.Duration
+	audit   *CommandAudit // Records every command sent; nil records nothing
+	path    string        // workers.json enrolled workers are saved to
 }
 
-// NewRegistry creates a new worker registry
-func NewRegistry(logger *observability.Logger, timeout time.Duration) *Registry {
-	return &Registry{
+// NewRegistry creates a worker registry, restoring the workers enrolled
+// under dataDir
+func NewRegistry(dataDir string, logger *observability.Logger, timeout time.Duration, audit *CommandAudit) (*Registry, error) {
+	r := &Registry{
 		workers: make(map[string]*WorkerInfo),
 		logger:  logger,
 		timeout: timeout,
+		audit:   audit,
+	}
+	if err := r.openWorkerStore(dataDir); err != nil {
+		return nil, err
 	}
+	return r, nil
 }
 
-// Register registers a new worker
-func (r *Registry) Register(reg *pb.WorkerRegistration, authToken string) (*WorkerInfo, error) {
+// Register registers a worker by the fingerprint of the certificate it
+// presented. A worker presenting the certificate of one already enrolled, as
+// it does when reconnecting, keeps its ID; it cannot move to another
+// namespace until an admin removes it.
+func (r *Registry) Register(reg *pb.WorkerRegistration, fingerprint, authToken string, protocol int32, namespace string) (*WorkerInfo, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	workerID := generateWorkerID()
+	registeredAt := time.Now()
+	for id, w := range r.workers {
+		if w.TLSFingerprint != fingerprint {
+			continue
+		}
+		if w.Namespace != namespace {
+			return nil, fmt.Errorf("worker %s is enrolled in namespace %q; remove it before enrolling it in %q",
+				id, w.Namespace, namespace)
+		}
+		workerID = id
+		registeredAt = w.RegisteredAt
+		break
+	}
 
 	worker := &WorkerInfo{
 		ID:             workerID,
 		Name:           reg.WorkerName,
 		Hostname:       reg.Hostname,
 		GRPCAddress:    reg.GrpcAddress,
-		TLSFingerprint: reg.TlsFingerprint,
+		TLSFingerprint: fingerprint,
 		Labels:         reg.Labels,
 		Version:        reg.Version,
+		OutboundOnly:   reg.OutboundOnly,
+		Protocol:       protocol,
+		Namespace:      namespace,
 		Status:         pb.WorkerStatus_WORKER_STATUS_IDLE,
-		AuthToken:      authToken,
-		RegisteredAt:   time.Now(),
+		AuthTokenHash:  hashAPIToken(authToken),
+		TokenIssuedAt:  time.Now(),
+		RegisteredAt:   registeredAt,
 		LastHeartbeat:  time.Now(),
 		Containers:     make([]*pb.ContainerResource, 0),
 		Images:         make([]*pb.ImageResource, 0),
@@ -85,11 +138,15 @@ func (r *Registry) Register(reg *pb.WorkerRegistration, authToken string) (*Work
 	}
 
 	r.workers[workerID] = worker
+	r.saveLocked()
 
 	r.logger.Info("worker registered",
 		zap.String("worker_id", workerID),
 		zap.String("name", reg.WorkerName),
 		zap.String("hostname", reg.Hostname),
+		zap.Bool("outbound_only", reg.OutboundOnly),
+		zap.Int32("protocol", protocol),
+		zap.String("namespace", namespace),
 	)
 
 	return worker, nil
@@ -106,6 +163,7 @@ func (r *Registry) Unregister(workerID string) {
 			zap.String("name", w.Name),
 		)
 		delete(r.workers, workerID)
+		r.saveLocked()
 	}
 }
 
@@ -117,19 +175,104 @@ func (r *Registry) Get(workerID string) (*WorkerInfo, bool) {
 	return w, ok
 }
 
-// GetByAuthToken returns a worker by auth token
+// Resolve returns a worker by ID, falling back to its name
+func (r *Registry) Resolve(idOrName string) (*WorkerInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if w, ok := r.workers[idOrName]; ok {
+		return w, true
+	}
+	for _, w := range r.workers {
+		if w.Name == idOrName {
+			return w, true
+		}
+	}
+	return nil, false
+}
+
+// GetByAuthToken returns a worker by auth token. A token replaced by rotation
+// still matches until its overlap ends.
 func (r *Registry) GetByAuthToken(token string) (*WorkerInfo, bool) {
+	if token == "" {
+		return nil, false
+	}
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	hash := []byte(hashAPIToken(token))
+	now := time.Now()
 	for _, w := range r.workers {
-		if w.AuthToken == token {
+		if subtle.ConstantTimeCompare([]byte(w.AuthTokenHash), hash) == 1 {
+			return w, true
+		}
+		if w.PreviousTokenHash != "" && subtle.ConstantTimeCompare([]byte(w.PreviousTokenHash), hash) == 1 &&
+			now.Before(w.PreviousTokenExpiry) {
 			return w, true
 		}
 	}
 	return nil, false
 }
 
+// RotateAuthToken makes token the worker's auth token, keeping the current one
+// valid for overlap
+func (r *Registry) RotateAuthToken(workerID, token string, overlap time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.workers[workerID]
+	if !ok {
+		return fmt.Errorf("worker not found: %s", workerID)
+	}
+	w.PreviousTokenHash = w.AuthTokenHash
+	w.PreviousTokenExpiry = time.Now().Add(overlap)
+	w.AuthTokenHash = hashAPIToken(token)
+	w.TokenIssuedAt = time.Now()
+	r.saveLocked()
+	return nil
+}
+
+// RevertAuthToken undoes a rotation to token that never reached the worker
+func (r *Registry) RevertAuthToken(workerID, token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.workers[workerID]
+	if !ok || w.PreviousTokenHash == "" ||
+		subtle.ConstantTimeCompare([]byte(w.AuthTokenHash), []byte(hashAPIToken(token))) != 1 {
+		return
+	}
+	r.logger.Warn("kept previous worker auth token after failed rotation",
+		zap.String("worker_id", workerID),
+	)
+	w.AuthTokenHash = w.PreviousTokenHash
+	w.PreviousTokenHash = ""
+	w.PreviousTokenExpiry = time.Time{}
+	r.saveLocked()
+}
+
+// DueForTokenRotation lists connected workers whose auth token is older than
+// maxAge and not still in a rotation's overlap
+func (r *Registry) DueForTokenRotation(maxAge time.Duration) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	cutoff := now.Add(-maxAge)
+	var due []string
+	for id, w := range r.workers {
+		w.streamMu.Lock()
+		connected := w.stream != nil
+		w.streamMu.Unlock()
+		rotating := w.PreviousTokenHash != "" && now.Before(w.PreviousTokenExpiry)
+		if connected && !rotating && w.TokenIssuedAt.Before(cutoff) {
+			due = append(due, id)
+		}
+	}
+	return due
+}
+
 // List returns all workers
 func (r *Registry) List() []*WorkerInfo {
 	r.mu.RLock()
@@ -172,6 +315,70 @@ func (r *Registry) UpdateHeartbeat(workerID string, status pb.WorkerStatus, reso
 	}
 }
 
+// UpdateClockSkew records the worker's clock skew from a message it stamped
+// with remoteMs, warning when the skew first exceeds peer.ClockSkewWarnThreshold
+func (r *Registry) UpdateClockSkew(workerID string, remoteMs int64) {
+	if remoteMs == 0 {
+		return
+	}
+	skew := time.UnixMilli(remoteMs).Sub(time.Now())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.workers[workerID]
+	if !ok {
+		return
+	}
+	wasExceeded := w.ClockSkewKnown && peer.ClockSkewExceeded(w.ClockSkew)
+	w.ClockSkew = skew
+	w.ClockSkewKnown = true
+
+	if peer.ClockSkewExceeded(skew) && !wasExceeded {
+		r.logger.Warn("worker clock differs from the master's; its timestamps are normalized in reports",
+			zap.String("worker_id", workerID),
+			zap.String("name", w.Name),
+			zap.Duration("clock_skew", skew),
+		)
+	}
+}
+
+// ClockSkew returns the worker's last measured clock skew, if known
+func (r *Registry) ClockSkew(workerID string) (time.Duration, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.workers[workerID]
+	if !ok || !w.ClockSkewKnown {
+		return 0, false
+	}
+	return w.ClockSkew, true
+}
+
+// NormalizeTime converts a timestamp the worker stamped with its own clock to
+// the master's clock. It returns the time now when the message carries no
+// timestamp or the worker's skew is unknown.
+func (r *Registry) NormalizeTime(workerID string, remoteMs int64) time.Time {
+	skew, ok := r.ClockSkew(workerID)
+	if remoteMs == 0 || !ok {
+		return time.Now()
+	}
+	return peer.NormalizeRemoteTime(remoteMs, skew)
+}
+
+// Containers returns the containers in a worker's last inventory and when
+// it was reported; the time is zero if the worker has not reported one
+func (r *Registry) Containers(workerID string) ([]*pb.ContainerResource, time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.workers[workerID]
+	if !ok {
+		return nil, time.Time{}
+	}
+	return w.Containers, w.LastInventory
+}
+
 // UpdateInventory updates a worker's resource inventory
 func (r *Registry) UpdateInventory(workerID string, inv *pb.ResourceInventory) {
 	r.mu.Lock()
@@ -183,6 +390,9 @@ func (r *Registry) UpdateInventory(workerID string, inv *pb.ResourceInventory) {
 		w.Images = inv.Images
 		w.Volumes = inv.Volumes
 		w.Networks = inv.Networks
+		if inv.DiskUsage != nil {
+			w.DiskUsage = inv.DiskUsage
+		}
 	}
 }
 
@@ -198,27 +408,44 @@ func (r *Registry) SetStream(workerID string, stream pb.MasterService_WorkerStre
 	}
 }
 
-// SendCommand sends a command to a worker
-func (r *Registry) SendCommand(workerID string, cmd *pb.MasterCommand) error {
+// SendCommand sends a command to a worker on behalf of issuer and records it
+// in the command audit log
+func (r *Registry) SendCommand(workerID string, cmd *pb.MasterCommand, issuer string) error {
 	r.mu.RLock()
 	w, ok := r.workers[workerID]
 	r.mu.RUnlock()
 
-	if !ok {
-		return fmt.Errorf("worker not found: %s", workerID)
+	var err error
+	if ok {
+		err = w.send(cmd)
+	} else {
+		err = fmt.Errorf("worker not found: %s", workerID)
 	}
 
+	if r.audit != nil {
+		rec := newCommandRecord(w, workerID, cmd, issuer)
+		rec.Outcome = CommandSent
+		if err != nil {
+			rec.Outcome = CommandFailed
+			rec.Error = err.Error()
+		}
+		r.audit.Record(rec)
+	}
+	return err
+}
+
+func (w *WorkerInfo) send(cmd *pb.MasterCommand) error {
 	w.streamMu.Lock()
 	defer w.streamMu.Unlock()
 
 	if w.stream == nil {
-		return fmt.Errorf("worker stream not connected: %s", workerID)
+		return fmt.Errorf("worker stream not connected: %s", w.ID)
 	}
 
 	return w.stream.Send(cmd)
 }
 
-// StartCleanup periodically removes stale workers
+// StartCleanup periodically marks workers offline once they stop heartbeating
 func (r *Registry) StartCleanup(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -233,21 +460,32 @@ func (r *Registry) StartCleanup(ctx context.Context, interval time.Duration) {
 	}
 }
 
+// cleanupStale drops the status and inventory of workers silent for three
+// times the timeout, so nothing acts on stale data. They stay enrolled, as
+// workers restored after a master restart may take a while to come back;
+// only an admin removes a worker for good.
 func (r *Registry) cleanupStale() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	cutoff := time.Now().Add(-r.timeout * 3) // 3x timeout before removal
+	cutoff := time.Now().Add(-r.timeout * 3)
 
 	for id, w := range r.workers {
-		if w.LastHeartbeat.Before(cutoff) {
-			r.logger.Warn("removing stale worker",
-				zap.String("worker_id", id),
-				zap.String("name", w.Name),
-				zap.Duration("since_heartbeat", time.Since(w.LastHeartbeat)),
-			)
-			delete(r.workers, id)
+		if !w.LastHeartbeat.Before(cutoff) || w.Status == pb.WorkerStatus_WORKER_STATUS_UNKNOWN {
+			continue
 		}
+		r.logger.Warn("worker stopped heartbeating, keeping its enrollment",
+			zap.String("worker_id", id),
+			zap.String("name", w.Name),
+			zap.Duration("since_heartbeat", time.Since(w.LastHeartbeat)),
+		)
+		w.Status = pb.WorkerStatus_WORKER_STATUS_UNKNOWN
+		w.Containers = make([]*pb.ContainerResource, 0)
+		w.Images = make([]*pb.ImageResource, 0)
+		w.Volumes = make([]*pb.VolumeResource, 0)
+		w.Networks = make([]*pb.NetworkResource, 0)
+		w.DiskUsage = nil
+		w.SystemResources = nil
 	}
 }
 
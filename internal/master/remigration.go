@@ -0,0 +1,77 @@
+package master
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/migration"
+	"go.uber.org/zap"
+)
+
+// checkMigratedCopies refuses a job that would leave two live instances of
+// a stateful app, one on each worker, judged from the workers' last
+// inventories. A worker that has not reported one blocks the job too.
+// With AllowRemigration the job goes ahead and the copies are only logged.
+func (o *Orchestrator) checkMigratedCopies(req *MigrationRequest, source, target *WorkerInfo) error {
+	if len(req.ContainerIDs) == 0 {
+		return nil
+	}
+
+	copies, err := o.findMigratedCopies(req.ContainerIDs, source, target)
+	if err != nil {
+		if !req.AllowRemigration {
+			return fmt.Errorf("%w: %v (set allow_remigration to migrate anyway)", migration.ErrRemigrationUnchecked, err)
+		}
+		o.logger.Warn("could not check target for migrated copies, migrating anyway",
+			zap.String("target", target.Name),
+			zap.Error(err),
+		)
+		return nil
+	}
+	if len(copies) == 0 {
+		return nil
+	}
+
+	if !req.AllowRemigration {
+		return fmt.Errorf("%w: %s (set allow_remigration to migrate anyway)", migration.ErrMigratedCopy, migration.DescribeMigratedCopies(copies))
+	}
+	for _, c := range copies {
+		o.logger.Warn("migrating despite a live copy on the target",
+			zap.String("container", c.Container),
+			zap.String("target", target.Name),
+			zap.String("target_container", c.Target),
+			zap.String("reason", c.Reason),
+		)
+	}
+	return nil
+}
+
+// findMigratedCopies compares the provenance labels of the requested
+// containers on the source with the target's running containers
+func (o *Orchestrator) findMigratedCopies(containerIDs []string, source, target *WorkerInfo) ([]migration.MigratedCopy, error) {
+	local, localAt := o.registry.Containers(source.ID)
+	if localAt.IsZero() {
+		return nil, fmt.Errorf("source worker %s has not reported its containers", source.Name)
+	}
+	remote, remoteAt := o.registry.Containers(target.ID)
+	if remoteAt.IsZero() {
+		return nil, fmt.Errorf("target worker %s has not reported its containers", target.Name)
+	}
+	if source.Hostname == "" || target.Hostname == "" {
+		return nil, fmt.Errorf("workers did not report their hostnames")
+	}
+
+	var copies []migration.MigratedCopy
+	for _, id := range containerIDs {
+		for _, c := range local {
+			name := strings.TrimPrefix(c.Name, "/")
+			if strings.HasPrefix(c.Id, id) || name == id {
+				own := docker.ProvenanceFromLabels(c.Labels)
+				copies = append(copies, migration.MatchMigratedCopies(name, own, source.Hostname, target.Hostname, remote)...)
+				break
+			}
+		}
+	}
+	return copies, nil
+}
@@ -0,0 +1,322 @@
+package master
+
+import (
+	"context"
+	"crypto/subtle"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/artemis/docker-migrate/internal/peer"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// rendezvousAnswerTimeout bounds how long an offer waits for the
+	// listening peer's answer
+	rendezvousAnswerTimeout = 15 * time.Second
+
+	// rendezvousSessionTTL bounds how long after its offer a session may
+	// still open its relay
+	rendezvousSessionTTL = time.Minute
+)
+
+// Rendezvous introduces P2P nodes that cannot reach each other directly, so
+// they can punch a UDP hole between them, and relays their traffic when they
+// cannot. Nodes are told apart by their TLS certificates and must present the
+// master's relay token. The relayed bytes are the nodes' own TLS session, so
+// the master cannot read them.
+type Rendezvous struct {
+	pb.UnimplementedRendezvousServiceServer
+
+	token  string
+	logger *observability.Logger
+
+	mu        sync.Mutex
+	listeners map[string]chan *pb.RendezvousOffer // fingerprint -> offers
+	sessions  map[string]*rendezvousSession       // session_id -> session
+}
+
+// rendezvousSession is an offer between two nodes and, once both sides
+// open it, their relay
+type rendezvousSession struct {
+	dialer   string
+	listener string
+	answer   chan *pb.RendezvousAnswer
+	expires  time.Time
+
+	// waiting is the first relay end to arrive, until its partner does
+	waiting *relayEnd
+}
+
+// relayEnd is one node's relay stream
+type relayEnd struct {
+	stream      pb.RendezvousService_RelayServer
+	fingerprint string
+	partner     chan *relayEnd
+}
+
+// NewRendezvous creates the rendezvous service; an empty token disables it
+func NewRendezvous(token string, logger *observability.Logger) *Rendezvous {
+	return &Rendezvous{
+		token:     token,
+		logger:    logger,
+		listeners: make(map[string]chan *pb.RendezvousOffer),
+		sessions:  make(map[string]*rendezvousSession),
+	}
+}
+
+// authorize checks the caller's relay token and returns its fingerprint
+func (r *Rendezvous) authorize(ctx context.Context) (string, error) {
+	if r.token == "" {
+		return "", status.Error(codes.Unavailable, "rendezvous is not enabled on this master")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md.Get(peer.RelayTokenHeader)
+	if len(tokens) != 1 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(r.token)) != 1 {
+		return "", status.Error(codes.PermissionDenied, "invalid relay token")
+	}
+
+	p, ok := grpcpeer.FromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "no peer info")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return "", status.Error(codes.Unauthenticated, "a client certificate is required")
+	}
+	return peer.ComputeFingerprint(tlsInfo.State.PeerCertificates[0]), nil
+}
+
+// Listen delivers offers for the caller until it disconnects. A node
+// listening again replaces its earlier stream.
+func (r *Rendezvous) Listen(_ *pb.RendezvousListen, stream pb.RendezvousService_ListenServer) error {
+	fingerprint, err := r.authorize(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	offers := make(chan *pb.RendezvousOffer, 16)
+	r.mu.Lock()
+	r.listeners[fingerprint] = offers
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		if r.listeners[fingerprint] == offers {
+			delete(r.listeners, fingerprint)
+		}
+		r.mu.Unlock()
+	}()
+
+	r.logger.Info("peer listening for rendezvous", zap.String("fingerprint", fingerprint))
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case offer := <-offers:
+			if err := stream.Send(offer); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Offer forwards the caller's candidates to the target and waits for its answer
+func (r *Rendezvous) Offer(ctx context.Context, offer *pb.RendezvousOffer) (*pb.RendezvousAnswer, error) {
+	fingerprint, err := r.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offer.SessionId == "" || offer.TargetFingerprint == "" {
+		return nil, status.Error(codes.InvalidArgument, "session_id and target_fingerprint are required")
+	}
+
+	session := &rendezvousSession{
+		dialer:   fingerprint,
+		listener: offer.TargetFingerprint,
+		answer:   make(chan *pb.RendezvousAnswer, 1),
+		expires:  time.Now().Add(rendezvousSessionTTL),
+	}
+
+	r.mu.Lock()
+	r.expireLocked()
+	offers, listening := r.listeners[offer.TargetFingerprint]
+	if _, exists := r.sessions[offer.SessionId]; exists {
+		r.mu.Unlock()
+		return nil, status.Error(codes.AlreadyExists, "session already offered")
+	}
+	if listening {
+		r.sessions[offer.SessionId] = session
+	}
+	r.mu.Unlock()
+	if !listening {
+		return nil, status.Error(codes.NotFound, "peer is not listening on this master")
+	}
+
+	forwarded := &pb.RendezvousOffer{
+		SessionId:         offer.SessionId,
+		TargetFingerprint: offer.TargetFingerprint,
+		SourceFingerprint: fingerprint,
+		Candidates:        offer.Candidates,
+	}
+	select {
+	case offers <- forwarded:
+	default:
+		r.dropSession(offer.SessionId)
+		return nil, status.Error(codes.ResourceExhausted, "peer has too many pending offers")
+	}
+
+	timer := time.NewTimer(rendezvousAnswerTimeout)
+	defer timer.Stop()
+	select {
+	case answer := <-session.answer:
+		if answer.Error != "" {
+			r.dropSession(offer.SessionId)
+		}
+		return answer, nil
+	case <-timer.C:
+		r.dropSession(offer.SessionId)
+		return nil, status.Error(codes.DeadlineExceeded, "peer did not answer")
+	case <-ctx.Done():
+		r.dropSession(offer.SessionId)
+		return nil, ctx.Err()
+	}
+}
+
+// Answer passes the listener's reply back to the waiting offer
+func (r *Rendezvous) Answer(ctx context.Context, answer *pb.RendezvousAnswer) (*pb.Empty, error) {
+	fingerprint, err := r.authorize(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	session, ok := r.sessions[answer.SessionId]
+	r.mu.Unlock()
+	if !ok || session.listener != fingerprint {
+		return nil, status.Error(codes.NotFound, "no such session")
+	}
+
+	select {
+	case session.answer <- answer:
+	default:
+		return nil, status.Error(codes.AlreadyExists, "session already answered")
+	}
+	return &pb.Empty{}, nil
+}
+
+// Relay pairs the two sides of a session and pipes frames between them
+// until either leaves
+func (r *Rendezvous) Relay(stream pb.RendezvousService_RelayServer) error {
+	fingerprint, err := r.authorize(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	sessionID := first.SessionId
+	end := &relayEnd{stream: stream, fingerprint: fingerprint, partner: make(chan *relayEnd, 1)}
+
+	r.mu.Lock()
+	session, ok := r.sessions[sessionID]
+	if !ok || time.Now().After(session.expires) || (fingerprint != session.dialer && fingerprint != session.listener) {
+		r.mu.Unlock()
+		return status.Error(codes.NotFound, "no such session")
+	}
+	waiting := session.waiting
+	if waiting != nil && waiting.fingerprint == fingerprint {
+		r.mu.Unlock()
+		return status.Error(codes.AlreadyExists, "relay already open")
+	}
+	if waiting == nil {
+		session.waiting = end
+	} else {
+		// Both sides are here; the session is used up
+		delete(r.sessions, sessionID)
+	}
+	r.mu.Unlock()
+
+	var other *relayEnd
+	if waiting != nil {
+		other = waiting
+		waiting.partner <- end
+	} else {
+		timer := time.NewTimer(time.Until(session.expires))
+		defer timer.Stop()
+		select {
+		case other = <-end.partner:
+		case <-timer.C:
+			r.dropSession(sessionID)
+			return status.Error(codes.DeadlineExceeded, "peer did not open the relay")
+		case <-stream.Context().Done():
+			r.dropSession(sessionID)
+			return nil
+		}
+	}
+
+	if waiting != nil {
+		r.logger.Info("relaying peer session",
+			zap.String("session_id", sessionID),
+			zap.String("dialer", session.dialer),
+			zap.String("listener", session.listener),
+		)
+	}
+
+	// Each side forwards what it receives; when one side ends, the other's
+	// stream context is cancelled as its partner's handler returns
+	var relayed int64
+	done := make(chan error, 1)
+	go func() {
+		for {
+			frame, err := stream.Recv()
+			if err != nil {
+				done <- err
+				return
+			}
+			atomic.AddInt64(&relayed, int64(len(frame.Data)))
+			if err := other.stream.Send(&pb.RelayFrame{Data: frame.Data}); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-other.stream.Context().Done():
+	}
+	r.logger.Debug("relay side closed",
+		zap.String("session_id", sessionID),
+		zap.String("fingerprint", fingerprint),
+		zap.Int64("bytes", atomic.LoadInt64(&relayed)),
+	)
+	return nil
+}
+
+// dropSession forgets a session that failed before its relay was paired
+func (r *Rendezvous) dropSession(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, sessionID)
+}
+
+// expireLocked forgets sessions whose relay was never opened
+func (r *Rendezvous) expireLocked() {
+	now := time.Now()
+	for id, session := range r.sessions {
+		if now.After(session.expires) {
+			delete(r.sessions, id)
+		}
+	}
+}
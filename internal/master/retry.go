@@ -0,0 +1,166 @@
+package master
+
+import (
+	"fmt"
+	"time"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultRetryBackoff is the delay before the first retry when a policy
+	// sets none
+	defaultRetryBackoff = 30 * time.Second
+
+	// maxRetryBackoff caps the doubling delay between retries
+	maxRetryBackoff = 10 * time.Minute
+)
+
+// RetryPolicy re-dispatches a failed migration. Attempts count the first
+// run, so MaxAttempts 3 allows two retries.
+type RetryPolicy struct {
+	MaxAttempts int           `json:"max_attempts"`
+	Backoff     time.Duration `json:"backoff"` // Delay before the first retry, doubling after each
+}
+
+// delay returns how long to wait before attempt n (from 2)
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for i := 2; i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	return backoff
+}
+
+// resourceKey names a resource the way workers report them in
+// MigrationComplete.resources_migrated
+func resourceKey(kind, id string) string {
+	return kind + ":" + id
+}
+
+// remaining returns the ids not yet confirmed by the target. Callers hold
+// j.mu.
+func (j *MigrationJob) remaining(kind string, ids []string) []string {
+	if len(j.CompletedResources) == 0 {
+		return ids
+	}
+	done := make(map[string]bool, len(j.CompletedResources))
+	for _, key := range j.CompletedResources {
+		done[key] = true
+	}
+	var left []string
+	for _, id := range ids {
+		if !done[resourceKey(kind, id)] {
+			left = append(left, id)
+		}
+	}
+	return left
+}
+
+// recordCompleted adds the resources the target confirmed. Callers hold j.mu.
+func (j *MigrationJob) recordCompleted(keys []string) {
+	seen := make(map[string]bool, len(j.CompletedResources))
+	for _, key := range j.CompletedResources {
+		seen[key] = true
+	}
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			j.CompletedResources = append(j.CompletedResources, key)
+		}
+	}
+}
+
+// scheduleRetry queues a failed job for another attempt if its policy
+// allows one. Callers hold job.mu; it reports whether a retry was queued.
+func (o *Orchestrator) scheduleRetry(job *MigrationJob, reason string) bool {
+	if job.Retry == nil || job.Attempt >= job.Retry.MaxAttempts {
+		return false
+	}
+
+	delay := job.Retry.delay(job.Attempt + 1)
+	job.Status = MigrationStatusRetrying
+	job.Error = reason
+	job.NextRetryAt = time.Now().Add(delay)
+
+	o.logger.Warn("migration failed, retrying",
+		zap.String("migration_id", job.ID),
+		zap.Int("attempt", job.Attempt),
+		zap.Int("max_attempts", job.Retry.MaxAttempts),
+		zap.Duration("backoff", delay),
+		zap.String("error", reason),
+	)
+
+	go o.retryAfter(job, job.Attempt, delay)
+	return true
+}
+
+// retryAfter re-dispatches job after delay unless it was cancelled meanwhile;
+// attempt is the one that failed
+func (o *Orchestrator) retryAfter(job *MigrationJob, attempt int, delay time.Duration) {
+	// Both workers drop what is left of the failed attempt before the next
+	// one arrives under the same migration ID
+	o.stopWorkers(job, attempt, "retrying after failure")
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-o.ctx.Done():
+		return
+	case <-timer.C:
+	}
+
+	job.mu.Lock()
+	if job.Status != MigrationStatusRetrying {
+		job.mu.Unlock()
+		return
+	}
+	source, target, err := o.onlineWorkers(job.SourceWorkerID, job.TargetWorkerID)
+	if err != nil {
+		// An offline worker uses up the attempt like any other failure
+		job.Attempt++
+		if !o.scheduleRetry(job, err.Error()) {
+			job.finishFailed(err.Error())
+		}
+		job.mu.Unlock()
+		o.persist()
+		return
+	}
+	job.Status = MigrationStatusPending
+	job.NextRetryAt = time.Time{}
+	job.mu.Unlock()
+	o.persist()
+
+	go o.executeMigration(o.ctx, job, source, target)
+}
+
+// stopWorkers tells both workers to abandon an attempt at the job, best effort
+func (o *Orchestrator) stopWorkers(job *MigrationJob, attempt int, reason string) {
+	cmd := &pb.MasterCommand{
+		CommandId: fmt.Sprintf("cancel-%s-%d", job.ID, attempt),
+		Payload: &pb.MasterCommand_CancelMigration{
+			CancelMigration: &pb.CancelMigrationCommand{
+				MigrationId: job.ID,
+				Reason:      reason,
+			},
+		},
+	}
+	_ = o.registry.SendCommand(job.SourceWorkerID, cmd, job.IssuedBy)
+	_ = o.registry.SendCommand(job.TargetWorkerID, cmd, job.IssuedBy)
+}
+
+// finishFailed marks the job failed for good. Callers hold j.mu.
+func (j *MigrationJob) finishFailed(reason string) {
+	j.Status = MigrationStatusFailed
+	j.Phase = pb.MigrationPhase_MIGRATION_PHASE_FAILED
+	j.Error = reason
+	j.NextRetryAt = time.Time{}
+	j.CompletedAt = time.Now()
+}
@@ -0,0 +1,130 @@
+package master
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Role limits what an API token may do on the master
+type Role string
+
+const (
+	// RoleViewer may list and inspect everything except secrets
+	RoleViewer Role = "viewer"
+	// RoleOperator may also act on resources and running migrations
+	RoleOperator Role = "operator"
+	// RoleAdmin may also start migrations, evict workers and manage
+	// credentials
+	RoleAdmin Role = "admin"
+)
+
+// roleContextKey is where Authenticate leaves the caller's role
+const roleContextKey = "api_token_role"
+
+// UserContextKey is where UI sign-in leaves the signed-in user's name
+const UserContextKey = "ui_user"
+
+// ParseRole parses a role name. Empty means admin, the access tokens had
+// before roles existed.
+func ParseRole(s string) (Role, error) {
+	switch Role(s) {
+	case "":
+		return RoleAdmin, nil
+	case RoleViewer, RoleOperator, RoleAdmin:
+		return Role(s), nil
+	}
+	return "", fmt.Errorf("unknown role %q: want viewer, operator or admin", s)
+}
+
+// rank orders roles by privilege; unknown roles rank lowest
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleOperator:
+		return 2
+	case RoleAdmin:
+		return 3
+	}
+	return 0
+}
+
+// Allows reports whether r grants at least the privileges of required
+func (r Role) Allows(required Role) bool {
+	return r.rank() >= required.rank()
+}
+
+// SetCallerRole records the role an authenticated caller holds
+func SetCallerRole(c *gin.Context, role Role) {
+	c.Set(roleContextKey, role)
+}
+
+// CallerRole returns the role of the request's API token or UI session.
+// Without authentication every caller is an admin.
+func CallerRole(c *gin.Context) Role {
+	if v, ok := c.Get(roleContextKey); ok {
+		if role, ok := v.(Role); ok {
+			return role
+		}
+	}
+	return RoleAdmin
+}
+
+// CallerIdentity names the caller for audit records: the API token or signed-in
+// user, and the client address
+func CallerIdentity(c *gin.Context) string {
+	who := "anonymous"
+	if user := c.GetString(UserContextKey); user != "" {
+		who = "user:" + user
+	} else if tokenID := c.GetString("api_token_id"); tokenID != "" {
+		who = "token:" + tokenID
+	}
+	return who + "@" + c.ClientIP()
+}
+
+// MethodRole is the least role a request needs by its method alone: reads
+// need viewer, anything else operator
+func MethodRole(method string) Role {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleViewer
+	}
+	return RoleOperator
+}
+
+// RequireRole guards a route with a minimum role
+func (m *Master) RequireRole(required Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CheckRole(c, m.logger, required) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckRole aborts the request with 403 unless the caller holds required
+func CheckRole(c *gin.Context, logger *observability.Logger, required Role) bool {
+	role := CallerRole(c)
+	if role.Allows(required) {
+		return true
+	}
+
+	tokenID, _ := c.Get("api_token_id")
+	logger.Warn("rejected request for insufficient role",
+		zap.Any("token_id", tokenID),
+		zap.String("role", string(role)),
+		zap.String("required", string(required)),
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+	)
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":         fmt.Sprintf("%s role required", required),
+		"role":          role,
+		"required_role": required,
+	})
+	return false
+}
@@ -0,0 +1,437 @@
+package master
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/migration"
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Schedule runs a migration between workers later: once at RunAt, or
+// whenever Cron matches. Its runs are issued as the admin who created it,
+// in their namespace.
+type Schedule struct {
+	ID          string                `json:"id"`
+	Description string                `json:"description,omitempty"`
+	Cron        string                `json:"cron,omitempty"`
+	RunAt       *time.Time            `json:"run_at,omitempty"`
+	Request     StartMigrationRequest `json:"request"`
+	CreatedBy   string                `json:"created_by"`
+	Namespace   string                `json:"namespace,omitempty"`
+
+	// NextRun is when the schedule is next due; unset once a one-shot
+	// schedule has run
+	NextRun   *time.Time `json:"next_run,omitempty"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastJobID string     `json:"last_job_id,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// next returns when the schedule is due after t, or nil if it is not again
+func (s *Schedule) next(t time.Time) (*time.Time, error) {
+	if s.Cron == "" {
+		if s.RunAt != nil && s.LastRun == nil {
+			at := *s.RunAt
+			return &at, nil
+		}
+		return nil, nil
+	}
+	cron, err := migration.ParseCron(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+	at := cron.Next(t.Local())
+	if at.IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", s.Cron)
+	}
+	return &at, nil
+}
+
+// ScheduleStore keeps the master's migration schedules in
+// master-schedules.json in the data directory
+type ScheduleStore struct {
+	path   string
+	logger *observability.Logger
+	mu     sync.Mutex
+}
+
+// NewScheduleStore opens the schedule store in dataDir
+func NewScheduleStore(dataDir string, logger *observability.Logger) (*ScheduleStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &ScheduleStore{
+		path:   filepath.Join(dataDir, "master-schedules.json"),
+		logger: logger,
+	}, nil
+}
+
+// List returns every schedule, soonest due first
+func (s *ScheduleStore) List() ([]*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*Schedule, 0, len(schedules))
+	for _, sched := range schedules {
+		list = append(list, sched)
+	}
+	// Schedules that are not due again go last
+	sort.Slice(list, func(i, k int) bool {
+		a, b := list[i].NextRun, list[k].NextRun
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.Before(*b)
+		case (a == nil) != (b == nil):
+			return a != nil
+		}
+		return list[i].ID < list[k].ID
+	})
+	return list, nil
+}
+
+// Get returns the schedule with id, or nil if there is none
+func (s *ScheduleStore) Get(id string) (*Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return schedules[id], nil
+}
+
+// Add validates a new schedule, works out when it is first due and saves it
+func (s *ScheduleStore) Add(sched *Schedule) error {
+	if (sched.Cron == "") == (sched.RunAt == nil) {
+		return fmt.Errorf("set exactly one of cron and run_at")
+	}
+	if _, err := sched.Request.migrationRequest(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if sched.RunAt != nil && !sched.RunAt.After(now) {
+		return fmt.Errorf("run_at %s is in the past", sched.RunAt.Format(time.RFC3339))
+	}
+	next, err := sched.next(now)
+	if err != nil {
+		return err
+	}
+
+	sched.ID = fmt.Sprintf("msched_%d", now.UnixNano())
+	sched.NextRun = next
+	sched.LastRun, sched.LastJobID, sched.LastError = nil, "", ""
+	sched.CreatedAt = now.UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return err
+	}
+	schedules[sched.ID] = sched
+	if err := s.write(schedules); err != nil {
+		return err
+	}
+	s.logger.Info("worker migration scheduled",
+		zap.String("schedule_id", sched.ID),
+		zap.String("source_worker_id", sched.Request.SourceWorkerID),
+		zap.String("target_worker_id", sched.Request.TargetWorkerID),
+		zap.Timep("next_run", sched.NextRun),
+	)
+	return nil
+}
+
+// Delete removes the schedule with id; jobs it already started carry on
+func (s *ScheduleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return err
+	}
+	delete(schedules, id)
+	return s.write(schedules)
+}
+
+// recordRun notes a run of the schedule and when it is next due
+func (s *ScheduleStore) recordRun(id string, at time.Time, jobID string, runErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return err
+	}
+	sched, ok := schedules[id]
+	if !ok {
+		// Deleted while it ran
+		return nil
+	}
+
+	ran := at.UTC()
+	sched.LastRun = &ran
+	sched.LastJobID = jobID
+	sched.LastError = ""
+	if runErr != nil {
+		sched.LastError = runErr.Error()
+	}
+	next, err := sched.next(at)
+	if err != nil {
+		sched.LastError = err.Error()
+	}
+	sched.NextRun = next
+
+	return s.write(schedules)
+}
+
+func (s *ScheduleStore) load() (map[string]*Schedule, error) {
+	schedules := make(map[string]*Schedule)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return schedules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+func (s *ScheduleStore) write(schedules map[string]*Schedule) error {
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedules: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return nil
+}
+
+// runSchedules starts scheduled migrations as they fall due until ctx is
+// cancelled. A schedule that fell due while the master was down runs once,
+// as soon as it is back.
+func (m *Master) runSchedules(ctx context.Context) {
+	ticker := time.NewTicker(migration.ScheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		schedules, err := m.schedules.List()
+		if err != nil {
+			m.logger.Warn("failed to read migration schedules", zap.Error(err))
+		}
+		now := time.Now()
+		for _, sched := range schedules {
+			if sched.NextRun != nil && !sched.NextRun.After(now) {
+				m.runSchedule(ctx, sched)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runSchedule starts a migration from the schedule and records the run on
+// it. While the job from the previous run is unfinished the run is skipped,
+// so a slow migration is never overlapped by the next one.
+func (m *Master) runSchedule(ctx context.Context, sched *Schedule) (*MigrationJob, error) {
+	now := time.Now()
+
+	var job *MigrationJob
+	err := m.scheduleOverlap(sched)
+	if err == nil {
+		var req *MigrationRequest
+		req, err = sched.Request.migrationRequest()
+		if err == nil {
+			req.Issuer = sched.CreatedBy
+			req.Namespace = sched.Namespace
+			job, err = m.orchestrator.StartMigration(ctx, req)
+		}
+	}
+
+	jobID := sched.LastJobID
+	if err != nil {
+		m.logger.Warn("scheduled migration did not start",
+			zap.String("schedule_id", sched.ID),
+			zap.Error(err),
+		)
+	} else {
+		jobID = job.ID
+		m.logger.Info("scheduled migration started",
+			zap.String("schedule_id", sched.ID),
+			zap.String("migration_id", job.ID),
+		)
+	}
+
+	if recordErr := m.schedules.recordRun(sched.ID, now, jobID, err); recordErr != nil {
+		m.logger.Warn("failed to record scheduled run",
+			zap.String("schedule_id", sched.ID),
+			zap.Error(recordErr),
+		)
+	}
+	return job, err
+}
+
+// scheduleOverlap returns an error if the job from the schedule's previous
+// run has yet to finish
+func (m *Master) scheduleOverlap(sched *Schedule) error {
+	if sched.LastJobID == "" {
+		return nil
+	}
+	job, ok := m.orchestrator.GetMigration(sched.LastJobID)
+	if !ok {
+		return nil
+	}
+	job.mu.RLock()
+	status := job.Status
+	job.mu.RUnlock()
+
+	switch status {
+	case MigrationStatusPending, MigrationStatusRunning, MigrationStatusRetrying, MigrationStatusAwaiting:
+		return fmt.Errorf("%w: migration %s is %s", migration.ErrScheduleOverlap, sched.LastJobID, status)
+	}
+	return nil
+}
+
+// RegisterScheduleRoutes registers the master's migration schedule routes.
+// They sit under /master so they cannot be mistaken for the peer-mode
+// /api/schedules routes.
+func (m *Master) RegisterScheduleRoutes(rg *gin.RouterGroup) {
+	admin := m.RequireRole(RoleAdmin)
+	schedules := rg.Group("/master/schedules")
+	schedules.GET("", m.listSchedules)
+	schedules.GET("/:id", m.getSchedule)
+	schedules.POST("", admin, m.RequireTOTP(), m.createSchedule)
+	schedules.DELETE("/:id", admin, m.deleteSchedule)
+	schedules.POST("/:id/run", admin, m.RequireTOTP(), m.runScheduleNow)
+}
+
+// visibleSchedule returns a schedule the caller may see, writing the error
+// response otherwise
+func (m *Master) visibleSchedule(c *gin.Context) (*Schedule, bool) {
+	sched, err := m.schedules.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if sched == nil || !CanSee(c, sched.Namespace) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+		return nil, false
+	}
+	return sched, true
+}
+
+func (m *Master) listSchedules(c *gin.Context) {
+	schedules, err := m.schedules.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	visible := make([]*Schedule, 0, len(schedules))
+	for _, sched := range schedules {
+		if CanSee(c, sched.Namespace) {
+			visible = append(visible, sched)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": visible,
+		"count":     len(visible),
+	})
+}
+
+func (m *Master) getSchedule(c *gin.Context) {
+	sched, ok := m.visibleSchedule(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sched)
+}
+
+// createSchedule schedules a migration: the body of POST /api/migrations
+// under request, plus a cron expression or an RFC 3339 run_at
+func (m *Master) createSchedule(c *gin.Context) {
+	var sched Schedule
+	if err := c.ShouldBindJSON(&sched); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sched.CreatedBy = CallerIdentity(c)
+	sched.Namespace = CallerNamespace(c)
+
+	if err := m.schedules.Add(&sched); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, sched)
+}
+
+func (m *Master) deleteSchedule(c *gin.Context) {
+	sched, ok := m.visibleSchedule(c)
+	if !ok {
+		return
+	}
+	if err := m.schedules.Delete(sched.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	m.logger.Info("worker migration schedule deleted",
+		zap.String("schedule_id", sched.ID),
+		zap.String("by", CallerIdentity(c)),
+	)
+	c.JSON(http.StatusOK, gin.H{"deleted": sched.ID})
+}
+
+// runScheduleNow starts a scheduled migration now instead of waiting for it
+func (m *Master) runScheduleNow(c *gin.Context) {
+	sched, ok := m.visibleSchedule(c)
+	if !ok {
+		return
+	}
+	job, err := m.runSchedule(c.Request.Context(), sched)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, migration.ErrScheduleOverlap) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, migrationToResponse(job))
+}
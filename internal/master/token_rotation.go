@@ -0,0 +1,83 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuthTokenRotation = 24 * time.Hour
+	defaultAuthTokenOverlap  = 5 * time.Minute
+)
+
+// authTokenRotation returns how often worker auth tokens are replaced and how
+// long a replaced token stays valid
+func (m *Master) authTokenRotation() (interval, overlap time.Duration) {
+	interval, overlap = defaultAuthTokenRotation, defaultAuthTokenOverlap
+	if m.config.Master.AuthTokenRotation > 0 {
+		interval = m.config.Master.AuthTokenRotation
+	}
+	if m.config.Master.AuthTokenOverlap > 0 {
+		overlap = m.config.Master.AuthTokenOverlap
+	}
+	return interval, overlap
+}
+
+// rotateAuthTokens periodically replaces the auth tokens of connected workers
+func (m *Master) rotateAuthTokens(ctx context.Context) {
+	interval, overlap := m.authTokenRotation()
+
+	// Check often enough that no token outlives the interval by much
+	ticker := time.NewTicker(min(interval/4, time.Hour))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, workerID := range m.registry.DueForTokenRotation(interval) {
+				if err := m.RotateWorkerToken(workerID, overlap, IssuerSystem); err != nil {
+					m.logger.Warn("failed to rotate worker auth token",
+						zap.String("worker_id", workerID),
+						zap.Error(err),
+					)
+				}
+			}
+		}
+	}
+}
+
+// RotateWorkerToken issues a worker a new auth token over its stream on behalf
+// of issuer. The old token keeps working for overlap, then is rejected.
+func (m *Master) RotateWorkerToken(workerID string, overlap time.Duration, issuer string) error {
+	token := m.GenerateWorkerAuthToken()
+	if err := m.registry.RotateAuthToken(workerID, token, overlap); err != nil {
+		return err
+	}
+
+	cmd := &pb.MasterCommand{
+		CommandId: fmt.Sprintf("rotate-%s-%d", workerID, time.Now().Unix()),
+		Payload: &pb.MasterCommand_RotateAuthToken{
+			RotateAuthToken: &pb.RotateAuthTokenCommand{
+				AuthToken:          token,
+				PreviousValidUntil: time.Now().Add(overlap).Unix(),
+			},
+		},
+	}
+	if err := m.registry.SendCommand(workerID, cmd, issuer); err != nil {
+		// The worker never saw the new token; keep the old one working
+		m.registry.RevertAuthToken(workerID, token)
+		return fmt.Errorf("failed to send new token: %w", err)
+	}
+
+	m.logger.Info("rotated worker auth token",
+		zap.String("worker_id", workerID),
+		zap.Duration("overlap", overlap),
+	)
+	return nil
+}
@@ -0,0 +1,459 @@
+package master
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	"go.uber.org/zap"
+)
+
+const (
+	apiTokenPrefix = "dmt_"
+
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+	totpSkew   = 1 // Accept codes one period either side of now
+
+	totpMaxFailures   = 5               // Wrong codes allowed per caller within totpFailureWindow
+	totpFailureWindow = 5 * time.Minute // Also how long a caller is locked out after too many
+
+	// totpMaxTotalFailures is the wrong codes allowed from all callers
+	// together within totpFailureWindow. Callers are told apart by client
+	// address when unauthenticated, so this bounds guessing from many.
+	totpMaxTotalFailures = 20
+)
+
+var (
+	// ErrTOTPInvalid means the code was wrong, reused or missing
+	ErrTOTPInvalid = errors.New("invalid totp code")
+	// ErrTOTPRateLimited means the caller got too many codes wrong recently
+	ErrTOTPRateLimited = errors.New("too many wrong totp codes, try again later")
+)
+
+// APIToken is an issued API token. Only a hash of the secret is stored; the
+// plaintext is shown once, at issuance.
+type APIToken struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Role       Role      `json:"role,omitempty"`      // Empty means admin
+	Namespace  string    `json:"namespace,omitempty"` // Confines the token to one namespace; empty sees all
+	Hash       string    `json:"hash"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"` // Zero means never
+	RevokedAt  time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+// Revoked reports whether the token is on the revocation list
+func (t *APIToken) Revoked() bool {
+	return !t.RevokedAt.IsZero()
+}
+
+// Expired reports whether the token is past its expiry
+func (t *APIToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt)
+}
+
+// tokenState is the on-disk form of a TokenStore
+type tokenState struct {
+	Tokens       []*APIToken `json:"tokens"`
+	TOTPSecret   string      `json:"totp_secret,omitempty"`
+	LastTOTPStep int64       `json:"last_totp_step,omitempty"` // Last step any caller used, so codes cannot be replayed
+}
+
+// totpFailures counts a caller's recent wrong codes
+type totpFailures struct {
+	count int
+	since time.Time
+}
+
+// TokenStore issues, validates and revokes master API tokens and holds the
+// optional TOTP secret guarding destructive operations. The file is shared
+// with the CLI, so it is re-read whenever it changes on disk.
+type TokenStore struct {
+	path     string
+	modTime  time.Time
+	state    tokenState
+	failures map[string]*totpFailures // In memory only, by caller
+	total    totpFailures             // In memory only, all callers together
+	logger   *observability.Logger
+	mu       sync.Mutex
+}
+
+// NewTokenStore loads the token store from dataDir
+func NewTokenStore(dataDir string, logger *observability.Logger) (*TokenStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	ts := &TokenStore{
+		path:     filepath.Join(dataDir, "api-tokens.json"),
+		failures: make(map[string]*totpFailures),
+		logger:   logger,
+	}
+	if err := ts.reloadLocked(); err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// reloadLocked re-reads the store if the file changed since it was last read
+func (ts *TokenStore) reloadLocked() error {
+	info, err := os.Stat(ts.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat token store: %w", err)
+	}
+	if info.ModTime().Equal(ts.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(ts.path)
+	if err != nil {
+		return fmt.Errorf("failed to read token store: %w", err)
+	}
+	var state tokenState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse token store: %w", err)
+	}
+
+	ts.state = state
+	ts.modTime = info.ModTime()
+	return nil
+}
+
+func (ts *TokenStore) saveLocked() error {
+	data, err := json.MarshalIndent(ts.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token store: %w", err)
+	}
+
+	tmpPath := ts.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write token store: %w", err)
+	}
+	if err := os.Rename(tmpPath, ts.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save token store: %w", err)
+	}
+
+	if info, err := os.Stat(ts.path); err == nil {
+		ts.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// Issue creates a token with role, confined to namespace unless it is empty
+// and valid for ttl (forever if zero), and returns its plaintext
+func (ts *TokenStore) Issue(name string, role Role, namespace string, ttl time.Duration) (string, *APIToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plaintext := apiTokenPrefix + hex.EncodeToString(secret)
+
+	// The ID is listed and logged, so it shares nothing with the secret
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	now := time.Now()
+	token := &APIToken{
+		ID:        "tok-" + hex.EncodeToString(id),
+		Name:      name,
+		Role:      role,
+		Namespace: namespace,
+		Hash:      hashAPIToken(plaintext),
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		token.ExpiresAt = now.Add(ttl)
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		return "", nil, err
+	}
+	ts.state.Tokens = append(ts.state.Tokens, token)
+	if err := ts.saveLocked(); err != nil {
+		return "", nil, err
+	}
+
+	ts.logger.Info("api token issued",
+		zap.String("token_id", token.ID),
+		zap.String("name", name),
+		zap.String("role", string(role)),
+		zap.String("namespace", namespace),
+		zap.Time("expires_at", token.ExpiresAt),
+	)
+
+	copied := *token
+	return plaintext, &copied, nil
+}
+
+// Revoke adds a token to the revocation list
+func (ts *TokenStore) Revoke(id string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		return err
+	}
+	for _, t := range ts.state.Tokens {
+		if t.ID != id {
+			continue
+		}
+		if t.Revoked() {
+			return nil
+		}
+		t.RevokedAt = time.Now()
+		if err := ts.saveLocked(); err != nil {
+			return err
+		}
+		ts.logger.Info("api token revoked", zap.String("token_id", id))
+		return nil
+	}
+	return fmt.Errorf("token not found: %s", id)
+}
+
+// List returns copies of all tokens, newest first, without their hashes
+func (ts *TokenStore) List() []APIToken {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		ts.logger.Warn("failed to reload token store", zap.Error(err))
+	}
+
+	tokens := make([]APIToken, 0, len(ts.state.Tokens))
+	for _, t := range ts.state.Tokens {
+		copied := *t
+		copied.Hash = ""
+		tokens = append(tokens, copied)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+	return tokens
+}
+
+// Validate checks a presented token and returns its record
+func (ts *TokenStore) Validate(plaintext string) (*APIToken, error) {
+	if !strings.HasPrefix(plaintext, apiTokenPrefix) {
+		return nil, fmt.Errorf("malformed token")
+	}
+	hash := hashAPIToken(plaintext)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		ts.logger.Warn("failed to reload token store", zap.Error(err))
+	}
+
+	now := time.Now()
+	for _, t := range ts.state.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) != 1 {
+			continue
+		}
+		if t.Revoked() {
+			return nil, fmt.Errorf("token revoked")
+		}
+		if t.Expired(now) {
+			return nil, fmt.Errorf("token expired")
+		}
+		// Usage is tracked in memory only; writing on every request would thrash the file
+		t.LastUsedAt = now
+		copied := *t
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("unknown token")
+}
+
+// TOTPEnabled reports whether destructive operations require a TOTP code
+func (ts *TokenStore) TOTPEnabled() bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		ts.logger.Warn("failed to reload token store", zap.Error(err))
+	}
+	return ts.state.TOTPSecret != ""
+}
+
+// EnrollTOTP generates a new TOTP secret, replacing any existing one, and
+// returns it with an otpauth:// URI for authenticator apps
+func (ts *TokenStore) EnrollTOTP(account string) (string, string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		return "", "", err
+	}
+	ts.state.TOTPSecret = secret
+	ts.state.LastTOTPStep = 0
+	if err := ts.saveLocked(); err != nil {
+		return "", "", err
+	}
+
+	ts.logger.Info("totp second factor enrolled")
+
+	uri := fmt.Sprintf("otpauth://totp/%s?secret=%s&issuer=%s&digits=%d&period=%d",
+		url.PathEscape("docker-migrate:"+account), secret, url.QueryEscape("docker-migrate"),
+		totpDigits, int(totpPeriod.Seconds()))
+	return secret, uri, nil
+}
+
+// DisableTOTP removes the TOTP secret
+func (ts *TokenStore) DisableTOTP() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		return err
+	}
+	ts.state.TOTPSecret = ""
+	ts.state.LastTOTPStep = 0
+	if err := ts.saveLocked(); err != nil {
+		return err
+	}
+
+	ts.logger.Info("totp second factor disabled")
+	return nil
+}
+
+// VerifyTOTP checks a code from caller against the enrolled secret. A code
+// is accepted once, from any caller, so an intercepted code cannot be
+// replayed. A caller is locked out for a while after too many wrong codes,
+// and everyone is after too many from all callers together.
+func (ts *TokenStore) VerifyTOTP(caller, code string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if err := ts.reloadLocked(); err != nil {
+		ts.logger.Warn("failed to reload token store", zap.Error(err))
+	}
+
+	now := time.Now()
+	failures := ts.failures[caller]
+	if failures != nil && now.Sub(failures.since) >= totpFailureWindow {
+		delete(ts.failures, caller)
+		failures = nil
+	}
+	if failures != nil && failures.count >= totpMaxFailures {
+		return ErrTOTPRateLimited
+	}
+	if now.Sub(ts.total.since) >= totpFailureWindow {
+		ts.total = totpFailures{}
+	}
+	if ts.total.count >= totpMaxTotalFailures {
+		return ErrTOTPRateLimited
+	}
+
+	if ts.state.TOTPSecret == "" || len(code) != totpDigits {
+		ts.recordTOTPFailureLocked(caller, now)
+		return ErrTOTPInvalid
+	}
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(ts.state.TOTPSecret)
+	if err != nil {
+		ts.logger.Error("invalid totp secret", zap.Error(err))
+		return ErrTOTPInvalid
+	}
+
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= ts.state.LastTOTPStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			ts.state.LastTOTPStep = step
+			if err := ts.saveLocked(); err != nil {
+				ts.logger.Warn("failed to save totp state", zap.Error(err))
+			}
+			delete(ts.failures, caller)
+			return nil
+		}
+	}
+	ts.recordTOTPFailureLocked(caller, now)
+	return ErrTOTPInvalid
+}
+
+// recordTOTPFailureLocked counts a wrong code against caller and against
+// all callers together
+func (ts *TokenStore) recordTOTPFailureLocked(caller string, now time.Time) {
+	if ts.total.count == 0 {
+		ts.total.since = now
+	}
+	ts.total.count++
+	if ts.total.count == totpMaxTotalFailures {
+		ts.logger.Warn("totp attempts locked out for all callers", zap.Int("failures", totpMaxTotalFailures))
+	}
+
+	failures := ts.failures[caller]
+	if failures == nil {
+		failures = &totpFailures{since: now}
+		ts.failures[caller] = failures
+	}
+	failures.count++
+	if failures.count == totpMaxFailures {
+		ts.logger.Warn("totp attempts locked out", zap.String("caller", caller))
+	}
+}
+
+// totpCode computes the RFC 6238 code for a time step
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+func hashAPIToken(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
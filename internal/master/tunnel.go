@@ -0,0 +1,66 @@
+package master
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/audit"
+	"github.com/artemis/docker-migrate/internal/peer"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RegisterTunnelRoutes exposes the WebSocket tunnel that carries worker gRPC
+// traffic (WorkerStream and proxy channels) through restrictive HTTP proxies
+func (m *Master) RegisterTunnelRoutes(rg *gin.RouterGroup) {
+	rg.GET("/tunnel", m.serveTunnel)
+}
+
+// serveTunnel splices an authenticated worker onto the local gRPC listener.
+// The worker presents its auth token, or an enrollment token when it has not
+// registered yet, as a bearer token on the upgrade request.
+func (m *Master) serveTunnel(c *gin.Context) {
+	if !m.tunnelAuthorized(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")) {
+		m.logger.Warn("worker tunnel refused, invalid token",
+			zap.String("remote", c.ClientIP()),
+		)
+		m.securityLog.Record(audit.Entry{
+			Action:  "worker.tunnel",
+			Actor:   CallerIdentity(c),
+			Outcome: audit.OutcomeDenied,
+		})
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "worker token required"})
+		return
+	}
+
+	target := peer.LocalDialAddress(m.config.GRPCAddr)
+
+	m.logger.Debug("worker tunnel opened",
+		zap.String("remote", c.ClientIP()),
+	)
+
+	if err := peer.ServeTunnel(c.Writer, c.Request, target); err != nil {
+		m.logger.Warn("worker tunnel failed",
+			zap.String("remote", c.ClientIP()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	m.logger.Debug("worker tunnel closed",
+		zap.String("remote", c.ClientIP()),
+	)
+}
+
+// tunnelAuthorized reports whether token is a registered worker's auth token
+// or a valid enrollment token
+func (m *Master) tunnelAuthorized(token string) bool {
+	if token == "" {
+		return false
+	}
+	if _, ok := m.registry.GetByAuthToken(token); ok {
+		return true
+	}
+	_, ok := m.ValidateEnrollmentToken(token)
+	return ok
+}
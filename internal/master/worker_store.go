@@ -0,0 +1,148 @@
+package master
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+)
+
+// workerRecord is the on-disk form of an enrolled worker. Only hashes of
+// its auth tokens are kept; inventory and connection state are rebuilt when
+// the worker reconnects.
+type workerRecord struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Hostname       string            `json:"hostname,omitempty"`
+	GRPCAddress    string            `json:"grpc_address,omitempty"`
+	TLSFingerprint string            `json:"tls_fingerprint"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Version        string            `json:"version,omitempty"`
+	OutboundOnly   bool              `json:"outbound_only,omitempty"`
+	Protocol       int32             `json:"protocol,omitempty"`
+	Namespace      string            `json:"namespace,omitempty"`
+
+	AuthTokenHash       string    `json:"auth_token_hash"`
+	PreviousTokenHash   string    `json:"previous_token_hash,omitempty"`
+	PreviousTokenExpiry time.Time `json:"previous_token_expiry,omitempty"`
+	TokenIssuedAt       time.Time `json:"token_issued_at"`
+
+	RegisteredAt  time.Time `json:"registered_at"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+// openWorkerStore points the registry at workers.json under dataDir and
+// loads the workers enrolled before the master last stopped. They come back
+// offline, as if their last heartbeat had just been missed, and are removed
+// as stale if they do not reconnect in time.
+func (r *Registry) openWorkerStore(dataDir string) error {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+	r.path = filepath.Join(dataDir, "workers.json")
+
+	data, err := os.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read worker registry: %w", err)
+	}
+	var records []workerRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("failed to parse worker registry: %w", err)
+	}
+
+	now := time.Now()
+	for _, rec := range records {
+		r.workers[rec.ID] = &WorkerInfo{
+			ID:                  rec.ID,
+			Name:                rec.Name,
+			Hostname:            rec.Hostname,
+			GRPCAddress:         rec.GRPCAddress,
+			TLSFingerprint:      rec.TLSFingerprint,
+			Labels:              rec.Labels,
+			Version:             rec.Version,
+			OutboundOnly:        rec.OutboundOnly,
+			Protocol:            rec.Protocol,
+			Namespace:           rec.Namespace,
+			Status:              pb.WorkerStatus_WORKER_STATUS_UNKNOWN,
+			AuthTokenHash:       rec.AuthTokenHash,
+			PreviousTokenHash:   rec.PreviousTokenHash,
+			PreviousTokenExpiry: rec.PreviousTokenExpiry,
+			TokenIssuedAt:       rec.TokenIssuedAt,
+			RegisteredAt:        rec.RegisteredAt,
+			LastHeartbeat:       now.Add(-r.timeout),
+			Containers:          make([]*pb.ContainerResource, 0),
+			Images:              make([]*pb.ImageResource, 0),
+			Volumes:             make([]*pb.VolumeResource, 0),
+			Networks:            make([]*pb.NetworkResource, 0),
+		}
+	}
+
+	if len(records) > 0 {
+		r.logger.Info("restored enrolled workers", zap.Int("workers", len(records)))
+	}
+	return nil
+}
+
+// saveLocked writes the enrolled workers to disk. A failure is logged
+// rather than returned: the registry stays correct in memory, and only a
+// restart would lose the change.
+func (r *Registry) saveLocked() {
+	if r.path == "" {
+		return
+	}
+
+	records := make([]workerRecord, 0, len(r.workers))
+	for _, w := range r.workers {
+		records = append(records, workerRecord{
+			ID:                  w.ID,
+			Name:                w.Name,
+			Hostname:            w.Hostname,
+			GRPCAddress:         w.GRPCAddress,
+			TLSFingerprint:      w.TLSFingerprint,
+			Labels:              w.Labels,
+			Version:             w.Version,
+			OutboundOnly:        w.OutboundOnly,
+			Protocol:            w.Protocol,
+			Namespace:           w.Namespace,
+			AuthTokenHash:       w.AuthTokenHash,
+			PreviousTokenHash:   w.PreviousTokenHash,
+			PreviousTokenExpiry: w.PreviousTokenExpiry,
+			TokenIssuedAt:       w.TokenIssuedAt,
+			RegisteredAt:        w.RegisteredAt,
+			LastHeartbeat:       w.LastHeartbeat,
+		})
+	}
+
+	if err := r.writeRecords(records); err != nil {
+		r.logger.Warn("failed to save worker registry", zap.Error(err))
+	}
+}
+
+func (r *Registry) writeRecords(records []workerRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal worker registry: %w", err)
+	}
+
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write worker registry: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save worker registry: %w", err)
+	}
+	return nil
+}
@@ -81,6 +81,8 @@ func (a *Auditor) RunAudit(ctx context.Context, job *MigrationJob, resultCh chan
 		{"Bind Mounts", a.checkBindMountsWrapper},
 		{"Name Conflicts", a.checkConflictsWrapper},
 		{"Network Drivers", a.checkNetworkDriversWrapper},
+		{"Volume Drivers", a.checkVolumeDriversWrapper},
+		{"Public Base Images", a.checkPublicBaseImages},
 	}
 
 	// Execute each check
@@ -363,3 +365,117 @@ func (a *Auditor) checkNetworkDrivers(ctx context.Context, peerID string, networ
 	check.EndTime = time.Now()
 	return check
 }
+
+// checkVolumeDriversWrapper wraps volume driver check
+func (a *Auditor) checkVolumeDriversWrapper(ctx context.Context, job *MigrationJob) AuditCheck {
+	volumes := make([]string, 0)
+	for _, res := range job.Resources {
+		if res.Type == "volume" {
+			volumes = append(volumes, res.Name)
+		}
+	}
+	return a.checkVolumeDrivers(ctx, job.PeerID, volumes, job.ReattachSharedVolumes)
+}
+
+// checkVolumeDrivers verifies shared-storage volume drivers exist on the target
+func (a *Auditor) checkVolumeDrivers(ctx context.Context, peerID string, volumes []string, reattach bool) AuditCheck {
+	check := AuditCheck{
+		Name:      "Volume Drivers",
+		Status:    CheckRunning,
+		IsBlocker: true,
+		StartTime: time.Now(),
+	}
+
+	// Collect drivers needed to re-attach shared-storage volumes
+	required := make(map[string][]string)
+	if a.docker != nil {
+		for _, name := range volumes {
+			vol, err := a.docker.InspectVolume(ctx, name)
+			if err != nil || !docker.IsSharedStorageVolume(vol) {
+				continue
+			}
+			required[vol.Driver] = append(required[vol.Driver], name)
+		}
+	}
+
+	if len(required) == 0 {
+		check.Status = CheckPassed
+		check.Message = fmt.Sprintf("No shared-storage volumes (%d volumes)", len(volumes))
+		check.EndTime = time.Now()
+		return check
+	}
+
+	if !reattach {
+		check.Status = CheckPassed
+		check.Message = fmt.Sprintf("Shared-storage volumes will be copied via helper container: %v", required)
+		check.EndTime = time.Now()
+		return check
+	}
+
+	var remoteDrivers []string
+	if a.peers != nil {
+		if p, ok := a.peers.GetPeer(peerID); ok {
+			remoteDrivers = p.VolumeDrivers
+		}
+	}
+	if len(remoteDrivers) == 0 {
+		check.Status = CheckWarning
+		check.IsBlocker = false
+		check.Message = fmt.Sprintf("Could not verify volume drivers on peer %s", peerID)
+		check.EndTime = time.Now()
+		return check
+	}
+
+	available := make(map[string]bool, len(remoteDrivers))
+	for _, d := range remoteDrivers {
+		available[d] = true
+	}
+
+	missing := make([]string, 0)
+	for driver := range required {
+		if !available[driver] {
+			missing = append(missing, driver)
+		}
+	}
+
+	if len(missing) > 0 {
+		check.Status = CheckFailed
+		check.Message = fmt.Sprintf("Volume drivers missing on target: %v", missing)
+	} else {
+		check.Status = CheckPassed
+		check.Message = fmt.Sprintf("All volume drivers available on target (%d drivers)", len(required))
+	}
+
+	check.EndTime = time.Now()
+	return check
+}
+
+// checkPublicBaseImages finds images that are, or are built on, Docker Hub
+// official images, which the target can pull instead of receiving
+func (a *Auditor) checkPublicBaseImages(ctx context.Context, job *MigrationJob) AuditCheck {
+	check := AuditCheck{
+		Name:      "Public Base Images",
+		Status:    CheckRunning,
+		IsBlocker: false,
+		StartTime: time.Now(),
+	}
+
+	pulls := findBasePulls(ctx, a.docker, job)
+	bases := make([]string, 0, len(pulls))
+	for _, p := range pulls {
+		bases = append(bases, fmt.Sprintf("%s (%s)", p.Image, p.Base))
+	}
+
+	check.Status = CheckPassed
+	switch {
+	case len(pulls) == 0:
+		check.Message = "No images built on public base images"
+	case job.PrePullBaseImages:
+		check.Message = fmt.Sprintf("Target will pull %d public base images from Docker Hub: %v", len(pulls), bases)
+	default:
+		check.Message = fmt.Sprintf("%d images use public base images the target could pull from Docker Hub (enable pre_pull_base_images): %v", len(pulls), bases)
+	}
+
+	check.EndTime = time.Now()
+	return check
+}
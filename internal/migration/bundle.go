@@ -0,0 +1,500 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/objectstore"
+
+	"go.uber.org/zap"
+)
+
+// bundleManifestVersion is the manifest layout this build writes and reads
+const bundleManifestVersion = 1
+
+// bundleManifestKey is written last, so a bundle without one is incomplete
+const bundleManifestKey = "manifest.json"
+
+// BundleManifest describes a migration bundle: the resources exported to
+// object storage from one host, for import on another
+type BundleManifest struct {
+	Version    int                   `json:"version"`
+	ID         string                `json:"id"`
+	CreatedAt  time.Time             `json:"created_at"`
+	Host       string                `json:"host,omitempty"`
+	Networks   []*docker.NetworkInfo `json:"networks,omitempty"`
+	Volumes    []BundleVolume        `json:"volumes,omitempty"`
+	Images     []BundleImage         `json:"images,omitempty"`
+	Containers []BundleContainer     `json:"containers,omitempty"`
+}
+
+// BundleObject is one object of a bundle, checked against its digest on import
+type BundleObject struct {
+	Key    string `json:"key"` // Relative to the bundle
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// BundleVolume is a volume's settings and its data as a tar
+type BundleVolume struct {
+	Name    string            `json:"name"`
+	Driver  string            `json:"driver"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Options map[string]string `json:"options,omitempty"`
+	Data    BundleObject      `json:"data"`
+}
+
+// BundleImage is an image saved with docker save
+type BundleImage struct {
+	ID   string       `json:"id"`
+	Tags []string     `json:"tags,omitempty"`
+	Data BundleObject `json:"data"`
+}
+
+// BundleContainer is a container's exported state
+type BundleContainer struct {
+	Name  string       `json:"name"`
+	Image string       `json:"image"`
+	State BundleObject `json:"state"`
+}
+
+// BundleExportRequest selects what goes into a bundle. The images, named
+// volumes and user-defined networks of selected containers are added.
+type BundleExportRequest struct {
+	ID         string // Empty = bundle-<UTC timestamp>
+	Containers []string
+	Images     []string
+	Volumes    []string
+	Networks   []string
+}
+
+// BundleImportOptions controls how a bundle is recreated
+type BundleImportOptions struct {
+	// Start starts the imported containers
+	Start bool
+	// AllowDefaultRuntime creates containers whose runtime is not installed
+	// here under the default runtime instead of failing
+	AllowDefaultRuntime bool
+}
+
+// BundleManager exports resources to, and imports them from, bundles in an
+// S3-compatible bucket. A bundle lives under prefix followed by its ID.
+type BundleManager struct {
+	docker *docker.Client
+	store  *objectstore.Client
+	prefix string
+	logger *zap.Logger
+}
+
+// NewBundleManager creates a bundle manager for the bucket behind store
+func NewBundleManager(dockerClient *docker.Client, store *objectstore.Client, prefix string, logger *zap.Logger) *BundleManager {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &BundleManager{
+		docker: dockerClient,
+		store:  store,
+		prefix: prefix,
+		logger: logger,
+	}
+}
+
+// List returns the IDs of the bundles in the bucket, including incomplete ones
+func (bm *BundleManager) List(ctx context.Context) ([]string, error) {
+	return bm.store.ListPrefixes(ctx, bm.prefix)
+}
+
+// Manifest reads a bundle's manifest
+func (bm *BundleManager) Manifest(ctx context.Context, id string) (*BundleManifest, error) {
+	if err := validateBundleID(id); err != nil {
+		return nil, err
+	}
+	r, err := bm.store.GetObject(ctx, bm.key(id, bundleManifestKey))
+	if err != nil {
+		return nil, fmt.Errorf("bundle %s not found or incomplete: %w", id, err)
+	}
+	defer r.Close()
+
+	var manifest BundleManifest
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	if manifest.Version != bundleManifestVersion {
+		return nil, fmt.Errorf("bundle %s has manifest version %d, this build reads %d", id, manifest.Version, bundleManifestVersion)
+	}
+	return &manifest, nil
+}
+
+// Export uploads the selected resources as a new bundle
+func (bm *BundleManager) Export(ctx context.Context, req BundleExportRequest) (*BundleManifest, error) {
+	id := req.ID
+	if id == "" {
+		id = "bundle-" + time.Now().UTC().Format("20060102-150405")
+	}
+	if err := validateBundleID(id); err != nil {
+		return nil, err
+	}
+	if _, err := bm.Manifest(ctx, id); err == nil {
+		return nil, fmt.Errorf("bundle %s already exists", id)
+	}
+
+	resources, err := bm.exportSelection(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BundleManifest{
+		Version:   bundleManifestVersion,
+		ID:        id,
+		CreatedAt: time.Now().UTC(),
+	}
+	manifest.Host, _ = os.Hostname()
+
+	bm.logger.Info("exporting bundle",
+		zap.String("bundle_id", id),
+		zap.Int("resources", len(resources)),
+	)
+
+	// Images named by both a container and the request are saved once
+	savedImages := make(map[string]bool)
+
+	for _, res := range resources {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		switch res.Type {
+		case "network":
+			info, err := bm.docker.ExportNetwork(ctx, res.ID)
+			if err != nil {
+				return nil, err
+			}
+			// Endpoints belong to the source host's containers
+			info.Containers = nil
+			manifest.Networks = append(manifest.Networks, info)
+
+		case "volume":
+			vol, err := bm.exportVolume(ctx, id, res.ID)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Volumes = append(manifest.Volumes, *vol)
+
+		case "image":
+			info, err := bm.docker.GetImageInfo(ctx, res.ID)
+			if err != nil {
+				return nil, err
+			}
+			if savedImages[info.ID] {
+				continue
+			}
+			savedImages[info.ID] = true
+			img, err := bm.exportImage(ctx, id, info)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Images = append(manifest.Images, *img)
+
+		case "container":
+			ctr, err := bm.exportContainer(ctx, id, res.ID)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Containers = append(manifest.Containers, *ctr)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle manifest: %w", err)
+	}
+	if _, err := bm.store.PutObject(ctx, bm.key(id, bundleManifestKey), strings.NewReader(string(data)), int64(len(data))); err != nil {
+		return nil, err
+	}
+
+	bm.logger.Info("bundle exported", zap.String("bundle_id", id))
+	return manifest, nil
+}
+
+// exportSelection orders the requested resources so each is exported after
+// what it depends on, adding the dependencies of selected containers
+func (bm *BundleManager) exportSelection(ctx context.Context, req BundleExportRequest) ([]ResourceRef, error) {
+	seen := make(map[string]bool)
+	byType := map[string][]ResourceRef{}
+	add := func(ref ResourceRef) {
+		key := ref.Type + ":" + ref.ID
+		if seen[key] || (ref.Type == "network" && builtinNetworks[ref.Name]) {
+			return
+		}
+		seen[key] = true
+		byType[ref.Type] = append(byType[ref.Type], ref)
+	}
+
+	for _, id := range req.Containers {
+		deps, err := containerResources(ctx, bm.docker, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			add(dep)
+		}
+		add(ResourceRef{Type: "container", ID: id, Name: id})
+	}
+	for _, id := range req.Networks {
+		add(ResourceRef{Type: "network", ID: id, Name: id})
+	}
+	for _, name := range req.Volumes {
+		add(ResourceRef{Type: "volume", ID: name, Name: name})
+	}
+	for _, id := range req.Images {
+		add(ResourceRef{Type: "image", ID: id, Name: id})
+	}
+
+	var ordered []ResourceRef
+	for _, t := range []string{"network", "volume", "image", "container"} {
+		ordered = append(ordered, byType[t]...)
+	}
+	if len(ordered) == 0 {
+		return nil, fmt.Errorf("nothing selected to export")
+	}
+	return ordered, nil
+}
+
+func (bm *BundleManager) exportVolume(ctx context.Context, bundleID, name string) (*BundleVolume, error) {
+	info, err := bm.docker.GetVolumeInfo(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := bm.docker.ExportVolume(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	obj, err := bm.put(ctx, bundleID, "volumes/"+name+".tar", r, info.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &BundleVolume{
+		Name:    info.Name,
+		Driver:  info.Driver,
+		Labels:  info.Labels,
+		Options: info.Options,
+		Data:    *obj,
+	}, nil
+}
+
+func (bm *BundleManager) exportImage(ctx context.Context, bundleID string, info *docker.ImageInfo) (*BundleImage, error) {
+	r, err := bm.docker.ExportImage(ctx, info.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	obj, err := bm.put(ctx, bundleID, "images/"+strings.TrimPrefix(info.ID, "sha256:")+".tar", r, info.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &BundleImage{ID: info.ID, Tags: info.RepoTags, Data: *obj}, nil
+}
+
+func (bm *BundleManager) exportContainer(ctx context.Context, bundleID, containerID string) (*BundleContainer, error) {
+	state, err := bm.docker.ExportContainerState(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal container state: %w", err)
+	}
+
+	name := strings.TrimPrefix(state.Name, "/")
+	obj, err := bm.put(ctx, bundleID, "containers/"+name+".json", strings.NewReader(string(data)), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return &BundleContainer{Name: name, Image: state.Image, State: *obj}, nil
+}
+
+// put uploads r, expected to be about expected bytes, under the bundle and
+// records its size and digest
+func (bm *BundleManager) put(ctx context.Context, bundleID, key string, r io.Reader, expected int64) (*BundleObject, error) {
+	h := sha256.New()
+	size, err := bm.store.PutObject(ctx, bm.key(bundleID, key), io.TeeReader(r, h), expected)
+	if err != nil {
+		return nil, err
+	}
+	bm.logger.Info("bundle object uploaded",
+		zap.String("bundle_id", bundleID),
+		zap.String("key", key),
+		zap.Int64("bytes", size),
+	)
+	return &BundleObject{Key: key, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// Import recreates a bundle's resources on this host. Volumes and containers
+// that already exist are refused rather than overwritten; networks that
+// already exist are reused.
+func (bm *BundleManager) Import(ctx context.Context, id string, opts BundleImportOptions) (*BundleManifest, error) {
+	manifest, err := bm.Manifest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked up front so a conflict does not leave a half-imported bundle
+	for _, vol := range manifest.Volumes {
+		if _, err := bm.docker.InspectVolume(ctx, vol.Name); err == nil {
+			return nil, fmt.Errorf("volume %s already exists", vol.Name)
+		}
+	}
+	for _, ctr := range manifest.Containers {
+		if _, err := bm.docker.InspectContainer(ctx, ctr.Name); err == nil {
+			return nil, fmt.Errorf("container %s already exists", ctr.Name)
+		}
+	}
+
+	bm.logger.Info("importing bundle", zap.String("bundle_id", id))
+
+	// Everything recreated is labelled with the bundle it came from
+	provenance := docker.Provenance{
+		Source:     manifest.Host,
+		JobID:      manifest.ID,
+		MigratedAt: time.Now(),
+	}
+
+	for _, info := range manifest.Networks {
+		if _, err := bm.docker.InspectNetwork(ctx, info.Name); err == nil {
+			bm.logger.Info("network already exists, reusing it", zap.String("network", info.Name))
+			continue
+		}
+		labelled := *info
+		provenance.OriginalName = info.Name
+		labelled.Labels = provenance.WithLabels(info.Labels)
+		if _, err := bm.docker.CreateNetwork(ctx, &labelled, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, vol := range manifest.Volumes {
+		provenance.OriginalName = vol.Name
+		labels := provenance.WithLabels(vol.Labels)
+		if _, err := bm.docker.CreateVolumeWithDriver(ctx, vol.Name, vol.Driver, labels, vol.Options); err != nil {
+			return nil, err
+		}
+		err := bm.get(ctx, id, vol.Data, func(r io.Reader) error {
+			return bm.docker.ImportVolume(ctx, vol.Name, r)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to import volume %s: %w", vol.Name, err)
+		}
+	}
+
+	for _, img := range manifest.Images {
+		err := bm.get(ctx, id, img.Data, func(r io.Reader) error {
+			return bm.docker.ImportImage(ctx, r)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to import image %s: %w", img.ID, err)
+		}
+		// Saved by ID, so the archive carries no tags
+		for _, tag := range img.Tags {
+			if err := bm.docker.TagImage(ctx, img.ID, tag); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, ctr := range manifest.Containers {
+		var state docker.ContainerState
+		err := bm.get(ctx, id, ctr.State, func(r io.Reader) error {
+			return json.NewDecoder(r).Decode(&state)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read container %s: %w", ctr.Name, err)
+		}
+
+		if state.Config != nil {
+			provenance.OriginalName = ctr.Name
+			state.Config.Labels = provenance.WithLabels(state.Config.Labels)
+		}
+
+		containerID, report, err := bm.docker.CreateContainer(ctx, &state, ctr.Name, opts.AllowDefaultRuntime)
+		if err != nil {
+			return nil, err
+		}
+		if report != nil && (len(report.Skipped) > 0 || len(report.Warnings) > 0) {
+			bm.logger.Warn("container created without some settings",
+				zap.String("container", ctr.Name),
+				zap.Int("skipped", len(report.Skipped)),
+				zap.Strings("warnings", report.Warnings),
+			)
+		}
+		if opts.Start {
+			if err := bm.docker.StartContainer(ctx, containerID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	bm.logger.Info("bundle imported", zap.String("bundle_id", id))
+	return manifest, nil
+}
+
+// get downloads a bundle object to a temporary file and checks it against
+// the manifest before passing it to consume, so nothing that fails the check
+// is imported
+func (bm *BundleManager) get(ctx context.Context, bundleID string, obj BundleObject, consume func(io.Reader) error) error {
+	f, err := os.CreateTemp("", "docker-migrate-bundle-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", obj.Key, err)
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	h := sha256.New()
+	n, err := bm.store.DownloadObject(ctx, bm.key(bundleID, obj.Key), io.MultiWriter(f, h))
+	if err != nil {
+		return err
+	}
+	if err := verifyBundleObject(obj, n, h); err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read %s: %w", obj.Key, err)
+	}
+	return consume(f)
+}
+
+func verifyBundleObject(obj BundleObject, size int64, h hash.Hash) error {
+	if size != obj.Size {
+		return fmt.Errorf("%s is %d bytes, manifest says %d", obj.Key, size, obj.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != obj.SHA256 {
+		return fmt.Errorf("%s checksum mismatch: got %s, manifest says %s", obj.Key, sum, obj.SHA256)
+	}
+	return nil
+}
+
+// key returns the object key of name in a bundle
+func (bm *BundleManager) key(bundleID, name string) string {
+	return bm.prefix + bundleID + "/" + name
+}
+
+// validateBundleID rejects IDs that would escape or nest under the prefix
+func validateBundleID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, "/\\") {
+		return fmt.Errorf("invalid bundle ID %q", id)
+	}
+	return nil
+}
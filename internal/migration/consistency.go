@@ -0,0 +1,217 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/artemis/docker-migrate/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// groupSnapshotPrefix names the directories group exports are written to
+// under DataDir/snapshots, so ones left by a crash can be found
+const groupSnapshotPrefix = "group-"
+
+// ConsistencyGroup is a set of volumes that must be captured at the same instant,
+// together with the containers writing to them (e.g. a database and its uploads)
+type ConsistencyGroup struct {
+	Name       string   `json:"name"`
+	Containers []string `json:"containers"`
+	Volumes    []string `json:"volumes"`
+}
+
+// GroupSnapshot holds the point-in-time exports of a consistency group's volumes
+type GroupSnapshot struct {
+	Group string
+	Dir   string
+	Files map[string]string // volume name -> tar file
+}
+
+// Cleanup removes the snapshot files
+func (gs *GroupSnapshot) Cleanup() error {
+	return os.RemoveAll(gs.Dir)
+}
+
+// validateConsistencyGroups checks that every group volume is part of the job
+func validateConsistencyGroups(job *MigrationJob) error {
+	volumes := make(map[string]bool)
+	for _, res := range job.Resources {
+		if res.Type == "volume" {
+			volumes[res.Name] = true
+		}
+	}
+
+	seen := make(map[string]string)
+	for _, group := range job.ConsistencyGroups {
+		if group.Name == "" {
+			return fmt.Errorf("consistency group name is required")
+		}
+		if len(group.Volumes) == 0 {
+			return fmt.Errorf("consistency group %s has no volumes", group.Name)
+		}
+		for _, vol := range group.Volumes {
+			if !volumes[vol] {
+				return fmt.Errorf("consistency group %s references volume %s which is not being migrated", group.Name, vol)
+			}
+			if other, ok := seen[vol]; ok {
+				return fmt.Errorf("volume %s is in consistency groups %s and %s", vol, other, group.Name)
+			}
+			seen[vol] = group.Name
+		}
+	}
+
+	return nil
+}
+
+// captureConsistencyGroup freezes all member containers, exports every group volume,
+// then resumes the containers. Only containers that are running are paused.
+func (e *Engine) captureConsistencyGroup(ctx context.Context, job *MigrationJob, group ConsistencyGroup) (*GroupSnapshot, error) {
+	e.logger.Info("capturing consistency group",
+		zap.String("job_id", job.ID),
+		zap.String("group", group.Name),
+		zap.Int("containers", len(group.Containers)),
+		zap.Int("volumes", len(group.Volumes)),
+	)
+
+	// Step 1: Flush and freeze all members
+	paused := make([]string, 0, len(group.Containers))
+	defer func() {
+		for _, id := range paused {
+			if err := e.docker.UnpauseContainer(context.Background(), id); err != nil {
+				e.logger.Error("failed to resume consistency group member",
+					zap.String("group", group.Name),
+					zap.String("container", id),
+					zap.Error(err),
+				)
+			}
+		}
+	}()
+
+	for _, id := range group.Containers {
+		inspect, err := e.docker.InspectContainer(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect group member %s: %w", id, err)
+		}
+		if inspect.State == nil || !inspect.State.Running || inspect.State.Paused {
+			continue
+		}
+
+		if job.QuiesceDatabases {
+			if err := e.quiescer.Quiesce(ctx, id); err != nil {
+				e.logger.Warn("database quiesce failed, continuing with freeze",
+					zap.String("container", id),
+					zap.Error(err),
+				)
+			}
+		}
+
+		if err := e.docker.PauseContainer(ctx, id); err != nil {
+			return nil, fmt.Errorf("failed to pause group member %s: %w", id, err)
+		}
+		paused = append(paused, id)
+		e.rollback.RecordContainerPaused(job.ID, id)
+	}
+
+	// Step 2: Export all volumes while frozen
+	root, err := e.groupSnapshotRoot()
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(root, groupSnapshotPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	snapshot := &GroupSnapshot{
+		Group: group.Name,
+		Dir:   dir,
+		Files: make(map[string]string),
+	}
+
+	for _, vol := range group.Volumes {
+		path := filepath.Join(dir, vol+".tar")
+		if err := e.exportVolumeToFile(ctx, vol, path); err != nil {
+			snapshot.Cleanup()
+			return nil, fmt.Errorf("failed to snapshot volume %s: %w", vol, err)
+		}
+		snapshot.Files[vol] = path
+	}
+
+	// Step 3: Containers resume via the deferred unpause
+	e.logger.Info("consistency group captured",
+		zap.String("job_id", job.ID),
+		zap.String("group", group.Name),
+		zap.Int("paused", len(paused)),
+	)
+
+	return snapshot, nil
+}
+
+// groupSnapshotRoot returns DataDir/snapshots, creating it if needed. Group
+// exports can be as large as the volumes, so they are kept with the rest of
+// the node's state rather than in a tmpfs /tmp.
+func (e *Engine) groupSnapshotRoot() (string, error) {
+	dataDir, err := config.ResolveDataDir(e.config.DataDir)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(dataDir, "snapshots")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	return dir, nil
+}
+
+// exportVolumeToFile writes a volume's tar export to a file
+func (e *Engine) exportVolumeToFile(ctx context.Context, volumeName, path string) error {
+	reader, err := e.docker.ExportVolume(ctx, volumeName)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(file, reader); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return file.Sync()
+}
+
+// captureConsistencyGroups captures every group in the job and returns volume -> snapshot file.
+// The returned cleanup function removes all snapshot files.
+func (e *Engine) captureConsistencyGroups(ctx context.Context, job *MigrationJob) (map[string]string, func(), error) {
+	files := make(map[string]string)
+	snapshots := make([]*GroupSnapshot, 0, len(job.ConsistencyGroups))
+
+	cleanup := func() {
+		for _, s := range snapshots {
+			if err := s.Cleanup(); err != nil {
+				e.logger.Warn("failed to remove group snapshot", zap.String("dir", s.Dir), zap.Error(err))
+			}
+		}
+	}
+
+	for _, group := range job.ConsistencyGroups {
+		snapshot, err := e.captureConsistencyGroup(ctx, job, group)
+		if err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("consistency group %s: %w", group.Name, err)
+		}
+		snapshots = append(snapshots, snapshot)
+		for vol, path := range snapshot.Files {
+			files[vol] = path
+		}
+	}
+
+	return files, cleanup, nil
+}
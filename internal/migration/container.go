@@ -3,6 +3,8 @@ package migration
 import (
 	"context"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/artemis/docker-migrate/internal/docker"
 	"github.com/artemis/docker-migrate/internal/peer"
@@ -15,6 +17,21 @@ type ContainerMigrator struct {
 	docker   *docker.Client
 	transfer *peer.TransferManager
 	logger   *zap.Logger
+
+	// job supplies the naming policy and image rewrites applied on the
+	// target and collects compatibility reports; may be nil
+	job *MigrationJob
+
+	// peers connects to the target to recreate the container
+	peers *peer.PeerDiscovery
+
+	// preStart, if set, runs just before the container is sent to the
+	// target to be started and may hold it there until the target is ready
+	preStart func(ctx context.Context, state *ContainerState) error
+
+	// targetIDs, if set, collects the ID each source container was created
+	// under on the target
+	targetIDs map[string]string
 }
 
 // ContainerState represents complete container configuration for recreation
@@ -90,18 +107,67 @@ func (cm *ContainerMigrator) MigrateContainer(ctx context.Context, containerID,
 		zap.Int("networks", len(state.Networks)),
 	)
 
+	// Recreate under the job's naming policy, if any
+	sourceName := state.Name
+	if cm.job != nil && !cm.job.Naming.IsZero() {
+		cm.job.renameContainerState(state)
+		cm.logger.Info("renaming container on target",
+			zap.String("container", sourceName),
+			zap.String("target_name", state.Name),
+		)
+	}
+
+	// Point the container at the target site's registry
+	if cm.job != nil && len(cm.job.ImageRewrites) > 0 {
+		if image := cm.job.rewriteImage(state.Image); image != state.Image {
+			cm.logger.Info("rewriting container image for target",
+				zap.String("container", state.Name),
+				zap.String("image", state.Image),
+				zap.String("target_image", image),
+			)
+			state.Image = image
+		}
+	}
+
+	// Label the copy with where it came from
+	if cm.job != nil {
+		state.Labels = cm.job.provenance(sourceName).WithLabels(state.Labels)
+	}
+
 	// Step 2: Ensure image exists on target (trigger image migration if needed)
 	// This would check if image exists and call ImageMigrator if not
 
 	// Step 3: Ensure networks exist on target
 	// This would create networks if they don't exist
 
-	// Step 4: Send container state to peer for recreation
-	if err := cm.sendContainerState(ctx, peerID, state); err != nil {
+	// Step 4: Make sure nothing on the target took its name, ports or
+	// volumes while the data was in flight
+	if cm.preStart != nil {
+		if err := cm.preStart(ctx, state); err != nil {
+			return err
+		}
+	}
+
+	// Step 5: Send container state to peer for recreation
+	report, err := cm.sendContainerState(ctx, containerID, sourceName, peerID, state)
+	if err != nil {
 		return fmt.Errorf("failed to send container state: %w", err)
 	}
+	if cm.job != nil {
+		cm.job.recordCompatibility(report)
+	}
+	if cm.targetIDs != nil {
+		cm.targetIDs[containerID] = report.ContainerID
+	}
+	if !report.Clean() {
+		cm.logger.Warn("target could not apply every container setting",
+			zap.String("container", state.Name),
+			zap.Int("skipped", len(report.Skipped)),
+			zap.Int("warnings", len(report.Warnings)),
+		)
+	}
 
-	// Step 5: Handle Move mode - disable source after verification
+	// Step 6: Handle Move mode - disable source after verification
 	if mode == ModeMove {
 		if err := cm.disableSourceContainer(ctx, containerID, state.Name); err != nil {
 			cm.logger.Warn("failed to disable source container",
@@ -142,21 +208,76 @@ func (cm *ContainerMigrator) exportContainerState(ctx context.Context, container
 	return state, nil
 }
 
-// sendContainerState sends container configuration to target for recreation
-func (cm *ContainerMigrator) sendContainerState(ctx context.Context, peerID string, state *ContainerState) error {
+// sendContainerState recreates the container on the target from its full
+// exported configuration, under the name, image and labels in state, and
+// returns the target's report of settings it could not apply
+func (cm *ContainerMigrator) sendContainerState(ctx context.Context, containerID, sourceName, peerID string, state *ContainerState) (*docker.CompatibilityReport, error) {
 	cm.logger.Info("sending container state to target",
 		zap.String("peer_id", peerID),
 		zap.String("container", state.Name),
 	)
 
-	// Would send via gRPC to target peer
-	// Target would:
-	// 1. Validate all dependencies exist (image, volumes, networks)
-	// 2. Create container with exact configuration
-	// 3. Start container
-	// 4. Verify health
+	if cm.peers == nil {
+		return nil, fmt.Errorf("no peer connection to recreate container %s", state.Name)
+	}
 
-	return nil
+	exported, err := cm.docker.ExportContainerState(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export container %s: %w", sourceName, err)
+	}
+	exported.Image = state.Image
+	exported.Config.Image = state.Image
+	if cm.job != nil {
+		exported.Config.Labels = cm.job.provenance(sourceName).WithLabels(exported.Config.Labels)
+	}
+
+	client, err := cm.peers.Connect(ctx, peerID, cm.transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	defer client.Close()
+
+	result, err := client.CreateContainer(ctx, &peer.ContainerCreateRequest{
+		State:               exported,
+		Name:                state.Name,
+		AllowDefaultRuntime: cm.job != nil && cm.job.AllowDefaultRuntime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Report == nil {
+		return &docker.CompatibilityReport{Container: state.Name, ContainerID: result.ID}, nil
+	}
+	return result.Report, nil
+}
+
+// provenance describes a resource the job recreates on its target, with
+// this host as its source
+func (job *MigrationJob) provenance(originalName string) docker.Provenance {
+	host, _ := os.Hostname()
+	return docker.Provenance{
+		Source:       host,
+		JobID:        job.ID,
+		OriginalName: originalName,
+		MigratedAt:   time.Now(),
+	}
+}
+
+// recordCompatibility attaches a container's compatibility report to the
+// job, replacing any from an earlier attempt. Containers are created one at
+// a time, so reports are never recorded concurrently.
+func (job *MigrationJob) recordCompatibility(report *docker.CompatibilityReport) {
+	if report == nil {
+		return
+	}
+
+	for i, existing := range job.Compatibility {
+		if existing.Container == report.Container {
+			job.Compatibility[i] = *report
+			return
+		}
+	}
+	job.Compatibility = append(job.Compatibility, *report)
 }
 
 // disableSourceContainer stops and renames source after successful migration
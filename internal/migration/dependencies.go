@@ -0,0 +1,125 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+)
+
+// DependencyInclusion records resources added to a job because a selected
+// container uses them
+type DependencyInclusion struct {
+	Container ResourceRef   `json:"container"`
+	Added     []ResourceRef `json:"added"`
+}
+
+// builtinNetworks exist on every Docker host and are never migrated
+var builtinNetworks = map[string]bool{
+	"bridge": true,
+	"host":   true,
+	"none":   true,
+}
+
+// containerResources returns the image, named volumes and user-defined
+// networks a container uses
+func containerResources(ctx context.Context, dc *docker.Client, containerID string) ([]ResourceRef, error) {
+	info, err := dc.InspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", containerID, err)
+	}
+
+	var refs []ResourceRef
+
+	imageName := info.Image
+	if info.Config != nil && info.Config.Image != "" {
+		imageName = info.Config.Image
+	}
+	refs = append(refs, ResourceRef{Type: "image", ID: info.Image, Name: imageName})
+
+	for _, m := range info.Mounts {
+		if m.Type == "volume" && m.Name != "" {
+			refs = append(refs, ResourceRef{Type: "volume", ID: m.Name, Name: m.Name})
+		}
+	}
+
+	if info.NetworkSettings != nil {
+		for name, endpoint := range info.NetworkSettings.Networks {
+			if builtinNetworks[name] {
+				continue
+			}
+			id := name
+			if endpoint != nil && endpoint.NetworkID != "" {
+				id = endpoint.NetworkID
+			}
+			refs = append(refs, ResourceRef{Type: "network", ID: id, Name: name})
+		}
+	}
+
+	return refs, nil
+}
+
+// ExpandDependencies adds the images, volumes and networks used by the
+// selected containers to resources, ahead of the containers themselves.
+// Containers that cannot be inspected are left for the preflight audit to
+// report.
+func (e *Engine) ExpandDependencies(ctx context.Context, resources []ResourceRef) ([]ResourceRef, []DependencyInclusion, error) {
+	present := func(ref ResourceRef) bool {
+		for _, r := range resources {
+			if r.Type == ref.Type && (r.ID == ref.ID || r.Name == ref.Name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var inclusions []DependencyInclusion
+	var added []ResourceRef
+
+	for _, res := range resources {
+		if res.Type != "container" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		deps, err := containerResources(ctx, e.docker, res.ID)
+		if err != nil {
+			continue
+		}
+
+		inclusion := DependencyInclusion{Container: res}
+		for _, dep := range deps {
+			if present(dep) {
+				continue
+			}
+			resources = append(resources, dep)
+			added = append(added, dep)
+			inclusion.Added = append(inclusion.Added, dep)
+		}
+		if len(inclusion.Added) > 0 {
+			inclusions = append(inclusions, inclusion)
+		}
+	}
+
+	if len(added) == 0 {
+		return resources, nil, nil
+	}
+
+	// Keep dependencies before containers so sequential strategies have
+	// the data in place when the container is created
+	ordered := make([]ResourceRef, 0, len(resources))
+	for _, r := range resources {
+		if r.Type != "container" {
+			ordered = append(ordered, r)
+		}
+	}
+	for _, r := range resources {
+		if r.Type == "container" {
+			ordered = append(ordered, r)
+		}
+	}
+
+	return ordered, inclusions, nil
+}
@@ -0,0 +1,277 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// containerStartTime is allowed per container for creating and starting
+	// it on the target
+	containerStartTime = 5 * time.Second
+
+	// warmDeltaFraction is the share of volume data assumed to change
+	// between a warm sync and its delta pass
+	warmDeltaFraction = 0.1
+
+	// throughputProbeBytes is streamed to a peer to measure its throughput
+	throughputProbeBytes = 16 * 1024 * 1024
+
+	// throughputProbeTimeout bounds a throughput measurement
+	throughputProbeTimeout = 30 * time.Second
+
+	// throughputTTL is how long a peer's measured throughput is reused
+	throughputTTL = 10 * time.Minute
+)
+
+// measuredThroughput is a peer's throughput from a benchmark
+type measuredThroughput struct {
+	mbps       int
+	measuredAt time.Time
+}
+
+// DowntimeReport compares a job's downtime budget with what it used.
+// Downtime runs from stopping, pausing or checkpointing the source
+// containers until they are started on the target.
+type DowntimeReport struct {
+	// BudgetMs is the job's max_downtime; 0 means none was set
+	BudgetMs    int64 `json:"budget_ms,omitempty"`
+	EstimatedMs int64 `json:"estimated_ms"`
+	// ThroughputMbps is the peer throughput the estimate assumed, as
+	// measured before the job; 0 means it could not be measured and
+	// EstimateTransferTime's default was used
+	ThroughputMbps int        `json:"throughput_mbps,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	ActualMs       *int64     `json:"actual_ms,omitempty"`
+	// Aborted is why cutover was abandoned to stay within the budget
+	Aborted string `json:"aborted,omitempty"`
+}
+
+// EstimateDowntime estimates how long a strategy keeps the source
+// containers down. A cold migration moves everything while they are
+// stopped; warm and live ones only a delta of the volumes and the
+// containers' writable layers. bandwidthMbps is the throughput to the
+// target; 0 uses EstimateTransferTime's default.
+func EstimateDowntime(strategy MigrationStrategy, resources []ResourceRef, estimate *SizeEstimate, bandwidthMbps int) time.Duration {
+	var bytes int64
+	containers := 0
+	for _, res := range resources {
+		size := estimate.Size(res)
+		switch res.Type {
+		case "container":
+			containers++
+			bytes += size
+		case "volume":
+			if strategy == StrategyWarm || strategy == StrategyLive {
+				size = int64(float64(size) * warmDeltaFraction)
+			}
+			bytes += size
+		case "image":
+			if strategy != StrategyWarm && strategy != StrategyLive {
+				bytes += size
+			}
+		}
+	}
+	return EstimateTransferTime(bytes, bandwidthMbps) + time.Duration(containers)*containerStartTime
+}
+
+// throughputProbe is a throughput measurement under way, which callers
+// asking about the same peer wait on instead of starting their own
+type throughputProbe struct {
+	done chan struct{}
+	mbps int
+}
+
+// peerThroughput returns the throughput to a peer in Mbps, measured by
+// streaming benchmark data to it and reused for throughputTTL. It returns 0
+// if the peer cannot be measured. The lock covers only the cache, so a slow
+// peer holds up only the callers asking about it.
+func (e *Engine) peerThroughput(ctx context.Context, peerID string) int {
+	e.throughputMu.Lock()
+	if m, ok := e.throughput[peerID]; ok && time.Since(m.measuredAt) < throughputTTL {
+		e.throughputMu.Unlock()
+		return m.mbps
+	}
+	if e.peers == nil {
+		e.throughputMu.Unlock()
+		return 0
+	}
+	if probe, ok := e.throughputProbes[peerID]; ok {
+		e.throughputMu.Unlock()
+		select {
+		case <-probe.done:
+			return probe.mbps
+		case <-ctx.Done():
+			return 0
+		}
+	}
+	probe := &throughputProbe{done: make(chan struct{})}
+	if e.throughputProbes == nil {
+		e.throughputProbes = make(map[string]*throughputProbe)
+	}
+	e.throughputProbes[peerID] = probe
+	e.throughputMu.Unlock()
+
+	probe.mbps = e.measureThroughput(ctx, peerID)
+
+	e.throughputMu.Lock()
+	delete(e.throughputProbes, peerID)
+	if probe.mbps > 0 {
+		if e.throughput == nil {
+			e.throughput = make(map[string]measuredThroughput)
+		}
+		e.throughput[peerID] = measuredThroughput{mbps: probe.mbps, measuredAt: time.Now()}
+	}
+	e.throughputMu.Unlock()
+	close(probe.done)
+
+	return probe.mbps
+}
+
+// measureThroughput streams benchmark data to a peer and returns the
+// throughput in Mbps, or 0 if the peer cannot be measured
+func (e *Engine) measureThroughput(ctx context.Context, peerID string) int {
+	ctx, cancel := context.WithTimeout(ctx, throughputProbeTimeout)
+	defer cancel()
+
+	client, err := e.peers.Connect(ctx, peerID, e.transfer)
+	if err != nil {
+		e.logger.Warn("cannot measure peer throughput", zap.String("peer_id", peerID), zap.Error(err))
+		return 0
+	}
+	defer client.Close()
+
+	result, err := client.RunPeerBenchmark(ctx, throughputProbeBytes, 0)
+	if err != nil {
+		e.logger.Warn("cannot measure peer throughput", zap.String("peer_id", peerID), zap.Error(err))
+		return 0
+	}
+
+	// ThroughputMBps is in MiB/s and EstimateTransferTime takes Mib/s
+	mbps := int(result.ThroughputMBps * 8)
+	if mbps < 1 {
+		mbps = 1
+	}
+
+	e.logger.Info("measured peer throughput",
+		zap.String("peer_id", peerID),
+		zap.Int("mbps", mbps),
+	)
+	return mbps
+}
+
+// planDowntime checks a job's strategy against its downtime budget. Without
+// a strategy, cold is picked if it fits and warm otherwise. A job whose
+// estimated downtime is over budget is rejected.
+func (e *Engine) planDowntime(ctx context.Context, job *MigrationJob) error {
+	if job.MaxDowntimeMs <= 0 {
+		return nil
+	}
+	budget := time.Duration(job.MaxDowntimeMs) * time.Millisecond
+	estimate := e.EstimateSizes(ctx, job.Resources)
+	mbps := e.peerThroughput(ctx, job.PeerID)
+
+	if job.Strategy == "" {
+		job.Strategy = StrategyCold
+		if EstimateDowntime(StrategyCold, job.Resources, estimate, mbps) > budget {
+			job.Strategy = StrategyWarm
+		}
+		e.logger.Info("picked strategy for downtime budget",
+			zap.String("job_id", job.ID),
+			zap.String("strategy", string(job.Strategy)),
+			zap.Duration("max_downtime", budget),
+		)
+	}
+
+	expected := EstimateDowntime(job.Strategy, job.Resources, estimate, mbps)
+	if expected > budget {
+		return fmt.Errorf("estimated downtime %s exceeds max_downtime %s with the %s strategy",
+			expected.Round(time.Second), budget, job.Strategy)
+	}
+
+	job.Downtime = &DowntimeReport{
+		BudgetMs:       job.MaxDowntimeMs,
+		EstimatedMs:    expected.Milliseconds(),
+		ThroughputMbps: mbps,
+	}
+	return nil
+}
+
+// beginDowntime marks the source containers going down. With a budget, the
+// job is cancelled once there is no longer time to start its containers on
+// the target within it, so cutover is abandoned and the job rolls back.
+// Call the returned function once they have started; it records the actual
+// downtime and may be called again.
+func (e *Engine) beginDowntime(job *MigrationJob) func() {
+	start := time.Now()
+
+	e.jobsMutex.Lock()
+	if job.Downtime == nil {
+		job.Downtime = &DowntimeReport{}
+	}
+	started := start.UTC()
+	job.Downtime.StartedAt = &started
+	job.Downtime.ActualMs = nil
+	budget := time.Duration(job.Downtime.BudgetMs) * time.Millisecond
+	e.jobsMutex.Unlock()
+
+	var timer *time.Timer
+	if budget > 0 {
+		containers := 0
+		for _, res := range job.Resources {
+			if res.Type == "container" {
+				containers++
+			}
+		}
+		// Leave time to start the containers, but never abort before half
+		// the budget is used
+		deadline := budget - time.Duration(containers)*containerStartTime
+		if deadline < budget/2 {
+			deadline = budget / 2
+		}
+
+		timer = time.AfterFunc(deadline, func() {
+			e.jobsMutex.Lock()
+			job.Downtime.Aborted = fmt.Sprintf("downtime reached %s of the %s budget before the containers could start on the target",
+				deadline.Round(time.Second), budget)
+			e.jobsMutex.Unlock()
+			e.logger.Warn("downtime budget would be exceeded, aborting cutover",
+				zap.String("job_id", job.ID),
+				zap.Duration("max_downtime", budget),
+			)
+			if job.cancel != nil {
+				job.cancel()
+			}
+		})
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if timer != nil {
+				timer.Stop()
+			}
+			actual := time.Since(start).Milliseconds()
+			e.jobsMutex.Lock()
+			job.Downtime.ActualMs = &actual
+			e.jobsMutex.Unlock()
+			e.logger.Info("source downtime ended",
+				zap.String("job_id", job.ID),
+				zap.Duration("downtime", time.Duration(actual)*time.Millisecond),
+			)
+		})
+	}
+}
+
+// downtimeAbort returns why the job abandoned cutover for its downtime
+// budget, or nil if it did not
+func (job *MigrationJob) downtimeAbort() error {
+	if job.Downtime == nil || job.Downtime.Aborted == "" {
+		return nil
+	}
+	return fmt.Errorf("cutover aborted: %s", job.Downtime.Aborted)
+}
@@ -9,8 +9,16 @@ type DryRunResult struct {
 	Operations         []Operation   `json:"operations"`
 	TotalTransferBytes int64         `json:"total_transfer_bytes"`
 	EstimatedDuration  time.Duration `json:"estimated_duration"`
-	Warnings           []string      `json:"warnings"`
-	Blockers           []string      `json:"blockers"`
+	// EstimatedDowntime is how long the source containers are expected to be down
+	EstimatedDowntime time.Duration `json:"estimated_downtime"`
+	// Strategy is the job's strategy, or the one picked for its downtime budget
+	Strategy MigrationStrategy `json:"strategy,omitempty"`
+	Warnings []string          `json:"warnings"`
+	Blockers []string          `json:"blockers"`
+	// SelectorExpansions shows what each label/pattern selector resolved to
+	SelectorExpansions []SelectorExpansion `json:"selector_expansions,omitempty"`
+	// IncludedDependencies shows resources added because a selected container uses them
+	IncludedDependencies []DependencyInclusion `json:"included_dependencies,omitempty"`
 }
 
 // Operation represents a single migration operation
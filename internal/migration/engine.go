@@ -2,16 +2,20 @@ package migration
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/events"
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/artemis/docker-migrate/internal/peer"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // Engine orchestrates migration operations with comprehensive state management
@@ -26,6 +30,17 @@ type Engine struct {
 	auditor     *Auditor
 	pathMapper  *PathMapper
 	conflict    *ConflictResolver
+	quiescer    *Quiescer
+	store       *JobStore
+	history     *HistoryStore
+	templates   *TemplateStore
+	schedules   *ScheduleStore
+	events      *events.Bus
+	jobLogs     *JobLogs
+	signer      *peer.CryptoManager
+
+	// Root context; jobs run under it rather than the request that started them
+	ctx context.Context
 
 	// Job management with thread-safe access
 	jobs      map[string]*MigrationJob
@@ -33,6 +48,12 @@ type Engine struct {
 
 	// Progress channels for real-time updates
 	progressChan chan MigrationUpdate
+
+	// Measured throughput to each peer, for downtime estimates, and the
+	// measurements under way
+	throughput       map[string]measuredThroughput
+	throughputProbes map[string]*throughputProbe
+	throughputMu     sync.Mutex
 }
 
 // MigrationJob represents a complete migration operation with full lifecycle tracking
@@ -57,6 +78,63 @@ type MigrationJob struct {
 	// User-provided configuration
 	PathMappings        map[string]PathMapping      `json:"path_mappings,omitempty"`
 	ConflictResolutions map[string]Resolution       `json:"conflict_resolutions,omitempty"`
+	ReattachSharedVolumes bool                     `json:"reattach_shared_volumes,omitempty"`
+	// AllowDefaultRuntime runs containers under the target's default runtime when theirs is not installed there
+	AllowDefaultRuntime   bool                     `json:"allow_default_runtime,omitempty"`
+	QuiesceDatabases      bool                     `json:"quiesce_databases,omitempty"`
+	ConsistencyGroups     []ConsistencyGroup       `json:"consistency_groups,omitempty"`
+	Verification          VerificationLevel        `json:"verification,omitempty"`
+	// Priority "high" pauses normal-priority transfers until this job's transfers finish
+	Priority              string                   `json:"priority,omitempty"`
+	// SelectorExpansions records what label/pattern selectors resolved to at creation
+	SelectorExpansions    []SelectorExpansion      `json:"selector_expansions,omitempty"`
+	// IncludedDependencies lists resources added because a selected container uses them
+	IncludedDependencies  []DependencyInclusion    `json:"included_dependencies,omitempty"`
+	// Naming renames containers and volumes on the target instead of resolving conflicts one by one
+	Naming                *NamingPolicy            `json:"naming,omitempty"`
+	// ImageRewrites point recreated containers and images at the target site's registry
+	ImageRewrites         docker.ImageRewriteRules `json:"image_rewrites,omitempty"`
+	// ImageMode "reference" has the target pull images from its registry instead of streaming
+	// layers; "registry" sends them through the configured intermediary registry
+	ImageMode             ImageTransferMode        `json:"image_mode,omitempty"`
+	// PrePullBaseImages has the target pull public base images from Docker Hub while other layers stream
+	PrePullBaseImages     bool                     `json:"pre_pull_base_images,omitempty"`
+	// Compatibility lists, per container, settings the target could not apply
+	Compatibility         []docker.CompatibilityReport `json:"compatibility,omitempty"`
+	// LiveFallback is why a live migration ran as a warm one instead
+	LiveFallback          string                   `json:"live_fallback,omitempty"`
+	// ColdStarted lists containers the target started fresh because their checkpoint would not restore
+	ColdStarted           []string                 `json:"cold_started,omitempty"`
+	// ClockSkewMs is how far the peer's clock was ahead of ours when the job
+	// started; unset when the peer does not report its time
+	ClockSkewMs           *int64                   `json:"clock_skew_ms,omitempty"`
+	// StartConflicts is what keeps the next container from starting on the
+	// target; the job stays paused until they are resolved and it is resumed
+	StartConflicts        []docker.StartConflict   `json:"start_conflicts,omitempty"`
+	// Integrity lists the checksum of every transferred volume and image,
+	// signed when the job finishes
+	Integrity             *IntegrityReport         `json:"integrity,omitempty"`
+	// MaxDowntimeMs is the longest the source containers may be down; cutover
+	// is abandoned rather than exceed it. 0 means no limit.
+	MaxDowntimeMs         int64                    `json:"max_downtime_ms,omitempty"`
+	// Downtime is the estimated and measured downtime of the source containers
+	Downtime              *DowntimeReport          `json:"downtime,omitempty"`
+	// ScheduleID is the schedule that started the job, if any
+	ScheduleID            string                   `json:"schedule_id,omitempty"`
+	// Prestage records images and volume baselines sent ahead of the migration window
+	Prestage              *PrestageReport          `json:"prestage,omitempty"`
+	// Transfers holds the last reported status of each volume and image transfer
+	Transfers             []TransferState          `json:"transfers,omitempty"`
+	// AllowRemigration runs the job even though the target already runs a
+	// copy of one of its containers, or the original of one
+	AllowRemigration      bool                     `json:"allow_remigration,omitempty"`
+	// MigratedCopies lists the live copies found on the target when the job started
+	MigratedCopies        []MigratedCopy           `json:"migrated_copies,omitempty"`
+	// HealthWindowMs is how long the containers have to become healthy on the
+	// target; 0 uses the configured window
+	HealthWindowMs        int64                    `json:"health_window_ms,omitempty"`
+	// Health is how the containers fared on the target once started
+	Health                *HealthReport            `json:"health,omitempty"`
 
 	// Internal control
 	ctx       context.Context
@@ -78,18 +156,21 @@ const (
 	StrategyCold     MigrationStrategy = "cold"     // Stop → Transfer → Start
 	StrategyWarm     MigrationStrategy = "warm"     // Sync while running → Pause → Delta → Cutover
 	StrategySnapshot MigrationStrategy = "snapshot" // LVM/ZFS snapshot → Transfer
+	StrategyLive     MigrationStrategy = "live"     // Sync while running → Checkpoint → Delta → Restore
 )
 
 type MigrationStatus string
 
 const (
-	StatusPending   MigrationStatus = "pending"
+	StatusPending   MigrationStatus = "pending"   // Created on hold; waits to be started
+	StatusStaging   MigrationStatus = "staging"   // Held job pre-staging images and volume baselines
 	StatusPreflight MigrationStatus = "preflight" // Running audit checks
 	StatusRunning   MigrationStatus = "running"
 	StatusPaused    MigrationStatus = "paused"
 	StatusComplete  MigrationStatus = "complete"
 	StatusFailed    MigrationStatus = "failed"
 	StatusRollingBack MigrationStatus = "rolling_back"
+	StatusInterrupted MigrationStatus = "interrupted" // Daemon restarted mid-transfer; resumable
 )
 
 // MigrationProgress tracks detailed progress with time estimation
@@ -103,6 +184,8 @@ type MigrationProgress struct {
 	BytesDone     int64     `json:"bytes_done"`
 	StartTime     time.Time `json:"start_time"`
 	EstimatedEnd  time.Time `json:"estimated_end"`
+	// Phase is "checkpoint" or "restore" while the live strategy is in one
+	Phase         string    `json:"phase,omitempty"`
 
 	// Per-resource checksums for verification
 	Checksums     map[string]string `json:"checksums,omitempty"`
@@ -137,6 +220,7 @@ type MigrationUpdate struct {
 
 // NewEngine creates a migration engine with all dependencies
 func NewEngine(
+	ctx context.Context,
 	dockerClient *docker.Client,
 	peers *peer.PeerDiscovery,
 	transfer *peer.TransferManager,
@@ -144,6 +228,12 @@ func NewEngine(
 	logger *zap.Logger,
 	metrics *observability.Metrics,
 ) *Engine {
+	// Lines tagged with a job_id are also kept per job for the UI
+	jobLogs := NewJobLogs(DefaultJobLogLines)
+	logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, jobLogs.Core())
+	}))
+
 	engine := &Engine{
 		docker:       dockerClient,
 		peers:        peers,
@@ -153,17 +243,193 @@ func NewEngine(
 		metrics:      metrics,
 		jobs:         make(map[string]*MigrationJob),
 		progressChan: make(chan MigrationUpdate, 100),
+		jobLogs:      jobLogs,
+		ctx:          ctx,
 	}
 
 	// Initialize sub-components
-	engine.rollback = NewRollbackManager(dockerClient, logger)
+	engine.rollback = NewRollbackManager(dockerClient, cfg.DataDir, logger)
+	engine.rollback.SetPeers(peers, transfer)
 	engine.auditor = NewAuditor(dockerClient, peers, logger)
 	engine.pathMapper = NewPathMapper()
 	engine.conflict = NewConflictResolver(dockerClient, peers, logger)
+	engine.quiescer = NewQuiescer(dockerClient, logger)
+
+	// Reflect transfer failures, stalls and completions on jobs as they happen
+	if transfer != nil {
+		transfer.OnStatusChange(engine.handleTransferEvent)
+	}
+
+	// Job persistence is best-effort; the engine still works in-memory without it
+	store, err := NewJobStore(cfg.DataDir, logger)
+	if err != nil {
+		logger.Warn("job persistence disabled", zap.Error(err))
+	} else {
+		engine.store = store
+	}
+
+	history, err := NewHistoryStore(cfg.DataDir, logger)
+	if err != nil {
+		logger.Warn("migration history disabled", zap.Error(err))
+	} else {
+		engine.history = history
+	}
+
+	templates, err := NewTemplateStore(cfg.DataDir, logger)
+	if err != nil {
+		logger.Warn("migration templates disabled", zap.Error(err))
+	} else {
+		engine.templates = templates
+	}
+
+	schedules, err := NewScheduleStore(cfg.DataDir, logger)
+	if err != nil {
+		logger.Warn("migration schedules disabled", zap.Error(err))
+	} else {
+		engine.schedules = schedules
+	}
+
+	if engine.store != nil {
+		engine.recoverJobs()
+	}
 
 	return engine
 }
 
+// recoverJobs reloads persisted jobs and settles any that were interrupted by a restart.
+// Jobs that died during execution keep their transfer checkpoints and become resumable;
+// jobs that died before changing anything, or mid-rollback, are marked failed.
+func (e *Engine) recoverJobs() {
+	jobs, err := e.store.LoadAll()
+	if err != nil {
+		e.logger.Warn("failed to load persisted jobs", zap.Error(err))
+		return
+	}
+
+	e.jobsMutex.Lock()
+	defer e.jobsMutex.Unlock()
+
+	for _, job := range jobs {
+		switch job.Status {
+		case StatusComplete, StatusFailed, StatusInterrupted:
+			// Terminal or already settled
+		case StatusRunning, StatusPaused:
+			if job.CurrentPhase == "execution" {
+				job.Status = StatusInterrupted
+				job.CanResume = true
+				job.Errors = append(job.Errors, MigrationError{
+					Timestamp:   time.Now(),
+					Phase:       job.CurrentPhase,
+					Message:     "interrupted by daemon restart",
+					Recoverable: true,
+				})
+			} else {
+				e.failRecoveredJob(job, "interrupted by daemon restart")
+			}
+		case StatusRollingBack:
+			e.failRecoveredJob(job, "rollback interrupted by daemon restart; source may need manual recovery")
+		case StatusPending:
+			// Held until started; nothing was changed yet
+		case StatusStaging:
+			// Pre-staging only copies to the target, so the job can stage again
+			job.Status = StatusPending
+			if job.Prestage != nil {
+				job.Prestage.Error = "interrupted by daemon restart"
+			}
+		default:
+			// Preflight: nothing was changed yet
+			e.failRecoveredJob(job, "interrupted by daemon restart before execution")
+		}
+
+		e.jobs[job.ID] = job
+		if err := e.store.Save(job); err != nil {
+			e.logger.Warn("failed to persist recovered job", zap.String("job_id", job.ID), zap.Error(err))
+		}
+		// Also fills in history for jobs that finished before it existed
+		e.recordHistory(job)
+	}
+
+	if len(jobs) > 0 {
+		e.logger.Info("recovered persisted jobs", zap.Int("count", len(jobs)))
+	}
+}
+
+// failRecoveredJob marks a recovered job as failed
+func (e *Engine) failRecoveredJob(job *MigrationJob, message string) {
+	now := time.Now()
+	job.Status = StatusFailed
+	job.CanResume = false
+	if job.EndTime == nil {
+		job.EndTime = &now
+	}
+	job.Errors = append(job.Errors, MigrationError{
+		Timestamp:   now,
+		Phase:       job.CurrentPhase,
+		Message:     message,
+		Recoverable: false,
+	})
+}
+
+// SetEventBus publishes job status and phase changes on bus
+func (e *Engine) SetEventBus(bus *events.Bus) {
+	e.events = bus
+}
+
+// publishJob announces a job's current status and phase on the event bus.
+// Call it after a status or phase change, once the job has been persisted.
+func (e *Engine) publishJob(job *MigrationJob) {
+	e.jobsMutex.RLock()
+	state := events.MigrationState{
+		JobID:  job.ID,
+		PeerID: job.PeerID,
+		Status: string(job.Status),
+		Phase:  job.CurrentPhase,
+	}
+	e.jobsMutex.RUnlock()
+
+	e.events.Publish(events.Event{
+		Type: events.MigrationUpdated,
+		Data: state,
+	})
+}
+
+// persistJob saves the job record, logging rather than failing on error
+func (e *Engine) persistJob(job *MigrationJob) {
+	// Transfers, health checks and integrity checks update the job under
+	// jobsMutex while it runs, so a copy taken under the lock is saved
+	e.jobsMutex.RLock()
+	data, err := json.Marshal(job)
+	e.jobsMutex.RUnlock()
+	if err != nil {
+		e.logger.Warn("failed to persist job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	saved := new(MigrationJob)
+	if err := json.Unmarshal(data, saved); err != nil {
+		e.logger.Warn("failed to persist job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return
+	}
+	job = saved
+
+	e.recordHistory(job)
+
+	if e.store == nil {
+		return
+	}
+	if err := e.store.Save(job); err != nil {
+		e.logger.Warn("failed to persist job",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
 // StartMigration begins a new migration job with preflight checks
 func (e *Engine) StartMigration(ctx context.Context, job *MigrationJob) error {
 	e.logger.Info("starting migration",
@@ -173,22 +439,78 @@ func (e *Engine) StartMigration(ctx context.Context, job *MigrationJob) error {
 		zap.String("strategy", string(job.Strategy)),
 	)
 
+	if err := e.prepareJob(ctx, job); err != nil {
+		return err
+	}
+	return e.launchJob(job)
+}
+
+// prepareJob validates a job's options and settles its strategy
+func (e *Engine) prepareJob(ctx context.Context, job *MigrationJob) error {
+	if err := validateConsistencyGroups(job); err != nil {
+		return fmt.Errorf("invalid consistency groups: %w", err)
+	}
+
+	level, err := ParseVerificationLevel(string(job.Verification))
+	if err != nil {
+		return err
+	}
+	job.Verification = level
+
+	if job.Priority != "" && job.Priority != "normal" && job.Priority != "high" {
+		return fmt.Errorf("unknown priority %q (expected normal or high)", job.Priority)
+	}
+
+	if err := job.Naming.Validate(); err != nil {
+		return err
+	}
+
+	if err := job.ImageRewrites.Validate(); err != nil {
+		return err
+	}
+
+	imageMode, err := ParseImageTransferMode(string(job.ImageMode))
+	if err != nil {
+		return err
+	}
+	if imageMode == ImageRegistry && e.config.ImageRegistry == nil {
+		return fmt.Errorf("image mode registry needs image_registry in the config")
+	}
+	job.ImageMode = imageMode
+
+	if err := e.checkMigratedCopies(ctx, job); err != nil {
+		return err
+	}
+
+	if err := e.planDowntime(ctx, job); err != nil {
+		return err
+	}
+	// Without a budget to pick one by, a job with no strategy runs cold
+	if job.Strategy == "" {
+		job.Strategy = StrategyCold
+	}
+	return nil
+}
+
+// launchJob takes a rollback snapshot and runs the job in the background
+func (e *Engine) launchJob(job *MigrationJob) error {
+	held := job.Status
+
 	// Initialize job runtime state
-	job.ctx, job.cancel = context.WithCancel(ctx)
+	job.ctx, job.cancel = context.WithCancel(peer.WithPriority(e.ctx, peer.ParseTransferPriority(job.Priority)))
 	job.pauseChan = make(chan struct{})
 	job.resumeChan = make(chan struct{})
 	job.StartTime = time.Now()
 	job.Status = StatusPreflight
 	job.Progress.StartTime = time.Now()
-
-	// Register job for tracking
-	e.jobsMutex.Lock()
-	e.jobs[job.ID] = job
-	e.jobsMutex.Unlock()
+	e.recordClockSkew(job)
 
 	// Create rollback snapshot BEFORE any changes
-	snapshot, err := e.rollback.CreateSnapshot(job.ID)
+	snapshot, err := e.rollback.CreateSnapshot(job.ctx, job)
 	if err != nil {
+		// A held job stays held
+		job.cancel()
+		job.Status = held
 		return fmt.Errorf("failed to create rollback snapshot: %w", err)
 	}
 	e.logger.Info("created rollback snapshot",
@@ -196,29 +518,60 @@ func (e *Engine) StartMigration(ctx context.Context, job *MigrationJob) error {
 		zap.Time("timestamp", snapshot.Timestamp),
 	)
 
+	// Register job for tracking
+	e.jobsMutex.Lock()
+	e.jobs[job.ID] = job
+	e.jobsMutex.Unlock()
+	e.persistJob(job)
+	e.publishJob(job)
+
 	// Run in background to allow immediate return
 	go e.executeMigration(job)
 
 	return nil
 }
 
+// recordClockSkew notes the peer's last measured clock skew on job, so
+// timestamps from the two hosts can be compared
+func (e *Engine) recordClockSkew(job *MigrationJob) {
+	if e.peers == nil {
+		return
+	}
+	p, ok := e.peers.GetPeer(job.PeerID)
+	if !ok || !p.ClockSkewKnown {
+		return
+	}
+	skewMs := p.ClockSkew.Milliseconds()
+	job.ClockSkewMs = &skewMs
+	if peer.ClockSkewExceeded(p.ClockSkew) {
+		e.logger.Warn("peer clock differs from ours; its timestamps are offset in this job",
+			zap.String("job_id", job.ID),
+			zap.String("peer_id", job.PeerID),
+			zap.Duration("clock_skew", p.ClockSkew),
+		)
+	}
+}
+
 // executeMigration runs the full migration lifecycle
 func (e *Engine) executeMigration(job *MigrationJob) {
 	var finalErr error
 
 	defer func() {
-		// Final status update
+		// Final status update. Transfer events append to job.Errors from
+		// other goroutines, so the job is only changed under jobsMutex.
 		now := time.Now()
-		job.EndTime = &now
 
 		if finalErr != nil {
-			job.Status = StatusFailed
+			e.jobsMutex.Lock()
+			job.EndTime = &now
 			job.Errors = append(job.Errors, MigrationError{
 				Timestamp:   time.Now(),
 				Phase:       job.CurrentPhase,
 				Message:     finalErr.Error(),
 				Recoverable: false,
 			})
+			job.Status = StatusRollingBack
+			e.jobsMutex.Unlock()
 
 			// Attempt rollback on failure
 			e.logger.Warn("migration failed, attempting rollback",
@@ -226,8 +579,12 @@ func (e *Engine) executeMigration(job *MigrationJob) {
 				zap.Error(finalErr),
 			)
 
-			job.Status = StatusRollingBack
-			if rbErr := e.rollback.Rollback(job.ID); rbErr != nil {
+			rbCtx, rbCancel := context.WithTimeout(e.ctx, RollbackTimeout)
+			rbErr := e.rollback.Rollback(rbCtx, job.ID)
+			rbCancel()
+
+			e.jobsMutex.Lock()
+			if rbErr != nil {
 				e.logger.Error("rollback failed",
 					zap.String("job_id", job.ID),
 					zap.Error(rbErr),
@@ -239,25 +596,37 @@ func (e *Engine) executeMigration(job *MigrationJob) {
 					Recoverable: false,
 				})
 			}
+			job.Status = StatusFailed
+			e.jobsMutex.Unlock()
 		} else {
+			e.jobsMutex.Lock()
+			job.EndTime = &now
 			job.Status = StatusComplete
+			e.jobsMutex.Unlock()
 			e.logger.Info("migration completed successfully",
 				zap.String("job_id", job.ID),
 				zap.Duration("duration", time.Since(job.StartTime)),
 			)
 		}
 
+		e.sealIntegrityReport(job)
+		e.persistJob(job)
+		e.publishJob(job)
+
 		// Send final update
+		e.jobsMutex.RLock()
+		progress := job.Progress
+		var lastErr *MigrationError
+		if len(job.Errors) > 0 {
+			migErr := job.Errors[len(job.Errors)-1]
+			lastErr = &migErr
+		}
+		e.jobsMutex.RUnlock()
 		e.progressChan <- MigrationUpdate{
 			Type:     "complete",
 			JobID:    job.ID,
-			Progress: &job.Progress,
-			Error: func() *MigrationError {
-				if len(job.Errors) > 0 {
-					return &job.Errors[len(job.Errors)-1]
-				}
-				return nil
-			}(),
+			Progress: &progress,
+			Error:    lastErr,
 		}
 
 		// Record metrics
@@ -266,6 +635,8 @@ func (e *Engine) executeMigration(job *MigrationJob) {
 
 	// Phase 1: Pre-flight audit
 	job.CurrentPhase = "audit"
+	e.persistJob(job)
+	e.publishJob(job)
 	auditResult, err := e.runAudit(job)
 	if err != nil {
 		finalErr = fmt.Errorf("audit failed: %w", err)
@@ -280,6 +651,8 @@ func (e *Engine) executeMigration(job *MigrationJob) {
 	// Phase 2: Execute strategy
 	job.CurrentPhase = "execution"
 	job.Status = StatusRunning
+	e.persistJob(job)
+	e.publishJob(job)
 
 	strategy, err := e.getStrategy(job.Strategy)
 	if err != nil {
@@ -288,17 +661,21 @@ func (e *Engine) executeMigration(job *MigrationJob) {
 	}
 
 	progressCh := make(chan MigrationProgress, 10)
+	defer close(progressCh)
 	go e.streamProgress(job.ID, progressCh)
 
 	if err := strategy.ExecuteMigration(job.ctx, job, progressCh); err != nil {
+		if abort := job.downtimeAbort(); abort != nil {
+			err = abort
+		}
 		finalErr = fmt.Errorf("migration execution failed: %w", err)
 		return
 	}
 
-	close(progressCh)
-
 	// Phase 3: Post-migration verification
 	job.CurrentPhase = "verification"
+	e.persistJob(job)
+	e.publishJob(job)
 	if err := e.verifyMigration(job); err != nil {
 		finalErr = fmt.Errorf("verification failed: %w", err)
 		return
@@ -330,23 +707,59 @@ func (e *Engine) runAudit(job *MigrationJob) (*AuditResult, error) {
 	return result, err
 }
 
-// streamProgress forwards progress updates to WebSocket
+// streamProgress records progress updates on the job and forwards them to
+// WebSocket at the configured granularity. An update held back is sent once
+// the interval passes, so the last one of a burst is never lost.
 func (e *Engine) streamProgress(jobID string, progressCh <-chan MigrationProgress) {
-	for progress := range progressCh {
-		e.jobsMutex.Lock()
-		if job, exists := e.jobs[jobID]; exists {
-			job.Progress = progress
-		}
-		e.jobsMutex.Unlock()
+	throttle := e.transfer.NewProgressThrottle()
+	ticker := time.NewTicker(throttle.Interval())
+	defer ticker.Stop()
+
+	var pending *MigrationProgress
+	for {
+		select {
+		case progress, ok := <-progressCh:
+			if !ok {
+				if pending != nil {
+					e.publishProgress(jobID, *pending)
+				}
+				return
+			}
 
-		e.progressChan <- MigrationUpdate{
-			Type:     "progress",
-			JobID:    jobID,
-			Progress: &progress,
+			if progress.EstimatedEnd.IsZero() {
+				progress.EstimatedEnd = peer.EstimateEnd(progress.StartTime, time.Now(), progress.BytesDone, progress.BytesTotal)
+			}
+
+			e.jobsMutex.Lock()
+			if job, exists := e.jobs[jobID]; exists {
+				job.Progress = progress
+			}
+			e.jobsMutex.Unlock()
+
+			if throttle.Allow(progress.BytesDone) {
+				e.publishProgress(jobID, progress)
+				pending = nil
+			} else {
+				pending = &progress
+			}
+		case <-ticker.C:
+			if pending != nil && throttle.Allow(pending.BytesDone) {
+				e.publishProgress(jobID, *pending)
+				pending = nil
+			}
 		}
 	}
 }
 
+// publishProgress sends a progress update to WebSocket
+func (e *Engine) publishProgress(jobID string, progress MigrationProgress) {
+	e.progressChan <- MigrationUpdate{
+		Type:     "progress",
+		JobID:    jobID,
+		Progress: &progress,
+	}
+}
+
 // getStrategy returns the appropriate migration strategy
 func (e *Engine) getStrategy(strategy MigrationStrategy) (Strategy, error) {
 	switch strategy {
@@ -356,74 +769,202 @@ func (e *Engine) getStrategy(strategy MigrationStrategy) (Strategy, error) {
 		return &WarmStrategy{engine: e}, nil
 	case StrategySnapshot:
 		return &SnapshotStrategy{engine: e}, nil
+	case StrategyLive:
+		return &LiveStrategy{engine: e}, nil
 	default:
 		return nil, fmt.Errorf("unknown strategy: %s", strategy)
 	}
 }
 
-// verifyMigration performs post-migration integrity checks
+// verifyMigration checks the migrated containers come up healthy on the
+// target; an error fails the job and rolls it back
 func (e *Engine) verifyMigration(job *MigrationJob) error {
 	e.logger.Info("verifying migration",
 		zap.String("job_id", job.ID),
 		zap.Int("resource_count", len(job.Resources)),
 	)
 
-	// Verify all resources exist on target
-	// This is where we'd use gRPC to query target peer
-	// For now, return success
-
-	return nil
+	return e.awaitHealthy(job.ctx, job)
 }
 
 // PauseMigration pauses a running migration if supported by strategy
 func (e *Engine) PauseMigration(jobID string) error {
+	// Checked and closed under one lock, so two pauses cannot both close
+	// the channel
 	e.jobsMutex.Lock()
 	job, exists := e.jobs[jobID]
-	e.jobsMutex.Unlock()
-
 	if !exists {
+		e.jobsMutex.Unlock()
 		return fmt.Errorf("job not found: %s", jobID)
 	}
 
 	if !job.CanPause {
+		e.jobsMutex.Unlock()
 		return fmt.Errorf("job cannot be paused in current state")
 	}
 
 	if job.Status != StatusRunning {
+		e.jobsMutex.Unlock()
 		return fmt.Errorf("job is not running (status: %s)", job.Status)
 	}
 
-	e.logger.Info("pausing migration", zap.String("job_id", jobID))
-
 	job.Status = StatusPaused
 	close(job.pauseChan)
+	e.jobsMutex.Unlock()
+
+	e.logger.Info("pausing migration", zap.String("job_id", jobID))
+	e.persistJob(job)
+	e.publishJob(job)
 
 	return nil
 }
 
 // ResumeMigration continues a paused migration
 func (e *Engine) ResumeMigration(jobID string) error {
+	// The check and the transition happen under one lock, so two resumes
+	// cannot both pass the check
 	e.jobsMutex.Lock()
 	job, exists := e.jobs[jobID]
-	e.jobsMutex.Unlock()
-
 	if !exists {
+		e.jobsMutex.Unlock()
 		return fmt.Errorf("job not found: %s", jobID)
 	}
 
 	if !job.CanResume {
+		e.jobsMutex.Unlock()
 		return fmt.Errorf("job cannot be resumed")
 	}
 
+	if job.Status == StatusInterrupted {
+		job.Status = StatusPreflight
+		e.jobsMutex.Unlock()
+		return e.restartInterrupted(job)
+	}
+
 	if job.Status != StatusPaused {
+		e.jobsMutex.Unlock()
 		return fmt.Errorf("job is not paused (status: %s)", job.Status)
 	}
 
+	// Wake anything waiting on this pause; a later pause signals and waits on
+	// fresh channels
+	resume := job.resumeChan
+	job.Status = StatusRunning
+	job.pauseChan = make(chan struct{})
+	job.resumeChan = make(chan struct{})
+	close(resume)
+	e.jobsMutex.Unlock()
+
 	e.logger.Info("resuming migration", zap.String("job_id", jobID))
+	e.persistJob(job)
+	e.publishJob(job)
 
-	job.Status = StatusRunning
+	return nil
+}
+
+// PeerActivity returns the unfinished jobs and active transfers involving a peer
+func (e *Engine) PeerActivity(peerID string) ([]*MigrationJob, []peer.TransferInfo) {
+	e.jobsMutex.RLock()
+	jobs := make([]*MigrationJob, 0)
+	for _, job := range e.jobs {
+		if job.PeerID == peerID && !isFinished(job) {
+			jobs = append(jobs, job)
+		}
+	}
+	e.jobsMutex.RUnlock()
+
+	return jobs, e.transfer.ListPeerTransfers(peerID)
+}
+
+// CancelPeerActivity cancels every unfinished job and active transfer involving
+// a peer, e.g. before it is untrusted. Returns the number of jobs cancelled.
+func (e *Engine) CancelPeerActivity(peerID string) int {
+	jobs, transfers := e.PeerActivity(peerID)
+
+	for _, job := range jobs {
+		if err := e.CancelMigration(job.ID); err != nil {
+			e.logger.Warn("failed to cancel migration for removed peer",
+				zap.String("job_id", job.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	for _, transfer := range transfers {
+		if err := e.transfer.CancelTransfer(transfer.ID); err != nil {
+			e.logger.Warn("failed to cancel transfer for removed peer",
+				zap.String("transfer_id", transfer.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	return len(jobs)
+}
+
+// RollbackJob restores the pre-migration state recorded in a job's persisted
+// snapshot. It works after a restart, including for jobs whose automatic
+// rollback never ran or did not finish. Active jobs must be cancelled first.
+// Rolling back a completed job deletes the migrated resources from the
+// target and brings the source back, so it is refused unless force is set.
+func (e *Engine) RollbackJob(jobID string, force bool) error {
+	e.jobsMutex.RLock()
+	job, exists := e.jobs[jobID]
+	e.jobsMutex.RUnlock()
+
+	if exists && !isFinished(job) && job.Status != StatusInterrupted {
+		return fmt.Errorf("job is still active (status: %s)", job.Status)
+	}
+	if exists && job.Status == StatusComplete && !force {
+		return ErrRollbackCompleted
+	}
+
+	if err := e.rollback.Rollback(e.ctx, jobID); err != nil {
+		return err
+	}
+
+	if exists {
+		job.CanResume = false
+		job.CurrentPhase = "rolled_back"
+		if job.Status == StatusInterrupted {
+			e.failRecoveredJob(job, "rolled back after interruption")
+		}
+		e.persistJob(job)
+		e.publishJob(job)
+	}
+
+	return e.rollback.DeleteSnapshot(jobID)
+}
+
+// restartInterrupted re-runs a job recovered after a restart. Completed transfers
+// are picked up from their checkpoints by the transfer manager.
+// The caller has already moved the job out of StatusInterrupted.
+func (e *Engine) restartInterrupted(job *MigrationJob) error {
+	e.logger.Info("restarting interrupted migration", zap.String("job_id", job.ID))
+
+	e.jobsMutex.Lock()
+	job.ctx, job.cancel = context.WithCancel(peer.WithPriority(e.ctx, peer.ParseTransferPriority(job.Priority)))
+	job.pauseChan = make(chan struct{})
 	job.resumeChan = make(chan struct{})
-	close(job.resumeChan)
+	job.EndTime = nil
+	job.Status = StatusPreflight
+	e.jobsMutex.Unlock()
+
+	// Keep the persisted snapshot: it holds the state from before the job
+	// first ran, where the source now is part way through the migration
+	if _, err := e.rollback.GetSnapshot(job.ID); err != nil {
+		if _, err := e.rollback.CreateSnapshot(job.ctx, job); err != nil {
+			e.jobsMutex.Lock()
+			job.cancel()
+			job.Status = StatusInterrupted
+			e.jobsMutex.Unlock()
+			return fmt.Errorf("failed to create rollback snapshot: %w", err)
+		}
+	}
+	e.persistJob(job)
+	e.publishJob(job)
+
+	go e.executeMigration(job)
 
 	return nil
 }
@@ -432,17 +973,35 @@ func (e *Engine) ResumeMigration(jobID string) error {
 func (e *Engine) CancelMigration(jobID string) error {
 	e.jobsMutex.Lock()
 	job, exists := e.jobs[jobID]
-	e.jobsMutex.Unlock()
-
 	if !exists {
+		e.jobsMutex.Unlock()
 		return fmt.Errorf("job not found: %s", jobID)
 	}
 
 	e.logger.Info("cancelling migration", zap.String("job_id", jobID))
 
+	// Held jobs, and jobs recovered as interrupted after a restart, have
+	// nothing running to cancel
+	switch job.Status {
+	case StatusPending:
+		e.failRecoveredJob(job, "cancelled before it started")
+		e.jobsMutex.Unlock()
+		e.persistJob(job)
+		e.publishJob(job)
+		return nil
+	case StatusInterrupted:
+		e.failRecoveredJob(job, "cancelled after interruption")
+		e.jobsMutex.Unlock()
+		e.persistJob(job)
+		e.publishJob(job)
+		return nil
+	}
+	cancel := job.cancel
+	e.jobsMutex.Unlock()
+
 	// Cancel context to stop all operations
-	if job.cancel != nil {
-		job.cancel()
+	if cancel != nil {
+		cancel()
 	}
 
 	// Rollback will be handled by deferred function in executeMigration
@@ -465,6 +1024,34 @@ func (e *Engine) GetStatus(jobID string) (*MigrationJob, error) {
 	return &jobCopy, nil
 }
 
+// ListJobs returns copies of all known jobs, including those recovered from disk
+func (e *Engine) ListJobs() []*MigrationJob {
+	e.jobsMutex.RLock()
+	defer e.jobsMutex.RUnlock()
+
+	jobs := make([]*MigrationJob, 0, len(e.jobs))
+	for _, job := range e.jobs {
+		jobCopy := *job
+		jobs = append(jobs, &jobCopy)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].StartTime.After(jobs[j].StartTime)
+	})
+
+	return jobs
+}
+
+// SubscribeJobLogs returns a job's recent log lines and a channel of new ones
+func (e *Engine) SubscribeJobLogs(jobID string) ([]JobLogEntry, <-chan JobLogEntry, func()) {
+	return e.jobLogs.Subscribe(jobID)
+}
+
+// JobLogs returns a job's recent log lines
+func (e *Engine) JobLogs(jobID string) []JobLogEntry {
+	return e.jobLogs.Entries(jobID)
+}
+
 // GetProgressChan returns the channel for receiving migration updates
 func (e *Engine) GetProgressChan() <-chan MigrationUpdate {
 	return e.progressChan
@@ -495,8 +1082,65 @@ func (e *Engine) GenerateDryRun(ctx context.Context, job *MigrationJob) (*DryRun
 
 	result.Warnings = auditResult.Warnings
 	result.Blockers = auditResult.Blockers
+	if err := validateConsistencyGroups(job); err != nil {
+		result.Blockers = append(result.Blockers, err.Error())
+	}
+	if level, err := ParseVerificationLevel(string(job.Verification)); err != nil {
+		result.Blockers = append(result.Blockers, err.Error())
+	} else if level == VerifyOff {
+		result.Warnings = append(result.Warnings, "Post-transfer volume verification is disabled")
+	}
+	namingErr := job.Naming.Validate()
+	if namingErr != nil {
+		result.Blockers = append(result.Blockers, namingErr.Error())
+	}
+	if err := job.ImageRewrites.Validate(); err != nil {
+		result.Blockers = append(result.Blockers, err.Error())
+	}
+	imageMode, err := ParseImageTransferMode(string(job.ImageMode))
+	if err != nil {
+		result.Blockers = append(result.Blockers, err.Error())
+	}
+	if imageMode == ImageRegistry && e.config.ImageRegistry == nil {
+		result.Blockers = append(result.Blockers, "image mode registry needs image_registry in the config")
+	}
 	result.EstimatedDuration = auditResult.EstimatedDuration
 	result.TotalTransferBytes = auditResult.TotalBytes
+	result.SelectorExpansions = job.SelectorExpansions
+	result.IncludedDependencies = job.IncludedDependencies
+	for _, exp := range job.SelectorExpansions {
+		if len(exp.Matched) == 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s selector matched nothing", exp.Selector.Type))
+		}
+	}
+
+	copies, err := e.findMigratedCopies(ctx, job)
+	var remigration []string
+	if err != nil {
+		remigration = append(remigration, fmt.Sprintf("%v: %v", ErrRemigrationUnchecked, err))
+	}
+	for _, c := range copies {
+		remigration = append(remigration, fmt.Sprintf("Container %s: %s as %s", c.Container, c.Reason, c.Target))
+	}
+	if job.AllowRemigration {
+		result.Warnings = append(result.Warnings, remigration...)
+	} else {
+		result.Blockers = append(result.Blockers, remigration...)
+	}
+
+	if err := e.planDowntime(ctx, job); err != nil {
+		result.Blockers = append(result.Blockers, err.Error())
+	}
+	result.Strategy = job.Strategy
+
+	estimate := e.EstimateSizes(ctx, job.Resources)
+	result.EstimatedDowntime = EstimateDowntime(job.Strategy, job.Resources, estimate, e.peerThroughput(ctx, job.PeerID))
+	if result.TotalTransferBytes == 0 {
+		result.TotalTransferBytes = estimate.TotalBytes
+	}
+	if result.EstimatedDuration == 0 {
+		result.EstimatedDuration = estimate.EstimatedDuration
+	}
 
 	// Enumerate operations without executing
 	for _, resource := range job.Resources {
@@ -504,7 +1148,67 @@ func (e *Engine) GenerateDryRun(ctx context.Context, job *MigrationJob) (*DryRun
 			Type:         fmt.Sprintf("transfer_%s", resource.Type),
 			ResourceName: resource.Name,
 			ResourceID:   resource.ID,
-			SizeBytes:    0, // Would be calculated from actual resource inspection
+			SizeBytes:    estimate.Size(resource),
+		}
+
+		// Databases get a flush hook before their container is stopped or paused
+		if resource.Type == "container" && job.QuiesceDatabases {
+			if kind, err := e.quiescer.DetectContainer(ctx, resource.ID); err == nil {
+				if hook, ok := GetQuiesceHook(kind); ok {
+					op.Notes = append(op.Notes, fmt.Sprintf("Quiesce %s: %s", kind, hook.Description))
+				}
+			}
+		}
+
+		// Show where the naming policy puts containers and volumes
+		if namingErr == nil {
+			if target, err := job.Naming.TargetName(resource.Type, resource.Name, job.ID); err != nil {
+				result.Blockers = append(result.Blockers, err.Error())
+			} else if target != resource.Name {
+				op.Notes = append(op.Notes, fmt.Sprintf("Created on target as %s", target))
+			}
+		}
+
+		// Show images that are rewritten for the target's registry
+		if resource.Type == "image" {
+			if target, ok := job.ImageRewrites.Rewrite(resource.Name); ok && target != resource.Name {
+				op.Notes = append(op.Notes, fmt.Sprintf("Tagged on target as %s", target))
+			}
+		}
+
+		// Public base images come from Docker Hub rather than the source
+		if resource.Type == "image" && job.PrePullBaseImages {
+			if base, err := e.docker.FindPublicBaseImage(ctx, resource.ID); err == nil && base != nil {
+				if base.Whole {
+					op.Notes = append(op.Notes, fmt.Sprintf("Pulled by the target from Docker Hub as %s", base.Reference))
+				} else {
+					op.Notes = append(op.Notes, fmt.Sprintf("Bottom %d layers pulled by the target from Docker Hub (%s)", base.Layers, base.Reference))
+				}
+			}
+		}
+
+		// In reference mode, images with a registry digest are pulled by the target
+		if resource.Type == "image" && imageMode == ImageReference {
+			if info, err := e.docker.GetImageInfo(ctx, resource.ID); err == nil && len(info.RepoDigests) > 0 {
+				op.Type = "pull_image"
+				op.SizeBytes = 0
+				op.Notes = append(op.Notes, "Pulled by the target from its registry, no layers streamed")
+			} else {
+				op.Notes = append(op.Notes, "No registry digest, layers are streamed")
+			}
+		}
+
+		if resource.Type == "image" && imageMode == ImageRegistry && e.config.ImageRegistry != nil {
+			op.Type = "pull_image"
+			op.Notes = append(op.Notes, fmt.Sprintf("Pushed to %s and pulled by the target", e.config.ImageRegistry.Address))
+		}
+
+		// Shared-storage volumes are re-created on the target instead of copied
+		if resource.Type == "volume" && job.ReattachSharedVolumes {
+			if vol, err := e.docker.InspectVolume(ctx, resource.Name); err == nil && docker.IsSharedStorageVolume(vol) {
+				op.Type = "reattach_volume"
+				op.Notes = append(op.Notes, fmt.Sprintf("Re-attach with driver %s, no data copied", vol.Driver))
+			}
 		}
 
 		result.Operations = append(result.Operations, op)
@@ -0,0 +1,90 @@
+package migration
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// ResourceSize is the estimated transfer size of one resource
+type ResourceSize struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SizeEstimate sizes each resource of a migration and the whole
+type SizeEstimate struct {
+	Resources         []ResourceSize `json:"resources"`
+	TotalBytes        int64          `json:"total_bytes"`
+	EstimatedDuration time.Duration  `json:"estimated_duration"`
+}
+
+// NewSizeEstimate totals per-resource sizes and estimates transfer time
+func NewSizeEstimate(resources []ResourceSize, bandwidthMbps int) *SizeEstimate {
+	estimate := &SizeEstimate{Resources: resources}
+	for _, r := range resources {
+		estimate.TotalBytes += r.SizeBytes
+	}
+	estimate.EstimatedDuration = EstimateTransferTime(estimate.TotalBytes, bandwidthMbps)
+	return estimate
+}
+
+// Size returns the estimated size of a resource, or 0 if it was not sized
+func (s *SizeEstimate) Size(res ResourceRef) int64 {
+	for _, r := range s.Resources {
+		if r.Type == res.Type && r.ID == res.ID {
+			return r.SizeBytes
+		}
+	}
+	return 0
+}
+
+// EstimateSizes sizes each resource on the local host: a walk of volume
+// data, the image size, and a container's writable layer. Networks carry
+// no data. Resources that cannot be sized are reported with an error and
+// count as zero.
+func (e *Engine) EstimateSizes(ctx context.Context, resources []ResourceRef) *SizeEstimate {
+	sizes := make([]ResourceSize, 0, len(resources))
+
+	for _, res := range resources {
+		size := ResourceSize{Type: res.Type, ID: res.ID, Name: res.Name}
+
+		switch res.Type {
+		case "volume":
+			if info, err := e.docker.GetVolumeInfo(ctx, res.Name); err != nil {
+				size.Error = err.Error()
+			} else {
+				size.SizeBytes = info.Size
+			}
+		case "image":
+			if info, err := e.docker.InspectImage(ctx, res.ID); err != nil {
+				size.Error = err.Error()
+			} else {
+				size.SizeBytes = info.Size
+			}
+		case "container":
+			if diff, err := e.docker.ContainerDiffSize(ctx, res.ID); err != nil {
+				size.Error = err.Error()
+			} else {
+				size.SizeBytes = diff
+			}
+		}
+
+		sizes = append(sizes, size)
+	}
+
+	return NewSizeEstimate(sizes, 0)
+}
+
+// largestFirst orders resources by estimated size, biggest first, so the
+// longest transfers start early and do not trail the rest of the job
+func largestFirst(resources []ResourceRef, estimate *SizeEstimate) []ResourceRef {
+	ordered := append([]ResourceRef(nil), resources...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return estimate.Size(ordered[i]) > estimate.Size(ordered[j])
+	})
+	return ordered
+}
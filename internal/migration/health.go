@@ -0,0 +1,249 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// HealthWindow is how long containers have to become healthy on the
+	// target when neither the job nor the config sets it
+	HealthWindow = 60 * time.Second
+	// HealthPollInterval is how often the target is asked while waiting
+	HealthPollInterval = 2 * time.Second
+	// HealthSettleTime is how long a container without a healthcheck must
+	// stay up to pass; shorter windows shorten it too
+	HealthSettleTime = 10 * time.Second
+)
+
+// Outcome of a container's health verification
+const (
+	HealthPassed  = "passed"
+	HealthFailed  = "failed"
+	HealthPending = "pending" // Had not settled when the window closed
+)
+
+// ContainerHealthResult is how one migrated container fared on the target
+type ContainerHealthResult struct {
+	Container string `json:"container"` // Name on the target
+	Status    string `json:"status"`    // passed, failed or pending
+	// Health is the container's Docker healthcheck state, if it has one
+	Health   string `json:"health,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// HealthReport records the health verification of a job's containers once
+// they were started on the target
+type HealthReport struct {
+	StartedAt  time.Time               `json:"started_at"`
+	FinishedAt *time.Time              `json:"finished_at,omitempty"`
+	WindowMs   int64                   `json:"window_ms"`
+	Containers []ContainerHealthResult `json:"containers"`
+}
+
+// healthWindow is how long the job's containers have to become healthy
+func (e *Engine) healthWindow(job *MigrationJob) time.Duration {
+	if job.HealthWindowMs > 0 {
+		return time.Duration(job.HealthWindowMs) * time.Millisecond
+	}
+	if e.config != nil && e.config.HealthWindow > 0 {
+		return e.config.HealthWindow
+	}
+	return HealthWindow
+}
+
+// awaitHealthy polls the Docker state of the job's containers on the
+// target until every one is healthy, or has stayed up for the settle time
+// when it has no healthcheck. A container that exits with an error or turns
+// unhealthy fails the job at once; one that has not settled when the window
+// closes fails it then. Containers are found by the job label their
+// provenance carries, and each is inspected on the target. A target that
+// cannot be asked, or that lacks some of the job's containers, is retried
+// until the window closes and then fails the job too.
+func (e *Engine) awaitHealthy(ctx context.Context, job *MigrationJob) error {
+	expected := 0
+	for _, r := range job.Resources {
+		if r.Type == "container" {
+			expected++
+		}
+	}
+	if expected == 0 {
+		return nil
+	}
+	if e.peers == nil {
+		return fmt.Errorf("cannot check container health: no peer connection to the target")
+	}
+
+	window := e.healthWindow(job)
+	settle := HealthSettleTime
+	if window < settle {
+		settle = window
+	}
+	report := &HealthReport{StartedAt: time.Now(), WindowMs: window.Milliseconds()}
+	deadline := report.StartedAt.Add(window)
+	upSince := make(map[string]time.Time)
+
+	ticker := time.NewTicker(HealthPollInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		var verdict error
+		containers, err := e.targetJobContainers(ctx, job)
+		switch {
+		case err != nil:
+			e.logger.Warn("could not check container health on target",
+				zap.String("job_id", job.ID),
+				zap.Error(err),
+			)
+			if !now.Before(deadline) {
+				verdict = fmt.Errorf("could not check container health on the target within %s: %w", window, err)
+			}
+		case len(containers) < expected:
+			if !now.Before(deadline) {
+				verdict = fmt.Errorf("target has %d of the job's %d containers after %s", len(containers), expected, window)
+			}
+		default:
+			results := make([]ContainerHealthResult, 0, len(containers))
+			var failed, pending []string
+			for _, c := range containers {
+				result := checkContainerHealth(c, upSince, settle, now)
+				switch result.Status {
+				case HealthFailed:
+					failed = append(failed, fmt.Sprintf("%s (%s)", result.Container, result.Detail))
+				case HealthPending:
+					pending = append(pending, result.Container)
+				}
+				results = append(results, result)
+			}
+
+			e.jobsMutex.Lock()
+			report.Containers = results
+			job.Health = report
+			e.jobsMutex.Unlock()
+
+			switch {
+			case len(failed) > 0:
+				verdict = fmt.Errorf("containers failed on the target: %s", strings.Join(failed, ", "))
+			case len(pending) == 0:
+				e.logger.Info("migrated containers are healthy on target",
+					zap.String("job_id", job.ID),
+					zap.Int("containers", len(results)),
+				)
+				e.finishHealthReport(job, report)
+				return nil
+			case !now.Before(deadline):
+				verdict = fmt.Errorf("containers did not become healthy within %s: %s", window, strings.Join(pending, ", "))
+			}
+		}
+
+		if verdict != nil {
+			e.finishHealthReport(job, report)
+			return verdict
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// finishHealthReport stamps the end of the health verification on the job
+func (e *Engine) finishHealthReport(job *MigrationJob, report *HealthReport) {
+	e.jobsMutex.Lock()
+	finished := time.Now()
+	report.FinishedAt = &finished
+	job.Health = report
+	e.jobsMutex.Unlock()
+	e.persistJob(job)
+}
+
+// targetJobContainers inspects the containers on the target that this job
+// created
+func (e *Engine) targetJobContainers(ctx context.Context, job *MigrationJob) ([]*pb.ContainerInspect, error) {
+	client, err := e.peers.Connect(ctx, job.PeerID, e.transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	defer client.Close()
+
+	all, err := client.ListContainers(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	var containers []*pb.ContainerInspect
+	for _, c := range all {
+		if c.Labels[docker.LabelMigratedJob] != job.ID {
+			continue
+		}
+		inspect, err := client.InspectContainer(ctx, c.Id)
+		if err != nil {
+			return nil, err
+		}
+		if inspect.Exists {
+			containers = append(containers, inspect)
+		}
+	}
+	return containers, nil
+}
+
+// checkContainerHealth judges one container from its inspected state.
+// upSince tracks when each container without a healthcheck was first seen
+// up.
+func checkContainerHealth(c *pb.ContainerInspect, upSince map[string]time.Time, settle time.Duration, now time.Time) ContainerHealthResult {
+	result := ContainerHealthResult{
+		Container: c.Name,
+		Health:    c.Health,
+		Detail:    c.Status,
+	}
+	if !c.Running || c.Restarting {
+		code := int(c.ExitCode)
+		result.ExitCode = &code
+	}
+	if !c.Running {
+		delete(upSince, c.Name)
+	}
+
+	switch {
+	case c.Health == docker.HealthHealthy:
+		result.Status = HealthPassed
+	case c.Health == docker.HealthUnhealthy:
+		result.Status = HealthFailed
+		result.Detail = "healthcheck reports unhealthy"
+	case c.Health == docker.HealthStarting, c.Restarting:
+		result.Status = HealthPending
+	case c.Running:
+		since, ok := upSince[c.Name]
+		if !ok {
+			since = now
+			upSince[c.Name] = now
+		}
+		result.Status = HealthPending
+		if now.Sub(since) >= settle {
+			result.Status = HealthPassed
+		}
+	case c.Status == "created":
+		result.Status = HealthFailed
+		result.Detail = "never started"
+	case c.ExitCode == 0 && c.Error == "":
+		result.Status = HealthPassed
+		result.Detail = "exited with code 0"
+	case c.Error != "":
+		result.Status = HealthFailed
+		result.Detail = c.Error
+	default:
+		result.Status = HealthFailed
+		result.Detail = fmt.Sprintf("exited with code %d", c.ExitCode)
+	}
+	return result
+}
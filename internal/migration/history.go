@@ -0,0 +1,292 @@
+package migration
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	_ "modernc.org/sqlite"
+	"go.uber.org/zap"
+)
+
+// ErrHistoryNotFound is returned when no finished job has the requested ID
+var ErrHistoryNotFound = errors.New("migration not found in history")
+
+// DefaultHistoryLimit caps history listings that do not set a limit
+const DefaultHistoryLimit = 100
+
+// HistoryStore keeps finished migrations in a SQLite database. Unlike the
+// job records under jobs/, entries outlive the retention policy.
+type HistoryStore struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// HistoryEntry summarizes one finished migration
+type HistoryEntry struct {
+	ID         string            `json:"id"`
+	PeerID     string            `json:"peer_id"`
+	Mode       MigrationMode     `json:"mode"`
+	Strategy   MigrationStrategy `json:"strategy"`
+	Status     MigrationStatus   `json:"status"`
+	StartTime  time.Time         `json:"start_time"`
+	EndTime    time.Time         `json:"end_time"`
+	Duration   time.Duration     `json:"duration"`
+	BytesDone  int64             `json:"bytes_done"`
+	BytesTotal int64             `json:"bytes_total"`
+	Resources  int               `json:"resources"`
+	Errors     int               `json:"errors"`
+	// LastError is the message of the job's last error, if any
+	LastError string `json:"last_error,omitempty"`
+}
+
+// HistoryFilter narrows a history listing. Zero fields match everything.
+type HistoryFilter struct {
+	Status   MigrationStatus
+	PeerID   string
+	Strategy MigrationStrategy
+	Since    time.Time // Finished at or after
+	Until    time.Time // Finished before
+	Limit    int       // 0 = DefaultHistoryLimit
+	Offset   int
+}
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS migrations (
+	id          TEXT PRIMARY KEY,
+	peer_id     TEXT NOT NULL,
+	mode        TEXT NOT NULL,
+	strategy    TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	start_time  INTEGER NOT NULL,
+	end_time    INTEGER NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	bytes_done  INTEGER NOT NULL,
+	bytes_total INTEGER NOT NULL,
+	resources   INTEGER NOT NULL,
+	errors      INTEGER NOT NULL,
+	last_error  TEXT NOT NULL,
+	job         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS migrations_end_time ON migrations (end_time);
+CREATE INDEX IF NOT EXISTS migrations_peer_id ON migrations (peer_id);
+`
+
+// NewHistoryStore opens or creates history.db under dataDir (default ~/.docker-migrate)
+func NewHistoryStore(dataDir string, logger *zap.Logger) (*HistoryStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	// WAL and a busy timeout let the history command read while the daemon writes
+	path := filepath.Join(dataDir, "history.db")
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history database: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		logger.Warn("failed to restrict history database permissions", zap.Error(err))
+	}
+
+	return &HistoryStore{
+		db:     db,
+		logger: logger,
+	}, nil
+}
+
+// Close closes the database
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// Record stores a finished job, replacing any earlier entry with its ID
+func (h *HistoryStore) Record(job *MigrationJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	entry := historyEntryFromJob(job)
+	_, err = h.db.Exec(`INSERT OR REPLACE INTO migrations
+		(id, peer_id, mode, strategy, status, start_time, end_time, duration_ms,
+		 bytes_done, bytes_total, resources, errors, last_error, job)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.PeerID, string(entry.Mode), string(entry.Strategy), string(entry.Status),
+		entry.StartTime.UnixNano(), entry.EndTime.UnixNano(), entry.Duration.Milliseconds(),
+		entry.BytesDone, entry.BytesTotal, entry.Resources, entry.Errors, entry.LastError,
+		string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// List returns matching entries, most recently finished first
+func (h *HistoryStore) List(filter HistoryFilter) ([]HistoryEntry, error) {
+	var where []string
+	var args []interface{}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	if filter.PeerID != "" {
+		where = append(where, "peer_id = ?")
+		args = append(args, filter.PeerID)
+	}
+	if filter.Strategy != "" {
+		where = append(where, "strategy = ?")
+		args = append(args, string(filter.Strategy))
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "end_time >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "end_time < ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+
+	query := `SELECT id, peer_id, mode, strategy, status, start_time, end_time, duration_ms,
+		bytes_done, bytes_total, resources, errors, last_error FROM migrations`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY end_time DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, filter.Offset)
+
+	rows, err := h.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]HistoryEntry, 0)
+	for rows.Next() {
+		var entry HistoryEntry
+		var mode, strategy, status string
+		var start, end, durationMs int64
+		if err := rows.Scan(&entry.ID, &entry.PeerID, &mode, &strategy, &status, &start, &end, &durationMs,
+			&entry.BytesDone, &entry.BytesTotal, &entry.Resources, &entry.Errors, &entry.LastError); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		entry.Mode = MigrationMode(mode)
+		entry.Strategy = MigrationStrategy(strategy)
+		entry.Status = MigrationStatus(status)
+		entry.StartTime = time.Unix(0, start)
+		entry.EndTime = time.Unix(0, end)
+		entry.Duration = time.Duration(durationMs) * time.Millisecond
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Get returns the full record of a finished job
+func (h *HistoryStore) Get(jobID string) (*MigrationJob, error) {
+	var data string
+	err := h.db.QueryRow("SELECT job FROM migrations WHERE id = ?", jobID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrHistoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var job MigrationJob
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return nil, fmt.Errorf("failed to parse job %s: %w", jobID, err)
+	}
+	return &job, nil
+}
+
+// historyEntryFromJob summarizes a job for the history table
+func historyEntryFromJob(job *MigrationJob) HistoryEntry {
+	end := jobFinishTime(job)
+	entry := HistoryEntry{
+		ID:         job.ID,
+		PeerID:     job.PeerID,
+		Mode:       job.Mode,
+		Strategy:   job.Strategy,
+		Status:     job.Status,
+		StartTime:  job.StartTime,
+		EndTime:    end,
+		BytesDone:  job.Progress.BytesDone,
+		BytesTotal: job.Progress.BytesTotal,
+		Resources:  len(job.Resources),
+		Errors:     len(job.Errors),
+	}
+	if !job.StartTime.IsZero() && end.After(job.StartTime) {
+		entry.Duration = end.Sub(job.StartTime)
+	}
+	if len(job.Errors) > 0 {
+		entry.LastError = job.Errors[len(job.Errors)-1].Message
+	}
+	return entry
+}
+
+// recordHistory stores job in the history database once it has finished
+func (e *Engine) recordHistory(job *MigrationJob) {
+	if e.history == nil || !isFinished(job) {
+		return
+	}
+	if err := e.history.Record(job); err != nil {
+		e.logger.Warn("failed to record job history",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+// History lists finished migrations, including those purged from the job list
+func (e *Engine) History(filter HistoryFilter) ([]HistoryEntry, error) {
+	if e.history == nil {
+		return nil, fmt.Errorf("migration history is not available")
+	}
+	return e.history.List(filter)
+}
+
+// HistoryJob returns the full record of a finished migration
+func (e *Engine) HistoryJob(jobID string) (*MigrationJob, error) {
+	if e.history == nil {
+		return nil, fmt.Errorf("migration history is not available")
+	}
+	return e.history.Get(jobID)
+}
+
+// ParseHistoryTime parses a history filter bound, either RFC 3339 or a
+// duration before now such as "24h"
+func ParseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339 or a duration like 24h", value)
+	}
+	return t, nil
+}
@@ -5,7 +5,10 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 
+	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/docker"
 	"github.com/artemis/docker-migrate/internal/peer"
 
@@ -18,6 +21,47 @@ type ImageMigrator struct {
 	docker   *docker.Client
 	transfer *peer.TransferManager
 	logger   *zap.Logger
+
+	// job supplies the image rewrite rules applied on the target; may be nil
+	job *MigrationJob
+	// jobsMutex is the engine's lock on its jobs, held while job is updated
+	jobsMutex *sync.RWMutex
+
+	// verification decides whether the target's copy of each image is checked
+	verification VerificationLevel
+
+	// peers connects to the target to check its copies
+	peers *peer.PeerDiscovery
+
+	// bases are public base images the target pulls from Docker Hub, keyed
+	// by the ID of the image they serve
+	bases map[string]*basePull
+
+	// registry is the intermediary registry for ImageRegistry mode
+	registry *config.RegistryConfig
+}
+
+// ImageTransferMode controls how images reach the target
+type ImageTransferMode string
+
+const (
+	ImageStream    ImageTransferMode = "stream"    // Stream missing layers peer-to-peer
+	ImageReference ImageTransferMode = "reference" // Send the digest; the target pulls from its registry
+	ImageRegistry  ImageTransferMode = "registry"  // Push to the intermediary registry; the target pulls from it
+)
+
+// ParseImageTransferMode validates an image mode, defaulting to stream
+func ParseImageTransferMode(s string) (ImageTransferMode, error) {
+	switch ImageTransferMode(s) {
+	case "", ImageStream:
+		return ImageStream, nil
+	case ImageReference:
+		return ImageReference, nil
+	case ImageRegistry:
+		return ImageRegistry, nil
+	default:
+		return "", fmt.Errorf("unknown image mode %q (expected stream, reference or registry)", s)
+	}
 }
 
 // ImageLayer represents a single layer in a Docker image
@@ -37,13 +81,80 @@ type ImageManifest struct {
 }
 
 // MigrateImage transfers an image with layer deduplication
-// This implements the critical optimization of only transferring missing layers
-func (im *ImageMigrator) MigrateImage(ctx context.Context, imageID, peerID string, progressCh chan<- MigrationProgress) error {
+// This implements the critical optimization of only transferring missing layers.
+// reference is the name the image is tagged with on the target, after the
+// job's rewrite rules.
+func (im *ImageMigrator) MigrateImage(ctx context.Context, imageID, reference, peerID string, progressCh chan<- MigrationProgress) error {
 	im.logger.Info("starting image migration",
 		zap.String("image_id", imageID),
 		zap.String("peer_id", peerID),
 	)
 
+	tag := reference
+	if im.job != nil {
+		tag = im.job.rewriteImage(reference)
+	}
+
+	// In reference mode the target pulls the image itself, so no layers
+	// cross the peer link. Images without a registry digest, or that the
+	// target cannot pull, are streamed instead.
+	if im.job != nil && im.job.ImageMode == ImageReference {
+		if ref, ok := im.registryReference(ctx, imageID, reference); ok {
+			err := im.requestTargetPull(ctx, peerID, imageID, ref, tag, "")
+			if err == nil {
+				im.logger.Info("image pulled by target, layers not streamed",
+					zap.String("image_id", imageID),
+					zap.String("ref", ref),
+				)
+				return nil
+			}
+			im.logger.Warn("target could not pull image, streaming layers instead",
+				zap.String("image_id", imageID),
+				zap.String("ref", ref),
+				zap.Error(err),
+			)
+		} else {
+			im.logger.Info("image has no registry digest, streaming layers",
+				zap.String("image_id", imageID),
+			)
+		}
+	}
+
+	// In registry mode the source pushes the image to the intermediary
+	// registry and the target pulls it; on failure it is streamed
+	if im.job != nil && im.job.ImageMode == ImageRegistry {
+		err := im.sendViaRegistry(ctx, imageID, reference, tag, peerID)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("migration cancelled: %w", ctx.Err())
+		}
+		im.logger.Warn("sending image through registry failed, streaming layers instead",
+			zap.String("image_id", imageID),
+			zap.Error(err),
+		)
+	}
+
+	// A public image is pulled by the target in full
+	base := im.bases[imageID]
+	if base != nil && base.whole {
+		err := im.requestTargetPull(ctx, peerID, imageID, base.reference, tag, "")
+		if err == nil {
+			im.logger.Info("public image pulled by target from Docker Hub",
+				zap.String("image_id", imageID),
+				zap.String("ref", base.reference),
+			)
+			return nil
+		}
+		im.logger.Warn("target could not pull public image, streaming layers instead",
+			zap.String("image_id", imageID),
+			zap.String("ref", base.reference),
+			zap.Error(err),
+		)
+		base = nil
+	}
+
 	// Step 1: Get local image manifest and layers
 	manifest, err := im.getImageManifest(ctx, imageID)
 	if err != nil {
@@ -56,7 +167,7 @@ func (im *ImageMigrator) MigrateImage(ctx context.Context, imageID, peerID strin
 	)
 
 	// Step 2: Query target for existing layers
-	existingLayers, err := im.queryTargetLayers(ctx, peerID, imageID)
+	existingLayers, err := im.queryTargetLayers(ctx, peerID, imageID, manifest.Layers)
 	if err != nil {
 		return fmt.Errorf("failed to query target layers: %w", err)
 	}
@@ -64,6 +175,17 @@ func (im *ImageMigrator) MigrateImage(ctx context.Context, imageID, peerID strin
 	// Step 3: Calculate missing layers (layer deduplication)
 	missingLayers := im.diffLayers(manifest.Layers, existingLayers)
 
+	// Leave the base image's layers to the target's pull from Docker Hub
+	var baseLayers []ImageLayer
+	if base != nil && base.layers < len(manifest.Layers) {
+		missingLayers, baseLayers = splitBaseLayers(missingLayers, manifest.Layers[:base.layers])
+		im.logger.Info("base layers pulled by target from Docker Hub",
+			zap.String("image_id", imageID),
+			zap.String("base", base.reference),
+			zap.Int("base_layers", len(baseLayers)),
+		)
+	}
+
 	im.logger.Info("calculated missing layers",
 		zap.Int("total_layers", len(manifest.Layers)),
 		zap.Int("existing_layers", len(existingLayers)),
@@ -82,8 +204,28 @@ func (im *ImageMigrator) MigrateImage(ctx context.Context, imageID, peerID strin
 		}
 	}
 
+	// The target needs the base layers before it can assemble the image;
+	// stream them after all if its pull failed
+	if len(baseLayers) > 0 {
+		if err := base.wait(ctx); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("migration cancelled: %w", ctx.Err())
+			}
+			im.logger.Warn("base image pull failed, streaming its layers",
+				zap.String("image_id", imageID),
+				zap.String("base", base.reference),
+				zap.Error(err),
+			)
+			for i, layer := range baseLayers {
+				if err := im.transferLayer(ctx, peerID, layer, i+1, len(baseLayers), progressCh); err != nil {
+					return fmt.Errorf("failed to transfer layer %s: %w", layer.Digest, err)
+				}
+			}
+		}
+	}
+
 	// Step 5: Send manifest for image reconstruction on target
-	if err := im.sendManifest(ctx, peerID, imageID, manifest); err != nil {
+	if err := im.sendManifest(ctx, peerID, imageID, tag, manifest); err != nil {
 		return fmt.Errorf("failed to send manifest: %w", err)
 	}
 
@@ -95,28 +237,55 @@ func (im *ImageMigrator) MigrateImage(ctx context.Context, imageID, peerID strin
 	return nil
 }
 
-// getImageManifest retrieves the manifest for a local image
+// getImageManifest lists a local image's layers by diff ID, base layer
+// first, which is how peers compare the layers they hold
 func (im *ImageMigrator) getImageManifest(ctx context.Context, imageID string) (*ImageManifest, error) {
-	// Would use Docker SDK to get image manifest
-	// For now, return mock data
-	manifest := &ImageManifest{
-		Layers: []ImageLayer{
-			{Digest: "sha256:abc123", Size: 1024 * 1024, MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip"},
-			{Digest: "sha256:def456", Size: 2048 * 1024, MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip"},
-		},
+	if im.docker == nil {
+		return nil, fmt.Errorf("docker is not available")
+	}
+	inspect, err := im.docker.InspectImage(ctx, imageID)
+	if err != nil {
+		return nil, err
 	}
-	manifest.Config.Digest = "sha256:config789"
-	manifest.Config.Size = 4096
 
+	manifest := &ImageManifest{}
+	manifest.Config.Digest = inspect.ID
+	for _, diffID := range inspect.RootFS.Layers {
+		manifest.Layers = append(manifest.Layers, ImageLayer{
+			Digest:    diffID,
+			MediaType: "application/vnd.docker.image.rootfs.diff.tar",
+		})
+	}
 	return manifest, nil
 }
 
-// queryTargetLayers asks the target peer which layers it already has
-func (im *ImageMigrator) queryTargetLayers(ctx context.Context, peerID, imageID string) ([]ImageLayer, error) {
-	// Would send gRPC request to target peer to query existing layers
-	// Target would check its local image store
-	// For now, return empty (all layers need transfer)
-	return []ImageLayer{}, nil
+// queryTargetLayers asks the target peer which of the image's layers it
+// already has. The target answers with the prefix of layers its local image
+// store holds, since a layer is only usable on top of the same parents.
+func (im *ImageMigrator) queryTargetLayers(ctx context.Context, peerID, imageID string, layers []ImageLayer) ([]ImageLayer, error) {
+	if im.peers == nil {
+		return nil, fmt.Errorf("no peer connection to query layers")
+	}
+	client, err := im.peers.Connect(ctx, peerID, im.transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	defer client.Close()
+
+	diffIDs := make([]string, len(layers))
+	for i, layer := range layers {
+		diffIDs[i] = layer.Digest
+	}
+	present, err := client.QueryLayers(ctx, imageID, diffIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make([]ImageLayer, 0, len(present))
+	for _, diffID := range present {
+		existing = append(existing, ImageLayer{Digest: diffID})
+	}
+	return existing, nil
 }
 
 // diffLayers calculates which layers are missing on target
@@ -136,6 +305,23 @@ func (im *ImageMigrator) diffLayers(local []ImageLayer, remote []ImageLayer) []I
 	return missing
 }
 
+// splitBaseLayers separates the missing layers a base image provides from
+// those that must be streamed
+func splitBaseLayers(missing, base []ImageLayer) (stream, fromBase []ImageLayer) {
+	inBase := make(map[string]bool, len(base))
+	for _, layer := range base {
+		inBase[layer.Digest] = true
+	}
+	for _, layer := range missing {
+		if inBase[layer.Digest] {
+			fromBase = append(fromBase, layer)
+		} else {
+			stream = append(stream, layer)
+		}
+	}
+	return stream, fromBase
+}
+
 // transferLayer streams a single layer blob to the target with integrity checks
 func (im *ImageMigrator) transferLayer(ctx context.Context, peerID string, layer ImageLayer, current, total int, progressCh chan<- MigrationProgress) error {
 	im.logger.Info("transferring layer",
@@ -168,17 +354,115 @@ func (im *ImageMigrator) transferLayer(ctx context.Context, peerID string, layer
 }
 
 // sendManifest sends the image manifest to target for reconstruction
-func (im *ImageMigrator) sendManifest(ctx context.Context, peerID, imageID string, manifest *ImageManifest) error {
+func (im *ImageMigrator) sendManifest(ctx context.Context, peerID, imageID, tag string, manifest *ImageManifest) error {
 	im.logger.Info("sending image manifest",
 		zap.String("image_id", imageID),
+		zap.String("tag", tag),
 		zap.String("peer_id", peerID),
 	)
 
 	// Would send manifest via gRPC
 	// Target uses manifest to reconstruct image from received layers
+	// and tags it as tag
+	return nil
+}
+
+// registryReference returns a digest reference the image can be pulled by,
+// preferring the repository it is referenced as, with the job's rewrite
+// rules applied. Images built locally have none.
+func (im *ImageMigrator) registryReference(ctx context.Context, imageID, reference string) (string, bool) {
+	if im.docker == nil {
+		return "", false
+	}
+	info, err := im.docker.GetImageInfo(ctx, imageID)
+	if err != nil || len(info.RepoDigests) == 0 {
+		return "", false
+	}
+
+	ref := info.RepoDigests[0]
+	repo := reference
+	if i := strings.LastIndexByte(repo, ':'); i > strings.LastIndexByte(repo, '/') {
+		repo = repo[:i]
+	}
+	for _, digest := range info.RepoDigests {
+		if strings.HasPrefix(digest, repo+"@") {
+			ref = digest
+			break
+		}
+	}
+
+	if im.job != nil {
+		ref = im.job.rewriteImage(ref)
+	}
+	return ref, true
+}
+
+// sendViaRegistry pushes the image to the intermediary registry and has the
+// target pull it and tag it as tag
+func (im *ImageMigrator) sendViaRegistry(ctx context.Context, imageID, reference, tag, peerID string) error {
+	if im.docker == nil || im.registry == nil {
+		return fmt.Errorf("no intermediary registry configured")
+	}
+
+	ref := docker.IntermediaryReference(im.registry.Address, reference, imageID)
+	if err := im.docker.TagImage(ctx, imageID, ref); err != nil {
+		return err
+	}
+	// Untagging the only tag would delete the image, so images referenced
+	// by ID keep the intermediary one
+	if reference != "" && reference != imageID {
+		defer func() {
+			if err := im.docker.UntagImage(context.Background(), ref); err != nil {
+				im.logger.Warn("failed to remove intermediary image tag",
+					zap.String("ref", ref),
+					zap.Error(err),
+				)
+			}
+		}()
+	}
+
+	server, _, _ := strings.Cut(im.registry.Address, "/")
+	auth, err := docker.EncodeRegistryAuth(server, im.registry.Username, im.registry.Password)
+	if err != nil {
+		return err
+	}
+	if err := im.docker.PushImage(ctx, ref, auth); err != nil {
+		return err
+	}
+
+	if err := im.requestTargetPull(ctx, peerID, imageID, ref, tag, auth); err != nil {
+		return err
+	}
+	im.logger.Info("image sent through registry",
+		zap.String("image_id", imageID),
+		zap.String("ref", ref),
+	)
 	return nil
 }
 
+// requestTargetPull asks the target to pull an image by digest and, if tag
+// is set, tag it. registryAuth is the encoded credentials for ref's registry;
+// empty leaves the target to use its own. An error means the target cannot
+// reach the registry or lacks credentials.
+func (im *ImageMigrator) requestTargetPull(ctx context.Context, peerID, imageID, ref, tag, registryAuth string) error {
+	im.logger.Info("requesting target pull",
+		zap.String("peer_id", peerID),
+		zap.String("ref", ref),
+		zap.String("tag", tag),
+	)
+
+	if im.peers == nil {
+		return fmt.Errorf("no peer connection to request a pull")
+	}
+	client, err := im.peers.Connect(ctx, peerID, im.transfer)
+	if err != nil {
+		return fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	defer client.Close()
+
+	return client.PullImage(ctx, imageID, ref, tag, registryAuth)
+}
+
 // CalculateChecksum computes SHA-256 for a data stream
 // This is used for integrity verification during transfer
 func (im *ImageMigrator) CalculateChecksum(r io.Reader) (string, error) {
@@ -0,0 +1,298 @@
+package migration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/peer"
+
+	"go.uber.org/zap"
+)
+
+// Algorithms recorded in an integrity report
+const (
+	// IntegrityMerkle is the root of a SHA-256 Merkle tree over a volume's directories and file ranges
+	IntegrityMerkle = "sha256-merkle"
+	// IntegrityFileIndex is a SHA-256 over a volume's file index, used by warm syncs
+	IntegrityFileIndex = "sha256-file-index"
+	// IntegrityImageID is an image's content-addressed ID
+	IntegrityImageID = "sha256-image-id"
+)
+
+// IntegrityReport is a manifest of the checksum of every volume and image a
+// migration moved, signed with this node's key when the job finishes, so it
+// can be shown later that the data arrived unmodified
+type IntegrityReport struct {
+	JobID       string           `json:"job_id"`
+	PeerID      string           `json:"peer_id"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Entries     []IntegrityEntry `json:"entries"`
+
+	// Signer is the fingerprint of the certificate whose key signed the
+	// report; Certificate holds it PEM-encoded
+	Signer      string `json:"signer,omitempty"`
+	Certificate string `json:"certificate,omitempty"`
+	// Signature is a base64 ECDSA signature over the rest of the report
+	Signature string `json:"signature,omitempty"`
+}
+
+// IntegrityEntry is the checksum of one transferred resource
+type IntegrityEntry struct {
+	ResourceType string `json:"resource_type"`
+	Resource     string `json:"resource"`
+	// TargetName is set when the resource has another name on the target
+	TargetName   string            `json:"target_name,omitempty"`
+	Algorithm    string            `json:"algorithm"`
+	Checksum     string            `json:"checksum"`
+	Verification VerificationLevel `json:"verification,omitempty"`
+	// VerifiedAt is when the target's copy was found to match; unset if it
+	// was not checked
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+// recordIntegrity adds an entry to the job's integrity report, replacing
+// one for the same resource from an earlier attempt. Volumes and images
+// transferring in parallel add to the report, and the engine persists the
+// job meanwhile, so jobsMutex is the engine's lock on its jobs.
+func (job *MigrationJob) recordIntegrity(jobsMutex *sync.RWMutex, entry IntegrityEntry) {
+	if job == nil {
+		return
+	}
+	jobsMutex.Lock()
+	defer jobsMutex.Unlock()
+
+	if job.Integrity == nil {
+		job.Integrity = &IntegrityReport{}
+	}
+	for i, existing := range job.Integrity.Entries {
+		if existing.ResourceType == entry.ResourceType && existing.Resource == entry.Resource {
+			job.Integrity.Entries[i] = entry
+			return
+		}
+	}
+	job.Integrity.Entries = append(job.Integrity.Entries, entry)
+}
+
+// SetSigner signs each finished job's integrity report with crypto's key.
+// Without one, reports are kept unsigned.
+func (e *Engine) SetSigner(crypto *peer.CryptoManager) {
+	e.signer = crypto
+}
+
+// sealIntegrityReport finishes a job's integrity report and signs it. Every
+// job gets one, listing whatever was transferred before it ended.
+func (e *Engine) sealIntegrityReport(job *MigrationJob) {
+	e.jobsMutex.Lock()
+	defer e.jobsMutex.Unlock()
+
+	report := job.Integrity
+	if report == nil {
+		report = &IntegrityReport{}
+		job.Integrity = report
+	}
+	if report.Entries == nil {
+		report.Entries = []IntegrityEntry{}
+	}
+	report.JobID = job.ID
+	report.PeerID = job.PeerID
+	report.GeneratedAt = time.Now().UTC()
+	report.Signer, report.Certificate, report.Signature = "", "", ""
+
+	if e.signer == nil {
+		return
+	}
+	report.Signer = e.signer.GetFingerprint()
+	report.Certificate = string(e.signer.GetCertificatePEM())
+
+	payload, err := report.payload()
+	if err == nil {
+		var signature []byte
+		if signature, err = e.signer.Sign(payload); err == nil {
+			report.Signature = base64.StdEncoding.EncodeToString(signature)
+			return
+		}
+	}
+	report.Signer, report.Certificate = "", ""
+	e.logger.Warn("failed to sign integrity report",
+		zap.String("job_id", job.ID),
+		zap.Error(err),
+	)
+}
+
+// TrustedSigners returns the certificate fingerprints an integrity report
+// may be signed with: this node's own, and those of its paired peers
+func (e *Engine) TrustedSigners() []string {
+	var trusted []string
+	if e.signer != nil {
+		trusted = append(trusted, e.signer.GetFingerprint())
+	}
+	for _, p := range e.config.ListTrustedPeers() {
+		trusted = append(trusted, p.Fingerprint)
+	}
+	return trusted
+}
+
+// Verify checks the report's signature against its certificate, that the
+// certificate is the signer's, and that the signer is one of trusted. A
+// report carries its own certificate, so without the last check anyone could
+// re-sign an altered report.
+func (r *IntegrityReport) Verify(trusted []string) error {
+	if r.Signature == "" {
+		return fmt.Errorf("report is not signed")
+	}
+	if !slices.Contains(trusted, r.Signer) {
+		return fmt.Errorf("report is signed by %s, which is not this node or a paired peer", r.Signer)
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	payload, err := r.payload()
+	if err != nil {
+		return err
+	}
+
+	fingerprint, err := peer.VerifySignature([]byte(r.Certificate), payload, signature)
+	if err != nil {
+		return err
+	}
+	if fingerprint != r.Signer {
+		return fmt.Errorf("certificate fingerprint %s does not match signer %s", fingerprint, r.Signer)
+	}
+	return nil
+}
+
+// payload is what the signature covers: the report without its signature
+func (r *IntegrityReport) payload() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode integrity report: %w", err)
+	}
+	return data, nil
+}
+
+// recordVolumeIntegrity notes a volume sent by a cold migration, identified
+// by the Merkle root of its file range checksums. verified is set when the
+// target's own checksums were compared with it.
+func (vm *VolumeMigrator) recordVolumeIntegrity(volumeName string, checkpoint *VolumeCheckpoint, verified bool) {
+	if vm.job == nil {
+		return
+	}
+
+	root := checkpoint.MerkleRoot
+	if root == "" {
+		if tree, err := NewMerkleTreeFromCheckpoint(checkpoint); err == nil {
+			root = tree.Root()
+		}
+	}
+
+	entry := IntegrityEntry{
+		ResourceType: "volume",
+		Resource:     volumeName,
+		Algorithm:    IntegrityMerkle,
+		Checksum:     root,
+		Verification: vm.verification,
+	}
+	if target := vm.targetName(volumeName); target != volumeName {
+		entry.TargetName = target
+	}
+	if verified {
+		now := time.Now().UTC()
+		entry.VerifiedAt = &now
+	}
+	vm.job.recordIntegrity(vm.jobsMutex, entry)
+}
+
+// recordImageIntegrity notes an image by its ID, which Docker derives from
+// the image's content. Unless verification is off, the target is asked for
+// the ID of its copy, which must match; a target without the image leaves
+// the entry unverified.
+func (im *ImageMigrator) recordImageIntegrity(ctx context.Context, res ResourceRef, peerID string) error {
+	if im.job == nil {
+		return nil
+	}
+
+	entry := IntegrityEntry{
+		ResourceType: "image",
+		Resource:     res.Name,
+		Algorithm:    IntegrityImageID,
+		Checksum:     res.ID,
+		Verification: im.verification,
+	}
+	target := im.job.rewriteImage(res.Name)
+	if target != res.Name {
+		entry.TargetName = target
+	}
+
+	if im.verification != VerifyOff && im.peers != nil {
+		client, err := im.peers.Connect(ctx, peerID, im.transfer)
+		if err != nil {
+			return fmt.Errorf("failed to connect to peer: %w", err)
+		}
+		defer client.Close()
+
+		inspect, err := client.InspectImage(ctx, target)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !inspect.Exists:
+			im.logger.Warn("target does not list the transferred image, leaving it unverified",
+				zap.String("image", res.Name),
+				zap.String("target_name", target),
+			)
+		case inspect.Id != res.ID:
+			return fmt.Errorf("target image %s has ID %s, expected %s", target, inspect.Id, res.ID)
+		default:
+			now := time.Now().UTC()
+			entry.VerifiedAt = &now
+		}
+	}
+
+	im.job.recordIntegrity(im.jobsMutex, entry)
+	return nil
+}
+
+// recordWarmIntegrity notes a volume after its final warm sync, identified
+// by the digest of its file index. Unless verification is off, the
+// target's index is fetched again and must match.
+func (vm *VolumeMigrator) recordWarmIntegrity(ctx context.Context, client *peer.GRPCClient, volumeName string, source docker.FileIndex) error {
+	if vm.job == nil {
+		return nil
+	}
+
+	entry := IntegrityEntry{
+		ResourceType: "volume",
+		Resource:     volumeName,
+		Algorithm:    IntegrityFileIndex,
+		Checksum:     source.Digest(),
+		Verification: vm.verification,
+	}
+	targetName := vm.targetName(volumeName)
+	if targetName != volumeName {
+		entry.TargetName = targetName
+	}
+
+	if vm.verification != VerifyOff {
+		target, _, err := client.GetVolumeIndex(ctx, targetName)
+		if err != nil {
+			return err
+		}
+		if changed, deleted := docker.DiffIndex(source, target); len(changed) > 0 || len(deleted) > 0 {
+			return fmt.Errorf("target copy of volume %s differs after final sync (%d changed, %d extra)", volumeName, len(changed), len(deleted))
+		}
+		now := time.Now().UTC()
+		entry.VerifiedAt = &now
+	}
+
+	vm.job.recordIntegrity(vm.jobsMutex, entry)
+	return nil
+}
@@ -0,0 +1,163 @@
+package migration
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultJobLogLines is how many log lines are kept per job
+const DefaultJobLogLines = 1000
+
+// JobLogEntry is one structured log line recorded for a job
+type JobLogEntry struct {
+	Time    time.Time              `json:"time"`
+	Level   string                 `json:"level"`
+	Logger  string                 `json:"logger,omitempty"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// JobLogs keeps the most recent log lines of each job and fans new lines
+// out to subscribers, so a job can be debugged from the UI without access
+// to the daemon's own logs. Lines are attributed by their job_id field.
+type JobLogs struct {
+	mu          sync.Mutex
+	size        int
+	buffers     map[string][]JobLogEntry
+	subscribers map[string]map[chan JobLogEntry]struct{}
+}
+
+// NewJobLogs creates a log store keeping size lines per job
+func NewJobLogs(size int) *JobLogs {
+	if size <= 0 {
+		size = DefaultJobLogLines
+	}
+	return &JobLogs{
+		size:        size,
+		buffers:     make(map[string][]JobLogEntry),
+		subscribers: make(map[string]map[chan JobLogEntry]struct{}),
+	}
+}
+
+// Append records a line for a job and delivers it to subscribers. Slow
+// subscribers miss lines rather than blocking the logger.
+func (l *JobLogs) Append(jobID string, entry JobLogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	buf := append(l.buffers[jobID], entry)
+	if len(buf) > l.size {
+		buf = buf[len(buf)-l.size:]
+	}
+	l.buffers[jobID] = buf
+
+	for ch := range l.subscribers[jobID] {
+		select {
+		case ch <- entry:
+		default:
+		}
+	}
+}
+
+// Entries returns the buffered lines of a job, oldest first
+func (l *JobLogs) Entries(jobID string) []JobLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]JobLogEntry(nil), l.buffers[jobID]...)
+}
+
+// Subscribe returns the buffered lines of a job and a channel receiving
+// lines logged after them. Call the returned function to unsubscribe.
+func (l *JobLogs) Subscribe(jobID string) ([]JobLogEntry, <-chan JobLogEntry, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan JobLogEntry, 256)
+	if l.subscribers[jobID] == nil {
+		l.subscribers[jobID] = make(map[chan JobLogEntry]struct{})
+	}
+	l.subscribers[jobID][ch] = struct{}{}
+
+	backlog := append([]JobLogEntry(nil), l.buffers[jobID]...)
+
+	unsubscribe := func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if subs, ok := l.subscribers[jobID]; ok {
+			delete(subs, ch)
+			if len(subs) == 0 {
+				delete(l.subscribers, jobID)
+			}
+		}
+	}
+	return backlog, ch, unsubscribe
+}
+
+// Remove drops a job's buffered lines
+func (l *JobLogs) Remove(jobID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.buffers, jobID)
+}
+
+// Core returns a zap core that records Info and above lines carrying a
+// job_id field. Tee it with the engine's logger core.
+func (l *JobLogs) Core() zapcore.Core {
+	return &jobLogCore{
+		LevelEnabler: zapcore.InfoLevel,
+		logs:         l,
+	}
+}
+
+// jobLogCore captures log lines into JobLogs
+type jobLogCore struct {
+	zapcore.LevelEnabler
+	logs   *JobLogs
+	fields []zapcore.Field
+}
+
+func (c *jobLogCore) With(fields []zapcore.Field) zapcore.Core {
+	return &jobLogCore{
+		LevelEnabler: c.LevelEnabler,
+		logs:         c.logs,
+		fields:       append(append([]zapcore.Field(nil), c.fields...), fields...),
+	}
+}
+
+func (c *jobLogCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+func (c *jobLogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	jobID, _ := enc.Fields["job_id"].(string)
+	if jobID == "" {
+		return nil
+	}
+	delete(enc.Fields, "job_id")
+
+	c.logs.Append(jobID, JobLogEntry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		Fields:  enc.Fields,
+	})
+	return nil
+}
+
+func (c *jobLogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,387 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/peer"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+)
+
+// Progress phases reported by the live strategy
+const (
+	PhaseCheckpoint = "checkpoint"
+	PhaseRestore    = "restore"
+)
+
+// LiveStrategy implements Sync → Checkpoint → Delta → Restore migration.
+// Containers are checkpointed with CRIU into a volume of their own, which
+// is sent to the target like any other volume and restored from there, so
+// running processes carry on where they stopped. Where either daemon cannot
+// checkpoint, the job runs as a warm migration.
+type LiveStrategy struct {
+	engine *Engine
+
+	// Source containers stopped by a checkpoint whose checkpoint volumes
+	// have not been removed
+	checkpointed []liveCheckpoint
+}
+
+// liveCheckpoint is a source container's checkpoint
+type liveCheckpoint struct {
+	ResourceRef
+	volume string // Volume holding the checkpoint
+	dir    string // The volume's mountpoint on the source daemon's host
+}
+
+func (l *LiveStrategy) PrepareMigration(ctx context.Context, job *MigrationJob) error {
+	l.engine.logger.Info("preparing live migration",
+		zap.String("job_id", job.ID),
+	)
+
+	// Containers are stopped between checkpoint and restore; pausing there
+	// would only lengthen the downtime
+	job.CanPause = false
+	job.CanResume = false
+
+	return nil
+}
+
+func (l *LiveStrategy) ExecuteMigration(ctx context.Context, job *MigrationJob, progressCh chan<- MigrationProgress) error {
+	if reason := l.unsupported(ctx); reason != "" {
+		return l.fallBack(ctx, job, progressCh, reason)
+	}
+	if reason := l.targetUnsupported(ctx, job.PeerID); reason != "" {
+		return l.fallBack(ctx, job, progressCh, reason)
+	}
+
+	l.engine.logger.Info("executing live migration", zap.String("job_id", job.ID))
+
+	progress := MigrationProgress{
+		TotalSteps: 6,
+		TotalItems: len(job.Resources),
+		Checksums:  make(map[string]string),
+		StartTime:  time.Now(),
+	}
+
+	// Phase 1: Initial sync while containers run
+	progress.CurrentStep = 1
+	progress.CurrentItem = "Initial sync (containers running)"
+	progressCh <- progress
+
+	groupSnapshots, cleanupGroups, err := l.engine.captureConsistencyGroups(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to capture consistency groups: %w", err)
+	}
+	defer cleanupGroups()
+
+	volumeMigrator := &VolumeMigrator{
+		docker:         l.engine.docker,
+		transfer:       l.engine.transfer,
+		logger:         l.engine.logger,
+		reattachShared: job.ReattachSharedVolumes,
+		groupSnapshots: groupSnapshots,
+		verification:   job.Verification,
+		job:            job,
+		jobsMutex:      &l.engine.jobsMutex,
+		peers:          l.engine.peers,
+	}
+
+	reattached := make(map[string]bool)
+	for _, res := range job.Resources {
+		if res.Type == "volume" {
+			done, err := volumeMigrator.reattachIfShared(ctx, res.Name, job.PeerID)
+			if err != nil {
+				return fmt.Errorf("failed to re-attach volume %s: %w", res.Name, err)
+			}
+			if done {
+				reattached[res.Name] = true
+				continue
+			}
+			if err := volumeMigrator.warmSync(ctx, res.Name, job.PeerID, false); err != nil {
+				return fmt.Errorf("initial sync failed for volume %s: %w", res.Name, err)
+			}
+		}
+	}
+
+	// Phase 2: Checkpoint source containers. The checkpoint stops each
+	// container so its volumes hold still for the delta sync, and they are
+	// down until restored on the target.
+	endDowntime := l.engine.beginDowntime(job)
+	defer endDowntime()
+
+	progress.CurrentStep = 2
+	progress.Phase = PhaseCheckpoint
+	checkpointID := liveCheckpointID(job)
+	for _, res := range job.Resources {
+		if res.Type != "container" {
+			continue
+		}
+		progress.CurrentItem = fmt.Sprintf("Checkpointing %s", res.Name)
+		progressCh <- progress
+
+		cp, err := l.createCheckpointVolume(ctx, job, res)
+		if err != nil {
+			l.restoreSource(ctx, checkpointID)
+			l.removeCheckpoints(ctx)
+			endDowntime()
+			return err
+		}
+		if err := l.engine.docker.CreateCheckpoint(ctx, res.ID, checkpointID, cp.dir, true); err != nil {
+			// Usually CRIU missing or unable to handle the container; put
+			// back what was checkpointed so far and run warm instead
+			l.removeCheckpointVolume(ctx, cp)
+			l.restoreSource(ctx, checkpointID)
+			l.removeCheckpoints(ctx)
+			endDowntime()
+			return l.fallBack(ctx, job, progressCh, err.Error())
+		}
+		l.checkpointed = append(l.checkpointed, cp)
+	}
+
+	// Phase 3: Delta sync - only changes since initial sync
+	progress.CurrentStep = 3
+	progress.Phase = ""
+	progress.CurrentItem = "Delta sync (final changes)"
+	progressCh <- progress
+
+	for _, res := range job.Resources {
+		if res.Type == "volume" && !reattached[res.Name] {
+			if err := volumeMigrator.warmSync(ctx, res.Name, job.PeerID, true); err != nil {
+				return fmt.Errorf("delta sync failed for volume %s: %w", res.Name, err)
+			}
+		}
+	}
+
+	// Phase 4: Send checkpoints and recreate containers on target
+	progress.CurrentStep = 4
+	progress.CurrentItem = "Transferring checkpoints"
+	progressCh <- progress
+
+	containerMigrator := &ContainerMigrator{
+		docker:   l.engine.docker,
+		transfer: l.engine.transfer,
+		logger:   l.engine.logger,
+		job:      job,
+		peers:    l.engine.peers,
+		preStart: func(ctx context.Context, state *ContainerState) error {
+			return l.engine.awaitStartable(ctx, job, state)
+		},
+		targetIDs: make(map[string]string),
+	}
+
+	for _, cp := range l.checkpointed {
+		if err := l.transferCheckpoint(ctx, volumeMigrator, job.PeerID, cp, progressCh); err != nil {
+			return fmt.Errorf("failed to transfer checkpoint of %s: %w", cp.Name, err)
+		}
+		if err := containerMigrator.MigrateContainer(ctx, cp.ID, job.PeerID, job.Mode, progressCh); err != nil {
+			return fmt.Errorf("failed to create container %s on target: %w", cp.Name, err)
+		}
+	}
+
+	// Phase 5: Restore on target. A container the target cannot restore is
+	// started fresh, losing its memory state but not its data.
+	progress.CurrentStep = 5
+	progress.Phase = PhaseRestore
+	for _, cp := range l.checkpointed {
+		progress.CurrentItem = fmt.Sprintf("Restoring %s on target", cp.Name)
+		progressCh <- progress
+
+		targetID := containerMigrator.targetIDs[cp.ID]
+		if targetID == "" {
+			return fmt.Errorf("target did not report the ID of container %s", cp.Name)
+		}
+		volume := volumeMigrator.targetName(cp.volume)
+		if err := l.requestRestore(ctx, job.PeerID, targetID, volume, checkpointID); err != nil {
+			l.engine.logger.Warn("target could not restore checkpoint, starting container fresh",
+				zap.String("job_id", job.ID),
+				zap.String("container", cp.Name),
+				zap.Error(err),
+			)
+			if err := l.requestStart(ctx, job.PeerID, targetID); err != nil {
+				return fmt.Errorf("failed to start container %s on target: %w", cp.Name, err)
+			}
+			job.ColdStarted = append(job.ColdStarted, cp.Name)
+		}
+	}
+	endDowntime()
+
+	// Phase 6: Cleanup. Copy mode leaves the source running, so its
+	// containers resume from the same checkpoint.
+	progress.CurrentStep = 6
+	progress.Phase = ""
+	progress.CurrentItem = "Cleanup"
+	progressCh <- progress
+
+	if job.Mode == ModeCopy {
+		l.restoreSource(ctx, checkpointID)
+	}
+	l.removeCheckpoints(ctx)
+
+	progress.EstimatedEnd = time.Now()
+	progressCh <- progress
+
+	return nil
+}
+
+func (l *LiveStrategy) Rollback(ctx context.Context, job *MigrationJob) error {
+	l.engine.logger.Info("rolling back live migration", zap.String("job_id", job.ID))
+
+	l.restoreSource(ctx, liveCheckpointID(job))
+	l.removeCheckpoints(ctx)
+	return nil
+}
+
+// unsupported returns why containers cannot be checkpointed on this host,
+// or "" if they can
+func (l *LiveStrategy) unsupported(ctx context.Context) string {
+	if l.engine.docker == nil {
+		return "docker client unavailable"
+	}
+	if err := l.engine.docker.CheckpointSupported(ctx); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// targetUnsupported returns why the target cannot restore checkpoints, or
+// "" if it can. A target too old to answer cannot restore them either.
+func (l *LiveStrategy) targetUnsupported(ctx context.Context, peerID string) string {
+	client, err := l.connect(ctx, peerID)
+	if err != nil {
+		return err.Error()
+	}
+	defer client.Close()
+
+	reason, err := client.CheckpointSupport(ctx)
+	if err != nil {
+		return err.Error()
+	}
+	if reason != "" {
+		return "target: " + reason
+	}
+	return ""
+}
+
+// fallBack records why live migration is unavailable and runs the job as a
+// warm migration instead
+func (l *LiveStrategy) fallBack(ctx context.Context, job *MigrationJob, progressCh chan<- MigrationProgress, reason string) error {
+	l.engine.logger.Warn("live migration unavailable, falling back to warm",
+		zap.String("job_id", job.ID),
+		zap.String("reason", reason),
+	)
+	job.LiveFallback = reason
+
+	warm := &WarmStrategy{engine: l.engine}
+	return warm.ExecuteMigration(ctx, job, progressCh)
+}
+
+// restoreSource resumes checkpointed source containers from their
+// checkpoint, or with a plain start if the restore fails
+func (l *LiveStrategy) restoreSource(ctx context.Context, checkpointID string) {
+	for _, cp := range l.checkpointed {
+		err := l.engine.docker.RestoreCheckpoint(ctx, cp.ID, checkpointID, cp.dir)
+		if err != nil {
+			l.engine.logger.Warn("failed to restore source container from checkpoint, starting it",
+				zap.String("container", cp.Name),
+				zap.Error(err),
+			)
+			err = l.engine.docker.StartContainer(ctx, cp.ID)
+		}
+		if err != nil {
+			l.engine.logger.Error("failed to restart source container",
+				zap.String("container", cp.Name),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// createCheckpointVolume creates the local volume a container is
+// checkpointed into
+func (l *LiveStrategy) createCheckpointVolume(ctx context.Context, job *MigrationJob, res ResourceRef) (liveCheckpoint, error) {
+	name := liveCheckpointID(job) + "-" + strings.TrimPrefix(res.Name, "/")
+	vol, err := l.engine.docker.CreateVolume(ctx, name, job.provenance(res.Name).WithLabels(nil), nil)
+	if err != nil {
+		return liveCheckpoint{}, fmt.Errorf("failed to create checkpoint volume for %s: %w", res.Name, err)
+	}
+	return liveCheckpoint{ResourceRef: res, volume: name, dir: vol.Mountpoint}, nil
+}
+
+// removeCheckpointVolume deletes a source checkpoint and its volume
+func (l *LiveStrategy) removeCheckpointVolume(ctx context.Context, cp liveCheckpoint) {
+	if err := l.engine.docker.RemoveVolume(ctx, cp.volume, true); err != nil {
+		l.engine.logger.Warn("failed to remove source checkpoint",
+			zap.String("container", cp.Name),
+			zap.String("volume", cp.volume),
+			zap.Error(err),
+		)
+	}
+}
+
+// removeCheckpoints deletes the source checkpoints once nothing needs to
+// restore from them
+func (l *LiveStrategy) removeCheckpoints(ctx context.Context) {
+	for _, cp := range l.checkpointed {
+		l.removeCheckpointVolume(ctx, cp)
+	}
+	l.checkpointed = nil
+}
+
+// transferCheckpoint sends a container's checkpoint volume to the peer. The
+// containers are stopped, so it is copied like a cold volume.
+func (l *LiveStrategy) transferCheckpoint(ctx context.Context, vm *VolumeMigrator, peerID string, cp liveCheckpoint, progressCh chan<- MigrationProgress) error {
+	l.engine.logger.Info("transferring checkpoint",
+		zap.String("container", cp.Name),
+		zap.String("volume", cp.volume),
+		zap.String("peer_id", peerID),
+	)
+	return vm.coldMigrate(ctx, cp.volume, peerID, progressCh)
+}
+
+// requestRestore asks the peer to start a container from the checkpoint in
+// a volume it was sent. The peer removes the volume afterwards.
+func (l *LiveStrategy) requestRestore(ctx context.Context, peerID, containerID, volume, checkpointID string) error {
+	client, err := l.connect(ctx, peerID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.RestoreContainer(ctx, &pb.ContainerRestoreRequest{
+		ContainerId:  containerID,
+		CheckpointId: checkpointID,
+		Volume:       volume,
+	})
+}
+
+// requestStart asks the peer to start a container without a checkpoint
+func (l *LiveStrategy) requestStart(ctx context.Context, peerID, containerID string) error {
+	client, err := l.connect(ctx, peerID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.StartContainer(ctx, containerID)
+}
+
+// connect opens a client to the target
+func (l *LiveStrategy) connect(ctx context.Context, peerID string) (*peer.GRPCClient, error) {
+	if l.engine.peers == nil {
+		return nil, fmt.Errorf("no peer connection to the target")
+	}
+	client, err := l.engine.peers.Connect(ctx, peerID, l.engine.transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	return client, nil
+}
+
+// liveCheckpointID names the checkpoints a job takes
+func liveCheckpointID(job *MigrationJob) string {
+	return "docker-migrate-" + job.ID
+}
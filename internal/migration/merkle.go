@@ -0,0 +1,91 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// MerkleTree is a binary hash tree over SHA-256 checksums of a volume's
+// directories and file ranges. Comparing two trees pinpoints the differing
+// ranges in O(k log n) instead of re-hashing or re-sending the whole volume.
+type MerkleTree struct {
+	// levels[0] holds the leaves; the last level holds the root
+	levels [][][]byte
+}
+
+// NewMerkleTree builds a tree from hex-encoded leaf checksums in order
+func NewMerkleTree(leafChecksums []string) (*MerkleTree, error) {
+	if len(leafChecksums) == 0 {
+		return nil, fmt.Errorf("cannot build merkle tree without leaves")
+	}
+
+	leaves := make([][]byte, len(leafChecksums))
+	for i, sum := range leafChecksums {
+		raw, err := hex.DecodeString(sum)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum for leaf %d: %w", i, err)
+		}
+		// Domain-separate leaves from interior nodes
+		leaf := sha256.Sum256(append([]byte{0x00}, raw...))
+		leaves[i] = leaf[:]
+	}
+
+	tree := &MerkleTree{levels: [][][]byte{leaves}}
+	for level := leaves; len(level) > 1; {
+		next := make([][]byte, (len(level)+1)/2)
+		for i := range next {
+			left := level[2*i]
+			right := left // Odd node is paired with itself
+			if 2*i+1 < len(level) {
+				right = level[2*i+1]
+			}
+			node := sha256.Sum256(append(append([]byte{0x01}, left...), right...))
+			next[i] = node[:]
+		}
+		tree.levels = append(tree.levels, next)
+		level = next
+	}
+
+	return tree, nil
+}
+
+// NewMerkleTreeFromCheckpoint builds a tree over a volume checkpoint's leaf checksums
+func NewMerkleTreeFromCheckpoint(checkpoint *VolumeCheckpoint) (*MerkleTree, error) {
+	return NewMerkleTree(checkpoint.LeafChecksums)
+}
+
+// Root returns the hex-encoded root hash
+func (t *MerkleTree) Root() string {
+	return hex.EncodeToString(t.levels[len(t.levels)-1][0])
+}
+
+// Leaves returns the number of leaves of the tree
+func (t *MerkleTree) Leaves() int {
+	return len(t.levels[0])
+}
+
+// Diff returns the indices of leaves whose hashes differ between two trees.
+// Trees must cover the same number of leaves.
+func (t *MerkleTree) Diff(other *MerkleTree) ([]int, error) {
+	if t.Leaves() != other.Leaves() {
+		return nil, fmt.Errorf("merkle trees cover different leaf counts: %d vs %d", t.Leaves(), other.Leaves())
+	}
+
+	var diffs []int
+	var walk func(level, index int)
+	walk = func(level, index int) {
+		if index >= len(t.levels[level]) || string(t.levels[level][index]) == string(other.levels[level][index]) {
+			return
+		}
+		if level == 0 {
+			diffs = append(diffs, index)
+			return
+		}
+		walk(level-1, 2*index)
+		walk(level-1, 2*index+1)
+	}
+	walk(len(t.levels)-1, 0)
+
+	return diffs, nil
+}
@@ -0,0 +1,116 @@
+package migration
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// NamingPolicy renames containers and volumes as they are recreated on the
+// target, so a job can avoid name clashes without resolving each conflict
+// by hand. A template takes precedence over prefix and suffix.
+type NamingPolicy struct {
+	Prefix string `json:"prefix,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+	// Template is a Go template such as "{{.Name}}-migrated". It can use
+	// .Name, .Type (container or volume) and .JobID.
+	Template string `json:"template,omitempty"`
+}
+
+// namingData is what a naming template is executed with
+type namingData struct {
+	Name  string
+	Type  string
+	JobID string
+}
+
+// validDockerName matches the names Docker accepts for containers and volumes
+var validDockerName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+// IsZero reports whether the policy leaves names unchanged
+func (p *NamingPolicy) IsZero() bool {
+	return p == nil || (p.Prefix == "" && p.Suffix == "" && p.Template == "")
+}
+
+// Validate checks the template parses and produces a usable name
+func (p *NamingPolicy) Validate() error {
+	if p.IsZero() {
+		return nil
+	}
+	name, err := p.TargetName("container", "example", "job")
+	if err != nil {
+		return err
+	}
+	if name == "example" {
+		return fmt.Errorf("naming template %q does not change names", p.Template)
+	}
+	return nil
+}
+
+// TargetName returns the name a container or volume gets on the target.
+// Other resource types keep their name.
+func (p *NamingPolicy) TargetName(resourceType, name, jobID string) (string, error) {
+	// Inspect reports container names with a leading slash
+	name = strings.TrimPrefix(name, "/")
+	if p.IsZero() || (resourceType != "container" && resourceType != "volume") {
+		return name, nil
+	}
+
+	target := p.Prefix + name + p.Suffix
+	if p.Template != "" {
+		tmpl, err := template.New("naming").Option("missingkey=error").Parse(p.Template)
+		if err != nil {
+			return "", fmt.Errorf("invalid naming template: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, namingData{Name: name, Type: resourceType, JobID: jobID}); err != nil {
+			return "", fmt.Errorf("invalid naming template: %w", err)
+		}
+		target = buf.String()
+	}
+
+	if !validDockerName.MatchString(target) {
+		return "", fmt.Errorf("naming policy gives %s %q the invalid name %q", resourceType, name, target)
+	}
+	return target, nil
+}
+
+// targetName applies the job's naming policy, keeping the original name if
+// the policy fails; StartMigration has already validated it
+func (job *MigrationJob) targetName(resourceType, name string) string {
+	target, err := job.Naming.TargetName(resourceType, name, job.ID)
+	if err != nil {
+		return strings.TrimPrefix(name, "/")
+	}
+	return target
+}
+
+// renameContainerState points a container at its target name and at the
+// target names of the job's volumes it mounts
+func (job *MigrationJob) renameContainerState(state *ContainerState) {
+	if job.Naming.IsZero() {
+		return
+	}
+
+	state.Name = job.targetName("container", state.Name)
+
+	migrated := make(map[string]bool)
+	for _, res := range job.Resources {
+		if res.Type == "volume" {
+			migrated[res.Name] = true
+		}
+	}
+	for i, v := range state.Volumes {
+		if v.Type == "volume" && migrated[v.Source] {
+			state.Volumes[i].Source = job.targetName("volume", v.Source)
+		}
+	}
+}
+
+// rewriteImage applies the job's image rewrite rules to a reference
+func (job *MigrationJob) rewriteImage(ref string) string {
+	rewritten, _ := job.ImageRewrites.Rewrite(ref)
+	return rewritten
+}
@@ -0,0 +1,116 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	"go.uber.org/zap"
+)
+
+// BasePull is a public base image the target pulls from Docker Hub while
+// the rest of an image streams peer-to-peer
+type BasePull struct {
+	Image  string `json:"image"`  // Image resource it serves
+	Base   string `json:"base"`   // Docker Hub digest reference
+	Layers int    `json:"layers"` // Bottom layers of the image it provides
+	Whole  bool   `json:"whole"`  // The image is itself public and is pulled in full
+}
+
+// findBasePulls identifies the job's images that are, or are built on,
+// Docker Hub official images
+func findBasePulls(ctx context.Context, dockerClient *docker.Client, job *MigrationJob) []BasePull {
+	if dockerClient == nil {
+		return nil
+	}
+
+	var pulls []BasePull
+	for _, res := range job.Resources {
+		if res.Type != "image" {
+			continue
+		}
+		base, err := dockerClient.FindPublicBaseImage(ctx, res.ID)
+		if err != nil || base == nil {
+			continue
+		}
+		pulls = append(pulls, BasePull{
+			Image:  res.Name,
+			Base:   base.Reference,
+			Layers: base.Layers,
+			Whole:  base.Whole,
+		})
+	}
+	return pulls
+}
+
+// basePull tracks a pre-pull running on the target. done is closed once
+// it finishes, after err is set.
+type basePull struct {
+	reference string
+	layers    int
+	whole     bool
+	done      chan struct{}
+	err       error
+}
+
+// wait blocks until the pull finishes and returns its error
+func (p *basePull) wait(ctx context.Context) error {
+	select {
+	case <-p.done:
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// scheduleBasePulls adds a task per distinct public base image that asks
+// the target to pull it, and returns the pulls keyed by image ID. Whole
+// public images are pulled by their own image task instead. Pulls are added
+// before the image tasks so they start first even at a concurrency of one,
+// and a failed pull does not fail the job: the image falls back to
+// streaming those layers.
+func (s *ColdStrategy) scheduleBasePulls(ctx context.Context, job *MigrationJob, scheduler *Scheduler, im *ImageMigrator) map[string]*basePull {
+	plan := findBasePulls(ctx, s.engine.docker, job)
+	sort.Slice(plan, func(i, j int) bool { return plan[i].Base < plan[j].Base })
+
+	idByName := make(map[string]string)
+	for _, res := range job.Resources {
+		if res.Type == "image" {
+			idByName[res.Name] = res.ID
+		}
+	}
+
+	bases := make(map[string]*basePull)
+	shared := make(map[string]*basePull)
+	for _, p := range plan {
+		id := idByName[p.Image]
+		if p.Whole {
+			bases[id] = &basePull{reference: p.Base, layers: p.Layers, whole: true}
+			continue
+		}
+
+		// Images on the same base share one pull
+		if pull, ok := shared[p.Base]; ok {
+			bases[id] = pull
+			continue
+		}
+		pull := &basePull{reference: p.Base, layers: p.Layers, done: make(chan struct{})}
+		shared[p.Base] = pull
+		bases[id] = pull
+
+		scheduler.Add(fmt.Sprintf("prepull:%s", p.Base), nil, func(ctx context.Context) error {
+			pull.err = im.requestTargetPull(ctx, job.PeerID, "", pull.reference, "", "")
+			if pull.err != nil {
+				s.engine.logger.Warn("target could not pre-pull base image",
+					zap.String("job_id", job.ID),
+					zap.String("base", pull.reference),
+					zap.Error(pull.err),
+				)
+			}
+			close(pull.done)
+			return nil
+		})
+	}
+	return bases
+}
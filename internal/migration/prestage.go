@@ -0,0 +1,200 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+
+	"go.uber.org/zap"
+)
+
+// PrestageReport records what was sent to the target ahead of a held job's
+// migration window
+type PrestageReport struct {
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Images     []string   `json:"images,omitempty"`
+	Volumes    []string   `json:"volumes,omitempty"`
+	// Error is why the last pre-stage stopped early; stage again to finish it
+	Error string `json:"error,omitempty"`
+}
+
+// CreateMigration validates a job and holds it pending instead of running
+// it, so it can be pre-staged ahead of its window and started later
+func (e *Engine) CreateMigration(ctx context.Context, job *MigrationJob) error {
+	e.logger.Info("creating held migration",
+		zap.String("job_id", job.ID),
+		zap.String("peer_id", job.PeerID),
+		zap.String("mode", string(job.Mode)),
+		zap.String("strategy", string(job.Strategy)),
+	)
+
+	if err := e.prepareJob(ctx, job); err != nil {
+		return err
+	}
+	job.Status = StatusPending
+
+	e.jobsMutex.Lock()
+	e.jobs[job.ID] = job
+	e.jobsMutex.Unlock()
+	e.persistJob(job)
+	e.publishJob(job)
+
+	return nil
+}
+
+// StartPendingMigration runs a held job
+func (e *Engine) StartPendingMigration(jobID string) error {
+	e.jobsMutex.RLock()
+	job, exists := e.jobs[jobID]
+	e.jobsMutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+	if job.Status != StatusPending {
+		return fmt.Errorf("job is not waiting to start (status: %s)", job.Status)
+	}
+
+	e.logger.Info("starting held migration", zap.String("job_id", jobID))
+	return e.launchJob(job)
+}
+
+// PrestageMigration sends a held job's images and a baseline copy of its
+// volumes to the target while the source containers keep running. When the
+// job is started, only the volume changes since then and the containers are
+// left for the migration window. Staging again refreshes the baseline.
+func (e *Engine) PrestageMigration(jobID string) error {
+	e.jobsMutex.Lock()
+	job, exists := e.jobs[jobID]
+	if !exists {
+		e.jobsMutex.Unlock()
+		return fmt.Errorf("job not found: %s", jobID)
+	}
+	if job.Status != StatusPending {
+		e.jobsMutex.Unlock()
+		return fmt.Errorf("only held jobs can be pre-staged (status: %s)", job.Status)
+	}
+	// Cold, warm and live migrations all send only what the target's copy
+	// of a volume lacks, so a baseline shortens their window
+	if job.Strategy != StrategyCold && job.Strategy != StrategyWarm && job.Strategy != StrategyLive {
+		e.jobsMutex.Unlock()
+		return fmt.Errorf("pre-staging needs the cold, warm or live strategy, not %q", job.Strategy)
+	}
+
+	job.ctx, job.cancel = context.WithCancel(e.ctx)
+	job.Status = StatusStaging
+	job.Prestage = &PrestageReport{StartedAt: time.Now().UTC()}
+	e.jobsMutex.Unlock()
+	e.persistJob(job)
+	e.publishJob(job)
+
+	go e.runPrestage(job)
+	return nil
+}
+
+// runPrestage stages a job and returns it to pending. A job cancelled while
+// staging is failed like any other cancelled job.
+func (e *Engine) runPrestage(job *MigrationJob) {
+	ctx := job.ctx
+	err := e.prestage(ctx, job)
+	cancelled := ctx.Err() != nil
+	job.cancel()
+
+	e.jobsMutex.Lock()
+	now := time.Now().UTC()
+	job.Prestage.FinishedAt = &now
+	job.ctx, job.cancel = nil, nil
+	job.Status = StatusPending
+	if err != nil {
+		job.Prestage.Error = err.Error()
+	}
+	e.jobsMutex.Unlock()
+
+	switch {
+	case cancelled:
+		e.failRecoveredJob(job, "cancelled while pre-staging")
+	case err != nil:
+		e.logger.Warn("pre-stage failed; the job can be staged again or started",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+	default:
+		e.logger.Info("pre-stage complete",
+			zap.String("job_id", job.ID),
+			zap.Int("images", len(job.Prestage.Images)),
+			zap.Int("volumes", len(job.Prestage.Volumes)),
+		)
+	}
+	e.persistJob(job)
+	e.publishJob(job)
+}
+
+// prestage transfers the job's images and syncs its volumes while their
+// containers run. Shared-storage volumes to be re-attached are left for
+// the window, as re-attaching them moves no data.
+func (e *Engine) prestage(ctx context.Context, job *MigrationJob) error {
+	jobLogger := e.logger.With(zap.String("job_id", job.ID))
+
+	progressCh := make(chan MigrationProgress, 10)
+	go e.streamProgress(job.ID, progressCh)
+	defer close(progressCh)
+
+	imageMigrator := &ImageMigrator{
+		docker:       e.docker,
+		transfer:     e.transfer,
+		logger:       jobLogger,
+		job:          job,
+		jobsMutex:    &e.jobsMutex,
+		verification: job.Verification,
+		peers:        e.peers,
+		registry:     e.config.ImageRegistry,
+	}
+
+	volumeMigrator := &VolumeMigrator{
+		docker:       e.docker,
+		transfer:     e.transfer,
+		logger:       jobLogger,
+		verification: job.Verification,
+		job:          job,
+		jobsMutex:    &e.jobsMutex,
+		peers:        e.peers,
+	}
+
+	for _, res := range job.Resources {
+		if res.Type != "image" {
+			continue
+		}
+		if err := imageMigrator.MigrateImage(ctx, res.ID, res.Name, job.PeerID, progressCh); err != nil {
+			return fmt.Errorf("failed to pre-stage image %s: %w", res.Name, err)
+		}
+		e.jobsMutex.Lock()
+		job.Prestage.Images = append(job.Prestage.Images, res.Name)
+		e.jobsMutex.Unlock()
+	}
+
+	for _, res := range job.Resources {
+		if res.Type != "volume" {
+			continue
+		}
+		if job.ReattachSharedVolumes && e.docker != nil {
+			vol, err := e.docker.InspectVolume(ctx, res.Name)
+			if err != nil {
+				return fmt.Errorf("failed to inspect volume %s: %w", res.Name, err)
+			}
+			if docker.IsSharedStorageVolume(vol) {
+				continue
+			}
+		}
+		if err := volumeMigrator.warmSync(ctx, res.Name, job.PeerID, false); err != nil {
+			return fmt.Errorf("failed to pre-stage volume %s: %w", res.Name, err)
+		}
+		e.jobsMutex.Lock()
+		job.Prestage.Volumes = append(job.Prestage.Volumes, res.Name)
+		e.jobsMutex.Unlock()
+	}
+
+	return nil
+}
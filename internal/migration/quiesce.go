@@ -0,0 +1,154 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+
+	"go.uber.org/zap"
+)
+
+// DatabaseKind identifies a well-known database engine running in a container
+type DatabaseKind string
+
+const (
+	DatabaseNone     DatabaseKind = ""
+	DatabasePostgres DatabaseKind = "postgres"
+	DatabaseMySQL    DatabaseKind = "mysql"
+	DatabaseRedis    DatabaseKind = "redis"
+)
+
+// QuiesceHook is the built-in action that flushes a database to disk before snapshotting
+type QuiesceHook struct {
+	Kind        DatabaseKind `json:"kind"`
+	Description string       `json:"description"`
+	Command     []string     `json:"command"`
+}
+
+// quiesceHooks maps each database to a flush command safe to run against a live server.
+// Credentials come from the official images' environment variables.
+var quiesceHooks = map[DatabaseKind]QuiesceHook{
+	DatabasePostgres: {
+		Kind:        DatabasePostgres,
+		Description: "CHECKPOINT (flush dirty buffers and WAL)",
+		Command:     []string{"sh", "-c", `psql -U "${POSTGRES_USER:-postgres}" -d "${POSTGRES_DB:-${POSTGRES_USER:-postgres}}" -c CHECKPOINT`},
+	},
+	DatabaseMySQL: {
+		Kind:        DatabaseMySQL,
+		Description: "FLUSH TABLES and FLUSH LOGS",
+		Command:     []string{"sh", "-c", `mysql -uroot ${MYSQL_ROOT_PASSWORD:+-p"$MYSQL_ROOT_PASSWORD"} ${MARIADB_ROOT_PASSWORD:+-p"$MARIADB_ROOT_PASSWORD"} -e "FLUSH TABLES; FLUSH LOGS"`},
+	},
+	DatabaseRedis: {
+		Kind:        DatabaseRedis,
+		Description: "SAVE (write RDB snapshot)",
+		Command:     []string{"sh", "-c", `redis-cli ${REDIS_PASSWORD:+-a "$REDIS_PASSWORD"} SAVE`},
+	},
+}
+
+// databasePorts maps default listening ports to database kinds
+var databasePorts = map[string]DatabaseKind{
+	"5432": DatabasePostgres,
+	"3306": DatabaseMySQL,
+	"6379": DatabaseRedis,
+}
+
+// DetectDatabase identifies a database by image name, falling back to exposed ports
+func DetectDatabase(image string, ports []string) DatabaseKind {
+	// Strip registry and tag: "docker.io/library/postgres:16" -> "postgres"
+	name := strings.ToLower(image)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.IndexAny(name, ":@"); i >= 0 {
+		name = name[:i]
+	}
+
+	switch {
+	case strings.Contains(name, "postgres") || strings.Contains(name, "postgis") || strings.Contains(name, "timescale"):
+		return DatabasePostgres
+	case strings.Contains(name, "mysql") || strings.Contains(name, "mariadb") || strings.Contains(name, "percona"):
+		return DatabaseMySQL
+	case strings.Contains(name, "redis") || strings.Contains(name, "valkey"):
+		return DatabaseRedis
+	}
+
+	for _, port := range ports {
+		// Exposed ports look like "5432/tcp"
+		port = strings.SplitN(port, "/", 2)[0]
+		if kind, ok := databasePorts[port]; ok {
+			return kind
+		}
+	}
+
+	return DatabaseNone
+}
+
+// GetQuiesceHook returns the built-in quiesce hook for a database kind
+func GetQuiesceHook(kind DatabaseKind) (QuiesceHook, bool) {
+	hook, ok := quiesceHooks[kind]
+	return hook, ok
+}
+
+// Quiescer runs database quiesce hooks inside containers before snapshotting
+type Quiescer struct {
+	docker *docker.Client
+	logger *zap.Logger
+}
+
+// NewQuiescer creates a new quiescer
+func NewQuiescer(dockerClient *docker.Client, logger *zap.Logger) *Quiescer {
+	return &Quiescer{
+		docker: dockerClient,
+		logger: logger,
+	}
+}
+
+// DetectContainer inspects a container and returns the database it runs, if any
+func (q *Quiescer) DetectContainer(ctx context.Context, containerID string) (DatabaseKind, error) {
+	inspect, err := q.docker.InspectContainer(ctx, containerID)
+	if err != nil {
+		return DatabaseNone, err
+	}
+	if inspect.Config == nil {
+		return DatabaseNone, nil
+	}
+
+	ports := make([]string, 0, len(inspect.Config.ExposedPorts))
+	for port := range inspect.Config.ExposedPorts {
+		ports = append(ports, string(port))
+	}
+
+	return DetectDatabase(inspect.Config.Image, ports), nil
+}
+
+// Quiesce flushes a running database container to disk so it starts cleanly after migration.
+// Containers without a known database are left untouched.
+func (q *Quiescer) Quiesce(ctx context.Context, containerID string) error {
+	if q.docker == nil {
+		return nil
+	}
+
+	kind, err := q.DetectContainer(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to detect database: %w", err)
+	}
+
+	hook, ok := GetQuiesceHook(kind)
+	if !ok {
+		return nil
+	}
+
+	q.logger.Info("quiescing database",
+		zap.String("container", containerID),
+		zap.String("database", string(kind)),
+		zap.String("action", hook.Description),
+	)
+
+	if _, err := q.docker.ExecInContainer(ctx, containerID, hook.Command); err != nil {
+		return fmt.Errorf("%s quiesce failed: %w", kind, err)
+	}
+
+	return nil
+}
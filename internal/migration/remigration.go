@@ -0,0 +1,165 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+
+	"go.uber.org/zap"
+)
+
+// ErrMigratedCopy marks a job blocked because the target already runs a
+// copy of one of its containers, or the original one of them was copied from
+var ErrMigratedCopy = errors.New("target already runs a migrated copy")
+
+// ErrRemigrationUnchecked marks a job blocked because the target could not
+// be checked for migrated copies
+var ErrRemigrationUnchecked = errors.New("could not check the target for migrated copies")
+
+// MigratedCopy is a running container on the target that would end up
+// live alongside a container in the job
+type MigratedCopy struct {
+	// Container is the container in the job
+	Container string `json:"container"`
+	// Target is the running container on the target
+	Target string `json:"target"`
+	// JobID is the job that made the copy, if known
+	JobID  string `json:"job_id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// checkMigratedCopies stops a job that would leave two live instances of a
+// stateful app, one on each host: the target already runs a copy of one of
+// its containers, or one of them is itself a copy whose original still runs
+// there. A target that cannot be asked, such as a peer too old to report
+// its host, blocks the job too. With AllowRemigration the job goes ahead
+// and the copies are only recorded on it.
+func (e *Engine) checkMigratedCopies(ctx context.Context, job *MigrationJob) error {
+	copies, err := e.findMigratedCopies(ctx, job)
+	if err != nil {
+		if !job.AllowRemigration {
+			return fmt.Errorf("%w: %v (set allow_remigration to migrate anyway)", ErrRemigrationUnchecked, err)
+		}
+		e.logger.Warn("could not check target for migrated copies, migrating anyway",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	job.MigratedCopies = copies
+	if len(copies) == 0 {
+		return nil
+	}
+
+	if !job.AllowRemigration {
+		return fmt.Errorf("%w: %s (set allow_remigration to migrate anyway)", ErrMigratedCopy, DescribeMigratedCopies(copies))
+	}
+	for _, c := range copies {
+		e.logger.Warn("migrating despite a live copy on the target",
+			zap.String("job_id", job.ID),
+			zap.String("container", c.Container),
+			zap.String("target_container", c.Target),
+			zap.String("reason", c.Reason),
+		)
+	}
+	return nil
+}
+
+// findMigratedCopies compares the provenance labels of the job's containers
+// and the target's running ones
+func (e *Engine) findMigratedCopies(ctx context.Context, job *MigrationJob) ([]MigratedCopy, error) {
+	var refs []ResourceRef
+	for _, r := range job.Resources {
+		if r.Type == "container" {
+			refs = append(refs, r)
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	if e.peers == nil || e.docker == nil {
+		return nil, fmt.Errorf("no docker client or peer connection to compare containers with")
+	}
+
+	local, err := e.docker.ListContainers(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := e.peers.Connect(ctx, job.PeerID, e.transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	defer client.Close()
+
+	targetHost, err := client.InspectHost(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remote, err := client.ListContainers(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	var copies []MigratedCopy
+	for _, ref := range refs {
+		for _, c := range local {
+			cname := ""
+			if len(c.Names) > 0 {
+				cname = strings.TrimPrefix(c.Names[0], "/")
+			}
+			if ref.ID != "" && (strings.HasPrefix(c.ID, ref.ID) || cname == ref.ID) {
+				own := docker.ProvenanceFromLabels(c.Labels)
+				copies = append(copies, MatchMigratedCopies(cname, own, host, targetHost, remote)...)
+				break
+			}
+		}
+	}
+	return copies, nil
+}
+
+// MatchMigratedCopies finds the target's running containers that would be
+// live alongside a source container called name, whose provenance labels
+// are own. sourceHost and targetHost name the two hosts as provenance
+// labels record them.
+func MatchMigratedCopies(name string, own *docker.Provenance, sourceHost, targetHost string, remote []*pb.ContainerResource) []MigratedCopy {
+	var copies []MigratedCopy
+	for _, r := range remote {
+		if r.State != "running" {
+			continue
+		}
+		theirs := docker.ProvenanceFromLabels(r.Labels)
+		switch {
+		case theirs != nil && theirs.Source == sourceHost && theirs.OriginalName == name:
+			copies = append(copies, MigratedCopy{
+				Container: name,
+				Target:    r.Name,
+				JobID:     theirs.JobID,
+				Reason:    "a copy of it is already running on the target",
+			})
+		case own != nil && theirs == nil && own.Source == targetHost && r.Name == own.OriginalName:
+			copies = append(copies, MigratedCopy{
+				Container: name,
+				Target:    r.Name,
+				JobID:     own.JobID,
+				Reason:    "it is a migrated copy and its original is still running on the target",
+			})
+		}
+	}
+	return copies
+}
+
+// DescribeMigratedCopies lists copies for an error message
+func DescribeMigratedCopies(copies []MigratedCopy) string {
+	parts := make([]string, 0, len(copies))
+	for _, c := range copies {
+		parts = append(parts, fmt.Sprintf("%s: %s as %s", c.Container, c.Reason, c.Target))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,215 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrJobNotFound is returned for a job ID the engine does not know
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrRollbackCompleted is returned when asked to roll back a completed job
+// without force
+var ErrRollbackCompleted = errors.New("job completed; rolling it back deletes the migrated resources from the target, set force to confirm")
+
+// RetentionPolicy bounds how many finished jobs are kept and for how long
+type RetentionPolicy struct {
+	MaxAge   time.Duration // 0 or negative = no age limit
+	MaxCount int           // 0 or negative = no count limit
+}
+
+// isFinished reports whether a job can no longer change state
+func isFinished(job *MigrationJob) bool {
+	switch job.Status {
+	case StatusComplete, StatusFailed:
+		return true
+	}
+	return false
+}
+
+// PurgeJobs removes finished jobs outside the retention policy, along with their
+// persisted records and rollback snapshots. Running, paused and interrupted jobs are never purged.
+// Returns the IDs of purged jobs.
+func (e *Engine) PurgeJobs(policy RetentionPolicy) []string {
+	e.jobsMutex.Lock()
+
+	finished := make([]*MigrationJob, 0, len(e.jobs))
+	for _, job := range e.jobs {
+		if isFinished(job) {
+			finished = append(finished, job)
+		}
+	}
+
+	// Newest first so the count limit keeps the most recent jobs
+	sort.Slice(finished, func(i, j int) bool {
+		return jobFinishTime(finished[i]).After(jobFinishTime(finished[j]))
+	})
+
+	cutoff := time.Now().Add(-policy.MaxAge)
+	purged := make([]string, 0)
+	for i, job := range finished {
+		expired := policy.MaxAge > 0 && jobFinishTime(job).Before(cutoff)
+		overflow := policy.MaxCount > 0 && i >= policy.MaxCount
+		if expired || overflow {
+			delete(e.jobs, job.ID)
+			purged = append(purged, job.ID)
+		}
+	}
+	e.jobsMutex.Unlock()
+
+	for _, id := range purged {
+		e.rollback.DeleteSnapshot(id)
+		if e.store != nil {
+			if err := e.store.Delete(id); err != nil {
+				e.logger.Warn("failed to delete job record", zap.String("job_id", id), zap.Error(err))
+			}
+		}
+		e.jobLogs.Remove(id)
+	}
+
+	if len(purged) > 0 {
+		e.logger.Info("purged finished jobs", zap.Int("count", len(purged)))
+	}
+
+	return purged
+}
+
+// PurgeJob removes a single finished job
+func (e *Engine) PurgeJob(jobID string) error {
+	e.jobsMutex.Lock()
+	job, exists := e.jobs[jobID]
+	if !exists {
+		e.jobsMutex.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	if !isFinished(job) {
+		e.jobsMutex.Unlock()
+		return fmt.Errorf("job is still active (status: %s)", job.Status)
+	}
+	delete(e.jobs, jobID)
+	e.jobsMutex.Unlock()
+
+	e.rollback.DeleteSnapshot(jobID)
+	e.jobLogs.Remove(jobID)
+	if e.store != nil {
+		return e.store.Delete(jobID)
+	}
+	return nil
+}
+
+// StartRetentionLoop periodically purges jobs according to policy until ctx is cancelled
+func (e *Engine) StartRetentionLoop(ctx context.Context, policy RetentionPolicy, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.PurgeJobs(policy)
+		}
+	}
+}
+
+// SweepStaleState removes recovery state no job needs any more: rollback
+// snapshots taken before cutoff for jobs that no longer exist, and job
+// records and snapshots a crash left half-written before cutoff. Returns how
+// many it removed.
+func (e *Engine) SweepStaleState(cutoff time.Time) int {
+	removed := e.rollback.SweepOrphans(cutoff, func(jobID string) bool {
+		e.jobsMutex.RLock()
+		defer e.jobsMutex.RUnlock()
+		_, exists := e.jobs[jobID]
+		return exists
+	})
+	if e.store != nil {
+		removed += e.store.SweepTemp(cutoff)
+	}
+	return removed
+}
+
+// SweepCheckpoints removes transfer checkpoints last written before cutoff,
+// keeping those of transfers recorded on jobs that have not finished. An
+// interrupted job resumes from them after a restart, when the transfer
+// manager no longer knows the transfer. Returns how many it removed.
+func (e *Engine) SweepCheckpoints(cutoff time.Time) int {
+	e.jobsMutex.RLock()
+	keep := make(map[string]bool)
+	for _, job := range e.jobs {
+		if isFinished(job) {
+			continue
+		}
+		for _, transfer := range job.Transfers {
+			keep[transfer.ID] = true
+		}
+	}
+	e.jobsMutex.RUnlock()
+
+	return e.transfer.SweepCheckpoints(cutoff, func(transferID string) bool {
+		return keep[transferID]
+	})
+}
+
+// SweepTempFiles removes consistency group exports a crash left under
+// DataDir/snapshots, untouched since before cutoff. Returns how many it
+// removed.
+func (e *Engine) SweepTempFiles(cutoff time.Time) int {
+	dir, err := e.groupSnapshotRoot()
+	if err != nil {
+		return 0
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), groupSnapshotPrefix) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if lastModified(path).After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			e.logger.Warn("failed to remove stale group export", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// lastModified returns the latest modification time of dir and the files
+// directly in it, so an export still being written is not taken as stale
+func lastModified(dir string) time.Time {
+	var latest time.Time
+	if info, err := os.Stat(dir); err == nil {
+		latest = info.ModTime()
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// jobFinishTime returns when a job ended, falling back to its start time
+func jobFinishTime(job *MigrationJob) time.Time {
+	if job.EndTime != nil {
+		return *job.EndTime
+	}
+	return job.StartTime
+}
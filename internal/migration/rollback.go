@@ -1,84 +1,295 @@
 package migration
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/peer"
+	pb "github.com/artemis/docker-migrate/proto"
 
 	"go.uber.org/zap"
 )
 
+// RollbackTimeout bounds an automatic rollback after a failed migration
+const RollbackTimeout = 5 * time.Minute
+
 // RollbackManager handles migration rollback with snapshot capabilities
 // This is critical for recovering from failed migrations without manual intervention
 type RollbackManager struct {
 	docker      *docker.Client
+	peers       *peer.PeerDiscovery
+	transfer    *peer.TransferManager
 	logger      *zap.Logger
 	snapshots   map[string]*Snapshot
 	snapshotMux sync.RWMutex
+	dir         string // Empty when snapshots are memory-only
 }
 
 // Snapshot represents the complete pre-migration state
 type Snapshot struct {
-	JobID             string               `json:"job_id"`
-	Timestamp         time.Time            `json:"timestamp"`
-	StoppedContainers []string             `json:"stopped_containers"`
-	PausedContainers  []string             `json:"paused_containers"`
-	CreatedResources  []ResourceRef        `json:"created_resources"`
-	ModifiedConfigs   []ConfigBackup       `json:"modified_configs"`
-	SourceState       map[string]string    `json:"source_state"` // Container ID -> state
+	JobID     string    `json:"job_id"`
+	Timestamp time.Time `json:"timestamp"`
+	// PeerID is the target the migration created resources on
+	PeerID            string              `json:"peer_id,omitempty"`
+	Containers        []ContainerSnapshot `json:"containers,omitempty"`
+	StoppedContainers []string            `json:"stopped_containers"`
+	PausedContainers  []string            `json:"paused_containers"`
+	// CreatedResources are what the migration creates on the target, by
+	// their names there; they are removed on rollback
+	CreatedResources []ResourceRef     `json:"created_resources"`
+	SourceState      map[string]string `json:"source_state"` // Container ID -> state
+}
+
+// ContainerSnapshot is a source container as it was before the migration
+type ContainerSnapshot struct {
+	ID            string        `json:"id"`
+	Name          string        `json:"name"`
+	Running       bool          `json:"running"`
+	Paused        bool          `json:"paused"`
+	RestartPolicy RestartPolicy `json:"restart_policy"`
 }
 
-// ConfigBackup stores original configuration for restoration
-type ConfigBackup struct {
-	ResourceType string `json:"resource_type"`
-	ResourceID   string `json:"resource_id"`
-	Config       string `json:"config"` // JSON-encoded original config
+// state names the container's state for Snapshot.SourceState
+func (cs ContainerSnapshot) state() string {
+	switch {
+	case cs.Paused:
+		return "paused"
+	case cs.Running:
+		return "running"
+	default:
+		return "stopped"
+	}
 }
 
-// NewRollbackManager creates a rollback manager
-func NewRollbackManager(dockerClient *docker.Client, logger *zap.Logger) *RollbackManager {
-	return &RollbackManager{
+// NewRollbackManager creates a rollback manager whose snapshots are written under
+// dataDir/rollback (default ~/.docker-migrate), so a rollback is still possible
+// after a crash. If the directory is unusable, snapshots are kept in memory only.
+func NewRollbackManager(dockerClient *docker.Client, dataDir string, logger *zap.Logger) *RollbackManager {
+	rm := &RollbackManager{
 		docker:    dockerClient,
 		logger:    logger,
 		snapshots: make(map[string]*Snapshot),
 	}
+
+	dir, err := rollbackDir(dataDir)
+	if err != nil {
+		logger.Warn("rollback snapshot persistence disabled", zap.Error(err))
+		return rm
+	}
+	rm.dir = dir
+	rm.loadSnapshots()
+
+	return rm
+}
+
+// SetPeers lets rollbacks reach the target to record what a migration will
+// create there and remove it again. Without peers only the source is restored.
+func (rm *RollbackManager) SetPeers(peers *peer.PeerDiscovery, transfer *peer.TransferManager) {
+	rm.peers = peers
+	rm.transfer = transfer
+}
+
+// rollbackDir resolves and creates the snapshot directory
+func rollbackDir(dataDir string) (string, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(dataDir, "rollback")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create rollback directory: %w", err)
+	}
+	return dir, nil
 }
 
-// CreateSnapshot captures current state before migration begins
-func (rm *RollbackManager) CreateSnapshot(jobID string) (*Snapshot, error) {
-	rm.logger.Info("creating rollback snapshot", zap.String("job_id", jobID))
+// loadSnapshots reads persisted snapshots. Unreadable files are skipped and logged.
+func (rm *RollbackManager) loadSnapshots() {
+	entries, err := os.ReadDir(rm.dir)
+	if err != nil {
+		rm.logger.Warn("failed to read rollback directory", zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(rm.dir, entry.Name()))
+		if err != nil {
+			rm.logger.Warn("failed to read rollback snapshot", zap.String("file", entry.Name()), zap.Error(err))
+			continue
+		}
+
+		var snapshot Snapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			rm.logger.Warn("failed to parse rollback snapshot", zap.String("file", entry.Name()), zap.Error(err))
+			continue
+		}
+		rm.snapshots[snapshot.JobID] = &snapshot
+	}
+}
+
+// persistLocked writes a snapshot atomically; callers hold snapshotMux.
+// Failures are logged: an unpersisted snapshot still works until restart.
+func (rm *RollbackManager) persistLocked(snapshot *Snapshot) {
+	if rm.dir == "" {
+		return
+	}
+
+	path := filepath.Join(rm.dir, snapshot.JobID+".json")
+	tmpPath := path + ".tmp"
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err == nil {
+		err = os.WriteFile(tmpPath, data, 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		rm.logger.Warn("failed to persist rollback snapshot",
+			zap.String("job_id", snapshot.JobID),
+			zap.Error(err),
+		)
+	}
+}
+
+// CreateSnapshot captures current state before migration begins: each source
+// container's inspect output, restart policy and whether it runs, and which
+// of the job's containers, volumes and networks the target does not have yet
+func (rm *RollbackManager) CreateSnapshot(ctx context.Context, job *MigrationJob) (*Snapshot, error) {
+	rm.logger.Info("creating rollback snapshot", zap.String("job_id", job.ID))
 
 	snapshot := &Snapshot{
-		JobID:             jobID,
+		JobID:             job.ID,
 		Timestamp:         time.Now(),
+		PeerID:            job.PeerID,
 		StoppedContainers: make([]string, 0),
 		PausedContainers:  make([]string, 0),
 		CreatedResources:  make([]ResourceRef, 0),
-		ModifiedConfigs:   make([]ConfigBackup, 0),
 		SourceState:       make(map[string]string),
 	}
 
-	// Would capture:
-	// - Current state of all containers
-	// - Running/stopped/paused status
-	// - Original configurations
-	// - Volume mount points
-	// - Network attachments
+	for _, res := range job.Resources {
+		if res.Type != "container" {
+			continue
+		}
+		captured, err := rm.captureContainer(ctx, res.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to capture container %s: %w", res.Name, err)
+		}
+		snapshot.Containers = append(snapshot.Containers, *captured)
+		snapshot.SourceState[captured.ID] = captured.state()
+	}
+
+	snapshot.CreatedResources = rm.targetResources(ctx, job)
 
 	rm.snapshotMux.Lock()
-	rm.snapshots[jobID] = snapshot
+	rm.snapshots[job.ID] = snapshot
+	rm.persistLocked(snapshot)
 	rm.snapshotMux.Unlock()
 
 	rm.logger.Info("rollback snapshot created",
-		zap.String("job_id", jobID),
+		zap.String("job_id", job.ID),
 		zap.Time("timestamp", snapshot.Timestamp),
+		zap.Int("containers", len(snapshot.Containers)),
+		zap.Int("target_resources", len(snapshot.CreatedResources)),
 	)
 
 	return snapshot, nil
 }
 
+// captureContainer records a source container's state and configuration
+func (rm *RollbackManager) captureContainer(ctx context.Context, containerID string) (*ContainerSnapshot, error) {
+	inspect, err := rm.docker.InspectContainer(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	captured := &ContainerSnapshot{
+		ID:   inspect.ID,
+		Name: strings.TrimPrefix(inspect.Name, "/"),
+	}
+	if inspect.State != nil {
+		captured.Running = inspect.State.Running
+		captured.Paused = inspect.State.Paused
+	}
+	if inspect.HostConfig != nil {
+		captured.RestartPolicy = RestartPolicy{
+			Name:              string(inspect.HostConfig.RestartPolicy.Name),
+			MaximumRetryCount: inspect.HostConfig.RestartPolicy.MaximumRetryCount,
+		}
+	}
+	return captured, nil
+}
+
+// targetResources lists the job's containers, volumes and networks, by their
+// names on the target, that the target does not have yet; the migration
+// creates them. If the target cannot be asked, nothing is listed, so nothing
+// there is removed on rollback.
+func (rm *RollbackManager) targetResources(ctx context.Context, job *MigrationJob) []ResourceRef {
+	created := make([]ResourceRef, 0)
+	if rm.peers == nil || job.PeerID == "" {
+		return created
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	client, err := rm.peers.Connect(listCtx, job.PeerID, rm.transfer)
+	if err != nil {
+		rm.logger.Warn("cannot list target resources, rollback will not remove any",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return created
+	}
+	defer client.Close()
+
+	existing, err := client.ListResources(listCtx)
+	if err != nil {
+		rm.logger.Warn("cannot list target resources, rollback will not remove any",
+			zap.String("job_id", job.ID),
+			zap.Error(err),
+		)
+		return created
+	}
+
+	present := make(map[string]bool)
+	for _, c := range existing.Containers {
+		present["container/"+c.Name] = true
+	}
+	for _, v := range existing.Volumes {
+		present["volume/"+v.Name] = true
+	}
+	for _, n := range existing.Networks {
+		present["network/"+n.Name] = true
+	}
+
+	for _, res := range job.Resources {
+		if res.Type != "container" && res.Type != "volume" && res.Type != "network" {
+			continue
+		}
+		name, err := job.Naming.TargetName(res.Type, res.Name, job.ID)
+		if err != nil || present[res.Type+"/"+name] {
+			continue
+		}
+		created = append(created, ResourceRef{Type: res.Type, Name: name})
+	}
+	return created
+}
+
 // RecordContainerStopped adds a container to the stopped list
 func (rm *RollbackManager) RecordContainerStopped(jobID, containerID string) error {
 	rm.snapshotMux.Lock()
@@ -91,6 +302,7 @@ func (rm *RollbackManager) RecordContainerStopped(jobID, containerID string) err
 
 	snapshot.StoppedContainers = append(snapshot.StoppedContainers, containerID)
 	snapshot.SourceState[containerID] = "stopped"
+	rm.persistLocked(snapshot)
 
 	return nil
 }
@@ -107,6 +319,7 @@ func (rm *RollbackManager) RecordContainerPaused(jobID, containerID string) erro
 
 	snapshot.PausedContainers = append(snapshot.PausedContainers, containerID)
 	snapshot.SourceState[containerID] = "paused"
+	rm.persistLocked(snapshot)
 
 	return nil
 }
@@ -122,12 +335,16 @@ func (rm *RollbackManager) RecordResourceCreated(jobID string, resource Resource
 	}
 
 	snapshot.CreatedResources = append(snapshot.CreatedResources, resource)
+	rm.persistLocked(snapshot)
 
 	return nil
 }
 
-// Rollback restores to pre-migration state
-func (rm *RollbackManager) Rollback(jobID string) error {
+// Rollback restores to pre-migration state. What the migration created on
+// the target is removed first, so the source never runs beside a copy; then
+// each source container gets back its name, restart policy and running or
+// paused state.
+func (rm *RollbackManager) Rollback(ctx context.Context, jobID string) error {
 	rm.snapshotMux.RLock()
 	snapshot, exists := rm.snapshots[jobID]
 	rm.snapshotMux.RUnlock()
@@ -138,6 +355,7 @@ func (rm *RollbackManager) Rollback(jobID string) error {
 
 	rm.logger.Info("starting rollback",
 		zap.String("job_id", jobID),
+		zap.Int("containers", len(snapshot.Containers)),
 		zap.Int("stopped_containers", len(snapshot.StoppedContainers)),
 		zap.Int("paused_containers", len(snapshot.PausedContainers)),
 		zap.Int("created_resources", len(snapshot.CreatedResources)),
@@ -145,44 +363,48 @@ func (rm *RollbackManager) Rollback(jobID string) error {
 
 	var rollbackErrors []error
 
-	// Step 1: Restart stopped containers
-	for _, containerID := range snapshot.StoppedContainers {
-		if err := rm.restartContainer(containerID); err != nil {
-			rm.logger.Warn("failed to restart container during rollback",
-				zap.String("container_id", containerID),
-				zap.Error(err),
-			)
-			rollbackErrors = append(rollbackErrors, err)
-		}
+	// Step 1: Remove created resources on target
+	if err := rm.removeTargetResources(ctx, snapshot); err != nil {
+		rm.logger.Warn("failed to remove migrated resources from target",
+			zap.String("job_id", jobID),
+			zap.String("peer_id", snapshot.PeerID),
+			zap.Error(err),
+		)
+		rollbackErrors = append(rollbackErrors, err)
 	}
 
-	// Step 2: Unpause paused containers
-	for _, containerID := range snapshot.PausedContainers {
-		if err := rm.unpauseContainer(containerID); err != nil {
-			rm.logger.Warn("failed to unpause container during rollback",
-				zap.String("container_id", containerID),
+	// Step 2: Restore source containers
+	for _, captured := range snapshot.Containers {
+		if err := rm.restoreContainer(ctx, captured); err != nil {
+			rm.logger.Warn("failed to restore container during rollback",
+				zap.String("container", captured.Name),
 				zap.Error(err),
 			)
 			rollbackErrors = append(rollbackErrors, err)
 		}
 	}
 
-	// Step 3: Remove created resources on target (would need gRPC call)
-	for _, resource := range snapshot.CreatedResources {
-		rm.logger.Info("would remove created resource",
-			zap.String("type", resource.Type),
-			zap.String("name", resource.Name),
-		)
-		// Would send gRPC request to target to remove resource
-	}
-
-	// Step 4: Restore modified configurations
-	for _, backup := range snapshot.ModifiedConfigs {
-		rm.logger.Info("would restore config",
-			zap.String("type", backup.ResourceType),
-			zap.String("id", backup.ResourceID),
-		)
-		// Would restore original configuration
+	// Snapshots from before containers were captured only list what the
+	// migration stopped or paused
+	if len(snapshot.Containers) == 0 {
+		for _, containerID := range snapshot.StoppedContainers {
+			if err := rm.restartContainer(ctx, containerID); err != nil {
+				rm.logger.Warn("failed to restart container during rollback",
+					zap.String("container_id", containerID),
+					zap.Error(err),
+				)
+				rollbackErrors = append(rollbackErrors, err)
+			}
+		}
+		for _, containerID := range snapshot.PausedContainers {
+			if err := rm.unpauseContainer(ctx, containerID); err != nil {
+				rm.logger.Warn("failed to unpause container during rollback",
+					zap.String("container_id", containerID),
+					zap.Error(err),
+				)
+				rollbackErrors = append(rollbackErrors, err)
+			}
+		}
 	}
 
 	if len(rollbackErrors) > 0 {
@@ -190,7 +412,7 @@ func (rm *RollbackManager) Rollback(jobID string) error {
 			zap.String("job_id", jobID),
 			zap.Int("error_count", len(rollbackErrors)),
 		)
-		return fmt.Errorf("rollback completed with %d errors", len(rollbackErrors))
+		return fmt.Errorf("rollback completed with %d errors: %w", len(rollbackErrors), rollbackErrors[0])
 	}
 
 	rm.logger.Info("rollback completed successfully", zap.String("job_id", jobID))
@@ -198,18 +420,112 @@ func (rm *RollbackManager) Rollback(jobID string) error {
 	return nil
 }
 
+// removeTargetResources asks the target to delete what the migration created
+// there. The target only removes resources it can tell came from this job.
+func (rm *RollbackManager) removeTargetResources(ctx context.Context, snapshot *Snapshot) error {
+	if len(snapshot.CreatedResources) == 0 {
+		return nil
+	}
+	if rm.peers == nil || snapshot.PeerID == "" {
+		return fmt.Errorf("cannot reach target to remove %d migrated resources", len(snapshot.CreatedResources))
+	}
+
+	client, err := rm.peers.Connect(ctx, snapshot.PeerID, rm.transfer)
+	if err != nil {
+		return fmt.Errorf("failed to connect to target: %w", err)
+	}
+	defer client.Close()
+
+	list := &pb.ResourceList{}
+	for _, res := range snapshot.CreatedResources {
+		switch res.Type {
+		case "container":
+			list.Containers = append(list.Containers, &pb.ContainerResource{Name: res.Name})
+		case "volume":
+			list.Volumes = append(list.Volumes, &pb.VolumeResource{Name: res.Name})
+		case "network":
+			list.Networks = append(list.Networks, &pb.NetworkResource{Name: res.Name})
+		}
+	}
+
+	removed, err := client.RemoveResources(ctx, snapshot.JobID, list)
+	if err != nil {
+		return err
+	}
+	rm.logger.Info("removed migrated resources from target",
+		zap.String("job_id", snapshot.JobID),
+		zap.String("peer_id", snapshot.PeerID),
+		zap.Int("containers", len(removed.Containers)),
+		zap.Int("volumes", len(removed.Volumes)),
+		zap.Int("networks", len(removed.Networks)),
+	)
+	return nil
+}
+
+// restoreContainer puts a source container back as it was captured
+func (rm *RollbackManager) restoreContainer(ctx context.Context, captured ContainerSnapshot) error {
+	inspect, err := rm.docker.InspectContainer(ctx, captured.ID)
+	if err != nil {
+		return err
+	}
+
+	if current := strings.TrimPrefix(inspect.Name, "/"); current != captured.Name {
+		if err := rm.docker.RenameContainer(ctx, captured.ID, captured.Name); err != nil {
+			return err
+		}
+	}
+
+	if inspect.HostConfig != nil {
+		policy := inspect.HostConfig.RestartPolicy
+		if restartPolicyName(string(policy.Name)) != restartPolicyName(captured.RestartPolicy.Name) ||
+			policy.MaximumRetryCount != captured.RestartPolicy.MaximumRetryCount {
+			if err := rm.docker.UpdateRestartPolicy(ctx, captured.ID, captured.RestartPolicy.Name, captured.RestartPolicy.MaximumRetryCount); err != nil {
+				return err
+			}
+		}
+	}
+
+	running := inspect.State != nil && inspect.State.Running
+	paused := inspect.State != nil && inspect.State.Paused
+	switch {
+	case captured.Paused:
+		if !running {
+			if err := rm.docker.StartContainer(ctx, captured.ID); err != nil {
+				return err
+			}
+		}
+		if !paused {
+			return rm.docker.PauseContainer(ctx, captured.ID)
+		}
+	case captured.Running:
+		if paused {
+			return rm.docker.UnpauseContainer(ctx, captured.ID)
+		}
+		if !running {
+			return rm.docker.StartContainer(ctx, captured.ID)
+		}
+	}
+	return nil
+}
+
+// restartPolicyName treats an empty restart policy as "no", as Docker does
+func restartPolicyName(name string) string {
+	if name == "" {
+		return "no"
+	}
+	return name
+}
+
 // restartContainer starts a stopped container
-func (rm *RollbackManager) restartContainer(containerID string) error {
+func (rm *RollbackManager) restartContainer(ctx context.Context, containerID string) error {
 	rm.logger.Info("restarting container", zap.String("container_id", containerID))
-	// Would use Docker SDK to start container
-	return nil
+	return rm.docker.StartContainer(ctx, containerID)
 }
 
 // unpauseContainer resumes a paused container
-func (rm *RollbackManager) unpauseContainer(containerID string) error {
+func (rm *RollbackManager) unpauseContainer(ctx context.Context, containerID string) error {
 	rm.logger.Info("unpausing container", zap.String("container_id", containerID))
-	// Would use Docker SDK to unpause container
-	return nil
+	return rm.docker.UnpauseContainer(ctx, containerID)
 }
 
 // GetSnapshot retrieves a snapshot by job ID
@@ -225,12 +541,44 @@ func (rm *RollbackManager) GetSnapshot(jobID string) (*Snapshot, error) {
 	return snapshot, nil
 }
 
+// SweepOrphans removes snapshots taken before cutoff whose job known no
+// longer reports, and snapshots a crash left half-written before cutoff.
+// Returns how many it removed.
+func (rm *RollbackManager) SweepOrphans(cutoff time.Time, known func(jobID string) bool) int {
+	rm.snapshotMux.RLock()
+	var orphans []string
+	for jobID, snapshot := range rm.snapshots {
+		if snapshot.Timestamp.Before(cutoff) && !known(jobID) {
+			orphans = append(orphans, jobID)
+		}
+	}
+	rm.snapshotMux.RUnlock()
+
+	removed := 0
+	for _, jobID := range orphans {
+		if err := rm.DeleteSnapshot(jobID); err != nil {
+			rm.logger.Warn("failed to remove orphaned rollback snapshot", zap.String("job_id", jobID), zap.Error(err))
+			continue
+		}
+		removed++
+	}
+	if rm.dir != "" {
+		removed += sweepTempFiles(rm.dir, cutoff, rm.logger)
+	}
+	return removed
+}
+
 // DeleteSnapshot removes a snapshot after successful migration
 func (rm *RollbackManager) DeleteSnapshot(jobID string) error {
 	rm.snapshotMux.Lock()
 	defer rm.snapshotMux.Unlock()
 
 	delete(rm.snapshots, jobID)
+	if rm.dir != "" {
+		if err := os.Remove(filepath.Join(rm.dir, jobID+".json")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+	}
 	rm.logger.Info("snapshot deleted", zap.String("job_id", jobID))
 
 	return nil
@@ -0,0 +1,384 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"go.uber.org/zap"
+)
+
+// ScheduleInterval is how often the engine looks for schedules that are due
+const ScheduleInterval = 30 * time.Second
+
+// ErrScheduleOverlap marks a scheduled run skipped because the job from the
+// schedule's previous run has not finished
+var ErrScheduleOverlap = errors.New("previous scheduled run still in progress")
+
+// MigrationSchedule runs a MigrationSpec against a peer later: once at
+// RunAt, or whenever Cron matches
+type MigrationSchedule struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description,omitempty"`
+	PeerID      string     `json:"peer_id"`
+	Cron        string     `json:"cron,omitempty"`
+	RunAt       *time.Time `json:"run_at,omitempty"`
+	MigrationSpec
+
+	// NextRun is when the schedule is next due; unset once a one-shot
+	// schedule has run
+	NextRun   *time.Time `json:"next_run,omitempty"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastJobID string     `json:"last_job_id,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// next returns when the schedule is due after t, or nil if it is not again
+func (s *MigrationSchedule) next(t time.Time) (*time.Time, error) {
+	if s.Cron == "" {
+		if s.RunAt != nil && s.LastRun == nil {
+			at := *s.RunAt
+			return &at, nil
+		}
+		return nil, nil
+	}
+	cron, err := ParseCron(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+	at := cron.Next(t.Local())
+	if at.IsZero() {
+		return nil, fmt.Errorf("cron expression %q never matches", s.Cron)
+	}
+	return &at, nil
+}
+
+// ScheduleStore keeps migration schedules in schedules.json in the data
+// directory. The file is read on every call, so the CLI can edit it while
+// the server runs.
+type ScheduleStore struct {
+	path   string
+	logger *zap.Logger
+	mu     sync.Mutex
+}
+
+// NewScheduleStore opens the schedule store under dataDir (default ~/.docker-migrate)
+func NewScheduleStore(dataDir string, logger *zap.Logger) (*ScheduleStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &ScheduleStore{
+		path:   filepath.Join(dataDir, "schedules.json"),
+		logger: logger,
+	}, nil
+}
+
+// List returns every schedule, soonest due first
+func (s *ScheduleStore) List() ([]*MigrationSchedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*MigrationSchedule, 0, len(schedules))
+	for _, sched := range schedules {
+		list = append(list, sched)
+	}
+	// Schedules that are not due again go last
+	sort.Slice(list, func(i, k int) bool {
+		a, b := list[i].NextRun, list[k].NextRun
+		switch {
+		case a != nil && b != nil && !a.Equal(*b):
+			return a.Before(*b)
+		case (a == nil) != (b == nil):
+			return a != nil
+		}
+		return list[i].ID < list[k].ID
+	})
+	return list, nil
+}
+
+// Get returns the schedule with id
+func (s *ScheduleStore) Get(id string) (*MigrationSchedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	sched, ok := schedules[id]
+	if !ok {
+		return nil, fmt.Errorf("schedule not found: %s", id)
+	}
+	return sched, nil
+}
+
+// Add validates a new schedule, works out when it is first due and saves it
+func (s *ScheduleStore) Add(sched *MigrationSchedule) error {
+	if sched.PeerID == "" {
+		return fmt.Errorf("%w: peer_id is required", ErrInvalidSpec)
+	}
+	if (sched.Cron == "") == (sched.RunAt == nil) {
+		return fmt.Errorf("%w: set exactly one of cron and run_at", ErrInvalidSpec)
+	}
+
+	now := time.Now()
+	if sched.RunAt != nil && !sched.RunAt.After(now) {
+		return fmt.Errorf("%w: run_at %s is in the past", ErrInvalidSpec, sched.RunAt.Format(time.RFC3339))
+	}
+	next, err := sched.next(now)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+
+	sched.ID = fmt.Sprintf("sched_%d", now.UnixNano())
+	sched.NextRun = next
+	sched.LastRun, sched.LastJobID, sched.LastError = nil, "", ""
+	sched.CreatedAt = now.UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return err
+	}
+	schedules[sched.ID] = sched
+	if err := s.write(schedules); err != nil {
+		return err
+	}
+	s.logger.Info("migration scheduled",
+		zap.String("schedule_id", sched.ID),
+		zap.String("peer_id", sched.PeerID),
+		zap.Timep("next_run", sched.NextRun),
+	)
+	return nil
+}
+
+// Delete removes the schedule with id; jobs it already started carry on
+func (s *ScheduleStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := schedules[id]; !ok {
+		return fmt.Errorf("schedule not found: %s", id)
+	}
+	delete(schedules, id)
+
+	if err := s.write(schedules); err != nil {
+		return err
+	}
+	s.logger.Info("migration schedule deleted", zap.String("schedule_id", id))
+	return nil
+}
+
+// recordRun notes a run of the schedule and when it is next due
+func (s *ScheduleStore) recordRun(id string, at time.Time, jobID string, runErr error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedules, err := s.load()
+	if err != nil {
+		return err
+	}
+	sched, ok := schedules[id]
+	if !ok {
+		// Deleted while it ran
+		return nil
+	}
+
+	ran := at.UTC()
+	sched.LastRun = &ran
+	sched.LastJobID = jobID
+	sched.LastError = ""
+	if runErr != nil {
+		sched.LastError = runErr.Error()
+	}
+	next, err := sched.next(at)
+	if err != nil {
+		sched.LastError = err.Error()
+	}
+	sched.NextRun = next
+
+	return s.write(schedules)
+}
+
+func (s *ScheduleStore) load() (map[string]*MigrationSchedule, error) {
+	schedules := make(map[string]*MigrationSchedule)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return schedules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+func (s *ScheduleStore) write(schedules map[string]*MigrationSchedule) error {
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedules: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write schedules: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save schedules: %w", err)
+	}
+	return nil
+}
+
+// Schedules returns the engine's schedule store
+func (e *Engine) Schedules() (*ScheduleStore, error) {
+	if e.schedules == nil {
+		return nil, fmt.Errorf("migration schedules are not available")
+	}
+	return e.schedules, nil
+}
+
+// StartScheduleLoop starts scheduled migrations as they fall due until ctx
+// is cancelled. A schedule that fell due while the server was down runs
+// once, as soon as it is back.
+func (e *Engine) StartScheduleLoop(ctx context.Context, interval time.Duration) {
+	if e.schedules == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		e.runDueSchedules(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Engine) runDueSchedules(ctx context.Context) {
+	schedules, err := e.schedules.List()
+	if err != nil {
+		e.logger.Warn("failed to read migration schedules", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, sched := range schedules {
+		if sched.NextRun == nil || sched.NextRun.After(now) {
+			continue
+		}
+		e.runSchedule(ctx, sched)
+	}
+}
+
+// RunSchedule starts a migration from the schedule with id now, ahead of
+// when it is due, and returns the job's ID. A one-shot schedule is then
+// spent.
+func (e *Engine) RunSchedule(ctx context.Context, id string) (string, error) {
+	if e.schedules == nil {
+		return "", fmt.Errorf("migration schedules are not available")
+	}
+	sched, err := e.schedules.Get(id)
+	if err != nil {
+		return "", err
+	}
+	return e.runSchedule(ctx, sched)
+}
+
+// runSchedule starts a migration from the schedule and records the run on
+// it. While the job from the previous run is unfinished the run is skipped,
+// so a slow migration is never overlapped by the next one.
+func (e *Engine) runSchedule(ctx context.Context, sched *MigrationSchedule) (string, error) {
+	now := time.Now()
+	if status, active := e.scheduledJobActive(sched.LastJobID); active {
+		err := fmt.Errorf("%w: job %s is %s", ErrScheduleOverlap, sched.LastJobID, status)
+		e.logger.Warn("skipping scheduled migration",
+			zap.String("schedule_id", sched.ID),
+			zap.Error(err),
+		)
+		if recordErr := e.schedules.recordRun(sched.ID, now, sched.LastJobID, err); recordErr != nil {
+			e.logger.Warn("failed to record scheduled run",
+				zap.String("schedule_id", sched.ID),
+				zap.Error(recordErr),
+			)
+		}
+		return "", err
+	}
+
+	job, err := e.NewJob(ctx, sched.PeerID, &sched.MigrationSpec)
+	if err == nil {
+		job.ScheduleID = sched.ID
+		err = e.StartMigration(ctx, job)
+	}
+
+	jobID := ""
+	if err != nil {
+		e.logger.Error("scheduled migration failed to start",
+			zap.String("schedule_id", sched.ID),
+			zap.Error(err),
+		)
+	} else {
+		jobID = job.ID
+		e.logger.Info("scheduled migration started",
+			zap.String("schedule_id", sched.ID),
+			zap.String("job_id", job.ID),
+		)
+	}
+
+	if recordErr := e.schedules.recordRun(sched.ID, now, jobID, err); recordErr != nil {
+		e.logger.Warn("failed to record scheduled run",
+			zap.String("schedule_id", sched.ID),
+			zap.Error(recordErr),
+		)
+	}
+	return jobID, err
+}
+
+// scheduledJobActive reports whether a job a schedule started has yet to
+// finish, and its status
+func (e *Engine) scheduledJobActive(jobID string) (MigrationStatus, bool) {
+	if jobID == "" {
+		return "", false
+	}
+	e.jobsMutex.RLock()
+	defer e.jobsMutex.RUnlock()
+
+	job, ok := e.jobs[jobID]
+	if !ok {
+		return "", false
+	}
+	switch job.Status {
+	case StatusComplete, StatusFailed:
+		return job.Status, false
+	}
+	return job.Status, true
+}
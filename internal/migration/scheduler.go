@@ -0,0 +1,111 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+)
+
+// schedTask is one unit of work in a job, runnable once its dependencies finish
+type schedTask struct {
+	key  string
+	deps []string
+	run  func(ctx context.Context) error
+}
+
+// Scheduler runs a job's transfers concurrently, bounded by a worker limit,
+// starting each task only once every task it depends on has succeeded.
+// The first failure cancels the tasks still running and starts no more.
+type Scheduler struct {
+	limit int
+	tasks []*schedTask
+	index map[string]*schedTask
+}
+
+// NewScheduler creates a scheduler running at most limit tasks at a time
+func NewScheduler(limit int) *Scheduler {
+	if limit < 1 {
+		limit = 1
+	}
+	return &Scheduler{
+		limit: limit,
+		index: make(map[string]*schedTask),
+	}
+}
+
+// Add registers a task. Tasks with no outstanding dependencies start in the
+// order they were added.
+func (s *Scheduler) Add(key string, deps []string, run func(ctx context.Context) error) {
+	t := &schedTask{key: key, deps: deps, run: run}
+	s.tasks = append(s.tasks, t)
+	s.index[key] = t
+}
+
+// Run executes all tasks and returns the first error
+func (s *Scheduler) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pending := make(map[string]int, len(s.tasks))
+	dependents := make(map[string][]*schedTask)
+	var ready []*schedTask
+
+	for _, t := range s.tasks {
+		for _, dep := range t.deps {
+			if _, ok := s.index[dep]; !ok {
+				return fmt.Errorf("task %s depends on unknown task %s", t.key, dep)
+			}
+			dependents[dep] = append(dependents[dep], t)
+		}
+		pending[t.key] = len(t.deps)
+		if len(t.deps) == 0 {
+			ready = append(ready, t)
+		}
+	}
+
+	type result struct {
+		task *schedTask
+		err  error
+	}
+	done := make(chan result)
+	running, finished := 0, 0
+	var firstErr error
+
+	for finished < len(s.tasks) {
+		for firstErr == nil && len(ready) > 0 && running < s.limit {
+			t := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				done <- result{task: t, err: t.run(ctx)}
+			}()
+		}
+
+		if running == 0 {
+			if firstErr != nil {
+				return firstErr
+			}
+			return fmt.Errorf("dependency cycle among %d remaining tasks", len(s.tasks)-finished)
+		}
+
+		r := <-done
+		running--
+		finished++
+
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+				cancel()
+			}
+			continue
+		}
+
+		for _, t := range dependents[r.task.key] {
+			pending[t.key]--
+			if pending[t.key] == 0 {
+				ready = append(ready, t)
+			}
+		}
+	}
+
+	return firstErr
+}
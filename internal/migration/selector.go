@@ -0,0 +1,186 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ResourceSelector picks resources by name pattern and/or labels instead of
+// explicit IDs. It is expanded against the local Docker host when the job is
+// created, so later resources matching it are not picked up.
+type ResourceSelector struct {
+	Type string `json:"type"` // container, volume, network, image
+	// Pattern is a glob matched against resource names (image tags for images)
+	Pattern string `json:"pattern,omitempty"`
+	// Labels must all match; an empty value only requires the key to be present
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SelectorExpansion records which resources a selector matched
+type SelectorExpansion struct {
+	Selector ResourceSelector `json:"selector"`
+	Matched  []ResourceRef    `json:"matched"`
+}
+
+// selectorCandidate is a resource a selector is evaluated against
+type selectorCandidate struct {
+	ref    ResourceRef
+	names  []string
+	labels map[string]string
+}
+
+// Validate checks that the selector has a known type and at least one criterion
+func (sel ResourceSelector) Validate() error {
+	switch sel.Type {
+	case "container", "volume", "network", "image":
+	default:
+		return fmt.Errorf("invalid selector type %q", sel.Type)
+	}
+	if sel.Pattern == "" && len(sel.Labels) == 0 {
+		return fmt.Errorf("%s selector needs a pattern or labels", sel.Type)
+	}
+	if sel.Pattern != "" {
+		if _, err := path.Match(sel.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", sel.Pattern, err)
+		}
+	}
+	return nil
+}
+
+func (sel ResourceSelector) matches(c selectorCandidate) bool {
+	for key, want := range sel.Labels {
+		got, ok := c.labels[key]
+		if !ok || (want != "" && got != want) {
+			return false
+		}
+	}
+	if sel.Pattern == "" {
+		return true
+	}
+	for _, name := range c.names {
+		if ok, _ := path.Match(sel.Pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandSelectors resolves selectors to resources on the local host and
+// appends them to resources, skipping any already present. The expansion of
+// each selector is returned for reporting.
+func (e *Engine) ExpandSelectors(ctx context.Context, resources []ResourceRef, selectors []ResourceSelector) ([]ResourceRef, []SelectorExpansion, error) {
+	if len(selectors) == 0 {
+		return resources, nil, nil
+	}
+
+	seen := make(map[string]bool, len(resources))
+	for _, r := range resources {
+		seen[r.Type+"/"+r.ID] = true
+	}
+
+	candidates := make(map[string][]selectorCandidate)
+	expansions := make([]SelectorExpansion, 0, len(selectors))
+
+	for _, sel := range selectors {
+		if err := sel.Validate(); err != nil {
+			return nil, nil, err
+		}
+
+		if _, ok := candidates[sel.Type]; !ok {
+			list, err := e.selectorCandidates(ctx, sel.Type)
+			if err != nil {
+				return nil, nil, err
+			}
+			candidates[sel.Type] = list
+		}
+
+		expansion := SelectorExpansion{Selector: sel, Matched: make([]ResourceRef, 0)}
+		for _, c := range candidates[sel.Type] {
+			if !sel.matches(c) {
+				continue
+			}
+			expansion.Matched = append(expansion.Matched, c.ref)
+			if key := c.ref.Type + "/" + c.ref.ID; !seen[key] {
+				seen[key] = true
+				resources = append(resources, c.ref)
+			}
+		}
+		expansions = append(expansions, expansion)
+	}
+
+	return resources, expansions, nil
+}
+
+func (e *Engine) selectorCandidates(ctx context.Context, resourceType string) ([]selectorCandidate, error) {
+	var out []selectorCandidate
+
+	switch resourceType {
+	case "container":
+		containers, err := e.docker.ListContainers(ctx, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list containers: %w", err)
+		}
+		for _, ctr := range containers {
+			names := make([]string, 0, len(ctr.Names))
+			for _, n := range ctr.Names {
+				names = append(names, strings.TrimPrefix(n, "/"))
+			}
+			name := ctr.ID
+			if len(names) > 0 {
+				name = names[0]
+			}
+			out = append(out, selectorCandidate{
+				ref:    ResourceRef{Type: "container", ID: ctr.ID, Name: name},
+				names:  names,
+				labels: ctr.Labels,
+			})
+		}
+
+	case "image":
+		images, err := e.docker.ListImages(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list images: %w", err)
+		}
+		for _, img := range images {
+			name := img.ID
+			if len(img.RepoTags) > 0 {
+				name = img.RepoTags[0]
+			}
+			out = append(out, selectorCandidate{
+				ref:    ResourceRef{Type: "image", ID: img.ID, Name: name},
+				names:  img.RepoTags,
+				labels: img.Labels,
+			})
+		}
+
+	case "volume":
+		volumes, err := e.docker.ListVolumes(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list volumes: %w", err)
+		}
+		for _, vol := range volumes {
+			out = append(out, selectorCandidate{
+				ref:    ResourceRef{Type: "volume", ID: vol.Name, Name: vol.Name},
+				names:  []string{vol.Name},
+				labels: vol.Labels,
+			})
+		}
+
+	case "network":
+		networks, err := e.docker.ListNetworks(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list networks: %w", err)
+		}
+		for _, n := range networks {
+			out = append(out, selectorCandidate{
+				ref:    ResourceRef{Type: "network", ID: n.ID, Name: n.Name},
+				names:  []string{n.Name},
+				labels: n.Labels,
+			})
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,164 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/peer"
+
+	"go.uber.org/zap"
+)
+
+// startConflictWait bounds how long a job stays paused on start conflicts.
+// A cold migration waits with its source containers stopped, so a job left
+// paused fails and is rolled back rather than keeping them down.
+const startConflictWait = 30 * time.Minute
+
+// awaitStartable re-checks, just before a container is started on the
+// target, that its name, host ports and volumes are still free there; long
+// transfers leave time for something else to take them. While anything is
+// in the way the job is paused with the conflicts listed on it, and the
+// check runs again each time the user resumes, for up to startConflictWait.
+// A check that fails fails the job; only a peer too old to run it is
+// started on without one, with a warning recorded on the job.
+func (e *Engine) awaitStartable(ctx context.Context, job *MigrationJob, state *ContainerState) error {
+	if e.peers == nil {
+		return nil
+	}
+	check := job.startCheck(state)
+	deadline := time.NewTimer(startConflictWait)
+	defer deadline.Stop()
+
+	for {
+		conflicts, err := e.checkStartConflicts(ctx, job.PeerID, check)
+		if errors.Is(err, peer.ErrStartCheckUnsupported) {
+			e.logger.Warn("target cannot check for start conflicts, starting without the check",
+				zap.String("job_id", job.ID),
+				zap.String("container", state.Name),
+			)
+			e.jobsMutex.Lock()
+			job.Errors = append(job.Errors, MigrationError{
+				Timestamp:    time.Now(),
+				Phase:        job.CurrentPhase,
+				ResourceType: "container",
+				ResourceName: state.Name,
+				Message:      "target is too old to check for start conflicts; started without the check",
+				Recoverable:  true,
+			})
+			e.jobsMutex.Unlock()
+			e.persistJob(job)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not check target for start conflicts of %s: %w", state.Name, err)
+		}
+
+		if len(conflicts) == 0 {
+			if len(job.StartConflicts) > 0 {
+				e.jobsMutex.Lock()
+				job.StartConflicts = nil
+				e.jobsMutex.Unlock()
+				e.persistJob(job)
+			}
+			return nil
+		}
+
+		resume := e.pauseForStartConflicts(job, state.Name, conflicts)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return fmt.Errorf("%s still cannot start on target after %s: %s", state.Name, startConflictWait, conflicts[0].Detail)
+		case <-resume:
+		}
+		e.logger.Info("re-checking target for start conflicts",
+			zap.String("job_id", job.ID),
+			zap.String("container", state.Name),
+		)
+	}
+}
+
+// checkStartConflicts asks the peer what would stop a container starting there
+func (e *Engine) checkStartConflicts(ctx context.Context, peerID string, check docker.StartCheck) ([]docker.StartConflict, error) {
+	client, err := e.peers.Connect(ctx, peerID, e.transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	defer client.Close()
+
+	return client.CheckStartConflicts(ctx, check)
+}
+
+// pauseForStartConflicts pauses a job until the user resolves what blocks
+// a container starting on the target, returning the channel closed when
+// they resume it
+func (e *Engine) pauseForStartConflicts(job *MigrationJob, container string, conflicts []docker.StartConflict) <-chan struct{} {
+	e.jobsMutex.Lock()
+	job.StartConflicts = conflicts
+	job.Status = StatusPaused
+	job.CanResume = true
+	resume := job.resumeChan
+	e.jobsMutex.Unlock()
+
+	e.logger.Warn("container cannot start on target, pausing until resolved",
+		zap.String("job_id", job.ID),
+		zap.String("container", container),
+		zap.Int("conflicts", len(conflicts)),
+	)
+	e.persistJob(job)
+	e.publishJob(job)
+
+	e.progressChan <- MigrationUpdate{
+		Type:  "error",
+		JobID: job.ID,
+		Error: &MigrationError{
+			Timestamp:    time.Now(),
+			Phase:        job.CurrentPhase,
+			ResourceType: "container",
+			ResourceName: container,
+			Message:      fmt.Sprintf("%d conflicts on target; resolve them and resume: %s", len(conflicts), conflicts[0].Detail),
+			Recoverable:  true,
+		},
+	}
+
+	return resume
+}
+
+// startCheck lists what a container needs free on the target. Volumes may
+// be shared with the job's other containers, which start alongside it.
+func (job *MigrationJob) startCheck(state *ContainerState) docker.StartCheck {
+	check := docker.StartCheck{Name: state.Name}
+
+	for _, p := range state.Ports {
+		if p.HostPort == 0 {
+			continue
+		}
+		check.Ports = append(check.Ports, docker.HostPort{
+			IP:       p.HostIP,
+			Port:     p.HostPort,
+			Protocol: p.Protocol,
+		})
+	}
+
+	for _, v := range state.Volumes {
+		switch v.Type {
+		case "volume":
+			check.Volumes = append(check.Volumes, v.Source)
+		case "bind":
+			check.BindPaths = append(check.BindPaths, v.Source)
+		}
+	}
+
+	for _, res := range job.Resources {
+		if res.Type == "container" {
+			if name := job.targetName("container", res.Name); name != state.Name {
+				check.SharedWith = append(check.SharedWith, name)
+			}
+		}
+	}
+
+	return check
+}
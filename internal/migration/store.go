@@ -0,0 +1,128 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"go.uber.org/zap"
+)
+
+// JobStore persists migration jobs to disk so they survive daemon restarts
+type JobStore struct {
+	dir    string
+	logger *zap.Logger
+}
+
+// NewJobStore creates a job store under dataDir (default ~/.docker-migrate)
+func NewJobStore(dataDir string, logger *zap.Logger) (*JobStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(dataDir, "jobs")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	return &JobStore{
+		dir:    dir,
+		logger: logger,
+	}, nil
+}
+
+// Save writes a job record atomically
+func (s *JobStore) Save(job *MigrationJob) error {
+	path := filepath.Join(s.dir, job.ID+".json")
+	tmpPath := path + ".tmp"
+
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write job: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename job: %w", err)
+	}
+
+	return nil
+}
+
+// LoadAll reads every persisted job. Unreadable records are skipped and logged.
+func (s *JobStore) LoadAll() ([]*MigrationJob, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job directory: %w", err)
+	}
+
+	jobs := make([]*MigrationJob, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			s.logger.Warn("failed to read job record", zap.String("file", entry.Name()), zap.Error(err))
+			continue
+		}
+
+		var job MigrationJob
+		if err := json.Unmarshal(data, &job); err != nil {
+			s.logger.Warn("failed to parse job record", zap.String("file", entry.Name()), zap.Error(err))
+			continue
+		}
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, nil
+}
+
+// Delete removes a job record
+func (s *JobStore) Delete(jobID string) error {
+	if err := os.Remove(filepath.Join(s.dir, jobID+".json")); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	return nil
+}
+
+// SweepTemp removes records a crash left half-written before cutoff
+func (s *JobStore) SweepTemp(cutoff time.Time) int {
+	return sweepTempFiles(s.dir, cutoff, s.logger)
+}
+
+// sweepTempFiles removes *.tmp files in dir last modified before cutoff
+func sweepTempFiles(dir string, cutoff time.Time, logger *zap.Logger) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			logger.Warn("failed to remove stale file", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		removed++
+	}
+	return removed
+}
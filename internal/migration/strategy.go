@@ -3,6 +3,7 @@ package migration
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -74,6 +75,23 @@ func (s *ColdStrategy) ExecuteMigration(ctx context.Context, job *MigrationJob,
 		StartTime:   time.Now(),
 	}
 
+	// Size the data transfers so the largest start first. Sizing walks the
+	// volumes, so it is done before the containers go down.
+	var transfers []ResourceRef
+	position := make(map[string]int)
+	for i, res := range job.Resources {
+		if res.Type == "image" || res.Type == "volume" {
+			transfers = append(transfers, res)
+		}
+		position[taskKey(res)] = i
+	}
+	estimate := s.engine.EstimateSizes(ctx, transfers)
+	progress.BytesTotal = estimate.TotalBytes
+
+	// Source containers are down from here until they start on the target
+	endDowntime := s.engine.beginDowntime(job)
+	defer endDowntime()
+
 	// Step 1: Stop source containers
 	currentStep++
 	progress.CurrentStep = currentStep
@@ -82,95 +100,158 @@ func (s *ColdStrategy) ExecuteMigration(ctx context.Context, job *MigrationJob,
 
 	for _, res := range job.Resources {
 		if res.Type == "container" {
+			if job.QuiesceDatabases {
+				if err := s.engine.quiescer.Quiesce(ctx, res.ID); err != nil {
+					s.engine.logger.Warn("database quiesce failed, continuing with stop",
+						zap.String("container", res.Name),
+						zap.Error(err),
+					)
+				}
+			}
 			if err := s.stopContainer(ctx, res.Name); err != nil {
 				return fmt.Errorf("failed to stop container %s: %w", res.Name, err)
 			}
 		}
 	}
 
-	// Step 2: Migrate images with layer deduplication
-	imageMigrator := &ImageMigrator{
-		docker:   s.engine.docker,
-		transfer: s.engine.transfer,
-		logger:   s.engine.logger,
+	// Consistency groups are exported together while their members are frozen
+	groupSnapshots, cleanupGroups, err := s.engine.captureConsistencyGroups(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to capture consistency groups: %w", err)
 	}
+	defer cleanupGroups()
 
-	for i, res := range job.Resources {
-		if res.Type == "image" {
-			currentStep++
-			progress.CurrentStep = currentStep
-			progress.CurrentNumber = i + 1
-			progress.CurrentItem = fmt.Sprintf("Transferring image: %s", res.Name)
-			progressCh <- progress
-
-			if err := imageMigrator.MigrateImage(ctx, res.ID, job.PeerID, progressCh); err != nil {
-				return fmt.Errorf("failed to migrate image %s: %w", res.Name, err)
-			}
-		}
+	// Tag the migrators' lines so they show up in the job's log stream
+	jobLogger := s.engine.logger.With(zap.String("job_id", job.ID))
+
+	imageMigrator := &ImageMigrator{
+		docker:       s.engine.docker,
+		transfer:     s.engine.transfer,
+		logger:       jobLogger,
+		job:          job,
+		jobsMutex:    &s.engine.jobsMutex,
+		verification: job.Verification,
+		peers:        s.engine.peers,
+		registry:     s.engine.config.ImageRegistry,
 	}
 
-	// Step 3: Migrate volumes with checksums
 	volumeMigrator := &VolumeMigrator{
-		docker:   s.engine.docker,
-		transfer: s.engine.transfer,
-		logger:   s.engine.logger,
+		docker:         s.engine.docker,
+		transfer:       s.engine.transfer,
+		logger:         jobLogger,
+		reattachShared: job.ReattachSharedVolumes,
+		groupSnapshots: groupSnapshots,
+		verification:   job.Verification,
+		job:            job,
+		jobsMutex:      &s.engine.jobsMutex,
+		peers:          s.engine.peers,
 	}
 
-	for i, res := range job.Resources {
-		if res.Type == "volume" {
-			currentStep++
-			progress.CurrentStep = currentStep
-			progress.CurrentNumber = i + 1
-			progress.CurrentItem = fmt.Sprintf("Transferring volume: %s", res.Name)
-			progressCh <- progress
-
-			if err := volumeMigrator.MigrateVolume(ctx, res.Name, job.PeerID, StrategyCold, progressCh); err != nil {
-				return fmt.Errorf("failed to migrate volume %s: %w", res.Name, err)
-			}
-		}
+	networkMigrator := &NetworkMigrator{
+		docker:   s.engine.docker,
+		transfer: s.engine.transfer,
+		logger:   jobLogger,
 	}
 
-	// Step 4: Create networks on target
-	networkMigrator := &NetworkMigrator{
+	containerMigrator := &ContainerMigrator{
 		docker:   s.engine.docker,
 		transfer: s.engine.transfer,
-		logger:   s.engine.logger,
+		logger:   jobLogger,
+		job:      job,
+		peers:    s.engine.peers,
+		preStart: func(ctx context.Context, state *ContainerState) error {
+			return s.engine.awaitStartable(ctx, job, state)
+		},
 	}
 
-	for i, res := range job.Resources {
+	// Steps 2-5: images, volumes and networks are independent of each other
+	// and transfer in parallel; each container waits for the resources it
+	// uses, and containers are created in job order
+	var progressMu sync.Mutex
+	step := func(number int, item string) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		currentStep++
+		progress.CurrentStep = currentStep
+		progress.CurrentNumber = number
+		progress.CurrentItem = item
+		progressCh <- progress
+	}
+
+	scheduler := NewScheduler(s.engine.config.MaxConcurrent)
+
+	var ordered []ResourceRef
+	ordered = append(ordered, largestFirst(transfers, estimate)...)
+	for _, res := range job.Resources {
 		if res.Type == "network" {
-			currentStep++
-			progress.CurrentStep = currentStep
-			progress.CurrentNumber = i + 1
-			progress.CurrentItem = fmt.Sprintf("Creating network: %s", res.Name)
-			progressCh <- progress
-
-			if err := networkMigrator.MigrateNetwork(ctx, res.Name, job.PeerID); err != nil {
-				return fmt.Errorf("failed to migrate network %s: %w", res.Name, err)
-			}
+			ordered = append(ordered, res)
 		}
 	}
 
-	// Step 5: Create and start containers on target
-	containerMigrator := &ContainerMigrator{
-		docker:   s.engine.docker,
-		transfer: s.engine.transfer,
-		logger:   s.engine.logger,
+	// Public base images are pulled by the target while private layers stream
+	if job.PrePullBaseImages {
+		imageMigrator.bases = s.scheduleBasePulls(ctx, job, scheduler, imageMigrator)
 	}
 
+	for _, res := range ordered {
+		i, res := position[taskKey(res)], res
+		switch res.Type {
+		case "image":
+			scheduler.Add(taskKey(res), nil, func(ctx context.Context) error {
+				step(i+1, fmt.Sprintf("Transferring image: %s", res.Name))
+				if err := imageMigrator.MigrateImage(ctx, res.ID, res.Name, job.PeerID, progressCh); err != nil {
+					return fmt.Errorf("failed to migrate image %s: %w", res.Name, err)
+				}
+				if err := imageMigrator.recordImageIntegrity(ctx, res, job.PeerID); err != nil {
+					return fmt.Errorf("failed to verify image %s: %w", res.Name, err)
+				}
+				return nil
+			})
+		case "volume":
+			scheduler.Add(taskKey(res), nil, func(ctx context.Context) error {
+				step(i+1, fmt.Sprintf("Transferring volume: %s", res.Name))
+				if err := volumeMigrator.MigrateVolume(ctx, res.Name, job.PeerID, StrategyCold, progressCh); err != nil {
+					return fmt.Errorf("failed to migrate volume %s: %w", res.Name, err)
+				}
+				return nil
+			})
+		case "network":
+			scheduler.Add(taskKey(res), nil, func(ctx context.Context) error {
+				step(i+1, fmt.Sprintf("Creating network: %s", res.Name))
+				if err := networkMigrator.MigrateNetwork(ctx, res.Name, job.PeerID); err != nil {
+					return fmt.Errorf("failed to migrate network %s: %w", res.Name, err)
+				}
+				return nil
+			})
+		}
+	}
+
+	previousContainer := ""
 	for i, res := range job.Resources {
-		if res.Type == "container" {
-			currentStep++
-			progress.CurrentStep = currentStep
-			progress.CurrentNumber = i + 1
-			progress.CurrentItem = fmt.Sprintf("Creating container: %s", res.Name)
-			progressCh <- progress
+		if res.Type != "container" {
+			continue
+		}
+		i, res := i, res
+
+		deps := s.containerDependencies(ctx, job, res)
+		if previousContainer != "" {
+			deps = append(deps, previousContainer)
+		}
+		previousContainer = taskKey(res)
 
+		scheduler.Add(taskKey(res), deps, func(ctx context.Context) error {
+			step(i+1, fmt.Sprintf("Creating container: %s", res.Name))
 			if err := containerMigrator.MigrateContainer(ctx, res.ID, job.PeerID, job.Mode, progressCh); err != nil {
 				return fmt.Errorf("failed to migrate container %s: %w", res.Name, err)
 			}
-		}
+			return nil
+		})
+	}
+
+	if err := scheduler.Run(ctx); err != nil {
+		return err
 	}
+	endDowntime()
 
 	// Step 6: Cleanup based on mode
 	currentStep++
@@ -218,15 +299,19 @@ func (s *ColdStrategy) Rollback(ctx context.Context, job *MigrationJob) error {
 }
 
 func (s *ColdStrategy) stopContainer(ctx context.Context, name string) error {
-	// Would use Docker SDK to stop container
-	// For now, log the operation
+	if s.engine.docker == nil {
+		return fmt.Errorf("docker client unavailable")
+	}
 	s.engine.logger.Info("stopping container", zap.String("name", name))
-	return nil
+	return s.engine.docker.StopContainer(ctx, name, nil)
 }
 
 func (s *ColdStrategy) startContainer(ctx context.Context, name string) error {
+	if s.engine.docker == nil {
+		return fmt.Errorf("docker client unavailable")
+	}
 	s.engine.logger.Info("starting container", zap.String("name", name))
-	return nil
+	return s.engine.docker.StartContainer(ctx, name)
 }
 
 func (s *ColdStrategy) disableSourceContainer(ctx context.Context, name string) error {
@@ -238,6 +323,42 @@ func (s *ColdStrategy) disableSourceContainer(ctx context.Context, name string)
 	return nil
 }
 
+// containerDependencies returns the scheduler keys of the job's images,
+// volumes and networks that a container uses. If the container cannot be
+// inspected it waits for all of them.
+func (s *ColdStrategy) containerDependencies(ctx context.Context, job *MigrationJob, res ResourceRef) []string {
+	used, err := containerResources(ctx, s.engine.docker, res.ID)
+	if err != nil {
+		s.engine.logger.Warn("failed to inspect container, waiting for all transfers",
+			zap.String("container", res.Name),
+			zap.Error(err),
+		)
+	}
+
+	var deps []string
+	for _, r := range job.Resources {
+		if r.Type != "image" && r.Type != "volume" && r.Type != "network" {
+			continue
+		}
+		if err != nil {
+			deps = append(deps, taskKey(r))
+			continue
+		}
+		for _, u := range used {
+			if u.Type == r.Type && (u.ID == r.ID || u.Name == r.Name || u.Name == r.ID) {
+				deps = append(deps, taskKey(r))
+				break
+			}
+		}
+	}
+	return deps
+}
+
+// taskKey identifies a resource's task within a job's scheduler
+func taskKey(res ResourceRef) string {
+	return res.Type + ":" + res.ID + "/" + res.Name
+}
+
 // WarmStrategy implements Sync → Pause → Delta → Cutover migration
 // This minimizes downtime by pre-syncing data while containers run
 type WarmStrategy struct {
@@ -271,27 +392,61 @@ func (w *WarmStrategy) ExecuteMigration(ctx context.Context, job *MigrationJob,
 	progress.CurrentItem = "Initial warm sync (containers running)"
 	progressCh <- progress
 
+	groupSnapshots, cleanupGroups, err := w.engine.captureConsistencyGroups(ctx, job)
+	if err != nil {
+		return fmt.Errorf("failed to capture consistency groups: %w", err)
+	}
+	defer cleanupGroups()
+
 	volumeMigrator := &VolumeMigrator{
-		docker:   w.engine.docker,
-		transfer: w.engine.transfer,
-		logger:   w.engine.logger,
+		docker:         w.engine.docker,
+		transfer:       w.engine.transfer,
+		logger:         w.engine.logger,
+		reattachShared: job.ReattachSharedVolumes,
+		groupSnapshots: groupSnapshots,
+		verification:   job.Verification,
+		job:            job,
+		jobsMutex:      &w.engine.jobsMutex,
+		peers:          w.engine.peers,
 	}
 
+	// Shared-storage volumes are re-attached once and skipped by the delta sync
+	reattached := make(map[string]bool)
 	for _, res := range job.Resources {
 		if res.Type == "volume" {
+			done, err := volumeMigrator.reattachIfShared(ctx, res.Name, job.PeerID)
+			if err != nil {
+				return fmt.Errorf("failed to re-attach volume %s: %w", res.Name, err)
+			}
+			if done {
+				reattached[res.Name] = true
+				continue
+			}
 			if err := volumeMigrator.warmSync(ctx, res.Name, job.PeerID, false); err != nil {
 				return fmt.Errorf("warm sync failed for volume %s: %w", res.Name, err)
 			}
 		}
 	}
 
-	// Phase 2: Pause source containers
+	// Phase 2: Pause source containers. They are down from here until
+	// they start on the target.
+	endDowntime := w.engine.beginDowntime(job)
+	defer endDowntime()
+
 	progress.CurrentStep = 2
 	progress.CurrentItem = "Pausing source containers"
 	progressCh <- progress
 
 	for _, res := range job.Resources {
 		if res.Type == "container" {
+			if job.QuiesceDatabases {
+				if err := w.engine.quiescer.Quiesce(ctx, res.ID); err != nil {
+					w.engine.logger.Warn("database quiesce failed, continuing with pause",
+						zap.String("container", res.Name),
+						zap.Error(err),
+					)
+				}
+			}
 			if err := w.pauseContainer(ctx, res.Name); err != nil {
 				return fmt.Errorf("failed to pause container %s: %w", res.Name, err)
 			}
@@ -304,7 +459,7 @@ func (w *WarmStrategy) ExecuteMigration(ctx context.Context, job *MigrationJob,
 	progressCh <- progress
 
 	for _, res := range job.Resources {
-		if res.Type == "volume" {
+		if res.Type == "volume" && !reattached[res.Name] {
 			if err := volumeMigrator.warmSync(ctx, res.Name, job.PeerID, true); err != nil {
 				return fmt.Errorf("delta sync failed for volume %s: %w", res.Name, err)
 			}
@@ -320,6 +475,11 @@ func (w *WarmStrategy) ExecuteMigration(ctx context.Context, job *MigrationJob,
 		docker:   w.engine.docker,
 		transfer: w.engine.transfer,
 		logger:   w.engine.logger,
+		job:      job,
+		peers:    w.engine.peers,
+		preStart: func(ctx context.Context, state *ContainerState) error {
+			return w.engine.awaitStartable(ctx, job, state)
+		},
 	}
 
 	for _, res := range job.Resources {
@@ -329,6 +489,7 @@ func (w *WarmStrategy) ExecuteMigration(ctx context.Context, job *MigrationJob,
 			}
 		}
 	}
+	endDowntime()
 
 	// Phase 5: Cleanup source
 	progress.CurrentStep = 5
@@ -373,18 +534,27 @@ func (w *WarmStrategy) Rollback(ctx context.Context, job *MigrationJob) error {
 }
 
 func (w *WarmStrategy) pauseContainer(ctx context.Context, name string) error {
+	if w.engine.docker == nil {
+		return fmt.Errorf("docker client unavailable")
+	}
 	w.engine.logger.Info("pausing container", zap.String("name", name))
-	return nil
+	return w.engine.docker.PauseContainer(ctx, name)
 }
 
 func (w *WarmStrategy) unpauseContainer(ctx context.Context, name string) error {
+	if w.engine.docker == nil {
+		return fmt.Errorf("docker client unavailable")
+	}
 	w.engine.logger.Info("unpausing container", zap.String("name", name))
-	return nil
+	return w.engine.docker.UnpauseContainer(ctx, name)
 }
 
 func (w *WarmStrategy) stopContainer(ctx context.Context, name string) error {
+	if w.engine.docker == nil {
+		return fmt.Errorf("docker client unavailable")
+	}
 	w.engine.logger.Info("stopping container", zap.String("name", name))
-	return nil
+	return w.engine.docker.StopContainer(ctx, name, nil)
 }
 
 // SnapshotStrategy uses filesystem snapshots for instant migration
@@ -0,0 +1,308 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/docker"
+	"go.uber.org/zap"
+)
+
+// MigrationSpec is everything about a migration except where it goes: what
+// to move and how. It is the body of POST /api/migrate without peer_id and
+// dry_run, and what a template stores.
+type MigrationSpec struct {
+	Mode       string   `json:"mode"`     // copy or move
+	Strategy   string   `json:"strategy"` // cold, warm, snapshot, live
+	Containers []string `json:"containers"`
+	Images     []string `json:"images"`
+	Volumes    []string `json:"volumes"`
+	Networks   []string `json:"networks"`
+	// ReattachSharedVolumes recreates NFS/cloud-driver volumes on the target instead of copying them
+	ReattachSharedVolumes bool `json:"reattach_shared_volumes"`
+	// AllowDefaultRuntime runs containers under the target's default runtime
+	// when theirs, such as runsc or kata, is not installed there
+	AllowDefaultRuntime bool `json:"allow_default_runtime"`
+	// QuiesceDatabases flushes detected Postgres/MySQL/Redis containers before stop/pause
+	QuiesceDatabases bool `json:"quiesce_databases"`
+	// ConsistencyGroups lists volumes captured together while their containers are frozen
+	ConsistencyGroups []ConsistencyGroup `json:"consistency_groups"`
+	// Verification is full (default), fast (sampled) or off
+	Verification string `json:"verification"`
+	// Priority "high" pauses other transfers until this migration's transfers finish
+	Priority string `json:"priority"`
+	// Selectors add resources by name glob and/or labels, e.g. all volumes matching "gitlab*"
+	Selectors []ResourceSelector `json:"selectors"`
+	// SkipDependencies stops the images, volumes and networks of selected containers being added
+	SkipDependencies bool `json:"skip_dependencies"`
+	// Naming renames containers and volumes on the target, e.g. {"suffix": "-migrated"}
+	Naming *NamingPolicy `json:"naming"`
+	// ImageRewrites rewrite image references, e.g. {"from": "registry.old.local/*", "to": "registry.new.local/*"}
+	ImageRewrites docker.ImageRewriteRules `json:"image_rewrites"`
+	// ImageMode "reference" sends only image digests for the target to pull from its registry;
+	// "registry" pushes images to the configured image_registry for the target to pull
+	ImageMode string `json:"image_mode"`
+	// PrePullBaseImages has the target pull public base images from Docker Hub in parallel
+	PrePullBaseImages bool `json:"pre_pull_base_images"`
+	// MaxDowntime is the longest the source containers may be down, e.g. "2m"; with no
+	// strategy, cold is used if it fits and warm otherwise
+	MaxDowntime string `json:"max_downtime"`
+	// HealthWindow is how long the containers have to become healthy on the
+	// target, e.g. "5m"; defaults to the configured health_window
+	HealthWindow string `json:"health_window"`
+	// AllowRemigration migrates containers even when the target already runs
+	// a migrated copy of one, or the original a copy was made from
+	AllowRemigration bool `json:"allow_remigration"`
+}
+
+// ErrInvalidSpec marks a migration spec that cannot be turned into a job
+var ErrInvalidSpec = errors.New("invalid migration spec")
+
+// NewJob builds a job moving spec's resources to peerID. Selectors and,
+// unless skipped, the dependencies of selected containers are expanded now
+// so the job runs against a fixed resource list.
+func (e *Engine) NewJob(ctx context.Context, peerID string, spec *MigrationSpec) (*MigrationJob, error) {
+	var resources []ResourceRef
+	for _, id := range spec.Containers {
+		resources = append(resources, ResourceRef{Type: "container", ID: id, Name: id})
+	}
+	for _, id := range spec.Images {
+		resources = append(resources, ResourceRef{Type: "image", ID: id, Name: id})
+	}
+	for _, name := range spec.Volumes {
+		resources = append(resources, ResourceRef{Type: "volume", ID: name, Name: name})
+	}
+	for _, id := range spec.Networks {
+		resources = append(resources, ResourceRef{Type: "network", ID: id, Name: id})
+	}
+
+	resources, expansions, err := e.ExpandSelectors(ctx, resources, spec.Selectors)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+
+	var included []DependencyInclusion
+	if !spec.SkipDependencies {
+		resources, included, err = e.ExpandDependencies(ctx, resources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var maxDowntime time.Duration
+	if spec.MaxDowntime != "" {
+		maxDowntime, err = time.ParseDuration(spec.MaxDowntime)
+		if err != nil || maxDowntime <= 0 {
+			return nil, fmt.Errorf("%w: invalid max_downtime %q", ErrInvalidSpec, spec.MaxDowntime)
+		}
+	}
+
+	var healthWindow time.Duration
+	if spec.HealthWindow != "" {
+		healthWindow, err = time.ParseDuration(spec.HealthWindow)
+		if err != nil || healthWindow <= 0 {
+			return nil, fmt.Errorf("%w: invalid health_window %q", ErrInvalidSpec, spec.HealthWindow)
+		}
+	}
+
+	return &MigrationJob{
+		ID:                    fmt.Sprintf("mig_%d", time.Now().UnixNano()),
+		PeerID:                peerID,
+		Mode:                  MigrationMode(spec.Mode),
+		Strategy:              MigrationStrategy(spec.Strategy),
+		Resources:             resources,
+		ReattachSharedVolumes: spec.ReattachSharedVolumes,
+		AllowDefaultRuntime:   spec.AllowDefaultRuntime,
+		QuiesceDatabases:      spec.QuiesceDatabases,
+		ConsistencyGroups:     spec.ConsistencyGroups,
+		Verification:          VerificationLevel(spec.Verification),
+		Priority:              spec.Priority,
+		SelectorExpansions:    expansions,
+		IncludedDependencies:  included,
+		Naming:                spec.Naming,
+		ImageRewrites:         spec.ImageRewrites,
+		ImageMode:             ImageTransferMode(spec.ImageMode),
+		PrePullBaseImages:     spec.PrePullBaseImages,
+		MaxDowntimeMs:         maxDowntime.Milliseconds(),
+		AllowRemigration:      spec.AllowRemigration,
+		HealthWindowMs:        healthWindow.Milliseconds(),
+	}, nil
+}
+
+// MigrationTemplate is a named MigrationSpec that can be run against any
+// peer. One that also names its peer is a saved plan, re-run as it is.
+type MigrationTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// PeerID is the target used when a run names none
+	PeerID string `json:"peer_id,omitempty"`
+	MigrationSpec
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// templateNamePattern keeps template names usable in URLs and on the command line
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidateTemplateName rejects names that cannot be used in a URL path
+func ValidateTemplateName(name string) error {
+	if !templateNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid template name %q: use up to 64 letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// TemplateStore keeps migration templates in templates.json in the data directory
+type TemplateStore struct {
+	path   string
+	logger *zap.Logger
+	mu     sync.Mutex
+}
+
+// NewTemplateStore opens the template store under dataDir (default ~/.docker-migrate)
+func NewTemplateStore(dataDir string, logger *zap.Logger) (*TemplateStore, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &TemplateStore{
+		path:   filepath.Join(dataDir, "templates.json"),
+		logger: logger,
+	}, nil
+}
+
+// List returns every template, sorted by name
+func (s *TemplateStore) List() ([]*MigrationTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*MigrationTemplate, 0, len(templates))
+	for _, t := range templates {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, k int) bool { return list[i].Name < list[k].Name })
+	return list, nil
+}
+
+// Get returns the template called name
+func (s *TemplateStore) Get(name string) (*MigrationTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("template not found: %s", name)
+	}
+	return t, nil
+}
+
+// Save creates the template or replaces the one with the same name, keeping
+// its creation time
+func (s *TemplateStore) Save(t *MigrationTemplate) error {
+	if err := ValidateTemplateName(t.Name); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	t.CreatedAt = now
+	if existing, ok := templates[t.Name]; ok {
+		t.CreatedAt = existing.CreatedAt
+	}
+	t.UpdatedAt = now
+	templates[t.Name] = t
+
+	if err := s.write(templates); err != nil {
+		return err
+	}
+	s.logger.Info("migration template saved", zap.String("template", t.Name))
+	return nil
+}
+
+// Delete removes the template called name
+func (s *TemplateStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	templates, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := templates[name]; !ok {
+		return fmt.Errorf("template not found: %s", name)
+	}
+	delete(templates, name)
+
+	if err := s.write(templates); err != nil {
+		return err
+	}
+	s.logger.Info("migration template deleted", zap.String("template", name))
+	return nil
+}
+
+func (s *TemplateStore) load() (map[string]*MigrationTemplate, error) {
+	templates := make(map[string]*MigrationTemplate)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return templates, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	return templates, nil
+}
+
+func (s *TemplateStore) write(templates map[string]*MigrationTemplate) error {
+	data, err := json.MarshalIndent(templates, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal templates: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write templates: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save templates: %w", err)
+	}
+	return nil
+}
+
+// Templates returns the engine's template store
+func (e *Engine) Templates() (*TemplateStore, error) {
+	if e.templates == nil {
+		return nil, fmt.Errorf("migration templates are not available")
+	}
+	return e.templates, nil
+}
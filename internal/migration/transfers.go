@@ -0,0 +1,130 @@
+package migration
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/peer"
+	"go.uber.org/zap"
+)
+
+// TransferState is the last reported status of one of a job's transfers
+type TransferState struct {
+	ID           string    `json:"id"`
+	ResourceType string    `json:"resource_type"`
+	ResourceName string    `json:"resource_name"`
+	Status       string    `json:"status"`
+	BytesDone    int64     `json:"bytes_done"`
+	BytesTotal   int64     `json:"bytes_total"`
+	Error        string    `json:"error,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// transferResourceType maps a transfer type to the resource type it moves
+func transferResourceType(t peer.TransferType) string {
+	switch t {
+	case peer.TransferVolume:
+		return "volume"
+	case peer.TransferImage:
+		return "image"
+	default:
+		return ""
+	}
+}
+
+// findResource returns the job's resource a transfer of sourceID belongs to
+func (job *MigrationJob) findResource(resourceType, sourceID string) (ResourceRef, bool) {
+	for _, res := range job.Resources {
+		if res.Type == resourceType && (res.ID == sourceID || res.Name == sourceID) {
+			return res, true
+		}
+	}
+	return ResourceRef{}, false
+}
+
+// recordTransfer stores the transfer's latest state on the job, replacing
+// any earlier report for the same transfer
+func (job *MigrationJob) recordTransfer(state TransferState) {
+	for i := range job.Transfers {
+		if job.Transfers[i].ID == state.ID {
+			job.Transfers[i] = state
+			return
+		}
+	}
+	job.Transfers = append(job.Transfers, state)
+}
+
+// handleTransferEvent applies a transfer status change to the unfinished
+// jobs moving that resource, so a failed or stalled stream shows up on the
+// job as it happens rather than when the strategy gets round to it
+func (e *Engine) handleTransferEvent(event peer.TransferEvent) {
+	resourceType := transferResourceType(event.Type)
+	if resourceType == "" {
+		return
+	}
+
+	var updated []*MigrationJob
+	var updates []MigrationUpdate
+
+	e.jobsMutex.Lock()
+	for _, job := range e.jobs {
+		if isFinished(job) {
+			continue
+		}
+		res, ok := job.findResource(resourceType, event.SourceID)
+		if !ok {
+			continue
+		}
+
+		job.recordTransfer(TransferState{
+			ID:           event.TransferID,
+			ResourceType: res.Type,
+			ResourceName: res.Name,
+			Status:       event.Status.String(),
+			BytesDone:    event.TransferredBytes,
+			BytesTotal:   event.TotalBytes,
+			Error:        event.Error,
+			UpdatedAt:    event.Time,
+		})
+
+		switch event.Status {
+		case peer.TransferFailed, peer.TransferStalled:
+			// The transfer keeps its checkpoint, so it can be resumed
+			migErr := MigrationError{
+				Timestamp:    event.Time,
+				Phase:        job.CurrentPhase,
+				ResourceType: res.Type,
+				ResourceName: res.Name,
+				Message:      fmt.Sprintf("transfer %s: %s", event.Status, event.Error),
+				Recoverable:  true,
+			}
+			job.Errors = append(job.Errors, migErr)
+			updates = append(updates, MigrationUpdate{Type: "error", JobID: job.ID, Error: &migErr})
+		default:
+			progress := job.Progress
+			updates = append(updates, MigrationUpdate{Type: "progress", JobID: job.ID, Progress: &progress})
+		}
+		updated = append(updated, job)
+
+		e.logger.Info("transfer status changed",
+			zap.String("job_id", job.ID),
+			zap.String("transfer_id", event.TransferID),
+			zap.String("resource", res.Name),
+			zap.String("status", event.Status.String()),
+		)
+	}
+	e.jobsMutex.Unlock()
+
+	for _, job := range updated {
+		e.persistJob(job)
+	}
+
+	// Called on the transfer's goroutine; drop updates nobody is reading
+	// rather than hold up the stream
+	for _, update := range updates {
+		select {
+		case e.progressChan <- update:
+		default:
+		}
+	}
+}
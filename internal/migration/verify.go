@@ -0,0 +1,147 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/peer"
+
+	"go.uber.org/zap"
+)
+
+// VerificationLevel controls how thoroughly transferred volumes are checked
+type VerificationLevel string
+
+const (
+	VerifyFull VerificationLevel = "full" // Compare every file range via Merkle tree
+	VerifyFast VerificationLevel = "fast" // Compare metadata plus a random sample of files
+	VerifyOff  VerificationLevel = "off"  // Trust per-chunk acks only
+)
+
+const (
+	// FastVerifySampleRate is the fraction of files spot-checked in fast mode
+	FastVerifySampleRate = 0.01
+
+	// FastVerifyMinSamples is the minimum number of files spot-checked in fast mode
+	FastVerifyMinSamples = 16
+)
+
+// ParseVerificationLevel validates a verification level, defaulting to full
+func ParseVerificationLevel(s string) (VerificationLevel, error) {
+	switch VerificationLevel(s) {
+	case "", VerifyFull:
+		return VerifyFull, nil
+	case VerifyFast, VerifyOff:
+		return VerificationLevel(s), nil
+	default:
+		return "", fmt.Errorf("unknown verification level %q (expected full, fast or off)", s)
+	}
+}
+
+// sampleLeaves picks the files checked in fast mode, by leaf index: a
+// fraction of them, but at least FastVerifyMinSamples
+func sampleLeaves(total int, rng *rand.Rand) []int {
+	n := int(float64(total) * FastVerifySampleRate)
+	if n < FastVerifyMinSamples {
+		n = FastVerifyMinSamples
+	}
+	if n >= total {
+		all := make([]int, total)
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+
+	picked := make(map[int]bool, n)
+	for len(picked) < n {
+		picked[rng.Intn(total)] = true
+	}
+
+	samples := make([]int, 0, len(picked))
+	for c := range picked {
+		samples = append(samples, c)
+	}
+	sort.Ints(samples)
+	return samples
+}
+
+// fastVerifyVolume compares the number and total size of the target's files
+// with the source's, and the checksums of a random sample of them
+func (vm *VolumeMigrator) fastVerifyVolume(ctx context.Context, client *peer.GRPCClient, volumeName string, source docker.FileIndex, checkpoint *VolumeCheckpoint) error {
+	samples := sampleLeaves(len(checkpoint.Files), rand.New(rand.NewSource(rand.Int63())))
+	paths := make([]string, len(samples))
+	for i, leaf := range samples {
+		paths[i] = checkpoint.Files[leaf]
+	}
+
+	vm.logger.Info("fast-verifying volume",
+		zap.String("volume", volumeName),
+		zap.Int("files", len(checkpoint.Files)),
+		zap.Int("sampled_files", len(samples)),
+	)
+
+	target, err := vm.fetchTargetSample(ctx, client, volumeName, paths)
+	if err != nil {
+		return fmt.Errorf("failed to get target sample: %w", err)
+	}
+
+	var sourceBytes int64
+	for _, meta := range source {
+		sourceBytes += meta.Size
+	}
+	mismatch := ""
+	switch {
+	case target.Files != len(source):
+		mismatch = fmt.Sprintf("source has %d files, target has %d", len(source), target.Files)
+	case target.Bytes != sourceBytes:
+		mismatch = fmt.Sprintf("source has %d bytes, target has %d", sourceBytes, target.Bytes)
+	default:
+		want := fileChecksums(paths, source)
+		got := fileChecksums(paths, target.Index)
+		for i := range samples {
+			if got[i] != want[i] {
+				mismatch = fmt.Sprintf("sampled file %s differs", paths[i])
+				break
+			}
+		}
+	}
+
+	// A mismatch means the sample cannot be trusted; fall back to a full check
+	if mismatch != "" {
+		vm.logger.Warn("fast verification found mismatches, escalating to full verification",
+			zap.String("volume", volumeName),
+			zap.String("mismatch", mismatch),
+		)
+		return vm.fullVerifyVolume(ctx, client, volumeName, source, checkpoint)
+	}
+
+	checkpoint.FinalChecksum = fmt.Sprintf("sampled:%d/%d", len(samples), len(checkpoint.Files))
+	return nil
+}
+
+// targetSample is the target's view of a received volume for fast verification
+type targetSample struct {
+	Files int
+	Bytes int64
+	// Index holds the sampled files, hashed on the target
+	Index docker.FileIndex
+}
+
+// fetchTargetSample requests the target's file count and size of its copy
+// of a volume, and the checksums of the given files, which the target reads
+// back and hashes
+func (vm *VolumeMigrator) fetchTargetSample(ctx context.Context, client *peer.GRPCClient, volumeName string, paths []string) (*targetSample, error) {
+	resp, err := client.SampleVolume(ctx, vm.targetName(volumeName), paths)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Exists {
+		return nil, fmt.Errorf("target has no volume %s", vm.targetName(volumeName))
+	}
+
+	return &targetSample{Files: resp.Files, Bytes: resp.Bytes, Index: resp.Sample}, nil
+}
@@ -5,10 +5,14 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/artemis/docker-migrate/internal/docker"
 	"github.com/artemis/docker-migrate/internal/peer"
+	pb "github.com/artemis/docker-migrate/proto"
 
 	"go.uber.org/zap"
 )
@@ -19,28 +23,144 @@ type VolumeMigrator struct {
 	docker   *docker.Client
 	transfer *peer.TransferManager
 	logger   *zap.Logger
+
+	// reattachShared recreates shared-storage volumes on the target instead of copying data
+	reattachShared bool
+
+	// groupSnapshots maps volumes in a consistency group to their point-in-time
+	// export, which cold migrations and the first warm pass send instead of
+	// the live volume
+	groupSnapshots map[string]string
+
+	// verification selects full, fast (sampled) or no post-transfer verification
+	verification VerificationLevel
+
+	// job supplies the naming policy applied on the target; may be nil
+	job *MigrationJob
+	// jobsMutex is the engine's lock on its jobs, held while job is updated
+	jobsMutex *sync.RWMutex
+
+	// peers connects to the target for warm syncs
+	peers *peer.PeerDiscovery
+
+	// syncIndexes keeps each volume's file index from its last warm sync so
+	// the delta pass only re-hashes files whose size or mtime changed
+	syncIndexes map[string]docker.FileIndex
 }
 
-const (
-	// ChunkSize for volume transfers - 4MB chunks for balanced memory/network efficiency
-	ChunkSize = 4 * 1024 * 1024
+// VolumeReattachSpec describes a shared-storage volume to recreate on the target
+type VolumeReattachSpec struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	DriverOpts map[string]string `json:"driver_opts,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
 
-	// MaxRetries for failed chunk transfers
+const (
+	// MaxRetries is how many times ranges that differ on the target are sent again
 	MaxRetries = 3
 
-	// ChecksumAlgorithm used for integrity verification
-	ChecksumAlgorithm = "SHA256"
+	// VolumeRangeSize is the size of the file ranges a cold transfer hashes,
+	// compares and sends. A range that differs on the target is sent again
+	// on its own rather than with the rest of its file.
+	VolumeRangeSize = 4 * 1024 * 1024
 )
 
-// VolumeCheckpoint represents resumable transfer state
+// VolumeLeaf is one leaf of a volume's Merkle tree: a directory, or one
+// range of a file
+type VolumeLeaf struct {
+	Path  string `json:"path"`
+	Range int    `json:"range,omitempty"`
+	Dir   bool   `json:"dir,omitempty"`
+}
+
+// VolumeCheckpoint records what a cold transfer sent of a volume and how
+// the target's copy compared. The volume's directories and file ranges, in
+// path order, are the leaves of its Merkle tree.
 type VolumeCheckpoint struct {
-	VolumeName       string            `json:"volume_name"`
-	ChunksCompleted  int               `json:"chunks_completed"`
-	TotalChunks      int               `json:"total_chunks"`
-	BytesTransferred int64             `json:"bytes_transferred"`
-	ChunkChecksums   map[int]string    `json:"chunk_checksums"`
-	LastUpdate       time.Time         `json:"last_update"`
-	FinalChecksum    string            `json:"final_checksum,omitempty"`
+	VolumeName       string       `json:"volume_name"`
+	Files            []string     `json:"files"`
+	Leaves           []VolumeLeaf `json:"leaves"`
+	LeafChecksums    []string     `json:"leaf_checksums"`
+	RangesSent       int          `json:"ranges_sent"`
+	BytesTransferred int64        `json:"bytes_transferred"`
+	LastUpdate       time.Time    `json:"last_update"`
+	FinalChecksum    string       `json:"final_checksum,omitempty"`
+	MerkleRoot       string       `json:"merkle_root,omitempty"`
+}
+
+// newVolumeCheckpoint lists a volume's directories and file ranges as
+// Merkle leaves. source must be indexed with VolumeRangeSize.
+func newVolumeCheckpoint(volumeName string, source docker.FileIndex) *VolumeCheckpoint {
+	checkpoint := &VolumeCheckpoint{
+		VolumeName: volumeName,
+		Files:      make([]string, 0, len(source)),
+		LastUpdate: time.Now(),
+	}
+	for name := range source {
+		checkpoint.Files = append(checkpoint.Files, name)
+	}
+	sort.Strings(checkpoint.Files)
+	for _, name := range checkpoint.Files {
+		meta := source[name]
+		if meta.Dir {
+			checkpoint.Leaves = append(checkpoint.Leaves, VolumeLeaf{Path: name, Dir: true})
+			continue
+		}
+		for i := range meta.Ranges {
+			checkpoint.Leaves = append(checkpoint.Leaves, VolumeLeaf{Path: name, Range: i})
+		}
+	}
+	checkpoint.LeafChecksums = leafChecksums(checkpoint.Leaves, source)
+	return checkpoint
+}
+
+// leafChecksums hashes each leaf's entry in index, in the order given. A
+// range's checksum covers its data, and the last range's the file's size
+// too, so a file that grew or shrank differs in its last range. A leaf the
+// index lacks gets a checksum no present one can have.
+func leafChecksums(leaves []VolumeLeaf, index docker.FileIndex) []string {
+	sums := make([]string, len(leaves))
+	for i, leaf := range leaves {
+		meta, ok := index[leaf.Path]
+		var entry string
+		switch {
+		case !ok || meta.Dir != leaf.Dir || (!leaf.Dir && leaf.Range >= len(meta.Ranges)):
+			entry = fmt.Sprintf("missing\x00%s\x00%d", leaf.Path, leaf.Range)
+		case leaf.Dir:
+			entry = "d\x00" + leaf.Path
+		default:
+			entry = fmt.Sprintf("r\x00%s\x00%d\x00%s", leaf.Path, leaf.Range, meta.Ranges[leaf.Range])
+			if leaf.Range == len(meta.Ranges)-1 {
+				entry += fmt.Sprintf("\x00%d", meta.Size)
+			}
+		}
+		sum := sha256.Sum256([]byte(entry))
+		sums[i] = fmt.Sprintf("%x", sum[:])
+	}
+	return sums
+}
+
+// fileChecksums hashes each path's entry in index, in the order given, for
+// comparing whole files. A path the index lacks gets a checksum no present
+// file can have.
+func fileChecksums(paths []string, index docker.FileIndex) []string {
+	sums := make([]string, len(paths))
+	for i, name := range paths {
+		meta, ok := index[name]
+		var entry string
+		switch {
+		case !ok:
+			entry = "missing\x00" + name
+		case meta.Dir:
+			entry = "d\x00" + name
+		default:
+			entry = fmt.Sprintf("f\x00%s\x00%d\x00%s", name, meta.Size, meta.Hash)
+		}
+		sum := sha256.Sum256([]byte(entry))
+		sums[i] = fmt.Sprintf("%x", sum[:])
+	}
+	return sums
 }
 
 // MigrateVolume transfers volume data with comprehensive integrity checks
@@ -51,6 +171,11 @@ func (vm *VolumeMigrator) MigrateVolume(ctx context.Context, volumeName, peerID
 		zap.String("strategy", string(strategy)),
 	)
 
+	// Volumes on NFS/cloud drivers have no local data to copy
+	if reattached, err := vm.reattachIfShared(ctx, volumeName, peerID); err != nil || reattached {
+		return err
+	}
+
 	switch strategy {
 	case StrategyCold:
 		return vm.coldMigrate(ctx, volumeName, peerID, progressCh)
@@ -61,52 +186,127 @@ func (vm *VolumeMigrator) MigrateVolume(ctx context.Context, volumeName, peerID
 	}
 }
 
-// coldMigrate implements simple tar stream with chunking and checksums
-// This is the safest approach with guaranteed consistency
+// reattachIfShared re-attaches a shared-storage volume on the target and reports whether it did so
+func (vm *VolumeMigrator) reattachIfShared(ctx context.Context, volumeName, peerID string) (bool, error) {
+	if vm.docker == nil {
+		return false, nil
+	}
+
+	vol, err := vm.docker.InspectVolume(ctx, volumeName)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect volume: %w", err)
+	}
+	if !docker.IsSharedStorageVolume(vol) {
+		return false, nil
+	}
+
+	// Without re-attach the data is copied through a helper container
+	if !vm.reattachShared {
+		return false, nil
+	}
+
+	labels := vol.Labels
+	if vm.job != nil {
+		labels = vm.job.provenance(vol.Name).WithLabels(vol.Labels)
+	}
+	err = vm.reattachOnTarget(ctx, peerID, &VolumeReattachSpec{
+		Name:       vm.targetName(vol.Name),
+		Driver:     vol.Driver,
+		DriverOpts: vol.Options,
+		Labels:     labels,
+	})
+	return true, err
+}
+
+// reattachOnTarget recreates a shared-storage volume on the target with the same driver options
+func (vm *VolumeMigrator) reattachOnTarget(ctx context.Context, peerID string, spec *VolumeReattachSpec) error {
+	vm.logger.Info("re-attaching shared volume on target",
+		zap.String("volume", spec.Name),
+		zap.String("peer_id", peerID),
+		zap.String("driver", spec.Driver),
+	)
+
+	client, err := vm.connect(ctx, peerID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// The target points the volume at the same backing storage; no data is copied
+	return client.CreateVolume(ctx, &pb.VolumeSpec{
+		Name:       spec.Name,
+		Driver:     spec.Driver,
+		DriverOpts: spec.DriverOpts,
+		Labels:     spec.Labels,
+	})
+}
+
+// targetName returns the name a volume is created with on the target
+func (vm *VolumeMigrator) targetName(volumeName string) string {
+	if vm.job == nil {
+		return volumeName
+	}
+	return vm.job.targetName("volume", volumeName)
+}
+
+// coldMigrate copies a volume whose containers are stopped. Both sides
+// index the volume and hash its files in ranges; the ranges the target
+// lacks or holds differently are sent, and files it has beyond the
+// source's are removed, so a retry only sends what is still missing.
 func (vm *VolumeMigrator) coldMigrate(ctx context.Context, volumeName, peerID string, progressCh chan<- MigrationProgress) error {
 	vm.logger.Info("cold volume migration", zap.String("volume", volumeName))
 
-	// Step 1: Create checkpoint for resumability
-	checkpoint := &VolumeCheckpoint{
-		VolumeName:      volumeName,
-		ChunkChecksums:  make(map[int]string),
-		LastUpdate:      time.Now(),
+	client, err := vm.connect(ctx, peerID)
+	if err != nil {
+		return err
 	}
+	defer client.Close()
 
-	// Step 2: Export volume to tar stream (would use Docker SDK)
-	// This would mount volume and create tar archive
-	volumeSize := int64(100 * 1024 * 1024) // Mock: 100MB volume
-	totalChunks := int(volumeSize / ChunkSize)
-	if volumeSize%ChunkSize != 0 {
-		totalChunks++
+	source, err := vm.indexSource(ctx, volumeName, nil, true, VolumeRangeSize)
+	if err != nil {
+		return err
 	}
-	checkpoint.TotalChunks = totalChunks
+	checkpoint := newVolumeCheckpoint(volumeName, source)
+
+	targetName := vm.targetName(volumeName)
+	target, exists, err := client.GetVolumeRangeIndex(ctx, targetName, VolumeRangeSize)
+	if err != nil {
+		return err
+	}
+	ranges, deleted := diffLeaves(checkpoint, source, target)
 
 	vm.logger.Info("volume export prepared",
 		zap.String("volume", volumeName),
-		zap.Int64("size_bytes", volumeSize),
-		zap.Int("total_chunks", totalChunks),
+		zap.String("target_name", targetName),
+		zap.Int("files", len(source)),
+		zap.Int("leaves", len(checkpoint.Leaves)),
+		zap.Int("to_send", len(ranges)),
+		zap.Int("to_remove", len(deleted)),
 	)
 
-	// Step 3: Stream chunks with per-chunk checksums
-	for chunkNum := 0; chunkNum < totalChunks; chunkNum++ {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("migration cancelled: %w", ctx.Err())
-		default:
-			if err := vm.transferChunk(ctx, volumeName, peerID, chunkNum, checkpoint, progressCh); err != nil {
-				return fmt.Errorf("failed to transfer chunk %d: %w", chunkNum, err)
-			}
+	if !exists {
+		if err := vm.createOnTarget(ctx, client, volumeName); err != nil {
+			return err
 		}
 	}
 
-	// Step 4: Final integrity verification
-	if err := vm.verifyVolume(ctx, volumeName, peerID, checkpoint); err != nil {
+	total := source.PatchSize(ranges, VolumeRangeSize)
+	vm.reportVolumeProgress(progressCh, volumeName, 0, total)
+	if len(ranges) > 0 || len(deleted) > 0 {
+		if err := vm.sendRanges(ctx, client, volumeName, source, ranges, deleted, checkpoint); err != nil {
+			return err
+		}
+	}
+	vm.reportVolumeProgress(progressCh, volumeName, total, total)
+
+	if err := vm.verifyVolume(ctx, client, volumeName, source, checkpoint); err != nil {
 		return fmt.Errorf("volume integrity verification failed: %w", err)
 	}
+	vm.recordVolumeIntegrity(volumeName, checkpoint, vm.verification != VerifyOff)
 
 	vm.logger.Info("cold volume migration completed",
 		zap.String("volume", volumeName),
+		zap.Int("ranges_sent", checkpoint.RangesSent),
 		zap.Int64("bytes_transferred", checkpoint.BytesTransferred),
 		zap.String("checksum", checkpoint.FinalChecksum),
 	)
@@ -114,94 +314,263 @@ func (vm *VolumeMigrator) coldMigrate(ctx context.Context, volumeName, peerID st
 	return nil
 }
 
-// transferChunk transfers a single chunk with retry logic and checksum
-func (vm *VolumeMigrator) transferChunk(ctx context.Context, volumeName, peerID string, chunkNum int, checkpoint *VolumeCheckpoint, progressCh chan<- MigrationProgress) error {
-	var lastErr error
-
-	for attempt := 0; attempt < MaxRetries; attempt++ {
-		if attempt > 0 {
-			vm.logger.Warn("retrying chunk transfer",
-				zap.String("volume", volumeName),
-				zap.Int("chunk", chunkNum),
-				zap.Int("attempt", attempt+1),
-			)
-
-			// Exponential backoff
-			backoff := time.Duration(attempt) * time.Second
-			select {
-			case <-time.After(backoff):
-			case <-ctx.Done():
-				return ctx.Err()
-			}
+// createOnTarget creates an empty local volume on the target, labelled with
+// the migration's provenance and the source volume's own labels, before its
+// files are sent
+func (vm *VolumeMigrator) createOnTarget(ctx context.Context, client *peer.GRPCClient, volumeName string) error {
+	vol, err := vm.docker.InspectVolume(ctx, volumeName)
+	if err != nil {
+		return fmt.Errorf("failed to inspect volume: %w", err)
+	}
+	labels := vol.Labels
+	if vm.job != nil {
+		labels = vm.job.provenance(volumeName).WithLabels(vol.Labels)
+	}
+	return client.CreateVolume(ctx, &pb.VolumeSpec{
+		Name:   vm.targetName(volumeName),
+		Driver: docker.LocalVolumeDriver,
+		Labels: labels,
+	})
+}
+
+// connect opens a transfer client to the target
+func (vm *VolumeMigrator) connect(ctx context.Context, peerID string) (*peer.GRPCClient, error) {
+	if vm.docker == nil || vm.peers == nil {
+		return nil, fmt.Errorf("volume transfer needs a docker client and peer discovery")
+	}
+	client, err := vm.peers.Connect(ctx, peerID, vm.transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer: %w", err)
+	}
+	return client, nil
+}
+
+// indexSource indexes the volume's consistency group snapshot if it has one
+// and fromSnapshot is set, or else the live volume, hashing files in ranges
+// of rangeSize if it is not zero
+func (vm *VolumeMigrator) indexSource(ctx context.Context, volumeName string, previous docker.FileIndex, fromSnapshot bool, rangeSize int64) (docker.FileIndex, error) {
+	path, ok := vm.groupSnapshots[volumeName]
+	if !ok || !fromSnapshot {
+		return vm.docker.IndexVolume(ctx, volumeName, previous, rangeSize)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open group snapshot: %w", err)
+	}
+	defer file.Close()
+
+	index, err := docker.IndexVolumeTar(file, previous, rangeSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index group snapshot of %s: %w", volumeName, err)
+	}
+	return index, nil
+}
+
+// exportSource exports the given files from the volume's consistency group
+// snapshot if it has one and fromSnapshot is set, or else from the live
+// volume
+func (vm *VolumeMigrator) exportSource(ctx context.Context, volumeName string, paths []string, fromSnapshot bool) (io.ReadCloser, error) {
+	path, ok := vm.groupSnapshots[volumeName]
+	if !ok || !fromSnapshot {
+		return vm.docker.ExportVolumeFiles(ctx, volumeName, paths)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open group snapshot: %w", err)
+	}
+	return docker.SelectTarFiles(file, paths), nil
+}
+
+// exportRanges exports a patch of the given ranges of the volume, from its
+// consistency group snapshot if it has one
+func (vm *VolumeMigrator) exportRanges(ctx context.Context, volumeName string, ranges docker.VolumeRanges) (io.ReadCloser, error) {
+	path, ok := vm.groupSnapshots[volumeName]
+	if !ok {
+		return vm.docker.ExportVolumeRanges(ctx, volumeName, ranges, VolumeRangeSize)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open group snapshot: %w", err)
+	}
+	return docker.SelectTarRanges(file, ranges, VolumeRangeSize), nil
+}
+
+// diffLeaves compares the target's range index with the checkpoint's
+// leaves. It returns the directories and file ranges to send, and the paths
+// to remove from the target first: those only it has, and those that are a
+// file on one side and a directory on the other.
+func diffLeaves(checkpoint *VolumeCheckpoint, source, target docker.FileIndex) (docker.VolumeRanges, []string) {
+	got := leafChecksums(checkpoint.Leaves, target)
+	var bad []int
+	for i, sum := range got {
+		if sum != checkpoint.LeafChecksums[i] {
+			bad = append(bad, i)
 		}
+	}
+	_, deleted := docker.DiffIndex(source, target)
+	return leafRanges(checkpoint, bad), deleted
+}
 
-		// Would read chunk data from volume export
-		chunkData := make([]byte, ChunkSize) // Mock data
-		chunkSize := int64(len(chunkData))
-
-		// Calculate chunk checksum
-		chunkChecksum := vm.calculateChunkChecksum(chunkData)
-
-		// Send chunk to peer via gRPC stream
-		// In production, this would:
-		// 1. Stream chunk data
-		// 2. Peer calculates checksum on receive
-		// 3. Peer responds with calculated checksum
-		// 4. Compare checksums to detect corruption
-
-		// Store checksum in checkpoint
-		checkpoint.ChunkChecksums[chunkNum] = chunkChecksum
-		checkpoint.ChunksCompleted = chunkNum + 1
-		checkpoint.BytesTransferred += chunkSize
-		checkpoint.LastUpdate = time.Now()
-
-		// Update progress
-		if progressCh != nil {
-			progress := MigrationProgress{
-				CurrentNumber: chunkNum + 1,
-				TotalItems:    checkpoint.TotalChunks,
-				CurrentItem:   fmt.Sprintf("Volume chunk %d/%d", chunkNum+1, checkpoint.TotalChunks),
-				BytesDone:     checkpoint.BytesTransferred,
-				BytesTotal:    int64(checkpoint.TotalChunks * ChunkSize),
-			}
-			progressCh <- progress
+// leafRanges groups the given leaves by path
+func leafRanges(checkpoint *VolumeCheckpoint, leaves []int) docker.VolumeRanges {
+	ranges := make(docker.VolumeRanges)
+	for _, i := range leaves {
+		leaf := checkpoint.Leaves[i]
+		if leaf.Dir {
+			ranges[leaf.Path] = nil
+			continue
 		}
+		ranges[leaf.Path] = append(ranges[leaf.Path], leaf.Range)
+	}
+	return ranges
+}
 
-		// Success - exit retry loop
-		return nil
+// sendRanges sends a patch of the given ranges of a volume for the target
+// to write into its copy, after removing the deleted paths there. The patch
+// goes through the chunked volume stream, so it is checkpointed, resumed
+// after a reconnect and has corrupted chunks sent again.
+func (vm *VolumeMigrator) sendRanges(ctx context.Context, client *peer.GRPCClient, volumeName string, source docker.FileIndex, ranges docker.VolumeRanges, deleted []string, checkpoint *VolumeCheckpoint) error {
+	reader, err := vm.exportRanges(ctx, volumeName, ranges)
+	if err != nil {
+		return fmt.Errorf("failed to export volume ranges: %w", err)
+	}
+	defer reader.Close()
+
+	size := source.PatchSize(ranges, VolumeRangeSize)
+	if err := client.SendVolumePatch(ctx, vm.targetName(volumeName), reader, size, deleted); err != nil {
+		return fmt.Errorf("failed to send volume ranges: %w", err)
 	}
 
-	return fmt.Errorf("chunk transfer failed after %d attempts: %w", MaxRetries, lastErr)
+	for _, indices := range ranges {
+		checkpoint.RangesSent += len(indices)
+	}
+	checkpoint.BytesTransferred += size
+	checkpoint.LastUpdate = time.Now()
+	return nil
 }
 
-// calculateChunkChecksum computes SHA-256 for a chunk
-func (vm *VolumeMigrator) calculateChunkChecksum(data []byte) string {
-	hash := sha256.Sum256(data)
-	return fmt.Sprintf("%x", hash[:])
+// reportVolumeProgress reports bytes sent of a volume
+func (vm *VolumeMigrator) reportVolumeProgress(progressCh chan<- MigrationProgress, volumeName string, done, total int64) {
+	if progressCh == nil {
+		return
+	}
+	progressCh <- MigrationProgress{
+		CurrentItem: fmt.Sprintf("Volume %s", volumeName),
+		BytesDone:   done,
+		BytesTotal:  total,
+	}
 }
 
-// verifyVolume performs final integrity check after transfer
-func (vm *VolumeMigrator) verifyVolume(ctx context.Context, volumeName, peerID string, checkpoint *VolumeCheckpoint) error {
-	vm.logger.Info("verifying volume integrity",
+// verifyVolume performs final integrity check after transfer at the configured level
+func (vm *VolumeMigrator) verifyVolume(ctx context.Context, client *peer.GRPCClient, volumeName string, source docker.FileIndex, checkpoint *VolumeCheckpoint) error {
+	switch vm.verification {
+	case VerifyOff:
+		vm.logger.Warn("volume verification disabled, relying on per-chunk acks",
+			zap.String("volume", volumeName),
+		)
+		return nil
+	case VerifyFast:
+		if err := vm.fastVerifyVolume(ctx, client, volumeName, source, checkpoint); err != nil {
+			return err
+		}
+	default:
+		if err := vm.fullVerifyVolume(ctx, client, volumeName, source, checkpoint); err != nil {
+			return err
+		}
+	}
+
+	vm.logger.Info("volume integrity verified",
 		zap.String("volume", volumeName),
-		zap.Int("chunks_verified", len(checkpoint.ChunkChecksums)),
+		zap.String("level", string(vm.verification)),
+		zap.String("checksum", checkpoint.FinalChecksum),
 	)
 
-	// Would:
-	// 1. Request target to compute final checksum of assembled volume
-	// 2. Compare with expected checksum
-	// 3. Fail loudly if mismatch detected
-
-	checkpoint.FinalChecksum = "sha256:final_checksum_placeholder"
+	return nil
+}
 
-	vm.logger.Info("volume integrity verified",
+// fullVerifyVolume compares the Merkle tree over the source's file ranges
+// with one over the target's, hashed on the target. Only the ranges that
+// differ are sent again rather than their files or the whole volume.
+func (vm *VolumeMigrator) fullVerifyVolume(ctx context.Context, client *peer.GRPCClient, volumeName string, source docker.FileIndex, checkpoint *VolumeCheckpoint) error {
+	vm.logger.Info("verifying volume integrity",
 		zap.String("volume", volumeName),
-		zap.String("checksum", checkpoint.FinalChecksum),
+		zap.Int("files", len(checkpoint.Files)),
+		zap.Int("leaves", len(checkpoint.Leaves)),
 	)
 
+	var sourceTree *MerkleTree
+	if len(checkpoint.Leaves) > 0 {
+		tree, err := NewMerkleTreeFromCheckpoint(checkpoint)
+		if err != nil {
+			return fmt.Errorf("failed to build merkle tree: %w", err)
+		}
+		sourceTree = tree
+		checkpoint.MerkleRoot = tree.Root()
+	}
+
+	for attempt := 0; ; attempt++ {
+		target, err := vm.fetchTargetIndex(ctx, client, volumeName)
+		if err != nil {
+			return fmt.Errorf("failed to get target file index: %w", err)
+		}
+
+		var bad []int
+		if sourceTree != nil {
+			targetTree, err := NewMerkleTree(leafChecksums(checkpoint.Leaves, target))
+			if err != nil {
+				return err
+			}
+			if bad, err = sourceTree.Diff(targetTree); err != nil {
+				return err
+			}
+		}
+		// Files only the target has are not leaves of the source's tree
+		_, extra := docker.DiffIndex(source, target)
+		if len(bad) == 0 && len(extra) == 0 {
+			break
+		}
+
+		if attempt == MaxRetries {
+			return fmt.Errorf("volume still differs after %d repair attempts (%d ranges differ, %d extra)", MaxRetries, len(bad), len(extra))
+		}
+
+		resend := leafRanges(checkpoint, bad)
+		vm.logger.Warn("volume verification mismatch, re-sending affected ranges",
+			zap.String("volume", volumeName),
+			zap.Int("bad_ranges", len(bad)),
+			zap.Int("bad_files", len(resend)),
+			zap.Int("extra_files", len(extra)),
+			zap.Int("attempt", attempt+1),
+		)
+		if err := vm.sendRanges(ctx, client, volumeName, source, resend, extra, checkpoint); err != nil {
+			return fmt.Errorf("failed to re-send ranges: %w", err)
+		}
+	}
+
+	if sourceTree == nil {
+		checkpoint.FinalChecksum = "merkle:empty"
+		return nil
+	}
+	checkpoint.FinalChecksum = "merkle:" + checkpoint.MerkleRoot
 	return nil
 }
 
+// fetchTargetIndex fetches the target's file index of its copy of a volume,
+// which the target hashes itself, file by file and range by range
+func (vm *VolumeMigrator) fetchTargetIndex(ctx context.Context, client *peer.GRPCClient, volumeName string) (docker.FileIndex, error) {
+	target, exists, err := client.GetVolumeRangeIndex(ctx, vm.targetName(volumeName), VolumeRangeSize)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("target has no volume %s", vm.targetName(volumeName))
+	}
+	return target, nil
+}
+
 // warmMigrate implements rsync-style sync with delta transfers
 // This minimizes downtime by syncing while container runs
 func (vm *VolumeMigrator) warmMigrate(ctx context.Context, volumeName, peerID string, progressCh chan<- MigrationProgress) error {
@@ -223,7 +592,9 @@ func (vm *VolumeMigrator) warmMigrate(ctx context.Context, volumeName, peerID st
 	return nil
 }
 
-// warmSync performs rsync-style synchronization
+// warmSync performs rsync-style synchronization. Both sides index the
+// volume by size, mtime and hash; only files missing or different on the
+// target are sent, and files gone from the source are removed there.
 func (vm *VolumeMigrator) warmSync(ctx context.Context, volumeName, peerID string, deltaOnly bool) error {
 	syncType := "initial"
 	if deltaOnly {
@@ -235,38 +606,76 @@ func (vm *VolumeMigrator) warmSync(ctx context.Context, volumeName, peerID strin
 		zap.String("sync_type", syncType),
 	)
 
-	// Would implement rsync algorithm:
-	// 1. Build file list with metadata (size, mtime, checksum)
-	// 2. Send metadata to target
-	// 3. Target responds with files it needs
-	// 4. Transfer only missing/changed files
-	// 5. For delta sync, use inotify/fsnotify to track changes
+	// The first pass sends the group's point-in-time export; the delta pass
+	// then catches up with the live volume
+	if path, ok := vm.groupSnapshots[volumeName]; ok && !deltaOnly {
+		vm.logger.Info("seeding warm sync from consistency group snapshot",
+			zap.String("volume", volumeName),
+			zap.String("snapshot", path),
+		)
+	}
+
+	client, err := vm.connect(ctx, peerID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
 
+	if vm.syncIndexes == nil {
+		vm.syncIndexes = make(map[string]docker.FileIndex)
+	}
+	source, err := vm.indexSource(ctx, volumeName, vm.syncIndexes[volumeName], !deltaOnly, 0)
+	if err != nil {
+		return err
+	}
+	vm.syncIndexes[volumeName] = source
+
+	targetName := vm.targetName(volumeName)
+	target, exists, err := client.GetVolumeIndex(ctx, targetName)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := vm.createOnTarget(ctx, client, volumeName); err != nil {
+			return err
+		}
+	}
+
+	changed, deleted := docker.DiffIndex(source, target)
+	vm.logger.Info("warm sync plan",
+		zap.String("volume", volumeName),
+		zap.String("sync_type", syncType),
+		zap.Int("files", len(source)),
+		zap.Int("changed", len(changed)),
+		zap.Int("deleted", len(deleted)),
+	)
+	if len(changed) > 0 || len(deleted) > 0 {
+		reader, err := vm.exportSource(ctx, volumeName, changed, !deltaOnly)
+		if err != nil {
+			return fmt.Errorf("failed to export changed files: %w", err)
+		}
+		defer reader.Close()
+
+		if err := client.SendVolumeDelta(ctx, targetName, reader, source.TarSize(changed), deleted); err != nil {
+			return fmt.Errorf("failed to send changed files: %w", err)
+		}
+	}
+
+	// The final pass leaves the target's copy as it stays
+	if deltaOnly {
+		return vm.recordWarmIntegrity(ctx, client, volumeName, source)
+	}
 	return nil
 }
 
-// ResumeTransfer resumes an interrupted volume transfer from checkpoint
+// ResumeTransfer resumes an interrupted volume transfer. The target's file
+// index shows what it already received, so only the rest is sent.
 func (vm *VolumeMigrator) ResumeTransfer(ctx context.Context, checkpoint *VolumeCheckpoint, peerID string, progressCh chan<- MigrationProgress) error {
 	vm.logger.Info("resuming volume transfer",
 		zap.String("volume", checkpoint.VolumeName),
-		zap.Int("chunks_completed", checkpoint.ChunksCompleted),
-		zap.Int("chunks_remaining", checkpoint.TotalChunks-checkpoint.ChunksCompleted),
+		zap.Int("ranges_sent", checkpoint.RangesSent),
 	)
-
-	// Continue from last completed chunk
-	for chunkNum := checkpoint.ChunksCompleted; chunkNum < checkpoint.TotalChunks; chunkNum++ {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("migration cancelled: %w", ctx.Err())
-		default:
-			if err := vm.transferChunk(ctx, checkpoint.VolumeName, peerID, chunkNum, checkpoint, progressCh); err != nil {
-				return fmt.Errorf("failed to transfer chunk %d: %w", chunkNum, err)
-			}
-		}
-	}
-
-	// Final verification
-	return vm.verifyVolume(ctx, checkpoint.VolumeName, peerID, checkpoint)
+	return vm.coldMigrate(ctx, checkpoint.VolumeName, peerID, progressCh)
 }
 
 // SaveCheckpoint persists transfer state for resumability
@@ -275,7 +684,7 @@ func (vm *VolumeMigrator) SaveCheckpoint(checkpoint *VolumeCheckpoint) error {
 	// Location: /var/lib/docker-migrate/checkpoints/{volume_name}.json
 	vm.logger.Info("saving volume checkpoint",
 		zap.String("volume", checkpoint.VolumeName),
-		zap.Int("chunks_completed", checkpoint.ChunksCompleted),
+		zap.Int("ranges_sent", checkpoint.RangesSent),
 	)
 	return nil
 }
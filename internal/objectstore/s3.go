@@ -0,0 +1,471 @@
+// Package objectstore is a minimal client for S3-compatible object storage,
+// covering the calls migration bundles need: streaming uploads, downloads
+// and listing.
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+)
+
+const (
+	// partSize is the smallest multipart upload part, and the range each
+	// download request asks for. Uploads of larger objects use larger parts.
+	partSize = 16 << 20
+
+	// maxParts is the most parts S3 accepts in one multipart upload
+	maxParts = 10000
+
+	// maxObjectSize is the largest object S3 stores
+	maxObjectSize = 5 << 40
+
+	// requestTimeout bounds each request, including its body. Large objects
+	// go up and come down one part per request, so this never limits their
+	// total size.
+	requestTimeout = 5 * time.Minute
+
+	defaultRegion = "us-east-1"
+
+	// emptyPayloadHash is the SHA-256 of an empty body
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// Client talks to one bucket, addressing it path-style as MinIO and other
+// S3-compatible services expect
+type Client struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	http      *http.Client
+}
+
+// New creates a client for the bucket in cfg
+func New(cfg *config.ObjectStoreConfig) (*Client, error) {
+	if cfg == nil || cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("object store needs an endpoint and a bucket")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid object store endpoint %q", cfg.Endpoint)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	return &Client{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		http:      &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+// PutObject uploads r as key and returns the bytes written. size is the
+// expected size of r, or -1 if it is unknown, and sets the part size so the
+// upload fits in S3's part limit. Bodies larger than one part go up as a
+// multipart upload, so r is never held in memory whole.
+func (c *Client) PutObject(ctx context.Context, key string, r io.Reader, size int64) (int64, error) {
+	if size > maxObjectSize {
+		return 0, fmt.Errorf("failed to upload %s: %d bytes exceeds the %d an object can hold", key, size, int64(maxObjectSize))
+	}
+	buf := make([]byte, partSizeFor(size))
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return 0, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if n < len(buf) {
+		resp, err := c.do(ctx, http.MethodPut, key, nil, buf[:n])
+		if err != nil {
+			return 0, fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		resp.Body.Close()
+		return int64(n), nil
+	}
+
+	uploadID, err := c.createMultipartUpload(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start upload of %s: %w", key, err)
+	}
+
+	total, err := c.uploadParts(ctx, key, uploadID, buf, n, r)
+	if err != nil {
+		// Abandoned parts are billed until aborted
+		if resp, abortErr := c.do(context.Background(), http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil); abortErr == nil {
+			resp.Body.Close()
+		}
+		return 0, fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return total, nil
+}
+
+// partSizeFor returns the part size for an upload of size bytes. Sizes are
+// estimates, such as a volume's disk usage before tar adds its headers, so
+// the expected size fills only half the parts allowed.
+func partSizeFor(size int64) int {
+	if size <= 0 {
+		return partSize
+	}
+	const step = 1 << 20
+	need := (size/(maxParts/2)/step + 1) * step
+	if need < partSize {
+		return partSize
+	}
+	return int(need)
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// uploadParts sends the first n bytes of buf and then the rest of r as
+// parts of uploadID, and completes the upload
+func (c *Client) uploadParts(ctx context.Context, key, uploadID string, buf []byte, n int, r io.Reader) (int64, error) {
+	var parts []completedPart
+	var total int64
+
+	for part := 1; n > 0; part++ {
+		if part > maxParts {
+			return 0, fmt.Errorf("more than %d parts of %d bytes; the object is larger than its expected size", maxParts, len(buf))
+		}
+		query := url.Values{
+			"partNumber": {strconv.Itoa(part)},
+			"uploadId":   {uploadID},
+		}
+		resp, err := c.do(ctx, http.MethodPut, key, query, buf[:n])
+		if err != nil {
+			return 0, fmt.Errorf("part %d: %w", part, err)
+		}
+		resp.Body.Close()
+		parts = append(parts, completedPart{PartNumber: part, ETag: resp.Header.Get("ETag")})
+		total += int64(n)
+
+		if n, err = io.ReadFull(r, buf); err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return 0, err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Completion can fail after the 200 status has been sent
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if err := parseError(data); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (c *Client) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse upload response: %w", err)
+	}
+	if result.UploadID == "" {
+		return "", fmt.Errorf("no upload ID in response")
+	}
+	return result.UploadID, nil
+}
+
+// GetObject downloads key in a single request, for small objects. The
+// caller must close the returned reader.
+func (c *Client) GetObject(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+// DownloadObject copies key to w one part-sized range at a time and returns
+// the bytes written, so an object of any size downloads within the per-request
+// timeout
+func (c *Client) DownloadObject(ctx context.Context, key string, w io.Writer) (int64, error) {
+	var written int64
+	for {
+		header := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", written, written+partSize-1)}}
+		resp, err := c.doHeader(ctx, http.MethodGet, key, nil, header, nil)
+		// No range of an empty object exists, not even its first byte
+		var statusErr *StatusError
+		if written == 0 && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return 0, nil
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to download %s: %w", key, err)
+		}
+		n, err := io.Copy(w, resp.Body)
+		resp.Body.Close()
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("failed to download %s: %w", key, err)
+		}
+
+		// A service that ignores ranges sends the whole object at once
+		if resp.StatusCode != http.StatusPartialContent {
+			return written, nil
+		}
+		total, err := contentRangeTotal(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return written, fmt.Errorf("failed to download %s: %w", key, err)
+		}
+		if written >= total {
+			return written, nil
+		}
+		if n == 0 {
+			return written, fmt.Errorf("failed to download %s: empty range at %d of %d bytes", key, written, total)
+		}
+	}
+}
+
+// contentRangeTotal returns the object size from a Content-Range header such
+// as "bytes 0-99/1000"
+func contentRangeTotal(header string) (int64, error) {
+	i := strings.LastIndex(header, "/")
+	if i < 0 {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	total, err := strconv.ParseInt(header[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Content-Range %q", header)
+	}
+	return total, nil
+}
+
+// ListPrefixes returns the names directly under prefix that contain further
+// objects, like the directories of a listing
+func (c *Client) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		query := url.Values{
+			"list-type": {"2"},
+			"prefix":    {prefix},
+			"delimiter": {"/"},
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := c.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		var result struct {
+			CommonPrefixes []struct {
+				Prefix string `xml:"Prefix"`
+			} `xml:"CommonPrefixes"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse listing: %w", err)
+		}
+
+		for _, p := range result.CommonPrefixes {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(p.Prefix, prefix), "/"))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return names, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// do sends a signed request for key and returns the response if its status
+// is 2xx
+func (c *Client) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	return c.doHeader(ctx, method, key, query, nil, body)
+}
+
+// doHeader is do with extra, unsigned request headers
+func (c *Client) doHeader(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	u := *c.endpoint
+	u.Path = u.Path + "/" + c.bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	// Signed as sent, so the encoding is not left to net/url
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	c.sign(req, body, time.Now().UTC())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		err := parseError(data)
+		if err == nil {
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return nil, &StatusError{StatusCode: resp.StatusCode, Err: err}
+	}
+	return resp, nil
+}
+
+// StatusError is a request the service answered with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string { return e.Err.Error() }
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// sign adds AWS Signature Version 4 headers to req
+func (c *Client) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	key = hmacSHA256(key, c.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes query sorted by key, as both the URL and the
+// signature use it
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, escape(k, true)+"="+escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath URI-encodes each segment of path
+func escapePath(path string) string {
+	return escape(path, false)
+}
+
+// escape percent-encodes everything but RFC 3986 unreserved characters, and
+// slashes unless encodeSlash is set
+func escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '_', ch == '.', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// parseError returns the error in an S3 error document, or nil if data is
+// not one
+func parseError(data []byte) error {
+	var doc struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil || doc.Code == "" {
+		return nil
+	}
+	return fmt.Errorf("%s: %s", doc.Code, doc.Message)
+}
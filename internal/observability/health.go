@@ -24,6 +24,8 @@ type ComponentHealth struct {
 	Status    HealthStatus `json:"status"`
 	Message   string       `json:"message,omitempty"`
 	LastCheck time.Time    `json:"last_check"`
+	// Readiness marks a check that only gates /readyz; others gate /healthz too
+	Readiness bool `json:"readiness,omitempty"`
 }
 
 // HealthChecker manages health checks for all components
@@ -31,6 +33,8 @@ type HealthChecker struct {
 	mu         sync.RWMutex
 	components map[string]*ComponentHealth
 	checks     map[string]HealthCheckFunc
+	readiness  map[string]bool
+	startTime  time.Time
 }
 
 // HealthCheckFunc is a function that checks the health of a component
@@ -41,10 +45,14 @@ func NewHealthChecker() *HealthChecker {
 	return &HealthChecker{
 		components: make(map[string]*ComponentHealth),
 		checks:     make(map[string]HealthCheckFunc),
+		readiness:  make(map[string]bool),
+		startTime:  time.Now(),
 	}
 }
 
-// RegisterCheck registers a health check function for a component
+// RegisterCheck registers a health check function for a component. A failing
+// check fails both liveness and readiness, so keep it to faults a restart
+// would fix.
 func (hc *HealthChecker) RegisterCheck(name string, check HealthCheckFunc) {
 	hc.mu.Lock()
 	defer hc.mu.Unlock()
@@ -55,18 +63,42 @@ func (hc *HealthChecker) RegisterCheck(name string, check HealthCheckFunc) {
 	}
 }
 
-// RunChecks executes all registered health checks
-func (hc *HealthChecker) RunChecks(ctx context.Context) {
+// RegisterReadinessCheck registers a check for a dependency the node cannot
+// serve without, such as Docker or the master. A failing check takes the node
+// out of rotation on /readyz but does not fail /healthz. It counts as failing
+// until it has first run.
+func (hc *HealthChecker) RegisterReadinessCheck(name string, check HealthCheckFunc) {
 	hc.mu.Lock()
 	defer hc.mu.Unlock()
+	hc.checks[name] = check
+	hc.readiness[name] = true
+	hc.components[name] = &ComponentHealth{
+		Status:    HealthStatusUnhealthy,
+		Message:   "not checked yet",
+		LastCheck: time.Now(),
+		Readiness: true,
+	}
+}
 
+// RunChecks executes all registered health checks. Checks run without the
+// lock held, so a slow one does not hold up the endpoints.
+func (hc *HealthChecker) RunChecks(ctx context.Context) {
+	hc.mu.RLock()
+	checks := make(map[string]HealthCheckFunc, len(hc.checks))
 	for name, check := range hc.checks {
+		checks[name] = check
+	}
+	hc.mu.RUnlock()
+
+	for name, check := range checks {
 		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		err := check(checkCtx)
 		cancel()
 
+		hc.mu.Lock()
 		health := &ComponentHealth{
 			LastCheck: time.Now(),
+			Readiness: hc.readiness[name],
 		}
 
 		if err != nil {
@@ -77,6 +109,7 @@ func (hc *HealthChecker) RunChecks(ctx context.Context) {
 		}
 
 		hc.components[name] = health
+		hc.mu.Unlock()
 	}
 }
 
@@ -105,14 +138,26 @@ func (hc *HealthChecker) IsHealthy() bool {
 	return true
 }
 
-// IsReady returns true if critical components are healthy
+// IsReady returns true if every component, liveness or readiness, is healthy
 func (hc *HealthChecker) IsReady() bool {
 	hc.mu.RLock()
 	defer hc.mu.RUnlock()
 
-	// Check docker component specifically for readiness
-	if docker, ok := hc.components["docker"]; ok {
-		if docker.Status == HealthStatusUnhealthy {
+	for _, health := range hc.components {
+		if health.Status == HealthStatusUnhealthy {
+			return false
+		}
+	}
+	return true
+}
+
+// IsLive returns true unless a check registered with RegisterCheck fails
+func (hc *HealthChecker) IsLive() bool {
+	hc.mu.RLock()
+	defer hc.mu.RUnlock()
+
+	for name, health := range hc.components {
+		if !hc.readiness[name] && health.Status == HealthStatusUnhealthy {
 			return false
 		}
 	}
@@ -155,6 +200,74 @@ func (hc *HealthChecker) ReadyHandler() gin.HandlerFunc {
 	}
 }
 
+// LivezHandler returns a gin handler for the /healthz endpoint. It answers
+// 200 while the process is serving and no liveness check fails, with the
+// status of each liveness check; readiness checks are left to /readyz so a
+// lost dependency does not get the node restarted.
+func (hc *HealthChecker) LivezHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hc.probeResponse(c, hc.IsLive(), false)
+	}
+}
+
+// ReadyzHandler returns a gin handler for the /readyz endpoint. It answers
+// 200 only when every check passes, with the status of each.
+func (hc *HealthChecker) ReadyzHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hc.probeResponse(c, hc.IsReady(), true)
+	}
+}
+
+// probeResponse writes a probe's verdict with the checks it covers
+func (hc *HealthChecker) probeResponse(c *gin.Context, ok bool, readiness bool) {
+	checks := make(map[string]*ComponentHealth)
+	for name, health := range hc.GetHealth() {
+		if readiness || !health.Readiness {
+			checks[name] = health
+		}
+	}
+
+	status, code := "ok", http.StatusOK
+	if !ok {
+		status, code = "fail", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{
+		"status":         status,
+		"checks":         checks,
+		"uptime_seconds": int64(time.Since(hc.startTime).Seconds()),
+		"timestamp":      time.Now(),
+	})
+}
+
+// Serve answers the health endpoints on addr until ctx is done, for nodes
+// that run no web server of their own
+func (hc *HealthChecker) Serve(ctx context.Context, addr string) error {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.GET("/healthz", hc.LivezHandler())
+	r.GET("/readyz", hc.ReadyzHandler())
+	r.GET("/health", hc.HealthHandler())
+	r.GET("/ready", hc.ReadyHandler())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           r,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("health server: %w", err)
+	}
+	return nil
+}
+
 func (hc *HealthChecker) overallStatus() HealthStatus {
 	hc.mu.RLock()
 	defer hc.mu.RUnlock()
@@ -180,8 +293,10 @@ func (hc *HealthChecker) overallStatus() HealthStatus {
 	return HealthStatusHealthy
 }
 
-// StartPeriodicChecks starts running health checks periodically
+// StartPeriodicChecks runs the health checks now and then periodically
 func (hc *HealthChecker) StartPeriodicChecks(ctx context.Context, interval time.Duration) {
+	hc.RunChecks(ctx)
+
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -204,3 +319,25 @@ func DockerHealthCheck(pingFunc func(context.Context) error) HealthCheckFunc {
 		return nil
 	}
 }
+
+// ServingHealthCheck creates a health check that fails while a listener such
+// as the gRPC server is not serving
+func ServingHealthCheck(what string, serving func() bool) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		if !serving() {
+			return fmt.Errorf("%s is not serving", what)
+		}
+		return nil
+	}
+}
+
+// MasterHealthCheck creates a health check that fails while a worker is not
+// connected to its master
+func MasterHealthCheck(connected func() bool) HealthCheckFunc {
+	return func(ctx context.Context) error {
+		if !connected() {
+			return fmt.Errorf("not connected to master")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,242 @@
+package observability
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Default sampling: per message and second, log the first 100 lines and
+// every 100th after that
+const (
+	DefaultSampleInitial    = 100
+	DefaultSampleThereafter = 100
+)
+
+// LogControl holds logging settings that can be changed while running: the
+// base level, per-module level overrides and sampling of chatty lines.
+//
+// A module is the package and file a line is logged from, e.g.
+// "peer.transfer" for internal/peer/transfer.go. An override for "peer"
+// applies to every file in the package; the most specific override wins.
+type LogControl struct {
+	level zap.AtomicLevel
+
+	mu      sync.RWMutex
+	modules map[string]zapcore.Level
+
+	// floor is the lowest level any module logs at, so lines below it are
+	// rejected before their fields are built
+	floor zap.AtomicLevel
+
+	sampleInitial    atomic.Int64
+	sampleThereafter atomic.Int64
+	sampler          sampler
+}
+
+func newLogControl(level zapcore.Level) *LogControl {
+	c := &LogControl{
+		level:   zap.NewAtomicLevelAt(level),
+		modules: make(map[string]zapcore.Level),
+		floor:   zap.NewAtomicLevelAt(level),
+	}
+	c.sampleInitial.Store(DefaultSampleInitial)
+	c.sampleThereafter.Store(DefaultSampleThereafter)
+	return c
+}
+
+// Level returns the base log level
+func (c *LogControl) Level() string {
+	return c.level.Level().String()
+}
+
+// SetLevel changes the base log level
+func (c *LogControl) SetLevel(level string) error {
+	var l zapcore.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.level.SetLevel(l)
+	c.updateFloor()
+	return nil
+}
+
+// ModuleLevels returns the per-module level overrides
+func (c *LogControl) ModuleLevels() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]string, len(c.modules))
+	for module, level := range c.modules {
+		out[module] = level.String()
+	}
+	return out
+}
+
+// SetModuleLevels replaces the per-module level overrides
+func (c *LogControl) SetModuleLevels(levels map[string]string) error {
+	modules := make(map[string]zapcore.Level, len(levels))
+	for module, level := range levels {
+		var l zapcore.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("invalid log level %q for module %s", level, module)
+		}
+		modules[strings.TrimSpace(module)] = l
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.modules = modules
+	c.updateFloor()
+	return nil
+}
+
+// Sampling returns how many lines of a message are logged per second before
+// sampling starts, and the sampling interval after that
+func (c *LogControl) Sampling() (initial, thereafter int) {
+	return int(c.sampleInitial.Load()), int(c.sampleThereafter.Load())
+}
+
+// SetSampling changes sampling of debug and info lines. An initial of 0
+// turns sampling off; a thereafter of 0 drops everything past initial.
+// Warnings and errors are never sampled.
+func (c *LogControl) SetSampling(initial, thereafter int) error {
+	if initial < 0 || thereafter < 0 {
+		return fmt.Errorf("sampling values must not be negative")
+	}
+	c.sampleInitial.Store(int64(initial))
+	c.sampleThereafter.Store(int64(thereafter))
+	return nil
+}
+
+// ParseModuleLevels parses "module=level" pairs separated by commas, e.g.
+// "peer.transfer=warn,server=info"
+func ParseModuleLevels(spec string) (map[string]string, error) {
+	levels := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		module, level, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(module) == "" {
+			return nil, fmt.Errorf("invalid module level %q, want module=level", pair)
+		}
+		levels[strings.TrimSpace(module)] = strings.TrimSpace(level)
+	}
+	return levels, nil
+}
+
+// updateFloor must be called with mu held
+func (c *LogControl) updateFloor() {
+	floor := c.level.Level()
+	for _, level := range c.modules {
+		if level < floor {
+			floor = level
+		}
+	}
+	c.floor.SetLevel(floor)
+}
+
+// enabled reports whether a line from module at level should be logged
+func (c *LogControl) enabled(module string, level zapcore.Level) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	// Most specific override wins: "peer.transfer" before "peer"
+	best := ""
+	for key := range c.modules {
+		if len(key) > len(best) && (module == key || strings.HasPrefix(module, key+".")) {
+			best = key
+		}
+	}
+	if best != "" {
+		return level >= c.modules[best]
+	}
+	return c.level.Enabled(level)
+}
+
+// sampled reports whether a debug or info line survives sampling
+func (c *LogControl) sampled(entry zapcore.Entry) bool {
+	if entry.Level >= zapcore.WarnLevel {
+		return true
+	}
+	initial := c.sampleInitial.Load()
+	if initial == 0 {
+		return true
+	}
+	n := c.sampler.count(entry)
+	if n <= initial {
+		return true
+	}
+	thereafter := c.sampleThereafter.Load()
+	return thereafter > 0 && (n-initial)%thereafter == 0
+}
+
+// sampler counts lines per message within one-second windows
+type sampler struct {
+	mu     sync.Mutex
+	window int64
+	counts map[string]int64
+}
+
+func (s *sampler) count(entry zapcore.Entry) int64 {
+	window := entry.Time.UnixNano() / int64(time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if window != s.window || s.counts == nil {
+		s.window = window
+		s.counts = make(map[string]int64)
+	}
+	key := entry.Level.String() + "|" + entry.Message
+	s.counts[key]++
+	return s.counts[key]
+}
+
+// moduleOf names the module a line was logged from
+func moduleOf(entry zapcore.Entry) string {
+	if !entry.Caller.Defined {
+		return entry.LoggerName
+	}
+	file := entry.Caller.File
+	pkg := filepath.Base(filepath.Dir(file))
+	return pkg + "." + strings.TrimSuffix(filepath.Base(file), ".go")
+}
+
+// controlledCore applies a LogControl in front of another core
+type controlledCore struct {
+	zapcore.Core
+	control *LogControl
+}
+
+func (c *controlledCore) Enabled(level zapcore.Level) bool {
+	return c.control.floor.Enabled(level)
+}
+
+func (c *controlledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &controlledCore{Core: c.Core.With(fields), control: c.control}
+}
+
+func (c *controlledCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return ce.AddCore(entry, c)
+	}
+	return ce
+}
+
+// Write filters by module here rather than in Check because the caller is
+// only known once the entry is written
+func (c *controlledCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if !c.control.enabled(moduleOf(entry), entry.Level) || !c.control.sampled(entry) {
+		return nil
+	}
+	return c.Core.Write(entry, fields)
+}
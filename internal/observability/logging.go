@@ -23,6 +23,7 @@ var (
 // Logger wraps zap.Logger with secret redaction
 type Logger struct {
 	*zap.Logger
+	control *LogControl
 }
 
 // NewLogger creates a production logger with JSON encoding and secret redaction
@@ -32,14 +33,12 @@ func NewLogger(level string) (*Logger, error) {
 		zapLevel = zapcore.InfoLevel
 	}
 
+	// Levels and sampling are applied by the LogControl so they can change
+	// at runtime; the underlying core accepts everything
 	config := zap.Config{
-		Level:       zap.NewAtomicLevelAt(zapLevel),
+		Level:       zap.NewAtomicLevelAt(zapcore.DebugLevel),
 		Development: false,
-		Sampling: &zap.SamplingConfig{
-			Initial:    100,
-			Thereafter: 100,
-		},
-		Encoding: "json",
+		Encoding:    "json",
 		EncoderConfig: zapcore.EncoderConfig{
 			TimeKey:        "ts",
 			LevelKey:       "level",
@@ -58,12 +57,20 @@ func NewLogger(level string) (*Logger, error) {
 		ErrorOutputPaths: []string{"stderr"},
 	}
 
-	logger, err := config.Build()
+	control := newLogControl(zapLevel)
+	logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &controlledCore{Core: core, control: control}
+	}))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Logger{Logger: logger}, nil
+	return &Logger{Logger: logger, control: control}, nil
+}
+
+// Control returns the runtime logging settings
+func (l *Logger) Control() *LogControl {
+	return l.control
 }
 
 // RedactString removes secrets from a string
@@ -106,6 +106,23 @@ var (
 		[]string{"method", "error_type"},
 	)
 
+	// PairingLockouts counts addresses banned for too many failed pairing attempts
+	PairingLockouts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "docker_migrate_pairing_lockouts_total",
+			Help: "Total number of addresses locked out of pairing",
+		},
+	)
+
+	// ChunksDeduplicated counts received chunks discarded, in whole or in
+	// part, because their data was already written
+	ChunksDeduplicated = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "docker_migrate_chunks_deduplicated_total",
+			Help: "Total number of received chunks discarded as duplicates",
+		},
+	)
+
 	// BufferUtilization tracks buffer usage in streaming operations
 	BufferUtilization = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -0,0 +1,208 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// BenchmarkResult reports the throughput of one benchmark run
+type BenchmarkResult struct {
+	ChunkSize        int           `json:"chunk_size"`
+	Bytes            int64         `json:"bytes"`
+	WireBytes        int64         `json:"wire_bytes"` // After compression
+	Chunks           int           `json:"chunks"`
+	Duration         time.Duration `json:"duration"`
+	ThroughputMBps   float64       `json:"throughput_mbps"`
+	CompressionRatio float64       `json:"compression_ratio"`
+}
+
+// SyntheticReader produces deterministic pseudo-random data. A fraction of each
+// block is zero-filled so compression has something realistic to work with.
+type SyntheticReader struct {
+	rng          *rand.Rand
+	remaining    int64
+	compressible float64
+}
+
+// NewSyntheticReader creates a reader yielding size bytes; compressible is 0..1
+func NewSyntheticReader(size int64, compressible float64, seed int64) *SyntheticReader {
+	if compressible < 0 {
+		compressible = 0
+	}
+	if compressible > 1 {
+		compressible = 1
+	}
+	return &SyntheticReader{
+		rng:          rand.New(rand.NewSource(seed)),
+		remaining:    size,
+		compressible: compressible,
+	}
+}
+
+// Read implements io.Reader
+func (sr *SyntheticReader) Read(p []byte) (int, error) {
+	if sr.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > sr.remaining {
+		p = p[:sr.remaining]
+	}
+
+	random := int(float64(len(p)) * (1 - sr.compressible))
+	sr.rng.Read(p[:random])
+	clear(p[random:])
+
+	sr.remaining -= int64(len(p))
+	return len(p), nil
+}
+
+// RunLoopbackBenchmark pushes size bytes of synthetic data through the chunk reader,
+// optional compression and the verifying chunk writer, all in-process. Chunks are
+// compressed concurrently within the pool's worker and CPU caps, and written in
+// order. A nil pool disables compression.
+func RunLoopbackBenchmark(ctx context.Context, size int64, chunkSize int, pool *CompressionPool, compressible float64) (*BenchmarkResult, error) {
+	reader := NewChunkReader(NewSyntheticReader(size, compressible, 1), chunkSize, size)
+	writer := NewChunkWriter(io.Discard, 0, nil)
+
+	result := &BenchmarkResult{ChunkSize: reader.chunkSize}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Each chunk's compression runs in its own goroutine, at most one
+	// more per worker than the pool can run at once
+	type compressedChunk struct {
+		chunk *Chunk
+		data  chan []byte
+		err   chan error
+	}
+	window := 1
+	if pool != nil {
+		window = pool.Workers() + 1
+	}
+	queue := make(chan compressedChunk, window)
+	readErr := make(chan error, 1)
+
+	start := time.Now()
+	go func() {
+		defer close(queue)
+		for {
+			chunk, err := reader.ReadChunk()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+
+			cc := compressedChunk{chunk: chunk, data: make(chan []byte, 1), err: make(chan error, 1)}
+			if pool != nil {
+				go func() {
+					data, err := pool.Compress(ctx, chunk.Data)
+					if err != nil {
+						cc.err <- err
+						return
+					}
+					cc.data <- data
+				}()
+			}
+			select {
+			case queue <- cc:
+			case <-ctx.Done():
+				return
+			}
+			if chunk.IsFinal {
+				return
+			}
+		}
+	}()
+
+	for cc := range queue {
+		chunk := cc.chunk
+		wireBytes := int64(chunk.Size)
+		if pool != nil {
+			var compressed []byte
+			select {
+			case compressed = <-cc.data:
+			case err := <-cc.err:
+				return nil, err
+			}
+			wireBytes = int64(len(compressed))
+
+			data, err := pool.Decompress(compressed)
+			if err != nil {
+				return nil, err
+			}
+			chunk.Data = data
+		}
+
+		if err := writer.WriteChunk(chunk); err != nil {
+			return nil, err
+		}
+
+		result.Bytes += int64(chunk.Size)
+		result.WireBytes += wireBytes
+		result.Chunks++
+	}
+	select {
+	case err := <-readErr:
+		return nil, err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Duration = time.Since(start)
+	result.finish()
+	return result, nil
+}
+
+// BenchmarkVolumePrefix marks a volume stream as benchmark data, which the
+// receiver checks and acknowledges but does not keep
+const BenchmarkVolumePrefix = "docker-migrate-benchmark-"
+
+// IsBenchmarkVolume reports whether a received volume is benchmark data
+func IsBenchmarkVolume(volumeID string) bool {
+	return strings.HasPrefix(volumeID, BenchmarkVolumePrefix)
+}
+
+// RunPeerBenchmark streams size bytes of synthetic data to a paired peer over
+// gRPC. The peer verifies and acknowledges each chunk, then discards it.
+func (gc *GRPCClient) RunPeerBenchmark(ctx context.Context, size int64, compressible float64) (*BenchmarkResult, error) {
+	volumeID := fmt.Sprintf("%s%d", BenchmarkVolumePrefix, time.Now().UnixNano())
+
+	var chunks int
+	start := time.Now()
+	if err := gc.sendVolume(ctx, volumeID, NewSyntheticReader(size, compressible, 1), size, nil, &chunks); err != nil {
+		return nil, err
+	}
+
+	result := &BenchmarkResult{
+		ChunkSize: DefaultChunkSize,
+		Bytes:     size,
+		WireBytes: size,
+		Chunks:    chunks,
+		Duration:  time.Since(start),
+	}
+	if chunks > 0 {
+		result.ChunkSize = int(size / int64(chunks))
+	}
+	result.finish()
+	return result, nil
+}
+
+// finish computes derived figures
+func (r *BenchmarkResult) finish() {
+	if secs := r.Duration.Seconds(); secs > 0 {
+		r.ThroughputMBps = float64(r.Bytes) / secs / (1024 * 1024)
+	}
+	if r.WireBytes > 0 {
+		r.CompressionRatio = float64(r.Bytes) / float64(r.WireBytes)
+	}
+}
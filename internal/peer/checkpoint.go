@@ -0,0 +1,58 @@
+package peer
+
+import (
+	"os"
+	"time"
+)
+
+func (tm *TransferManager) checkpointBytes() int64 {
+	if tm.config != nil && tm.config.CheckpointBytes > 0 {
+		return tm.config.CheckpointBytes
+	}
+	return CheckpointBytes
+}
+
+func (tm *TransferManager) checkpointInterval() time.Duration {
+	if tm.config != nil && tm.config.CheckpointInterval > 0 {
+		return tm.config.CheckpointInterval
+	}
+	return CheckpointInterval
+}
+
+// checkpointDue reports whether a transfer has gone unsaved for long
+// enough, in bytes or time, that its checkpoint should be written.
+// The caller holds transfer.mu.
+func (tm *TransferManager) checkpointDue(transfer *Transfer) bool {
+	if transfer.TransferredBytes-transfer.savedOffset >= tm.checkpointBytes() {
+		return true
+	}
+	return transfer.TransferredBytes > transfer.savedOffset &&
+		time.Since(transfer.LastCheckpoint) >= tm.checkpointInterval()
+}
+
+// writeFileSync writes data to path and fsyncs it before returning
+func writeFileSync(path string, data []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir fsyncs a directory so a rename into it is durable
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
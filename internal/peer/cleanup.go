@@ -0,0 +1,143 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RemoveResources deletes what a failed migration from the calling peer
+// left on this host, returning what it removed. Only resources the migration
+// made are touched: containers, networks and volumes must carry its job
+// label, which they are given when they are created. Resources already gone
+// are skipped. The job label is only a scope, not a credential: the caller
+// must be a trusted peer whatever server this is registered on, and the
+// host's policy must allow remove_resources.
+func (gs *GRPCServer) RemoveResources(ctx context.Context, req *pb.RemoveResourcesRequest) (*pb.ResourceList, error) {
+	if err := gs.verifyPeer(ctx); err != nil {
+		gs.logger.Warn("refusing rollback from untrusted peer", zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "peer not trusted")
+	}
+	if err := gs.allow(OpRemoveResources); err != nil {
+		return nil, err
+	}
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+
+	jobID := req.JobId
+	if jobID == "" {
+		return nil, status.Error(codes.InvalidArgument, "no migration job given")
+	}
+
+	list := req.Resources
+	if list == nil {
+		list = &pb.ResourceList{}
+	}
+
+	removed := &pb.ResourceList{}
+	var failures []string
+	fail := func(kind, name string, err error) {
+		gs.logger.Warn("rollback: not removing resource",
+			zap.String("job_id", jobID),
+			zap.String("type", kind),
+			zap.String("name", name),
+			zap.Error(err),
+		)
+		failures = append(failures, fmt.Sprintf("%s %s: %v", kind, name, err))
+	}
+
+	// Containers go first so the networks and volumes they use are free
+	for _, c := range list.Containers {
+		inspect, err := gs.docker.InspectContainer(ctx, c.Name)
+		if docker.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			fail("container", c.Name, err)
+			continue
+		}
+		if inspect.Config == nil || inspect.Config.Labels[docker.LabelMigratedJob] != jobID {
+			fail("container", c.Name, fmt.Errorf("not created by migration %s", jobID))
+			continue
+		}
+		if err := gs.docker.RemoveContainer(ctx, inspect.ID, true); err != nil {
+			fail("container", c.Name, err)
+			continue
+		}
+		removed.Containers = append(removed.Containers, &pb.ContainerResource{Id: inspect.ID, Name: c.Name})
+	}
+
+	for _, n := range list.Networks {
+		info, err := gs.docker.InspectNetwork(ctx, n.Name)
+		if docker.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			fail("network", n.Name, err)
+			continue
+		}
+		if info.Labels[docker.LabelMigratedJob] != jobID {
+			fail("network", n.Name, fmt.Errorf("not created by migration %s", jobID))
+			continue
+		}
+		if err := gs.docker.RemoveNetwork(ctx, info.ID); err != nil {
+			fail("network", n.Name, err)
+			continue
+		}
+		removed.Networks = append(removed.Networks, &pb.NetworkResource{Id: info.ID, Name: n.Name})
+	}
+
+	for _, v := range list.Volumes {
+		vol, err := gs.docker.InspectVolume(ctx, v.Name)
+		if docker.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			fail("volume", v.Name, err)
+			continue
+		}
+		if vol.Labels[docker.LabelMigratedJob] != jobID {
+			fail("volume", v.Name, fmt.Errorf("not created by migration %s", jobID))
+			continue
+		}
+		// Not forced: a volume something else has started using stays
+		if err := gs.docker.RemoveVolume(ctx, v.Name, false); err != nil {
+			fail("volume", v.Name, err)
+			continue
+		}
+		gs.volumeIndexes.invalidate(v.Name)
+		removed.Volumes = append(removed.Volumes, &pb.VolumeResource{Name: v.Name})
+	}
+
+	gs.logger.Info("rollback: removed migrated resources",
+		zap.String("job_id", jobID),
+		zap.Int("containers", len(removed.Containers)),
+		zap.Int("networks", len(removed.Networks)),
+		zap.Int("volumes", len(removed.Volumes)),
+		zap.Int("refused", len(failures)),
+	)
+
+	if len(failures) > 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "could not remove %d resources: %s",
+			len(failures), strings.Join(failures, "; "))
+	}
+	return removed, nil
+}
+
+// RemoveResources asks the peer to delete what migration jobID left there,
+// returning what it removed. A peer that refuses some resources still
+// removes the rest; the error lists those it refused.
+func (gc *GRPCClient) RemoveResources(ctx context.Context, jobID string, list *pb.ResourceList) (*pb.ResourceList, error) {
+	removed, err := gc.client.RemoveResources(ctx, &pb.RemoveResourcesRequest{JobId: jobID, Resources: list})
+	if err != nil {
+		return nil, peerCallError("remove migrated resources", err)
+	}
+	return removed, nil
+}
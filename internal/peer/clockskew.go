@@ -0,0 +1,31 @@
+package peer
+
+import (
+	"time"
+)
+
+// ClockSkewWarnThreshold is the clock difference between two hosts above
+// which their timestamps are flagged as unreliable when compared
+const ClockSkewWarnThreshold = 2 * time.Second
+
+// EstimateClockSkew returns how far a remote clock is ahead of ours, given
+// the remote's time in a reply to a request sent at sent that took rtt to
+// come back. The reply is assumed to have been stamped halfway through.
+func EstimateClockSkew(remoteMs int64, sent time.Time, rtt time.Duration) time.Duration {
+	return time.UnixMilli(remoteMs).Sub(sent.Add(rtt / 2))
+}
+
+// ClockSkewExceeded reports whether skew is large enough to warn about
+func ClockSkewExceeded(skew time.Duration) bool {
+	return skew > ClockSkewWarnThreshold || skew < -ClockSkewWarnThreshold
+}
+
+// NormalizeRemoteTime converts a remote host's Unix millisecond timestamp to
+// our clock, given how far its clock is ahead of ours. A zero timestamp,
+// sent by hosts that do not stamp their reports, gives the zero time.
+func NormalizeRemoteTime(remoteMs int64, skew time.Duration) time.Time {
+	if remoteMs == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(remoteMs).Add(-skew)
+}
@@ -0,0 +1,216 @@
+package peer
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MaxComposeBundleSize bounds a bundle sent in one message, well within the
+// server's message size limit
+const MaxComposeBundleSize = 4 << 20
+
+// ReceiveBundle writes a compose bundle to the bundles directory under the
+// data dir, named after its stack and the time it arrived. Sealed files in
+// it stay sealed until opened with compose decrypt.
+func (gs *GRPCServer) ReceiveBundle(ctx context.Context, req *pb.ComposeBundle) (*pb.ComposeBundleReceipt, error) {
+	if err := gs.allow(OpReceiveContainers); err != nil {
+		return nil, err
+	}
+	if req.Stack == "" || filepath.Base(req.Stack) != req.Stack || req.Stack == "." || req.Stack == ".." {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid stack name %q", req.Stack)
+	}
+	if len(req.Data) > MaxComposeBundleSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "bundle exceeds %d bytes", MaxComposeBundleSize)
+	}
+
+	dataDir, err := config.ResolveDataDir(gs.config.DataDir)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "resolve data dir: %v", err)
+	}
+	dir := filepath.Join(dataDir, "bundles")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, status.Errorf(codes.Internal, "create bundles directory: %v", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.tar", req.Stack, time.Now().UTC().Format("20060102T150405Z")))
+	if err := os.WriteFile(path, req.Data, 0600); err != nil {
+		return nil, status.Errorf(codes.Internal, "write bundle: %v", err)
+	}
+
+	gs.logger.Info("received compose bundle",
+		zap.String("stack", req.Stack),
+		zap.String("path", path),
+		zap.Int("bytes", len(req.Data)),
+	)
+	return &pb.ComposeBundleReceipt{Path: path}, nil
+}
+
+// SendComposeBundle hands a stack's bundle to the peer and returns where
+// the peer kept it
+func (gc *GRPCClient) SendComposeBundle(ctx context.Context, stack string, data []byte) (string, error) {
+	if len(data) > MaxComposeBundleSize {
+		return "", fmt.Errorf("bundle of %d bytes exceeds the %d bytes a peer accepts", len(data), MaxComposeBundleSize)
+	}
+	receipt, err := gc.client.ReceiveBundle(ctx, &pb.ComposeBundle{Stack: stack, Data: data})
+	if err != nil {
+		return "", peerCallError("receive compose bundles", err)
+	}
+	return receipt.Path, nil
+}
+
+// PeerCertificate returns the certificate the peer presented, which the
+// client checked against its pinned fingerprint
+func (gc *GRPCClient) PeerCertificate(ctx context.Context) (*x509.Certificate, error) {
+	var p grpcpeer.Peer
+	if _, err := gc.client.Ping(ctx, &pb.Empty{}, grpc.Peer(&p)); err != nil {
+		return nil, fmt.Errorf("ping failed: %w", err)
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("peer presented no certificate")
+	}
+	return tlsInfo.State.PeerCertificates[0], nil
+}
+
+// SealedBundleKey is a bundle's session key sealed for the one peer it was
+// sent to. An ephemeral key agreed with the peer's certificate key, run
+// through the session key derivation, encrypts it, so only the holder of
+// that certificate's private key can open it.
+type SealedBundleKey struct {
+	Recipient string `json:"recipient"` // Fingerprint of the certificate it is sealed for
+	Ephemeral []byte `json:"ephemeral"` // Uncompressed P-256 point
+	Nonce     []byte `json:"nonce"`
+	Key       []byte `json:"key"`
+}
+
+// SealBundleKey seals a bundle's session key for the holder of recipient
+func SealBundleKey(recipient *x509.Certificate, sessionKey []byte) (*SealedBundleKey, error) {
+	recipientKey, ok := recipient.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("peer certificate has no ECDSA key")
+	}
+	remote, err := recipientKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("peer certificate key cannot agree keys: %w", err)
+	}
+	ephemeral, err := remote.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	shared, err := ephemeral.ECDH(remote)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	sealed := &SealedBundleKey{
+		Recipient: ComputeFingerprint(recipient),
+		Ephemeral: ephemeral.PublicKey().Bytes(),
+		Nonce:     make([]byte, 12),
+	}
+	gcm, err := sealed.cipher(shared)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed.Key = gcm.Seal(nil, sealed.Nonce, sessionKey, []byte(sealed.Recipient))
+	return sealed, nil
+}
+
+// OpenBundleKey opens a session key sealed for this node with the private
+// key kept under dataDir. It only reads the key, so a node whose
+// certificate has since been replaced fails rather than generating one.
+func OpenBundleKey(dataDir string, sealed *SealedBundleKey) ([]byte, error) {
+	cert, err := ReadCertificate(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	if fingerprint := ComputeFingerprint(cert); fingerprint != sealed.Recipient {
+		return nil, fmt.Errorf("bundle key is sealed for %s, not this node (%s)", sealed.Recipient, fingerprint)
+	}
+	privateKey, err := readPrivateKey(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	local, err := privateKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("node key cannot agree keys: %w", err)
+	}
+	remote, err := local.Curve().NewPublicKey(sealed.Ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle key: %w", err)
+	}
+	shared, err := local.ECDH(remote)
+	if err != nil {
+		return nil, fmt.Errorf("key agreement failed: %w", err)
+	}
+
+	gcm, err := sealed.cipher(shared)
+	if err != nil {
+		return nil, err
+	}
+	sessionKey, err := gcm.Open(nil, sealed.Nonce, sealed.Key, []byte(sealed.Recipient))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle key: %w", err)
+	}
+	return sessionKey, nil
+}
+
+// cipher derives the key-wrapping cipher from the agreed secret, salted
+// with the ephemeral key and the recipient's fingerprint
+func (s *SealedBundleKey) cipher(shared []byte) (cipher.AEAD, error) {
+	wrapKey, err := deriveSessionKey(shared, append(append([]byte(nil), s.Ephemeral...), s.Recipient...))
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readPrivateKey returns the private key NewCryptoManager keeps under
+// dataDir without generating one when there is none
+func readPrivateKey(dataDir string) (*ecdsa.PrivateKey, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(dataDir, "certs", "server.key"))
+	if os.IsNotExist(err) {
+		keyPEM, err = os.ReadFile(filepath.Join(dataDir, "server.key"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse private key PEM")
+	}
+	privateKey, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return privateKey, nil
+}
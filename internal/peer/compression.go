@@ -0,0 +1,118 @@
+package peer
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// CompressionPool bounds the goroutines compressing chunks and their aggregate CPU,
+// so migrations don't steal cycles from production containers on the source host.
+// Transfers are sent uncompressed for now; the loopback benchmark compresses
+// through it.
+type CompressionPool struct {
+	level   int
+	workers int
+
+	// dutyCycle is the fraction of wall time each worker may spend compressing (1 = no limit)
+	dutyCycle float64
+
+	sem     chan struct{}
+	writers sync.Pool
+}
+
+// DefaultCompressionWorkers uses half the host's CPUs, at least one
+func DefaultCompressionWorkers() int {
+	if n := runtime.NumCPU() / 2; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// NewCompressionPool creates a pool. workers <= 0 uses DefaultCompressionWorkers.
+// cpuPercent caps aggregate CPU in percent of one core (150 = 1.5 cores); 0 = unlimited.
+func NewCompressionPool(level, workers, cpuPercent int) (*CompressionPool, error) {
+	if level < flate.BestSpeed || level > flate.BestCompression {
+		return nil, fmt.Errorf("compression level must be between %d and %d", flate.BestSpeed, flate.BestCompression)
+	}
+	if workers <= 0 {
+		workers = DefaultCompressionWorkers()
+	}
+
+	dutyCycle := 1.0
+	if cpuPercent > 0 {
+		dutyCycle = float64(cpuPercent) / 100 / float64(workers)
+		if dutyCycle > 1 {
+			dutyCycle = 1
+		}
+	}
+
+	cp := &CompressionPool{
+		level:     level,
+		workers:   workers,
+		dutyCycle: dutyCycle,
+		sem:       make(chan struct{}, workers),
+	}
+	cp.writers.New = func() interface{} {
+		w, _ := flate.NewWriter(io.Discard, level)
+		return w
+	}
+
+	return cp, nil
+}
+
+// Workers returns the maximum number of concurrent compressions
+func (cp *CompressionPool) Workers() int {
+	return cp.workers
+}
+
+// Compress deflates data, waiting for a free worker slot. When a CPU cap is set
+// the worker idles afterwards so its busy time stays within its share.
+func (cp *CompressionPool) Compress(ctx context.Context, data []byte) ([]byte, error) {
+	select {
+	case cp.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-cp.sem }()
+
+	start := time.Now()
+
+	var buf bytes.Buffer
+	w := cp.writers.Get().(*flate.Writer)
+	w.Reset(&buf)
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+	cp.writers.Put(w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress chunk: %w", err)
+	}
+
+	if cp.dutyCycle < 1 {
+		busy := time.Since(start)
+		idle := time.Duration(float64(busy) * (1/cp.dutyCycle - 1))
+		select {
+		case <-time.After(idle):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress inflates a chunk produced by Compress
+func (cp *CompressionPool) Decompress(data []byte) ([]byte, error) {
+	out, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress chunk: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,212 @@
+package peer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ContainerCreateRequest carries a container's exported state and the name
+// to create it under
+type ContainerCreateRequest struct {
+	State *docker.ContainerState
+	Name  string
+	// AllowDefaultRuntime runs the container under the default runtime when
+	// the one it asks for is not installed
+	AllowDefaultRuntime bool
+}
+
+// ContainerCreateResult is the created container and what it lost
+type ContainerCreateResult struct {
+	ID     string
+	Report *docker.CompatibilityReport
+}
+
+// CreateContainer creates a container from the source's exported state and
+// reports the settings this daemon could not apply. The container is not
+// started.
+func (gs *GRPCServer) CreateContainer(ctx context.Context, req *pb.ContainerCreateRequest) (*pb.ContainerCreateResult, error) {
+	if err := gs.allow(OpReceiveContainers); err != nil {
+		return nil, err
+	}
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	var state docker.ContainerState
+	if err := json.Unmarshal(req.StateData, &state); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid container state: %v", err)
+	}
+	if state.Config == nil || state.HostConfig == nil {
+		return nil, status.Error(codes.InvalidArgument, "container state is required")
+	}
+
+	id, report, err := gs.docker.CreateContainer(ctx, &state, req.Name, req.AllowDefaultRuntime)
+	if errors.Is(err, docker.ErrRuntimeUnavailable) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "create container: %v", err)
+	}
+
+	gs.logger.Info("created container for migration",
+		zap.String("name", req.Name),
+		zap.String("container_id", id),
+		zap.Int("skipped", len(report.Skipped)),
+	)
+	return &pb.ContainerCreateResult{Id: id, Report: compatibilityReportToProto(report)}, nil
+}
+
+// CreateContainer asks the peer to recreate a container and returns its ID
+// and compatibility report
+func (gc *GRPCClient) CreateContainer(ctx context.Context, req *ContainerCreateRequest) (*ContainerCreateResult, error) {
+	stateData, err := json.Marshal(req.State)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode container state: %w", err)
+	}
+
+	resp, err := gc.client.CreateContainer(ctx, &pb.ContainerCreateRequest{
+		StateData:           stateData,
+		Name:                req.Name,
+		AllowDefaultRuntime: req.AllowDefaultRuntime,
+	})
+	if err != nil {
+		return nil, peerCallError("create containers", err)
+	}
+	return &ContainerCreateResult{ID: resp.Id, Report: compatibilityReportFromProto(resp.Report)}, nil
+}
+
+func compatibilityReportToProto(report *docker.CompatibilityReport) *pb.CompatibilityReport {
+	if report == nil {
+		return nil
+	}
+	out := &pb.CompatibilityReport{
+		Container:   report.Container,
+		ContainerId: report.ContainerID,
+		Warnings:    report.Warnings,
+	}
+	for _, f := range report.Skipped {
+		out.Skipped = append(out.Skipped, &pb.SkippedField{
+			Field:     f.Field,
+			Requested: f.Requested,
+			Applied:   f.Applied,
+			Reason:    f.Reason,
+		})
+	}
+	return out
+}
+
+func compatibilityReportFromProto(report *pb.CompatibilityReport) *docker.CompatibilityReport {
+	if report == nil {
+		return nil
+	}
+	out := &docker.CompatibilityReport{
+		Container:   report.Container,
+		ContainerID: report.ContainerId,
+		Warnings:    report.Warnings,
+	}
+	for _, f := range report.Skipped {
+		out.Skipped = append(out.Skipped, docker.SkippedField{
+			Field:     f.Field,
+			Requested: f.Requested,
+			Applied:   f.Applied,
+			Reason:    f.Reason,
+		})
+	}
+	return out
+}
+
+// CheckpointSupport reports whether this daemon can restore checkpoints.
+// Like the source's own check it cannot see a missing CRIU, which only
+// shows up when a restore is attempted.
+func (gs *GRPCServer) CheckpointSupport(ctx context.Context, req *pb.Empty) (*pb.CheckpointSupportResult, error) {
+	if gs.docker == nil {
+		return &pb.CheckpointSupportResult{Unsupported: "docker is not available"}, nil
+	}
+	if err := gs.docker.CheckpointSupported(ctx); err != nil {
+		return &pb.CheckpointSupportResult{Unsupported: err.Error()}, nil
+	}
+	return &pb.CheckpointSupportResult{}, nil
+}
+
+// RestoreContainer starts a container from the checkpoint in a received
+// volume, then removes the volume whether or not the restore succeeded
+func (gs *GRPCServer) RestoreContainer(ctx context.Context, req *pb.ContainerRestoreRequest) (*pb.Empty, error) {
+	if err := gs.allow(OpReceiveContainers); err != nil {
+		return nil, err
+	}
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.ContainerId == "" || req.CheckpointId == "" || req.Volume == "" {
+		return nil, status.Error(codes.InvalidArgument, "container, checkpoint and volume are required")
+	}
+
+	vol, err := gs.docker.InspectVolume(ctx, req.Volume)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "checkpoint volume: %v", err)
+	}
+	defer func() {
+		if err := gs.docker.RemoveVolume(context.WithoutCancel(ctx), req.Volume, true); err != nil {
+			gs.logger.Warn("failed to remove checkpoint volume",
+				zap.String("volume", req.Volume),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	if err := gs.docker.RestoreCheckpoint(ctx, req.ContainerId, req.CheckpointId, vol.Mountpoint); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "restore checkpoint: %v", err)
+	}
+	return &pb.Empty{}, nil
+}
+
+// StartContainer starts a created container
+func (gs *GRPCServer) StartContainer(ctx context.Context, req *pb.ContainerStartRequest) (*pb.Empty, error) {
+	if err := gs.allow(OpReceiveContainers); err != nil {
+		return nil, err
+	}
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.ContainerId == "" {
+		return nil, status.Error(codes.InvalidArgument, "container is required")
+	}
+	if err := gs.docker.StartContainer(ctx, req.ContainerId); err != nil {
+		return nil, status.Errorf(codes.Internal, "start container: %v", err)
+	}
+	return &pb.Empty{}, nil
+}
+
+// CheckpointSupport asks the peer why it cannot restore checkpoints; an
+// empty reason means it can
+func (gc *GRPCClient) CheckpointSupport(ctx context.Context) (string, error) {
+	resp, err := gc.client.CheckpointSupport(ctx, &pb.Empty{})
+	if err != nil {
+		return "", peerCallError("restore checkpoints", err)
+	}
+	return resp.Unsupported, nil
+}
+
+// RestoreContainer asks the peer to start a container from a checkpoint in
+// a volume already sent to it
+func (gc *GRPCClient) RestoreContainer(ctx context.Context, req *pb.ContainerRestoreRequest) error {
+	if _, err := gc.client.RestoreContainer(ctx, req); err != nil {
+		return peerCallError("restore checkpoints", err)
+	}
+	return nil
+}
+
+// StartContainer asks the peer to start a container it created
+func (gc *GRPCClient) StartContainer(ctx context.Context, containerID string) error {
+	if _, err := gc.client.StartContainer(ctx, &pb.ContainerStartRequest{ContainerId: containerID}); err != nil {
+		return peerCallError("start containers", err)
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/observability"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/hkdf"
@@ -29,29 +30,37 @@ type CryptoManager struct {
 	certificate   *x509.Certificate
 	certPEM       []byte
 	trustedCerts  map[string]*x509.Certificate
+	tofu          bool                    // record unknown peers for confirmation
+	pending       map[string]*PendingPeer // unknown peers seen while tofu is on
 	certPath      string
 	keyPath       string
 	logger        *observability.Logger
 	mu            sync.RWMutex
 }
 
-// NewCryptoManager creates a new crypto manager
-func NewCryptoManager(logger *observability.Logger, certDir string) (*CryptoManager, error) {
-	if certDir == "" {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
-		}
-		certDir = filepath.Join(homeDir, ".docker-migrate", "certs")
+// NewCryptoManager creates a crypto manager keeping its certificate under
+// dataDir/certs (default ~/.docker-migrate/certs)
+func NewCryptoManager(logger *observability.Logger, dataDir string) (*CryptoManager, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
 	}
+	certDir := filepath.Join(dataDir, "certs")
 
 	// Ensure cert directory exists
 	if err := os.MkdirAll(certDir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create cert directory: %w", err)
 	}
 
+	// Earlier versions kept the certificate directly in a custom data_dir;
+	// move it so the node keeps its fingerprint and its pairings
+	if err := moveLegacyCerts(dataDir, certDir, logger); err != nil {
+		return nil, err
+	}
+
 	cm := &CryptoManager{
 		trustedCerts: make(map[string]*x509.Certificate),
+		pending:      make(map[string]*PendingPeer),
 		certPath:     filepath.Join(certDir, "server.crt"),
 		keyPath:      filepath.Join(certDir, "server.key"),
 		logger:       logger,
@@ -69,6 +78,61 @@ func NewCryptoManager(logger *observability.Logger, certDir string) (*CryptoMana
 	return cm, nil
 }
 
+// ReadCertificate returns the certificate NewCryptoManager keeps under
+// dataDir without generating one when there is none
+func ReadCertificate(dataDir string) (*x509.Certificate, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM, err := os.ReadFile(filepath.Join(dataDir, "certs", "server.crt"))
+	if os.IsNotExist(err) {
+		certPEM, err = os.ReadFile(filepath.Join(dataDir, "server.crt"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// moveLegacyCerts moves server.crt and server.key from dataDir into certDir
+// unless certDir already holds a certificate
+func moveLegacyCerts(dataDir, certDir string, logger *observability.Logger) error {
+	legacyCert := filepath.Join(dataDir, "server.crt")
+	legacyKey := filepath.Join(dataDir, "server.key")
+	if _, err := os.Stat(legacyCert); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(legacyKey); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(certDir, "server.crt")); err == nil {
+		return nil
+	}
+
+	// Key first: a crash between the renames leaves the certificate behind,
+	// and the next start finishes the move
+	if err := os.Rename(legacyKey, filepath.Join(certDir, "server.key")); err != nil {
+		return fmt.Errorf("failed to move key into %s: %w", certDir, err)
+	}
+	if err := os.Rename(legacyCert, filepath.Join(certDir, "server.crt")); err != nil {
+		return fmt.Errorf("failed to move certificate into %s: %w", certDir, err)
+	}
+
+	logger.Info("moved certificate into data directory", zap.String("cert_dir", certDir))
+	return nil
+}
+
 // loadOrGenerateKeypair loads existing keypair or generates a new one
 func (cm *CryptoManager) loadOrGenerateKeypair() error {
 	// Check if cert and key exist
@@ -233,9 +297,13 @@ func (cm *CryptoManager) DeriveSessionKey(spakeSecret []byte, salt []byte) ([]by
 	if len(spakeSecret) == 0 {
 		return nil, fmt.Errorf("spake secret cannot be empty")
 	}
+	return deriveSessionKey(spakeSecret, salt)
+}
 
-	// Use HKDF-SHA256 to derive 32-byte key
-	hkdfReader := hkdf.New(sha256.New, spakeSecret, salt, []byte("docker-migrate-session-key-v1"))
+// deriveSessionKey derives a 32-byte key from a shared secret with
+// HKDF-SHA256
+func deriveSessionKey(secret []byte, salt []byte) ([]byte, error) {
+	hkdfReader := hkdf.New(sha256.New, secret, salt, []byte("docker-migrate-session-key-v1"))
 	sessionKey := make([]byte, 32)
 
 	if _, err := hkdfReader.Read(sessionKey); err != nil {
@@ -314,8 +382,65 @@ func (cm *CryptoManager) TLSClientConfig(expectedFingerprint string) (*tls.Confi
 	return config, nil
 }
 
+// captureClientTLSConfig returns a client TLS configuration that accepts any
+// server certificate and passes it to capture. Only use it where the caller
+// checks the certificate itself afterwards.
+func (cm *CryptoManager) captureClientTLSConfig(capture func(*x509.Certificate)) (*tls.Config, error) {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	if cm.certificate == nil || cm.privateKey == nil {
+		return nil, fmt.Errorf("certificate or private key not initialized")
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{cm.certificate.Raw},
+		PrivateKey:  cm.privateKey,
+	}
+
+	config := &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: true, // The caller verifies the captured certificate
+		MinVersion:         tls.VersionTLS13,
+		NextProtos:         []string{"h2"},
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("no peer certificate provided")
+			}
+			peerCert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return fmt.Errorf("failed to parse peer certificate: %w", err)
+			}
+			capture(peerCert)
+			return nil
+		},
+	}
+
+	return config, nil
+}
+
+// TLSConfigAllowPairing returns the server TLS configuration for the peer
+// gRPC port. Unknown client certificates complete the handshake so they can
+// call Pair; every other call checks trust itself.
+func (cm *CryptoManager) TLSConfigAllowPairing() (*tls.Config, error) {
+	config, err := cm.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		return cm.checkPeerCertificate(rawCerts, true)
+	}
+	return config, nil
+}
+
 // verifyPeerCertificate verifies peer certificate against trusted store
 func (cm *CryptoManager) verifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return cm.checkPeerCertificate(rawCerts, false)
+}
+
+// checkPeerCertificate checks a peer certificate is valid and, unless
+// allowUntrusted is set, that it is in the trusted store
+func (cm *CryptoManager) checkPeerCertificate(rawCerts [][]byte, allowUntrusted bool) error {
 	if len(rawCerts) == 0 {
 		return fmt.Errorf("no peer certificate provided")
 	}
@@ -340,9 +465,20 @@ func (cm *CryptoManager) verifyPeerCertificate(rawCerts [][]byte, verifiedChains
 
 	// Check against trusted certs
 	cm.mu.RLock()
-	defer cm.mu.RUnlock()
+	_, trusted := cm.trustedCerts[fingerprint]
+	tofu := cm.tofu
+	cm.mu.RUnlock()
 
-	if _, trusted := cm.trustedCerts[fingerprint]; !trusted {
+	if !trusted {
+		if tofu {
+			cm.recordFirstUse(cert, "")
+		}
+		if allowUntrusted {
+			return nil
+		}
+		if tofu {
+			return fmt.Errorf("peer certificate awaiting trust-on-first-use confirmation: %s", fingerprint)
+		}
 		return fmt.Errorf("peer certificate not in trusted store: %s", fingerprint)
 	}
 
@@ -371,6 +507,23 @@ func (cm *CryptoManager) AddTrustedCert(cert *x509.Certificate) error {
 	return nil
 }
 
+// TrustFingerprint pins a fingerprint for which no certificate is known yet,
+// as for statically declared peers. Reports whether it was not trusted before.
+func (cm *CryptoManager) TrustFingerprint(fingerprint string) bool {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if _, ok := cm.trustedCerts[fingerprint]; ok {
+		return false
+	}
+	cm.trustedCerts[fingerprint] = nil
+
+	cm.logger.Info("pinned trusted fingerprint",
+		zap.String("fingerprint", fingerprint),
+	)
+	return true
+}
+
 // RemoveTrustedCert removes a certificate from the trusted store
 func (cm *CryptoManager) RemoveTrustedCert(fingerprint string) {
 	cm.mu.Lock()
@@ -427,43 +580,56 @@ func ComputeFingerprintFromPEM(certPEM []byte) (string, error) {
 	return ComputeFingerprint(cert), nil
 }
 
-// HashPassword creates a SHA-256 hash suitable for SPAKE2+
-func HashPassword(password string) []byte {
-	hash := sha256.Sum256([]byte(password))
-	return hash[:]
-}
-
-// GetServerTLSConfig returns TLS configuration for server (alias for TLSConfig)
-func (cm *CryptoManager) GetServerTLSConfig() (*tls.Config, error) {
-	return cm.TLSConfig()
-}
-
-// GetClientTLSConfig returns TLS configuration for client without fingerprint verification
-// This is used when connecting to servers where fingerprint is not known in advance
-func (cm *CryptoManager) GetClientTLSConfig() (*tls.Config, error) {
+// Sign signs the SHA-256 digest of data with this node's private key,
+// returning an ASN.1 ECDSA signature
+func (cm *CryptoManager) Sign(data []byte) ([]byte, error) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
 
-	if cm.certificate == nil || cm.privateKey == nil {
-		return nil, fmt.Errorf("certificate or private key not initialized")
+	if cm.privateKey == nil {
+		return nil, fmt.Errorf("private key not initialized")
 	}
+	digest := sha256.Sum256(data)
+	return ecdsa.SignASN1(rand.Reader, cm.privateKey, digest[:])
+}
 
-	cert := tls.Certificate{
-		Certificate: [][]byte{cm.certificate.Raw},
-		PrivateKey:  cm.privateKey,
+// VerifySignature checks a signature made by Sign against the key in a
+// PEM-encoded certificate, and returns the certificate's fingerprint
+func VerifySignature(certPEM, data, signature []byte) (string, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return "", fmt.Errorf("failed to parse certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse certificate: %w", err)
 	}
 
-	config := &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		InsecureSkipVerify: false, // Will be set by caller if needed
-		MinVersion:         tls.VersionTLS13,
+	key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("certificate key is not ECDSA")
 	}
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(key, digest[:], signature) {
+		return "", fmt.Errorf("signature does not match")
+	}
+	return ComputeFingerprint(cert), nil
+}
 
-	return config, nil
+// HashPassword creates a SHA-256 hash suitable for SPAKE2+
+func HashPassword(password string) []byte {
+	hash := sha256.Sum256([]byte(password))
+	return hash[:]
+}
+
+// GetServerTLSConfig returns TLS configuration for server (alias for TLSConfig)
+func (cm *CryptoManager) GetServerTLSConfig() (*tls.Config, error) {
+	return cm.TLSConfig()
 }
 
 // TLSConfigNoClientAuth returns TLS configuration for server that doesn't require client certs
-// This is used for master-worker architecture where auth is via enrollment tokens
+// This is used for master-worker architecture where auth is via enrollment tokens.
+// Certificates clients do present are kept unverified, to identify rendezvous callers.
 func (cm *CryptoManager) TLSConfigNoClientAuth() (*tls.Config, error) {
 	cm.mu.RLock()
 	defer cm.mu.RUnlock()
@@ -486,7 +652,7 @@ func (cm *CryptoManager) TLSConfigNoClientAuth() (*tls.Config, error) {
 			tls.TLS_AES_128_GCM_SHA256,
 		},
 		PreferServerCipherSuites: true,
-		ClientAuth:               tls.NoClientCert, // Don't require client certs
+		ClientAuth:               tls.RequestClientCert, // Don't require client certs
 	}
 
 	return config, nil
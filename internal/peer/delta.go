@@ -0,0 +1,212 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// volumeDelta marks a volume stream as a tar of changed files to merge
+// into the peer's copy rather than a whole volume
+type volumeDelta struct {
+	deleted []string // Removed from the peer's copy before merging
+	patch   bool     // The tar is a patch of file ranges, written in place
+}
+
+const (
+	// volumeIndexBatch is how many files go in one VolumeIndex message
+	volumeIndexBatch = 1000
+
+	// minVolumeRangeSize is the smallest range size a file index is hashed
+	// in, so a request cannot make the index larger than the volume
+	minVolumeRangeSize = 64 * 1024
+)
+
+// volumeIndexCache keeps the last file index of each volume, so indexing it
+// again only re-hashes files whose size or mtime changed. A volume's entry
+// is dropped whenever this node writes to it: imported files keep the
+// sender's mtimes, so a changed file could otherwise look unchanged.
+type volumeIndexCache struct {
+	mu      sync.Mutex
+	indexes map[string]docker.FileIndex
+}
+
+func (c *volumeIndexCache) get(volume string) docker.FileIndex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.indexes[volume]
+}
+
+func (c *volumeIndexCache) put(volume string, index docker.FileIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.indexes == nil {
+		c.indexes = make(map[string]docker.FileIndex)
+	}
+	c.indexes[volume] = index
+}
+
+func (c *volumeIndexCache) invalidate(volume string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.indexes, volume)
+}
+
+// GetVolumeIndex streams the file index of one of this host's volumes, so a
+// sender can work out which files a warm sync needs to send, or with a range
+// size which ranges of them a cold transfer does. A missing volume has an
+// empty index.
+func (gs *GRPCServer) GetVolumeIndex(req *pb.VolumeIndexRequest, stream pb.MigrationService_GetVolumeIndexServer) error {
+	ctx := stream.Context()
+	if gs.docker == nil {
+		return status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.VolumeId == "" {
+		return status.Error(codes.InvalidArgument, "volume_id is required")
+	}
+	if req.RangeSize != 0 && req.RangeSize < minVolumeRangeSize {
+		return status.Errorf(codes.InvalidArgument, "range_size must be at least %d", minVolumeRangeSize)
+	}
+
+	if _, err := gs.docker.InspectVolume(ctx, req.VolumeId); err != nil {
+		gs.volumeIndexes.invalidate(req.VolumeId)
+		return stream.Send(&pb.VolumeIndex{Exists: false})
+	}
+
+	index, err := gs.docker.IndexVolume(ctx, req.VolumeId, gs.volumeIndexes.get(req.VolumeId), req.RangeSize)
+	if err != nil {
+		return status.Errorf(codes.Internal, "index volume: %v", err)
+	}
+	gs.volumeIndexes.put(req.VolumeId, index)
+
+	files := fileIndexToProto(index)
+	for {
+		batch := files
+		if len(batch) > volumeIndexBatch {
+			batch = batch[:volumeIndexBatch]
+		}
+		if err := stream.Send(&pb.VolumeIndex{Exists: true, Files: batch}); err != nil {
+			return err
+		}
+		files = files[len(batch):]
+		if len(files) == 0 {
+			return nil
+		}
+	}
+}
+
+// applyVolumeDelta removes deleted paths from a volume and merges in the
+// changed files spooled to file, or writes the ranges of a patch
+func (gs *GRPCServer) applyVolumeDelta(ctx context.Context, volumeID string, file *os.File, deleted []string, patch bool) error {
+	if gs.docker == nil {
+		return fmt.Errorf("docker is not available")
+	}
+	defer gs.volumeIndexes.invalidate(volumeID)
+
+	// Deletions go first: a path that turned from a file into a directory,
+	// or back, is removed and then sent again
+	if err := gs.docker.RemoveVolumePaths(ctx, volumeID, deleted); err != nil {
+		return err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind delta: %w", err)
+	}
+	var err error
+	if patch {
+		err = gs.docker.PatchVolume(ctx, volumeID, file)
+	} else {
+		err = gs.docker.ImportVolume(ctx, volumeID, file)
+	}
+	if err != nil {
+		return err
+	}
+
+	gs.logger.Info("applied volume delta",
+		zap.String("volume_id", volumeID),
+		zap.Int("deleted", len(deleted)),
+		zap.Bool("patch", patch),
+	)
+	return nil
+}
+
+// GetVolumeIndex fetches the peer's file index of a volume. exists is false
+// if the peer has no such volume.
+func (gc *GRPCClient) GetVolumeIndex(ctx context.Context, volumeID string) (index docker.FileIndex, exists bool, err error) {
+	return gc.GetVolumeRangeIndex(ctx, volumeID, 0)
+}
+
+// GetVolumeRangeIndex fetches the peer's file index of a volume with each
+// file also hashed in ranges of rangeSize bytes
+func (gc *GRPCClient) GetVolumeRangeIndex(ctx context.Context, volumeID string, rangeSize int64) (index docker.FileIndex, exists bool, err error) {
+	stream, err := gc.client.GetVolumeIndex(ctx, &pb.VolumeIndexRequest{VolumeId: volumeID, RangeSize: rangeSize})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get volume index: %w", err)
+	}
+
+	index = make(docker.FileIndex)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return index, exists, nil
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to receive volume index: %w", err)
+		}
+		exists = resp.Exists
+		addFilesFromProto(index, resp.Files)
+	}
+}
+
+// SendVolumeDelta streams a tar of changed files for the peer to merge into
+// its copy of a volume, after removing the deleted paths. The peer creates
+// the volume if it does not have it yet.
+func (gc *GRPCClient) SendVolumeDelta(ctx context.Context, volumeID string, reader io.Reader, totalSize int64, deleted []string) error {
+	return gc.sendVolume(ctx, volumeID, reader, totalSize, &volumeDelta{deleted: deleted}, nil)
+}
+
+// SendVolumePatch streams a patch of file ranges for the peer to write into
+// its copy of a volume, after removing the deleted paths. Like any volume
+// stream it is checkpointed, resumed after a reconnect, and has corrupted
+// chunks sent again.
+func (gc *GRPCClient) SendVolumePatch(ctx context.Context, volumeID string, reader io.Reader, totalSize int64, deleted []string) error {
+	return gc.sendVolume(ctx, volumeID, reader, totalSize, &volumeDelta{deleted: deleted, patch: true}, nil)
+}
+
+func fileIndexToProto(index docker.FileIndex) []*pb.VolumeFile {
+	files := make([]*pb.VolumeFile, 0, len(index))
+	for _, meta := range index {
+		files = append(files, &pb.VolumeFile{
+			Path:    meta.Path,
+			Mode:    uint32(meta.Mode),
+			Size:    meta.Size,
+			ModTime: meta.ModTime,
+			Hash:    meta.Hash,
+			Dir:     meta.Dir,
+			Ranges:  meta.Ranges,
+		})
+	}
+	return files
+}
+
+func addFilesFromProto(index docker.FileIndex, files []*pb.VolumeFile) {
+	for _, f := range files {
+		index[f.Path] = docker.FileMeta{
+			Path:    f.Path,
+			Mode:    os.FileMode(f.Mode),
+			Size:    f.Size,
+			ModTime: f.ModTime,
+			Hash:    f.Hash,
+			Dir:     f.Dir,
+			Ranges:  f.Ranges,
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// resolveBindAddress returns the local IP transfer connections should originate from.
+// An explicit address wins over an interface name; nil means let the OS choose.
+func resolveBindAddress(address, iface string, remoteIsIPv6 bool) (net.IP, error) {
+	if address != "" {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid transfer bind address: %s", address)
+		}
+		return ip, nil
+	}
+	if iface == "" {
+		return nil, nil
+	}
+
+	nic, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("transfer interface %s: %w", iface, err)
+	}
+	addrs, err := nic.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses on %s: %w", iface, err)
+	}
+
+	// Pick the first usable address of the same family as the remote
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipNet.IP.To4() == nil) == remoteIsIPv6 {
+			return ipNet.IP, nil
+		}
+	}
+
+	return nil, fmt.Errorf("interface %s has no usable address", iface)
+}
+
+// TransferDialOptions returns gRPC dial options that bind outbound transfer
+// connections to the configured local address or interface, so bulk traffic can be
+// kept on e.g. a storage VLAN. Control-plane connections should not use these.
+func (tm *TransferManager) TransferDialOptions() []grpc.DialOption {
+	if tm == nil {
+		return nil
+	}
+
+	address, iface := tm.config.TransferBindAddress, tm.config.TransferInterface
+	if address == "" && iface == "" {
+		return nil
+	}
+
+	dialer := func(ctx context.Context, target string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target address %s: %w", target, err)
+		}
+
+		remoteIsIPv6 := false
+		if ip := net.ParseIP(host); ip != nil {
+			remoteIsIPv6 = ip.To4() == nil
+		}
+
+		localIP, err := resolveBindAddress(address, iface, remoteIsIPv6)
+		if err != nil {
+			return nil, err
+		}
+
+		d := net.Dialer{}
+		if localIP != nil {
+			d.LocalAddr = &net.TCPAddr{IP: localIP}
+		}
+		return d.DialContext(ctx, "tcp", target)
+	}
+
+	return []grpc.DialOption{grpc.WithContextDialer(dialer)}
+}
@@ -3,14 +3,19 @@ package peer
 import (
 	"context"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/observability"
+	pb "github.com/artemis/docker-migrate/proto"
 	"go.uber.org/zap"
 )
 
+// AddressProbeTimeout bounds how long each candidate address is tried
+const AddressProbeTimeout = 3 * time.Second
+
 // PeerStatus represents the online/offline status of a peer
 type PeerStatus int
 
@@ -41,6 +46,9 @@ const (
 	ConnectionTailscale
 	ConnectionWireGuard
 	ConnectionTURN
+	ConnectionSSH
+	ConnectionNAT   // Punched UDP path
+	ConnectionRelay // Relayed by the rendezvous master
 )
 
 func (ct ConnectionType) String() string {
@@ -53,6 +61,12 @@ func (ct ConnectionType) String() string {
 		return "wireguard"
 	case ConnectionTURN:
 		return "turn"
+	case ConnectionSSH:
+		return "ssh"
+	case ConnectionNAT:
+		return "nat"
+	case ConnectionRelay:
+		return "relay"
 	default:
 		return "unknown"
 	}
@@ -68,6 +82,19 @@ type Peer struct {
 	Connection   ConnectionType
 	Latency      time.Duration
 	Fingerprint  string
+	VolumeDrivers []string
+
+	// ClockSkew is how far the peer's clock is ahead of ours, measured at the
+	// last ping; ClockSkewKnown is false until a peer that reports its time answers
+	ClockSkew      time.Duration
+	ClockSkewKnown bool
+
+	// Addresses holds every known address in preference order; Address is the one
+	// that last answered and is tried first
+	Addresses []string
+
+	// SSHTunnel is the peer's own tunnel; the global ssh_tunnel applies without one
+	SSHTunnel *config.SSHTunnelConfig
 }
 
 // PeerDiscovery handles peer discovery and health checking
@@ -77,20 +104,26 @@ type PeerDiscovery struct {
 	config       *config.Config
 	pairing      *PairingManager
 	crypto       *CryptoManager
+	nat          *natTraversal // Set when nat_traversal is configured
+	inbound      func(net.Conn)
+	nearby       map[string]*NearbyPeer // Seen over mDNS, by fingerprint; nil unless mdns is on
+	dnssdPeers   map[string]string      // Registered from DNS-SD: peer ID to SRV target
 	logger       *observability.Logger
 	mu           sync.RWMutex
 	ctx          context.Context
 	cancel       context.CancelFunc
 }
 
-// NewPeerDiscovery creates a new peer discovery service
+// NewPeerDiscovery creates a new peer discovery service. Its background
+// checks stop when ctx is cancelled or Stop is called.
 func NewPeerDiscovery(
+	ctx context.Context,
 	cfg *config.Config,
 	pairing *PairingManager,
 	crypto *CryptoManager,
 	logger *observability.Logger,
 ) *PeerDiscovery {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 
 	localPeer := &Peer{
 		ID:          fmt.Sprintf("local-%s", crypto.GetFingerprint()[:8]),
@@ -112,16 +145,27 @@ func NewPeerDiscovery(
 		cancel:     cancel,
 	}
 
+	if cfg.NATTraversal != nil {
+		nat, err := newNATTraversal(cfg.NATTraversal, crypto, logger)
+		if err != nil {
+			logger.Warn("nat traversal disabled", zap.Error(err))
+		} else {
+			pd.nat = nat
+		}
+	}
+
 	// Load trusted peers
 	for _, trustedPeer := range pairing.ListTrustedPeers() {
 		pd.knownPeers[trustedPeer.ID] = &Peer{
 			ID:          trustedPeer.ID,
 			Name:        trustedPeer.Name,
 			Address:     trustedPeer.Address,
+			Addresses:   peerAddresses(trustedPeer),
 			Status:      PeerOffline,
 			LastSeen:    trustedPeer.LastSeen,
-			Connection:  ConnectionDirect,
+			Connection:  pd.connectionType(trustedPeer.SSHTunnel),
 			Fingerprint: trustedPeer.Fingerprint,
+			SSHTunnel:   trustedPeer.SSHTunnel,
 		}
 	}
 
@@ -135,13 +179,28 @@ func (pd *PeerDiscovery) Start(ctx context.Context) error {
 	// Start health check goroutine
 	go pd.StartHealthCheck(pd.ctx)
 
+	// Stay reachable through the rendezvous master for peers that cannot
+	// reach this node directly
+	if pd.nat != nil && pd.inbound != nil {
+		go pd.nat.listen(pd.ctx, pd.inbound)
+	}
+
 	return nil
 }
 
+// SetInboundHandler sets what serves connections peers open through NAT
+// traversal; call it before Start
+func (pd *PeerDiscovery) SetInboundHandler(serve func(net.Conn)) {
+	pd.inbound = serve
+}
+
 // Stop stops the discovery service
 func (pd *PeerDiscovery) Stop() error {
 	pd.logger.Info("stopping peer discovery service")
 	pd.cancel()
+	if pd.nat != nil {
+		pd.nat.Close()
+	}
 	return nil
 }
 
@@ -154,10 +213,12 @@ func (pd *PeerDiscovery) RegisterPeer(trustedPeer *TrustedPeer) error {
 		ID:          trustedPeer.ID,
 		Name:        trustedPeer.Name,
 		Address:     trustedPeer.Address,
+		Addresses:   peerAddresses(trustedPeer),
 		Status:      PeerOffline,
 		LastSeen:    trustedPeer.LastSeen,
-		Connection:  ConnectionDirect,
+		Connection:  pd.connectionType(trustedPeer.SSHTunnel),
 		Fingerprint: trustedPeer.Fingerprint,
+		SSHTunnel:   trustedPeer.SSHTunnel,
 	}
 
 	pd.knownPeers[trustedPeer.ID] = peer
@@ -242,31 +303,245 @@ func (pd *PeerDiscovery) checkPeerHealth() {
 
 // checkSinglePeer checks health of a single peer
 func (pd *PeerDiscovery) checkSinglePeer(peer *Peer) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	timeout := 5 * time.Second
+	if pd.nat != nil {
+		timeout += NATTraversalTimeout
+	}
+	ctx, cancel := context.WithTimeout(pd.ctx, timeout)
 	defer cancel()
 
-	// Try to create client and ping
-	client, err := NewGRPCClient(
-		peer.Address,
-		peer.Fingerprint,
-		nil, // No transfer manager needed for ping
-		pd.crypto,
-		pd.logger,
-	)
+	// Try each address until one answers; no transfer manager needed for ping
+	client, pong, latency, err := pd.dialAddresses(ctx, peer, nil)
 	if err != nil {
 		pd.updatePeerStatus(peer.ID, PeerOffline, 0)
 		return
 	}
 	defer client.Close()
 
-	_, latency, err := client.Ping(ctx)
+	pd.updatePeerVolumeDrivers(peer.ID, pong.VolumeDrivers)
+	pd.updatePeerClockSkew(peer.ID, client)
+	pd.updatePeerStatus(peer.ID, PeerOnline, latency)
+	pd.pairing.UpdatePeerLastSeen(peer.ID)
+}
+
+// ProbePeer pings a peer now, updating its status, and returns the round-trip latency
+func (pd *PeerDiscovery) ProbePeer(ctx context.Context, peerID string) (time.Duration, error) {
+	peer, ok := pd.GetPeer(peerID)
+	if !ok {
+		return 0, fmt.Errorf("peer not found: %s", peerID)
+	}
+
+	client, pong, latency, err := pd.dialAddresses(ctx, peer, nil)
 	if err != nil {
-		pd.updatePeerStatus(peer.ID, PeerOffline, 0)
+		pd.updatePeerStatus(peerID, PeerOffline, 0)
+		return 0, err
+	}
+	defer client.Close()
+
+	pd.updatePeerVolumeDrivers(peerID, pong.VolumeDrivers)
+	pd.updatePeerClockSkew(peerID, client)
+	pd.updatePeerStatus(peerID, PeerOnline, latency)
+	pd.pairing.UpdatePeerLastSeen(peerID)
+
+	return latency, nil
+}
+
+// sshTunnel returns the tunnel used to reach peer, if any
+func (pd *PeerDiscovery) sshTunnel(peer *Peer) *config.SSHTunnelConfig {
+	if peer.SSHTunnel != nil {
+		return peer.SSHTunnel
+	}
+	return pd.config.SSHTunnel
+}
+
+// connectionType reports how a peer with the given tunnel is reached
+func (pd *PeerDiscovery) connectionType(tunnel *config.SSHTunnelConfig) ConnectionType {
+	if tunnel != nil || pd.config.SSHTunnel != nil {
+		return ConnectionSSH
+	}
+	return ConnectionDirect
+}
+
+// peerAddresses returns a trusted peer's addresses in preference order without duplicates
+func peerAddresses(tp *TrustedPeer) []string {
+	seen := make(map[string]bool)
+	addrs := make([]string, 0, 1+len(tp.Addresses))
+	for _, addr := range append([]string{tp.Address}, tp.Addresses...) {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// dialAddresses tries the peer's last working address first, then the rest in order,
+// and returns a client for the first address that answers a ping
+func (pd *PeerDiscovery) dialAddresses(ctx context.Context, peer *Peer, transfer *TransferManager) (*GRPCClient, *pb.Pong, time.Duration, error) {
+	pd.mu.RLock()
+	candidates := make([]string, 0, 1+len(peer.Addresses))
+	if peer.Address != "" {
+		candidates = append(candidates, peer.Address)
+	}
+	for _, addr := range peer.Addresses {
+		if addr != peer.Address {
+			candidates = append(candidates, addr)
+		}
+	}
+	fingerprint := peer.Fingerprint
+	tunnel := pd.sshTunnel(peer)
+	pd.mu.RUnlock()
+
+	if len(candidates) == 0 && (pd.nat == nil || tunnel != nil) {
+		return nil, nil, 0, fmt.Errorf("peer %s has no addresses", peer.ID)
+	}
+
+	var lastErr error
+	for _, addr := range candidates {
+		client, err := NewGRPCClient(addr, fingerprint, tunnel, transfer, pd.crypto, pd.logger)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, AddressProbeTimeout)
+		pong, latency, err := client.Ping(probeCtx)
+		cancel()
+		if err != nil {
+			client.Close()
+			lastErr = err
+			pd.logger.Debug("peer address unreachable",
+				zap.String("peer_id", peer.ID),
+				zap.String("address", addr),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		pd.setPreferredAddress(peer.ID, addr)
+		pd.setConnectionType(peer.ID, pd.connectionType(peer.SSHTunnel))
+		return client, pong, latency, nil
+	}
+
+	// Traversal replaces a tunnel, so it is only tried without one
+	if pd.nat != nil && tunnel == nil {
+		client, pong, latency, err := pd.dialNAT(ctx, peer, fingerprint, transfer)
+		if err == nil {
+			return client, pong, latency, nil
+		}
+		lastErr = err
+	}
+
+	return nil, nil, 0, fmt.Errorf("all %d addresses unreachable: %w", len(candidates), lastErr)
+}
+
+// dialNAT reaches a peer through the rendezvous master, punching a UDP hole
+// or relaying, and returns a client once the peer answers a ping
+func (pd *PeerDiscovery) dialNAT(ctx context.Context, peer *Peer, fingerprint string, transfer *TransferManager) (*GRPCClient, *pb.Pong, time.Duration, error) {
+	traversalCtx, cancel := context.WithTimeout(ctx, NATTraversalTimeout)
+	defer cancel()
+
+	conn, kind, err := pd.nat.dial(traversalCtx, fingerprint)
+	if err != nil {
+		pd.logger.Debug("nat traversal failed",
+			zap.String("peer_id", peer.ID),
+			zap.Error(err),
+		)
+		return nil, nil, 0, err
+	}
+
+	client, err := newGRPCClientOverConn(conn, fingerprint, transfer, pd.crypto, pd.logger)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	pong, latency, err := client.Ping(traversalCtx)
+	if err != nil {
+		client.Close()
+		return nil, nil, 0, err
+	}
+
+	pd.setConnectionType(peer.ID, kind)
+	return client, pong, latency, nil
+}
+
+// Connect opens a transfer client to a peer, falling back through its addresses
+func (pd *PeerDiscovery) Connect(ctx context.Context, peerID string, transfer *TransferManager) (*GRPCClient, error) {
+	peer, ok := pd.GetPeer(peerID)
+	if !ok {
+		return nil, fmt.Errorf("peer not found: %s", peerID)
+	}
+
+	client, _, _, err := pd.dialAddresses(ctx, peer, transfer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to peer %s: %w", peerID, err)
+	}
+	pd.updatePeerClockSkew(peerID, client)
+	return client, nil
+}
+
+// setPreferredAddress makes addr the first address tried for a peer
+func (pd *PeerDiscovery) setPreferredAddress(peerID, addr string) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	peer, ok := pd.knownPeers[peerID]
+	if !ok || peer.Address == addr {
 		return
 	}
 
-	pd.updatePeerStatus(peer.ID, PeerOnline, latency)
-	pd.pairing.UpdatePeerLastSeen(peer.ID)
+	pd.logger.Info("peer address changed",
+		zap.String("peer_id", peerID),
+		zap.String("old_address", peer.Address),
+		zap.String("new_address", addr),
+	)
+	peer.Address = addr
+}
+
+// setConnectionType records how a peer was last reached
+func (pd *PeerDiscovery) setConnectionType(peerID string, kind ConnectionType) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	if peer, ok := pd.knownPeers[peerID]; ok {
+		peer.Connection = kind
+	}
+}
+
+// updatePeerVolumeDrivers records the volume drivers a peer advertised
+func (pd *PeerDiscovery) updatePeerVolumeDrivers(peerID string, drivers []string) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	if peer, ok := pd.knownPeers[peerID]; ok {
+		peer.VolumeDrivers = drivers
+	}
+}
+
+// updatePeerClockSkew records the clock skew client measured, warning when
+// it first exceeds ClockSkewWarnThreshold
+func (pd *PeerDiscovery) updatePeerClockSkew(peerID string, client *GRPCClient) {
+	skew, ok := client.ClockSkew()
+	if !ok {
+		return
+	}
+
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	peer, found := pd.knownPeers[peerID]
+	if !found {
+		return
+	}
+	wasExceeded := peer.ClockSkewKnown && ClockSkewExceeded(peer.ClockSkew)
+	peer.ClockSkew = skew
+	peer.ClockSkewKnown = true
+
+	if ClockSkewExceeded(skew) && !wasExceeded {
+		pd.logger.Warn("peer clock differs from ours; compare timestamps with care",
+			zap.String("peer_id", peerID),
+			zap.Duration("clock_skew", skew),
+		)
+	}
 }
 
 // updatePeerStatus updates the status of a peer
@@ -0,0 +1,85 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetDiskUsage reports this host's Docker disk usage to a peer
+func (gs *GRPCServer) GetDiskUsage(ctx context.Context, req *pb.Empty) (*pb.DiskUsageReport, error) {
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+
+	summary, err := gs.docker.SystemDF(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get disk usage: %v", err)
+	}
+
+	return DiskUsageToProto(summary), nil
+}
+
+// GetDiskUsage fetches the peer's Docker disk usage
+func (gc *GRPCClient) GetDiskUsage(ctx context.Context) (*docker.DiskUsageSummary, error) {
+	report, err := gc.client.GetDiskUsage(ctx, &pb.Empty{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer disk usage: %w", err)
+	}
+	return DiskUsageFromProto(report), nil
+}
+
+// DiskUsageToProto converts a disk usage summary to its wire form
+func DiskUsageToProto(s *docker.DiskUsageSummary) *pb.DiskUsageReport {
+	if s == nil {
+		return nil
+	}
+	return &pb.DiskUsageReport{
+		Images:        categoryToProto(s.Images),
+		Containers:    categoryToProto(s.Containers),
+		Volumes:       categoryToProto(s.Volumes),
+		BuildCache:    categoryToProto(s.BuildCache),
+		DiskTotal:     s.DiskTotal,
+		DiskAvailable: s.DiskAvailable,
+	}
+}
+
+// DiskUsageFromProto converts a wire disk usage report to a summary
+func DiskUsageFromProto(r *pb.DiskUsageReport) *docker.DiskUsageSummary {
+	if r == nil {
+		return nil
+	}
+	return &docker.DiskUsageSummary{
+		Images:        categoryFromProto(r.Images),
+		Containers:    categoryFromProto(r.Containers),
+		Volumes:       categoryFromProto(r.Volumes),
+		BuildCache:    categoryFromProto(r.BuildCache),
+		DiskTotal:     r.DiskTotal,
+		DiskAvailable: r.DiskAvailable,
+	}
+}
+
+func categoryToProto(c docker.DiskUsageCategory) *pb.DiskUsageCategory {
+	return &pb.DiskUsageCategory{
+		Count:       int32(c.Count),
+		Active:      int32(c.Active),
+		Size:        c.Size,
+		Reclaimable: c.Reclaimable,
+	}
+}
+
+func categoryFromProto(c *pb.DiskUsageCategory) docker.DiskUsageCategory {
+	if c == nil {
+		return docker.DiskUsageCategory{}
+	}
+	return docker.DiskUsageCategory{
+		Count:       int(c.Count),
+		Active:      int(c.Active),
+		Size:        c.Size,
+		Reclaimable: c.Reclaimable,
+	}
+}
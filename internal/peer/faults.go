@@ -0,0 +1,111 @@
+package peer
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FaultInjector injects corruption, disconnects and latency into chunk transfers.
+// A nil injector is valid and injects nothing.
+type FaultInjector struct {
+	cfg    config.FaultInjectionConfig
+	rng    *rand.Rand
+	mu     sync.Mutex
+	logger *observability.Logger
+}
+
+// NewFaultInjector returns an injector for cfg, or nil if fault injection is disabled
+func NewFaultInjector(cfg *config.FaultInjectionConfig, logger *observability.Logger) *FaultInjector {
+	if cfg == nil {
+		return nil
+	}
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	logger.Warn("transfer fault injection enabled",
+		zap.Float64("corrupt_rate", cfg.CorruptRate),
+		zap.Float64("disconnect_rate", cfg.DisconnectRate),
+		zap.Duration("latency", cfg.Latency),
+		zap.Duration("latency_jitter", cfg.LatencyJitter),
+		zap.Int64("seed", seed),
+	)
+
+	return &FaultInjector{
+		cfg:    *cfg,
+		rng:    rand.New(rand.NewSource(seed)),
+		logger: logger,
+	}
+}
+
+// roll returns true with probability p
+func (fi *FaultInjector) roll(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	fi.mu.Lock()
+	defer fi.mu.Unlock()
+	return fi.rng.Float64() < p
+}
+
+// Delay sleeps for the configured latency plus jitter
+func (fi *FaultInjector) Delay(ctx context.Context) error {
+	if fi == nil || (fi.cfg.Latency <= 0 && fi.cfg.LatencyJitter <= 0) {
+		return nil
+	}
+
+	delay := fi.cfg.Latency
+	if fi.cfg.LatencyJitter > 0 {
+		fi.mu.Lock()
+		delay += time.Duration(fi.rng.Int63n(int64(fi.cfg.LatencyJitter)))
+		fi.mu.Unlock()
+	}
+
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Disconnect returns an Unavailable error if a disconnect should be injected
+func (fi *FaultInjector) Disconnect(offset int64) error {
+	if fi == nil || !fi.roll(fi.cfg.DisconnectRate) {
+		return nil
+	}
+	fi.logger.Warn("injecting disconnect", zap.Int64("offset", offset))
+	return status.Error(codes.Unavailable, "injected disconnect")
+}
+
+// Corrupt returns data with one byte flipped if corruption should be injected.
+// The original slice is never modified.
+func (fi *FaultInjector) Corrupt(data []byte, offset int64) []byte {
+	if fi == nil || len(data) == 0 || !fi.roll(fi.cfg.CorruptRate) {
+		return data
+	}
+
+	fi.mu.Lock()
+	pos := fi.rng.Intn(len(data))
+	fi.mu.Unlock()
+
+	corrupted := make([]byte, len(data))
+	copy(corrupted, data)
+	corrupted[pos] ^= 0xFF
+
+	fi.logger.Warn("injecting chunk corruption",
+		zap.Int64("offset", offset),
+		zap.Int("byte", pos),
+	)
+	return corrupted
+}
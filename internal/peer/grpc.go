@@ -8,6 +8,7 @@ import (
 	"net"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/artemis/docker-migrate/internal/config"
@@ -41,13 +42,19 @@ type GRPCServer struct {
 	config           *config.Config
 	logger           *observability.Logger
 	peerID           string
-	skipClientVerify bool // For master mode, don't verify client certs
+	spoolDir         string
+	volumeIndexes    volumeIndexCache
+	skipClientVerify bool // For master mode, don't require client certs in the handshake
+	guard            OperationGuard
+	serving          atomic.Bool
 }
 
 // GRPCServerOption is a functional option for GRPCServer
 type GRPCServerOption func(*GRPCServer)
 
-// WithNoClientVerify disables client certificate verification (for master mode)
+// WithNoClientVerify lets clients connect without a trusted certificate, for
+// master mode, where workers authenticate to the master's own services with
+// their enrollment token. Every other service still requires a trusted peer.
 func WithNoClientVerify() GRPCServerOption {
 	return func(gs *GRPCServer) {
 		gs.skipClientVerify = true
@@ -64,31 +71,17 @@ func NewGRPCServer(
 	logger *observability.Logger,
 	opts ...GRPCServerOption,
 ) (*GRPCServer, error) {
-
-	peerID := fmt.Sprintf("peer-%s", crypto.GetFingerprint()[:8])
-
-	gs := &GRPCServer{
-		docker:   dockerClient,
-		transfer: transfer,
-		pairing:  pairing,
-		crypto:   crypto,
-		config:   cfg,
-		logger:   logger,
-		peerID:   peerID,
-	}
-
-	// Apply options
-	for _, opt := range opts {
-		opt(gs)
+	gs, err := NewPeerServices(dockerClient, transfer, pairing, crypto, cfg, logger, opts...)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get TLS config - use different config based on mode
 	var tlsConfig *tls.Config
-	var err error
 	if gs.skipClientVerify {
 		tlsConfig, err = crypto.TLSConfigNoClientAuth()
 	} else {
-		tlsConfig, err = crypto.TLSConfig()
+		tlsConfig, err = crypto.TLSConfigAllowPairing()
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get TLS config: %w", err)
@@ -97,7 +90,7 @@ func NewGRPCServer(
 	creds := credentials.NewTLS(tlsConfig)
 
 	// Create gRPC server with security and keepalive
-	gs.server = grpc.NewServer(
+	gs.server = grpc.NewServer(append([]grpc.ServerOption{
 		grpc.Creds(creds),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			Time:    KeepaliveTime,
@@ -108,15 +101,68 @@ func NewGRPCServer(
 			MinTime:             15 * time.Second,
 			PermitWithoutStream: true,
 		}),
+	}, gs.ServerOptions()...)...)
+
+	gs.RegisterServices(gs.server)
+
+	return gs, nil
+}
+
+// NewPeerServices creates the peer services without a server of their own,
+// for a host that serves them beside its own services with RegisterServices.
+// Without a pairing manager, Pair is refused.
+func NewPeerServices(
+	dockerClient *docker.Client,
+	transfer *TransferManager,
+	pairing *PairingManager,
+	crypto *CryptoManager,
+	cfg *config.Config,
+	logger *observability.Logger,
+	opts ...GRPCServerOption,
+) (*GRPCServer, error) {
+	peerID := fmt.Sprintf("peer-%s", crypto.GetFingerprint()[:8])
+
+	gs := &GRPCServer{
+		docker:   dockerClient,
+		transfer: transfer,
+		pairing:  pairing,
+		crypto:   crypto,
+		config:   cfg,
+		logger:   logger,
+		peerID:   peerID,
+	}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(gs)
+	}
+
+	// Receive into the spool rather than the OS temp dir, which is often a
+	// small tmpfs; anything left there is from an interrupted run
+	spoolDir, err := SpoolDir(cfg)
+	if err != nil {
+		return nil, err
+	}
+	CleanSpool(spoolDir, logger)
+	gs.spoolDir = spoolDir
+
+	return gs, nil
+}
+
+// ServerOptions returns the message limits and the interceptors that check
+// callers, for any server the peer services are registered on
+func (gs *GRPCServer) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
 		grpc.MaxRecvMsgSize(8 * 1024 * 1024), // 8MB max message size
 		grpc.MaxSendMsgSize(8 * 1024 * 1024),
 		grpc.UnaryInterceptor(gs.unaryInterceptor),
 		grpc.StreamInterceptor(gs.streamInterceptor),
-	)
-
-	pb.RegisterMigrationServiceServer(gs.server, gs)
+	}
+}
 
-	return gs, nil
+// RegisterServices registers the peer services on server
+func (gs *GRPCServer) RegisterServices(server *grpc.Server) {
+	pb.RegisterMigrationServiceServer(server, gs)
 }
 
 // Start starts the gRPC server
@@ -128,6 +174,8 @@ func (gs *GRPCServer) Start(addr string) error {
 
 	gs.logger.Info("starting gRPC server", zap.String("addr", addr))
 
+	gs.serving.Store(true)
+	defer gs.serving.Store(false)
 	if err := gs.server.Serve(listener); err != nil {
 		return fmt.Errorf("failed to serve: %w", err)
 	}
@@ -135,10 +183,24 @@ func (gs *GRPCServer) Start(addr string) error {
 	return nil
 }
 
+// Serving reports whether the server is listening on its address
+func (gs *GRPCServer) Serving() bool {
+	return gs.serving.Load()
+}
+
+// ServeConn serves one connection opened by NAT traversal, returning once
+// it closes
+func (gs *GRPCServer) ServeConn(conn net.Conn) {
+	if err := gs.server.Serve(newSingleConnListener(conn)); err != nil && err != grpc.ErrServerStopped {
+		gs.logger.Debug("nat connection ended", zap.Error(err))
+	}
+}
+
 // Stop stops the gRPC server gracefully
 func (gs *GRPCServer) Stop() {
 	if gs.server != nil {
 		gs.logger.Info("stopping gRPC server")
+		gs.serving.Store(false)
 		gs.server.GracefulStop()
 	}
 }
@@ -166,25 +228,47 @@ func (gs *GRPCServer) TransferVolume(stream pb.MigrationService_TransferVolumeSe
 	var totalSize int64
 	var writer *ChunkWriter
 	var tmpFile *os.File
+	var wb *WriteBehind
 	receivedBytes := int64(0)
 	startTime := time.Now()
+	delta := false
+	patch := false
 
-	// Create temporary file for atomic write
-	tmpFile, err := os.CreateTemp("", "volume-transfer-*")
-	if err != nil {
-		return status.Errorf(codes.Internal, "failed to create temp file: %v", err)
-	}
+	// Data from a broken stream is kept so the sender can reconnect and resume
+	keepPartial := false
 	defer func() {
+		if tmpFile == nil {
+			return
+		}
 		tmpFile.Close()
+		if keepPartial {
+			if err := os.Rename(tmpFile.Name(), gs.partialPath(volumeID)); err == nil {
+				return
+			}
+		}
 		os.Remove(tmpFile.Name())
 	}()
+	defer func() {
+		if wb != nil {
+			wb.Abort()
+		}
+	}()
+
+	// interrupted flushes what was received so far and keeps it for a resume
+	interrupted := func() {
+		if wb != nil && receivedBytes > 0 && wb.Close() == nil {
+			keepPartial = true
+		}
+	}
 
-	writer = NewChunkWriter(tmpFile, 0, gs.logger)
+	// A chunk that fails its checksum is asked for again, a few times
+	retransmits := make(chunkRetransmits)
 
 	// Receive chunks
 	for {
 		select {
 		case <-ctx.Done():
+			interrupted()
 			return status.Error(codes.Canceled, "transfer canceled")
 		default:
 		}
@@ -195,18 +279,64 @@ func (gs *GRPCServer) TransferVolume(stream pb.MigrationService_TransferVolumeSe
 		}
 		if err != nil {
 			gs.logger.Error("failed to receive chunk", zap.Error(err))
+			interrupted()
 			return status.Errorf(codes.Internal, "receive error: %v", err)
 		}
 
-		// First chunk initializes transfer
+		// First chunk initializes transfer; a non-zero offset resumes one
 		if volumeID == "" {
 			volumeID = chunk.VolumeId
 			totalSize = chunk.TotalSize
 			gs.logger.Info("receiving volume",
 				zap.String("volume_id", volumeID),
 				zap.Int64("total_size", totalSize),
+				zap.Int64("offset", chunk.Offset),
 			)
+
+			// Benchmark data is verified and acknowledged like any other,
+			// then dropped rather than spooled
+			if IsBenchmarkVolume(volumeID) {
+				writer = NewChunkWriter(io.Discard, chunk.Offset, gs.logger)
+				receivedBytes = chunk.Offset
+			} else {
+				if err := gs.allow(OpReceiveVolumes); err != nil {
+					stream.Send(&pb.TransferAck{
+						Offset:  chunk.Offset,
+						Success: false,
+						Error:   err.Error(),
+					})
+					return err
+				}
+
+				if err := checkSpoolSpace(gs.spoolDir, totalSize-chunk.Offset); err != nil {
+					gs.logger.Warn("refusing volume transfer", zap.String("volume_id", volumeID), zap.Error(err))
+					stream.Send(&pb.TransferAck{
+						Offset:  chunk.Offset,
+						Success: false,
+						Error:   err.Error(),
+					})
+					return status.Error(codes.ResourceExhausted, err.Error())
+				}
+
+				tmpFile, err = gs.openReceiveFile(volumeID, chunk.Offset)
+				if err != nil {
+					gs.logger.Warn("cannot receive volume", zap.String("volume_id", volumeID), zap.Error(err))
+					stream.Send(&pb.TransferAck{
+						Offset:  chunk.Offset,
+						Success: false,
+						Error:   err.Error(),
+					})
+					return status.Error(codes.FailedPrecondition, err.Error())
+				}
+
+				// Buffer writes so a slow disk does not hold up receiving and acking
+				wb = NewWriteBehind(tmpFile, gs.config.ReceiveBuffer, gs.config.ReceiveFsyncBytes)
+				writer = NewChunkWriter(wb, chunk.Offset, gs.logger)
+				receivedBytes = chunk.Offset
+			}
 		}
+		delta = delta || chunk.Delta
+		patch = patch || chunk.Patch
 
 		// Write chunk with verification
 		peerChunk := &Chunk{
@@ -218,6 +348,18 @@ func (gs *GRPCServer) TransferVolume(stream pb.MigrationService_TransferVolumeSe
 		}
 
 		if err := writer.WriteChunk(peerChunk); err != nil {
+			if retransmits.request(chunk.Offset, err) {
+				gs.logger.Warn("chunk failed verification, asking for it again",
+					zap.String("volume_id", volumeID),
+					zap.Int64("offset", chunk.Offset),
+					zap.Int("attempt", retransmits[chunk.Offset]),
+				)
+				if err := stream.Send(retransmitAck(chunk.Offset, err)); err != nil {
+					interrupted()
+					return status.Errorf(codes.Internal, "ack error: %v", err)
+				}
+				continue
+			}
 			gs.logger.Error("failed to write chunk",
 				zap.Int64("offset", chunk.Offset),
 				zap.Error(err),
@@ -232,12 +374,48 @@ func (gs *GRPCServer) TransferVolume(stream pb.MigrationService_TransferVolumeSe
 			return status.Errorf(codes.DataLoss, "write error: %v", err)
 		}
 
-		receivedBytes += int64(len(chunk.Data))
+		receivedBytes = writer.GetOffset()
+
+		// The final ack promises the whole volume is on disk
+		if chunk.IsFinal && wb != nil {
+			if err := wb.Close(); err != nil {
+				gs.logger.Error("failed to flush volume data", zap.Error(err))
+				stream.Send(&pb.TransferAck{
+					Offset:   chunk.Offset,
+					Success:  false,
+					Error:    err.Error(),
+					Progress: float32(receivedBytes) / float32(totalSize),
+				})
+				return status.Errorf(codes.DataLoss, "write error: %v", err)
+			}
+
+			// A delta is merged before the final ack so the sender learns
+			// whether its changes landed
+			if delta {
+				if err := gs.applyVolumeDelta(ctx, volumeID, tmpFile, chunk.DeletedPaths, patch); err != nil {
+					gs.logger.Error("failed to apply volume delta",
+						zap.String("volume_id", volumeID),
+						zap.Error(err),
+					)
+					stream.Send(&pb.TransferAck{
+						Offset:   chunk.Offset,
+						Success:  false,
+						Error:    err.Error(),
+						Progress: float32(receivedBytes) / float32(totalSize),
+					})
+					return status.Errorf(codes.Internal, "apply delta: %v", err)
+				}
+			}
+		}
+
+		if err := gs.transfer.faults.Delay(ctx); err != nil {
+			return status.Error(codes.Canceled, "transfer canceled")
+		}
 
 		// Send success ack
 		progress := float32(receivedBytes) / float32(totalSize)
 		if err := stream.Send(&pb.TransferAck{
-			Offset:   chunk.Offset + int64(len(chunk.Data)),
+			Offset:   writer.GetOffset(),
 			Success:  true,
 			Progress: progress,
 		}); err != nil {
@@ -259,12 +437,26 @@ func (gs *GRPCServer) TransferVolume(stream pb.MigrationService_TransferVolumeSe
 		}
 	}
 
+	// Senders that end the stream without a final chunk still get durable data
+	if wb != nil {
+		if err := wb.Close(); err != nil {
+			gs.logger.Error("failed to flush volume data", zap.Error(err))
+			return status.Errorf(codes.DataLoss, "write error: %v", err)
+		}
+	}
+
 	duration := time.Since(startTime)
 	speed := float64(receivedBytes) / duration.Seconds() / (1024 * 1024)
 
+	var duplicateBytes int64
+	if writer != nil {
+		duplicateBytes = writer.DuplicateBytes()
+	}
+
 	gs.logger.Info("volume transfer completed",
 		zap.String("volume_id", volumeID),
 		zap.Int64("total_bytes", receivedBytes),
+		zap.Int64("duplicate_bytes", duplicateBytes),
 		zap.Duration("duration", duration),
 		zap.Float64("speed_mbps", speed),
 	)
@@ -277,11 +469,24 @@ func (gs *GRPCServer) TransferVolume(stream pb.MigrationService_TransferVolumeSe
 
 // Ping checks peer connectivity and latency
 func (gs *GRPCServer) Ping(ctx context.Context, req *pb.Empty) (*pb.Pong, error) {
-	return &pb.Pong{
-		PeerId:    gs.peerID,
-		Timestamp: time.Now().Unix(),
-		Version:   "1.0.0",
-	}, nil
+	pong := &pb.Pong{
+		PeerId:      gs.peerID,
+		Timestamp:   time.Now().Unix(),
+		Version:     "1.0.0",
+		TimestampMs: time.Now().UnixMilli(),
+	}
+
+	// Advertise volume drivers so peers can check re-attach compatibility
+	if gs.docker != nil {
+		drivers, err := gs.docker.ListVolumeDrivers(ctx)
+		if err != nil {
+			gs.logger.Warn("failed to list volume drivers", zap.Error(err))
+		} else {
+			pong.VolumeDrivers = drivers
+		}
+	}
+
+	return pong, nil
 }
 
 // unaryInterceptor adds logging and authentication to unary calls
@@ -293,9 +498,7 @@ func (gs *GRPCServer) unaryInterceptor(
 ) (interface{}, error) {
 	start := time.Now()
 
-	// Skip peer verification for master mode (auth via enrollment token)
-	// or for MasterService methods
-	if !gs.skipClientVerify && !isMasterServiceMethod(info.FullMethod) {
+	if gs.requiresTrustedPeer(info.FullMethod) {
 		if err := gs.verifyPeer(ctx); err != nil {
 			gs.logger.Warn("peer verification failed", zap.Error(err))
 			return nil, status.Error(codes.Unauthenticated, "peer not trusted")
@@ -324,9 +527,7 @@ func (gs *GRPCServer) streamInterceptor(
 ) error {
 	start := time.Now()
 
-	// Skip peer verification for master mode (auth via enrollment token)
-	// or for MasterService methods
-	if !gs.skipClientVerify && !isMasterServiceMethod(info.FullMethod) {
+	if gs.requiresTrustedPeer(info.FullMethod) {
 		if err := gs.verifyPeer(ss.Context()); err != nil {
 			gs.logger.Warn("peer verification failed", zap.Error(err))
 			return status.Error(codes.Unauthenticated, "peer not trusted")
@@ -346,9 +547,33 @@ func (gs *GRPCServer) streamInterceptor(
 	return err
 }
 
-// isMasterServiceMethod checks if the method belongs to MasterService
-func isMasterServiceMethod(fullMethod string) bool {
-	return strings.HasPrefix(fullMethod, "/proto.MasterService/")
+// requiresTrustedPeer reports whether a method may only be called by a
+// trusted peer. Pair is open to untrusted peers, and on the master its own
+// services check the caller themselves. Everything else, including the
+// services that create, start and remove containers, needs a trusted
+// certificate in every mode.
+func (gs *GRPCServer) requiresTrustedPeer(fullMethod string) bool {
+	if isPairingMethod(fullMethod) {
+		return false
+	}
+	return !(gs.skipClientVerify && isMasterMethod(fullMethod))
+}
+
+// isMasterMethod checks if the method belongs to a service the master
+// serves to workers, which authenticate with their enrollment token and
+// pinned certificate
+func isMasterMethod(fullMethod string) bool {
+	for _, service := range []string{"MasterService", "ProxyService", "RendezvousService"} {
+		if strings.HasPrefix(fullMethod, "/migrate."+service+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isPairingMethod checks if the method is open to untrusted peers for pairing
+func isPairingMethod(fullMethod string) bool {
+	return fullMethod == pb.MigrationService_Pair_FullMethodName
 }
 
 // verifyPeer verifies the peer certificate is trusted
@@ -375,6 +600,9 @@ func (gs *GRPCServer) verifyPeer(ctx context.Context) error {
 	}
 
 	// Update last seen
+	if gs.pairing == nil {
+		return nil
+	}
 	if trustedPeer, ok := gs.pairing.GetTrustedPeer(fingerprint); ok {
 		gs.pairing.UpdatePeerLastSeen(trustedPeer.ID)
 	}
@@ -389,12 +617,64 @@ type GRPCClient struct {
 	transfer *TransferManager
 	crypto   *CryptoManager
 	logger   *observability.Logger
+	path     io.Closer // SSH tunnel or NAT path the connection runs over, if any
+
+	// clockSkew is how far the peer's clock was ahead of ours at the last
+	// ping, when skewKnown; older peers do not send their time
+	clockSkew time.Duration
+	skewKnown bool
 }
 
-// NewGRPCClient creates a new gRPC client
+// NewGRPCClient creates a new gRPC client. With tunnelConfig set, address is
+// dialed from the SSH server rather than from this host.
 func NewGRPCClient(
 	address string,
 	expectedFingerprint string,
+	tunnelConfig *config.SSHTunnelConfig,
+	transfer *TransferManager,
+	crypto *CryptoManager,
+	logger *observability.Logger,
+) (*GRPCClient, error) {
+
+	// The transfer bind address does not apply to tunnelled connections
+	if tunnelConfig == nil {
+		return newGRPCClient(address, expectedFingerprint, transfer.TransferDialOptions(), nil, transfer, crypto, logger)
+	}
+
+	tunnel, err := newSSHTunnel(tunnelConfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newGRPCClient(address, expectedFingerprint, []grpc.DialOption{tunnel.DialOption()}, tunnel, transfer, crypto, logger)
+	if err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// newGRPCClientOverConn creates a gRPC client running over conn, a path
+// opened by NAT traversal. The client owns conn.
+func newGRPCClientOverConn(
+	conn net.Conn,
+	expectedFingerprint string,
+	transfer *TransferManager,
+	crypto *CryptoManager,
+	logger *observability.Logger,
+) (*GRPCClient, error) {
+	client, err := newGRPCClient(conn.RemoteAddr().String(), expectedFingerprint, []grpc.DialOption{connDialOption(conn)}, conn, transfer, crypto, logger)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func newGRPCClient(
+	address string,
+	expectedFingerprint string,
+	pathOpts []grpc.DialOption,
+	path io.Closer,
 	transfer *TransferManager,
 	crypto *CryptoManager,
 	logger *observability.Logger,
@@ -409,7 +689,7 @@ func NewGRPCClient(
 	creds := credentials.NewTLS(tlsConfig)
 
 	// Create gRPC connection
-	conn, err := grpc.Dial(address,
+	dialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(creds),
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
 			Time:                KeepaliveTime,
@@ -420,7 +700,10 @@ func NewGRPCClient(
 			grpc.MaxCallRecvMsgSize(8*1024*1024),
 			grpc.MaxCallSendMsgSize(8*1024*1024),
 		),
-	)
+	}
+	dialOpts = append(dialOpts, pathOpts...)
+
+	conn, err := grpc.Dial(address, dialOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
@@ -429,6 +712,7 @@ func NewGRPCClient(
 
 	logger.Info("gRPC client connected",
 		zap.String("address", address),
+		zap.Bool("tunnelled", path != nil),
 	)
 
 	return &GRPCClient{
@@ -437,16 +721,20 @@ func NewGRPCClient(
 		transfer: transfer,
 		crypto:   crypto,
 		logger:   logger,
+		path:     path,
 	}, nil
 }
 
-// SendVolume streams volume to peer
+// SendVolume streams volume to peer. A broken stream is reopened with
+// backoff and the transfer continues from the last acknowledged chunk.
 func (gc *GRPCClient) SendVolume(ctx context.Context, volumeID string, reader io.Reader, totalSize int64) error {
-	stream, err := gc.client.TransferVolume(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create stream: %w", err)
-	}
+	return gc.sendVolume(ctx, volumeID, reader, totalSize, nil, nil)
+}
 
+// sendVolume streams a whole volume, or with delta set a tar of changed
+// files to merge into the peer's copy. chunks, if set, counts the chunks the
+// peer acknowledged.
+func (gc *GRPCClient) sendVolume(ctx context.Context, volumeID string, reader io.Reader, totalSize int64, delta *volumeDelta, chunks *int) error {
 	// Create transfer tracking
 	transfer, err := gc.transfer.CreateTransfer(ctx, TransferVolume, volumeID, "peer", totalSize)
 	if err != nil {
@@ -454,6 +742,12 @@ func (gc *GRPCClient) SendVolume(ctx context.Context, volumeID string, reader io
 	}
 
 	transfer.Status = TransferActive
+	stream, closeStream, err := gc.openVolumeStream(transfer)
+	if err != nil {
+		gc.transfer.FailTransfer(transfer.ID, err)
+		return fmt.Errorf("failed to create stream: %w", err)
+	}
+	defer func() { closeStream() }()
 
 	// Create chunk reader with dynamic sizing
 	chunkSize := gc.transfer.DynamicChunkSize(transfer)
@@ -465,6 +759,11 @@ func (gc *GRPCClient) SendVolume(ctx context.Context, volumeID string, reader io
 		zap.Int("chunk_size", chunkSize),
 	)
 
+	// Chunks are acknowledged one at a time, so only the chunk in flight
+	// needs resending after a reconnect
+	var pending *Chunk
+	retries := 0
+
 	// Send chunks
 	for {
 		select {
@@ -474,41 +773,82 @@ func (gc *GRPCClient) SendVolume(ctx context.Context, volumeID string, reader io
 		default:
 		}
 
-		chunk, err := chunkReader.ReadChunk()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			gc.transfer.FailTransfer(transfer.ID, err)
-			return fmt.Errorf("failed to read chunk: %w", err)
+		if pending == nil {
+			chunk, err := chunkReader.ReadChunk()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				gc.transfer.FailTransfer(transfer.ID, err)
+				return fmt.Errorf("failed to read chunk: %w", err)
+			}
+			pending = chunk
 		}
+		chunk := pending
 
-		// Send chunk
-		pbChunk := &pb.VolumeChunk{
-			VolumeId:  volumeID,
-			Offset:    chunk.Offset,
-			Data:      chunk.Data,
-			Checksum:  chunk.Checksum,
-			TotalSize: totalSize,
-			IsFinal:   chunk.IsFinal,
+		// Yield to high-priority transfers between chunks
+		if err := gc.transfer.WaitForTurn(ctx, transfer); err != nil {
+			gc.transfer.CancelTransfer(transfer.ID)
+			return err
 		}
 
-		if err := stream.Send(pbChunk); err != nil {
-			gc.transfer.FailTransfer(transfer.ID, err)
-			return fmt.Errorf("failed to send chunk: %w", err)
+		// Injected faults (testing only): latency, dropped stream, corrupted payload
+		if err := gc.transfer.faults.Delay(ctx); err != nil {
+			gc.transfer.CancelTransfer(transfer.ID)
+			return err
 		}
 
-		// Receive ack
-		ack, err := stream.Recv()
-		if err != nil {
-			gc.transfer.FailTransfer(transfer.ID, err)
-			return fmt.Errorf("failed to receive ack: %w", err)
+		err := gc.transfer.faults.Disconnect(chunk.Offset)
+		if err == nil {
+			err = gc.sendVolumeChunk(stream, volumeID, totalSize, chunk, delta)
 		}
+		if err != nil {
+			if ctx.Err() != nil {
+				gc.transfer.CancelTransfer(transfer.ID)
+				return ctx.Err()
+			}
+			err = stallError(transfer, err)
+			if !retryableStreamError(err) || retries >= gc.transfer.maxStreamRetries() {
+				gc.transfer.FailTransfer(transfer.ID, err)
+				return fmt.Errorf("failed to send chunk: %w", err)
+			}
 
-		if !ack.Success {
-			err := fmt.Errorf("chunk transfer failed: %s", ack.Error)
-			gc.transfer.FailTransfer(transfer.ID, err)
-			return err
+			retries++
+			gc.logger.Warn("volume stream broken, reconnecting",
+				zap.String("transfer_id", transfer.ID),
+				zap.Int64("offset", chunk.Offset),
+				zap.Int("attempt", retries),
+				zap.Error(err),
+			)
+
+			closeStream()
+			for {
+				stream, closeStream, err = gc.reconnectVolumeStream(ctx, transfer, retries)
+				if err == nil {
+					break
+				}
+				closeStream = func() {}
+				if ctx.Err() != nil {
+					gc.transfer.CancelTransfer(transfer.ID)
+					return ctx.Err()
+				}
+				if retries >= gc.transfer.maxStreamRetries() {
+					gc.transfer.FailTransfer(transfer.ID, err)
+					return fmt.Errorf("failed to reconnect: %w", err)
+				}
+				retries++
+				gc.logger.Warn("reconnect failed",
+					zap.String("transfer_id", transfer.ID),
+					zap.Int("attempt", retries),
+					zap.Error(err),
+				)
+			}
+			continue
+		}
+		retries = 0
+		pending = nil
+		if chunks != nil {
+			*chunks++
 		}
 
 		// Add checkpoint
@@ -547,6 +887,50 @@ func (gc *GRPCClient) SendVolume(ctx context.Context, volumeID string, reader io
 	return nil
 }
 
+// sendVolumeChunk sends one chunk and waits for its acknowledgement,
+// re-sending it while the peer asks for it again
+func (gc *GRPCClient) sendVolumeChunk(stream pb.MigrationService_TransferVolumeClient, volumeID string, totalSize int64, chunk *Chunk, delta *volumeDelta) error {
+	pbChunk := &pb.VolumeChunk{
+		VolumeId:  volumeID,
+		Offset:    chunk.Offset,
+		Data:      gc.transfer.faults.Corrupt(chunk.Data, chunk.Offset),
+		Checksum:  chunk.Checksum,
+		TotalSize: totalSize,
+		IsFinal:   chunk.IsFinal,
+	}
+	if delta != nil {
+		pbChunk.Delta = true
+		pbChunk.Patch = delta.patch
+		if chunk.IsFinal {
+			pbChunk.DeletedPaths = delta.deleted
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := stream.Send(pbChunk); err != nil {
+			return err
+		}
+
+		ack, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if ack.Success {
+			return nil
+		}
+		if !isRetransmit(ack, chunk.Offset, attempt) {
+			return fmt.Errorf("%w: %s", errChunkRejected, ack.Error)
+		}
+
+		gc.logger.Warn("peer asked for chunk again",
+			zap.String("volume_id", volumeID),
+			zap.Int64("offset", chunk.Offset),
+			zap.String("reason", ack.Error),
+		)
+		pbChunk.Data = gc.transfer.faults.Corrupt(chunk.Data, chunk.Offset)
+	}
+}
+
 // Ping pings the peer and measures latency
 func (gc *GRPCClient) Ping(ctx context.Context) (*pb.Pong, time.Duration, error) {
 	start := time.Now()
@@ -557,15 +941,38 @@ func (gc *GRPCClient) Ping(ctx context.Context) (*pb.Pong, time.Duration, error)
 	}
 
 	latency := time.Since(start)
+	if pong.TimestampMs != 0 {
+		gc.clockSkew = EstimateClockSkew(pong.TimestampMs, start, latency)
+		gc.skewKnown = true
+	}
 
 	return pong, latency, nil
 }
 
+// ClockSkew returns how far the peer's clock was ahead of ours at the last
+// ping, and whether the peer reported its time
+func (gc *GRPCClient) ClockSkew() (time.Duration, bool) {
+	return gc.clockSkew, gc.skewKnown
+}
+
 // Close closes the gRPC connection
 func (gc *GRPCClient) Close() error {
 	if gc.conn != nil {
 		gc.logger.Info("closing gRPC client")
-		return gc.conn.Close()
+		err := gc.conn.Close()
+		if gc.path != nil {
+			gc.path.Close()
+		}
+		return err
 	}
 	return nil
 }
+
+// peerCallError wraps the error from a call to the peer. what names the
+// call, and older peers that lack the method get a clear error.
+func peerCallError(what string, err error) error {
+	if status.Code(err) == codes.Unimplemented {
+		return fmt.Errorf("peer is too old to %s", what)
+	}
+	return fmt.Errorf("failed to %s: %w", what, err)
+}
@@ -0,0 +1,260 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LayerSender is the sending half of an image layer stream, direct or proxied
+type LayerSender interface {
+	Send(*pb.LayerBlob) error
+	Recv() (*pb.TransferAck, error)
+}
+
+// StreamImage chunks an image tar (e.g. from ImageSave) straight onto stream.
+// One chunk buffer is reused throughout, so memory use does not grow with
+// image size. onAck, if set, is called with the bytes sent so far each time
+// the peer acknowledges a chunk. Returns the bytes sent.
+func StreamImage(ctx context.Context, stream LayerSender, imageID string, reader io.Reader, chunkSize int, onAck func(sent int64)) (int64, error) {
+	chunkReader := NewChunkReader(reader, chunkSize, 0).ReuseBuffer()
+	sent := int64(0)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		blob := &pb.LayerBlob{ImageId: imageID}
+
+		chunk, err := chunkReader.ReadChunk()
+		switch {
+		case err == io.EOF:
+			// The tar ended on a chunk boundary; mark the end explicitly
+			blob.Offset = chunkReader.GetOffset()
+			blob.IsFinal = true
+		case err != nil:
+			return sent, err
+		default:
+			blob.Offset = chunk.Offset
+			blob.Data = chunk.Data
+			blob.Checksum = chunk.Checksum
+			blob.IsFinal = chunk.IsFinal
+		}
+
+		// The chunk buffer is not reused until the next read, so a chunk
+		// the peer asks for again can be re-sent as is
+		for attempt := 0; ; attempt++ {
+			if err := stream.Send(blob); err != nil {
+				return sent, fmt.Errorf("failed to send image data: %w", err)
+			}
+
+			ack, err := stream.Recv()
+			if err != nil {
+				return sent, fmt.Errorf("failed to receive ack: %w", err)
+			}
+			if ack.Success {
+				break
+			}
+			if !isRetransmit(ack, blob.Offset, attempt) {
+				return sent, fmt.Errorf("image transfer failed at offset %d: %s", blob.Offset, ack.Error)
+			}
+		}
+
+		sent += int64(len(blob.Data))
+		if onAck != nil {
+			onAck(sent)
+		}
+		if blob.IsFinal {
+			return sent, nil
+		}
+	}
+}
+
+// SendImage streams an image tar to the peer, which loads it into Docker
+func (gc *GRPCClient) SendImage(ctx context.Context, imageID string, reader io.Reader) error {
+	// Image tars are produced on the fly, so the size is unknown up front
+	transfer, err := gc.transfer.CreateTransfer(ctx, TransferImage, imageID, "peer", 0)
+	if err != nil {
+		return fmt.Errorf("failed to create transfer: %w", err)
+	}
+
+	// The stream runs on the transfer's context so a stall can tear it down
+	stream, err := gc.client.TransferImageLayers(transfer.ctx)
+	if err != nil {
+		gc.transfer.FailTransfer(transfer.ID, err)
+		return fmt.Errorf("failed to create stream: %w", err)
+	}
+
+	transfer.Status = TransferActive
+	stopWatch := gc.transfer.WatchStall(transfer, transfer.cancel)
+	defer stopWatch()
+
+	chunkSize := gc.transfer.DynamicChunkSize(transfer)
+
+	gc.logger.Info("starting image transfer",
+		zap.String("image_id", imageID),
+		zap.Int("chunk_size", chunkSize),
+	)
+
+	// Each acknowledged chunk is progress, which keeps the stall watch quiet
+	sent, err := StreamImage(transfer.ctx, stream, imageID, reader, chunkSize, func(sent int64) {
+		transfer.mu.Lock()
+		transfer.TransferredBytes = sent
+		transfer.LastChunkTime = time.Now()
+		transfer.mu.Unlock()
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			gc.transfer.CancelTransfer(transfer.ID)
+			return ctx.Err()
+		}
+		gc.transfer.FailTransfer(transfer.ID, err)
+		return stallError(transfer, err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		gc.transfer.FailTransfer(transfer.ID, err)
+		return fmt.Errorf("failed to close stream: %w", err)
+	}
+
+	gc.transfer.AddCheckpoint(transfer.ID, sent, "")
+	gc.transfer.CompleteTransfer(transfer.ID)
+
+	gc.logger.Info("image transfer completed",
+		zap.String("image_id", imageID),
+		zap.Int64("bytes", sent),
+	)
+
+	return nil
+}
+
+// TransferImageLayers receives an image tar and pipes it into docker load as
+// it arrives, without staging it in memory or on disk
+func (gs *GRPCServer) TransferImageLayers(stream pb.MigrationService_TransferImageLayersServer) error {
+	ctx := stream.Context()
+
+	if err := gs.allow(OpReceiveImages); err != nil {
+		return err
+	}
+	if gs.docker == nil {
+		return status.Error(codes.Unavailable, "docker is not available")
+	}
+
+	pr, pw := io.Pipe()
+	loadDone := make(chan error, 1)
+	go func() {
+		err := gs.docker.ImportImage(ctx, pr)
+		// Unblock the writer if docker stopped reading early
+		pr.CloseWithError(fmt.Errorf("image load stopped: %v", err))
+		loadDone <- err
+	}()
+
+	writer := NewChunkWriter(pw, 0, gs.logger)
+	retransmits := make(chunkRetransmits)
+	var imageID string
+	received := int64(0)
+	startTime := time.Now()
+
+	fail := func(offset int64, code codes.Code, err error) error {
+		pw.CloseWithError(err)
+		<-loadDone
+		stream.Send(&pb.TransferAck{
+			Offset:  offset,
+			Success: false,
+			Error:   err.Error(),
+		})
+		return status.Errorf(code, "image transfer failed: %v", err)
+	}
+
+	for {
+		blob, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			pw.CloseWithError(err)
+			<-loadDone
+			return status.Errorf(codes.Internal, "receive error: %v", err)
+		}
+
+		if imageID == "" {
+			imageID = blob.ImageId
+			gs.logger.Info("receiving image", zap.String("image_id", imageID))
+		}
+
+		if len(blob.Data) > 0 {
+			if err := writer.WriteChunk(&Chunk{
+				Offset:   blob.Offset,
+				Data:     blob.Data,
+				Checksum: blob.Checksum,
+				Size:     len(blob.Data),
+				IsFinal:  blob.IsFinal,
+			}); err != nil {
+				if retransmits.request(blob.Offset, err) {
+					gs.logger.Warn("image chunk failed verification, asking for it again",
+						zap.String("image_id", imageID),
+						zap.Int64("offset", blob.Offset),
+						zap.Int("attempt", retransmits[blob.Offset]),
+					)
+					if err := stream.Send(retransmitAck(blob.Offset, err)); err != nil {
+						return fail(blob.Offset, codes.Internal, err)
+					}
+					continue
+				}
+				return fail(blob.Offset, codes.DataLoss, err)
+			}
+			received = writer.GetOffset()
+		}
+
+		// The final ack reports whether docker accepted the image
+		if blob.IsFinal {
+			pw.Close()
+			if err := <-loadDone; err != nil {
+				stream.Send(&pb.TransferAck{
+					Offset:  writer.GetOffset(),
+					Success: false,
+					Error:   err.Error(),
+				})
+				return status.Errorf(codes.Internal, "image load failed: %v", err)
+			}
+			gs.logImageReceived(imageID, received, startTime)
+			return stream.Send(&pb.TransferAck{
+				Offset:   writer.GetOffset(),
+				Success:  true,
+				Progress: 1,
+			})
+		}
+
+		if err := stream.Send(&pb.TransferAck{
+			Offset:  writer.GetOffset(),
+			Success: true,
+		}); err != nil {
+			return fail(blob.Offset, codes.Internal, err)
+		}
+	}
+
+	// Sender closed without a final blob; load whatever arrived
+	pw.Close()
+	if err := <-loadDone; err != nil {
+		return status.Errorf(codes.Internal, "image load failed: %v", err)
+	}
+	gs.logImageReceived(imageID, received, startTime)
+	return nil
+}
+
+func (gs *GRPCServer) logImageReceived(imageID string, bytes int64, start time.Time) {
+	duration := time.Since(start)
+	gs.logger.Info("image transfer completed",
+		zap.String("image_id", imageID),
+		zap.Int64("total_bytes", bytes),
+		zap.Duration("duration", duration),
+		zap.Float64("speed_mbps", float64(bytes)/duration.Seconds()/(1024*1024)),
+	)
+}
@@ -0,0 +1,105 @@
+package peer
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// InspectImage returns the ID of a local image, which Docker derives from
+// the image's content. A missing image is not an error.
+func (gs *GRPCServer) InspectImage(ctx context.Context, req *pb.ImageInspectRequest) (*pb.ImageInspect, error) {
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.Reference == "" {
+		return nil, status.Error(codes.InvalidArgument, "image reference is required")
+	}
+
+	inspect, err := gs.docker.InspectImage(ctx, req.Reference)
+	if docker.IsNotFound(err) {
+		return &pb.ImageInspect{}, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "inspect image: %v", err)
+	}
+	return &pb.ImageInspect{Exists: true, Id: inspect.ID}, nil
+}
+
+// InspectImage asks the peer for the ID of one of its images
+func (gc *GRPCClient) InspectImage(ctx context.Context, reference string) (*pb.ImageInspect, error) {
+	resp, err := gc.client.InspectImage(ctx, &pb.ImageInspectRequest{Reference: reference})
+	if err != nil {
+		return nil, peerCallError("inspect images", err)
+	}
+	return resp, nil
+}
+
+// InspectHost returns this host's name, which it records as the source of
+// the resources it migrates
+func (gs *GRPCServer) InspectHost(ctx context.Context, req *pb.Empty) (*pb.HostInspect, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "hostname: %v", err)
+	}
+	return &pb.HostInspect{Hostname: host}, nil
+}
+
+// InspectHost asks the peer for its hostname
+func (gc *GRPCClient) InspectHost(ctx context.Context) (string, error) {
+	resp, err := gc.client.InspectHost(ctx, &pb.Empty{})
+	if err != nil {
+		return "", peerCallError("report its host", err)
+	}
+	return resp.Hostname, nil
+}
+
+// InspectContainer returns a local container's state. A missing container
+// is not an error.
+func (gs *GRPCServer) InspectContainer(ctx context.Context, req *pb.ContainerInspectRequest) (*pb.ContainerInspect, error) {
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.Reference == "" {
+		return nil, status.Error(codes.InvalidArgument, "container reference is required")
+	}
+
+	inspect, err := gs.docker.InspectContainer(ctx, req.Reference)
+	if docker.IsNotFound(err) {
+		return &pb.ContainerInspect{}, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "inspect container: %v", err)
+	}
+
+	resp := &pb.ContainerInspect{
+		Exists: true,
+		Id:     inspect.ID,
+		Name:   strings.TrimPrefix(inspect.Name, "/"),
+	}
+	if state := inspect.State; state != nil {
+		resp.Status = state.Status
+		resp.Running = state.Running
+		resp.Restarting = state.Restarting
+		resp.ExitCode = int32(state.ExitCode)
+		resp.Error = state.Error
+		if state.Health != nil {
+			resp.Health = state.Health.Status
+		}
+	}
+	return resp, nil
+}
+
+// InspectContainer asks the peer for the state of one of its containers
+func (gc *GRPCClient) InspectContainer(ctx context.Context, reference string) (*pb.ContainerInspect, error) {
+	resp, err := gc.client.InspectContainer(ctx, &pb.ContainerInspectRequest{Reference: reference})
+	if err != nil {
+		return nil, peerCallError("inspect containers", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,127 @@
+package peer
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// QueryLayers tells a sender which of an image's layers this host already has
+func (gs *GRPCServer) QueryLayers(ctx context.Context, req *pb.LayerQuery) (*pb.LayerQueryResult, error) {
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+
+	present, err := gs.docker.SharedLayers(ctx, req.DiffIds)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check layers: %v", err)
+	}
+
+	gs.logger.Info("answered layer query",
+		zap.String("image_id", req.ImageId),
+		zap.Int("layers", len(req.DiffIds)),
+		zap.Int("present", len(present)),
+	)
+
+	return &pb.LayerQueryResult{Present: present}, nil
+}
+
+// QueryLayers asks the peer which of an image's layers, given as diff IDs
+// base layer first, it already has
+func (gc *GRPCClient) QueryLayers(ctx context.Context, imageID string, diffIDs []string) ([]string, error) {
+	result, err := gc.client.QueryLayers(ctx, &pb.LayerQuery{
+		ImageId: imageID,
+		DiffIds: diffIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query peer layers: %w", err)
+	}
+	return result.Present, nil
+}
+
+// LayerSkipper is an image tar from docker save with the layer blobs the
+// target already has left out
+type LayerSkipper struct {
+	pr *io.PipeReader
+
+	// Set by the copy; safe to read once Read has returned io.EOF
+	layers int
+	bytes  int64
+}
+
+// SkipLayers filters an image tar, dropping the layer blobs named by diff ID
+// in present. docker load only reads a layer it does not have, so the target
+// can still load the image. Only the OCI layout written by Docker 25 and newer
+// names uncompressed layer blobs by diff ID; other tars pass through whole.
+func SkipLayers(r io.Reader, present []string) *LayerSkipper {
+	skip := make(map[string]bool, len(present))
+	for _, diffID := range present {
+		skip[strings.TrimPrefix(diffID, "sha256:")] = true
+	}
+
+	pr, pw := io.Pipe()
+	s := &LayerSkipper{pr: pr}
+	go func() {
+		pw.CloseWithError(s.copy(r, pw, skip))
+	}()
+	return s
+}
+
+func (s *LayerSkipper) copy(r io.Reader, w io.Writer, skip map[string]bool) error {
+	tr := tar.NewReader(r)
+	tw := tar.NewWriter(w)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return tw.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read image tar: %w", err)
+		}
+
+		if digest := layerBlobDigest(hdr.Name); digest != "" && hdr.Typeflag == tar.TypeReg && skip[digest] {
+			s.layers++
+			s.bytes += hdr.Size
+			continue
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// layerBlobDigest returns the hex digest of an OCI blob path, or "" if name
+// is not one
+func layerBlobDigest(name string) string {
+	digest, ok := strings.CutPrefix(name, "blobs/sha256/")
+	if !ok || strings.Contains(digest, "/") {
+		return ""
+	}
+	return digest
+}
+
+// Read reads the filtered tar
+func (s *LayerSkipper) Read(p []byte) (int, error) {
+	return s.pr.Read(p)
+}
+
+// Close stops the filter
+func (s *LayerSkipper) Close() error {
+	return s.pr.Close()
+}
+
+// Skipped returns how many layers, and bytes of them, were left out
+func (s *LayerSkipper) Skipped() (int, int64) {
+	return s.layers, s.bytes
+}
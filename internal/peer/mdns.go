@@ -0,0 +1,403 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// MDNSService is the DNS-SD service type peers advertise on the local network
+const MDNSService = "_docker-migrate._tcp"
+
+const (
+	mdnsGroup          = "224.0.0.251:5353"
+	mdnsTTL            = 120 // Seconds a record stays valid without being seen again
+	mdnsBrowseInterval = time.Minute
+)
+
+// NearbyPeer is a peer advertising itself on the local network, paired or not
+type NearbyPeer struct {
+	Instance    string    `json:"instance"`
+	Name        string    `json:"name"`
+	Fingerprint string    `json:"fingerprint"`
+	Addresses   []string  `json:"addresses"`
+	Paired      bool      `json:"paired"`
+	LastSeen    time.Time `json:"last_seen"`
+
+	expires time.Time
+}
+
+// mdnsAdvertiser answers multicast DNS queries for this node's service
+// instance and collects the instances other nodes announce
+type mdnsAdvertiser struct {
+	conn     *net.UDPConn
+	group    *net.UDPAddr
+	service  dnsmessage.Name
+	instance dnsmessage.Name
+	host     dnsmessage.Name
+	port     uint16
+	name     string
+	self     string // Our fingerprint, so our own announcements are ignored
+}
+
+// StartMDNS advertises this node as a _docker-migrate._tcp service on the
+// local network and browses for others until ctx is cancelled. Nodes found
+// are listed by NearbyPeers; nothing is trusted until it is paired.
+func (pd *PeerDiscovery) StartMDNS(ctx context.Context) {
+	if !pd.config.MDNS {
+		return
+	}
+
+	m, err := pd.newMDNSAdvertiser()
+	if err != nil {
+		pd.logger.Warn("mdns discovery disabled", zap.Error(err))
+		return
+	}
+	defer m.conn.Close()
+
+	pd.mu.Lock()
+	pd.nearby = make(map[string]*NearbyPeer)
+	pd.mu.Unlock()
+
+	pd.logger.Info("advertising on the local network",
+		zap.String("instance", m.instance.String()),
+		zap.Uint16("port", m.port),
+	)
+
+	go pd.mdnsReceive(m)
+
+	m.send(m.announcement(mdnsTTL))
+	m.send(m.query())
+	ticker := time.NewTicker(mdnsBrowseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Goodbye: a zero TTL tells other nodes to forget us now
+			m.send(m.announcement(0))
+			return
+		case <-ticker.C:
+			m.send(m.query())
+		}
+	}
+}
+
+// NearbyPeers returns the peers seen on the local network whose records
+// have not expired, with Paired set for those already trusted
+func (pd *PeerDiscovery) NearbyPeers() []NearbyPeer {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	now := time.Now()
+	peers := make([]NearbyPeer, 0, len(pd.nearby))
+	for fp, p := range pd.nearby {
+		if now.After(p.expires) {
+			delete(pd.nearby, fp)
+			continue
+		}
+		copied := *p
+		copied.Addresses = append([]string(nil), p.Addresses...)
+		copied.Paired = pd.crypto.IsTrusted(fp)
+		peers = append(peers, copied)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+	return peers
+}
+
+// MDNSEnabled reports whether local network discovery is running
+func (pd *PeerDiscovery) MDNSEnabled() bool {
+	pd.mu.RLock()
+	defer pd.mu.RUnlock()
+	return pd.nearby != nil
+}
+
+func (pd *PeerDiscovery) newMDNSAdvertiser() (*mdnsAdvertiser, error) {
+	_, portStr, err := net.SplitHostPort(pd.config.GRPCAddr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grpc_addr %q: %w", pd.config.GRPCAddr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid grpc_addr port %q", portStr)
+	}
+
+	name, err := os.Hostname()
+	if err != nil || name == "" {
+		name = "docker-migrate"
+	}
+	fingerprint := pd.crypto.GetFingerprint()
+	label := mdnsLabel(name) + "-" + fingerprint[:8]
+
+	service, err := dnsmessage.NewName(MDNSService + ".local.")
+	if err != nil {
+		return nil, err
+	}
+	instance, err := dnsmessage.NewName(label + "." + MDNSService + ".local.")
+	if err != nil {
+		return nil, err
+	}
+	host, err := dnsmessage.NewName(label + ".local.")
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsGroup)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join mdns group: %w", err)
+	}
+
+	return &mdnsAdvertiser{
+		conn:     conn,
+		group:    group,
+		service:  service,
+		instance: instance,
+		host:     host,
+		port:     uint16(port),
+		name:     name,
+		self:     fingerprint,
+	}, nil
+}
+
+// mdnsLabel makes a hostname usable as one DNS label
+func mdnsLabel(name string) string {
+	label := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+	if len(label) > 40 {
+		label = label[:40]
+	}
+	return label
+}
+
+func (m *mdnsAdvertiser) send(msg []byte) {
+	if msg != nil {
+		m.conn.WriteToUDP(msg, m.group)
+	}
+}
+
+// query asks every node on the network for its service instance
+func (m *mdnsAdvertiser) query() []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	b.StartQuestions()
+	b.Question(dnsmessage.Question{Name: m.service, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+	msg, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// announcement describes this node's instance: where its gRPC port is and
+// which certificate it will present
+func (m *mdnsAdvertiser) announcement(ttl uint32) []byte {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{Response: true, Authoritative: true})
+	b.EnableCompression()
+	b.StartAnswers()
+
+	header := func(name dnsmessage.Name) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: ttl}
+	}
+	b.PTRResource(header(m.service), dnsmessage.PTRResource{PTR: m.instance})
+	b.SRVResource(header(m.instance), dnsmessage.SRVResource{Target: m.host, Port: m.port})
+	b.TXTResource(header(m.instance), dnsmessage.TXTResource{TXT: []string{
+		"fingerprint=" + m.self,
+		"name=" + m.name,
+	}})
+
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+				continue
+			}
+			var a [4]byte
+			copy(a[:], ipNet.IP.To4())
+			b.AResource(header(m.host), dnsmessage.AResource{A: a})
+		}
+	}
+
+	msg, err := b.Finish()
+	if err != nil {
+		return nil
+	}
+	return msg
+}
+
+// mdnsReceive answers queries for our service and records the instances
+// other nodes announce, until the connection is closed
+func (pd *PeerDiscovery) mdnsReceive(m *mdnsAdvertiser) {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := m.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		var p dnsmessage.Parser
+		header, err := p.Start(buf[:n])
+		if err != nil {
+			continue
+		}
+
+		if !header.Response {
+			questions, err := p.AllQuestions()
+			if err != nil {
+				continue
+			}
+			for _, q := range questions {
+				if q.Type != dnsmessage.TypePTR && q.Type != dnsmessage.TypeALL {
+					continue
+				}
+				if strings.EqualFold(q.Name.String(), m.service.String()) {
+					m.send(m.announcement(mdnsTTL))
+					break
+				}
+			}
+			continue
+		}
+
+		if err := p.SkipAllQuestions(); err != nil {
+			continue
+		}
+		answers, err := p.AllAnswers()
+		if err != nil {
+			continue
+		}
+		if err := p.SkipAllAuthorities(); err != nil {
+			continue
+		}
+		additionals, _ := p.AllAdditionals()
+
+		for _, found := range parseMDNSInstances(m.service, append(answers, additionals...), from) {
+			if found.Fingerprint == m.self {
+				continue
+			}
+			pd.recordNearby(found)
+		}
+	}
+}
+
+// recordNearby adds or refreshes a nearby peer; one announced with a zero
+// TTL is leaving and is forgotten
+func (pd *PeerDiscovery) recordNearby(found *NearbyPeer) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+
+	if pd.nearby == nil {
+		return
+	}
+	if !found.expires.After(found.LastSeen) {
+		delete(pd.nearby, found.Fingerprint)
+		return
+	}
+	if _, known := pd.nearby[found.Fingerprint]; !known {
+		pd.logger.Info("found peer on the local network",
+			zap.String("name", found.Name),
+			zap.String("fingerprint", found.Fingerprint),
+			zap.Strings("addresses", found.Addresses),
+		)
+	}
+	pd.nearby[found.Fingerprint] = found
+}
+
+// parseMDNSInstances collects the service instances described by records
+// from one response. Instances without a valid fingerprint are skipped.
+// Addresses come from A records for the SRV target, or the sender's address
+// when the response has none.
+func parseMDNSInstances(service dnsmessage.Name, records []dnsmessage.Resource, from *net.UDPAddr) []*NearbyPeer {
+	type instance struct {
+		ttl    uint32
+		target string
+		port   uint16
+		txt    []string
+	}
+	instances := make(map[string]*instance)
+	hosts := make(map[string][]net.IP)
+
+	get := func(name string) *instance {
+		inst, ok := instances[name]
+		if !ok {
+			inst = &instance{ttl: mdnsTTL}
+			instances[name] = inst
+		}
+		return inst
+	}
+
+	for _, r := range records {
+		name := strings.ToLower(r.Header.Name.String())
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if strings.EqualFold(name, service.String()) {
+				get(strings.ToLower(body.PTR.String())).ttl = r.Header.TTL
+			}
+		case *dnsmessage.SRVResource:
+			inst := get(name)
+			inst.target = strings.ToLower(body.Target.String())
+			inst.port = body.Port
+		case *dnsmessage.TXTResource:
+			get(name).txt = body.TXT
+		case *dnsmessage.AResource:
+			hosts[name] = append(hosts[name], net.IP(body.A[:]))
+		}
+	}
+
+	now := time.Now()
+	var peers []*NearbyPeer
+	for name, inst := range instances {
+		if inst.port == 0 || !strings.HasSuffix(name, "."+strings.ToLower(service.String())) {
+			continue
+		}
+
+		p := &NearbyPeer{
+			Instance: strings.TrimSuffix(name, "."+strings.ToLower(service.String())),
+			LastSeen: now,
+			expires:  now.Add(time.Duration(inst.ttl) * time.Second),
+		}
+		for _, txt := range inst.txt {
+			key, value, ok := strings.Cut(txt, "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(key) {
+			case "fingerprint":
+				p.Fingerprint = value
+			case "name":
+				p.Name = value
+			}
+		}
+		fingerprint, err := NormalizeFingerprint(p.Fingerprint)
+		if err != nil {
+			continue
+		}
+		p.Fingerprint = fingerprint
+		if p.Name == "" {
+			p.Name = p.Instance
+		}
+
+		ips := hosts[inst.target]
+		if len(ips) == 0 && from != nil {
+			ips = []net.IP{from.IP}
+		}
+		for _, ip := range ips {
+			p.Addresses = append(p.Addresses, net.JoinHostPort(ip.String(), strconv.Itoa(int(inst.port))))
+		}
+		peers = append(peers, p)
+	}
+	return peers
+}
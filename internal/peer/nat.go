@@ -0,0 +1,450 @@
+package peer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// RelayTokenHeader carries the master's relay token on rendezvous calls
+const RelayTokenHeader = "x-relay-token"
+
+const (
+	// NATTraversalTimeout bounds reaching a peer through the master, from
+	// the offer until the punched or relayed path is up
+	NATTraversalTimeout = 20 * time.Second
+
+	// relayFrameSize is the most data sent in one relay frame
+	relayFrameSize = 32 * 1024
+
+	// stunTimeout bounds asking one STUN server for our public address
+	stunTimeout = 2 * time.Second
+)
+
+// natTraversal reaches peers behind NAT through a master. Both sides send
+// the master their candidate addresses, found with STUN, and punch a UDP hole
+// towards each other's. When that fails the master relays the connection.
+// The peers' TLS session runs end to end over either path.
+type natTraversal struct {
+	cfg    *config.NATTraversalConfig
+	crypto *CryptoManager
+	logger *observability.Logger
+
+	mu   sync.Mutex
+	conn *grpc.ClientConn
+}
+
+// newNATTraversal checks cfg; the master is not dialed until needed
+func newNATTraversal(cfg *config.NATTraversalConfig, crypto *CryptoManager, logger *observability.Logger) (*natTraversal, error) {
+	if cfg.MasterAddr == "" || cfg.MasterFingerprint == "" || cfg.RelayToken == "" {
+		return nil, fmt.Errorf("nat_traversal needs master_addr, master_fingerprint and relay_token")
+	}
+	return &natTraversal{cfg: cfg, crypto: crypto, logger: logger}, nil
+}
+
+// client returns a rendezvous client, connecting to the master on first use
+func (n *natTraversal) client() (pb.RendezvousServiceClient, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.conn == nil {
+		tlsConfig, err := n.crypto.TLSClientConfig(n.cfg.MasterFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := grpc.Dial(n.cfg.MasterAddr, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to rendezvous master: %w", err)
+		}
+		n.conn = conn
+	}
+	return pb.NewRendezvousServiceClient(n.conn), nil
+}
+
+// withToken attaches the relay token to a rendezvous call
+func (n *natTraversal) withToken(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, RelayTokenHeader, n.cfg.RelayToken)
+}
+
+// Close drops the connection to the master
+func (n *natTraversal) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+}
+
+// dial reaches the peer with fingerprint through the master and returns the
+// punched or relayed connection and which it is
+func (n *natTraversal) dial(ctx context.Context, fingerprint string) (net.Conn, ConnectionType, error) {
+	client, err := n.client()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	udp, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open udp socket: %w", err)
+	}
+	sessionID, err := newSessionID()
+	if err != nil {
+		udp.Close()
+		return nil, 0, err
+	}
+
+	answer, err := client.Offer(n.withToken(ctx), &pb.RendezvousOffer{
+		SessionId:         sessionID,
+		TargetFingerprint: fingerprint,
+		Candidates:        n.candidates(udp),
+	})
+	if err != nil {
+		udp.Close()
+		return nil, 0, fmt.Errorf("rendezvous offer failed: %w", err)
+	}
+	if answer.Error != "" {
+		udp.Close()
+		return nil, 0, fmt.Errorf("peer declined rendezvous: %s", answer.Error)
+	}
+
+	return n.connect(ctx, client, udp, sessionID, answer.Candidates)
+}
+
+// connect punches a hole towards candidates, falling back to the relay
+func (n *natTraversal) connect(ctx context.Context, client pb.RendezvousServiceClient, udp *net.UDPConn, sessionID string, candidates []string) (net.Conn, ConnectionType, error) {
+	punchCtx, cancel := context.WithTimeout(ctx, PunchTimeout)
+	remote, err := punchHole(punchCtx, udp, resolveCandidates(candidates), []byte(sessionID))
+	cancel()
+	if err == nil {
+		n.logger.Info("punched udp path through nat",
+			zap.String("session_id", sessionID),
+			zap.String("remote", remote.String()),
+		)
+		return newUDPStream(udp, remote, []byte(sessionID)), ConnectionNAT, nil
+	}
+	udp.Close()
+
+	n.logger.Info("hole punching failed, relaying through master",
+		zap.String("session_id", sessionID),
+		zap.Error(err),
+	)
+	conn, err := n.relay(client, sessionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return conn, ConnectionRelay, nil
+}
+
+// relay opens the session's relay stream. It outlives ctx: the connection
+// is used long after the traversal that opened it.
+func (n *natTraversal) relay(client pb.RendezvousServiceClient, sessionID string) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(n.withToken(context.Background()))
+	stream, err := client.Relay(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open relay: %w", err)
+	}
+	if err := stream.Send(&pb.RelayFrame{SessionId: sessionID}); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open relay: %w", err)
+	}
+	return &relayConn{stream: stream, cancel: cancel, sessionID: sessionID}, nil
+}
+
+// listen keeps this node reachable through the master, handing every
+// connection a trusted peer opens to serve, until ctx is cancelled
+func (n *natTraversal) listen(ctx context.Context, serve func(net.Conn)) {
+	backoff := 5 * time.Second
+	for ctx.Err() == nil {
+		err := n.listenOnce(ctx, serve)
+		if ctx.Err() != nil {
+			return
+		}
+		n.logger.Warn("rendezvous listen ended, retrying",
+			zap.String("master", n.cfg.MasterAddr),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (n *natTraversal) listenOnce(ctx context.Context, serve func(net.Conn)) error {
+	client, err := n.client()
+	if err != nil {
+		return err
+	}
+	stream, err := client.Listen(n.withToken(ctx), &pb.RendezvousListen{})
+	if err != nil {
+		return err
+	}
+	n.logger.Info("listening for peers through rendezvous master", zap.String("master", n.cfg.MasterAddr))
+
+	for {
+		offer, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		go n.answer(ctx, client, offer, serve)
+	}
+}
+
+// answer replies to an offer from a trusted peer and serves the connection
+// that follows
+func (n *natTraversal) answer(ctx context.Context, client pb.RendezvousServiceClient, offer *pb.RendezvousOffer, serve func(net.Conn)) {
+	ctx, cancel := context.WithTimeout(ctx, NATTraversalTimeout)
+	defer cancel()
+
+	reply := &pb.RendezvousAnswer{SessionId: offer.SessionId}
+	if !n.crypto.IsTrusted(offer.SourceFingerprint) {
+		n.logger.Warn("refused rendezvous from untrusted peer",
+			zap.String("fingerprint", offer.SourceFingerprint),
+		)
+		reply.Error = "not paired"
+		client.Answer(n.withToken(ctx), reply)
+		return
+	}
+
+	udp, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		reply.Error = "no udp socket"
+		client.Answer(n.withToken(ctx), reply)
+		return
+	}
+	reply.Candidates = n.candidates(udp)
+	if _, err := client.Answer(n.withToken(ctx), reply); err != nil {
+		udp.Close()
+		n.logger.Warn("failed to answer rendezvous offer", zap.Error(err))
+		return
+	}
+
+	conn, kind, err := n.connect(ctx, client, udp, offer.SessionId, offer.Candidates)
+	if err != nil {
+		n.logger.Warn("failed to reach peer through nat",
+			zap.String("fingerprint", offer.SourceFingerprint),
+			zap.Error(err),
+		)
+		return
+	}
+	n.logger.Info("accepted peer connection through nat",
+		zap.String("fingerprint", offer.SourceFingerprint),
+		zap.String("connection", kind.String()),
+	)
+	serve(conn)
+}
+
+// candidates returns the addresses udp may be reached at: its public
+// address as seen by a STUN server, then its address on each local network
+func (n *natTraversal) candidates(udp *net.UDPConn) []string {
+	port := udp.LocalAddr().(*net.UDPAddr).Port
+	var candidates []string
+
+	servers := n.cfg.STUNServers
+	if len(servers) == 0 {
+		servers = []string{DefaultSTUNServer}
+	}
+	for _, server := range servers {
+		addr, err := stunMappedAddress(udp, server, stunTimeout)
+		if err != nil {
+			n.logger.Debug("stun server did not answer", zap.String("server", server), zap.Error(err))
+			continue
+		}
+		candidates = append(candidates, addr.String())
+		break
+	}
+
+	addrs, err := net.InterfaceAddrs()
+	if err == nil {
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() || ipNet.IP.To4() == nil {
+				continue
+			}
+			candidates = append(candidates, net.JoinHostPort(ipNet.IP.String(), strconv.Itoa(port)))
+		}
+	}
+	return candidates
+}
+
+// resolveCandidates parses candidate addresses, skipping malformed ones
+func resolveCandidates(candidates []string) []*net.UDPAddr {
+	addrs := make([]*net.UDPAddr, 0, len(candidates))
+	for _, candidate := range candidates {
+		addr, err := net.ResolveUDPAddr("udp4", candidate)
+		if err == nil && !containsUDPAddr(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// relayConn is a connection relayed by the master, as a net.Conn
+type relayConn struct {
+	stream    pb.RendezvousService_RelayClient
+	cancel    context.CancelFunc
+	sessionID string
+
+	readMu  sync.Mutex
+	pending []byte
+
+	writeMu sync.Mutex
+	once    sync.Once
+}
+
+func (c *relayConn) Read(p []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for len(c.pending) == 0 {
+		frame, err := c.stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return 0, io.EOF
+			}
+			return 0, fmt.Errorf("relay: %w", err)
+		}
+		c.pending = frame.Data
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *relayConn) Write(p []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	written := 0
+	for written < len(p) {
+		end := written + relayFrameSize
+		if end > len(p) {
+			end = len(p)
+		}
+		data := append([]byte(nil), p[written:end]...)
+		if err := c.stream.Send(&pb.RelayFrame{Data: data}); err != nil {
+			return written, fmt.Errorf("relay: %w", err)
+		}
+		written = end
+	}
+	return written, nil
+}
+
+func (c *relayConn) Close() error {
+	c.once.Do(func() {
+		c.writeMu.Lock()
+		c.stream.CloseSend()
+		c.writeMu.Unlock()
+		c.cancel()
+	})
+	return nil
+}
+
+func (c *relayConn) LocalAddr() net.Addr  { return relayAddr(c.sessionID) }
+func (c *relayConn) RemoteAddr() net.Addr { return relayAddr(c.sessionID) }
+
+// Deadlines are not supported; a broken relay ends with the stream
+func (c *relayConn) SetDeadline(time.Time) error      { return nil }
+func (c *relayConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *relayConn) SetWriteDeadline(time.Time) error { return nil }
+
+// relayAddr names the relay session as a connection's address
+type relayAddr string
+
+func (a relayAddr) Network() string { return "relay" }
+func (a relayAddr) String() string  { return "relay:" + string(a) }
+
+// connDialOption hands gRPC an already established connection. The path
+// cannot be redialed, so any later dial fails and the client must be
+// recreated through traversal.
+func connDialOption(conn net.Conn) grpc.DialOption {
+	var once sync.Once
+	return grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+		var dialed net.Conn
+		once.Do(func() { dialed = conn })
+		if dialed == nil {
+			return nil, fmt.Errorf("nat path to %s is closed", addr)
+		}
+		return dialed, nil
+	})
+}
+
+// singleConnListener serves one accepted connection to a gRPC server, and
+// reports itself closed once that connection is
+type singleConnListener struct {
+	conn   chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+	addr   net.Addr
+}
+
+func newSingleConnListener(conn net.Conn) *singleConnListener {
+	l := &singleConnListener{
+		conn:   make(chan net.Conn, 1),
+		closed: make(chan struct{}),
+		addr:   conn.LocalAddr(),
+	}
+	l.conn <- &closeNotifyConn{Conn: conn, onClose: l.close}
+	return l
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conn:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *singleConnListener) Close() error {
+	l.close()
+	return nil
+}
+
+func (l *singleConnListener) close() {
+	l.once.Do(func() { close(l.closed) })
+}
+
+func (l *singleConnListener) Addr() net.Addr { return l.addr }
+
+// closeNotifyConn calls onClose after closing the connection
+type closeNotifyConn struct {
+	net.Conn
+	onClose func()
+}
+
+func (c *closeNotifyConn) Close() error {
+	err := c.Conn.Close()
+	c.onClose()
+	return err
+}
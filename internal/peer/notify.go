@@ -0,0 +1,73 @@
+package peer
+
+import (
+	"time"
+)
+
+// TransferEvent reports a transfer reaching a new status
+type TransferEvent struct {
+	TransferID       string
+	Type             TransferType
+	SourceID         string
+	DestPeer         string
+	Status           TransferStatus
+	TransferredBytes int64
+	TotalBytes       int64
+	Error            string
+	Time             time.Time
+}
+
+// TransferListener receives transfer status changes. It is called on the
+// goroutine that changed the status, with no transfer locks held, so it
+// must not block for long.
+type TransferListener func(TransferEvent)
+
+// OnStatusChange registers fn to be called whenever a transfer completes,
+// fails, stalls or is paused. Call the returned function to unregister.
+func (tm *TransferManager) OnStatusChange(fn TransferListener) func() {
+	tm.listenersMu.Lock()
+	defer tm.listenersMu.Unlock()
+
+	if tm.listeners == nil {
+		tm.listeners = make(map[int]TransferListener)
+	}
+	id := tm.nextListener
+	tm.nextListener++
+	tm.listeners[id] = fn
+
+	return func() {
+		tm.listenersMu.Lock()
+		defer tm.listenersMu.Unlock()
+		delete(tm.listeners, id)
+	}
+}
+
+// notify delivers event to every listener. Callers must not hold tm.mu or
+// the transfer's lock, since listeners may call back into the manager.
+func (tm *TransferManager) notify(event TransferEvent) {
+	tm.listenersMu.RLock()
+	listeners := make([]TransferListener, 0, len(tm.listeners))
+	for _, fn := range tm.listeners {
+		listeners = append(listeners, fn)
+	}
+	tm.listenersMu.RUnlock()
+
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
+// event snapshots the transfer's current state; the caller holds t.mu
+func (t *Transfer) event() TransferEvent {
+	return TransferEvent{
+		TransferID:       t.ID,
+		Type:             t.Type,
+		SourceID:         t.SourceID,
+		DestPeer:         t.DestPeer,
+		Status:           t.Status,
+		TransferredBytes: t.TransferredBytes,
+		TotalBytes:       t.TotalBytes,
+		Error:            t.Error,
+		Time:             time.Now(),
+	}
+}
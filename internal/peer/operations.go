@@ -0,0 +1,40 @@
+package peer
+
+import (
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Operation is something a caller may have this host do
+type Operation string
+
+const (
+	OpReceiveContainers Operation = "receive_containers"
+	OpReceiveImages     Operation = "receive_images"
+	OpReceiveVolumes    Operation = "receive_volumes"
+	OpReceiveNetworks   Operation = "receive_networks"
+	OpRemoveResources   Operation = "remove_resources"
+)
+
+// OperationGuard is a host's local policy on what callers may have it do
+type OperationGuard interface {
+	Allows(op Operation) bool
+}
+
+// WithOperationGuard refuses calls that need an operation guard does not
+// allow, whoever the caller is
+func WithOperationGuard(guard OperationGuard) GRPCServerOption {
+	return func(gs *GRPCServer) {
+		gs.guard = guard
+	}
+}
+
+// allow returns a PermissionDenied error if the guard refuses op
+func (gs *GRPCServer) allow(op Operation) error {
+	if gs.guard == nil || gs.guard.Allows(op) {
+		return nil
+	}
+	gs.logger.Warn("refusing call by local policy", zap.String("operation", string(op)))
+	return status.Errorf(codes.PermissionDenied, "refused by worker policy: %s not allowed", op)
+}
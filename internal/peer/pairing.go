@@ -1,17 +1,22 @@
 package peer
 
 import (
-	"crypto/ecdh"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base32"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"filippo.io/edwards25519"
+	"github.com/artemis/docker-migrate/internal/audit"
 	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/observability"
 	"go.uber.org/zap"
@@ -25,33 +30,42 @@ const (
 	// Rate limiting
 	MaxAttemptsPerMinute = 5
 	BanDuration          = 15 * time.Minute
+
+	// MaxPairingExchanges is how many pairing attempts one code answers
+	// before it is withdrawn
+	MaxPairingExchanges = 5
 )
 
 // PairingManager handles secure peer pairing with rate limiting
-// Uses X25519 ECDH key exchange with pairing code as verification
+// Uses SPAKE2 keyed by the pairing code, so the code cannot be guessed offline
 type PairingManager struct {
 	activeSessions map[string]*PairingSession
 	trustedPeers   map[string]*TrustedPeer
+	unconfirmed    map[string]*TrustedPeer // Paired, awaiting the user's check of the symbols
 	attempts       map[string]*rateLimitTracker
 	config         *config.Config
 	crypto         *CryptoManager
 	logger         *observability.Logger
+	rateLimitPath  string             // Where attempts survive restarts; empty disables persistence
+	onPaired       func(*TrustedPeer) // Called when a peer pairs with one of our codes
+	auditLog       *audit.Log         // Records pairings peers start with us; nil records nothing
 	mu             sync.RWMutex
 }
 
 // PairingSession represents an active pairing session
 type PairingSession struct {
 	Code         string
-	CodeHash     []byte // SHA-256 hash of code for verification
+	Password     *edwards25519.Scalar // SPAKE2 password scalar derived from the code
 	ExpiresAt    time.Time
-	PrivateKey   *ecdh.PrivateKey // X25519 ephemeral key
-	PublicKey    []byte           // Our public key to send
-	PeerPublic   []byte           // Peer's public key
-	SharedSecret []byte           // Derived shared secret
+	PublicKey    []byte // Our SPAKE2 share in the current exchange
+	PeerPublic   []byte // Peer's SPAKE2 share
+	PeerConfirm  []byte // Key confirmation the peer must send
+	SharedSecret []byte // Derived shared secret
 	PeerCert     *x509.Certificate
 	PeerAddress  string
 	Role         PairingRole
 	Created      time.Time
+	Exchanges    int // Exchanges opened against this code
 	Completed    bool
 }
 
@@ -72,7 +86,9 @@ type TrustedPeer struct {
 	FirstSeen   time.Time
 	LastSeen    time.Time
 	Address     string
+	Addresses   []string // Fallback addresses tried after Address
 	Certificate *x509.Certificate
+	SSHTunnel   *config.SSHTunnelConfig // Overrides the global ssh_tunnel
 }
 
 // rateLimitTracker tracks pairing attempts for rate limiting
@@ -82,24 +98,41 @@ type rateLimitTracker struct {
 	bannedUntil  time.Time
 }
 
+// persistedRateLimit is the on-disk form of a rateLimitTracker
+type persistedRateLimit struct {
+	Attempts     int       `json:"attempts"`
+	FirstAttempt time.Time `json:"first_attempt"`
+	BannedUntil  time.Time `json:"banned_until,omitempty"`
+}
+
 // PairingMessage is exchanged during pairing
 type PairingMessage struct {
-	PublicKey    []byte `json:"public_key"`
-	CodeVerifier []byte `json:"code_verifier"` // HMAC of public key using code hash
+	PublicKey    []byte `json:"public_key"`    // SPAKE2 share
+	CodeVerifier []byte `json:"code_verifier"` // Key confirmation; empty on the opening message
 	Certificate  []byte `json:"certificate"`   // PEM encoded certificate
 }
 
-// NewPairingManager creates a new pairing manager
-func NewPairingManager(cfg *config.Config, crypto *CryptoManager, logger *observability.Logger) *PairingManager {
+// NewPairingManager creates a new pairing manager. Expired sessions are
+// cleaned up in the background until ctx is cancelled.
+func NewPairingManager(ctx context.Context, cfg *config.Config, crypto *CryptoManager, logger *observability.Logger) *PairingManager {
 	pm := &PairingManager{
 		activeSessions: make(map[string]*PairingSession),
 		trustedPeers:   make(map[string]*TrustedPeer),
+		unconfirmed:    make(map[string]*TrustedPeer),
 		attempts:       make(map[string]*rateLimitTracker),
 		config:         cfg,
 		crypto:         crypto,
 		logger:         logger,
 	}
 
+	// Reload bans so restarting the daemon doesn't reset them
+	if path, err := rateLimitStatePath(cfg.DataDir); err != nil {
+		logger.Warn("pairing rate-limit persistence disabled", zap.Error(err))
+	} else {
+		pm.rateLimitPath = path
+		pm.loadRateLimits()
+	}
+
 	// Load trusted peers from config
 	for _, peer := range cfg.ListTrustedPeers() {
 		pm.trustedPeers[peer.ID] = &TrustedPeer{
@@ -109,16 +142,29 @@ func NewPairingManager(cfg *config.Config, crypto *CryptoManager, logger *observ
 			FirstSeen:   peer.AddedAt,
 			LastSeen:    peer.LastSeen,
 			Address:     peer.Address,
+			Addresses:   peer.Addresses,
+			SSHTunnel:   peer.SSHTunnel,
 		}
+		// Accept their connections again after a restart
+		crypto.TrustFingerprint(peer.Fingerprint)
 	}
 
 	// Start cleanup goroutine
-	go pm.cleanupExpiredSessions()
+	go pm.cleanupExpiredSessions(ctx)
 
 	return pm
 }
 
-// GeneratePairingCode creates a 6-char alphanumeric code valid for 5 minutes
+// SetAuditLog records pairings that peers start with our codes in log
+func (pm *PairingManager) SetAuditLog(log *audit.Log) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.auditLog = log
+}
+
+// GeneratePairingCode creates a 6-char alphanumeric code valid for 5
+// minutes. A host answering a pairing cannot tell from the exchange which
+// code the other side holds, so only the newest code is kept.
 func (pm *PairingManager) GeneratePairingCode() (string, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
@@ -129,25 +175,20 @@ func (pm *PairingManager) GeneratePairingCode() (string, error) {
 		return "", fmt.Errorf("failed to generate pairing code: %w", err)
 	}
 
-	// Generate ephemeral X25519 key pair
-	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
-	if err != nil {
-		return "", fmt.Errorf("failed to generate ephemeral key: %w", err)
+	for existing, session := range pm.activeSessions {
+		if !session.Completed {
+			delete(pm.activeSessions, existing)
+		}
 	}
 
-	// Hash the code for verification
-	codeHash := sha256.Sum256([]byte(code))
-
 	// Store session
 	session := &PairingSession{
-		Code:       code,
-		CodeHash:   codeHash[:],
-		ExpiresAt:  time.Now().Add(PairingTimeout),
-		PrivateKey: privateKey,
-		PublicKey:  privateKey.PublicKey().Bytes(),
-		Role:       RoleInitiator,
-		Created:    time.Now(),
-		Completed:  false,
+		Code:      code,
+		Password:  pairingScalar(code),
+		ExpiresAt: time.Now().Add(PairingTimeout),
+		Role:      RoleInitiator,
+		Created:   time.Now(),
+		Completed: false,
 	}
 
 	pm.activeSessions[code] = session
@@ -160,164 +201,92 @@ func (pm *PairingManager) GeneratePairingCode() (string, error) {
 	return code, nil
 }
 
-// GetPairingMessage returns the message to send during pairing
-func (pm *PairingManager) GetPairingMessage(code string) (*PairingMessage, error) {
-	pm.mu.RLock()
-	session, exists := pm.activeSessions[code]
-	pm.mu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("invalid or expired pairing code")
-	}
-
-	if time.Now().After(session.ExpiresAt) {
-		pm.mu.Lock()
-		delete(pm.activeSessions, code)
-		pm.mu.Unlock()
-		return nil, fmt.Errorf("pairing code expired")
+// pendingSessionLocked returns the code still waiting to be used, if any.
+// pm.mu must be held.
+func (pm *PairingManager) pendingSessionLocked() (string, *PairingSession, bool) {
+	now := time.Now()
+	for code, session := range pm.activeSessions {
+		if session.Role == RoleInitiator && !session.Completed && now.Before(session.ExpiresAt) {
+			return code, session, true
+		}
 	}
-
-	// Create code verifier: HMAC(public_key, code_hash)
-	verifier := computeCodeVerifier(session.PublicKey, session.CodeHash)
-
-	// Get our certificate
-	certPEM := pm.crypto.GetCertificatePEM()
-
-	return &PairingMessage{
-		PublicKey:    session.PublicKey,
-		CodeVerifier: verifier,
-		Certificate:  certPEM,
-	}, nil
+	return "", nil, false
 }
 
-// AcceptPairing handles incoming pairing request
-func (pm *PairingManager) AcceptPairing(code string, peerAddress string, peerMsg *PairingMessage) (*PairingMessage, error) {
-	// Check rate limiting
-	if err := pm.checkRateLimit(peerAddress); err != nil {
-		return nil, err
-	}
-
+// answerExchange runs our half of SPAKE2 for the pending code against the
+// share a peer opened with. It returns our share and our key confirmation;
+// the peer's confirmation, which proves it holds the code, is checked by
+// CompletePairing. Each code answers MaxPairingExchanges openings before it
+// is withdrawn, so guessing it online is capped too.
+func (pm *PairingManager) answerExchange(peerAddress string, clientCert *x509.Certificate, peerMsg *PairingMessage) (*PairingMessage, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	// Record attempt
-	pm.recordAttempt(peerAddress)
-
-	// Hash the provided code
-	codeHash := sha256.Sum256([]byte(code))
-
-	// Verify the peer's code verifier
-	expectedVerifier := computeCodeVerifier(peerMsg.PublicKey, codeHash[:])
-	if !secureCompare(peerMsg.CodeVerifier, expectedVerifier) {
-		return nil, fmt.Errorf("invalid pairing code")
+	code, session, ok := pm.pendingSessionLocked()
+	if !ok {
+		return nil, fmt.Errorf("invalid or expired pairing code")
 	}
-
-	// Generate our ephemeral key pair
-	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	session.Exchanges++
+	if session.Exchanges > MaxPairingExchanges {
+		delete(pm.activeSessions, code)
+		return nil, fmt.Errorf("too many pairing attempts, generate a new code")
 	}
 
-	// Import peer's public key
-	peerPubKey, err := ecdh.X25519().NewPublicKey(peerMsg.PublicKey)
+	share, err := newSPAKEShare(session.Password, spakeN)
 	if err != nil {
-		return nil, fmt.Errorf("invalid peer public key: %w", err)
+		return nil, err
 	}
-
-	// Compute shared secret
-	sharedSecret, err := privateKey.ECDH(peerPubKey)
+	shared, err := share.sharedPoint(session.Password, spakeM, peerMsg.PublicKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to compute shared secret: %w", err)
+		return nil, err
 	}
-
-	// Parse peer certificate
-	peerCert, err := parseCertificatePEM(peerMsg.Certificate)
+	keys, err := derivePairingKeys(session.Password, ComputeFingerprint(clientCert), pm.crypto.GetFingerprint(), peerMsg.PublicKey, share.public, shared)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse peer certificate: %w", err)
+		return nil, err
 	}
 
-	// Create session for this responder
-	session := &PairingSession{
-		Code:         code,
-		CodeHash:     codeHash[:],
-		ExpiresAt:    time.Now().Add(PairingTimeout),
-		PrivateKey:   privateKey,
-		PublicKey:    privateKey.PublicKey().Bytes(),
-		PeerPublic:   peerMsg.PublicKey,
-		SharedSecret: sharedSecret,
-		PeerCert:     peerCert,
-		PeerAddress:  peerAddress,
-		Role:         RoleResponder,
-		Created:      time.Now(),
-		Completed:    false,
-	}
-
-	pm.activeSessions[code+"-responder"] = session
-
-	// Create our response
-	verifier := computeCodeVerifier(session.PublicKey, codeHash[:])
-	certPEM := pm.crypto.GetCertificatePEM()
-
-	pm.logger.Info("accepted pairing request",
-		zap.String("peer_address", peerAddress),
-	)
+	session.PublicKey = share.public
+	session.PeerPublic = peerMsg.PublicKey
+	session.SharedSecret = keys.secret
+	session.PeerConfirm = keys.confirmA
+	session.PeerCert = clientCert
+	session.PeerAddress = peerAddress
 
 	return &PairingMessage{
-		PublicKey:    session.PublicKey,
-		CodeVerifier: verifier,
-		Certificate:  certPEM,
+		PublicKey:    share.public,
+		CodeVerifier: keys.confirmB,
+		Certificate:  pm.crypto.GetCertificatePEM(),
 	}, nil
 }
 
-// CompletePairing finishes the pairing and establishes trust
-func (pm *PairingManager) CompletePairing(code string, peerMsg *PairingMessage) (*TrustedPeer, error) {
+// CompletePairing checks the key confirmation a peer sends for the exchange
+// it opened with peerMsg.PublicKey. The peer is held until the user confirms
+// the verification symbols with ConfirmPairing. A wrong confirmation means
+// the peer does not hold the code and ends the exchange.
+func (pm *PairingManager) CompletePairing(clientCert *x509.Certificate, peerMsg *PairingMessage) (*TrustedPeer, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	session, exists := pm.activeSessions[code]
-	if !exists {
-		return nil, fmt.Errorf("invalid or expired pairing code")
-	}
-
-	if time.Now().After(session.ExpiresAt) {
-		delete(pm.activeSessions, code)
-		return nil, fmt.Errorf("pairing code expired")
-	}
-
-	if session.Completed {
-		return nil, fmt.Errorf("pairing already completed")
-	}
-
-	// Verify peer's code verifier
-	expectedVerifier := computeCodeVerifier(peerMsg.PublicKey, session.CodeHash)
-	if !secureCompare(peerMsg.CodeVerifier, expectedVerifier) {
-		return nil, fmt.Errorf("invalid pairing verification")
-	}
-
-	// Import peer's public key and compute shared secret
-	peerPubKey, err := ecdh.X25519().NewPublicKey(peerMsg.PublicKey)
-	if err != nil {
-		return nil, fmt.Errorf("invalid peer public key: %w", err)
-	}
-
-	sharedSecret, err := session.PrivateKey.ECDH(peerPubKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to compute shared secret: %w", err)
+	_, session, ok := pm.pendingSessionLocked()
+	if !ok || session.PeerCert == nil || !secureCompare(session.PeerPublic, peerMsg.PublicKey) ||
+		ComputeFingerprint(session.PeerCert) != ComputeFingerprint(clientCert) {
+		return nil, fmt.Errorf("no pairing exchange to complete")
 	}
 
-	// Parse peer certificate
-	peerCert, err := parseCertificatePEM(peerMsg.Certificate)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse peer certificate: %w", err)
+	if !secureCompare(peerMsg.CodeVerifier, session.PeerConfirm) {
+		session.PeerPublic = nil
+		session.PeerConfirm = nil
+		session.SharedSecret = nil
+		session.PeerCert = nil
+		return nil, fmt.Errorf("invalid pairing code")
 	}
 
-	// Derive session key
-	sessionKey, err := pm.crypto.DeriveSessionKey(sharedSecret, session.CodeHash)
+	sessionKey, err := pm.crypto.DeriveSessionKey(session.SharedSecret, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to derive session key: %w", err)
 	}
 
-	// Compute certificate fingerprint
+	peerCert := session.PeerCert
 	fingerprint := ComputeFingerprint(peerCert)
 
 	// Create trusted peer
@@ -333,47 +302,122 @@ func (pm *PairingManager) CompletePairing(code string, peerMsg *PairingMessage)
 		Certificate: peerCert,
 	}
 
-	// Store trusted peer
-	pm.trustedPeers[peerID] = trustedPeer
+	pm.unconfirmed[peerID] = trustedPeer
+
+	// Mark session as completed
+	session.Completed = true
+
+	pm.logger.Info("pairing completed, waiting for the verification symbols to be confirmed",
+		zap.String("peer_id", peerID),
+		zap.String("fingerprint", fingerprint),
+	)
+
+	// Let the cleanup loop drop the session after a minute
+	if expires := time.Now().Add(time.Minute); expires.Before(session.ExpiresAt) {
+		session.ExpiresAt = expires
+	}
+
+	return trustedPeer, nil
+}
+
+// ConfirmPairing trusts a peer whose pairing completed, once the user has
+// checked that both hosts show the same verification symbols
+func (pm *PairingManager) ConfirmPairing(peerID string) (*TrustedPeer, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	trustedPeer, ok := pm.unconfirmed[peerID]
+	if !ok {
+		return nil, fmt.Errorf("no pairing awaiting confirmation for %s", peerID)
+	}
+	if err := pm.storeTrustedPeerLocked(trustedPeer); err != nil {
+		return nil, err
+	}
+	delete(pm.unconfirmed, peerID)
+
+	pm.logger.Info("pairing confirmed",
+		zap.String("peer_id", peerID),
+		zap.String("fingerprint", trustedPeer.Fingerprint),
+	)
+	return trustedPeer, nil
+}
+
+// RejectPairing discards a completed pairing whose symbols did not match,
+// reporting whether one was waiting
+func (pm *PairingManager) RejectPairing(peerID string) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
 
-	// Add certificate to crypto manager's trusted store
-	if err := pm.crypto.AddTrustedCert(peerCert); err != nil {
-		return nil, fmt.Errorf("failed to add trusted certificate: %w", err)
+	if _, ok := pm.unconfirmed[peerID]; !ok {
+		return false
 	}
+	delete(pm.unconfirmed, peerID)
+
+	pm.logger.Warn("pairing rejected, the verification symbols did not match",
+		zap.String("peer_id", peerID),
+	)
+	return true
+}
+
+// UnconfirmedPairing returns a completed pairing awaiting confirmation
+func (pm *PairingManager) UnconfirmedPairing(peerID string) (*TrustedPeer, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	trustedPeer, ok := pm.unconfirmed[peerID]
+	return trustedPeer, ok
+}
+
+// storeTrustedPeerLocked adds a peer's certificate to the trusted store and
+// saves the peer to config. pm.mu must be held.
+func (pm *PairingManager) storeTrustedPeerLocked(trustedPeer *TrustedPeer) error {
+	if err := pm.crypto.AddTrustedCert(trustedPeer.Certificate); err != nil {
+		return fmt.Errorf("failed to add trusted certificate: %w", err)
+	}
+	// Re-pairing keeps a tunnel configured for the peer by hand, and the
+	// addresses it was reachable on become fallbacks for the new one
+	if existing, ok := pm.config.GetTrustedPeer(trustedPeer.ID); ok {
+		if trustedPeer.SSHTunnel == nil {
+			trustedPeer.SSHTunnel = existing.SSHTunnel
+		}
+		trustedPeer.Addresses = mergeAddresses(trustedPeer.Address, trustedPeer.Addresses, append([]string{existing.Address}, existing.Addresses...))
+	}
+	if existing, ok := pm.trustedPeers[trustedPeer.ID]; ok {
+		trustedPeer.Addresses = mergeAddresses(trustedPeer.Address, trustedPeer.Addresses, peerAddresses(existing))
+	}
+	pm.trustedPeers[trustedPeer.ID] = trustedPeer
+
+	// A paired peer no longer waits for trust-on-first-use confirmation
+	pm.crypto.takePending(trustedPeer.Fingerprint)
 
-	// Save to config
 	pm.config.AddTrustedPeer(&config.TrustedPeer{
-		ID:          peerID,
+		ID:          trustedPeer.ID,
 		Name:        trustedPeer.Name,
-		Fingerprint: fingerprint,
-		Address:     session.PeerAddress,
-		AddedAt:     trustedPeer.FirstSeen,
+		Fingerprint: trustedPeer.Fingerprint,
+		Address:     trustedPeer.Address,
+		Addresses:   trustedPeer.Addresses,
+		AddedAt:     time.Now(),
 		LastSeen:    trustedPeer.LastSeen,
+		SSHTunnel:   trustedPeer.SSHTunnel,
 	})
 
 	if err := pm.config.Save(""); err != nil {
 		pm.logger.Warn("failed to save config", zap.Error(err))
 	}
 
-	// Mark session as completed
-	session.Completed = true
-	session.SharedSecret = sharedSecret
-	session.PeerCert = peerCert
-
-	pm.logger.Info("pairing completed",
-		zap.String("peer_id", peerID),
-		zap.String("fingerprint", fingerprint),
-	)
-
-	// Clean up session after delay
-	go func() {
-		time.Sleep(1 * time.Minute)
-		pm.mu.Lock()
-		delete(pm.activeSessions, code)
-		pm.mu.Unlock()
-	}()
+	return nil
+}
 
-	return trustedPeer, nil
+// mergeAddresses appends the previous addresses missing from addrs, leaving
+// out primary, and returns the result
+func mergeAddresses(primary string, addrs, previous []string) []string {
+	merged := make([]string, 0, len(addrs)+len(previous))
+	for _, addr := range append(append([]string(nil), addrs...), previous...) {
+		if addr != "" && addr != primary && !containsAddress(merged, addr) {
+			merged = append(merged, addr)
+		}
+	}
+	return merged
 }
 
 // GetTrustedPeer retrieves a trusted peer by ID
@@ -415,6 +459,39 @@ func (pm *PairingManager) RemoveTrustedPeer(peerID string) error {
 	return nil
 }
 
+// AddStaticPeer trusts a peer declared in configuration or DNS-SD. Unlike
+// paired peers it is not written back to the config file.
+func (pm *PairingManager) AddStaticPeer(peer *TrustedPeer) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if existing, ok := pm.trustedPeers[peer.ID]; ok {
+		peer.FirstSeen = existing.FirstSeen
+		peer.LastSeen = existing.LastSeen
+	}
+	pm.trustedPeers[peer.ID] = peer
+	pm.crypto.TrustFingerprint(peer.Fingerprint)
+}
+
+// RemoveStaticPeer stops trusting a peer added by AddStaticPeer. A peer that
+// has since been paired is kept. Reports whether the peer was removed.
+func (pm *PairingManager) RemoveStaticPeer(peerID string) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	peer, ok := pm.trustedPeers[peerID]
+	if !ok {
+		return false
+	}
+	if _, paired := pm.config.GetTrustedPeer(peerID); paired {
+		return false
+	}
+
+	pm.crypto.RemoveTrustedCert(peer.Fingerprint)
+	delete(pm.trustedPeers, peerID)
+	return true
+}
+
 // ListTrustedPeers returns all trusted peers
 func (pm *PairingManager) ListTrustedPeers() []*TrustedPeer {
 	pm.mu.RLock()
@@ -466,8 +543,11 @@ func (pm *PairingManager) checkRateLimit(address string) error {
 	if tracker.attempts >= MaxAttemptsPerMinute {
 		pm.mu.Lock()
 		tracker.bannedUntil = time.Now().Add(BanDuration)
+		pm.saveRateLimitsLocked()
 		pm.mu.Unlock()
 
+		observability.PairingLockouts.Inc()
+
 		pm.logger.Warn("rate limit exceeded, banning address",
 			zap.String("address", address),
 			zap.Time("banned_until", tracker.bannedUntil),
@@ -487,6 +567,7 @@ func (pm *PairingManager) recordAttempt(address string) {
 			attempts:     1,
 			firstAttempt: time.Now(),
 		}
+		pm.saveRateLimitsLocked()
 		return
 	}
 
@@ -494,18 +575,106 @@ func (pm *PairingManager) recordAttempt(address string) {
 	if time.Since(tracker.firstAttempt) > time.Minute {
 		tracker.attempts = 1
 		tracker.firstAttempt = time.Now()
+		pm.saveRateLimitsLocked()
 		return
 	}
 
 	tracker.attempts++
+	pm.saveRateLimitsLocked()
+}
+
+// rateLimitStatePath returns the rate-limit state file under dataDir (default ~/.docker-migrate)
+func rateLimitStatePath(dataDir string) (string, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return filepath.Join(dataDir, "pairing-ratelimit.json"), nil
+}
+
+// loadRateLimits restores persisted attempt counters and bans
+func (pm *PairingManager) loadRateLimits() {
+	data, err := os.ReadFile(pm.rateLimitPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			pm.logger.Warn("failed to read pairing rate-limit state", zap.Error(err))
+		}
+		return
+	}
+
+	var state map[string]persistedRateLimit
+	if err := json.Unmarshal(data, &state); err != nil {
+		pm.logger.Warn("failed to parse pairing rate-limit state", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for address, entry := range state {
+		if now.After(entry.BannedUntil) && now.Sub(entry.FirstAttempt) > time.Minute {
+			continue // Expired
+		}
+		pm.attempts[address] = &rateLimitTracker{
+			attempts:     entry.Attempts,
+			firstAttempt: entry.FirstAttempt,
+			bannedUntil:  entry.BannedUntil,
+		}
+		if now.Before(entry.BannedUntil) {
+			pm.logger.Info("restored pairing ban",
+				zap.String("address", address),
+				zap.Time("banned_until", entry.BannedUntil),
+			)
+		}
+	}
+}
+
+// saveRateLimitsLocked writes attempt counters and bans atomically; callers hold pm.mu
+func (pm *PairingManager) saveRateLimitsLocked() {
+	if pm.rateLimitPath == "" {
+		return
+	}
+
+	state := make(map[string]persistedRateLimit, len(pm.attempts))
+	for address, tracker := range pm.attempts {
+		state[address] = persistedRateLimit{
+			Attempts:     tracker.attempts,
+			FirstAttempt: tracker.firstAttempt,
+			BannedUntil:  tracker.bannedUntil,
+		}
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		pm.logger.Warn("failed to marshal pairing rate-limit state", zap.Error(err))
+		return
+	}
+
+	tmpPath := pm.rateLimitPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		pm.logger.Warn("failed to write pairing rate-limit state", zap.Error(err))
+		return
+	}
+	if err := os.Rename(tmpPath, pm.rateLimitPath); err != nil {
+		os.Remove(tmpPath)
+		pm.logger.Warn("failed to save pairing rate-limit state", zap.Error(err))
+	}
 }
 
 // cleanupExpiredSessions removes expired pairing sessions
-func (pm *PairingManager) cleanupExpiredSessions() {
+func (pm *PairingManager) cleanupExpiredSessions(ctx context.Context) {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
 		pm.mu.Lock()
 		now := time.Now()
 
@@ -518,25 +687,32 @@ func (pm *PairingManager) cleanupExpiredSessions() {
 			}
 		}
 
+		// Pairings nobody confirmed in time are dropped untrusted
+		for peerID, trustedPeer := range pm.unconfirmed {
+			if now.After(trustedPeer.FirstSeen.Add(PairingTimeout)) {
+				delete(pm.unconfirmed, peerID)
+				pm.logger.Info("dropped unconfirmed pairing",
+					zap.String("peer_id", peerID),
+				)
+			}
+		}
+
 		// Clean up rate limit trackers
+		expired := 0
 		for addr, tracker := range pm.attempts {
 			if now.After(tracker.bannedUntil) && time.Since(tracker.firstAttempt) > time.Minute {
 				delete(pm.attempts, addr)
+				expired++
 			}
 		}
+		if expired > 0 {
+			pm.saveRateLimitsLocked()
+		}
 
 		pm.mu.Unlock()
 	}
 }
 
-// computeCodeVerifier creates HMAC-like verifier
-func computeCodeVerifier(publicKey, codeHash []byte) []byte {
-	// Combine public key and code hash
-	combined := append(publicKey, codeHash...)
-	hash := sha256.Sum256(combined)
-	return hash[:]
-}
-
 // secureCompare performs constant-time comparison
 func secureCompare(a, b []byte) bool {
 	if len(a) != len(b) {
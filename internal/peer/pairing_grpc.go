@@ -0,0 +1,274 @@
+package peer
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/audit"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// PairingRPCTimeout bounds a pairing exchange over gRPC
+const PairingRPCTimeout = 30 * time.Second
+
+// OnPaired registers a function called when a peer completes pairing with
+// one of our codes over gRPC. The peer is not trusted until ConfirmPairing.
+func (pm *PairingManager) OnPaired(fn func(*TrustedPeer)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.onPaired = fn
+}
+
+// PairWith pairs with the host at address using the code it generated. The
+// exchange runs over that host's gRPC port, so its web port need not be
+// reachable from here. It takes two calls: the first swaps SPAKE2 shares and
+// the other host proves it holds the code, the second proves we do. The peer
+// is trusted once the user confirms the verification symbols with
+// ConfirmPairing.
+func (pm *PairingManager) PairWith(ctx context.Context, address, code string) (*TrustedPeer, error) {
+	password := pairingScalar(strings.ToUpper(strings.TrimSpace(code)))
+	share, err := newSPAKEShare(password, spakeM)
+	if err != nil {
+		return nil, err
+	}
+
+	var serverCert *x509.Certificate
+	tlsConfig, err := pm.crypto.captureClientTLSConfig(func(cert *x509.Certificate) {
+		serverCert = cert
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	if pm.config.SSHTunnel != nil {
+		tunnel, err := newSSHTunnel(pm.config.SSHTunnel)
+		if err != nil {
+			return nil, err
+		}
+		defer tunnel.Close()
+		dialOpts = append(dialOpts, tunnel.DialOption())
+	}
+
+	conn, err := grpc.Dial(address, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, PairingRPCTimeout)
+	defer cancel()
+
+	client := pb.NewMigrationServiceClient(conn)
+	reply, err := client.Pair(ctx, &pb.PairingExchange{
+		PublicKey:   share.public,
+		Certificate: pm.crypto.GetCertificatePEM(),
+		GrpcPort:    grpcPort(pm.config.GRPCAddr),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("pairing with %s failed: %s", address, status.Convert(err).Message())
+	}
+
+	// The reply must come from the certificate that secured this
+	// connection, and prove the peer holds the code
+	peerCert, err := parseCertificatePEM(reply.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse peer certificate: %w", err)
+	}
+	if serverCert == nil || ComputeFingerprint(serverCert) != ComputeFingerprint(peerCert) {
+		return nil, fmt.Errorf("peer certificate does not match the TLS connection")
+	}
+
+	shared, err := share.sharedPoint(password, spakeN, reply.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := derivePairingKeys(password, pm.crypto.GetFingerprint(), ComputeFingerprint(peerCert), share.public, reply.PublicKey, shared)
+	if err != nil {
+		return nil, err
+	}
+	if !secureCompare(reply.CodeVerifier, keys.confirmB) {
+		return nil, fmt.Errorf("invalid pairing code, or %s is not the host that generated it", address)
+	}
+
+	if _, err := client.Pair(ctx, &pb.PairingExchange{
+		PublicKey:    share.public,
+		CodeVerifier: keys.confirmA,
+		Certificate:  pm.crypto.GetCertificatePEM(),
+		GrpcPort:     grpcPort(pm.config.GRPCAddr),
+	}); err != nil {
+		return nil, fmt.Errorf("pairing with %s failed: %s", address, status.Convert(err).Message())
+	}
+
+	sessionKey, err := pm.crypto.DeriveSessionKey(keys.secret, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive session key: %w", err)
+	}
+
+	trustedPeer := &TrustedPeer{
+		ID:          generatePeerID(peerCert),
+		Name:        peerCert.Subject.CommonName,
+		PublicKey:   sessionKey,
+		Fingerprint: ComputeFingerprint(peerCert),
+		FirstSeen:   time.Now(),
+		LastSeen:    time.Now(),
+		Address:     address,
+		Certificate: peerCert,
+	}
+
+	pm.mu.Lock()
+	pm.unconfirmed[trustedPeer.ID] = trustedPeer
+	pm.mu.Unlock()
+
+	pm.logger.Info("pairing completed, confirm the verification words match the other host's before it is trusted",
+		zap.String("peer_id", trustedPeer.ID),
+		zap.String("fingerprint", trustedPeer.Fingerprint),
+		zap.String("address", address),
+		zap.Strings("verification", verificationWords(pm.Verification(trustedPeer.Fingerprint))),
+	)
+
+	return trustedPeer, nil
+}
+
+// AnswerPairing answers one step of a pairing a remote host runs with our
+// pending code. clientCert is the certificate from the TLS handshake; the one
+// in the message must match it, binding the exchange to that connection. A
+// message without a key confirmation opens the exchange and is answered
+// with our share and confirmation. One with a confirmation completes it and
+// returns the peer, which is trusted once the user confirms the symbols.
+func (pm *PairingManager) AnswerPairing(remoteHost, peerAddress string, clientCert *x509.Certificate, peerMsg *PairingMessage) (*PairingMessage, *TrustedPeer, error) {
+	if err := pm.checkRateLimit(remoteHost); err != nil {
+		return nil, nil, err
+	}
+
+	msgCert, err := parseCertificatePEM(peerMsg.Certificate)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse peer certificate: %w", err)
+	}
+	if ComputeFingerprint(msgCert) != ComputeFingerprint(clientCert) {
+		return nil, nil, fmt.Errorf("peer certificate does not match the TLS connection")
+	}
+
+	pm.mu.RLock()
+	auditLog := pm.auditLog
+	pm.mu.RUnlock()
+	denied := func(err error) error {
+		auditLog.Record(audit.Entry{
+			Action:  "peer.paired",
+			Actor:   "peer:" + remoteHost,
+			Detail:  "fingerprint=" + ComputeFingerprint(clientCert),
+			Outcome: audit.OutcomeDenied,
+		})
+		return err
+	}
+
+	if len(peerMsg.CodeVerifier) == 0 {
+		pm.mu.Lock()
+		pm.recordAttempt(remoteHost)
+		pm.mu.Unlock()
+
+		reply, err := pm.answerExchange(peerAddress, clientCert, peerMsg)
+		if err != nil {
+			return nil, nil, denied(err)
+		}
+		return reply, nil, nil
+	}
+
+	trustedPeer, err := pm.CompletePairing(clientCert, peerMsg)
+	if err != nil {
+		return nil, nil, denied(err)
+	}
+	auditLog.Record(audit.Entry{
+		Action: "peer.paired",
+		Actor:  "peer:" + remoteHost,
+		Target: trustedPeer.ID,
+		Detail: "fingerprint=" + trustedPeer.Fingerprint,
+	})
+
+	pm.mu.RLock()
+	onPaired := pm.onPaired
+	pm.mu.RUnlock()
+	if onPaired != nil {
+		onPaired(trustedPeer)
+	}
+
+	return &PairingMessage{Certificate: pm.crypto.GetCertificatePEM()}, trustedPeer, nil
+}
+
+// Pair answers a pairing exchange from a host that was given one of our codes
+func (gs *GRPCServer) Pair(ctx context.Context, req *pb.PairingExchange) (*pb.PairingExchange, error) {
+	if gs.pairing == nil {
+		return nil, status.Error(codes.Unimplemented, "pairing is not available on this host")
+	}
+	peerInfo, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "no peer info in context")
+	}
+	tlsInfo, ok := peerInfo.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "pairing requires a client certificate")
+	}
+
+	remoteHost, _, err := net.SplitHostPort(peerInfo.Addr.String())
+	if err != nil {
+		remoteHost = peerInfo.Addr.String()
+	}
+
+	// Reach the peer on its gRPC port at the address it connected from
+	var peerAddress string
+	if req.GrpcPort > 0 {
+		peerAddress = net.JoinHostPort(remoteHost, strconv.Itoa(int(req.GrpcPort)))
+	}
+
+	reply, trustedPeer, err := gs.pairing.AnswerPairing(remoteHost, peerAddress, tlsInfo.State.PeerCertificates[0], &PairingMessage{
+		PublicKey:    req.PublicKey,
+		CodeVerifier: req.CodeVerifier,
+		Certificate:  req.Certificate,
+	})
+	if err != nil {
+		gs.logger.Warn("pairing over gRPC rejected",
+			zap.String("remote", remoteHost),
+			zap.Error(err),
+		)
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+
+	if trustedPeer != nil {
+		gs.logger.Info("paired over gRPC, confirm the verification words match the other host's before it is trusted",
+			zap.String("peer_id", trustedPeer.ID),
+			zap.String("address", peerAddress),
+			zap.Strings("verification", verificationWords(gs.pairing.Verification(trustedPeer.Fingerprint))),
+		)
+	}
+
+	return &pb.PairingExchange{
+		PublicKey:    reply.PublicKey,
+		CodeVerifier: reply.CodeVerifier,
+		Certificate:  reply.Certificate,
+		GrpcPort:     grpcPort(gs.config.GRPCAddr),
+	}, nil
+}
+
+// grpcPort returns the port of a listen address such as ":9090", or zero
+func grpcPort(addr string) int32 {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return 0
+	}
+	return int32(n)
+}
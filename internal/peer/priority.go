@@ -0,0 +1,116 @@
+package peer
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TransferPriority orders transfers competing for bandwidth
+type TransferPriority int
+
+const (
+	PriorityNormal TransferPriority = iota
+	PriorityHigh
+)
+
+func (p TransferPriority) String() string {
+	if p == PriorityHigh {
+		return "high"
+	}
+	return "normal"
+}
+
+// ParseTransferPriority converts "high"/"normal" ("" = normal)
+func ParseTransferPriority(s string) TransferPriority {
+	if s == "high" {
+		return PriorityHigh
+	}
+	return PriorityNormal
+}
+
+type priorityKey struct{}
+
+// WithPriority tags a context so transfers created under it use the given priority
+func WithPriority(ctx context.Context, p TransferPriority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the transfer priority carried by ctx
+func PriorityFromContext(ctx context.Context) TransferPriority {
+	if p, ok := ctx.Value(priorityKey{}).(TransferPriority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// acquirePriorityLocked registers a high-priority transfer. Caller must hold tm.mu.
+func (tm *TransferManager) acquirePriorityLocked(transfer *Transfer) {
+	if transfer.Priority != PriorityHigh || transfer.preempting {
+		return
+	}
+	transfer.preempting = true
+	tm.highPriority++
+	if tm.highPriority == 1 {
+		tm.preemptDone = make(chan struct{})
+		tm.logger.Info("high-priority transfer started, pausing normal transfers",
+			zap.String("transfer_id", transfer.ID),
+		)
+	}
+}
+
+// releasePriorityLocked unregisters a high-priority transfer. Caller must hold tm.mu.
+func (tm *TransferManager) releasePriorityLocked(transfer *Transfer) {
+	if !transfer.preempting {
+		return
+	}
+	transfer.preempting = false
+	tm.highPriority--
+	if tm.highPriority == 0 {
+		close(tm.preemptDone)
+		tm.preemptDone = nil
+		tm.logger.Info("no high-priority transfers remaining, resuming normal transfers")
+	}
+}
+
+// WaitForTurn blocks a normal-priority transfer between chunks while any
+// high-priority transfer is running. High-priority transfers never wait.
+func (tm *TransferManager) WaitForTurn(ctx context.Context, transfer *Transfer) error {
+	if transfer.Priority == PriorityHigh {
+		return nil
+	}
+
+	tm.mu.RLock()
+	done := tm.preemptDone
+	tm.mu.RUnlock()
+	if done == nil {
+		return nil
+	}
+
+	tm.logger.Info("transfer preempted by high-priority transfer",
+		zap.String("transfer_id", transfer.ID),
+	)
+
+	transfer.mu.Lock()
+	transfer.Status = TransferPaused
+	transfer.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	transfer.mu.Lock()
+	transfer.Status = TransferActive
+	transfer.LastChunkTime = time.Now() // Waiting is not a stall
+	transfer.mu.Unlock()
+
+	tm.logger.Info("transfer resumed after preemption",
+		zap.String("transfer_id", transfer.ID),
+	)
+
+	// Another high-priority transfer may have started meanwhile
+	return tm.WaitForTurn(ctx, transfer)
+}
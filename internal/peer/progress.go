@@ -0,0 +1,78 @@
+package peer
+
+import (
+	"sync"
+	"time"
+)
+
+// Default progress reporting granularity
+const (
+	ProgressInterval = time.Second // Longest gap between reports while progressing
+	ProgressBytes    = 0           // Report after this much progress; 0 reports by time only
+)
+
+// ProgressThrottle spaces out progress reports so fast transfers do not send
+// one per chunk. A report is due once ProgressBytes have moved or
+// ProgressInterval has passed since the last one; the first is always due.
+type ProgressThrottle struct {
+	bytes    int64
+	interval time.Duration
+
+	mu        sync.Mutex
+	reported  bool
+	lastBytes int64
+	lastAt    time.Time
+}
+
+// NewProgressThrottle returns a throttle using the configured granularity
+func (tm *TransferManager) NewProgressThrottle() *ProgressThrottle {
+	t := &ProgressThrottle{bytes: ProgressBytes, interval: ProgressInterval}
+	if tm != nil && tm.config != nil {
+		if tm.config.ProgressBytes > 0 {
+			t.bytes = tm.config.ProgressBytes
+		}
+		if tm.config.ProgressInterval > 0 {
+			t.interval = tm.config.ProgressInterval
+		}
+	}
+	return t
+}
+
+// Interval is the longest gap between reports, for callers that flush a
+// held-back report on a timer
+func (t *ProgressThrottle) Interval() time.Duration {
+	return t.interval
+}
+
+// Allow reports whether progress at bytesDone should be reported now, and if
+// so counts it as reported
+func (t *ProgressThrottle) Allow(bytesDone int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	due := !t.reported ||
+		time.Since(t.lastAt) >= t.interval ||
+		(t.bytes > 0 && bytesDone-t.lastBytes >= t.bytes)
+	if due {
+		t.reported = true
+		t.lastBytes = bytesDone
+		t.lastAt = time.Now()
+	}
+	return due
+}
+
+// EstimateEnd projects when a transfer that began at start and had moved
+// done of total bytes at now will finish, at the average rate so far. start
+// and now must be on the same clock. It returns the zero time until there is
+// a rate to go by.
+func EstimateEnd(start, now time.Time, done, total int64) time.Time {
+	elapsed := now.Sub(start)
+	if done <= 0 || total <= 0 || elapsed <= 0 {
+		return time.Time{}
+	}
+	if done >= total {
+		return now
+	}
+	remaining := time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+	return now.Add(remaining)
+}
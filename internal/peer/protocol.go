@@ -0,0 +1,95 @@
+package peer
+
+import (
+	"fmt"
+)
+
+// Master-worker protocol versions. Bump ProtocolVersion when a change needs
+// both sides to understand it, and raise MinProtocolVersion only when an
+// older peer can no longer be driven at all.
+//
+//	1: original protocol (registration carries no version)
+//	2: outbound-only workers, disk usage in inventory, worker migration requests
+//	3: targets recreate networks with subnets chosen by the master
+//	4: auto-mode migrations fall back to the master's proxy mid-transfer
+const (
+	ProtocolVersion    int32 = 4
+	MinProtocolVersion int32 = 1
+)
+
+// Feature is a protocol capability that older peers may lack
+type Feature int
+
+const (
+	FeatureOutboundOnly      Feature = iota // Master routes transfers for workers without a listener
+	FeatureDiskUsage                        // Inventory includes a disk usage report
+	FeatureMigrationRequests                // Workers may request migrations for approval
+	FeatureNetworkRecreation                // Targets create networks from master-planned specs
+	FeatureTransferFallback                 // Auto-mode transfers switch to the proxy when direct fails
+)
+
+var featureVersions = map[Feature]int32{
+	FeatureOutboundOnly:      2,
+	FeatureDiskUsage:         2,
+	FeatureMigrationRequests: 2,
+	FeatureNetworkRecreation: 3,
+	FeatureTransferFallback:  4,
+}
+
+func (f Feature) String() string {
+	switch f {
+	case FeatureOutboundOnly:
+		return "outbound-only workers"
+	case FeatureDiskUsage:
+		return "disk usage reporting"
+	case FeatureMigrationRequests:
+		return "worker migration requests"
+	case FeatureNetworkRecreation:
+		return "network recreation"
+	case FeatureTransferFallback:
+		return "proxy transfer fallback"
+	default:
+		return fmt.Sprintf("feature(%d)", int(f))
+	}
+}
+
+// EffectiveProtocolVersion maps the zero value sent by pre-versioning peers to 1
+func EffectiveProtocolVersion(v int32) int32 {
+	if v <= 0 {
+		return 1
+	}
+	return v
+}
+
+// FeatureVersion returns the protocol version that introduced f
+func FeatureVersion(f Feature) int32 {
+	return featureVersions[f]
+}
+
+// SupportsFeature reports whether a negotiated protocol version includes f
+func SupportsFeature(version int32, f Feature) bool {
+	required, ok := featureVersions[f]
+	return ok && EffectiveProtocolVersion(version) >= required
+}
+
+// NegotiateProtocol picks the highest version both sides speak, or explains
+// why there is none. remoteMin of zero means the remote accepts anything up
+// to its own version.
+func NegotiateProtocol(remote, remoteMin int32) (int32, error) {
+	remote = EffectiveProtocolVersion(remote)
+	if remoteMin <= 0 {
+		remoteMin = 1
+	}
+
+	if remote < MinProtocolVersion {
+		return 0, fmt.Errorf("peer speaks protocol v%d but this build requires at least v%d; upgrade the peer", remote, MinProtocolVersion)
+	}
+	if remoteMin > ProtocolVersion {
+		return 0, fmt.Errorf("peer requires protocol v%d or newer but this build speaks up to v%d; upgrade this node", remoteMin, ProtocolVersion)
+	}
+
+	if remote < ProtocolVersion {
+		return remote, nil
+	}
+	return ProtocolVersion, nil
+}
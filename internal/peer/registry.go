@@ -0,0 +1,78 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PullImage pulls an image the sender pushed to an intermediary registry and
+// gives it back its original name. A failed pull is reported in the result so
+// the sender can stream the image instead.
+func (gs *GRPCServer) PullImage(ctx context.Context, req *pb.ImagePullRequest) (*pb.TransferResult, error) {
+	if err := gs.allow(OpReceiveImages); err != nil {
+		return nil, err
+	}
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.Reference == "" {
+		return nil, status.Error(codes.InvalidArgument, "no image reference to pull")
+	}
+
+	start := time.Now()
+	result := &pb.TransferResult{ResourceId: req.ImageId}
+
+	if err := gs.docker.PullImageWithAuth(ctx, req.Reference, req.RegistryAuth); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	if req.Tag != "" && req.Tag != req.Reference {
+		if err := gs.docker.TagImage(ctx, req.Reference, req.Tag); err != nil {
+			result.Error = err.Error()
+			return result, nil
+		}
+		// The image keeps its original name, so the intermediary one can go
+		if err := gs.docker.UntagImage(ctx, req.Reference); err != nil {
+			gs.logger.Warn("failed to remove intermediary image tag",
+				zap.String("ref", req.Reference),
+				zap.Error(err),
+			)
+		}
+	}
+
+	result.Success = true
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	gs.logger.Info("image pulled from intermediary registry",
+		zap.String("image_id", req.ImageId),
+		zap.String("ref", req.Reference),
+		zap.String("tag", req.Tag),
+		zap.Int64("duration_ms", result.DurationMs),
+	)
+	return result, nil
+}
+
+// PullImage asks the peer to pull an image from an intermediary registry and
+// tag it as tag
+func (gc *GRPCClient) PullImage(ctx context.Context, imageID, reference, tag, registryAuth string) error {
+	result, err := gc.client.PullImage(ctx, &pb.ImagePullRequest{
+		ImageId:      imageID,
+		Reference:    reference,
+		Tag:          tag,
+		RegistryAuth: registryAuth,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request peer pull: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("peer could not pull %s: %s", reference, result.Error)
+	}
+	return nil
+}
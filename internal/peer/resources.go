@@ -0,0 +1,106 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetResourceList lists this host's containers and volumes with their
+// labels, so a source can see what earlier migrations left here, and its
+// networks. Images are not listed. With include_details a container's state is
+// Docker's status text, such as "Up 2 minutes (healthy)" or "Exited (1) 5
+// seconds ago", so its health and exit code can be read from it.
+func (gs *GRPCServer) GetResourceList(ctx context.Context, req *pb.ResourceRequest) (*pb.ResourceList, error) {
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+
+	list := &pb.ResourceList{}
+
+	if req.Type == pb.ResourceType_ALL || req.Type == pb.ResourceType_CONTAINERS {
+		containers, err := gs.docker.ListContainers(ctx, true)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "list containers: %v", err)
+		}
+		for _, c := range containers {
+			name := ""
+			if len(c.Names) > 0 {
+				name = strings.TrimPrefix(c.Names[0], "/")
+			}
+			state := c.State
+			if req.IncludeDetails {
+				state = c.Status
+			}
+			list.Containers = append(list.Containers, &pb.ContainerResource{
+				Id:      c.ID,
+				Name:    name,
+				Image:   c.Image,
+				State:   state,
+				Created: c.Created,
+				Labels:  c.Labels,
+			})
+		}
+	}
+
+	if req.Type == pb.ResourceType_ALL || req.Type == pb.ResourceType_VOLUMES {
+		volumes, err := gs.docker.ListVolumes(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "list volumes: %v", err)
+		}
+		for _, vol := range volumes {
+			list.Volumes = append(list.Volumes, &pb.VolumeResource{
+				Name:       vol.Name,
+				Driver:     vol.Driver,
+				Mountpoint: vol.Mountpoint,
+				Labels:     vol.Labels,
+			})
+		}
+	}
+
+	if req.Type == pb.ResourceType_ALL || req.Type == pb.ResourceType_NETWORKS {
+		networks, err := gs.docker.ListNetworks(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "list networks: %v", err)
+		}
+		for _, n := range networks {
+			list.Networks = append(list.Networks, &pb.NetworkResource{
+				Id:             n.ID,
+				Name:           n.Name,
+				Driver:         n.Driver,
+				Scope:          n.Scope,
+				Internal:       n.Internal,
+				ContainerCount: int32(len(n.Containers)),
+			})
+		}
+	}
+
+	return list, nil
+}
+
+// ListContainers returns every container on the peer, running or not, by
+// name without the leading slash. With details each state is Docker's
+// status text; InspectContainer reports the state in fields.
+func (gc *GRPCClient) ListContainers(ctx context.Context, details bool) ([]*pb.ContainerResource, error) {
+	resp, err := gc.client.GetResourceList(ctx, &pb.ResourceRequest{
+		Type:           pb.ResourceType_CONTAINERS,
+		IncludeDetails: details,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peer containers: %w", err)
+	}
+	return resp.Containers, nil
+}
+
+// ListResources returns the peer's containers, volumes and networks
+func (gc *GRPCClient) ListResources(ctx context.Context) (*pb.ResourceList, error) {
+	resp, err := gc.client.GetResourceList(ctx, &pb.ResourceRequest{Type: pb.ResourceType_ALL})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peer resources: %w", err)
+	}
+	return resp, nil
+}
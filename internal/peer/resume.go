@@ -0,0 +1,164 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"github.com/cespare/xxhash/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// partialPath returns where the data of an interrupted volume transfer is
+// kept until its sender reconnects
+func (gs *GRPCServer) partialPath(volumeID string) string {
+	return filepath.Join(gs.spoolDir, fmt.Sprintf("%spartial-%016x", spoolPrefix, xxhash.Sum64String(volumeID)))
+}
+
+// openReceiveFile opens the file a volume transfer starting at offset is
+// written to. Offset zero starts afresh; anything else continues the partial
+// data an interrupted transfer left behind, which must reach that far.
+func (gs *GRPCServer) openReceiveFile(volumeID string, offset int64) (*os.File, error) {
+	partial := gs.partialPath(volumeID)
+
+	if offset == 0 {
+		os.Remove(partial)
+		file, err := os.CreateTemp(gs.spoolDir, spoolPrefix+"*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		return file, nil
+	}
+
+	file, err := os.OpenFile(partial, os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("no partial data to resume from at offset %d", offset)
+	}
+
+	info, err := file.Stat()
+	if err == nil && info.Size() < offset {
+		err = fmt.Errorf("partial data ends at %d, before resume offset %d", info.Size(), offset)
+	}
+	// Anything past the offset was written but never acknowledged
+	if err == nil {
+		err = file.Truncate(offset)
+	}
+	if err == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(partial)
+		return nil, err
+	}
+
+	gs.logger.Info("resuming volume transfer",
+		zap.String("volume_id", volumeID),
+		zap.Int64("offset", offset),
+	)
+	return file, nil
+}
+
+// openVolumeStream opens a volume stream on its own context, watched for
+// stalls. The returned function stops the watchdog and closes the stream.
+func (gc *GRPCClient) openVolumeStream(transfer *Transfer) (pb.MigrationService_TransferVolumeClient, func(), error) {
+	ctx, cancel := context.WithCancel(transfer.ctx)
+	stream, err := gc.client.TransferVolume(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	stopWatch := gc.transfer.WatchStall(transfer, cancel)
+	return stream, func() {
+		stopWatch()
+		cancel()
+	}, nil
+}
+
+// reconnectVolumeStream waits out the backoff for the given attempt and
+// opens a fresh stream. The transfer is marked active again so the stall
+// watchdog judges the new stream on its own.
+func (gc *GRPCClient) reconnectVolumeStream(ctx context.Context, transfer *Transfer, attempt int) (pb.MigrationService_TransferVolumeClient, func(), error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-time.After(gc.transfer.retryBackoff(attempt)):
+	}
+
+	stream, closeStream, err := gc.openVolumeStream(transfer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transfer.mu.Lock()
+	transfer.Status = TransferActive
+	transfer.Error = ""
+	transfer.LastChunkTime = time.Now()
+	transfer.mu.Unlock()
+
+	return stream, closeStream, nil
+}
+
+// maxStreamRetries is how many times a broken transfer stream is reopened
+// in a row before the transfer fails
+func (tm *TransferManager) maxStreamRetries() int {
+	if tm.config != nil {
+		return tm.config.MaxRetries
+	}
+	return 0
+}
+
+// retryBackoff returns the delay before reconnect attempt n (from 1),
+// doubling from RetryBackoff up to RetryMaxBackoff
+func (tm *TransferManager) retryBackoff(attempt int) time.Duration {
+	backoff, maxBackoff := time.Second, time.Minute
+	if tm.config != nil && tm.config.RetryBackoff > 0 {
+		backoff = tm.config.RetryBackoff
+	}
+	if tm.config != nil && tm.config.RetryMaxBackoff > 0 {
+		maxBackoff = tm.config.RetryMaxBackoff
+	}
+
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// errChunkRejected marks a chunk the receiver refused; resending it on a
+// new stream would not help
+var errChunkRejected = errors.New("chunk rejected by peer")
+
+// retryableStreamError reports whether a stream error looks like a broken
+// connection rather than the peer refusing the transfer
+func retryableStreamError(err error) bool {
+	if errors.Is(err, errChunkRejected) {
+		return false
+	}
+	// Stalls cancel the stream, and Send reports a broken stream as EOF
+	if errors.Is(err, ErrTransferStalled) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	switch st.Code() {
+	case codes.Unavailable, codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,52 @@
+package peer
+
+import (
+	"errors"
+
+	"github.com/artemis/docker-migrate/internal/observability"
+	pb "github.com/artemis/docker-migrate/proto"
+)
+
+// MaxChunkRetransmits is how many times a receiver asks for the same chunk
+// again after it fails verification before failing the transfer
+const MaxChunkRetransmits = 3
+
+// ErrChecksumMismatch marks a chunk whose data does not match its checksum,
+// which a fresh copy from the sender can fix
+var ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+
+// chunkRetransmits counts the re-sends a receiver has asked for, per offset,
+// on one stream
+type chunkRetransmits map[int64]int
+
+// request reports whether another re-send of the chunk at offset may be
+// asked for after err. Only checksum failures qualify; a bad offset or a
+// failed write would fail again.
+func (r chunkRetransmits) request(offset int64, err error) bool {
+	if !errors.Is(err, ErrChecksumMismatch) {
+		return false
+	}
+	r[offset]++
+	if r[offset] > MaxChunkRetransmits {
+		observability.RetryAttempts.WithLabelValues("chunk_retransmit", "exhausted").Inc()
+		return false
+	}
+	observability.RetryAttempts.WithLabelValues("chunk_retransmit", "retry").Inc()
+	return true
+}
+
+// retransmitAck asks the sender for the chunk at offset again
+func retransmitAck(offset int64, err error) *pb.TransferAck {
+	return &pb.TransferAck{
+		Offset:     offset,
+		Success:    false,
+		Error:      err.Error(),
+		Retransmit: true,
+	}
+}
+
+// isRetransmit reports whether an ack asks for the chunk at offset again.
+// Senders bound the re-sends themselves too, in case a receiver keeps asking.
+func isRetransmit(ack *pb.TransferAck, offset int64, attempt int) bool {
+	return ack.Retransmit && ack.Offset == offset && attempt < MaxChunkRetransmits
+}
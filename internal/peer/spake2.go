@@ -0,0 +1,134 @@
+package peer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"filippo.io/edwards25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Pairing runs SPAKE2 (RFC 9382) over edwards25519 keyed by the pairing
+// code. Each side's share is blinded with the code, so neither share nor the
+// key confirmations let anyone test guesses at the code offline: every guess
+// costs a pairing attempt against the other host.
+//
+// The host entering the code is A and blinds with spakeM; the host that
+// generated it is B and blinds with spakeN.
+var (
+	spakeM = hashToPoint("docker-migrate SPAKE2 M")
+	spakeN = hashToPoint("docker-migrate SPAKE2 N")
+)
+
+// hashToPoint derives a point whose discrete log nobody knows, by hashing
+// label with a counter until the digest decodes as a point and clearing the
+// cofactor
+func hashToPoint(label string) *edwards25519.Point {
+	identity := edwards25519.NewIdentityPoint()
+	for i := uint32(0); ; i++ {
+		var counter [4]byte
+		binary.BigEndian.PutUint32(counter[:], i)
+		digest := sha256.Sum256(append([]byte(label), counter[:]...))
+
+		p, err := new(edwards25519.Point).SetBytes(digest[:])
+		if err != nil {
+			continue
+		}
+		p.MultByCofactor(p)
+		if p.Equal(identity) == 1 {
+			continue
+		}
+		return p
+	}
+}
+
+// pairingScalar maps a pairing code onto the SPAKE2 password scalar
+func pairingScalar(code string) *edwards25519.Scalar {
+	digest := sha512.Sum512([]byte("docker-migrate pairing code " + code))
+	w, err := edwards25519.NewScalar().SetUniformBytes(digest[:])
+	if err != nil {
+		panic(err) // SetUniformBytes only fails on a length other than 64
+	}
+	return w
+}
+
+// spakeShare is one side's ephemeral SPAKE2 state
+type spakeShare struct {
+	secret *edwards25519.Scalar
+	public []byte // secret·G + w·blind
+}
+
+// newSPAKEShare picks a fresh secret and blinds its public share with the
+// password scalar w times blind
+func newSPAKEShare(w *edwards25519.Scalar, blind *edwards25519.Point) (*spakeShare, error) {
+	var seed [64]byte
+	if _, err := io.ReadFull(rand.Reader, seed[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate pairing share: %w", err)
+	}
+	secret, err := edwards25519.NewScalar().SetUniformBytes(seed[:])
+	if err != nil {
+		return nil, err
+	}
+
+	public := new(edwards25519.Point).ScalarBaseMult(secret)
+	public.Add(public, new(edwards25519.Point).ScalarMult(w, blind))
+	return &spakeShare{secret: secret, public: public.Bytes()}, nil
+}
+
+// sharedPoint unblinds the peer's share, which the peer blinded with
+// peerBlind, and multiplies it by our secret
+func (s *spakeShare) sharedPoint(w *edwards25519.Scalar, peerBlind *edwards25519.Point, peerPublic []byte) ([]byte, error) {
+	p, err := new(edwards25519.Point).SetBytes(peerPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer pairing share: %w", err)
+	}
+	p.Subtract(p, new(edwards25519.Point).ScalarMult(w, peerBlind))
+	shared := new(edwards25519.Point).ScalarMult(s.secret, p)
+	shared.MultByCofactor(shared)
+	if shared.Equal(edwards25519.NewIdentityPoint()) == 1 {
+		return nil, fmt.Errorf("invalid peer pairing share")
+	}
+	return shared.Bytes(), nil
+}
+
+// pairingKeys are what both sides derive from a SPAKE2 transcript
+type pairingKeys struct {
+	secret   []byte // Shared secret the session key is derived from
+	confirmA []byte // Proves to B that A holds the code
+	confirmB []byte // Proves to A that B holds the code
+}
+
+// derivePairingKeys hashes the transcript: both hosts' certificate
+// fingerprints, both shares, the shared point and the password scalar. The
+// fingerprints bind the result to the certificates each side saw in TLS.
+func derivePairingKeys(w *edwards25519.Scalar, fingerprintA, fingerprintB string, shareA, shareB, shared []byte) (*pairingKeys, error) {
+	transcript := sha256.New()
+	for _, part := range [][]byte{[]byte(fingerprintA), []byte(fingerprintB), shareA, shareB, shared, w.Bytes()} {
+		var length [8]byte
+		binary.LittleEndian.PutUint64(length[:], uint64(len(part)))
+		transcript.Write(length[:])
+		transcript.Write(part)
+	}
+	digest := transcript.Sum(nil)
+
+	keys := make([]byte, 96)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, digest, nil, []byte("docker-migrate pairing keys v2")), keys); err != nil {
+		return nil, fmt.Errorf("failed to derive pairing keys: %w", err)
+	}
+
+	mac := func(key []byte) []byte {
+		h := hmac.New(sha256.New, key)
+		h.Write(digest)
+		return h.Sum(nil)
+	}
+	return &pairingKeys{
+		secret:   keys[:32],
+		confirmA: mac(keys[32:64]),
+		confirmB: mac(keys[64:]),
+	}, nil
+}
@@ -0,0 +1,75 @@
+package peer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/observability"
+	"go.uber.org/zap"
+)
+
+const (
+	// spoolPrefix names received transfer files so stale ones can be found
+	spoolPrefix = "volume-transfer-"
+
+	// SpoolReserve is free space kept on the spool filesystem beyond the
+	// incoming transfer so it is never filled completely
+	SpoolReserve = 256 * 1024 * 1024
+)
+
+// SpoolDir returns the directory received transfer files are written to,
+// creating it if needed
+func SpoolDir(cfg *config.Config) (string, error) {
+	dir := cfg.SpoolDir
+	if dir == "" {
+		dataDir, err := config.ResolveDataDir(cfg.DataDir)
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(dataDir, "spool")
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	return dir, nil
+}
+
+// CleanSpool removes transfer files left behind by a previous run. Only
+// called at startup, when no transfer can be using them.
+func CleanSpool(dir string, logger *observability.Logger) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Warn("failed to read spool directory", zap.String("dir", dir), zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), spoolPrefix) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			logger.Warn("failed to remove stale spool file", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		logger.Info("removed stale spool file", zap.String("path", path))
+	}
+}
+
+// checkSpoolSpace fails if the spool filesystem cannot hold size more bytes
+// plus SpoolReserve. Platforms without free space reporting are not checked.
+func checkSpoolSpace(dir string, size int64) error {
+	_, avail, err := docker.DiskSpace(dir)
+	if err != nil {
+		return nil
+	}
+	if need := size + SpoolReserve; avail < need {
+		return fmt.Errorf("insufficient spool space in %s: need %d bytes, %d available", dir, need, avail)
+	}
+	return nil
+}
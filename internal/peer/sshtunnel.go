@@ -0,0 +1,169 @@
+package peer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"google.golang.org/grpc"
+)
+
+// sshTunnel opens gRPC connections as direct-tcpip channels of one SSH
+// connection, which is reopened if it drops. The peer's TLS session runs
+// inside the channel, so the SSH server never sees migration data in clear.
+type sshTunnel struct {
+	addr   string
+	config *ssh.ClientConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+	agent  net.Conn // Open while agent keys may be needed for a reconnect
+}
+
+// newSSHTunnel checks cfg and loads its key and known hosts; nothing is
+// dialed until the first connection
+func newSSHTunnel(cfg *config.SSHTunnelConfig) (*sshTunnel, error) {
+	if cfg.Host == "" || cfg.User == "" {
+		return nil, fmt.Errorf("ssh tunnel needs a host and a user")
+	}
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+
+	knownHostsFile := cfg.KnownHostsFile
+	if knownHostsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find known_hosts: %w", err)
+		}
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known hosts: %w", err)
+	}
+
+	t := &sshTunnel{addr: net.JoinHostPort(cfg.Host, strconv.Itoa(port))}
+
+	var auth ssh.AuthMethod
+	if cfg.KeyFile != "" {
+		key, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ssh key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ssh key %s: %w", cfg.KeyFile, err)
+		}
+		auth = ssh.PublicKeys(signer)
+	} else {
+		socket := os.Getenv("SSH_AUTH_SOCK")
+		if socket == "" {
+			return nil, fmt.Errorf("ssh tunnel needs a key_file or a running ssh-agent")
+		}
+		conn, err := net.Dial("unix", socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reach ssh-agent: %w", err)
+		}
+		t.agent = conn
+		auth = ssh.PublicKeysCallback(agent.NewClient(conn).Signers)
+	}
+
+	t.config = &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         TunnelDialTimeout,
+	}
+	return t, nil
+}
+
+// DialOption routes the gRPC connection through the tunnel
+func (t *sshTunnel) DialOption() grpc.DialOption {
+	return grpc.WithContextDialer(t.dial)
+}
+
+// dial opens a channel to addr, as resolved from the SSH server. A broken SSH
+// connection is replaced once before giving up.
+func (t *sshTunnel) dial(ctx context.Context, addr string) (net.Conn, error) {
+	for attempt := 0; ; attempt++ {
+		client, err := t.connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := client.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			return conn, nil
+		}
+		if attempt > 0 || ctx.Err() != nil {
+			return nil, fmt.Errorf("ssh tunnel to %s via %s: %w", addr, t.addr, err)
+		}
+		t.reset(client)
+	}
+}
+
+// connect returns the SSH connection, opening it if there is none
+func (t *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		return t.client, nil
+	}
+
+	dialer := net.Dialer{Timeout: TunnelDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach ssh server %s: %w", t.addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, t.addr, t.config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", t.addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	t.client = ssh.NewClient(sshConn, chans, reqs)
+	return t.client, nil
+}
+
+// reset drops client if it is still the current connection
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client == client {
+		t.client.Close()
+		t.client = nil
+	}
+}
+
+// Close closes the SSH connection and any agent connection
+func (t *sshTunnel) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var err error
+	if t.client != nil {
+		err = t.client.Close()
+		t.client = nil
+	}
+	if t.agent != nil {
+		t.agent.Close()
+		t.agent = nil
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultStallTimeout is how long a transfer may go without an acknowledged
+// chunk before it is considered stalled
+const DefaultStallTimeout = 60 * time.Second
+
+// ErrTransferStalled is returned when a transfer made no progress within the
+// stall timeout, typically because its connection hung
+var ErrTransferStalled = errors.New("transfer stalled")
+
+func (tm *TransferManager) stallTimeout() time.Duration {
+	if tm.config != nil && tm.config.StallTimeout > 0 {
+		return tm.config.StallTimeout
+	}
+	return DefaultStallTimeout
+}
+
+// WatchStall watches an active transfer's LastChunkTime. If no chunk is
+// acknowledged within the stall timeout the transfer is marked stalled and
+// cancel is called, unblocking a stream stuck on a dead connection.
+// Call the returned function once the stream finishes.
+func (tm *TransferManager) WatchStall(transfer *Transfer, cancel context.CancelFunc) func() {
+	timeout := tm.stallTimeout()
+	interval := timeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			transfer.mu.Lock()
+			idle := time.Since(transfer.LastChunkTime)
+			if transfer.Status != TransferActive || idle < timeout {
+				transfer.mu.Unlock()
+				continue
+			}
+			transfer.Status = TransferStalled
+			transfer.Error = fmt.Sprintf("no progress for %s", idle.Round(time.Second))
+			offset := transfer.TransferredBytes
+			event := transfer.event()
+			transfer.mu.Unlock()
+
+			tm.logger.Warn("transfer stalled",
+				zap.String("transfer_id", transfer.ID),
+				zap.Duration("idle", idle),
+				zap.Int64("offset", offset),
+			)
+			cancel()
+			tm.notify(event)
+			return
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// Stalled reports whether the stall watchdog tore this transfer down
+func (t *Transfer) Stalled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.Status == TransferStalled
+}
+
+// stallError wraps err as ErrTransferStalled if the transfer stalled
+func stallError(transfer *Transfer, err error) error {
+	transfer.mu.RLock()
+	stalled, offset := transfer.Status == TransferStalled, transfer.TransferredBytes
+	transfer.mu.RUnlock()
+
+	if stalled {
+		return fmt.Errorf("%w at offset %d: %v", ErrTransferStalled, offset, err)
+	}
+	return err
+}
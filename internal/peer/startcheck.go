@@ -0,0 +1,90 @@
+package peer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrStartCheckUnsupported is returned by a peer too old to check whether a
+// container can start there
+var ErrStartCheckUnsupported = errors.New("peer is too old to check start conflicts")
+
+// CheckStartConflicts reports what on this host would stop a migrated
+// container starting: its name, host ports or volumes already taken, or a
+// bind-mount source that is missing
+func (gs *GRPCServer) CheckStartConflicts(ctx context.Context, req *pb.StartCheckRequest) (*pb.StartCheckResult, error) {
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+
+	check := docker.StartCheck{
+		Name:       req.ContainerName,
+		Volumes:    req.Volumes,
+		BindPaths:  req.BindPaths,
+		SharedWith: req.SharedWith,
+	}
+	for _, p := range req.Ports {
+		check.Ports = append(check.Ports, docker.HostPort{
+			IP:       p.HostIp,
+			Port:     int(p.HostPort),
+			Protocol: p.Protocol,
+		})
+	}
+
+	conflicts, err := gs.docker.CheckStartConflicts(ctx, check)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "check start conflicts: %v", err)
+	}
+
+	result := &pb.StartCheckResult{}
+	for _, c := range conflicts {
+		result.Conflicts = append(result.Conflicts, &pb.StartConflict{
+			Kind:     c.Kind,
+			Resource: c.Resource,
+			Detail:   c.Detail,
+		})
+	}
+	return result, nil
+}
+
+// CheckStartConflicts asks the peer whether a container can start there
+// now. An empty result means nothing is in the way.
+func (gc *GRPCClient) CheckStartConflicts(ctx context.Context, check docker.StartCheck) ([]docker.StartConflict, error) {
+	req := &pb.StartCheckRequest{
+		ContainerName: check.Name,
+		Volumes:       check.Volumes,
+		BindPaths:     check.BindPaths,
+		SharedWith:    check.SharedWith,
+	}
+	for _, p := range check.Ports {
+		req.Ports = append(req.Ports, &pb.HostPort{
+			HostIp:   p.IP,
+			HostPort: int32(p.Port),
+			Protocol: p.Protocol,
+		})
+	}
+
+	resp, err := gc.client.CheckStartConflicts(ctx, req)
+	if status.Code(err) == codes.Unimplemented {
+		return nil, ErrStartCheckUnsupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check start conflicts: %w", err)
+	}
+
+	conflicts := make([]docker.StartConflict, 0, len(resp.Conflicts))
+	for _, c := range resp.Conflicts {
+		conflicts = append(conflicts, docker.StartConflict{
+			Kind:     c.Kind,
+			Resource: c.Resource,
+			Detail:   c.Detail,
+		})
+	}
+	return conflicts, nil
+}
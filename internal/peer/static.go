@@ -0,0 +1,250 @@
+package peer
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"go.uber.org/zap"
+)
+
+// DNSSDRefreshInterval is how often DNS-SD peer records are re-resolved
+const DNSSDRefreshInterval = 5 * time.Minute
+
+// NormalizeFingerprint lowercases a SHA-256 fingerprint and strips colon separators
+func NormalizeFingerprint(fingerprint string) (string, error) {
+	fp := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+	if len(fp) != 64 {
+		return "", fmt.Errorf("fingerprint must be a hex SHA-256 digest, got %d characters", len(fp))
+	}
+	if _, err := hex.DecodeString(fp); err != nil {
+		return "", fmt.Errorf("invalid fingerprint: %w", err)
+	}
+	return fp, nil
+}
+
+// staticPeerID derives the same ID pairing would assign to the peer's certificate
+func staticPeerID(fingerprint string) string {
+	return "peer-" + fingerprint[:16]
+}
+
+// LoadStaticPeers trusts and registers every peer declared in the config,
+// then resolves DNS-SD peers if a service name is configured
+func (pd *PeerDiscovery) LoadStaticPeers(ctx context.Context) error {
+	for _, sp := range pd.config.StaticPeers {
+		if err := pd.registerStaticPeer(sp, "config"); err != nil {
+			return fmt.Errorf("invalid static peer %q: %w", sp.Name, err)
+		}
+	}
+
+	if pd.config.PeerDNSSD != "" {
+		pd.refreshDNSSD(ctx)
+	}
+
+	return nil
+}
+
+// StartDNSSDRefresh periodically re-resolves DNS-SD peers until ctx is cancelled
+func (pd *PeerDiscovery) StartDNSSDRefresh(ctx context.Context, interval time.Duration) {
+	if pd.config.PeerDNSSD == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pd.refreshDNSSD(ctx)
+		}
+	}
+}
+
+// refreshDNSSD resolves the configured service and registers every valid
+// target. Peers an earlier lookup registered are dropped once their target
+// is no longer listed; a target whose records could not be read this time
+// keeps its peer until the next refresh.
+func (pd *PeerDiscovery) refreshDNSSD(ctx context.Context) {
+	peers, skipped, err := LookupDNSSDPeers(ctx, pd.config.PeerDNSSD)
+	if err != nil {
+		pd.logger.Warn("DNS-SD peer lookup failed",
+			zap.String("service", pd.config.PeerDNSSD),
+			zap.Error(err),
+		)
+		return
+	}
+	for target, err := range skipped {
+		pd.logger.Warn("ignoring DNS-SD target",
+			zap.String("target", target),
+			zap.Error(err),
+		)
+	}
+
+	current := make(map[string]string)
+	for _, sp := range peers {
+		if err := pd.registerStaticPeer(sp, "dnssd"); err != nil {
+			pd.logger.Warn("ignoring DNS-SD peer",
+				zap.String("name", sp.Name),
+				zap.Error(err),
+			)
+			continue
+		}
+		fingerprint, _ := NormalizeFingerprint(sp.Fingerprint)
+		target, _, _ := net.SplitHostPort(sp.Address)
+		current[staticPeerID(fingerprint)] = target
+	}
+
+	pd.mu.Lock()
+	previous := pd.dnssdPeers
+	pd.dnssdPeers = current
+	for peerID, target := range previous {
+		if _, ok := current[peerID]; ok {
+			continue
+		}
+		if _, ok := skipped[target]; ok {
+			pd.dnssdPeers[peerID] = target
+		}
+	}
+	pd.mu.Unlock()
+
+	for peerID, target := range previous {
+		if _, ok := current[peerID]; ok {
+			continue
+		}
+		if _, ok := skipped[target]; ok || pd.configuredStaticPeer(peerID) {
+			continue
+		}
+		if !pd.pairing.RemoveStaticPeer(peerID) {
+			continue
+		}
+		pd.RemovePeer(peerID)
+		pd.logger.Info("dropped DNS-SD peer no longer listed",
+			zap.String("peer_id", peerID),
+			zap.String("target", target),
+		)
+	}
+}
+
+// configuredStaticPeer reports whether static_peers declares the peer
+func (pd *PeerDiscovery) configuredStaticPeer(peerID string) bool {
+	for _, sp := range pd.config.StaticPeers {
+		if fingerprint, err := NormalizeFingerprint(sp.Fingerprint); err == nil && staticPeerID(fingerprint) == peerID {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupDNSSDPeers resolves SRV records for service and reads each target's
+// pinned fingerprint from its TXT records. A target whose TXT records cannot
+// be read or carry no fingerprint is left out and returned in skipped with
+// the reason, so one bad record does not hide the others. The pinned
+// fingerprints are only as trustworthy as the DNS answers: whoever can
+// answer for the service's records decides which certificates are trusted,
+// so use DNSSEC or a resolver on a network you control.
+func LookupDNSSDPeers(ctx context.Context, service string) (peers []*config.StaticPeer, skipped map[string]error, err error) {
+	_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", service)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve SRV records: %w", err)
+	}
+
+	peers = make([]*config.StaticPeer, 0, len(srvs))
+	skipped = make(map[string]error)
+	for _, srv := range srvs {
+		target := strings.TrimSuffix(srv.Target, ".")
+
+		txts, err := net.DefaultResolver.LookupTXT(ctx, srv.Target)
+		if err != nil {
+			skipped[target] = fmt.Errorf("failed to resolve TXT records: %w", err)
+			continue
+		}
+
+		sp := &config.StaticPeer{
+			Name:    target,
+			Address: net.JoinHostPort(target, strconv.Itoa(int(srv.Port))),
+		}
+		for _, txt := range txts {
+			key, value, ok := strings.Cut(txt, "=")
+			if !ok {
+				continue
+			}
+			switch strings.ToLower(key) {
+			case "fingerprint":
+				sp.Fingerprint = value
+			case "name":
+				sp.Name = value
+			}
+		}
+		if sp.Fingerprint == "" {
+			skipped[target] = fmt.Errorf("no fingerprint TXT record")
+			continue
+		}
+
+		peers = append(peers, sp)
+	}
+
+	return peers, skipped, nil
+}
+
+// registerStaticPeer pins the peer's fingerprint and makes it known to discovery
+func (pd *PeerDiscovery) registerStaticPeer(sp *config.StaticPeer, source string) error {
+	if sp.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+
+	fingerprint, err := NormalizeFingerprint(sp.Fingerprint)
+	if err != nil {
+		return err
+	}
+
+	name := sp.Name
+	if name == "" {
+		name = sp.Address
+	}
+
+	trustedPeer := &TrustedPeer{
+		ID:          staticPeerID(fingerprint),
+		Name:        name,
+		Fingerprint: fingerprint,
+		FirstSeen:   time.Now(),
+		Address:     sp.Address,
+		Addresses:   sp.Addresses,
+		SSHTunnel:   sp.SSHTunnel,
+	}
+	pd.pairing.AddStaticPeer(trustedPeer)
+
+	pd.mu.RLock()
+	existing, known := pd.knownPeers[trustedPeer.ID]
+	unchanged := known && existing.Fingerprint == fingerprint &&
+		containsAddress(existing.Addresses, sp.Address)
+	pd.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	pd.logger.Info("trusting static peer",
+		zap.String("peer_id", trustedPeer.ID),
+		zap.String("source", source),
+		zap.String("address", sp.Address),
+	)
+
+	return pd.RegisterPeer(trustedPeer)
+}
+
+// containsAddress reports whether addr is among addrs
+func containsAddress(addrs []string, addr string) bool {
+	for _, a := range addrs {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
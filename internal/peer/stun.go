@@ -0,0 +1,151 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultSTUNServer discovers the public address when none is configured
+const DefaultSTUNServer = "stun.l.google.com:19302"
+
+// STUN (RFC 5389) message constants; only the binding request is used
+const (
+	stunBindingRequest       = 0x0001
+	stunBindingSuccess       = 0x0101
+	stunMagicCookie          = 0x2112A442
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXORMappedAddress = 0x0020
+	stunHeaderSize           = 20
+)
+
+// stunMappedAddress asks a STUN server which address packets from conn
+// arrive from, which is conn's address on the far side of any NAT
+func stunMappedAddress(conn *net.UDPConn, server string, timeout time.Duration) (*net.UDPAddr, error) {
+	serverAddr, err := net.ResolveUDPAddr("udp4", server)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve stun server %s: %w", server, err)
+	}
+
+	request := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	txID := request[8:20]
+	if _, err := rand.Read(txID); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	defer conn.SetReadDeadline(time.Time{})
+
+	// Resent a few times: either packet may be lost
+	buf := make([]byte, 1500)
+	for attempt := 0; time.Now().Before(deadline); attempt++ {
+		if _, err := conn.WriteToUDP(request, serverAddr); err != nil {
+			return nil, fmt.Errorf("failed to send stun request: %w", err)
+		}
+		wait := time.Now().Add(500 * time.Millisecond << attempt)
+		if wait.After(deadline) {
+			wait = deadline
+		}
+		conn.SetReadDeadline(wait)
+
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				break // Timed out; resend
+			}
+			if !from.IP.Equal(serverAddr.IP) || from.Port != serverAddr.Port {
+				continue
+			}
+			addr, err := parseSTUNResponse(buf[:n], txID)
+			if err != nil {
+				return nil, err
+			}
+			if addr != nil {
+				return addr, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no answer from stun server %s", server)
+}
+
+// parseSTUNResponse returns the mapped address in a binding response to
+// txID, or nil for a packet that is not one
+func parseSTUNResponse(msg, txID []byte) (*net.UDPAddr, error) {
+	if len(msg) < stunHeaderSize ||
+		binary.BigEndian.Uint32(msg[4:8]) != stunMagicCookie ||
+		!bytes.Equal(msg[8:20], txID) {
+		return nil, nil
+	}
+	if binary.BigEndian.Uint16(msg[0:2]) != stunBindingSuccess {
+		return nil, fmt.Errorf("stun server refused the binding request")
+	}
+
+	length := int(binary.BigEndian.Uint16(msg[2:4]))
+	if stunHeaderSize+length > len(msg) {
+		return nil, fmt.Errorf("truncated stun response")
+	}
+	attrs := msg[stunHeaderSize : stunHeaderSize+length]
+
+	var mapped *net.UDPAddr
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if 4+attrLen > len(attrs) {
+			break
+		}
+		value := attrs[4 : 4+attrLen]
+
+		switch attrType {
+		case stunAttrXORMappedAddress:
+			if addr := stunAddress(value, msg[4:20]); addr != nil {
+				return addr, nil
+			}
+		case stunAttrMappedAddress:
+			mapped = stunAddress(value, nil)
+		}
+
+		// Attributes are padded to a multiple of four bytes
+		attrs = attrs[4+(attrLen+3)&^3:]
+	}
+	if mapped == nil {
+		return nil, fmt.Errorf("stun response has no mapped address")
+	}
+	return mapped, nil
+}
+
+// stunAddress decodes an address attribute, XORed with mask (the magic
+// cookie followed by the transaction ID) when mask is set
+func stunAddress(value, mask []byte) *net.UDPAddr {
+	if len(value) < 4 {
+		return nil
+	}
+	var ip net.IP
+	switch value[1] {
+	case 0x01:
+		if len(value) < 8 {
+			return nil
+		}
+		ip = net.IP(append([]byte(nil), value[4:8]...))
+	case 0x02:
+		if len(value) < 20 {
+			return nil
+		}
+		ip = net.IP(append([]byte(nil), value[4:20]...))
+	default:
+		return nil
+	}
+	port := binary.BigEndian.Uint16(value[2:4])
+
+	if mask != nil {
+		port ^= uint16(stunMagicCookie >> 16)
+		for i := range ip {
+			ip[i] ^= mask[i]
+		}
+	}
+	return &net.UDPAddr{IP: ip, Port: int(port)}
+}
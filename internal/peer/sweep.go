@@ -0,0 +1,93 @@
+package peer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SweepCheckpoints removes transfer checkpoints last written before cutoff,
+// returning how many it removed. Checkpoints of transfers that are pending,
+// running or paused are kept however old they are, as are those keep
+// reports as still needed by an unfinished job. keep may be nil.
+func (tm *TransferManager) SweepCheckpoints(cutoff time.Time, keep func(transferID string) bool) int {
+	entries, err := os.ReadDir(tm.checkpointDir)
+	if err != nil {
+		tm.logger.Warn("failed to read checkpoint directory", zap.String("dir", tm.checkpointDir), zap.Error(err))
+		return 0
+	}
+
+	tm.mu.RLock()
+	live := make(map[string]bool)
+	for id, transfer := range tm.activeTransfers {
+		transfer.mu.RLock()
+		switch transfer.Status {
+		case TransferPending, TransferActive, TransferPaused:
+			live[id] = true
+		}
+		transfer.mu.RUnlock()
+	}
+	tm.mu.RUnlock()
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".json") || strings.HasSuffix(name, ".json.tmp")) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimSuffix(name, ".tmp"), ".json")
+		if live[id] || (keep != nil && keep(id)) {
+			continue
+		}
+		if removeIfOlder(filepath.Join(tm.checkpointDir, name), cutoff, tm.logger.Logger) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// SweepSpool removes received transfer files, including partial data kept
+// for a sender to resume, that were last written before cutoff. Returns how
+// many it removed.
+func (gs *GRPCServer) SweepSpool(cutoff time.Time) int {
+	if gs.spoolDir == "" {
+		return 0
+	}
+	entries, err := os.ReadDir(gs.spoolDir)
+	if err != nil {
+		gs.logger.Warn("failed to read spool directory", zap.String("dir", gs.spoolDir), zap.Error(err))
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), spoolPrefix) {
+			continue
+		}
+		if removeIfOlder(filepath.Join(gs.spoolDir, entry.Name()), cutoff, gs.logger.Logger) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// removeIfOlder removes the file at path if it was last modified before
+// cutoff, reporting whether it did
+func removeIfOlder(path string, cutoff time.Time, logger *zap.Logger) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.ModTime().Before(cutoff) {
+		return false
+	}
+	if err := os.Remove(path); err != nil {
+		logger.Warn("failed to remove stale file", zap.String("path", path), zap.Error(err))
+		return false
+	}
+	logger.Debug("removed stale file",
+		zap.String("path", path),
+		zap.Time("modified", info.ModTime()),
+	)
+	return true
+}
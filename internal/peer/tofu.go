@@ -0,0 +1,218 @@
+package peer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxPendingPeers bounds how many unconfirmed peers are remembered, so a
+// noisy network cannot grow the list without limit
+const maxPendingPeers = 64
+
+// PendingPeer is an unknown certificate seen while trust-on-first-use is
+// enabled. It is not trusted until the user confirms it.
+type PendingPeer struct {
+	Fingerprint string    `json:"fingerprint"`
+	Name        string    `json:"name"`
+	Address     string    `json:"address,omitempty"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	Attempts    int       `json:"attempts"`
+
+	cert *x509.Certificate
+}
+
+// EnableTOFU makes unknown peer certificates wait for confirmation instead
+// of being rejected outright
+func (cm *CryptoManager) EnableTOFU() {
+	cm.mu.Lock()
+	cm.tofu = true
+	cm.mu.Unlock()
+
+	cm.logger.Warn("TRUST ON FIRST USE IS ENABLED: any host that connects can be offered for trust. " +
+		"Confirm only fingerprints you have checked, and never enable this outside a lab network.")
+}
+
+// TOFUEnabled reports whether trust-on-first-use is enabled
+func (cm *CryptoManager) TOFUEnabled() bool {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	return cm.tofu
+}
+
+// recordFirstUse remembers an unknown certificate for confirmation. It
+// returns nil if too many are already pending.
+func (cm *CryptoManager) recordFirstUse(cert *x509.Certificate, address string) *PendingPeer {
+	fingerprint := ComputeFingerprint(cert)
+
+	cm.mu.Lock()
+	pending, seen := cm.pending[fingerprint]
+	if !seen && len(cm.pending) >= maxPendingPeers {
+		cm.mu.Unlock()
+		cm.logger.Warn("too many pending peers, not recording", zap.String("fingerprint", fingerprint))
+		return nil
+	}
+	if !seen {
+		pending = &PendingPeer{
+			Fingerprint: fingerprint,
+			Name:        cert.Subject.CommonName,
+			FirstSeen:   time.Now(),
+			cert:        cert,
+		}
+		cm.pending[fingerprint] = pending
+	}
+	if address != "" {
+		pending.Address = address
+	}
+	pending.LastSeen = time.Now()
+	pending.Attempts++
+	snapshot := *pending
+	cm.mu.Unlock()
+
+	if !seen {
+		cm.logger.Warn("UNTRUSTED PEER RECORDED FOR TRUST ON FIRST USE - confirm its fingerprint out of band before trusting it",
+			zap.String("fingerprint", fingerprint),
+			zap.String("name", snapshot.Name),
+			zap.String("address", snapshot.Address),
+		)
+	}
+	return &snapshot
+}
+
+// PendingPeers lists unknown peers awaiting confirmation, oldest first
+func (cm *CryptoManager) PendingPeers() []PendingPeer {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+
+	peers := make([]PendingPeer, 0, len(cm.pending))
+	for _, pending := range cm.pending {
+		peers = append(peers, *pending)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].FirstSeen.Before(peers[j].FirstSeen)
+	})
+	return peers
+}
+
+// takePending removes and returns a pending peer
+func (cm *CryptoManager) takePending(fingerprint string) (*PendingPeer, bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	pending, ok := cm.pending[fingerprint]
+	if ok {
+		delete(cm.pending, fingerprint)
+	}
+	return pending, ok
+}
+
+// RejectPending forgets a pending peer; it is recorded again if it reconnects
+func (cm *CryptoManager) RejectPending(fingerprint string) bool {
+	_, ok := cm.takePending(fingerprint)
+	if ok {
+		cm.logger.Info("rejected trust-on-first-use peer", zap.String("fingerprint", fingerprint))
+	}
+	return ok
+}
+
+// TOFUEnabled reports whether unknown peers are recorded for confirmation
+func (pm *PairingManager) TOFUEnabled() bool {
+	return pm.crypto.TOFUEnabled()
+}
+
+// PendingPeers lists unknown peers awaiting trust-on-first-use confirmation
+func (pm *PairingManager) PendingPeers() []PendingPeer {
+	return pm.crypto.PendingPeers()
+}
+
+// RejectFirstUse forgets a pending peer without trusting it
+func (pm *PairingManager) RejectFirstUse(fingerprint string) bool {
+	return pm.crypto.RejectPending(fingerprint)
+}
+
+// ProbeFirstUse connects to address and records the certificate it presents
+// for confirmation. The handshake also presents ours, so a peer running with
+// trust-on-first-use records this host in turn.
+func (pm *PairingManager) ProbeFirstUse(ctx context.Context, address string) (*PendingPeer, error) {
+	if !pm.crypto.TOFUEnabled() {
+		return nil, fmt.Errorf("trust on first use is not enabled")
+	}
+
+	var peerCert *x509.Certificate
+	tlsConfig, err := pm.crypto.captureClientTLSConfig(func(cert *x509.Certificate) {
+		peerCert = cert
+	})
+	if err != nil {
+		return nil, err
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		Config:    tlsConfig,
+	}
+
+	// The peer may refuse our certificate once it has seen it; that is
+	// expected, and its certificate has been captured by then. Waiting for
+	// its first reply makes sure it has processed ours before hanging up.
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if conn != nil {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	if peerCert == nil {
+		if err == nil {
+			err = fmt.Errorf("no peer certificate provided")
+		}
+		return nil, fmt.Errorf("failed to probe %s: %w", address, err)
+	}
+
+	if pm.crypto.IsTrusted(ComputeFingerprint(peerCert)) {
+		return nil, fmt.Errorf("peer at %s is already trusted", address)
+	}
+
+	pending := pm.crypto.recordFirstUse(peerCert, address)
+	if pending == nil {
+		return nil, fmt.Errorf("too many peers awaiting confirmation")
+	}
+	return pending, nil
+}
+
+// ConfirmFirstUse trusts a pending peer as if it had been paired
+func (pm *PairingManager) ConfirmFirstUse(fingerprint string) (*TrustedPeer, error) {
+	pending, ok := pm.crypto.takePending(fingerprint)
+	if !ok {
+		return nil, fmt.Errorf("no pending peer with fingerprint %s", fingerprint)
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	peerID := generatePeerID(pending.cert)
+	trustedPeer := &TrustedPeer{
+		ID:          peerID,
+		Name:        pending.Name,
+		Fingerprint: pending.Fingerprint,
+		FirstSeen:   pending.FirstSeen,
+		LastSeen:    pending.LastSeen,
+		Address:     pending.Address,
+		Certificate: pending.cert,
+	}
+
+	if err := pm.storeTrustedPeerLocked(trustedPeer); err != nil {
+		return nil, err
+	}
+
+	pm.logger.Warn("peer trusted on first use without pairing",
+		zap.String("peer_id", peerID),
+		zap.String("fingerprint", trustedPeer.Fingerprint),
+	)
+
+	return trustedPeer, nil
+}
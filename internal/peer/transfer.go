@@ -26,9 +26,9 @@ const (
 
 	// Transfer configuration
 	StableChunkCount    = 10
-	CheckpointInterval  = 10 * time.Second
+	CheckpointInterval  = 10 * time.Second // Default longest gap between checkpoint writes
 	KeepaliveInterval   = 30 * time.Second
-	CheckpointBatchSize = 100 // Save checkpoint every N chunks
+	CheckpointBytes     = 64 * 1024 * 1024 // Default progress written between checkpoint writes
 )
 
 // TransferType identifies the type of resource being transferred
@@ -65,6 +65,7 @@ const (
 	TransferPaused
 	TransferCompleted
 	TransferFailed
+	TransferStalled // No progress within the stall timeout; stream torn down
 )
 
 func (s TransferStatus) String() string {
@@ -79,6 +80,8 @@ func (s TransferStatus) String() string {
 		return "completed"
 	case TransferFailed:
 		return "failed"
+	case TransferStalled:
+		return "stalled"
 	default:
 		return "unknown"
 	}
@@ -90,7 +93,19 @@ type TransferManager struct {
 	config          *config.Config
 	logger          *observability.Logger
 	checkpointDir   string
-	mu              sync.RWMutex
+	faults          *FaultInjector
+
+	// highPriority counts running high-priority transfers; preemptDone is
+	// closed when it drops back to zero so paused transfers can continue
+	highPriority int
+	preemptDone  chan struct{}
+
+	// Status change listeners, see OnStatusChange
+	listeners    map[int]TransferListener
+	nextListener int
+	listenersMu  sync.RWMutex
+
+	mu sync.RWMutex
 }
 
 // Transfer represents an ongoing transfer operation
@@ -109,6 +124,9 @@ type Transfer struct {
 	Status           TransferStatus
 	Error            string
 	Speed            float64 // bytes per second
+	Priority         TransferPriority
+	preempting       bool
+	savedOffset      int64 // TransferredBytes at the last checkpoint write
 	ctx              context.Context
 	cancel           context.CancelFunc
 	mu               sync.RWMutex
@@ -133,12 +151,12 @@ type Chunk struct {
 
 // NewTransferManager creates a new transfer manager
 func NewTransferManager(cfg *config.Config, logger *observability.Logger) (*TransferManager, error) {
-	homeDir, err := os.UserHomeDir()
+	dataDir, err := config.ResolveDataDir(cfg.DataDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
 
-	checkpointDir := filepath.Join(homeDir, ".docker-migrate", "checkpoints")
+	checkpointDir := filepath.Join(dataDir, "checkpoints")
 	if err := os.MkdirAll(checkpointDir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create checkpoint directory: %w", err)
 	}
@@ -148,6 +166,7 @@ func NewTransferManager(cfg *config.Config, logger *observability.Logger) (*Tran
 		config:          cfg,
 		logger:          logger,
 		checkpointDir:   checkpointDir,
+		faults:          NewFaultInjector(cfg.FaultInjection, logger),
 	}, nil
 }
 
@@ -167,6 +186,8 @@ func (tm *TransferManager) CreateTransfer(ctx context.Context, transferType Tran
 			return existing, nil
 		}
 		// Resume existing transfer
+		existing.Priority = PriorityFromContext(ctx)
+		tm.acquirePriorityLocked(existing)
 		return tm.resumeTransfer(ctx, existing)
 	}
 
@@ -185,17 +206,20 @@ func (tm *TransferManager) CreateTransfer(ctx context.Context, transferType Tran
 		LastCheckpoint:   time.Now(),
 		Checkpoints:      make([]Checkpoint, 0),
 		Status:           TransferPending,
+		Priority:         PriorityFromContext(ctx),
 		ctx:              ctx,
 		cancel:           cancel,
 	}
 
 	tm.activeTransfers[transferID] = transfer
+	tm.acquirePriorityLocked(transfer)
 
 	tm.logger.Info("created transfer",
 		zap.String("transfer_id", transferID),
 		zap.String("type", transferType.String()),
 		zap.String("source_id", sourceID),
 		zap.Int64("total_bytes", totalBytes),
+		zap.String("priority", transfer.Priority.String()),
 	)
 
 	return transfer, nil
@@ -221,6 +245,8 @@ func (tm *TransferManager) resumeTransfer(ctx context.Context, transfer *Transfe
 	transfer.cancel = cancel
 	transfer.Status = TransferPending
 	transfer.LastChunkTime = time.Now()
+	transfer.LastCheckpoint = time.Now()
+	transfer.savedOffset = transfer.TransferredBytes
 
 	tm.logger.Info("resuming transfer",
 		zap.String("transfer_id", transfer.ID),
@@ -236,6 +262,7 @@ type ChunkReader struct {
 	chunkSize int
 	offset    int64
 	totalSize int64
+	buffer    []byte // Reused across reads when set
 }
 
 // NewChunkReader creates a new chunk reader
@@ -255,9 +282,20 @@ func NewChunkReader(reader io.Reader, chunkSize int, totalSize int64) *ChunkRead
 	}
 }
 
+// ReuseBuffer makes every chunk share one buffer, so streaming a source of
+// any size allocates a single chunk. Each chunk's Data is only valid until
+// the next ReadChunk; use it when chunks are sent before reading on.
+func (cr *ChunkReader) ReuseBuffer() *ChunkReader {
+	cr.buffer = make([]byte, cr.chunkSize)
+	return cr
+}
+
 // ReadChunk reads the next chunk with checksum
 func (cr *ChunkReader) ReadChunk() (*Chunk, error) {
-	buffer := make([]byte, cr.chunkSize)
+	buffer := cr.buffer
+	if buffer == nil {
+		buffer = make([]byte, cr.chunkSize)
+	}
 	n, err := io.ReadFull(cr.reader, buffer)
 
 	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
@@ -293,11 +331,14 @@ func (cr *ChunkReader) GetOffset() int64 {
 	return cr.offset
 }
 
-// ChunkWriter writes chunks with verification
+// ChunkWriter writes chunks with verification. Delivery is at-least-once:
+// after a retry or resume a chunk may arrive again, so data before the
+// committed offset is discarded rather than treated as an error.
 type ChunkWriter struct {
 	writer         io.Writer
 	offset         int64
 	expectedOffset int64
+	duplicateBytes int64
 	logger         *observability.Logger
 }
 
@@ -313,28 +354,42 @@ func NewChunkWriter(writer io.Writer, startOffset int64, logger *observability.L
 
 // WriteChunk writes and verifies a chunk
 func (cw *ChunkWriter) WriteChunk(chunk *Chunk) error {
-	// Verify offset continuity
-	if chunk.Offset != cw.expectedOffset {
+	// Verify offset continuity; a gap means data was lost
+	if chunk.Offset > cw.expectedOffset {
 		return fmt.Errorf("chunk offset mismatch: expected %d, got %d", cw.expectedOffset, chunk.Offset)
 	}
 
+	// Everything in the chunk is already committed
+	end := chunk.Offset + int64(len(chunk.Data))
+	if chunk.Offset < cw.expectedOffset && end <= cw.expectedOffset {
+		cw.discardDuplicate(chunk.Offset, int64(len(chunk.Data)))
+		return nil
+	}
+
 	// Verify checksum
 	hash := xxhash.Sum64(chunk.Data)
 	actualChecksum := fmt.Sprintf("%016x", hash)
 
 	if actualChecksum != chunk.Checksum {
-		return fmt.Errorf("chunk checksum mismatch at offset %d: expected %s, got %s",
-			chunk.Offset, chunk.Checksum, actualChecksum)
+		return fmt.Errorf("%w at offset %d: expected %s, got %s",
+			ErrChecksumMismatch, chunk.Offset, chunk.Checksum, actualChecksum)
+	}
+
+	// A chunk overlapping the committed offset contributes only its tail
+	data := chunk.Data
+	if overlap := cw.expectedOffset - chunk.Offset; overlap > 0 {
+		cw.discardDuplicate(chunk.Offset, overlap)
+		data = data[overlap:]
 	}
 
 	// Write data
-	n, err := cw.writer.Write(chunk.Data)
+	n, err := cw.writer.Write(data)
 	if err != nil {
 		return fmt.Errorf("failed to write chunk at offset %d: %w", chunk.Offset, err)
 	}
 
-	if n != len(chunk.Data) {
-		return fmt.Errorf("incomplete write at offset %d: wrote %d of %d bytes", chunk.Offset, n, len(chunk.Data))
+	if n != len(data) {
+		return fmt.Errorf("incomplete write at offset %d: wrote %d of %d bytes", chunk.Offset, n, len(data))
 	}
 
 	cw.offset += int64(n)
@@ -343,6 +398,25 @@ func (cw *ChunkWriter) WriteChunk(chunk *Chunk) error {
 	return nil
 }
 
+// discardDuplicate records bytes from offset that were already written
+func (cw *ChunkWriter) discardDuplicate(offset, size int64) {
+	cw.duplicateBytes += size
+	observability.ChunksDeduplicated.Inc()
+	if cw.logger != nil {
+		cw.logger.Debug("discarding duplicate chunk data",
+			zap.Int64("offset", offset),
+			zap.Int64("bytes", size),
+			zap.Int64("committed_offset", cw.expectedOffset),
+		)
+	}
+}
+
+// DuplicateBytes returns how many received bytes were discarded as
+// duplicates of data already written
+func (cw *ChunkWriter) DuplicateBytes() int64 {
+	return cw.duplicateBytes
+}
+
 // GetOffset returns current offset
 func (cw *ChunkWriter) GetOffset() int64 {
 	return cw.offset
@@ -427,8 +501,9 @@ func (tm *TransferManager) AddCheckpoint(transferID string, offset int64, checks
 		transfer.Speed = float64(transfer.TransferredBytes) / elapsed
 	}
 
-	// Save checkpoint periodically
-	if len(transfer.Checkpoints)%CheckpointBatchSize == 0 {
+	// Save checkpoint once enough data or time has gone unsaved, whichever
+	// comes first, so the loss on a crash is bounded regardless of chunk size
+	if tm.checkpointDue(transfer) {
 		if err := tm.saveCheckpoint(transfer); err != nil {
 			tm.logger.Warn("failed to save checkpoint",
 				zap.String("transfer_id", transferID),
@@ -450,8 +525,10 @@ func (tm *TransferManager) saveCheckpoint(transfer *Transfer) error {
 		return fmt.Errorf("failed to marshal checkpoint: %w", err)
 	}
 
-	// Atomic write
-	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+	// Atomic write, synced so a checkpoint that claims an offset survives a
+	// power loss along with the data it covers
+	if err := writeFileSync(tmpPath, data, 0600); err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write checkpoint: %w", err)
 	}
 
@@ -459,8 +536,12 @@ func (tm *TransferManager) saveCheckpoint(transfer *Transfer) error {
 		os.Remove(tmpPath)
 		return fmt.Errorf("failed to rename checkpoint: %w", err)
 	}
+	if err := syncDir(tm.checkpointDir); err != nil {
+		return fmt.Errorf("failed to sync checkpoint directory: %w", err)
+	}
 
 	transfer.LastCheckpoint = time.Now()
+	transfer.savedOffset = transfer.TransferredBytes
 
 	tm.logger.Debug("saved checkpoint",
 		zap.String("transfer_id", transfer.ID),
@@ -504,17 +585,17 @@ func (tm *TransferManager) GetTransfer(transferID string) (*Transfer, bool) {
 // CompleteTransfer marks a transfer as completed
 func (tm *TransferManager) CompleteTransfer(transferID string) error {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
 
 	transfer, ok := tm.activeTransfers[transferID]
 	if !ok {
+		tm.mu.Unlock()
 		return fmt.Errorf("transfer not found: %s", transferID)
 	}
 
 	transfer.mu.Lock()
-	defer transfer.mu.Unlock()
 
 	transfer.Status = TransferCompleted
+	tm.releasePriorityLocked(transfer)
 
 	// Save final checkpoint
 	if err := tm.saveCheckpoint(transfer); err != nil {
@@ -532,24 +613,34 @@ func (tm *TransferManager) CompleteTransfer(transferID string) error {
 	checkpointPath := filepath.Join(tm.checkpointDir, transferID+".json")
 	os.Remove(checkpointPath)
 
+	event := transfer.event()
+	transfer.mu.Unlock()
+	tm.mu.Unlock()
+
+	tm.notify(event)
 	return nil
 }
 
 // FailTransfer marks a transfer as failed
 func (tm *TransferManager) FailTransfer(transferID string, err error) error {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
 
 	transfer, ok := tm.activeTransfers[transferID]
 	if !ok {
+		tm.mu.Unlock()
 		return fmt.Errorf("transfer not found: %s", transferID)
 	}
 
 	transfer.mu.Lock()
-	defer transfer.mu.Unlock()
 
-	transfer.Status = TransferFailed
-	transfer.Error = err.Error()
+	// A stall is the more useful explanation than the error it caused, and
+	// listeners have already heard about it
+	stalled := transfer.Status == TransferStalled
+	if !stalled {
+		transfer.Status = TransferFailed
+		transfer.Error = err.Error()
+	}
+	tm.releasePriorityLocked(transfer)
 
 	// Save checkpoint for recovery
 	if saveErr := tm.saveCheckpoint(transfer); saveErr != nil {
@@ -562,16 +653,23 @@ func (tm *TransferManager) FailTransfer(transferID string, err error) error {
 		zap.Error(err),
 	)
 
+	event := transfer.event()
+	transfer.mu.Unlock()
+	tm.mu.Unlock()
+
+	if !stalled {
+		tm.notify(event)
+	}
 	return nil
 }
 
 // CancelTransfer cancels an active transfer
 func (tm *TransferManager) CancelTransfer(transferID string) error {
 	tm.mu.Lock()
-	defer tm.mu.Unlock()
 
 	transfer, ok := tm.activeTransfers[transferID]
 	if !ok {
+		tm.mu.Unlock()
 		return fmt.Errorf("transfer not found: %s", transferID)
 	}
 
@@ -579,6 +677,8 @@ func (tm *TransferManager) CancelTransfer(transferID string) error {
 
 	transfer.mu.Lock()
 	transfer.Status = TransferPaused
+	tm.releasePriorityLocked(transfer)
+	event := transfer.event()
 	transfer.mu.Unlock()
 
 	// Save checkpoint for resume
@@ -590,6 +690,8 @@ func (tm *TransferManager) CancelTransfer(transferID string) error {
 		zap.String("transfer_id", transferID),
 	)
 
+	tm.mu.Unlock()
+	tm.notify(event)
 	return nil
 }
 
@@ -606,6 +708,46 @@ func (tm *TransferManager) ListActiveTransfers() []*Transfer {
 	return transfers
 }
 
+// TransferInfo is a point-in-time copy of a transfer's progress, safe to serialize
+type TransferInfo struct {
+	ID               string    `json:"id"`
+	Type             string    `json:"type"`
+	SourceID         string    `json:"source_id"`
+	TotalBytes       int64     `json:"total_bytes"`
+	TransferredBytes int64     `json:"transferred_bytes"`
+	Status           string    `json:"status"`
+	Speed            float64   `json:"speed"`
+	StartTime        time.Time `json:"start_time"`
+}
+
+// ListPeerTransfers returns snapshots of active transfers with a peer
+func (tm *TransferManager) ListPeerTransfers(peerID string) []TransferInfo {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	infos := make([]TransferInfo, 0)
+	for _, transfer := range tm.activeTransfers {
+		if transfer.DestPeer != peerID {
+			continue
+		}
+
+		transfer.mu.RLock()
+		infos = append(infos, TransferInfo{
+			ID:               transfer.ID,
+			Type:             transfer.Type.String(),
+			SourceID:         transfer.SourceID,
+			TotalBytes:       transfer.TotalBytes,
+			TransferredBytes: transfer.TransferredBytes,
+			Status:           transfer.Status.String(),
+			Speed:            transfer.Speed,
+			StartTime:        transfer.StartTime,
+		})
+		transfer.mu.RUnlock()
+	}
+
+	return infos
+}
+
 // ComputeFileChecksum computes SHA-256 checksum of entire file
 func ComputeFileChecksum(reader io.Reader) (string, error) {
 	hash := sha256.New()
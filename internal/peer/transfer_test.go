@@ -0,0 +1,429 @@
+package peer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/observability"
+	pb "github.com/artemis/docker-migrate/proto"
+	"github.com/cespare/xxhash/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func testLogger() *observability.Logger {
+	return &observability.Logger{Logger: zap.NewNop()}
+}
+
+// testTransferManager returns a transfer manager keeping its state under a
+// test directory, injecting the given faults
+func testTransferManager(t *testing.T, faults *config.FaultInjectionConfig) *TransferManager {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	cfg.RetryBackoff = 200 * time.Millisecond
+	cfg.RetryMaxBackoff = 200 * time.Millisecond
+	cfg.FaultInjection = faults
+	tm, err := NewTransferManager(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("transfer manager: %v", err)
+	}
+	return tm
+}
+
+// streamCounts counts the volume streams a test server accepted and the
+// chunks received on them
+type streamCounts struct {
+	mu      sync.Mutex
+	streams int
+	chunks  int
+}
+
+func (c *streamCounts) get() (streams, chunks int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.streams, c.chunks
+}
+
+type countingStream struct {
+	grpc.ServerStream
+	counts *streamCounts
+}
+
+func (s *countingStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.counts.mu.Lock()
+		s.counts.chunks++
+		s.counts.mu.Unlock()
+	}
+	return err
+}
+
+// startTestServer serves the peer services over an in-memory connection,
+// without TLS or peer checks, spooling received volumes under a test
+// directory
+func startTestServer(t *testing.T) (*GRPCServer, *grpc.ClientConn, *streamCounts) {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.DataDir = t.TempDir()
+	gs := &GRPCServer{
+		transfer: testTransferManager(t, nil),
+		config:   cfg,
+		logger:   testLogger(),
+		spoolDir: t.TempDir(),
+	}
+
+	counts := &streamCounts{}
+	server := grpc.NewServer(grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		counts.mu.Lock()
+		counts.streams++
+		counts.mu.Unlock()
+		return handler(srv, &countingStream{ServerStream: ss, counts: counts})
+	}))
+	gs.RegisterServices(server)
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return gs, conn, counts
+}
+
+// testChunk cuts the chunk at offset out of data, checksummed as a sender would
+func testChunk(data []byte, offset, size int) *pb.VolumeChunk {
+	end := offset + size
+	if end > len(data) {
+		end = len(data)
+	}
+	return &pb.VolumeChunk{
+		VolumeId:  "data",
+		Offset:    int64(offset),
+		Data:      data[offset:end],
+		Checksum:  fmt.Sprintf("%016x", xxhash.Sum64(data[offset:end])),
+		TotalSize: int64(len(data)),
+		IsFinal:   end == len(data),
+	}
+}
+
+// sendChunks sends chunks on a stream, expecting each to be acknowledged
+func sendChunks(t *testing.T, stream pb.MigrationService_TransferVolumeClient, chunks ...*pb.VolumeChunk) {
+	t.Helper()
+	for _, chunk := range chunks {
+		if err := stream.Send(chunk); err != nil {
+			t.Fatalf("send chunk at %d: %v", chunk.Offset, err)
+		}
+		ack, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("ack for chunk at %d: %v", chunk.Offset, err)
+		}
+		if !ack.Success {
+			t.Fatalf("chunk at %d refused: %s", chunk.Offset, ack.Error)
+		}
+		if want := chunk.Offset + int64(len(chunk.Data)); ack.Offset != want {
+			t.Fatalf("ack offset = %d, want %d", ack.Offset, want)
+		}
+	}
+}
+
+// waitForFile waits for a file to appear, as the server keeps a broken
+// stream's data only once its handler returns
+func waitForFile(t *testing.T, path string) []byte {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return data
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not appear: %v", path, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTransferVolumeResumesFromPartialData(t *testing.T) {
+	gs, conn, _ := startTestServer(t)
+	client := pb.NewMigrationServiceClient(conn)
+	data := randomData(10 << 10)
+	const chunkSize = 1 << 10
+
+	// Two chunks are acknowledged, then the stream breaks
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.TransferVolume(ctx)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	sendChunks(t, stream, testChunk(data, 0, chunkSize), testChunk(data, chunkSize, chunkSize))
+	cancel()
+
+	partial := waitForFile(t, gs.partialPath("data"))
+	if !bytes.Equal(partial, data[:2*chunkSize]) {
+		t.Fatalf("kept %d bytes of partial data, want the %d acknowledged", len(partial), 2*chunkSize)
+	}
+
+	// The sender reconnects and carries on from the last acknowledged chunk
+	stream, err = client.TransferVolume(context.Background())
+	if err != nil {
+		t.Fatalf("reopen stream: %v", err)
+	}
+	var rest []*pb.VolumeChunk
+	for offset := 2 * chunkSize; offset < len(data); offset += chunkSize {
+		rest = append(rest, testChunk(data, offset, chunkSize))
+	}
+	sendChunks(t, stream, rest...)
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("stream ended with %v, want EOF", err)
+	}
+
+	if _, err := os.Stat(gs.partialPath("data")); !os.IsNotExist(err) {
+		t.Errorf("partial data left behind after the transfer completed: %v", err)
+	}
+}
+
+func TestTransferVolumeRefusesResumePastPartialData(t *testing.T) {
+	gs, conn, _ := startTestServer(t)
+	client := pb.NewMigrationServiceClient(conn)
+	data := randomData(4 << 10)
+	const chunkSize = 1 << 10
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.TransferVolume(ctx)
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	sendChunks(t, stream, testChunk(data, 0, chunkSize))
+	cancel()
+	waitForFile(t, gs.partialPath("data"))
+
+	// Resuming beyond what was kept would leave a gap
+	stream, err = client.TransferVolume(context.Background())
+	if err != nil {
+		t.Fatalf("reopen stream: %v", err)
+	}
+	if err := stream.Send(testChunk(data, 2*chunkSize, chunkSize)); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	ack, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if ack.Success {
+		t.Fatal("resume past the partial data was accepted")
+	}
+}
+
+func TestTransferVolumeAsksAgainForCorruptedChunk(t *testing.T) {
+	_, conn, _ := startTestServer(t)
+	stream, err := pb.NewMigrationServiceClient(conn).TransferVolume(context.Background())
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	data := randomData(2 << 10)
+	chunk := testChunk(data, 0, 1<<10)
+	good := chunk.Data
+
+	// Every corrupted copy is asked for again, up to the limit
+	for attempt := 1; attempt <= MaxChunkRetransmits; attempt++ {
+		chunk.Data = append([]byte(nil), good...)
+		chunk.Data[attempt] ^= 0xFF
+		if err := stream.Send(chunk); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		ack, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+		if ack.Success || !isRetransmit(ack, chunk.Offset, 0) {
+			t.Fatalf("corrupted copy %d: ack %+v, want a retransmit request", attempt, ack)
+		}
+	}
+
+	// An intact copy is then accepted and the transfer carries on
+	chunk.Data = good
+	sendChunks(t, stream, chunk, testChunk(data, 1<<10, 1<<10))
+}
+
+func TestTransferVolumeFailsAfterRetransmitLimit(t *testing.T) {
+	_, conn, _ := startTestServer(t)
+	stream, err := pb.NewMigrationServiceClient(conn).TransferVolume(context.Background())
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	chunk := testChunk(randomData(1<<10), 0, 1<<10)
+	chunk.Checksum = "0000000000000000"
+
+	for attempt := 0; attempt <= MaxChunkRetransmits; attempt++ {
+		if err := stream.Send(chunk); err != nil {
+			t.Fatalf("send: %v", err)
+		}
+		ack, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+		last := attempt == MaxChunkRetransmits
+		if ack.Success || ack.Retransmit == last {
+			t.Fatalf("attempt %d: ack %+v", attempt, ack)
+		}
+	}
+}
+
+func TestSendVolumeRecoversFromInjectedFaults(t *testing.T) {
+	gs, conn, counts := startTestServer(t)
+	gc := &GRPCClient{
+		conn:   conn,
+		client: pb.NewMigrationServiceClient(conn),
+		transfer: testTransferManager(t, &config.FaultInjectionConfig{
+			CorruptRate:    0.2,
+			DisconnectRate: 0.2,
+			Seed:           7,
+		}),
+		logger: testLogger(),
+	}
+
+	data := randomData(16 * DefaultChunkSize)
+	var chunks int
+	if err := gc.sendVolume(context.Background(), "data", bytes.NewReader(data), int64(len(data)), nil, &chunks); err != nil {
+		t.Fatalf("send volume: %v", err)
+	}
+
+	if chunks != 16 {
+		t.Errorf("%d chunks acknowledged, want 16", chunks)
+	}
+	streams, received := counts.get()
+	if streams < 2 {
+		t.Errorf("the seeded faults caused no reconnect (%d streams)", streams)
+	}
+	if received <= 16 {
+		t.Errorf("the seeded faults caused no re-send (%d chunks received)", received)
+	}
+
+	// The sender is done at the final ack, maybe before the receiver has
+	// cleaned up the data it resumed from
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := os.Stat(gs.partialPath("data"))
+		if os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("partial data left behind after the transfer completed: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFaultInjectorIsReproducible(t *testing.T) {
+	cfg := &config.FaultInjectionConfig{CorruptRate: 0.3, DisconnectRate: 0.3, Seed: 42}
+	run := func() []string {
+		fi := NewFaultInjector(cfg, testLogger())
+		var faults []string
+		data := []byte("chunk data")
+		for offset := int64(0); offset < 50; offset++ {
+			if fi.Disconnect(offset) != nil {
+				faults = append(faults, fmt.Sprintf("disconnect@%d", offset))
+			}
+			if got := fi.Corrupt(data, offset); !bytes.Equal(got, data) {
+				faults = append(faults, fmt.Sprintf("corrupt@%d:%x", offset, got))
+			}
+		}
+		if string(data) != "chunk data" {
+			t.Fatal("Corrupt modified its input")
+		}
+		return faults
+	}
+
+	first, second := run(), run()
+	if len(first) == 0 {
+		t.Fatal("no faults injected")
+	}
+	if fmt.Sprint(first) != fmt.Sprint(second) {
+		t.Fatalf("same seed injected different faults:\n%v\n%v", first, second)
+	}
+
+	var none *FaultInjector
+	if none.Disconnect(0) != nil || !bytes.Equal(none.Corrupt([]byte("x"), 0), []byte("x")) || none.Delay(context.Background()) != nil {
+		t.Error("nil injector injected a fault")
+	}
+}
+
+func TestChunkWriterVerifiesChunks(t *testing.T) {
+	data := randomData(3 << 10)
+	chunk := func(offset, size int) *Chunk {
+		c := testChunk(data, offset, size)
+		return &Chunk{Offset: c.Offset, Data: c.Data, Checksum: c.Checksum, Size: len(c.Data)}
+	}
+	var out bytes.Buffer
+	cw := NewChunkWriter(&out, 0, nil)
+
+	corrupted := chunk(0, 1<<10)
+	corrupted.Data = append([]byte(nil), corrupted.Data...)
+	corrupted.Data[10] ^= 1
+	if err := cw.WriteChunk(corrupted); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("corrupted chunk: %v, want a checksum mismatch", err)
+	}
+	if out.Len() != 0 || cw.GetOffset() != 0 {
+		t.Fatal("corrupted chunk was written")
+	}
+
+	if err := cw.WriteChunk(chunk(2<<10, 1<<10)); err == nil {
+		t.Fatal("chunk past a gap was accepted")
+	}
+
+	for _, c := range []*Chunk{
+		chunk(0, 1<<10),
+		chunk(0, 1<<10),   // Re-sent after a lost ack
+		chunk(512, 1<<10), // Overlaps the committed data
+		chunk(3<<9, 3<<9),
+	} {
+		if err := cw.WriteChunk(c); err != nil {
+			t.Fatalf("chunk at %d: %v", c.Offset, err)
+		}
+	}
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("wrote %d bytes that differ from the %d sent", out.Len(), len(data))
+	}
+	if cw.DuplicateBytes() != 1<<10+512 {
+		t.Errorf("duplicate bytes = %d, want %d", cw.DuplicateBytes(), 1<<10+512)
+	}
+}
+
+func TestChunkRetransmitsOnlyForChecksumFailures(t *testing.T) {
+	r := make(chunkRetransmits)
+	if r.request(0, errors.New("disk full")) {
+		t.Error("asked for a chunk again after a write failure")
+	}
+	mismatch := fmt.Errorf("%w at offset 0", ErrChecksumMismatch)
+	for i := 0; i < MaxChunkRetransmits; i++ {
+		if !r.request(0, mismatch) {
+			t.Fatalf("refused retransmit %d", i+1)
+		}
+	}
+	if r.request(0, mismatch) {
+		t.Error("asked for a chunk again past the limit")
+	}
+	if !r.request(1<<20, mismatch) {
+		t.Error("another chunk shares the first one's limit")
+	}
+}
@@ -0,0 +1,303 @@
+package peer
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+)
+
+// TunnelMode selects how gRPC connections to the master leave the host
+type TunnelMode string
+
+const (
+	TunnelNone      TunnelMode = ""          // Direct TCP
+	TunnelWebSocket TunnelMode = "websocket" // gRPC bytes framed as WebSocket binary messages
+	TunnelConnect   TunnelMode = "connect"   // HTTP CONNECT through a forward proxy
+)
+
+// TunnelDialTimeout bounds establishing the tunnel itself
+const TunnelDialTimeout = 30 * time.Second
+
+// ParseTunnelMode validates a configured tunnel mode
+func ParseTunnelMode(s string) (TunnelMode, error) {
+	switch mode := TunnelMode(strings.ToLower(s)); mode {
+	case TunnelNone, TunnelWebSocket, TunnelConnect:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown tunnel mode %q (want websocket or connect)", s)
+	}
+}
+
+// TunnelDialOption returns a dial option that carries gRPC through the tunnel,
+// or nil when mode is TunnelNone. tunnelURL is the master's WebSocket endpoint
+// (e.g. https://master:8080/api/tunnel); proxyURL overrides HTTPS_PROXY.
+// token is called on every WebSocket dial for the bearer token the master
+// checks before upgrading: the worker's auth token, or its enrollment token
+// before it has registered.
+func TunnelDialOption(mode TunnelMode, tunnelURL, proxyURL string, token func() string) (grpc.DialOption, error) {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+
+	switch mode {
+	case TunnelNone:
+		return nil, nil
+
+	case TunnelWebSocket:
+		if tunnelURL == "" {
+			return nil, fmt.Errorf("websocket tunnel requires a tunnel URL")
+		}
+		wsURL, err := websocketURL(tunnelURL)
+		if err != nil {
+			return nil, err
+		}
+		dialer := &websocket.Dialer{
+			Proxy:            proxy,
+			HandshakeTimeout: TunnelDialTimeout,
+		}
+		return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			header := make(http.Header)
+			if token != nil {
+				header.Set("Authorization", "Bearer "+token())
+			}
+			ws, _, err := dialer.DialContext(ctx, wsURL, header)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open websocket tunnel: %w", err)
+			}
+			return newWSConn(ws), nil
+		}), nil
+
+	case TunnelConnect:
+		return grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialConnect(ctx, proxy, addr)
+		}), nil
+
+	default:
+		return nil, fmt.Errorf("unknown tunnel mode %q", mode)
+	}
+}
+
+// websocketURL maps http(s) URLs onto ws(s)
+func websocketURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid tunnel URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", fmt.Errorf("unsupported tunnel URL scheme %q", u.Scheme)
+	}
+	return u.String(), nil
+}
+
+// dialConnect opens a raw TCP stream to addr via an HTTP CONNECT proxy
+func dialConnect(ctx context.Context, proxy func(*http.Request) (*url.URL, error), addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Scheme: "https", Host: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+
+	proxyURL, err := proxy(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve proxy: %w", err)
+	}
+	if proxyURL == nil {
+		return nil, fmt.Errorf("connect tunnel requires a proxy (set proxy_url or HTTPS_PROXY)")
+	}
+
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "3128")
+	}
+
+	dialer := &net.Dialer{Timeout: TunnelDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial proxy: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT: %s", resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &bufferedConn{Conn: conn, reader: br}, nil
+}
+
+// bufferedConn drains bytes the proxy sent along with its CONNECT response
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (bc *bufferedConn) Read(p []byte) (int, error) {
+	return bc.reader.Read(p)
+}
+
+// wsConn adapts a WebSocket to net.Conn, one binary message per write
+type wsConn struct {
+	ws     *websocket.Conn
+	reader io.Reader
+	rmu    sync.Mutex
+	wmu    sync.Mutex
+}
+
+func newWSConn(ws *websocket.Conn) *wsConn {
+	return &wsConn{ws: ws}
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	for {
+		if c.reader == nil {
+			_, r, err := c.ws.NextReader()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					return 0, io.EOF
+				}
+				return 0, err
+			}
+			c.reader = r
+		}
+
+		n, err := c.reader.Read(p)
+		if err == io.EOF {
+			c.reader = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if err := c.ws.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *wsConn) Close() error {
+	c.wmu.Lock()
+	c.ws.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+	c.wmu.Unlock()
+	return c.ws.Close()
+}
+
+func (c *wsConn) LocalAddr() net.Addr                { return c.ws.LocalAddr() }
+func (c *wsConn) RemoteAddr() net.Addr               { return c.ws.RemoteAddr() }
+func (c *wsConn) SetReadDeadline(t time.Time) error  { return c.ws.SetReadDeadline(t) }
+func (c *wsConn) SetWriteDeadline(t time.Time) error { return c.ws.SetWriteDeadline(t) }
+
+func (c *wsConn) SetDeadline(t time.Time) error {
+	if err := c.ws.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.ws.SetWriteDeadline(t)
+}
+
+// tunnelUpgrader accepts tunnel clients. They are workers, which send no
+// Origin header, so any request carrying one came from a browser page and is
+// refused.
+var tunnelUpgrader = websocket.Upgrader{
+	ReadBufferSize:  64 * 1024,
+	WriteBufferSize: 64 * 1024,
+	CheckOrigin:     func(r *http.Request) bool { return r.Header.Get("Origin") == "" },
+}
+
+// ServeTunnel upgrades an HTTP request to a WebSocket and splices it onto a
+// TCP connection to target (the local gRPC listener) until either side
+// closes. The caller authenticates the request first.
+func ServeTunnel(w http.ResponseWriter, r *http.Request, target string) error {
+	ws, err := tunnelUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade tunnel: %w", err)
+	}
+	conn := newWSConn(ws)
+	defer conn.Close()
+
+	backend, err := net.DialTimeout("tcp", target, TunnelDialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to dial tunnel target: %w", err)
+	}
+	defer backend.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(backend, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, backend)
+		done <- struct{}{}
+	}()
+	<-done
+
+	return nil
+}
+
+// LocalDialAddress turns a listen address like ":9090" into a dialable one
+func LocalDialAddress(listenAddr string) string {
+	host, port, err := net.SplitHostPort(listenAddr)
+	if err != nil {
+		return listenAddr
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
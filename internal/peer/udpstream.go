@@ -0,0 +1,518 @@
+package peer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Packet types on a punched UDP path. Every packet starts with the type and
+// a sequence number; punch packets carry the session token as payload.
+const (
+	udpPacketData byte = iota + 1
+	udpPacketAck       // Sequence is the next one expected
+	udpPacketFin       // Sequence is the one after the last data segment
+	udpPacketPunch
+	udpPacketPunchAck
+	udpPacketKeepalive
+)
+
+const (
+	udpHeaderSize        = 5
+	udpSegmentSize       = 1200 // Stays under common path MTUs
+	udpWindow            = 256  // Most unacknowledged segments in flight, whatever the congestion window
+	udpInitialWindow     = 10   // Congestion window a stream starts with, in segments
+	udpSocketBuffer      = 4 << 20
+	udpInitialRTO        = time.Second // Retransmit timeout before the round trip is measured
+	udpMinRTO            = 200 * time.Millisecond
+	udpMaxRTO            = 10 * time.Second
+	udpTimerInterval     = 10 * time.Millisecond
+	udpKeepaliveInterval = 10 * time.Second // Keeps NAT mappings open
+	udpIdleTimeout       = 45 * time.Second
+	udpLingerTimeout     = 10 * time.Second // Close waits this long for sent data to be acknowledged
+
+	// PunchTimeout bounds hole punching before the relay is used instead
+	PunchTimeout = 8 * time.Second
+)
+
+// errUDPStreamIdle is returned once the peer has been silent for too long
+var errUDPStreamIdle = errors.New("udp path: peer stopped answering")
+
+// punchHole sends punch packets carrying token to every candidate until one
+// of them acknowledges one, which proves packets pass both ways, and returns
+// that candidate. Punches from the peer are acknowledged as they arrive.
+func punchHole(ctx context.Context, conn *net.UDPConn, candidates []*net.UDPAddr, token []byte) (*net.UDPAddr, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("peer offered no candidates")
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	punch := udpPacket(udpPacketPunch, 0, token)
+	ack := udpPacket(udpPacketPunchAck, 0, token)
+	buf := make([]byte, udpHeaderSize+udpSegmentSize)
+
+	for ctx.Err() == nil {
+		for _, addr := range candidates {
+			conn.WriteToUDP(punch, addr)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				break
+			}
+			if n < udpHeaderSize || !bytes.Equal(buf[udpHeaderSize:n], token) {
+				continue
+			}
+			switch buf[0] {
+			case udpPacketPunch:
+				// The peer's NAT may map it to an address we were not told
+				// of, so answer wherever it came from and punch there too
+				conn.WriteToUDP(ack, from)
+				if !containsUDPAddr(candidates, from) {
+					candidates = append(candidates, from)
+				}
+			case udpPacketPunchAck:
+				return from, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("hole punching failed: %w", ctx.Err())
+}
+
+func containsUDPAddr(addrs []*net.UDPAddr, addr *net.UDPAddr) bool {
+	for _, a := range addrs {
+		if a.IP.Equal(addr.IP) && a.Port == addr.Port {
+			return true
+		}
+	}
+	return false
+}
+
+func udpPacket(kind byte, seq uint32, payload []byte) []byte {
+	packet := make([]byte, udpHeaderSize+len(payload))
+	packet[0] = kind
+	binary.BigEndian.PutUint32(packet[1:5], seq)
+	copy(packet[udpHeaderSize:], payload)
+	return packet
+}
+
+// udpSegment is sent data awaiting acknowledgement
+type udpSegment struct {
+	seq    uint32
+	packet []byte
+	sentAt time.Time
+	resent bool // Sent more than once, so its acknowledgement is no RTT sample
+	lost   bool // Presumed lost after a timeout, waiting for room to be resent
+}
+
+// udpStream is a reliable, ordered byte stream over a punched UDP path, so
+// TLS and gRPC can run over it as over TCP. Congestion is handled as TCP
+// Reno does: the retransmit timeout follows the measured round trip (RFC
+// 6298) and backs off exponentially, and a congestion window grows by slow
+// start and congestion avoidance. A timeout drops the window to one segment
+// and resends the oldest; three duplicate acknowledgements halve it and
+// resend the missing segment.
+type udpStream struct {
+	conn   *net.UDPConn
+	remote *net.UDPAddr
+	token  []byte
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	nextSeq   uint32
+	unacked   []*udpSegment
+	expected  uint32
+	pending   map[uint32][]byte // Out-of-order segments
+	readBuf   bytes.Buffer
+	lastHeard time.Time
+	dupAcks   int
+	srtt      time.Duration // Smoothed round trip; zero until the first sample
+	rttvar    time.Duration
+	rto       time.Duration
+	cwnd      float64 // Congestion window, in segments
+	ssthresh  float64 // Slow start threshold, in segments
+	finSeq    uint32
+	finSeen   bool // Peer sent FIN
+	finished  bool // Everything before the peer's FIN was received
+	closed    bool
+	err       error
+	done      chan struct{}
+}
+
+// newUDPStream starts a stream with remote over conn, which it then owns
+func newUDPStream(conn *net.UDPConn, remote *net.UDPAddr, token []byte) *udpStream {
+	s := &udpStream{
+		conn:      conn,
+		remote:    remote,
+		token:     token,
+		pending:   make(map[uint32][]byte),
+		lastHeard: time.Now(),
+		rto:       udpInitialRTO,
+		cwnd:      udpInitialWindow,
+		ssthresh:  udpWindow,
+		done:      make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	// A whole window arrives in a burst; the default buffer drops most of it
+	conn.SetReadBuffer(udpSocketBuffer)
+	conn.SetWriteBuffer(udpSocketBuffer)
+
+	go s.readLoop()
+	go s.timerLoop()
+	return s
+}
+
+func (s *udpStream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.readBuf.Len() == 0 && !s.finished && !s.closed && s.err == nil {
+		s.cond.Wait()
+	}
+	if s.readBuf.Len() > 0 {
+		return s.readBuf.Read(p)
+	}
+	if s.finished {
+		return 0, io.EOF
+	}
+	if s.err != nil {
+		return 0, s.err
+	}
+	return 0, net.ErrClosed
+}
+
+func (s *udpStream) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		end := written + udpSegmentSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		s.mu.Lock()
+		for len(s.unacked) >= s.sendWindowLocked() && !s.closed && s.err == nil {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return written, net.ErrClosed
+		}
+		if s.err != nil {
+			err := s.err
+			s.mu.Unlock()
+			return written, err
+		}
+		segment := &udpSegment{
+			seq:    s.nextSeq,
+			packet: udpPacket(udpPacketData, s.nextSeq, p[written:end]),
+			sentAt: time.Now(),
+		}
+		s.nextSeq++
+		s.unacked = append(s.unacked, segment)
+		s.mu.Unlock()
+
+		if _, err := s.conn.WriteToUDP(segment.packet, s.remote); err != nil {
+			s.fail(err)
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+// Close waits for sent data to be acknowledged, sends FIN and releases the
+// socket. FIN is not resent; a peer that misses it gives up when the idle
+// timeout expires.
+func (s *udpStream) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	linger := time.AfterFunc(udpLingerTimeout, func() {
+		s.mu.Lock()
+		s.closed = true
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	for len(s.unacked) > 0 && !s.closed && s.err == nil {
+		s.cond.Wait()
+	}
+	linger.Stop()
+	s.closed = true
+	close(s.done)
+	s.cond.Broadcast()
+	finSeq := s.nextSeq
+	s.mu.Unlock()
+
+	fin := udpPacket(udpPacketFin, finSeq, nil)
+	for i := 0; i < 3; i++ {
+		s.conn.WriteToUDP(fin, s.remote)
+	}
+	return s.conn.Close()
+}
+
+func (s *udpStream) LocalAddr() net.Addr  { return s.conn.LocalAddr() }
+func (s *udpStream) RemoteAddr() net.Addr { return s.remote }
+
+// Deadlines are not supported; the idle timeout ends a dead stream
+func (s *udpStream) SetDeadline(time.Time) error      { return nil }
+func (s *udpStream) SetReadDeadline(time.Time) error  { return nil }
+func (s *udpStream) SetWriteDeadline(time.Time) error { return nil }
+
+// fail ends the stream with err
+func (s *udpStream) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.cond.Broadcast()
+}
+
+func (s *udpStream) readLoop() {
+	buf := make([]byte, udpHeaderSize+udpSegmentSize+64)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		if n < udpHeaderSize || !from.IP.Equal(s.remote.IP) || from.Port != s.remote.Port {
+			continue
+		}
+		seq := binary.BigEndian.Uint32(buf[1:5])
+		payload := buf[udpHeaderSize:n]
+
+		s.mu.Lock()
+		s.lastHeard = time.Now()
+		switch buf[0] {
+		case udpPacketData:
+			s.receiveLocked(seq, payload)
+			ack := udpPacket(udpPacketAck, s.expected, nil)
+			s.mu.Unlock()
+			s.conn.WriteToUDP(ack, s.remote)
+			continue
+		case udpPacketAck:
+			if resend := s.acknowledgeLocked(seq, time.Now()); len(resend) > 0 {
+				s.mu.Unlock()
+				for _, packet := range resend {
+					s.conn.WriteToUDP(packet, s.remote)
+				}
+				continue
+			}
+		case udpPacketFin:
+			s.finSeq, s.finSeen = seq, true
+			s.checkFinishedLocked()
+		case udpPacketPunch:
+			// The peer has not seen our acknowledgement yet
+			if bytes.Equal(payload, s.token) {
+				s.conn.WriteToUDP(udpPacket(udpPacketPunchAck, 0, s.token), s.remote)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// receiveLocked stores a data segment and moves whatever is now in order
+// to the read buffer
+func (s *udpStream) receiveLocked(seq uint32, payload []byte) {
+	offset := int32(seq - s.expected)
+	if offset < 0 || offset >= 2*udpWindow {
+		return // Duplicate, or too far ahead to be ours
+	}
+	if offset > 0 {
+		if _, ok := s.pending[seq]; !ok {
+			s.pending[seq] = append([]byte(nil), payload...)
+		}
+		return
+	}
+
+	s.readBuf.Write(payload)
+	s.expected++
+	for {
+		data, ok := s.pending[s.expected]
+		if !ok {
+			break
+		}
+		delete(s.pending, s.expected)
+		s.readBuf.Write(data)
+		s.expected++
+	}
+	s.checkFinishedLocked()
+	s.cond.Broadcast()
+}
+
+// checkFinishedLocked ends reading once every segment before FIN arrived
+func (s *udpStream) checkFinishedLocked() {
+	if s.finSeen && s.expected == s.finSeq {
+		s.finished = true
+		s.cond.Broadcast()
+	}
+}
+
+// acknowledgeLocked drops every segment before next, measures the round
+// trip and grows the congestion window. The third repeat of an
+// acknowledgement means the segment after it was lost: the window is halved
+// and that segment resent without waiting for the timeout. Returns the
+// packets to send now.
+func (s *udpStream) acknowledgeLocked(next uint32, now time.Time) [][]byte {
+	i := 0
+	for i < len(s.unacked) && int32(next-s.unacked[i].seq) > 0 {
+		i++
+	}
+	if i > 0 {
+		if newest := s.unacked[i-1]; !newest.resent && !newest.lost {
+			s.sampleRTTLocked(now.Sub(newest.sentAt))
+		}
+		s.unacked = s.unacked[i:]
+		s.dupAcks = 0
+		s.growWindowLocked(i)
+		s.cond.Broadcast()
+		return s.resendLostLocked(now)
+	}
+
+	if len(s.unacked) == 0 || s.unacked[0].seq != next {
+		return nil
+	}
+	s.dupAcks++
+	if s.dupAcks != 3 {
+		return nil
+	}
+	s.ssthresh = max(float64(s.inFlightLocked())/2, 2)
+	s.cwnd = s.ssthresh
+	segment := s.unacked[0]
+	segment.sentAt, segment.resent, segment.lost = now, true, false
+	return [][]byte{segment.packet}
+}
+
+// retransmitLocked handles the retransmit timeout. Once the oldest segment
+// has gone unacknowledged for the timeout, everything in flight is presumed
+// lost, the window drops to one segment and the timeout doubles. Lost
+// segments are then resent as the window allows, starting with the oldest.
+// Returns the packets to send now.
+func (s *udpStream) retransmitLocked(now time.Time) [][]byte {
+	if len(s.unacked) == 0 || now.Sub(s.unacked[0].sentAt) < s.rto {
+		return nil
+	}
+
+	s.ssthresh = max(float64(s.inFlightLocked())/2, 2)
+	s.cwnd = 1
+	s.dupAcks = 0
+	s.rto = min(2*s.rto, udpMaxRTO)
+	for _, segment := range s.unacked {
+		segment.lost = true
+	}
+	return s.resendLostLocked(now)
+}
+
+// resendLostLocked resends segments presumed lost while the congestion
+// window has room, oldest first
+func (s *udpStream) resendLostLocked(now time.Time) [][]byte {
+	var packets [][]byte
+	inFlight := s.inFlightLocked()
+	for _, segment := range s.unacked {
+		if inFlight >= s.sendWindowLocked() {
+			break
+		}
+		if !segment.lost {
+			continue
+		}
+		segment.sentAt, segment.resent, segment.lost = now, true, false
+		inFlight++
+		packets = append(packets, segment.packet)
+	}
+	return packets
+}
+
+// inFlightLocked counts the segments sent and not presumed lost
+func (s *udpStream) inFlightLocked() int {
+	n := 0
+	for _, segment := range s.unacked {
+		if !segment.lost {
+			n++
+		}
+	}
+	return n
+}
+
+// sendWindowLocked is how many segments may be unacknowledged at once
+func (s *udpStream) sendWindowLocked() int {
+	return min(max(int(s.cwnd), 1), udpWindow)
+}
+
+// sampleRTTLocked folds a round trip measurement into the smoothed estimate
+// and sets the retransmit timeout from it, as RFC 6298 describes
+func (s *udpStream) sampleRTTLocked(rtt time.Duration) {
+	if s.srtt == 0 {
+		s.srtt, s.rttvar = rtt, rtt/2
+	} else {
+		diff := s.srtt - rtt
+		if diff < 0 {
+			diff = -diff
+		}
+		s.rttvar = (3*s.rttvar + diff) / 4
+		s.srtt = (7*s.srtt + rtt) / 8
+	}
+	s.rto = min(max(s.srtt+4*s.rttvar, udpMinRTO), udpMaxRTO)
+}
+
+// growWindowLocked opens the congestion window for acked segments: by one
+// segment each in slow start, by about one segment per round trip after
+func (s *udpStream) growWindowLocked(acked int) {
+	if s.cwnd < s.ssthresh {
+		s.cwnd += float64(acked)
+	} else {
+		s.cwnd += float64(acked) / s.cwnd
+	}
+	s.cwnd = min(s.cwnd, udpWindow)
+}
+
+// timerLoop resends segments the retransmit timeout finds lost, keeps the
+// path open while the stream is quiet and gives up on a silent peer
+func (s *udpStream) timerLoop() {
+	ticker := time.NewTicker(udpTimerInterval)
+	defer ticker.Stop()
+	lastSent := time.Now()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		s.mu.Lock()
+		if s.err != nil {
+			s.mu.Unlock()
+			return
+		}
+		if now.Sub(s.lastHeard) > udpIdleTimeout {
+			s.err = errUDPStreamIdle
+			s.cond.Broadcast()
+			s.mu.Unlock()
+			return
+		}
+		resend := s.retransmitLocked(now)
+		s.mu.Unlock()
+
+		for _, packet := range resend {
+			s.conn.WriteToUDP(packet, s.remote)
+			lastSent = now
+		}
+		if now.Sub(lastSent) >= udpKeepaliveInterval {
+			s.conn.WriteToUDP(udpPacket(udpPacketKeepalive, 0, nil), s.remote)
+			lastSent = now
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package peer
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listenLoopback opens a UDP socket on an ephemeral loopback port
+func listenLoopback(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	return conn
+}
+
+// lossyRelay forwards packets between two stream sockets and drops the
+// given fraction of data segments in both directions
+func lossyRelay(t *testing.T, a, b *net.UDPConn, loss float64) (toA, toB *net.UDPAddr) {
+	t.Helper()
+	facingA, facingB := listenLoopback(t), listenLoopback(t)
+	t.Cleanup(func() {
+		facingA.Close()
+		facingB.Close()
+	})
+
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(1))
+	forward := func(from, to *net.UDPConn, dest *net.UDPAddr) {
+		buf := make([]byte, 2048)
+		for {
+			n, _, err := from.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			drop := buf[0] == udpPacketData && rng.Float64() < loss
+			mu.Unlock()
+			if !drop {
+				to.WriteToUDP(buf[:n], dest)
+			}
+		}
+	}
+	go forward(facingA, facingB, b.LocalAddr().(*net.UDPAddr))
+	go forward(facingB, facingA, a.LocalAddr().(*net.UDPAddr))
+	return facingA.LocalAddr().(*net.UDPAddr), facingB.LocalAddr().(*net.UDPAddr)
+}
+
+// transfer writes data from one end and checks the other reads it back
+// followed by EOF
+func transfer(t *testing.T, sender, receiver *udpStream, data []byte) {
+	t.Helper()
+	received := make(chan []byte, 1)
+	go func() {
+		got, _ := io.ReadAll(receiver)
+		received <- got
+	}()
+
+	if _, err := sender.Write(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := sender.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	select {
+	case got := <-received:
+		if !bytes.Equal(got, data) {
+			t.Fatalf("received %d bytes that differ from the %d sent", len(got), len(data))
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("transfer did not finish")
+	}
+	receiver.Close()
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(2)).Read(data)
+	return data
+}
+
+func TestUDPStreamTransfer(t *testing.T) {
+	a, b := listenLoopback(t), listenLoopback(t)
+	sender := newUDPStream(a, b.LocalAddr().(*net.UDPAddr), nil)
+	receiver := newUDPStream(b, a.LocalAddr().(*net.UDPAddr), nil)
+
+	transfer(t, sender, receiver, randomData(2<<20))
+}
+
+func TestUDPStreamTransferWithLoss(t *testing.T) {
+	a, b := listenLoopback(t), listenLoopback(t)
+	toB, toA := lossyRelay(t, a, b, 0.05)
+	sender := newUDPStream(a, toB, nil)
+	receiver := newUDPStream(b, toA, nil)
+
+	transfer(t, sender, receiver, randomData(512<<10))
+}
+
+// unackedStream returns a stream with count segments sent at sentAt and
+// no sockets behind it
+func unackedStream(count int, sentAt time.Time) *udpStream {
+	s := &udpStream{rto: udpInitialRTO, cwnd: float64(count), ssthresh: udpWindow}
+	s.cond = sync.NewCond(&s.mu)
+	for i := 0; i < count; i++ {
+		s.unacked = append(s.unacked, &udpSegment{
+			seq:    s.nextSeq,
+			packet: udpPacket(udpPacketData, s.nextSeq, nil),
+			sentAt: sentAt,
+		})
+		s.nextSeq++
+	}
+	return s
+}
+
+func TestUDPStreamTimeoutResendsOldestSegment(t *testing.T) {
+	start := time.Now()
+	s := unackedStream(20, start)
+
+	if resend := s.retransmitLocked(start.Add(udpInitialRTO / 2)); len(resend) != 0 {
+		t.Fatalf("resent %d segments before the timeout", len(resend))
+	}
+
+	resend := s.retransmitLocked(start.Add(udpInitialRTO))
+	if len(resend) != 1 || !bytes.Equal(resend[0], s.unacked[0].packet) {
+		t.Fatalf("timeout resent %d segments, want only the oldest", len(resend))
+	}
+	if s.cwnd != 1 {
+		t.Errorf("cwnd after timeout = %v, want 1", s.cwnd)
+	}
+	if s.ssthresh != 10 {
+		t.Errorf("ssthresh after timeout = %v, want half the 20 in flight", s.ssthresh)
+	}
+	if s.rto != 2*udpInitialRTO {
+		t.Errorf("rto after timeout = %v, want %v", s.rto, 2*udpInitialRTO)
+	}
+
+	// The resent segment times out again: the timeout keeps doubling
+	// up to the cap and still only one segment goes out
+	now := start.Add(udpInitialRTO)
+	for i := 0; i < 6; i++ {
+		now = now.Add(s.rto)
+		if resend := s.retransmitLocked(now); len(resend) != 1 {
+			t.Fatalf("timeout %d resent %d segments, want 1", i+2, len(resend))
+		}
+	}
+	if s.rto != udpMaxRTO {
+		t.Errorf("rto after repeated timeouts = %v, want the %v cap", s.rto, udpMaxRTO)
+	}
+}
+
+func TestUDPStreamRecoversAfterTimeout(t *testing.T) {
+	start := time.Now()
+	s := unackedStream(20, start)
+	now := start.Add(udpInitialRTO)
+	s.retransmitLocked(now)
+
+	// Acknowledging the resent segment gives no RTT sample, opens the
+	// window to two and resends the next two lost segments
+	resend := s.acknowledgeLocked(1, now.Add(50*time.Millisecond))
+	if len(resend) != 2 {
+		t.Fatalf("ack after timeout resent %d segments, want 2", len(resend))
+	}
+	if s.srtt != 0 {
+		t.Errorf("srtt = %v, want no sample from a resent segment", s.srtt)
+	}
+	if s.rto != 2*udpInitialRTO {
+		t.Errorf("rto = %v, want the backed off timeout kept until a sample", s.rto)
+	}
+}
+
+func TestUDPStreamRTTEstimate(t *testing.T) {
+	start := time.Now()
+	s := unackedStream(3, start)
+
+	s.acknowledgeLocked(1, start.Add(100*time.Millisecond))
+	if s.srtt != 100*time.Millisecond || s.rttvar != 50*time.Millisecond {
+		t.Fatalf("first sample: srtt %v rttvar %v, want 100ms and 50ms", s.srtt, s.rttvar)
+	}
+	if s.rto != 300*time.Millisecond {
+		t.Errorf("rto = %v, want srtt + 4·rttvar = 300ms", s.rto)
+	}
+
+	s.acknowledgeLocked(2, start.Add(200*time.Millisecond))
+	if s.srtt != 112500*time.Microsecond || s.rttvar != 62500*time.Microsecond {
+		t.Errorf("second sample: srtt %v rttvar %v, want 112.5ms and 62.5ms", s.srtt, s.rttvar)
+	}
+
+	// A fast path still keeps the minimum timeout
+	for i := 0; i < 50; i++ {
+		s.sampleRTTLocked(time.Millisecond)
+	}
+	if s.rto != udpMinRTO {
+		t.Errorf("rto on a fast path = %v, want the %v minimum", s.rto, udpMinRTO)
+	}
+}
+
+func TestUDPStreamCongestionWindow(t *testing.T) {
+	start := time.Now()
+	s := unackedStream(40, start)
+	s.cwnd, s.ssthresh = 10, 16
+
+	// Slow start: one segment per acknowledged segment
+	s.acknowledgeLocked(4, start.Add(10*time.Millisecond))
+	if s.cwnd != 14 {
+		t.Fatalf("cwnd in slow start = %v, want 14", s.cwnd)
+	}
+
+	// Congestion avoidance: about one segment per window acknowledged
+	s.cwnd = 20
+	s.acknowledgeLocked(24, start.Add(20*time.Millisecond))
+	if s.cwnd <= 20 || s.cwnd >= 21.5 {
+		t.Fatalf("cwnd in congestion avoidance = %v, want just over 20", s.cwnd)
+	}
+
+	// Three duplicate acknowledgements halve the window and resend the
+	// missing segment
+	for i := 0; i < 2; i++ {
+		if resend := s.acknowledgeLocked(24, start.Add(30*time.Millisecond)); len(resend) != 0 {
+			t.Fatalf("duplicate ack %d resent a segment", i+1)
+		}
+	}
+	resend := s.acknowledgeLocked(24, start.Add(30*time.Millisecond))
+	if len(resend) != 1 || !bytes.Equal(resend[0], s.unacked[0].packet) {
+		t.Fatalf("third duplicate ack resent %d segments, want the missing one", len(resend))
+	}
+	if s.cwnd != 8 {
+		t.Errorf("cwnd after fast retransmit = %v, want half the 16 in flight", s.cwnd)
+	}
+	if got := s.sendWindowLocked(); got != 8 {
+		t.Errorf("send window = %d, want 8", got)
+	}
+}
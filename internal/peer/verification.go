@@ -0,0 +1,76 @@
+package peer
+
+import (
+	"crypto/sha256"
+)
+
+// VerificationSymbols is how many symbols a pairing verification shows. At
+// six bits each, seven symbols give a 42-bit check.
+const VerificationSymbols = 7
+
+// VerificationSymbol is one emoji and the word read out for it
+type VerificationSymbol struct {
+	Emoji string `json:"emoji"`
+	Word  string `json:"word"`
+}
+
+// verificationTable maps six bits to a symbol. The list is the one Matrix
+// uses for its short authentication strings, chosen to be distinct both at a
+// glance and when read aloud.
+var verificationTable = [64]VerificationSymbol{
+	{"🐶", "Dog"}, {"🐱", "Cat"}, {"🦁", "Lion"}, {"🐎", "Horse"},
+	{"🦄", "Unicorn"}, {"🐷", "Pig"}, {"🐘", "Elephant"}, {"🐰", "Rabbit"},
+	{"🐼", "Panda"}, {"🐓", "Rooster"}, {"🐧", "Penguin"}, {"🐢", "Turtle"},
+	{"🐟", "Fish"}, {"🐙", "Octopus"}, {"🦋", "Butterfly"}, {"🌷", "Flower"},
+	{"🌳", "Tree"}, {"🌵", "Cactus"}, {"🍄", "Mushroom"}, {"🌏", "Globe"},
+	{"🌙", "Moon"}, {"☁️", "Cloud"}, {"🔥", "Fire"}, {"🍌", "Banana"},
+	{"🍎", "Apple"}, {"🍓", "Strawberry"}, {"🌽", "Corn"}, {"🍕", "Pizza"},
+	{"🎂", "Cake"}, {"❤️", "Heart"}, {"🙂", "Smiley"}, {"🤖", "Robot"},
+	{"🎩", "Hat"}, {"👓", "Glasses"}, {"🔧", "Spanner"}, {"🎅", "Santa"},
+	{"👍", "Thumbs Up"}, {"☂️", "Umbrella"}, {"⌛", "Hourglass"}, {"⏰", "Clock"},
+	{"🎁", "Gift"}, {"💡", "Light Bulb"}, {"📕", "Book"}, {"✏️", "Pencil"},
+	{"📎", "Paperclip"}, {"✂️", "Scissors"}, {"🔒", "Lock"}, {"🔑", "Key"},
+	{"🔨", "Hammer"}, {"☎️", "Telephone"}, {"🏁", "Flag"}, {"🚂", "Train"},
+	{"🚲", "Bicycle"}, {"✈️", "Aeroplane"}, {"🚀", "Rocket"}, {"🏆", "Trophy"},
+	{"⚽", "Ball"}, {"🎸", "Guitar"}, {"🎺", "Trumpet"}, {"🔔", "Bell"},
+	{"⚓", "Anchor"}, {"🎧", "Headphones"}, {"📁", "Folder"}, {"📌", "Pin"},
+}
+
+// PairingVerification derives the symbols both sides of a pairing show for
+// out-of-band comparison. It depends on both certificate fingerprints but
+// not their order, so each host computes the same sequence; a host in the
+// middle would have to present a different certificate to one side.
+func PairingVerification(fingerprintA, fingerprintB string) []VerificationSymbol {
+	if fingerprintB < fingerprintA {
+		fingerprintA, fingerprintB = fingerprintB, fingerprintA
+	}
+	hash := sha256.Sum256([]byte("docker-migrate pairing\x00" + fingerprintA + "\x00" + fingerprintB))
+
+	symbols := make([]VerificationSymbol, VerificationSymbols)
+	for i := range symbols {
+		// Take six bits at a time from the front of the hash
+		bit := i * 6
+		chunk := uint16(hash[bit/8])<<8 | uint16(hash[bit/8+1])
+		symbols[i] = verificationTable[(chunk>>(10-bit%8))&0x3f]
+	}
+	return symbols
+}
+
+// VerificationWith returns the symbols to compare with a peer after pairing
+func (cm *CryptoManager) VerificationWith(peerFingerprint string) []VerificationSymbol {
+	return PairingVerification(cm.GetFingerprint(), peerFingerprint)
+}
+
+// Verification returns the symbols to compare with a peer after pairing
+func (pm *PairingManager) Verification(peerFingerprint string) []VerificationSymbol {
+	return pm.crypto.VerificationWith(peerFingerprint)
+}
+
+// verificationWords lists the words of symbols, for logs
+func verificationWords(symbols []VerificationSymbol) []string {
+	words := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		words[i] = symbol.Word
+	}
+	return words
+}
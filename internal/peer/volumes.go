@@ -0,0 +1,131 @@
+package peer
+
+import (
+	"context"
+	"slices"
+
+	"github.com/artemis/docker-migrate/internal/docker"
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// VolumeSample is a peer's view of its copy of a volume: how many files it
+// holds and their total size, and the sampled files hashed afresh
+type VolumeSample struct {
+	Exists bool
+	Files  int
+	Bytes  int64
+	Sample docker.FileIndex
+}
+
+// CreateVolume creates a volume with the driver, driver options and labels
+// of the spec. Shared-storage volumes recreated this way point at the
+// backing storage the source used, so no data is copied; local volumes are
+// created empty before their files are sent, so they carry the migration's
+// labels. A volume that already exists with the same driver is left as it
+// is.
+func (gs *GRPCServer) CreateVolume(ctx context.Context, req *pb.VolumeSpec) (*pb.VolumeSpec, error) {
+	if err := gs.allow(OpReceiveVolumes); err != nil {
+		return nil, err
+	}
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.Name == "" || req.Driver == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume name and driver are required")
+	}
+
+	existing, err := gs.docker.InspectVolume(ctx, req.Name)
+	if err == nil {
+		if existing.Driver != req.Driver {
+			return nil, status.Errorf(codes.AlreadyExists, "volume %s exists with driver %s", req.Name, existing.Driver)
+		}
+		return req, nil
+	}
+	if !docker.IsNotFound(err) {
+		return nil, status.Errorf(codes.Internal, "inspect volume: %v", err)
+	}
+
+	drivers, err := gs.docker.ListVolumeDrivers(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list volume drivers: %v", err)
+	}
+	if !slices.Contains(drivers, req.Driver) {
+		return nil, status.Errorf(codes.FailedPrecondition, "volume driver %s is not installed", req.Driver)
+	}
+
+	if _, err := gs.docker.CreateVolumeWithDriver(ctx, req.Name, req.Driver, req.Labels, req.DriverOpts); err != nil {
+		return nil, status.Errorf(codes.Internal, "create volume: %v", err)
+	}
+
+	gs.logger.Info("created volume for migration",
+		zap.String("volume", req.Name),
+		zap.String("driver", req.Driver),
+	)
+	return req, nil
+}
+
+// CreateVolume asks the peer to create a volume with the spec's driver and
+// options
+func (gc *GRPCClient) CreateVolume(ctx context.Context, spec *pb.VolumeSpec) error {
+	if _, err := gc.client.CreateVolume(ctx, spec); err != nil {
+		return peerCallError("create volumes", err)
+	}
+	return nil
+}
+
+// SampleVolume hashes the requested files of a volume, read back from the
+// volume rather than from any cached index, and counts the rest
+func (gs *GRPCServer) SampleVolume(ctx context.Context, req *pb.VolumeSampleRequest) (*pb.VolumeSample, error) {
+	if gs.docker == nil {
+		return nil, status.Error(codes.Unavailable, "docker is not available")
+	}
+	if req.Volume == "" {
+		return nil, status.Error(codes.InvalidArgument, "volume is required")
+	}
+
+	if _, err := gs.docker.InspectVolume(ctx, req.Volume); err != nil {
+		if docker.IsNotFound(err) {
+			return &pb.VolumeSample{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "inspect volume: %v", err)
+	}
+
+	index, err := gs.docker.SampleVolume(ctx, req.Volume, req.Paths)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "sample volume: %v", err)
+	}
+
+	resp := &pb.VolumeSample{Exists: true, Files: int64(len(index))}
+	for _, meta := range index {
+		resp.Bytes += meta.Size
+	}
+	sampled := make(docker.FileIndex, len(req.Paths))
+	for _, name := range req.Paths {
+		if meta, ok := index[name]; ok {
+			sampled[name] = meta
+		}
+	}
+	resp.Sample = fileIndexToProto(sampled)
+	return resp, nil
+}
+
+// SampleVolume asks the peer for the file count and size of its copy of a
+// volume, and for the checksums of the given files
+func (gc *GRPCClient) SampleVolume(ctx context.Context, volume string, paths []string) (*VolumeSample, error) {
+	resp, err := gc.client.SampleVolume(ctx, &pb.VolumeSampleRequest{Volume: volume, Paths: paths})
+	if err != nil {
+		return nil, peerCallError("sample volumes", err)
+	}
+
+	sample := &VolumeSample{
+		Exists: resp.Exists,
+		Files:  int(resp.Files),
+		Bytes:  resp.Bytes,
+		Sample: make(docker.FileIndex, len(resp.Sample)),
+	}
+	addFilesFromProto(sample.Sample, resp.Sample)
+	return sample, nil
+}
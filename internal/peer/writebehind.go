@@ -0,0 +1,208 @@
+package peer
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	// DefaultReceiveBuffer bounds data accepted off the network but not yet on disk
+	DefaultReceiveBuffer = 64 * 1024 * 1024
+
+	// DefaultReceiveFsyncBytes is how much is written between background fsyncs
+	DefaultReceiveFsyncBytes = 32 * 1024 * 1024
+)
+
+// WriteBehind decouples network receive from disk latency: Write queues the
+// buffer and returns while a background goroutine writes it out, fsyncing in
+// batches. Writers block once limit bytes are pending. The first disk error
+// is sticky and surfaces from the next Write, Flush or Close.
+//
+// Write keeps a reference to p, so callers must not reuse it afterwards.
+type WriteBehind struct {
+	file      *os.File
+	limit     int64
+	syncEvery int64
+
+	queue   [][]byte
+	pending int64 // Bytes queued or being written
+	synced  int64 // Bytes written since the last fsync
+	err     error
+	closed  bool
+	idle    bool // Writer goroutine has drained the queue
+
+	mu   sync.Mutex
+	cond *sync.Cond
+	done chan struct{}
+}
+
+// NewWriteBehind starts a write-behind writer on file. Non-positive sizes use defaults.
+func NewWriteBehind(file *os.File, limit, syncEvery int64) *WriteBehind {
+	if limit <= 0 {
+		limit = DefaultReceiveBuffer
+	}
+	if syncEvery <= 0 {
+		syncEvery = DefaultReceiveFsyncBytes
+	}
+
+	wb := &WriteBehind{
+		file:      file,
+		limit:     limit,
+		syncEvery: syncEvery,
+		idle:      true,
+		done:      make(chan struct{}),
+	}
+	wb.cond = sync.NewCond(&wb.mu)
+
+	go wb.run()
+	return wb
+}
+
+// Write queues p for writing, blocking while the buffer is full
+func (wb *WriteBehind) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	// A single buffer larger than the limit is admitted once the queue is empty
+	for wb.err == nil && !wb.closed && wb.pending > 0 && wb.pending+int64(len(p)) > wb.limit {
+		wb.cond.Wait()
+	}
+	if wb.err != nil {
+		return 0, wb.err
+	}
+	if wb.closed {
+		return 0, fmt.Errorf("write-behind writer is closed")
+	}
+
+	wb.queue = append(wb.queue, p)
+	wb.pending += int64(len(p))
+	wb.idle = false
+	wb.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// Buffered returns the number of bytes not yet written to disk
+func (wb *WriteBehind) Buffered() int64 {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return wb.pending
+}
+
+// Flush waits for queued data to reach the disk and fsyncs it
+func (wb *WriteBehind) Flush() error {
+	wb.mu.Lock()
+	for wb.err == nil && !wb.idle {
+		wb.cond.Wait()
+	}
+	if wb.err != nil {
+		err := wb.err
+		wb.mu.Unlock()
+		return err
+	}
+	wb.mu.Unlock()
+
+	// Only this goroutine writes while the queue is drained and the caller
+	// is not writing, so syncing outside the lock is safe
+	if err := wb.file.Sync(); err != nil {
+		return wb.fail(fmt.Errorf("failed to sync: %w", err))
+	}
+
+	wb.mu.Lock()
+	wb.synced = 0
+	wb.mu.Unlock()
+	return nil
+}
+
+// Close flushes outstanding data and stops the writer. The file stays open.
+func (wb *WriteBehind) Close() error {
+	err := wb.Flush()
+
+	wb.mu.Lock()
+	wb.closed = true
+	wb.cond.Broadcast()
+	wb.mu.Unlock()
+
+	<-wb.done
+	return err
+}
+
+// Abort stops the writer without waiting for queued data
+func (wb *WriteBehind) Abort() {
+	wb.mu.Lock()
+	wb.closed = true
+	wb.queue = nil
+	wb.cond.Broadcast()
+	wb.mu.Unlock()
+
+	<-wb.done
+}
+
+func (wb *WriteBehind) run() {
+	defer close(wb.done)
+
+	for {
+		wb.mu.Lock()
+		for len(wb.queue) == 0 && !wb.closed {
+			wb.idle = true
+			wb.cond.Broadcast()
+			wb.cond.Wait()
+		}
+		if len(wb.queue) == 0 || wb.err != nil {
+			wb.idle = true
+			wb.cond.Broadcast()
+			wb.mu.Unlock()
+			return
+		}
+		buf := wb.queue[0]
+		wb.queue[0] = nil
+		wb.queue = wb.queue[1:]
+		wb.mu.Unlock()
+
+		n, err := wb.file.Write(buf)
+		if err == nil && n != len(buf) {
+			err = fmt.Errorf("short write: wrote %d of %d bytes", n, len(buf))
+		}
+		if err != nil {
+			wb.fail(fmt.Errorf("failed to write: %w", err))
+			return
+		}
+
+		wb.mu.Lock()
+		wb.pending -= int64(len(buf))
+		wb.synced += int64(len(buf))
+		needSync := wb.synced >= wb.syncEvery
+		if needSync {
+			wb.synced = 0
+		}
+		wb.cond.Broadcast()
+		wb.mu.Unlock()
+
+		// Batch fsyncs so dirty pages never pile up into one long stall at the end
+		if needSync {
+			if err := wb.file.Sync(); err != nil {
+				wb.fail(fmt.Errorf("failed to sync: %w", err))
+				return
+			}
+		}
+	}
+}
+
+// fail records the first error and wakes every waiter
+func (wb *WriteBehind) fail(err error) error {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if wb.err == nil {
+		wb.err = err
+	}
+	wb.queue = nil
+	wb.idle = true
+	wb.cond.Broadcast()
+	return wb.err
+}
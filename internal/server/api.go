@@ -2,13 +2,20 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/events"
 	"github.com/artemis/docker-migrate/internal/migration"
+	"github.com/artemis/docker-migrate/internal/peer"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -20,6 +27,11 @@ func (s *Server) ListContainers(c *gin.Context) {
 
 	all := c.Query("all") == "true"
 
+	grouped, ok := groupByStack(c)
+	if !ok {
+		return
+	}
+
 	containers, err := s.docker.ListContainers(ctx, all)
 	if err != nil {
 		s.logger.Error("failed to list containers", zap.Error(err))
@@ -27,9 +39,32 @@ func (s *Server) ListContainers(c *gin.Context) {
 		return
 	}
 
+	if grouped {
+		stacks := docker.NewStackIndex()
+		for _, container := range containers {
+			stacks.AddContainer(container.ID, container.Labels)
+		}
+		c.JSON(http.StatusOK, gin.H{"containers": containers, "stacks": stacks.List()})
+		return
+	}
+
 	c.JSON(http.StatusOK, containers)
 }
 
+// groupByStack reports whether a list request asked for group_by=stack,
+// writing a 400 for any other grouping. Grouped lists are returned whole,
+// next to the stacks naming which of their resources each one holds.
+func groupByStack(c *gin.Context) (bool, bool) {
+	switch c.Query("group_by") {
+	case "":
+		return false, true
+	case "stack":
+		return true, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown group_by %q (expected stack)", c.Query("group_by"))})
+	return false, false
+}
+
 // GetContainer returns detailed container information
 func (s *Server) GetContainer(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
@@ -61,7 +96,7 @@ func (s *Server) StartContainer(c *gin.Context) {
 	}
 
 	// Broadcast update to all connected clients
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"containers"}`))
+	s.events.Publish(events.ResourceChanged("containers"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "started", "container_id": containerID})
 }
@@ -87,7 +122,7 @@ func (s *Server) StopContainer(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"containers"}`))
+	s.events.Publish(events.ResourceChanged("containers"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "stopped", "container_id": containerID})
 }
@@ -113,7 +148,7 @@ func (s *Server) RestartContainer(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"containers"}`))
+	s.events.Publish(events.ResourceChanged("containers"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "restarted", "container_id": containerID})
 }
@@ -132,7 +167,7 @@ func (s *Server) RemoveContainer(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"containers"}`))
+	s.events.Publish(events.ResourceChanged("containers"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "removed", "container_id": containerID})
 }
@@ -237,7 +272,7 @@ func (s *Server) PullImage(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"images"}`))
+	s.events.Publish(events.ResourceChanged("images"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "pulled", "image": req.Image})
 }
@@ -256,7 +291,7 @@ func (s *Server) RemoveImage(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"images"}`))
+	s.events.Publish(events.ResourceChanged("images"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "removed", "image_id": imageID})
 }
@@ -266,6 +301,11 @@ func (s *Server) ListVolumes(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
+	grouped, ok := groupByStack(c)
+	if !ok {
+		return
+	}
+
 	volumes, err := s.docker.ListVolumes(ctx)
 	if err != nil {
 		s.logger.Error("failed to list volumes", zap.Error(err))
@@ -273,6 +313,15 @@ func (s *Server) ListVolumes(c *gin.Context) {
 		return
 	}
 
+	var stacks []*docker.StackResources
+	if grouped {
+		index := docker.NewStackIndex()
+		for _, vol := range volumes {
+			index.AddVolume(vol.Name, vol.Labels)
+		}
+		stacks = index.List()
+	}
+
 	// Optionally calculate sizes
 	includeSize := c.Query("size") == "true"
 	var volumeInfos []*VolumeInfo
@@ -295,7 +344,13 @@ func (s *Server) ListVolumes(c *gin.Context) {
 				Size:       info.Size,
 			})
 		}
+		if grouped {
+			c.JSON(http.StatusOK, gin.H{"volumes": volumeInfos, "stacks": stacks})
+			return
+		}
 		c.JSON(http.StatusOK, volumeInfos)
+	} else if grouped {
+		c.JSON(http.StatusOK, gin.H{"volumes": volumes, "stacks": stacks})
 	} else {
 		c.JSON(http.StatusOK, volumes)
 	}
@@ -342,7 +397,7 @@ func (s *Server) CreateVolume(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"volumes"}`))
+	s.events.Publish(events.ResourceChanged("volumes"))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"status": "created",
@@ -365,7 +420,7 @@ func (s *Server) RemoveVolume(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"volumes"}`))
+	s.events.Publish(events.ResourceChanged("volumes"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "removed", "name": volumeName})
 }
@@ -375,6 +430,11 @@ func (s *Server) ListNetworks(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
+	grouped, ok := groupByStack(c)
+	if !ok {
+		return
+	}
+
 	networks, err := s.docker.ListNetworks(ctx)
 	if err != nil {
 		s.logger.Error("failed to list networks", zap.Error(err))
@@ -382,6 +442,15 @@ func (s *Server) ListNetworks(c *gin.Context) {
 		return
 	}
 
+	if grouped {
+		stacks := docker.NewStackIndex()
+		for _, network := range networks {
+			stacks.AddNetwork(network.ID, network.Name, network.Labels)
+		}
+		c.JSON(http.StatusOK, gin.H{"networks": networks, "stacks": stacks.List()})
+		return
+	}
+
 	c.JSON(http.StatusOK, networks)
 }
 
@@ -443,7 +512,7 @@ func (s *Server) CreateNetwork(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"networks"}`))
+	s.events.Publish(events.ResourceChanged("networks"))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"status":     "created",
@@ -465,11 +534,100 @@ func (s *Server) RemoveNetwork(c *gin.Context) {
 		return
 	}
 
-	s.hub.Broadcast([]byte(`{"type":"resource_update","resource":"networks"}`))
+	s.events.Publish(events.ResourceChanged("networks"))
 
 	c.JSON(http.StatusOK, gin.H{"status": "removed", "network_id": networkID})
 }
 
+// PruneImages removes dangling (or, with ?all=true, all unused) images
+func (s *Server) PruneImages(c *gin.Context) {
+	s.prune(c, "images", s.docker.PruneImages)
+}
+
+// PruneVolumes removes unreferenced anonymous (or, with ?all=true, named) volumes
+func (s *Server) PruneVolumes(c *gin.Context) {
+	s.prune(c, "volumes", s.docker.PruneVolumes)
+}
+
+// PruneBuildCache removes unused build cache
+func (s *Server) PruneBuildCache(c *gin.Context) {
+	s.prune(c, "", s.docker.PruneBuildCache)
+}
+
+// prune runs a prune honouring ?all= and ?dry_run=, announcing changes to resource
+// afterwards (empty for resources the UI doesn't list)
+func (s *Server) prune(c *gin.Context, resource string, fn func(ctx context.Context, all, dryRun bool) (*docker.PruneReport, error)) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+	defer cancel()
+
+	all := c.Query("all") == "true"
+	dryRun := c.Query("dry_run") == "true"
+
+	report, err := fn(ctx, all, dryRun)
+	if err != nil {
+		s.logger.Error("prune failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !dryRun && resource != "" && len(report.Deleted) > 0 {
+		s.events.Publish(events.ResourceChanged(resource))
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetSystemDF returns a `docker system df` style breakdown of disk usage plus
+// free space, for this host or, with ?peer= or ?worker=, a remote one
+func (s *Server) GetSystemDF(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	if peerID := c.Query("peer"); peerID != "" {
+		if s.discovery == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "peer discovery not initialized"})
+			return
+		}
+		client, err := s.discovery.Connect(ctx, peerID, nil)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		defer client.Close()
+
+		df, err := client.GetDiskUsage(ctx)
+		if err != nil {
+			s.logger.Error("failed to get peer disk usage", zap.String("peer_id", peerID), zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"peer_id": peerID, "disk_usage": df})
+		return
+	}
+
+	if workerID := c.Query("worker"); workerID != "" {
+		if s.master == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "not running in master mode"})
+			return
+		}
+		df, updatedAt, err := s.master.WorkerDiskUsage(workerID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"worker_id": workerID, "disk_usage": df, "updated_at": updatedAt})
+		return
+	}
+
+	df, err := s.docker.SystemDF(ctx)
+	if err != nil {
+		s.logger.Error("failed to get disk usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"disk_usage": df})
+}
+
 // ListPeers returns all connected peers
 func (s *Server) ListPeers(c *gin.Context) {
 	if s.pairing == nil {
@@ -481,6 +639,222 @@ func (s *Server) ListPeers(c *gin.Context) {
 	c.JSON(http.StatusOK, peers)
 }
 
+// GetPeer returns a trusted peer's details with a live reachability probe and
+// the migrations and transfers currently running against it
+func (s *Server) GetPeer(c *gin.Context) {
+	peerID := c.Param("id")
+
+	if s.pairing == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "pairing manager not initialized",
+		})
+		return
+	}
+
+	trusted, ok := s.pairing.GetTrustedPeer(peerID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "peer not found"})
+		return
+	}
+
+	response := gin.H{
+		"id":          trusted.ID,
+		"name":        trusted.Name,
+		"fingerprint": trusted.Fingerprint,
+		"address":     trusted.Address,
+		"addresses":   trusted.Addresses,
+		"first_seen":  trusted.FirstSeen,
+		"last_seen":   trusted.LastSeen,
+		"status":      peer.PeerOffline.String(),
+		// Compare with the other host to confirm the pairing wasn't intercepted
+		"verification": s.pairing.Verification(trusted.Fingerprint),
+	}
+
+	if s.discovery != nil {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+		latency, err := s.discovery.ProbePeer(ctx, peerID)
+		cancel()
+		if err != nil {
+			response["reachability_error"] = err.Error()
+		} else {
+			response["latency_ms"] = float64(latency.Microseconds()) / 1000
+		}
+
+		if p, ok := s.discovery.GetPeer(peerID); ok {
+			response["status"] = p.Status.String()
+			response["address"] = p.Address
+			response["last_seen"] = p.LastSeen
+			response["volume_drivers"] = p.VolumeDrivers
+			if p.ClockSkewKnown {
+				response["clock_skew_ms"] = p.ClockSkew.Milliseconds()
+				response["clock_skew_warning"] = peer.ClockSkewExceeded(p.ClockSkew)
+			}
+		}
+	}
+
+	if s.migration != nil {
+		jobs, transfers := s.migration.PeerActivity(peerID)
+		migrations := make([]gin.H, 0, len(jobs))
+		for _, job := range jobs {
+			migrations = append(migrations, gin.H{
+				"id":     job.ID,
+				"status": job.Status,
+				"phase":  job.CurrentPhase,
+			})
+		}
+		response["active_migrations"] = migrations
+		response["active_transfers"] = transfers
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RemovePeer untrusts a peer, cancelling its migrations and transfers first
+func (s *Server) RemovePeer(c *gin.Context) {
+	peerID := c.Param("id")
+
+	if s.pairing == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "pairing manager not initialized",
+		})
+		return
+	}
+
+	if _, ok := s.pairing.GetTrustedPeer(peerID); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "peer not found"})
+		return
+	}
+
+	cancelled := 0
+	if s.migration != nil {
+		cancelled = s.migration.CancelPeerActivity(peerID)
+	}
+
+	if s.discovery != nil {
+		s.discovery.RemovePeer(peerID)
+	}
+
+	if err := s.pairing.RemoveTrustedPeer(peerID); err != nil {
+		s.logger.Error("failed to remove peer",
+			zap.String("peer_id", peerID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.events.Publish(events.Event{
+		Type: events.PeerUpdated,
+		Data: gin.H{"peer_id": peerID, "removed": true},
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":               "removed",
+		"cancelled_migrations": cancelled,
+	})
+}
+
+// ListPendingPeers returns unknown peers recorded by trust-on-first-use
+func (s *Server) ListPendingPeers(c *gin.Context) {
+	if s.pairing == nil {
+		c.JSON(http.StatusOK, gin.H{"tofu": false, "pending": []interface{}{}})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tofu":    s.pairing.TOFUEnabled(),
+		"pending": s.pairing.PendingPeers(),
+	})
+}
+
+// ListNearbyPeers returns the peers advertising themselves on the local
+// network, so an unpaired one can be paired without typing its address
+func (s *Server) ListNearbyPeers(c *gin.Context) {
+	if s.discovery == nil || !s.discovery.MDNSEnabled() {
+		c.JSON(http.StatusOK, gin.H{"mdns": false, "peers": []interface{}{}})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"mdns":  true,
+		"peers": s.discovery.NearbyPeers(),
+	})
+}
+
+// ProbePeer connects to an address and records its certificate for
+// trust-on-first-use confirmation
+func (s *Server) ProbePeer(c *gin.Context) {
+	var req struct {
+		Address string `json:"address" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if s.pairing == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "pairing manager not initialized",
+		})
+		return
+	}
+
+	pending, err := s.pairing.ProbeFirstUse(c.Request.Context(), req.Address)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pending)
+}
+
+// ConfirmPendingPeer trusts a peer recorded by trust-on-first-use
+func (s *Server) ConfirmPendingPeer(c *gin.Context) {
+	fingerprint := c.Param("fingerprint")
+
+	if s.pairing == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "pairing manager not initialized",
+		})
+		return
+	}
+
+	trusted, err := s.pairing.ConfirmFirstUse(fingerprint)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if s.discovery != nil {
+		if err := s.discovery.RegisterPeer(trusted); err != nil {
+			s.logger.Warn("failed to register confirmed peer",
+				zap.String("peer_id", trusted.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	s.events.Publish(events.Event{
+		Type: events.PeerUpdated,
+		Data: gin.H{"peer_id": trusted.ID, "trusted_on_first_use": true},
+	})
+
+	c.JSON(http.StatusOK, trusted)
+}
+
+// RejectPendingPeer forgets a peer recorded by trust-on-first-use
+func (s *Server) RejectPendingPeer(c *gin.Context) {
+	fingerprint := c.Param("fingerprint")
+
+	if s.pairing == nil || !s.pairing.RejectFirstUse(fingerprint) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pending peer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "rejected"})
+}
+
 // GeneratePairingCode generates a pairing code for peer connection
 func (s *Server) GeneratePairingCode(c *gin.Context) {
 	if s.pairing == nil {
@@ -504,7 +878,7 @@ func (s *Server) GeneratePairingCode(c *gin.Context) {
 	})
 }
 
-// ConnectWithCode connects to a peer using a pairing code
+// ConnectWithCode pairs with the peer that generated a pairing code
 func (s *Server) ConnectWithCode(c *gin.Context) {
 	var req struct {
 		Code        string `json:"code" binding:"required"`
@@ -523,34 +897,84 @@ func (s *Server) ConnectWithCode(c *gin.Context) {
 		return
 	}
 
-	// Get pairing message for exchange
-	msg, err := s.pairing.GetPairingMessage(req.Code)
+	// The exchange runs over the peer's gRPC port, not its web port
+	trusted, err := s.pairing.PairWith(c.Request.Context(), req.PeerAddress, req.Code)
 	if err != nil {
-		s.logger.Error("failed to get pairing message", zap.Error(err))
+		s.logger.Warn("pairing failed",
+			zap.String("peer_address", req.PeerAddress),
+			zap.Error(err),
+		)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return pairing message for peer exchange
+	// Both hosts show these symbols; the user compares them out of band and
+	// confirms them on each host before the peer is trusted
 	c.JSON(http.StatusOK, gin.H{
-		"status":     "initiated",
-		"message":    "Pairing initiated, send this to peer",
-		"public_key": msg.PublicKey,
-		"verifier":   msg.CodeVerifier,
+		"id":           trusted.ID,
+		"name":         trusted.Name,
+		"fingerprint":  trusted.Fingerprint,
+		"address":      trusted.Address,
+		"status":       "awaiting_confirmation",
+		"verification": s.pairing.Verification(trusted.Fingerprint),
 	})
 }
 
+// ConfirmPairing trusts a paired peer once the user has checked that both
+// hosts show the same verification symbols
+func (s *Server) ConfirmPairing(c *gin.Context) {
+	peerID := c.Param("id")
+
+	if s.pairing == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "pairing manager not initialized",
+		})
+		return
+	}
+
+	trusted, err := s.pairing.ConfirmPairing(peerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if s.discovery != nil {
+		if err := s.discovery.RegisterPeer(trusted); err != nil {
+			s.logger.Warn("failed to register paired peer",
+				zap.String("peer_id", trusted.ID),
+				zap.Error(err),
+			)
+		}
+	}
+
+	s.events.Publish(events.Event{
+		Type: events.PeerUpdated,
+		Data: gin.H{"peer_id": trusted.ID, "paired": true, "confirmed": true},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"status": "trusted", "id": trusted.ID})
+}
+
+// RejectPairing discards a paired peer whose verification symbols did not match
+func (s *Server) RejectPairing(c *gin.Context) {
+	peerID := c.Param("id")
+
+	if s.pairing == nil || !s.pairing.RejectPairing(peerID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no pairing awaiting confirmation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "rejected"})
+}
+
 // StartMigration starts a migration job
 func (s *Server) StartMigration(c *gin.Context) {
 	var req struct {
-		PeerID     string   `json:"peer_id" binding:"required"`
-		Mode       string   `json:"mode"`      // copy or move
-		Strategy   string   `json:"strategy"`  // cold, warm, snapshot
-		Containers []string `json:"containers"`
-		Images     []string `json:"images"`
-		Volumes    []string `json:"volumes"`
-		Networks   []string `json:"networks"`
-		DryRun     bool     `json:"dry_run"`
+		PeerID string `json:"peer_id" binding:"required"`
+		DryRun bool   `json:"dry_run"`
+		// Hold creates the job without running it, to be pre-staged and started later
+		Hold bool `json:"hold"`
+		migration.MigrationSpec
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -565,48 +989,24 @@ func (s *Server) StartMigration(c *gin.Context) {
 		return
 	}
 
-	// Build resource refs
-	var resources []migration.ResourceRef
-	for _, id := range req.Containers {
-		resources = append(resources, migration.ResourceRef{
-			Type: "container",
-			ID:   id,
-			Name: id,
-		})
-	}
-	for _, id := range req.Images {
-		resources = append(resources, migration.ResourceRef{
-			Type: "image",
-			ID:   id,
-			Name: id,
-		})
-	}
-	for _, name := range req.Volumes {
-		resources = append(resources, migration.ResourceRef{
-			Type: "volume",
-			ID:   name,
-			Name: name,
-		})
-	}
-	for _, id := range req.Networks {
-		resources = append(resources, migration.ResourceRef{
-			Type: "network",
-			ID:   id,
-			Name: id,
-		})
-	}
+	s.runMigration(c, req.PeerID, req.DryRun, req.Hold, &req.MigrationSpec)
+}
 
-	// Create migration job
-	job := &migration.MigrationJob{
-		ID:        generateJobID(),
-		PeerID:    req.PeerID,
-		Mode:      migration.MigrationMode(req.Mode),
-		Strategy:  migration.MigrationStrategy(req.Strategy),
-		Resources: resources,
+// runMigration starts spec against the peer, or plans it when dryRun is set,
+// and writes the response. With hold the job is created but not run.
+func (s *Server) runMigration(c *gin.Context, peerID string, dryRun, hold bool, spec *migration.MigrationSpec) {
+	job, err := s.migration.NewJob(c.Request.Context(), peerID, spec)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, migration.ErrInvalidSpec) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Handle dry-run
-	if req.DryRun {
+	if dryRun {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
 		defer cancel()
 
@@ -621,10 +1021,24 @@ func (s *Server) StartMigration(c *gin.Context) {
 		return
 	}
 
+	if hold {
+		if err := s.migration.CreateMigration(c.Request.Context(), job); err != nil {
+			s.logger.Error("failed to create migration", zap.Error(err))
+			c.JSON(migrationStartStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{
+			"job_id":  job.ID,
+			"status":  string(job.Status),
+			"message": "Migration held; pre-stage it with POST /api/migrate/" + job.ID + "/prestage and run it with POST /api/migrate/" + job.ID + "/start",
+		})
+		return
+	}
+
 	// Start actual migration
 	if err := s.migration.StartMigration(c.Request.Context(), job); err != nil {
 		s.logger.Error("failed to start migration", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(migrationStartStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -635,9 +1049,12 @@ func (s *Server) StartMigration(c *gin.Context) {
 	})
 }
 
-// generateJobID creates a unique job identifier
-func generateJobID() string {
-	return fmt.Sprintf("mig_%d", time.Now().UnixNano())
+// migrationStartStatus is the HTTP status for a job that would not start
+func migrationStartStatus(err error) int {
+	if errors.Is(err, migration.ErrMigratedCopy) || errors.Is(err, migration.ErrRemigrationUnchecked) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
 }
 
 // GetMigrationStatus returns the status of a migration job
@@ -686,24 +1103,306 @@ func (s *Server) CancelMigration(c *gin.Context) {
 	})
 }
 
-// GetMigrationHistory returns past migrations
-func (s *Server) GetMigrationHistory(c *gin.Context) {
-	// TODO: Implement migration history
-	c.JSON(http.StatusOK, gin.H{
-		"migrations": []interface{}{},
-		"count":      0,
-	})
-}
-
-// ListComposeStacks returns all detected compose stacks
-func (s *Server) ListComposeStacks(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
-	defer cancel()
+// ResumeMigration resumes a paused migration or one interrupted by a restart
+func (s *Server) ResumeMigration(c *gin.Context) {
+	migrationID := c.Param("id")
 
-	stacks, err := s.docker.DetectComposeStacks(ctx)
-	if err != nil {
-		s.logger.Error("failed to detect compose stacks", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	if err := s.migration.ResumeMigration(migrationID); err != nil {
+		s.logger.Error("failed to resume migration",
+			zap.String("job_id", migrationID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "resumed",
+		"message": "Migration resumed",
+	})
+}
+
+// PrestageMigration sends a held migration's images and volume baselines to
+// the target ahead of its window
+func (s *Server) PrestageMigration(c *gin.Context) {
+	migrationID := c.Param("id")
+
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	if err := s.migration.PrestageMigration(migrationID); err != nil {
+		s.logger.Error("failed to pre-stage migration",
+			zap.String("job_id", migrationID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "staging",
+		"message": "Pre-staging started, check the job status for progress",
+	})
+}
+
+// StartHeldMigration runs a migration created with hold
+func (s *Server) StartHeldMigration(c *gin.Context) {
+	migrationID := c.Param("id")
+
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	if err := s.migration.StartPendingMigration(migrationID); err != nil {
+		s.logger.Error("failed to start held migration",
+			zap.String("job_id", migrationID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":  migrationID,
+		"status":  "started",
+		"message": "Migration started, use WebSocket for real-time progress",
+	})
+}
+
+// GetMigrationHistory lists finished migrations, most recent first. Query
+// parameters status, peer, strategy, since, until, limit and offset filter
+// the list; since and until take RFC 3339 times or durations like 24h.
+func (s *Server) GetMigrationHistory(c *gin.Context) {
+	if s.migration == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"migrations": []interface{}{},
+			"count":      0,
+		})
+		return
+	}
+
+	filter := migration.HistoryFilter{
+		Status:   migration.MigrationStatus(c.Query("status")),
+		PeerID:   c.Query("peer"),
+		Strategy: migration.MigrationStrategy(c.Query("strategy")),
+	}
+	now := time.Now()
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		value := c.Query(bound.name)
+		if value == "" {
+			continue
+		}
+		t, err := migration.ParseHistoryTime(value, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %v", bound.name, err)})
+			return
+		}
+		*bound.dst = t
+	}
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &filter.Limit}, {"offset", &filter.Offset}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s: %q", param.name, value)})
+			return
+		}
+		*param.dst = n
+	}
+
+	entries, err := s.migration.History(filter)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"migrations": entries,
+		"count":      len(entries),
+	})
+}
+
+// GetMigrationHistoryEntry returns the full record of a finished migration
+func (s *Server) GetMigrationHistoryEntry(c *gin.Context) {
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	job, err := s.migration.HistoryJob(c.Param("id"))
+	if errors.Is(err, migration.ErrHistoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetMigrationIntegrity returns a migration's integrity report and whether
+// its signature checks out. Finished jobs are looked up in the history too.
+func (s *Server) GetMigrationIntegrity(c *gin.Context) {
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	job, err := s.migration.GetStatus(c.Param("id"))
+	if err != nil {
+		job, err = s.migration.HistoryJob(c.Param("id"))
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if job.Integrity == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "migration has no integrity report yet"})
+		return
+	}
+
+	response := gin.H{
+		"report":    job.Integrity,
+		"signature": "valid",
+	}
+	if err := job.Integrity.Verify(s.migration.TrustedSigners()); err != nil {
+		response["signature"] = "invalid"
+		response["signature_error"] = err.Error()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// PurgeMigrations removes finished migration records. Without a body the configured
+// retention policy is applied; older_than and keep override it for this call.
+func (s *Server) PurgeMigrations(c *gin.Context) {
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	var req struct {
+		OlderThan string `json:"older_than"`
+		Keep      *int   `json:"keep"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	policy := migration.RetentionPolicy{
+		MaxAge:   s.config.JobRetention,
+		MaxCount: s.config.JobRetentionCount,
+	}
+	if req.OlderThan != "" {
+		age, err := time.ParseDuration(req.OlderThan)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid older_than: %v", err)})
+			return
+		}
+		policy.MaxAge = age
+	}
+	if req.Keep != nil {
+		policy.MaxCount = *req.Keep
+	}
+
+	purged := s.migration.PurgeJobs(policy)
+	c.JSON(http.StatusOK, gin.H{
+		"purged": purged,
+		"count":  len(purged),
+	})
+}
+
+// RollbackMigration restores the pre-migration state from a job's persisted snapshot
+func (s *Server) RollbackMigration(c *gin.Context) {
+	migrationID := c.Param("id")
+
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	force := c.Query("force") == "true"
+	if err := s.migration.RollbackJob(migrationID, force); err != nil {
+		s.logger.Error("failed to roll back migration",
+			zap.String("job_id", migrationID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "rolled_back",
+		"message": "Migration rolled back",
+	})
+}
+
+// DeleteMigration removes a single finished migration record
+func (s *Server) DeleteMigration(c *gin.Context) {
+	migrationID := c.Param("id")
+
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return
+	}
+
+	if err := s.migration.PurgeJob(migrationID); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, migration.ErrJobNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListComposeStacks returns all detected compose stacks
+func (s *Server) ListComposeStacks(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	stacks, err := s.docker.DetectComposeStacks(ctx)
+	if err != nil {
+		s.logger.Error("failed to detect compose stacks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -774,10 +1473,14 @@ func (s *Server) ValidateCompose(c *gin.Context) {
 	})
 }
 
-// ExportCompose exports all resources from a Compose project
+// ExportCompose exports all resources from a Compose project. With image
+// rewrites it also returns the compose file as it should be used on the
+// target.
 func (s *Server) ExportCompose(c *gin.Context) {
 	var req struct {
 		Path string `json:"path" binding:"required"`
+		// ImageRewrites rewrite the file's image references for the target's registry
+		ImageRewrites docker.ImageRewriteRules `json:"image_rewrites"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -785,6 +1488,11 @@ func (s *Server) ExportCompose(c *gin.Context) {
 		return
 	}
 
+	if err := req.ImageRewrites.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
 	defer cancel()
 
@@ -802,10 +1510,162 @@ func (s *Server) ExportCompose(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"project":   project.Name,
 		"resources": resources,
-	})
+	}
+
+	if len(req.ImageRewrites) > 0 {
+		data, err := os.ReadFile(req.Path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		rewritten, rewrites := docker.RewriteComposeFile(data, req.ImageRewrites)
+		response["compose_file"] = string(rewritten)
+		response["image_rewrites"] = rewrites
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportComposeBundle streams a detected stack's compose files as a tar
+// bundle, or with peer_id sends it to that peer. env_policy decides how
+// .env, env_file and secret files are bundled; encrypting needs peer_id, as
+// the session key that opens them travels in the bundle sealed for that
+// peer and is never returned.
+func (s *Server) ExportComposeBundle(c *gin.Context) {
+	var req struct {
+		EnvPolicy docker.EnvPolicy `json:"env_policy"`
+		PeerID    string           `json:"peer_id"`
+	}
+
+	// The body is optional; without it everything is bundled as it is
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := req.EnvPolicy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.EnvPolicy.Mode == docker.EnvEncrypt && req.PeerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the encrypt env policy needs peer_id, the peer to seal the session key for"})
+		return
+	}
+	if req.PeerID != "" && s.discovery == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "peer discovery not initialized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	stacks, err := s.docker.DetectComposeStacks(ctx)
+	if err != nil {
+		s.logger.Error("failed to detect compose stacks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var stack *docker.ComposeStack
+	for _, candidate := range stacks {
+		if candidate.Name == c.Param("name") {
+			stack = candidate
+			break
+		}
+	}
+	if stack == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "compose stack not found"})
+		return
+	}
+	if stack.ConfigPath == "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "compose stack has no known working directory"})
+		return
+	}
+
+	if req.PeerID != "" {
+		s.sendComposeBundle(c, ctx, stack, req.EnvPolicy, req.PeerID)
+		return
+	}
+
+	bundle, err := s.docker.ExportComposeBundle(ctx, stack, req.EnvPolicy, nil, nil)
+	if err != nil {
+		s.logger.Error("failed to export compose bundle", zap.String("stack", stack.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	extraHeaders := map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", stack.Name+".tar"),
+	}
+	c.DataFromReader(http.StatusOK, -1, "application/x-tar", bundle, extraHeaders)
+}
+
+// sendComposeBundle exports a stack's bundle and hands it to a peer. With
+// the encrypt policy its session key is sealed for the certificate the peer
+// presents, which the connection checks against the pinned fingerprint.
+func (s *Server) sendComposeBundle(c *gin.Context, ctx context.Context, stack *docker.ComposeStack, policy docker.EnvPolicy, peerID string) {
+	client, err := s.discovery.Connect(ctx, peerID, nil)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	defer client.Close()
+
+	var sessionKey, sealedKey []byte
+	if policy.Mode == docker.EnvEncrypt {
+		cert, err := client.PeerCertificate(ctx)
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		if sessionKey, err = docker.NewSessionKey(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		sealed, err := peer.SealBundleKey(cert, sessionKey)
+		if err == nil {
+			sealedKey, err = json.Marshal(sealed)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	bundle, err := s.docker.ExportComposeBundle(ctx, stack, policy, sessionKey, sealedKey)
+	if err != nil {
+		s.logger.Error("failed to export compose bundle", zap.String("stack", stack.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	data, err := io.ReadAll(bundle)
+	if err != nil {
+		s.logger.Error("failed to export compose bundle", zap.String("stack", stack.Name), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	path, err := client.SendComposeBundle(ctx, stack.Name, data)
+	if err != nil {
+		s.logger.Error("failed to send compose bundle",
+			zap.String("stack", stack.Name),
+			zap.String("peer_id", peerID),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("compose bundle sent",
+		zap.String("stack", stack.Name),
+		zap.String("peer_id", peerID),
+		zap.String("path", path),
+	)
+	c.JSON(http.StatusOK, gin.H{"peer_id": peerID, "path": path, "bytes": len(data)})
 }
 
 // VolumeInfo for API response
@@ -887,3 +1747,59 @@ func (s *Server) GetResourceCounts(c *gin.Context) {
 
 	c.JSON(http.StatusOK, counts)
 }
+
+// LoggingSettings is the runtime-adjustable logging configuration
+type LoggingSettings struct {
+	Level    string              `json:"level"`
+	Modules  map[string]string   `json:"modules"`
+	Sampling *config.LogSampling `json:"sampling"`
+}
+
+// GetLogging returns the current log level, module overrides and sampling
+func (s *Server) GetLogging(c *gin.Context) {
+	control := s.logger.Control()
+	initial, thereafter := control.Sampling()
+
+	c.JSON(http.StatusOK, LoggingSettings{
+		Level:    control.Level(),
+		Modules:  control.ModuleLevels(),
+		Sampling: &config.LogSampling{Initial: initial, Thereafter: thereafter},
+	})
+}
+
+// UpdateLogging changes logging at runtime. Omitted fields are left as they
+// are; modules replaces all overrides. Changes last until restart.
+func (s *Server) UpdateLogging(c *gin.Context) {
+	var req LoggingSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	control := s.logger.Control()
+	if req.Level != "" {
+		if err := control.SetLevel(req.Level); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Modules != nil {
+		if err := control.SetModuleLevels(req.Modules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Sampling != nil {
+		if err := control.SetSampling(req.Sampling.Initial, req.Sampling.Thereafter); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	s.logger.Info("logging settings changed",
+		zap.String("level", control.Level()),
+		zap.Any("modules", control.ModuleLevels()),
+	)
+
+	s.GetLogging(c)
+}
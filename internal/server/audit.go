@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/audit"
+	"github.com/artemis/docker-migrate/internal/master"
+	"github.com/gin-gonic/gin"
+)
+
+// auditedRoutes names the action each security-relevant route is recorded
+// as in the audit log. Routes missing here are not recorded.
+var auditedRoutes = map[string]string{
+	"POST /api/pair/generate":                      "pairing.code_generated",
+	"POST /api/pair/connect":                       "peer.paired",
+	"POST /api/pair/:id/confirm":                   "peer.pairing_confirmed",
+	"DELETE /api/pair/:id":                         "peer.pairing_rejected",
+	"POST /api/peers/pending/:fingerprint/confirm": "peer.trust_confirmed",
+	"DELETE /api/peers/pending/:fingerprint":       "peer.trust_rejected",
+	"DELETE /api/peers/:id":                        "peer.removed",
+
+	"POST /api/migrate":              "migration.start",
+	"POST /api/migrate/:id/start":    "migration.start",
+	"POST /api/templates/:name/run":  "migration.start",
+	"POST /api/plans/:name/run":      "migration.start",
+	"POST /api/schedules/:id/run":    "migration.start",
+	"POST /api/migrate/:id/cancel":   "migration.cancel",
+	"POST /api/migrate/:id/rollback": "migration.rollback",
+	"DELETE /api/migrate/:id":        "migration.deleted",
+	"POST /api/migrate/purge":        "migration.purged",
+
+	"DELETE /api/containers/:id":     "container.deleted",
+	"DELETE /api/images/:id":         "image.deleted",
+	"POST /api/images/prune":         "image.pruned",
+	"DELETE /api/volumes/:name":      "volume.deleted",
+	"POST /api/volumes/prune":        "volume.pruned",
+	"POST /api/build-cache/prune":    "build_cache.pruned",
+	"DELETE /api/networks/:id":       "network.deleted",
+	"DELETE /api/templates/:name":    "template.deleted",
+	"DELETE /api/plans/:name":        "template.deleted",
+	"DELETE /api/schedules/:id":      "schedule.deleted",
+	"POST /api/compose/:name/bundle": "compose.bundle_exported",
+
+	// Master mode
+	"POST /api/migrations":                                   "migration.start",
+	"POST /api/migrations/:id/approve":                       "migration.approved",
+	"POST /api/migrations/:id/reject":                        "migration.rejected",
+	"POST /api/migrations/:id/cancel":                        "migration.cancel",
+	"POST /api/master/migrations/:id/cancel":                 "migration.cancel",
+	"DELETE /api/workers/:id":                                "worker.removed",
+	"POST /api/workers/:id/rotate-token":                     "worker.token_rotated",
+	"POST /api/enrollment-token/regenerate":                  "enrollment.token_regenerated",
+	"POST /api/namespaces/:name/enrollment-token/regenerate": "enrollment.token_regenerated",
+	"POST /api/tokens":                                       "api_token.issued",
+	"DELETE /api/tokens/:id":                                 "api_token.revoked",
+	"POST /api/totp/enroll":                                  "totp.enrolled",
+	"DELETE /api/totp":                                       "totp.disabled",
+	"POST /api/namespaces":                                   "namespace.created",
+	"DELETE /api/namespaces/:name":                           "namespace.deleted",
+	"DELETE /api/ipam/allocations":                           "ipam.released",
+}
+
+// SetAuditLog records security-relevant API requests in log and serves it
+// for export and verification; call before Start
+func (s *Server) SetAuditLog(log *audit.Log) {
+	s.auditLog = log
+}
+
+// auditMiddleware records audited routes once they have been handled,
+// including requests refused for lack of authentication or role
+func (s *Server) auditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		action, ok := auditedRoutes[c.Request.Method+" "+c.FullPath()]
+		if !ok || s.auditLog == nil || c.GetBool(master.AuditRecordedKey) {
+			return
+		}
+
+		entry := audit.Entry{
+			Action: action,
+			Actor:  master.CallerIdentity(c),
+			Target: auditTarget(c),
+		}
+		switch status := c.Writer.Status(); {
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			entry.Outcome = audit.OutcomeDenied
+			entry.Detail = fmt.Sprintf("status=%d", status)
+		case status >= 400:
+			entry.Outcome = audit.OutcomeFailed
+			entry.Detail = fmt.Sprintf("status=%d", status)
+		}
+		s.auditLog.Record(entry)
+	}
+}
+
+// auditTarget describes what a request acted on from its path parameters
+func auditTarget(c *gin.Context) string {
+	parts := make([]string, 0, len(c.Params))
+	for _, p := range c.Params {
+		parts = append(parts, p.Key+"="+p.Value)
+	}
+	if subnet := c.Query("subnet"); subnet != "" {
+		parts = append(parts, "subnet="+subnet)
+	}
+	return strings.Join(parts, " ")
+}
+
+// requireUnscoped refuses callers confined to a namespace; only the whole
+// host's admins may read the audit log
+func requireUnscoped(c *gin.Context) {
+	if scope := master.CallerNamespace(c); scope != "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":     "not available to callers confined to a namespace",
+			"namespace": scope,
+		})
+		return
+	}
+	c.Next()
+}
+
+// ExportAuditLog downloads the audit log as JSON lines, with the hash of its
+// last entry in the X-Audit-Head header
+func (s *Server) ExportAuditLog(c *gin.Context) {
+	if s.auditLog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "audit log not initialized"})
+		return
+	}
+
+	var buf bytes.Buffer
+	head, err := s.auditLog.Export(&buf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("X-Audit-Head", head)
+	c.Header("Content-Disposition", `attachment; filename="`+audit.FileName+`"`)
+	c.Data(http.StatusOK, "application/x-ndjson", buf.Bytes())
+}
+
+// VerifyAuditLog checks the audit log's hash chain
+func (s *Server) VerifyAuditLog(c *gin.Context) {
+	if s.auditLog == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "audit log not initialized"})
+		return
+	}
+
+	result, err := s.auditLog.Verify()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,442 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/master"
+	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// sessionCookie holds the UI session ID
+	sessionCookie = "docker_migrate_session"
+
+	// loginPath starts the OIDC login; the API points browsers here on 401
+	loginPath = "/auth/login"
+
+	defaultSessionTTL = 12 * time.Hour
+
+	// stateCookie ties a login's state to the browser that started it
+	stateCookie = "docker_migrate_oidc_state"
+
+	// pendingLoginTTL bounds how long a user may take at the provider
+	pendingLoginTTL = 10 * time.Minute
+
+	// maxPendingLogins bounds the logins awaiting a callback, which anyone
+	// can start
+	maxPendingLogins = 1000
+)
+
+// uiSession is a signed-in web UI user
+type uiSession struct {
+	Subject   string      `json:"subject"`
+	Email     string      `json:"email,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Role      master.Role `json:"role"`
+	Namespace string      `json:"namespace,omitempty"`
+	ExpiresAt time.Time   `json:"expires_at"`
+}
+
+// pendingLogin is an authorization request awaiting the provider's callback
+type pendingLogin struct {
+	nonce     string
+	verifier  string
+	expiresAt time.Time
+}
+
+// oidcAuth signs web UI users in through an OpenID Connect provider and
+// keeps their sessions in memory; a restart signs everyone out
+type oidcAuth struct {
+	cfg         *config.OIDCConfig
+	roles       map[string]master.Role
+	defaultRole master.Role
+	sessionTTL  time.Duration
+	logger      *observability.Logger
+
+	mu       sync.Mutex
+	provider *oidc.Provider // discovered on first login
+	pending  map[string]*pendingLogin
+	sessions map[string]*uiSession
+}
+
+// newOIDCAuth validates cfg. The provider is not contacted until the first
+// login, so an unreachable provider does not keep the server from starting.
+func newOIDCAuth(cfg *config.OIDCConfig, logger *observability.Logger) (*oidcAuth, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: issuer, client_id and redirect_url are required")
+	}
+
+	roles := make(map[string]master.Role, len(cfg.GroupRoles))
+	for group, name := range cfg.GroupRoles {
+		if name == "" {
+			return nil, fmt.Errorf("oidc: group %q has no role", group)
+		}
+		role, err := master.ParseRole(name)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: group %q: %w", group, err)
+		}
+		roles[group] = role
+	}
+
+	var defaultRole master.Role
+	if cfg.DefaultRole != "" {
+		role, err := master.ParseRole(cfg.DefaultRole)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: default_role: %w", err)
+		}
+		defaultRole = role
+	}
+	if len(roles) == 0 && defaultRole == "" {
+		return nil, fmt.Errorf("oidc: set group_roles or default_role, or no one can sign in")
+	}
+	for group, namespace := range cfg.GroupNamespaces {
+		if err := master.ValidateNamespaceName(namespace); err != nil {
+			return nil, fmt.Errorf("oidc: group %q: %w", group, err)
+		}
+	}
+
+	sessionTTL := cfg.SessionTTL
+	if sessionTTL <= 0 {
+		sessionTTL = defaultSessionTTL
+	}
+
+	return &oidcAuth{
+		cfg:         cfg,
+		roles:       roles,
+		defaultRole: defaultRole,
+		sessionTTL:  sessionTTL,
+		logger:      logger,
+		pending:     make(map[string]*pendingLogin),
+		sessions:    make(map[string]*uiSession),
+	}, nil
+}
+
+// registerRoutes adds the login, callback, logout and whoami endpoints
+func (a *oidcAuth) registerRoutes(r *gin.Engine) {
+	r.GET(loginPath, a.login)
+	r.GET("/auth/callback", a.callback)
+	r.POST("/auth/logout", a.logout)
+	r.GET("/auth/me", a.me)
+}
+
+// oauth2Config discovers the provider on first use
+func (a *oidcAuth) oauth2Config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	a.mu.Lock()
+	provider := a.provider
+	a.mu.Unlock()
+
+	if provider == nil {
+		discovered, err := oidc.NewProvider(ctx, a.cfg.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+		}
+		a.mu.Lock()
+		a.provider = discovered
+		a.mu.Unlock()
+		provider = discovered
+	}
+
+	scopes := a.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"profile", "email", "groups"}
+	}
+	return &oauth2.Config{
+		ClientID:     a.cfg.ClientID,
+		ClientSecret: a.cfg.ClientSecret,
+		RedirectURL:  a.cfg.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       append([]string{oidc.ScopeOpenID}, scopes...),
+	}, provider, nil
+}
+
+func (a *oidcAuth) login(c *gin.Context) {
+	oauthCfg, _, err := a.oauth2Config(c.Request.Context())
+	if err != nil {
+		a.logger.Error("oidc login unavailable", zap.Error(err))
+		c.String(http.StatusBadGateway, "sign-in provider unavailable")
+		return
+	}
+
+	state, err := randomID()
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	nonce, err := randomID()
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	a.mu.Lock()
+	a.pruneLocked(time.Now())
+	if len(a.pending) >= maxPendingLogins {
+		a.mu.Unlock()
+		a.logger.Warn("refused oidc login, too many awaiting a callback", zap.Int("pending", maxPendingLogins))
+		c.String(http.StatusServiceUnavailable, "too many sign-ins in progress; try again shortly")
+		return
+	}
+	a.pending[state] = &pendingLogin{
+		nonce:     nonce,
+		verifier:  verifier,
+		expiresAt: time.Now().Add(pendingLoginTTL),
+	}
+	a.mu.Unlock()
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie, state, int(pendingLoginTTL.Seconds()), "/auth", "", a.secureCookies(), true)
+	c.Redirect(http.StatusFound, oauthCfg.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.S256ChallengeOption(verifier),
+	))
+}
+
+func (a *oidcAuth) callback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		c.String(http.StatusUnauthorized, "sign-in failed: %s", errCode)
+		return
+	}
+
+	// A callback for a login another browser started is refused, so no one
+	// can sign a victim in to their own account
+	state := c.Query("state")
+	cookie, err := c.Cookie(stateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		c.String(http.StatusBadRequest, "sign-in was not started in this browser; start again at %s", loginPath)
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie, "", -1, "/auth", "", a.secureCookies(), true)
+
+	a.mu.Lock()
+	pending, ok := a.pending[state]
+	delete(a.pending, state)
+	a.mu.Unlock()
+	if !ok || time.Now().After(pending.expiresAt) {
+		c.String(http.StatusBadRequest, "sign-in expired or was already used; start again at %s", loginPath)
+		return
+	}
+
+	ctx := c.Request.Context()
+	oauthCfg, provider, err := a.oauth2Config(ctx)
+	if err != nil {
+		c.String(http.StatusBadGateway, "sign-in provider unavailable")
+		return
+	}
+
+	token, err := oauthCfg.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(pending.verifier))
+	if err != nil {
+		a.logger.Warn("oidc code exchange failed", zap.Error(err))
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		c.String(http.StatusUnauthorized, "sign-in failed: provider returned no id_token")
+		return
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: a.cfg.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != pending.nonce {
+		a.logger.Warn("rejected oidc id token", zap.Error(err))
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		c.String(http.StatusUnauthorized, "sign-in failed")
+		return
+	}
+
+	groups := groupsClaim(claims, a.groupsClaimName())
+	session := &uiSession{
+		Subject:   idToken.Subject,
+		Email:     stringClaim(claims, "email"),
+		Name:      stringClaim(claims, "name"),
+		Role:      a.roleFor(groups),
+		ExpiresAt: time.Now().Add(a.sessionTTL),
+	}
+	if session.Role == "" {
+		a.logger.Warn("refused oidc user with no mapped group",
+			zap.String("subject", session.Subject),
+			zap.String("email", session.Email),
+		)
+		c.String(http.StatusForbidden, "your account is not in a group allowed to use docker-migrate")
+		return
+	}
+	namespace, err := a.namespaceFor(groups)
+	if err != nil {
+		a.logger.Warn("refused oidc user with conflicting namespaces",
+			zap.String("subject", session.Subject),
+			zap.String("email", session.Email),
+			zap.Error(err),
+		)
+		c.String(http.StatusForbidden, "your account's groups map to more than one namespace")
+		return
+	}
+	session.Namespace = namespace
+
+	id, err := randomID()
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	a.mu.Lock()
+	a.sessions[id] = session
+	a.mu.Unlock()
+
+	a.logger.Info("ui user signed in",
+		zap.String("subject", session.Subject),
+		zap.String("email", session.Email),
+		zap.String("role", string(session.Role)),
+		zap.String("namespace", session.Namespace),
+	)
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, id, int(a.sessionTTL.Seconds()), "/", "", a.secureCookies(), true)
+	c.Redirect(http.StatusFound, "/")
+}
+
+func (a *oidcAuth) logout(c *gin.Context) {
+	if id, err := c.Cookie(sessionCookie); err == nil {
+		a.mu.Lock()
+		delete(a.sessions, id)
+		a.mu.Unlock()
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookie, "", -1, "/", "", a.secureCookies(), true)
+	c.JSON(http.StatusOK, gin.H{"message": "signed out"})
+}
+
+func (a *oidcAuth) me(c *gin.Context) {
+	session := a.session(c)
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not signed in", "login_url": loginPath})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// session returns the request's live UI session, if any
+func (a *oidcAuth) session(c *gin.Context) *uiSession {
+	id, err := c.Cookie(sessionCookie)
+	if err != nil || id == "" {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	session, ok := a.sessions[id]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(session.ExpiresAt) {
+		delete(a.sessions, id)
+		return nil
+	}
+	return session
+}
+
+// pruneLocked drops expired sessions and abandoned logins
+func (a *oidcAuth) pruneLocked(now time.Time) {
+	for state, p := range a.pending {
+		if now.After(p.expiresAt) {
+			delete(a.pending, state)
+		}
+	}
+	for id, s := range a.sessions {
+		if now.After(s.ExpiresAt) {
+			delete(a.sessions, id)
+		}
+	}
+}
+
+// roleFor returns the highest role mapped from groups, else the default role
+func (a *oidcAuth) roleFor(groups []string) master.Role {
+	var best master.Role
+	for _, group := range groups {
+		role, ok := a.roles[group]
+		if ok && (best == "" || !best.Allows(role)) {
+			best = role
+		}
+	}
+	if best == "" {
+		return a.defaultRole
+	}
+	return best
+}
+
+// namespaceFor returns the namespace mapped from groups, or "" if none is.
+// Groups mapping to different namespaces are an error rather than a union.
+func (a *oidcAuth) namespaceFor(groups []string) (string, error) {
+	var namespace string
+	for _, group := range groups {
+		ns, ok := a.cfg.GroupNamespaces[group]
+		if !ok {
+			continue
+		}
+		if namespace != "" && namespace != ns {
+			return "", fmt.Errorf("groups map to namespaces %q and %q", namespace, ns)
+		}
+		namespace = ns
+	}
+	return namespace, nil
+}
+
+func (a *oidcAuth) groupsClaimName() string {
+	if a.cfg.GroupsClaim != "" {
+		return a.cfg.GroupsClaim
+	}
+	return "groups"
+}
+
+// secureCookies marks cookies Secure whenever the UI is served over HTTPS
+func (a *oidcAuth) secureCookies() bool {
+	return strings.HasPrefix(a.cfg.RedirectURL, "https://")
+}
+
+// groupsClaim reads a claim that providers send as a list or a single string
+func groupsClaim(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		groups := make([]string, 0, len(v))
+		for _, g := range v {
+			if s, ok := g.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+		return groups
+	}
+	return nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// randomID returns an unguessable URL-safe identifier
+func randomID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
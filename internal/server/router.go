@@ -1,12 +1,16 @@
 package server
 
 import (
+	"context"
 	"embed"
 	"io/fs"
 	"net/http"
+	"strings"
 
+	"github.com/artemis/docker-migrate/internal/audit"
 	"github.com/artemis/docker-migrate/internal/config"
 	"github.com/artemis/docker-migrate/internal/docker"
+	"github.com/artemis/docker-migrate/internal/events"
 	"github.com/artemis/docker-migrate/internal/master"
 	"github.com/artemis/docker-migrate/internal/migration"
 	"github.com/artemis/docker-migrate/internal/observability"
@@ -30,8 +34,13 @@ type Server struct {
 	discovery      *peer.PeerDiscovery
 	metrics        *observability.Metrics
 	hub            *Hub
+	events         *events.Bus
+	unsubscribe    func()
 	router         *gin.Engine
-	master         *master.Master // Set when running in master mode
+	master         *master.Master  // Set when running in master mode
+	oidc           *oidcAuth       // Set when UI login is configured
+	auditLog       *audit.Log      // Security-relevant actions; nil records nothing
+	ctx            context.Context // Root context, set by Start
 }
 
 // NewServer creates a new HTTP server
@@ -54,13 +63,16 @@ func NewServer(
 		logger: logger,
 		health: healthChecker,
 		hub:    NewHub(logger),
+		events: events.NewBus(logger.Logger),
+		ctx:    context.Background(),
 	}
 
 	s.setupRouter()
 	return s
 }
 
-// NewServerWithDeps creates a new HTTP server with all dependencies wired
+// NewServerWithDeps creates a new HTTP server with all dependencies wired.
+// bus is shared with the engine so its job events reach the WebSocket hub.
 func NewServerWithDeps(
 	cfg *config.Config,
 	dockerClient *docker.Client,
@@ -69,6 +81,7 @@ func NewServerWithDeps(
 	peerDiscovery *peer.PeerDiscovery,
 	healthChecker *observability.HealthChecker,
 	metrics *observability.Metrics,
+	bus *events.Bus,
 	logger *observability.Logger,
 ) *Server {
 	// Set gin mode based on log level
@@ -88,6 +101,8 @@ func NewServerWithDeps(
 		discovery: peerDiscovery,
 		metrics:   metrics,
 		hub:       NewHub(logger),
+		events:    bus,
+		ctx:       context.Background(),
 	}
 
 	s.setupRouter()
@@ -98,20 +113,36 @@ func NewServerWithDeps(
 func (s *Server) setupRouter() {
 	r := gin.New()
 
+	// Client addresses key TOTP lockouts and the audit log, so a forwarded
+	// address is only believed from a configured proxy
+	if err := r.SetTrustedProxies(s.config.TrustedProxies); err != nil {
+		s.logger.Error("invalid trusted_proxies, trusting no proxy", zap.Error(err))
+		r.SetTrustedProxies(nil)
+	}
+
 	// Middleware
 	r.Use(gin.Recovery())
 	r.Use(s.loggingMiddleware())
 	r.Use(s.corsMiddleware())
+	r.Use(s.auditMiddleware())
+	r.Use(s.authMiddleware())
+	r.Use(s.namespaceMiddleware())
 
 	// Health endpoints (no auth required)
 	r.GET("/health", s.health.HealthHandler())
 	r.GET("/ready", s.health.ReadyHandler())
+	r.GET("/healthz", s.health.LivezHandler())
+	r.GET("/readyz", s.health.ReadyzHandler())
 
 	// Metrics endpoint (no auth required)
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// API routes
 	api := r.Group("/api")
+	// Writes need operator even where authMiddleware's method check would
+	// catch them; bulk deletions and server settings need admin
+	operator := s.requireRole(master.RoleOperator)
+	admin := s.requireRole(master.RoleAdmin)
 	{
 		// Config info (for determining mode)
 		api.GET("/config", s.GetConfig)
@@ -122,51 +153,107 @@ func (s *Server) setupRouter() {
 		// Container management
 		api.GET("/containers", s.ListContainers)
 		api.GET("/containers/:id", s.GetContainer)
-		api.POST("/containers/:id/start", s.StartContainer)
-		api.POST("/containers/:id/stop", s.StopContainer)
-		api.POST("/containers/:id/restart", s.RestartContainer)
-		api.DELETE("/containers/:id", s.RemoveContainer)
+		api.POST("/containers/:id/start", operator, s.StartContainer)
+		api.POST("/containers/:id/stop", operator, s.StopContainer)
+		api.POST("/containers/:id/restart", operator, s.RestartContainer)
+		api.DELETE("/containers/:id", operator, s.RemoveContainer)
 		api.GET("/containers/:id/logs", s.GetContainerLogs)
 
 		// Image management
 		api.GET("/images", s.ListImages)
 		api.GET("/images/:id", s.GetImage)
-		api.POST("/images/pull", s.PullImage)
-		api.DELETE("/images/:id", s.RemoveImage)
+		api.POST("/images/pull", operator, s.PullImage)
+		api.DELETE("/images/:id", operator, s.RemoveImage)
+		api.POST("/images/prune", admin, s.PruneImages)
 
 		// Volume management
 		api.GET("/volumes", s.ListVolumes)
 		api.GET("/volumes/:name", s.GetVolume)
-		api.POST("/volumes", s.CreateVolume)
-		api.DELETE("/volumes/:name", s.RemoveVolume)
+		api.POST("/volumes", operator, s.CreateVolume)
+		api.POST("/volumes/prune", admin, s.PruneVolumes)
+		api.DELETE("/volumes/:name", operator, s.RemoveVolume)
+
+		// Build cache
+		api.POST("/build-cache/prune", admin, s.PruneBuildCache)
+
+		// System
+		api.GET("/system/df", s.GetSystemDF)
+
+		// Logging
+		api.GET("/logging", s.GetLogging)
+		api.PUT("/logging", admin, s.UpdateLogging)
 
 		// Network management
 		api.GET("/networks", s.ListNetworks)
 		api.GET("/networks/:id", s.GetNetwork)
-		api.POST("/networks", s.CreateNetwork)
-		api.DELETE("/networks/:id", s.RemoveNetwork)
+		api.POST("/networks", operator, s.CreateNetwork)
+		api.DELETE("/networks/:id", operator, s.RemoveNetwork)
 
 		// Peer management
 		api.GET("/peers", s.ListPeers)
-		api.POST("/pair/generate", s.GeneratePairingCode)
-		api.POST("/pair/connect", s.ConnectWithCode)
+		api.GET("/peers/:id", s.GetPeer)
+		api.DELETE("/peers/:id", admin, s.RemovePeer)
+		api.GET("/peers/pending", s.ListPendingPeers)
+		api.GET("/peers/nearby", s.ListNearbyPeers)
+		api.POST("/peers/probe", admin, s.ProbePeer)
+		api.POST("/peers/pending/:fingerprint/confirm", admin, s.ConfirmPendingPeer)
+		api.DELETE("/peers/pending/:fingerprint", admin, s.RejectPendingPeer)
+		api.POST("/pair/generate", admin, s.GeneratePairingCode)
+		api.POST("/pair/connect", admin, s.ConnectWithCode)
+		api.POST("/pair/:id/confirm", admin, s.ConfirmPairing)
+		api.DELETE("/pair/:id", admin, s.RejectPairing)
+
+		// Security audit log
+		api.GET("/audit/log", admin, requireUnscoped, s.ExportAuditLog)
+		api.GET("/audit/log/verify", admin, requireUnscoped, s.VerifyAuditLog)
 
 		// Migration operations
-		api.POST("/migrate", s.StartMigration)
+		api.POST("/migrate", admin, s.StartMigration)
 		api.GET("/migrate/:id/status", s.GetMigrationStatus)
-		api.POST("/migrate/:id/cancel", s.CancelMigration)
+		api.POST("/migrate/:id/cancel", operator, s.CancelMigration)
+		api.POST("/migrate/:id/resume", operator, s.ResumeMigration)
+		api.POST("/migrate/:id/prestage", admin, s.PrestageMigration)
+		api.POST("/migrate/:id/start", admin, s.StartHeldMigration)
+		api.POST("/migrate/:id/rollback", operator, s.RollbackMigration)
 		api.GET("/migrate/history", s.GetMigrationHistory)
+		api.GET("/migrate/history/:id", s.GetMigrationHistoryEntry)
+		api.GET("/migrate/:id/integrity", s.GetMigrationIntegrity)
+		api.POST("/migrate/purge", admin, s.PurgeMigrations)
+		api.DELETE("/migrate/:id", operator, s.DeleteMigration)
+
+		// Migration templates
+		api.GET("/templates", s.ListTemplates)
+		api.GET("/templates/:name", s.GetTemplate)
+		api.PUT("/templates/:name", admin, s.SaveTemplate)
+		api.DELETE("/templates/:name", admin, s.DeleteTemplate)
+		api.POST("/templates/:name/run", admin, s.RunTemplate)
+
+		// Saved plans are templates with a target peer, under their own name
+		api.GET("/plans", s.ListTemplates)
+		api.GET("/plans/:name", s.GetTemplate)
+		api.PUT("/plans/:name", admin, s.SaveTemplate)
+		api.DELETE("/plans/:name", admin, s.DeleteTemplate)
+		api.POST("/plans/:name/run", admin, s.RunTemplate)
+
+		// Scheduled migrations
+		api.GET("/schedules", s.ListSchedules)
+		api.GET("/schedules/:id", s.GetSchedule)
+		api.POST("/schedules", admin, s.CreateSchedule)
+		api.DELETE("/schedules/:id", admin, s.DeleteSchedule)
+		api.POST("/schedules/:id/run", admin, s.RunSchedule)
 
 		// Compose operations
 		api.GET("/compose", s.ListComposeStacks)
 		api.GET("/compose/:name", s.GetComposeStack)
-		api.POST("/compose/validate", s.ValidateCompose)
-		api.POST("/compose/export", s.ExportCompose)
+		api.POST("/compose/validate", operator, s.ValidateCompose)
+		api.POST("/compose/export", operator, s.ExportCompose)
+		api.POST("/compose/:name/bundle", admin, s.ExportComposeBundle)
 	}
 
 	// WebSocket endpoints
 	r.GET("/ws", s.HandleWebSocket)
 	r.GET("/ws/containers/:id/logs", s.HandleContainerLogs)
+	r.GET("/ws/migrate/:id/logs", s.HandleMigrationLogs)
 
 	// Serve embedded web UI
 	s.setupStaticFiles(r)
@@ -206,7 +293,8 @@ func (s *Server) setupStaticFiles(r *gin.Engine) {
 func (s *Server) loggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Don't log health check spam
-		if c.Request.URL.Path == "/health" || c.Request.URL.Path == "/ready" {
+		switch c.Request.URL.Path {
+		case "/health", "/ready", "/healthz", "/readyz":
 			c.Next()
 			return
 		}
@@ -228,7 +316,7 @@ func (s *Server) corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-TOTP-Code, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -240,10 +328,129 @@ func (s *Server) corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-// Start starts the HTTP server
-func (s *Server) Start() error {
-	// Start WebSocket hub
-	go s.hub.Run()
+// authMiddleware authenticates the API and WebSocket routes: by UI session
+// when OIDC is configured, and by API token in master mode. The worker tunnel
+// is exempt: it checks the worker's own token before upgrading.
+func (s *Server) authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if path == "/api/tunnel" ||
+			!(strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/ws")) {
+			c.Next()
+			return
+		}
+
+		if s.oidc != nil {
+			if session := s.oidc.session(c); session != nil {
+				master.SetCallerRole(c, session.Role)
+				master.SetCallerNamespace(c, session.Namespace)
+				user := session.Email
+				if user == "" {
+					user = session.Subject
+				}
+				c.Set(master.UserContextKey, user)
+				if master.CheckRole(c, s.logger, master.MethodRole(c.Request.Method)) {
+					c.Next()
+				}
+				return
+			}
+			// Without a session only API tokens get in, and only if enabled
+			if !s.config.IsMaster() || s.config.Master == nil || !s.config.Master.RequireAPIToken {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":     "sign-in required",
+					"login_url": loginPath,
+				})
+				return
+			}
+		}
+
+		if s.master == nil {
+			c.Next()
+			return
+		}
+		s.master.Authenticate(c)
+	}
+}
+
+// namespacedRoutes are the routes that confine what they show and do to the
+// caller's namespace. Callers confined to a namespace get nothing else: the
+// rest act on this host, which belongs to no namespace.
+var namespacedRoutes = map[string]bool{
+	"GET /api/config": true,
+	"GET /ws":         true, // Events outside the caller's namespace are filtered out
+
+	"GET /api/workers":                   true,
+	"GET /api/workers/:id":               true,
+	"GET /api/workers/:id/resources":     true,
+	"GET /api/workers/:id/df":            true,
+	"DELETE /api/workers/:id":            true,
+	"POST /api/workers/:id/rotate-token": true,
+
+	"POST /api/migrations":                   true,
+	"POST /api/migrations/estimate":          true,
+	"GET /api/migrations":                    true,
+	"GET /api/migrations/:id":                true,
+	"POST /api/migrations/:id/cancel":        true,
+	"POST /api/migrations/:id/approve":       true,
+	"POST /api/migrations/:id/reject":        true,
+	"GET /api/master/migrations":             true,
+	"GET /api/master/migrations/:id":         true,
+	"POST /api/master/migrations/:id/cancel": true,
+	"GET /api/master/schedules":              true,
+	"GET /api/master/schedules/:id":          true,
+	"POST /api/master/schedules":             true,
+	"DELETE /api/master/schedules/:id":       true,
+	"POST /api/master/schedules/:id/run":     true,
+
+	"GET /api/namespaces": true,
+	"POST /api/namespaces/:name/enrollment-token/regenerate": true,
+	"GET /api/totp": true,
+}
+
+// namespaceMiddleware refuses callers confined to a namespace on every API
+// and WebSocket route not in namespacedRoutes
+func (s *Server) namespaceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := master.CallerNamespace(c)
+		path := c.Request.URL.Path
+		if scope == "" || namespacedRoutes[c.Request.Method+" "+c.FullPath()] ||
+			!(strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/ws")) {
+			c.Next()
+			return
+		}
+
+		s.logger.Warn("rejected namespaced caller outside its namespace",
+			zap.String("namespace", scope),
+			zap.String("method", c.Request.Method),
+			zap.String("path", path),
+		)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":     "not available to callers confined to a namespace",
+			"namespace": scope,
+		})
+	}
+}
+
+// requireRole guards a route with a minimum role. Callers are admins unless
+// authMiddleware says otherwise.
+func (s *Server) requireRole(required master.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if master.CheckRole(c, s.logger, required) {
+			c.Next()
+		}
+	}
+}
+
+// Start starts the HTTP server. Background work such as the WebSocket hub
+// and log streams ends when ctx is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	s.ctx = ctx
+
+	// Start WebSocket hub and feed it from the event bus
+	go s.hub.Run(ctx)
+	var ch <-chan events.Event
+	ch, s.unsubscribe = s.events.Subscribe(events.DefaultSubscriberBuffer)
+	go s.hub.Consume(ch)
 
 	s.logger.Info("starting HTTP server",
 		zap.String("addr", s.config.HTTPAddr),
@@ -259,11 +466,14 @@ func (s *Server) Start() error {
 // Stop gracefully stops the server
 func (s *Server) Stop() error {
 	s.logger.Info("stopping HTTP server")
+	if s.unsubscribe != nil {
+		s.unsubscribe()
+	}
 	s.hub.Stop()
 	return nil
 }
 
-// Broadcast sends a message to all connected WebSocket clients
+// Broadcast sends a message to the WebSocket clients that see every namespace
 func (s *Server) Broadcast(message []byte) {
 	s.hub.Broadcast(message)
 }
@@ -275,6 +485,24 @@ func (s *Server) SetMaster(m *master.Master) {
 	api := s.router.Group("/api")
 	m.RegisterWorkerRoutes(api)
 	m.RegisterMigrationRoutes(api)
+	m.RegisterScheduleRoutes(api)
+	m.RegisterTunnelRoutes(api)
+	m.RegisterTokenRoutes(api)
+	m.RegisterNamespaceRoutes(api)
+	m.RegisterIPAMRoutes(api)
+	m.RegisterAuditRoutes(api)
+}
+
+// EnableOIDC requires UI users to sign in through the configured OIDC
+// provider; call before Start
+func (s *Server) EnableOIDC() error {
+	auth, err := newOIDCAuth(s.config.OIDC, s.logger)
+	if err != nil {
+		return err
+	}
+	s.oidc = auth
+	auth.registerRoutes(s.router)
+	return nil
 }
 
 // GetRouter returns the gin router for direct route registration
@@ -293,9 +521,20 @@ func (s *Server) GetConfig(c *gin.Context) {
 		"role": role,
 	}
 
-	// Include enrollment token if in master mode
+	// Include enrollment token if in master mode and the caller may manage it
 	if s.config.IsMaster() && s.config.Master != nil {
-		response["enrollment_token"] = s.config.Master.EnrollmentToken
+		callerRole := master.CallerRole(c)
+		response["api_role"] = callerRole
+		namespace := master.CallerNamespace(c)
+		if namespace != "" {
+			response["namespace"] = namespace
+		}
+		if callerRole.Allows(master.RoleAdmin) && namespace == "" {
+			response["enrollment_token"] = s.config.Master.EnrollmentToken
+		}
+		if s.master != nil {
+			response["master_fingerprint"] = s.master.Fingerprint()
+		}
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/migration"
+	"github.com/gin-gonic/gin"
+)
+
+// scheduleStore returns the engine's schedule store, writing the error
+// response when there is none
+func (s *Server) scheduleStore(c *gin.Context) (*migration.ScheduleStore, bool) {
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return nil, false
+	}
+	store, err := s.migration.Schedules()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return store, true
+}
+
+// scheduleError maps a schedule store error to a status code
+func scheduleError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		status = http.StatusNotFound
+	case errors.Is(err, migration.ErrInvalidSpec):
+		status = http.StatusBadRequest
+	case errors.Is(err, migration.ErrScheduleOverlap):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// ListSchedules returns every migration schedule, soonest due first
+func (s *Server) ListSchedules(c *gin.Context) {
+	store, ok := s.scheduleStore(c)
+	if !ok {
+		return
+	}
+	schedules, err := store.List()
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// GetSchedule returns one migration schedule
+func (s *Server) GetSchedule(c *gin.Context) {
+	store, ok := s.scheduleStore(c)
+	if !ok {
+		return
+	}
+	schedule, err := store.Get(c.Param("id"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// CreateSchedule schedules a migration: the body of POST /api/migrate
+// without dry_run, plus a cron expression or an RFC 3339 run_at
+func (s *Server) CreateSchedule(c *gin.Context) {
+	var schedule migration.MigrationSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, ok := s.scheduleStore(c)
+	if !ok {
+		return
+	}
+	if err := store.Add(&schedule); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// DeleteSchedule removes a migration schedule
+func (s *Server) DeleteSchedule(c *gin.Context) {
+	store, ok := s.scheduleStore(c)
+	if !ok {
+		return
+	}
+	if err := store.Delete(c.Param("id")); err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": c.Param("id")})
+}
+
+// RunSchedule starts a scheduled migration now instead of waiting for it
+func (s *Server) RunSchedule(c *gin.Context) {
+	if _, ok := s.scheduleStore(c); !ok {
+		return
+	}
+	jobID, err := s.migration.RunSchedule(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		scheduleError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":  jobID,
+		"status":  "started",
+		"message": "Migration started, use WebSocket for real-time progress",
+	})
+}
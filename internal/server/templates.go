@@ -0,0 +1,148 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/migration"
+	"github.com/gin-gonic/gin"
+)
+
+// templateStore returns the engine's template store, writing the error
+// response when there is none
+func (s *Server) templateStore(c *gin.Context) (*migration.TemplateStore, bool) {
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "migration engine not initialized",
+		})
+		return nil, false
+	}
+	store, err := s.migration.Templates()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return store, true
+}
+
+// templateError maps a template store error to a status code
+func templateError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		status = http.StatusNotFound
+	case strings.Contains(err.Error(), "invalid template name"):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// ListTemplates returns every saved migration template
+func (s *Server) ListTemplates(c *gin.Context) {
+	store, ok := s.templateStore(c)
+	if !ok {
+		return
+	}
+	templates, err := store.List()
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"count":     len(templates),
+	})
+}
+
+// GetTemplate returns one migration template
+func (s *Server) GetTemplate(c *gin.Context) {
+	store, ok := s.templateStore(c)
+	if !ok {
+		return
+	}
+	template, err := store.Get(c.Param("name"))
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// SaveTemplate creates or replaces the template named in the path
+func (s *Server) SaveTemplate(c *gin.Context) {
+	var req struct {
+		Description string `json:"description"`
+		PeerID      string `json:"peer_id"`
+		migration.MigrationSpec
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	store, ok := s.templateStore(c)
+	if !ok {
+		return
+	}
+	template := &migration.MigrationTemplate{
+		Name:          c.Param("name"),
+		Description:   req.Description,
+		PeerID:        req.PeerID,
+		MigrationSpec: req.MigrationSpec,
+	}
+	if err := store.Save(template); err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate removes a migration template
+func (s *Server) DeleteTemplate(c *gin.Context) {
+	store, ok := s.templateStore(c)
+	if !ok {
+		return
+	}
+	name := c.Param("name")
+	if err := store.Delete(name); err != nil {
+		templateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": name})
+}
+
+// RunTemplate starts a migration from a template against the given peer,
+// or the template's own when none is given
+func (s *Server) RunTemplate(c *gin.Context) {
+	var req struct {
+		PeerID string `json:"peer_id"`
+		DryRun bool   `json:"dry_run"`
+		Hold   bool   `json:"hold"`
+	}
+	// A saved plan can be run with no body at all
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	store, ok := s.templateStore(c)
+	if !ok {
+		return
+	}
+	template, err := store.Get(c.Param("name"))
+	if err != nil {
+		templateError(c, err)
+		return
+	}
+	peerID := req.PeerID
+	if peerID == "" {
+		peerID = template.PeerID
+	}
+	if peerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "peer_id is required: template " + template.Name + " has no target peer"})
+		return
+	}
+	s.runMigration(c, peerID, req.DryRun, req.Hold, &template.MigrationSpec)
+}
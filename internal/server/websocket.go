@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/artemis/docker-migrate/internal/events"
+	"github.com/artemis/docker-migrate/internal/master"
+	"github.com/artemis/docker-migrate/internal/migration"
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -23,35 +27,52 @@ var upgrader = websocket.Upgrader{
 
 // Client represents a WebSocket client
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
+	hub       *Hub
+	conn      *websocket.Conn
+	send      chan []byte
+	namespace string // The caller's namespace; empty sees every event
+}
+
+// hubMessage is a message for the clients allowed to see it
+type hubMessage struct {
+	data      []byte
+	namespace string // Also sent to clients confined to it; empty is for unscoped clients only
 }
 
 // Hub maintains active WebSocket connections
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan hubMessage
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
 	logger     *observability.Logger
 	running    bool
+	done       chan struct{} // closed when Run returns
+
+	// Resource updates are coalesced per resource type within coalesceWindow
+	pending   map[string]bool
+	pendingMu sync.Mutex
 }
 
+// coalesceWindow batches rapid resource changes into one update per type
+const coalesceWindow = 250 * time.Millisecond
+
 // NewHub creates a new WebSocket hub
 func NewHub(logger *observability.Logger) *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan hubMessage, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		logger:     logger,
+		pending:    make(map[string]bool),
+		done:       make(chan struct{}),
 	}
 }
 
-// Run starts the hub's main loop
-func (h *Hub) Run() {
+// Run starts the hub's main loop; it stops the hub and returns when ctx is cancelled
+func (h *Hub) Run(ctx context.Context) {
 	h.mu.Lock()
 	if h.running {
 		h.mu.Unlock()
@@ -61,9 +82,14 @@ func (h *Hub) Run() {
 	h.mu.Unlock()
 
 	h.logger.Info("websocket hub started")
+	defer close(h.done)
 
 	for {
 		select {
+		case <-ctx.Done():
+			h.Stop()
+			return
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -84,18 +110,21 @@ func (h *Hub) Run() {
 			)
 
 		case message := <-h.broadcast:
-			h.mu.RLock()
+			h.mu.Lock()
 			for client := range h.clients {
+				if client.namespace != "" && client.namespace != message.namespace {
+					continue
+				}
 				select {
-				case client.send <- message:
+				case client.send <- message.data:
 				default:
-					// Client send buffer is full, disconnect
-					h.mu.RUnlock()
-					h.unregister <- client
-					h.mu.RLock()
+					// Client send buffer is full, disconnect. Only this loop
+					// receives on unregister, so drop the client directly.
+					delete(h.clients, client)
+					close(client.send)
 				}
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
@@ -118,21 +147,74 @@ func (h *Hub) Stop() {
 	h.logger.Info("websocket hub stopped")
 }
 
-// Broadcast sends a message to all connected clients
+// Broadcast sends a message to the clients that see every namespace
 func (h *Hub) Broadcast(message []byte) {
+	h.BroadcastNamespace("", message)
+}
+
+// BroadcastNamespace sends a message to the clients that see every
+// namespace and to those confined to namespace
+func (h *Hub) BroadcastNamespace(namespace string, message []byte) {
 	if !h.running {
 		return
 	}
 
 	select {
-	case h.broadcast <- message:
+	case h.broadcast <- hubMessage{data: message, namespace: namespace}:
 	default:
 		h.logger.Warn("broadcast channel full, dropping message")
 	}
 }
 
-// BroadcastEvent sends a typed event to all clients
-func (h *Hub) BroadcastEvent(eventType string, data interface{}) {
+// NotifyResourceUpdate tells clients a resource type changed. Repeated calls
+// within coalesceWindow collapse into a single resource_update per type.
+func (h *Hub) NotifyResourceUpdate(resource string) {
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+
+	if len(h.pending) == 0 {
+		time.AfterFunc(coalesceWindow, h.flushResourceUpdates)
+	}
+	h.pending[resource] = true
+}
+
+// flushResourceUpdates broadcasts one update per resource type changed in the window
+func (h *Hub) flushResourceUpdates() {
+	h.pendingMu.Lock()
+	resources := make([]string, 0, len(h.pending))
+	for resource := range h.pending {
+		resources = append(resources, resource)
+	}
+	h.pending = make(map[string]bool)
+	h.pendingMu.Unlock()
+
+	sort.Strings(resources)
+	for _, resource := range resources {
+		message, err := json.Marshal(map[string]string{
+			"type":     "resource_update",
+			"resource": resource,
+		})
+		if err != nil {
+			continue
+		}
+		h.Broadcast(message)
+	}
+}
+
+// Consume forwards bus events to clients until the channel is closed.
+// Resource updates, which concern this host, go through the coalescing path.
+func (h *Hub) Consume(ch <-chan events.Event) {
+	for event := range ch {
+		if event.Type == events.ResourceUpdated {
+			h.NotifyResourceUpdate(event.Resource)
+			continue
+		}
+		h.BroadcastEvent(event.Namespace, string(event.Type), event.Data)
+	}
+}
+
+// BroadcastEvent sends a typed event to the clients that may see namespace
+func (h *Hub) BroadcastEvent(namespace, eventType string, data interface{}) {
 	event := map[string]interface{}{
 		"type":      eventType,
 		"data":      data,
@@ -145,7 +227,7 @@ func (h *Hub) BroadcastEvent(eventType string, data interface{}) {
 		return
 	}
 
-	h.Broadcast(message)
+	h.BroadcastNamespace(namespace, message)
 }
 
 // HandleWebSocket handles WebSocket connection upgrades
@@ -157,12 +239,18 @@ func (s *Server) HandleWebSocket(c *gin.Context) {
 	}
 
 	client := &Client{
-		hub:  s.hub,
-		conn: conn,
-		send: make(chan []byte, 256),
+		hub:       s.hub,
+		conn:      conn,
+		send:      make(chan []byte, 256),
+		namespace: master.CallerNamespace(c),
 	}
 
-	client.hub.register <- client
+	select {
+	case client.hub.register <- client:
+	case <-client.hub.done:
+		conn.Close()
+		return
+	}
 
 	// Start goroutines for reading and writing
 	go client.writePump()
@@ -186,7 +274,10 @@ const (
 // readPump pumps messages from the WebSocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.done:
+		}
 		c.conn.Close()
 	}()
 
@@ -339,8 +430,8 @@ func (s *Server) HandleContainerLogs(c *gin.Context) {
 func (s *Server) streamContainerLogs(client *LogStreamClient) {
 	defer client.conn.Close()
 
-	// Use background context - the done channel handles cancellation
-	ctx := context.Background()
+	// The done channel handles client disconnects; the server context shutdown
+	ctx := s.ctx
 
 	// Get log stream with follow=true
 	reader, err := s.docker.GetContainerLogs(ctx, client.containerID, "100", true)
@@ -386,6 +477,69 @@ func (s *Server) streamContainerLogs(client *LogStreamClient) {
 	}
 }
 
+// HandleMigrationLogs streams a migration job's log lines over WebSocket:
+// the buffered backlog first, then new lines as they are logged
+func (s *Server) HandleMigrationLogs(c *gin.Context) {
+	jobID := c.Param("id")
+
+	if s.migration == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "migration engine not initialized"})
+		return
+	}
+	if _, err := s.migration.GetStatus(jobID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		s.logger.Error("failed to upgrade websocket for migration logs", zap.Error(err))
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer conn.Close()
+
+		backlog, lines, unsubscribe := s.migration.SubscribeJobLogs(jobID)
+		defer unsubscribe()
+
+		send := func(entry migration.JobLogEntry) bool {
+			msg, _ := json.Marshal(map[string]interface{}{
+				"type":  "log",
+				"entry": entry,
+			})
+			return conn.WriteMessage(websocket.TextMessage, msg) == nil
+		}
+
+		for _, entry := range backlog {
+			if !send(entry) {
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case entry := <-lines:
+				if !send(entry) {
+					return
+				}
+			}
+		}
+	}()
+}
+
 // stripDockerLogHeader removes Docker's multiplexed stream headers
 func stripDockerLogHeader(data []byte) []byte {
 	var result []byte
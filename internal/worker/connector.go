@@ -2,7 +2,6 @@ package worker
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"io"
 	"os"
@@ -53,18 +52,11 @@ func (c *Connector) Connect(ctx context.Context, enrollmentToken string) error {
 	c.ctx, c.cancel = context.WithCancel(ctx)
 	c.enrollmentToken = enrollmentToken // Store for reconnection
 
-	// Get TLS config
-	tlsConfig, err := c.cryptoManager.GetClientTLSConfig()
-	if err != nil {
-		return fmt.Errorf("failed to get TLS config: %w", err)
-	}
-	tlsConfig.InsecureSkipVerify = true // Master cert may not be in trust store yet
-
 	// Connect with retry loop
-	return c.connectWithRetry(enrollmentToken, tlsConfig)
+	return c.connectWithRetry(enrollmentToken)
 }
 
-func (c *Connector) connectWithRetry(enrollmentToken string, tlsConfig *tls.Config) error {
+func (c *Connector) connectWithRetry(enrollmentToken string) error {
 	cfg := c.worker.GetConfig().Worker
 	backoff := cfg.ReconnectInterval
 	maxBackoff := cfg.MaxReconnectInterval
@@ -76,7 +68,7 @@ func (c *Connector) connectWithRetry(enrollmentToken string, tlsConfig *tls.Conf
 		default:
 		}
 
-		err := c.doConnect(enrollmentToken, tlsConfig)
+		err := c.doConnect(enrollmentToken)
 		if err == nil {
 			// Connected successfully, start maintenance loops
 			go c.heartbeatLoop()
@@ -104,17 +96,29 @@ func (c *Connector) connectWithRetry(enrollmentToken string, tlsConfig *tls.Conf
 	}
 }
 
-func (c *Connector) doConnect(enrollmentToken string, tlsConfig *tls.Config) error {
+func (c *Connector) doConnect(enrollmentToken string) error {
 	cfg := c.worker.GetConfig()
 	masterURL := cfg.Worker.MasterURL
 
 	c.logger.Info("connecting to master", zap.String("url", masterURL))
 
-	// Create gRPC connection
-	conn, err := grpc.Dial(
-		masterURL,
-		grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
-	)
+	// Verify the master against its pinned fingerprint
+	tlsConfig, err := masterTLSConfig(c.cryptoManager, c.worker.MasterFingerprint())
+	if err != nil {
+		return fmt.Errorf("failed to get TLS config: %w", err)
+	}
+
+	// Create gRPC connection, tunnelled when the worker sits behind a proxy
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	tunnel, err := c.worker.tunnelDialOption()
+	if err != nil {
+		return err
+	}
+	if tunnel != nil {
+		opts = append(opts, tunnel)
+	}
+
+	conn, err := grpc.Dial(masterURL, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to dial master: %w", err)
 	}
@@ -132,18 +136,34 @@ func (c *Connector) doConnect(enrollmentToken string, tlsConfig *tls.Config) err
 		return fmt.Errorf("failed to get fingerprint: certificate not initialized")
 	}
 
+	// Outbound-only workers advertise no address so nothing tries to dial them
+	grpcAddress := cfg.GRPCAddr
+	if cfg.Worker.OutboundOnly {
+		grpcAddress = ""
+	}
+
+	// Outbound-only workers are unreachable unless the master knows to relay
+	minProtocol := peer.MinProtocolVersion
+	if cfg.Worker.OutboundOnly {
+		minProtocol = peer.FeatureVersion(peer.FeatureOutboundOnly)
+	}
+
 	// Register with master
 	ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
 	defer cancel()
 
 	resp, err := c.client.RegisterWorker(ctx, &pb.WorkerRegistration{
-		EnrollmentToken: enrollmentToken,
-		WorkerName:      cfg.Worker.Name,
-		Hostname:        hostname,
-		GrpcAddress:     cfg.GRPCAddr,
-		TlsFingerprint:  fingerprint,
-		Labels:          cfg.Worker.Labels,
-		Version:         "1.0.0", // TODO: get from build
+		EnrollmentToken:    enrollmentToken,
+		WorkerName:         cfg.Worker.Name,
+		Hostname:           hostname,
+		GrpcAddress:        grpcAddress,
+		TlsFingerprint:     fingerprint,
+		Labels:             cfg.Worker.Labels,
+		Version:            "1.0.0", // TODO: get from build
+		OutboundOnly:       cfg.Worker.OutboundOnly,
+		ProtocolVersion:    peer.ProtocolVersion,
+		MinProtocolVersion: minProtocol,
+		TimestampMs:        time.Now().UnixMilli(),
 	})
 	if err != nil {
 		conn.Close()
@@ -155,6 +175,15 @@ func (c *Connector) doConnect(enrollmentToken string, tlsConfig *tls.Config) err
 		return fmt.Errorf("registration rejected: %s", resp.Error)
 	}
 
+	// Pre-versioning masters ignore min_protocol_version, so check here too
+	protocol := peer.EffectiveProtocolVersion(resp.ProtocolVersion)
+	if protocol < minProtocol {
+		conn.Close()
+		return fmt.Errorf("master speaks protocol v%d but this worker needs v%d (%s); upgrade the master",
+			protocol, minProtocol, peer.FeatureOutboundOnly)
+	}
+	c.worker.SetProtocol(protocol)
+
 	// Store credentials
 	c.worker.SetCredentials(resp.WorkerId, resp.AuthToken)
 
@@ -351,7 +380,26 @@ func (c *Connector) handleCommand(cmd *pb.MasterCommand) {
 	case *pb.MasterCommand_Shutdown:
 		c.logger.Info("shutdown command received", zap.String("reason", payload.Shutdown.Reason))
 		c.worker.Stop()
+
+	case *pb.MasterCommand_RotateAuthToken:
+		c.handleRotateAuthToken(payload.RotateAuthToken)
+
+	case *pb.MasterCommand_ProxyFallback:
+		c.worker.executor.ProxyFallback(payload.ProxyFallback)
+	}
+}
+
+// handleRotateAuthToken switches to the master's new auth token; the old one
+// stops working at previous_valid_until
+func (c *Connector) handleRotateAuthToken(cmd *pb.RotateAuthTokenCommand) {
+	if cmd.AuthToken == "" {
+		return
 	}
+	workerID, _ := c.worker.GetCredentials()
+	c.worker.SetCredentials(workerID, cmd.AuthToken)
+	c.logger.Info("auth token rotated by master",
+		zap.Time("previous_valid_until", time.Unix(cmd.PreviousValidUntil, 0)),
+	)
 }
 
 func (c *Connector) handleStartMigration(cmd *pb.StartMigrationCommand) {
@@ -396,20 +444,16 @@ func (c *Connector) handleDisconnect() {
 func (c *Connector) reconnect() {
 	c.logger.Info("attempting to reconnect to master")
 
-	tlsConfig, err := c.cryptoManager.GetClientTLSConfig()
-	if err != nil {
-		c.logger.Error("failed to get TLS config for reconnect", zap.Error(err))
-		return
-	}
-	tlsConfig.InsecureSkipVerify = true
-
 	// Use stored enrollment token for re-registration
-	c.connectWithRetry(c.enrollmentToken, tlsConfig)
+	c.connectWithRetry(c.enrollmentToken)
 }
 
 // SendProgress sends migration progress to master
 func (c *Connector) SendProgress(progress *pb.MigrationProgress) error {
 	workerID, authToken := c.worker.GetCredentials()
+	if progress.TimestampMs == 0 {
+		progress.TimestampMs = time.Now().UnixMilli()
+	}
 
 	msg := &pb.WorkerMessage{
 		WorkerId:  workerID,
@@ -433,6 +477,9 @@ func (c *Connector) SendProgress(progress *pb.MigrationProgress) error {
 // SendComplete sends migration completion to master
 func (c *Connector) SendComplete(complete *pb.MigrationComplete) error {
 	workerID, authToken := c.worker.GetCredentials()
+	if complete.TimestampMs == 0 {
+		complete.TimestampMs = time.Now().UnixMilli()
+	}
 
 	msg := &pb.WorkerMessage{
 		WorkerId:  workerID,
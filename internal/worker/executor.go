@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"sync"
@@ -11,14 +12,17 @@ import (
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/artemis/docker-migrate/internal/peer"
 	pb "github.com/artemis/docker-migrate/proto"
+	"github.com/docker/docker/api/types/network"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 )
 
-// CredentialsProvider provides worker credentials for authentication
+// CredentialsProvider provides worker credentials for authentication and the
+// master fingerprint to verify the master's proxy against
 type CredentialsProvider interface {
 	GetCredentials() (workerID, authToken string)
+	MasterFingerprint() string
 }
 
 // Executor handles migration execution
@@ -28,9 +32,20 @@ type Executor struct {
 	cryptoManager   *peer.CryptoManager
 	logger          *observability.Logger
 	credentials     CredentialsProvider
+	permissions     *Permissions
+
+	// Set when connections to the master must go through a tunnel
+	masterURL    string
+	tunnelOption grpc.DialOption
 
 	activeMigrations map[string]context.CancelFunc
 	mu               sync.RWMutex
+
+	// Source fingerprints trusted for running direct migrations
+	trustedSources map[string]*sourceTrust
+
+	// Auto-mode migrations waiting for the master to switch them to its proxy
+	fallbacks map[string]chan *pb.ProxyFallbackCommand
 }
 
 // NewExecutor creates a new migration executor
@@ -46,7 +61,83 @@ func NewExecutor(
 		cryptoManager:    cryptoManager,
 		logger:           logger,
 		activeMigrations: make(map[string]context.CancelFunc),
+		trustedSources:   make(map[string]*sourceTrust),
+		fallbacks:        make(map[string]chan *pb.ProxyFallbackCommand),
+	}
+}
+
+// sourceTrust counts the running migrations that need a source trusted
+type sourceTrust struct {
+	count int
+	added bool // The first of them added the fingerprint, so the last removes it
+}
+
+// trustSource lets a source worker's certificate through this worker's TLS
+// verification until the returned release is called. Sources of several
+// concurrent migrations stay trusted until the last one ends, and a
+// fingerprint that was trusted before is left trusted.
+func (e *Executor) trustSource(fingerprint string) (release func()) {
+	e.mu.Lock()
+	trust := e.trustedSources[fingerprint]
+	if trust == nil {
+		trust = &sourceTrust{added: e.cryptoManager.TrustFingerprint(fingerprint)}
+		e.trustedSources[fingerprint] = trust
+	}
+	trust.count++
+	e.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			trust.count--
+			if trust.count == 0 {
+				delete(e.trustedSources, fingerprint)
+				if trust.added {
+					e.cryptoManager.RemoveTrustedCert(fingerprint)
+				}
+			}
+		})
+	}
+}
+
+// transferDialOptions builds dial options for data-plane connections, honouring the
+// configured transfer bind address
+func (e *Executor) transferDialOptions(tlsConfig *tls.Config) []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	return append(opts, e.transferManager.TransferDialOptions()...)
+}
+
+// proxyDialOptions builds dial options for proxy channels, which terminate on the
+// master and therefore share the worker's tunnel to it. A tunnelled channel
+// uses the tunnel's dialer alone; the transfer bind address does not apply.
+func (e *Executor) proxyDialOptions(tlsConfig *tls.Config) []grpc.DialOption {
+	if e.tunnelOption == nil {
+		return e.transferDialOptions(tlsConfig)
+	}
+	return []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), e.tunnelOption}
+}
+
+// proxyAddress returns where to dial the master's proxy. Tunnelled workers use
+// their configured master URL, since the advertised listen address is
+// meaningless on the far side of a proxy.
+func (e *Executor) proxyAddress(advertised string) string {
+	if e.tunnelOption != nil && e.masterURL != "" {
+		return e.masterURL
 	}
+	return advertised
+}
+
+// SetTunnel routes proxy channel connections through the tunnel to masterURL
+func (e *Executor) SetTunnel(masterURL string, opt grpc.DialOption) {
+	e.masterURL = masterURL
+	e.tunnelOption = opt
+}
+
+// SetPermissions restricts the operations the master may run on this worker
+func (e *Executor) SetPermissions(p *Permissions) {
+	e.permissions = p
 }
 
 // SetCredentialsProvider sets the credentials provider for authentication
@@ -58,6 +149,15 @@ func (e *Executor) SetCredentialsProvider(provider CredentialsProvider) {
 func (e *Executor) ExecuteAsSource(ctx context.Context, req *pb.MigrationRequest, stream pb.MasterService_WorkerStreamClient) {
 	migrationID := req.MigrationId
 
+	if err := e.permissions.CheckSource(req); err != nil {
+		e.logger.Warn("refusing migration as source",
+			zap.String("migration_id", migrationID),
+			zap.Error(err),
+		)
+		e.sendComplete(stream, migrationID, false, err.Error(), 0, nil)
+		return
+	}
+
 	// Create cancellable context
 	ctx, cancel := context.WithCancel(ctx)
 	e.mu.Lock()
@@ -77,6 +177,9 @@ func (e *Executor) ExecuteAsSource(ctx context.Context, req *pb.MigrationRequest
 
 	startTime := time.Now()
 	var totalBytes int64
+	// migrated lists the resources the target has acknowledged, so a retry
+	// can skip them
+	var migrated []string
 
 	// Create transfer client based on mode
 	var client TransferClient
@@ -89,30 +192,63 @@ func (e *Executor) ExecuteAsSource(ctx context.Context, req *pb.MigrationRequest
 		client, err = e.createDirectClient(ctx, req)
 	}
 	if err != nil {
-		e.sendComplete(stream, migrationID, false, err.Error(), 0)
+		e.sendComplete(stream, migrationID, false, err.Error(), 0, nil)
 		return
 	}
-	defer client.Close()
+	defer func() { client.Close() }()
+
+	// In auto mode the direct connection may move to the master's proxy
+	// once, when the target cannot be reached
+	var fallback <-chan *pb.ProxyFallbackCommand
+	if req.TransferMode == pb.TransferMode_TRANSFER_MODE_AUTO {
+		var done func()
+		fallback, done = e.expectFallback(migrationID)
+		defer done()
+	}
+	send := func(op func(TransferClient) (int64, error)) (int64, error) {
+		bytes, err := op(client)
+		if err == nil || fallback == nil || !connectionFailed(err) {
+			return bytes, err
+		}
+		proxied, err := e.fallBackToProxy(ctx, req, stream, fallback, err)
+		fallback = nil
+		if err != nil {
+			return 0, err
+		}
+		client.Close()
+		client = proxied
+		return op(client)
+	}
+
+	// Phase changes and the end of each phase are always reported; progress
+	// within a phase is spaced out at the configured granularity
+	throttle := e.transferManager.NewProgressThrottle()
 
 	// Transfer volumes
 	e.sendProgress(stream, migrationID, pb.MigrationPhase_MIGRATION_PHASE_TRANSFERRING_VOLUMES, 0, 0, 0)
 	for i, volName := range req.VolumeNames {
 		select {
 		case <-ctx.Done():
-			e.sendComplete(stream, migrationID, false, "cancelled", totalBytes)
+			e.sendComplete(stream, migrationID, false, "cancelled", totalBytes, migrated)
 			return
 		default:
 		}
 
-		bytes, err := e.transferVolume(ctx, client, volName)
+		bytes, err := send(func(client TransferClient) (int64, error) {
+			return e.transferVolume(ctx, client, volName)
+		})
 		if err != nil {
-			e.sendComplete(stream, migrationID, false, fmt.Sprintf("volume transfer failed: %v", err), totalBytes)
+			e.sendComplete(stream, migrationID, false, fmt.Sprintf("volume transfer failed: %v", err), totalBytes, migrated)
 			return
 		}
 		totalBytes += bytes
+		migrated = append(migrated, "volume:"+volName)
 
-		progress := float32(i+1) / float32(len(req.VolumeNames))
-		e.sendProgress(stream, migrationID, pb.MigrationPhase_MIGRATION_PHASE_TRANSFERRING_VOLUMES, progress, totalBytes, 0)
+		// The last report of a phase always goes out, so it ends at 100%
+		if throttle.Allow(totalBytes) || i == len(req.VolumeNames)-1 {
+			progress := float32(i+1) / float32(len(req.VolumeNames))
+			e.sendProgress(stream, migrationID, pb.MigrationPhase_MIGRATION_PHASE_TRANSFERRING_VOLUMES, progress, totalBytes, 0)
+		}
 	}
 
 	// Transfer images
@@ -120,20 +256,26 @@ func (e *Executor) ExecuteAsSource(ctx context.Context, req *pb.MigrationRequest
 	for i, imageID := range req.ImageIds {
 		select {
 		case <-ctx.Done():
-			e.sendComplete(stream, migrationID, false, "cancelled", totalBytes)
+			e.sendComplete(stream, migrationID, false, "cancelled", totalBytes, migrated)
 			return
 		default:
 		}
 
-		bytes, err := e.transferImage(ctx, client, imageID)
+		bytes, err := send(func(client TransferClient) (int64, error) {
+			return e.sendImage(ctx, client, imageID, req.ImageRegistry)
+		})
 		if err != nil {
-			e.sendComplete(stream, migrationID, false, fmt.Sprintf("image transfer failed: %v", err), totalBytes)
+			e.sendComplete(stream, migrationID, false, fmt.Sprintf("image transfer failed: %v", err), totalBytes, migrated)
 			return
 		}
 		totalBytes += bytes
+		migrated = append(migrated, "image:"+imageID)
 
-		progress := float32(i+1) / float32(len(req.ImageIds))
-		e.sendProgress(stream, migrationID, pb.MigrationPhase_MIGRATION_PHASE_TRANSFERRING_IMAGES, progress, totalBytes, 0)
+		// The last report of a phase always goes out, so it ends at 100%
+		if throttle.Allow(totalBytes) || i == len(req.ImageIds)-1 {
+			progress := float32(i+1) / float32(len(req.ImageIds))
+			e.sendProgress(stream, migrationID, pb.MigrationPhase_MIGRATION_PHASE_TRANSFERRING_IMAGES, progress, totalBytes, 0)
+		}
 	}
 
 	// Mark complete
@@ -146,11 +288,40 @@ func (e *Executor) ExecuteAsSource(ctx context.Context, req *pb.MigrationRequest
 		zap.Int64("duration_ms", duration),
 	)
 
-	e.sendComplete(stream, migrationID, true, "", totalBytes)
+	e.sendComplete(stream, migrationID, true, "", totalBytes, migrated)
 }
 
 // ExecuteAsTarget executes migration as the target (receiver)
 func (e *Executor) ExecuteAsTarget(ctx context.Context, req *pb.AcceptMigrationRequest, stream pb.MasterService_WorkerStreamClient) {
+	// Checked before the source is trusted, so it can never connect
+	if err := e.permissions.CheckTarget(req); err != nil {
+		e.logger.Warn("refusing migration as target",
+			zap.String("migration_id", req.MigrationId),
+			zap.String("source_worker_id", req.SourceWorkerId),
+			zap.Error(err),
+		)
+		e.sendComplete(stream, req.MigrationId, false, err.Error(), 0, nil)
+		return
+	}
+
+	if err := e.createNetworks(ctx, req); err != nil {
+		e.logger.Error("failed to create networks",
+			zap.String("migration_id", req.MigrationId),
+			zap.Error(err),
+		)
+		e.sendComplete(stream, req.MigrationId, false, err.Error(), 0, nil)
+		return
+	}
+
+	if err := e.createVolumes(ctx, req); err != nil {
+		e.logger.Error("failed to create volumes",
+			zap.String("migration_id", req.MigrationId),
+			zap.Error(err),
+		)
+		e.sendComplete(stream, req.MigrationId, false, err.Error(), 0, nil)
+		return
+	}
+
 	if req.TransferMode == pb.TransferMode_TRANSFER_MODE_PROXY {
 		e.executeTargetViaProxy(ctx, req, stream)
 		return
@@ -171,6 +342,27 @@ func (e *Executor) ExecuteAsTarget(ctx context.Context, req *pb.AcceptMigrationR
 		e.mu.Unlock()
 	}()
 
+	// Only the source the master named may connect, and only while the
+	// migration runs
+	if req.SourceFingerprint == "" {
+		e.logger.Error("refusing direct migration: master sent no source fingerprint",
+			zap.String("migration_id", migrationID),
+			zap.String("source_worker_id", req.SourceWorkerId),
+		)
+		return
+	}
+	release := e.trustSource(req.SourceFingerprint)
+	defer release()
+
+	// In auto mode the source may give up on reaching us and have the
+	// master relay the rest
+	var fallback <-chan *pb.ProxyFallbackCommand
+	if req.TransferMode == pb.TransferMode_TRANSFER_MODE_AUTO {
+		var done func()
+		fallback, done = e.expectFallback(migrationID)
+		defer done()
+	}
+
 	e.logger.Info("ready to accept migration as target",
 		zap.String("migration_id", migrationID),
 		zap.String("source", req.SourceAddress),
@@ -180,7 +372,88 @@ func (e *Executor) ExecuteAsTarget(ctx context.Context, req *pb.AcceptMigrationR
 	// The source will connect and stream data to us
 	// We just wait for completion or cancellation
 
-	<-ctx.Done()
+	select {
+	case <-ctx.Done():
+	case cmd := <-fallback:
+		e.logger.Info("source could not reach us, receiving through the master",
+			zap.String("migration_id", migrationID),
+		)
+		e.receiveViaProxy(ctx, migrationID, cmd.ProxyAddress, cmd.ProxyNonce)
+	}
+}
+
+// createNetworks creates the networks the master planned for a migration,
+// with the subnets it chose. Networks that already exist by name, such as
+// those from an earlier attempt, are left as they are.
+func (e *Executor) createNetworks(ctx context.Context, req *pb.AcceptMigrationRequest) error {
+	if len(req.Networks) == 0 {
+		return nil
+	}
+
+	existing, err := e.docker.ListNetworks(ctx)
+	if err != nil {
+		return err
+	}
+	names := make(map[string]bool, len(existing))
+	for _, n := range existing {
+		names[n.Name] = true
+	}
+
+	for _, spec := range req.Networks {
+		if names[spec.Name] {
+			e.logger.Info("network already exists, keeping it",
+				zap.String("migration_id", req.MigrationId),
+				zap.String("network", spec.Name),
+			)
+			continue
+		}
+
+		info := &docker.NetworkInfo{
+			Name:     spec.Name,
+			Driver:   spec.Driver,
+			Internal: spec.Internal,
+			Labels: docker.Provenance{
+				Source:       req.SourceWorkerId,
+				JobID:        req.MigrationId,
+				OriginalName: spec.Name,
+				MigratedAt:   time.Now(),
+			}.WithLabels(nil),
+		}
+		if spec.Subnet != "" {
+			info.IPAM = network.IPAM{
+				Driver: "default",
+				Config: []network.IPAMConfig{{Subnet: spec.Subnet, Gateway: spec.Gateway}},
+			}
+		}
+		if _, err := e.docker.CreateNetwork(ctx, info, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createVolumes creates the migration's volumes empty before any data
+// arrives, labelled with where they came from, so a rollback can tell them
+// apart from volumes that were already here. Existing volumes are kept.
+func (e *Executor) createVolumes(ctx context.Context, req *pb.AcceptMigrationRequest) error {
+	for _, name := range req.VolumeNames {
+		if _, err := e.docker.InspectVolume(ctx, name); err == nil {
+			continue
+		} else if !docker.IsNotFound(err) {
+			return err
+		}
+
+		labels := docker.Provenance{
+			Source:       req.SourceWorkerId,
+			JobID:        req.MigrationId,
+			OriginalName: name,
+			MigratedAt:   time.Now(),
+		}.WithLabels(nil)
+		if _, err := e.docker.CreateVolume(ctx, name, labels, nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (e *Executor) executeTargetViaProxy(ctx context.Context, req *pb.AcceptMigrationRequest, masterStream pb.MasterService_WorkerStreamClient) {
@@ -198,20 +471,25 @@ func (e *Executor) executeTargetViaProxy(ctx context.Context, req *pb.AcceptMigr
 		e.mu.Unlock()
 	}()
 
+	e.receiveViaProxy(ctx, migrationID, req.ProxyAddress, req.ProxyNonce)
+}
+
+// receiveViaProxy opens the target's end of a migration's proxy channel and
+// receives from it until the source closes it
+func (e *Executor) receiveViaProxy(ctx context.Context, migrationID, proxyAddress, proxyNonce string) {
 	e.logger.Info("connecting to proxy as target",
 		zap.String("migration_id", migrationID),
-		zap.String("proxy_address", req.ProxyAddress),
+		zap.String("proxy_address", proxyAddress),
 	)
 
 	// Connect to master's proxy
-	tlsConfig, err := e.cryptoManager.GetClientTLSConfig()
+	tlsConfig, err := masterTLSConfig(e.cryptoManager, e.credentials.MasterFingerprint())
 	if err != nil {
 		e.logger.Error("failed to get TLS config", zap.Error(err))
 		return
 	}
-	tlsConfig.InsecureSkipVerify = true
 
-	conn, err := grpc.Dial(req.ProxyAddress, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	conn, err := grpc.Dial(e.proxyAddress(proxyAddress), e.proxyDialOptions(tlsConfig)...)
 	if err != nil {
 		e.logger.Error("failed to connect to proxy", zap.Error(err))
 		return
@@ -226,12 +504,16 @@ func (e *Executor) executeTargetViaProxy(ctx context.Context, req *pb.AcceptMigr
 	}
 
 	// Send handshake as TARGET
+	workerID, authToken := e.credentials.GetCredentials()
 	if err := stream.Send(&pb.ProxyData{
 		MigrationId: migrationID,
+		WorkerId:    workerID,
 		Type:        pb.ProxyDataType_PROXY_DATA_HANDSHAKE,
 		Payload: &pb.ProxyData_Handshake{
 			Handshake: &pb.ProxyHandshake{
-				Role: pb.ProxyRole_PROXY_ROLE_TARGET,
+				Role:      pb.ProxyRole_PROXY_ROLE_TARGET,
+				AuthToken: authToken,
+				Nonce:     proxyNonce,
 			},
 		},
 	}); err != nil {
@@ -350,37 +632,161 @@ func (e *Executor) transferVolume(ctx context.Context, client TransferClient, vo
 	return 0, err
 }
 
+// sendImage moves an image to the target, through the intermediary registry
+// if one is set. Images the registry route fails for are streamed.
+func (e *Executor) sendImage(ctx context.Context, client TransferClient, imageID string, registry *pb.ImageRegistry) (int64, error) {
+	if registry == nil {
+		return e.transferImage(ctx, client, imageID)
+	}
+
+	err := e.sendImageViaRegistry(ctx, client, imageID, registry)
+	if err == nil || ctx.Err() != nil {
+		return 0, err
+	}
+	e.logger.Warn("sending image through registry failed, streaming it instead",
+		zap.String("image", imageID),
+		zap.String("registry", registry.Address),
+		zap.Error(err),
+	)
+	return e.transferImage(ctx, client, imageID)
+}
+
+// sendImageViaRegistry pushes the image to the intermediary registry and has
+// the target pull it under its original name
+func (e *Executor) sendImageViaRegistry(ctx context.Context, client TransferClient, imageID string, registry *pb.ImageRegistry) error {
+	puller, ok := client.(ImagePuller)
+	if !ok {
+		return fmt.Errorf("the target cannot be asked to pull over a proxy channel")
+	}
+
+	info, err := e.docker.GetImageInfo(ctx, imageID)
+	if err != nil {
+		return err
+	}
+	var tag string
+	for _, t := range info.RepoTags {
+		if t != "<none>:<none>" {
+			tag = t
+			break
+		}
+	}
+
+	ref := docker.IntermediaryReference(registry.Address, tag, info.ID)
+	if err := e.docker.TagImage(ctx, info.ID, ref); err != nil {
+		return err
+	}
+	// Untagging the only tag would delete the image, so untagged images
+	// keep the intermediary one
+	if tag != "" {
+		defer func() {
+			if err := e.docker.UntagImage(context.Background(), ref); err != nil {
+				e.logger.Warn("failed to remove intermediary image tag",
+					zap.String("ref", ref),
+					zap.Error(err),
+				)
+			}
+		}()
+	}
+
+	if err := e.docker.PushImage(ctx, ref, registry.RegistryAuth); err != nil {
+		return err
+	}
+	if err := puller.PullImage(ctx, info.ID, ref, tag, registry.RegistryAuth); err != nil {
+		return err
+	}
+
+	e.logger.Info("image sent through registry",
+		zap.String("image", imageID),
+		zap.String("ref", ref),
+	)
+	return nil
+}
+
 func (e *Executor) transferImage(ctx context.Context, client TransferClient, imageID string) (int64, error) {
 	e.logger.Debug("transferring image", zap.String("image", imageID))
 
+	present := e.targetLayers(ctx, client, imageID)
+	sent, err := e.streamImage(ctx, client, imageID, present)
+	if err != nil && len(present) > 0 && ctx.Err() == nil {
+		// The target's Docker may need every layer in the tar, as the
+		// containerd image store does; send them all
+		e.logger.Warn("image load without shared layers failed, sending the full image",
+			zap.String("image", imageID),
+			zap.Error(err),
+		)
+		return e.streamImage(ctx, client, imageID, nil)
+	}
+	return sent, err
+}
+
+// targetLayers returns the image's layers the target already has, or nil if
+// it cannot tell
+func (e *Executor) targetLayers(ctx context.Context, client TransferClient, imageID string) []string {
+	querier, ok := client.(LayerQuerier)
+	if !ok {
+		return nil
+	}
+
+	layers, err := e.docker.GetImageLayers(ctx, imageID)
+	if err != nil || len(layers) == 0 {
+		return nil
+	}
+
+	present, err := querier.QueryLayers(ctx, imageID, layers)
+	if err != nil {
+		// Targets on older versions do not answer; send every layer
+		e.logger.Info("target did not report its image layers, sending all",
+			zap.String("image", imageID),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return present
+}
+
+// streamImage sends the image tar to the target, leaving out the layers in
+// present
+func (e *Executor) streamImage(ctx context.Context, client TransferClient, imageID string, present []string) (int64, error) {
+	reader, err := e.docker.ExportImage(ctx, imageID)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
 	stream, err := client.TransferImageLayers(ctx)
 	if err != nil {
 		return 0, err
 	}
 
-	// Send image layers
-	blob := &pb.LayerBlob{
-		ImageId:     imageID,
-		LayerDigest: "",
-		Offset:      0,
-		Data:        []byte{},
-		Checksum:    "",
-		LayerSize:   0,
-		IsFinal:     true,
+	var source io.Reader = reader
+	var skipper *peer.LayerSkipper
+	if len(present) > 0 {
+		skipper = peer.SkipLayers(reader, present)
+		defer skipper.Close()
+		source = skipper
 	}
 
-	if err := stream.Send(blob); err != nil {
-		return 0, err
+	// Stream the save output straight into the chunker so large images
+	// never sit in memory
+	sent, err := peer.StreamImage(ctx, stream, imageID, source, peer.DefaultChunkSize, nil)
+	if err != nil {
+		return sent, err
 	}
 
-	// Close send side and wait for final acknowledgment
 	if err := stream.CloseSend(); err != nil {
-		return 0, err
+		return sent, err
 	}
 
-	// Receive final ack
-	_, err = stream.Recv()
-	return 0, err
+	if skipper != nil {
+		layers, bytes := skipper.Skipped()
+		e.logger.Info("left out layers the target already has",
+			zap.String("image", imageID),
+			zap.Int("layers", layers),
+			zap.Int64("bytes", bytes),
+		)
+	}
+
+	return sent, nil
 }
 
 func (e *Executor) sendProgress(stream pb.MasterService_WorkerStreamClient, migrationID string, phase pb.MigrationPhase, progress float32, bytesTransferred, totalBytes int64) {
@@ -399,13 +805,14 @@ func (e *Executor) sendProgress(stream pb.MasterService_WorkerStreamClient, migr
 				Progress:         progress,
 				BytesTransferred: bytesTransferred,
 				TotalBytes:       totalBytes,
+				TimestampMs:      time.Now().UnixMilli(),
 			},
 		},
 	}
 	stream.Send(msg)
 }
 
-func (e *Executor) sendComplete(stream pb.MasterService_WorkerStreamClient, migrationID string, success bool, errMsg string, bytesTransferred int64) {
+func (e *Executor) sendComplete(stream pb.MasterService_WorkerStreamClient, migrationID string, success bool, errMsg string, bytesTransferred int64, migrated []string) {
 	var workerID, authToken string
 	if e.credentials != nil {
 		workerID, authToken = e.credentials.GetCredentials()
@@ -416,10 +823,12 @@ func (e *Executor) sendComplete(stream pb.MasterService_WorkerStreamClient, migr
 		AuthToken: authToken,
 		Payload: &pb.WorkerMessage_MigrationComplete{
 			MigrationComplete: &pb.MigrationComplete{
-				MigrationId:      migrationID,
-				Success:          success,
-				Error:            errMsg,
-				BytesTransferred: bytesTransferred,
+				MigrationId:       migrationID,
+				Success:           success,
+				Error:             errMsg,
+				BytesTransferred:  bytesTransferred,
+				ResourcesMigrated: migrated,
+				TimestampMs:       time.Now().UnixMilli(),
 			},
 		},
 	}
@@ -434,13 +843,17 @@ func (e *Executor) GetActiveMigrationCount() int {
 }
 
 func (e *Executor) createDirectClient(ctx context.Context, req *pb.MigrationRequest) (TransferClient, error) {
-	tlsConfig, err := e.cryptoManager.GetClientTLSConfig()
+	// The target must present the certificate it registered with the master;
+	// it checks ours the same way (see trustSource)
+	if req.TargetFingerprint == "" {
+		return nil, fmt.Errorf("master sent no fingerprint for target worker %s", req.TargetWorkerId)
+	}
+	tlsConfig, err := e.cryptoManager.TLSClientConfig(req.TargetFingerprint)
 	if err != nil {
 		return nil, err
 	}
-	tlsConfig.InsecureSkipVerify = true
 
-	conn, err := grpc.Dial(req.TargetAddress, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	conn, err := grpc.Dial(req.TargetAddress, e.transferDialOptions(tlsConfig)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to target: %w", err)
 	}
@@ -449,13 +862,13 @@ func (e *Executor) createDirectClient(ctx context.Context, req *pb.MigrationRequ
 }
 
 func (e *Executor) createProxyClient(ctx context.Context, req *pb.MigrationRequest) (TransferClient, error) {
-	tlsConfig, err := e.cryptoManager.GetClientTLSConfig()
+	// The proxy runs on the master
+	tlsConfig, err := masterTLSConfig(e.cryptoManager, e.credentials.MasterFingerprint())
 	if err != nil {
 		return nil, err
 	}
-	tlsConfig.InsecureSkipVerify = true
 
-	conn, err := grpc.Dial(req.ProxyAddress, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	conn, err := grpc.Dial(e.proxyAddress(req.ProxyAddress), e.proxyDialOptions(tlsConfig)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
 	}
@@ -468,13 +881,17 @@ func (e *Executor) createProxyClient(ctx context.Context, req *pb.MigrationReque
 	}
 
 	// Send handshake as SOURCE
+	workerID, authToken := e.credentials.GetCredentials()
 	if err := stream.Send(&pb.ProxyData{
 		MigrationId: req.MigrationId,
+		WorkerId:    workerID,
 		Type:        pb.ProxyDataType_PROXY_DATA_HANDSHAKE,
 		Payload: &pb.ProxyData_Handshake{
 			Handshake: &pb.ProxyHandshake{
 				Role:           pb.ProxyRole_PROXY_ROLE_SOURCE,
 				TargetWorkerId: req.TargetWorkerId,
+				AuthToken:      authToken,
+				Nonce:          req.ProxyNonce,
 			},
 		},
 	}); err != nil {
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/artemis/docker-migrate/proto"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// transferFallbackTimeout bounds how long a source waits for the master to
+// move a failed direct transfer onto its proxy
+const transferFallbackTimeout = 30 * time.Second
+
+// expectFallback registers an auto-mode migration for the master's proxy
+// fallback command until the returned done is called
+func (e *Executor) expectFallback(migrationID string) (fallback <-chan *pb.ProxyFallbackCommand, done func()) {
+	ch := make(chan *pb.ProxyFallbackCommand, 1)
+	e.mu.Lock()
+	e.fallbacks[migrationID] = ch
+	e.mu.Unlock()
+
+	return ch, func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.fallbacks[migrationID] == ch {
+			delete(e.fallbacks, migrationID)
+		}
+	}
+}
+
+// ProxyFallback hands the master's fallback command to the migration
+// waiting for it
+func (e *Executor) ProxyFallback(cmd *pb.ProxyFallbackCommand) {
+	e.mu.RLock()
+	ch, ok := e.fallbacks[cmd.MigrationId]
+	e.mu.RUnlock()
+
+	if !ok {
+		e.logger.Warn("proxy fallback for a migration not running in auto mode",
+			zap.String("migration_id", cmd.MigrationId),
+		)
+		return
+	}
+	select {
+	case ch <- cmd:
+	default:
+	}
+}
+
+// fallBackToProxy asks the master to relay a migration whose direct
+// connection failed with cause, and opens the proxy client once it agrees
+func (e *Executor) fallBackToProxy(ctx context.Context, req *pb.MigrationRequest, stream pb.MasterService_WorkerStreamClient, fallback <-chan *pb.ProxyFallbackCommand, cause error) (TransferClient, error) {
+	e.logger.Warn("direct transfer failed, asking the master to relay",
+		zap.String("migration_id", req.MigrationId),
+		zap.String("target", req.TargetAddress),
+		zap.Error(cause),
+	)
+
+	var workerID, authToken string
+	if e.credentials != nil {
+		workerID, authToken = e.credentials.GetCredentials()
+	}
+	if err := stream.Send(&pb.WorkerMessage{
+		WorkerId:  workerID,
+		AuthToken: authToken,
+		Payload: &pb.WorkerMessage_TransferFallback{
+			TransferFallback: &pb.TransferFallback{
+				MigrationId: req.MigrationId,
+				Reason:      cause.Error(),
+			},
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("%w; could not ask the master to relay: %v", cause, err)
+	}
+
+	timer := time.NewTimer(transferFallbackTimeout)
+	defer timer.Stop()
+
+	var cmd *pb.ProxyFallbackCommand
+	select {
+	case cmd = <-fallback:
+	case <-timer.C:
+		return nil, fmt.Errorf("%w; master did not answer the relay request", cause)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if cmd.Error != "" {
+		return nil, fmt.Errorf("%w; master will not relay: %s", cause, cmd.Error)
+	}
+
+	proxyReq := proto.Clone(req).(*pb.MigrationRequest)
+	proxyReq.TransferMode = pb.TransferMode_TRANSFER_MODE_PROXY
+	proxyReq.ProxyAddress = cmd.ProxyAddress
+	proxyReq.ProxyNonce = cmd.ProxyNonce
+	return e.createProxyClient(ctx, proxyReq)
+}
+
+// connectionFailed reports whether err means the target could not be
+// reached, rather than that it refused what was sent
+func connectionFailed(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync/atomic"
 
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/artemis/docker-migrate/internal/peer"
@@ -13,21 +14,25 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
-// GRPCServer implements WorkerService
+// GRPCServer implements WorkerService, and serves the peer services that
+// sources send to beside it
 type GRPCServer struct {
 	pb.UnimplementedWorkerServiceServer
 
 	worker        *Worker
 	cryptoManager *peer.CryptoManager
+	peerServices  *peer.GRPCServer
 	logger        *observability.Logger
 	server        *grpc.Server
+	serving       atomic.Bool
 }
 
 // NewGRPCServer creates a new gRPC server
-func NewGRPCServer(worker *Worker, cryptoManager *peer.CryptoManager, logger *observability.Logger) (*GRPCServer, error) {
+func NewGRPCServer(worker *Worker, cryptoManager *peer.CryptoManager, peerServices *peer.GRPCServer, logger *observability.Logger) (*GRPCServer, error) {
 	return &GRPCServer{
 		worker:        worker,
 		cryptoManager: cryptoManager,
+		peerServices:  peerServices,
 		logger:        logger,
 	}, nil
 }
@@ -44,21 +49,33 @@ func (s *GRPCServer) Start(addr string) error {
 		return fmt.Errorf("failed to get TLS config: %w", err)
 	}
 
+	// Only trusted certificates get through: the master's, and a source's
+	// while the master has it send here
 	opts := []grpc.ServerOption{
 		grpc.Creds(credentials.NewTLS(tlsConfig)),
 	}
+	opts = append(opts, s.peerServices.ServerOptions()...)
 
 	s.server = grpc.NewServer(opts...)
 	pb.RegisterWorkerServiceServer(s.server, s)
+	s.peerServices.RegisterServices(s.server)
 
 	s.logger.Info("worker gRPC server starting", zap.String("addr", addr))
 
+	s.serving.Store(true)
+	defer s.serving.Store(false)
 	return s.server.Serve(lis)
 }
 
+// Serving reports whether the server is listening on its address
+func (s *GRPCServer) Serving() bool {
+	return s.serving.Load()
+}
+
 // Stop stops the server
 func (s *GRPCServer) Stop() {
 	if s.server != nil {
+		s.serving.Store(false)
 		s.server.GracefulStop()
 	}
 }
@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"github.com/artemis/docker-migrate/internal/observability"
+)
+
+// RegisterHealthChecks adds the worker's readiness checks to hc: Docker is
+// reachable, the gRPC server is listening unless the worker is outbound-only,
+// and the worker is connected to its master
+func (w *Worker) RegisterHealthChecks(hc *observability.HealthChecker) {
+	hc.RegisterReadinessCheck("docker", observability.DockerHealthCheck(w.docker.Ping))
+	if !w.config.Worker.OutboundOnly {
+		hc.RegisterReadinessCheck("grpc", observability.ServingHealthCheck("gRPC server", w.grpcServer.Serving))
+	}
+	hc.RegisterReadinessCheck("master", observability.MasterHealthCheck(w.Connected))
+}
+
+// Connected reports whether the worker is connected to its master
+func (w *Worker) Connected() bool {
+	w.mu.RLock()
+	connector := w.connector
+	w.mu.RUnlock()
+	return connector != nil && connector.IsConnected()
+}
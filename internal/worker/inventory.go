@@ -2,17 +2,29 @@ package worker
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/artemis/docker-migrate/internal/docker"
 	"github.com/artemis/docker-migrate/internal/observability"
+	"github.com/artemis/docker-migrate/internal/peer"
 	pb "github.com/artemis/docker-migrate/proto"
 	"go.uber.org/zap"
 )
 
+// DiskUsageInterval is how long a disk usage scan is reused. Walking every
+// layer and volume is expensive, so it is rescanned sooner only when
+// containers or volumes come or go.
+const DiskUsageInterval = 15 * time.Minute
+
 // Inventory scans Docker resources
 type Inventory struct {
 	docker *docker.Client
 	logger *observability.Logger
+
+	mu          sync.Mutex
+	diskUsage   *docker.DiskUsageSummary
+	diskUsageAt time.Time
 }
 
 // NewInventory creates a new inventory scanner
@@ -90,6 +102,12 @@ func (i *Inventory) Scan(ctx context.Context) (*pb.ResourceInventory, error) {
 		i.logger.Error("failed to list networks", zap.Error(err))
 	} else {
 		for _, net := range networks {
+			var subnets []string
+			for _, cfg := range net.IPAM.Config {
+				if cfg.Subnet != "" {
+					subnets = append(subnets, cfg.Subnet)
+				}
+			}
 			inv.Networks = append(inv.Networks, &pb.NetworkResource{
 				Id:             net.ID,
 				Name:           net.Name,
@@ -97,10 +115,25 @@ func (i *Inventory) Scan(ctx context.Context) (*pb.ResourceInventory, error) {
 				Scope:          net.Scope,
 				Internal:       net.Internal,
 				ContainerCount: int32(len(net.Containers)),
+				Subnets:        subnets,
 			})
 		}
 	}
 
+	// Disk usage lets the master check a migration fits before it starts,
+	// and its per-object sizes feed migration estimates
+	if summary, err := i.systemDF(ctx, inv); err != nil {
+		i.logger.Warn("failed to get disk usage", zap.Error(err))
+	} else {
+		inv.DiskUsage = peer.DiskUsageToProto(summary)
+		for _, c := range inv.Containers {
+			c.SizeRw = summary.ContainerSizes[c.Id]
+		}
+		for _, v := range inv.Volumes {
+			v.Size = summary.VolumeSizes[v.Name]
+		}
+	}
+
 	i.logger.Debug("inventory scan complete",
 		zap.Int("containers", len(inv.Containers)),
 		zap.Int("images", len(inv.Images)),
@@ -110,3 +143,36 @@ func (i *Inventory) Scan(ctx context.Context) (*pb.ResourceInventory, error) {
 
 	return inv, nil
 }
+
+// systemDF returns the last disk usage scan while it is recent and still
+// covers every container and volume in inv, and rescans otherwise
+func (i *Inventory) systemDF(ctx context.Context, inv *pb.ResourceInventory) (*docker.DiskUsageSummary, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.diskUsage != nil && time.Since(i.diskUsageAt) < DiskUsageInterval && diskUsageCovers(i.diskUsage, inv) {
+		return i.diskUsage, nil
+	}
+
+	summary, err := i.docker.SystemDF(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i.diskUsage = summary
+	i.diskUsageAt = time.Now()
+	return summary, nil
+}
+
+// diskUsageCovers reports whether summary was taken with the same
+// containers and as many volumes as inv holds
+func diskUsageCovers(summary *docker.DiskUsageSummary, inv *pb.ResourceInventory) bool {
+	if summary.Containers.Count != len(inv.Containers) || summary.Volumes.Count != len(inv.Volumes) {
+		return false
+	}
+	for _, c := range inv.Containers {
+		if _, ok := summary.ContainerSizes[c.Id]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/artemis/docker-migrate/internal/peer"
+	pb "github.com/artemis/docker-migrate/proto"
+)
+
+// Operation is something the master, or a peer it sends, may have this
+// worker do
+type Operation = peer.Operation
+
+const (
+	OpSendContainers    Operation = "send_containers"
+	OpSendImages        Operation = "send_images"
+	OpSendVolumes       Operation = "send_volumes"
+	OpSendNetworks      Operation = "send_networks"
+	OpReceiveContainers           = peer.OpReceiveContainers
+	OpReceiveImages               = peer.OpReceiveImages
+	OpReceiveVolumes              = peer.OpReceiveVolumes
+	OpReceiveNetworks             = peer.OpReceiveNetworks
+	OpRemoveResources             = peer.OpRemoveResources
+)
+
+// operationGroups are shorthands accepted in allowed_operations
+var operationGroups = map[string][]Operation{
+	"send":    {OpSendContainers, OpSendImages, OpSendVolumes, OpSendNetworks},
+	"receive": {OpReceiveContainers, OpReceiveImages, OpReceiveVolumes, OpReceiveNetworks},
+}
+
+// Permissions is the worker's local allow-list of operations. It is enforced
+// here whatever the master asks for, and by the peer services on every call,
+// so a compromised or misconfigured master cannot push containers onto a
+// source-only host or have a peer remove containers from it.
+type Permissions struct {
+	allowed map[Operation]bool // nil allows everything
+}
+
+// ParsePermissions builds an allow-list from operation names and the "send"
+// and "receive" shorthands. An empty list allows everything.
+// remove_resources is in neither shorthand and must be named.
+func ParsePermissions(names []string) (*Permissions, error) {
+	if len(names) == 0 {
+		return &Permissions{}, nil
+	}
+
+	known := map[Operation]bool{OpRemoveResources: true}
+	for _, ops := range operationGroups {
+		for _, op := range ops {
+			known[op] = true
+		}
+	}
+
+	allowed := make(map[Operation]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if ops, ok := operationGroups[name]; ok {
+			for _, op := range ops {
+				allowed[op] = true
+			}
+			continue
+		}
+		if !known[Operation(name)] {
+			return nil, fmt.Errorf("unknown operation %q in allowed_operations", name)
+		}
+		allowed[Operation(name)] = true
+	}
+	return &Permissions{allowed: allowed}, nil
+}
+
+// Allows reports whether op is permitted
+func (p *Permissions) Allows(op Operation) bool {
+	return p == nil || p.allowed == nil || p.allowed[op]
+}
+
+// CheckSource returns an error naming the operations a source request needs
+// that this worker does not allow
+func (p *Permissions) CheckSource(req *pb.MigrationRequest) error {
+	return p.check(map[Operation]int{
+		OpSendContainers: len(req.ContainerIds),
+		OpSendImages:     len(req.ImageIds),
+		OpSendVolumes:    len(req.VolumeNames),
+		OpSendNetworks:   len(req.NetworkIds),
+	})
+}
+
+// CheckTarget returns an error naming the operations a target request needs
+// that this worker does not allow
+func (p *Permissions) CheckTarget(req *pb.AcceptMigrationRequest) error {
+	return p.check(map[Operation]int{
+		OpReceiveContainers: len(req.ContainerIds),
+		OpReceiveImages:     len(req.ImageIds),
+		OpReceiveVolumes:    len(req.VolumeNames),
+		OpReceiveNetworks:   len(req.NetworkIds),
+	})
+}
+
+func (p *Permissions) check(needed map[Operation]int) error {
+	var denied []string
+	for op, count := range needed {
+		if count > 0 && !p.Allows(op) {
+			denied = append(denied, string(op))
+		}
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	sort.Strings(denied)
+	return fmt.Errorf("refused by worker policy: %s not allowed", strings.Join(denied, ", "))
+}
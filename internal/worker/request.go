@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/peer"
+	pb "github.com/artemis/docker-migrate/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Session is the registration a running worker shares with local CLI
+// commands, so they can act on the master as that worker
+type Session struct {
+	WorkerID  string `json:"worker_id"`
+	AuthToken string `json:"auth_token"`
+	MasterURL string `json:"master_url"`
+	Tunnel    string `json:"tunnel,omitempty"`
+	TunnelURL string `json:"tunnel_url,omitempty"`
+	ProxyURL  string `json:"proxy_url,omitempty"`
+	Protocol  int32  `json:"protocol_version,omitempty"` // Negotiated with the master
+
+	// MasterFingerprint is the master certificate fingerprint the worker pinned
+	MasterFingerprint string `json:"master_fingerprint,omitempty"`
+}
+
+func sessionPath(dataDir string) (string, error) {
+	dataDir, err := config.ResolveDataDir(dataDir)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return filepath.Join(dataDir, "worker-session.json"), nil
+}
+
+// saveSession persists the worker's current registration
+func saveSession(cfg *config.Config, workerID, authToken string, protocol int32, masterFingerprint string) error {
+	path, err := sessionPath(cfg.DataDir)
+	if err != nil {
+		return err
+	}
+
+	session := Session{
+		WorkerID:  workerID,
+		AuthToken: authToken,
+		MasterURL: cfg.Worker.MasterURL,
+		Tunnel:    cfg.Worker.Tunnel,
+		TunnelURL: cfg.Worker.TunnelURL,
+		ProxyURL:  cfg.Worker.ProxyURL,
+		Protocol:  protocol,
+
+		MasterFingerprint: masterFingerprint,
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal worker session: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write worker session: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to save worker session: %w", err)
+	}
+	return nil
+}
+
+// LoadSession reads the registration of the worker running on this host
+func LoadSession(dataDir string) (*Session, error) {
+	path, err := sessionPath(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no worker session found; is the worker running and registered?")
+		}
+		return nil, fmt.Errorf("failed to read worker session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse worker session: %w", err)
+	}
+	if session.AuthToken == "" || session.MasterURL == "" {
+		return nil, fmt.Errorf("worker session is incomplete")
+	}
+	return &session, nil
+}
+
+// RequestMigration asks the master to migrate resources off this worker. The
+// migration stays pending until an admin approves it; the returned ID can be
+// looked up on the master's migrations API.
+func RequestMigration(ctx context.Context, session *Session, cryptoManager *peer.CryptoManager, req *pb.WorkerMigrationRequest) (string, error) {
+	if !peer.SupportsFeature(session.Protocol, peer.FeatureMigrationRequests) {
+		return "", fmt.Errorf("master speaks protocol v%d, which does not support %s; upgrade the master",
+			peer.EffectiveProtocolVersion(session.Protocol), peer.FeatureMigrationRequests)
+	}
+
+	// Verify the master the same way the running worker does
+	tlsConfig, err := masterTLSConfig(cryptoManager, session.MasterFingerprint)
+	if err != nil {
+		return "", fmt.Errorf("failed to get TLS config: %w", err)
+	}
+
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))}
+	mode, err := peer.ParseTunnelMode(session.Tunnel)
+	if err != nil {
+		return "", fmt.Errorf("invalid worker tunnel: %w", err)
+	}
+	tunnel, err := peer.TunnelDialOption(mode, session.TunnelURL, session.ProxyURL, func() string { return session.AuthToken })
+	if err != nil {
+		return "", fmt.Errorf("failed to configure worker tunnel: %w", err)
+	}
+	if tunnel != nil {
+		opts = append(opts, tunnel)
+	}
+
+	conn, err := grpc.Dial(session.MasterURL, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to dial master: %w", err)
+	}
+	defer conn.Close()
+
+	req.WorkerId = session.WorkerID
+	req.AuthToken = session.AuthToken
+
+	resp, err := pb.NewMasterServiceClient(conn).RequestMigration(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("migration request failed: %w", err)
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("migration request rejected: %s", resp.Error)
+	}
+	return resp.MigrationId, nil
+}
@@ -17,6 +17,18 @@ type TransferClient interface {
 	Close() error
 }
 
+// LayerQuerier is implemented by transfer clients that can ask the target
+// which image layers it already has
+type LayerQuerier interface {
+	QueryLayers(ctx context.Context, imageID string, diffIDs []string) ([]string, error)
+}
+
+// ImagePuller is implemented by transfer clients that can have the target
+// pull an image from an intermediary registry
+type ImagePuller interface {
+	PullImage(ctx context.Context, imageID, reference, tag, registryAuth string) error
+}
+
 // VolumeStream abstracts the volume transfer stream
 type VolumeStream interface {
 	Send(*pb.VolumeChunk) error
@@ -63,6 +75,36 @@ func (d *DirectTransferClient) TransferImageLayers(ctx context.Context) (ImageSt
 	return &directImageStream{stream: stream}, nil
 }
 
+// QueryLayers asks the target which of an image's layers it already has
+func (d *DirectTransferClient) QueryLayers(ctx context.Context, imageID string, diffIDs []string) ([]string, error) {
+	result, err := d.client.QueryLayers(ctx, &pb.LayerQuery{
+		ImageId: imageID,
+		DiffIds: diffIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query target layers: %w", err)
+	}
+	return result.Present, nil
+}
+
+// PullImage has the target pull an image from an intermediary registry and
+// tag it as tag
+func (d *DirectTransferClient) PullImage(ctx context.Context, imageID, reference, tag, registryAuth string) error {
+	result, err := d.client.PullImage(ctx, &pb.ImagePullRequest{
+		ImageId:      imageID,
+		Reference:    reference,
+		Tag:          tag,
+		RegistryAuth: registryAuth,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request target pull: %w", err)
+	}
+	if !result.Success {
+		return fmt.Errorf("target could not pull %s: %s", reference, result.Error)
+	}
+	return nil
+}
+
 // Close closes the underlying connection
 func (d *DirectTransferClient) Close() error {
 	if d.conn != nil {
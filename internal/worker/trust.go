@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/artemis/docker-migrate/internal/config"
+	"github.com/artemis/docker-migrate/internal/peer"
+)
+
+// resolveMasterFingerprint returns the master fingerprint to pin: the
+// configured one, else the one pinned by an earlier registration with the
+// same master. Without either the worker refuses to start, since its
+// enrollment token must never go to a master it cannot verify.
+func resolveMasterFingerprint(cfg *config.Config) (string, error) {
+	if cfg.Worker.MasterFingerprint != "" {
+		fp, err := peer.NormalizeFingerprint(cfg.Worker.MasterFingerprint)
+		if err != nil {
+			return "", fmt.Errorf("invalid master fingerprint: %w", err)
+		}
+		return fp, nil
+	}
+
+	session, err := LoadSession(cfg.DataDir)
+	if err == nil && session.MasterURL == cfg.Worker.MasterURL && session.MasterFingerprint != "" {
+		return session.MasterFingerprint, nil
+	}
+	return "", fmt.Errorf("master fingerprint required: pass --master-fingerprint with the master_fingerprint the master logs at startup")
+}
+
+// MasterFingerprint returns the pinned fingerprint of the master's certificate
+func (w *Worker) MasterFingerprint() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.masterFingerprint
+}
+
+// masterTLSConfig returns the client TLS configuration for connections to the
+// master, verifying its certificate against the pinned fingerprint
+func masterTLSConfig(cryptoManager *peer.CryptoManager, fingerprint string) (*tls.Config, error) {
+	if fingerprint == "" {
+		return nil, fmt.Errorf("master fingerprint is not configured")
+	}
+	return cryptoManager.TLSClientConfig(fingerprint)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/artemis/docker-migrate/internal/observability"
 	"github.com/artemis/docker-migrate/internal/peer"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
 // Worker represents a worker node
@@ -26,8 +27,13 @@ type Worker struct {
 	executor   *Executor
 	grpcServer *GRPCServer
 
-	workerID  string
-	authToken string
+	workerID        string
+	authToken       string
+	enrollmentToken string
+	protocol        int32 // Negotiated with the master
+
+	// SHA-256 fingerprint every connection to the master is verified against
+	masterFingerprint string
 
 	mu        sync.RWMutex
 	ctx       context.Context
@@ -43,6 +49,16 @@ func New(
 	transferManager *peer.TransferManager,
 	logger *observability.Logger,
 ) (*Worker, error) {
+	masterFingerprint, err := resolveMasterFingerprint(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := ParsePermissions(cfg.Worker.AllowedOperations)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	w := &Worker{
@@ -54,6 +70,8 @@ func New(
 		ctx:             ctx,
 		cancel:          cancel,
 		startTime:       time.Now(),
+
+		masterFingerprint: masterFingerprint,
 	}
 
 	// Initialize inventory scanner
@@ -62,10 +80,28 @@ func New(
 	// Initialize migration executor
 	w.executor = NewExecutor(dockerClient, transferManager, cryptoManager, logger)
 	w.executor.SetCredentialsProvider(w)
+	w.executor.SetPermissions(permissions)
+
+	// Tunnel master connections through restrictive proxies if configured
+	tunnel, err := w.tunnelDialOption()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if tunnel != nil {
+		w.executor.SetTunnel(cfg.Worker.MasterURL, tunnel)
+	}
+
+	// Sources send through the peer services, held to the same allow-list
+	peerServices, err := peer.NewPeerServices(dockerClient, transferManager, nil, cryptoManager, cfg, logger,
+		peer.WithOperationGuard(permissions))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create peer services: %w", err)
+	}
 
 	// Initialize gRPC server for WorkerService
-	var err error
-	w.grpcServer, err = NewGRPCServer(w, cryptoManager, logger)
+	w.grpcServer, err = NewGRPCServer(w, cryptoManager, peerServices, logger)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to create gRPC server: %w", err)
@@ -81,18 +117,27 @@ func (w *Worker) Start(ctx context.Context, enrollmentToken string) error {
 		zap.String("master_url", w.config.Worker.MasterURL),
 	)
 
-	// Start local gRPC server for incoming migration connections
-	go func() {
-		if err := w.grpcServer.Start(w.config.GRPCAddr); err != nil {
-			w.logger.Error("gRPC server error", zap.Error(err))
-		}
-	}()
+	// Start local gRPC server for incoming migration connections, unless the
+	// worker is outbound-only and reaches everything through WorkerStream
+	if w.config.Worker.OutboundOnly {
+		w.logger.Info("outbound-only mode: not listening for gRPC connections")
+	} else {
+		go func() {
+			if err := w.grpcServer.Start(w.config.GRPCAddr); err != nil {
+				w.logger.Error("gRPC server error", zap.Error(err))
+			}
+		}()
+	}
 
 	// Create connector and connect to master
-	w.connector = NewConnector(w, w.cryptoManager, w.logger)
+	connector := NewConnector(w, w.cryptoManager, w.logger)
+	w.mu.Lock()
+	w.connector = connector
+	w.enrollmentToken = enrollmentToken
+	w.mu.Unlock()
 
 	// Connect and register with master
-	if err := w.connector.Connect(ctx, enrollmentToken); err != nil {
+	if err := connector.Connect(ctx, enrollmentToken); err != nil {
 		return fmt.Errorf("failed to connect to master: %w", err)
 	}
 
@@ -102,6 +147,32 @@ func (w *Worker) Start(ctx context.Context, enrollmentToken string) error {
 	return nil
 }
 
+// tunnelDialOption returns the dial option tunnelling master connections, or nil
+func (w *Worker) tunnelDialOption() (grpc.DialOption, error) {
+	mode, err := peer.ParseTunnelMode(w.config.Worker.Tunnel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid worker tunnel: %w", err)
+	}
+
+	opt, err := peer.TunnelDialOption(mode, w.config.Worker.TunnelURL, w.config.Worker.ProxyURL, w.tunnelToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure worker tunnel: %w", err)
+	}
+	return opt, nil
+}
+
+// tunnelToken is the bearer token the master checks when the tunnel opens.
+// Every connection registers with the enrollment token, so that is sent;
+// the auth token is the fallback for a worker started without one.
+func (w *Worker) tunnelToken() string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.enrollmentToken != "" {
+		return w.enrollmentToken
+	}
+	return w.authToken
+}
+
 // Stop stops the worker
 func (w *Worker) Stop() {
 	w.logger.Info("stopping worker")
@@ -140,6 +211,21 @@ func (w *Worker) GetTransferManager() *peer.TransferManager {
 	return w.transferManager
 }
 
+// SetProtocol records the protocol version negotiated with the master; call
+// before SetCredentials so the saved session carries it
+func (w *Worker) SetProtocol(version int32) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.protocol = version
+}
+
+// Protocol returns the protocol version negotiated with the master
+func (w *Worker) Protocol() int32 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.protocol
+}
+
 // SetCredentials stores the worker ID and auth token from registration
 func (w *Worker) SetCredentials(workerID, authToken string) {
 	w.mu.Lock()
@@ -149,6 +235,11 @@ func (w *Worker) SetCredentials(workerID, authToken string) {
 
 	// Also store in config for persistence
 	w.config.SetWorkerCredentials(workerID, authToken)
+
+	// Let local CLI commands (e.g. "worker request") act as this worker
+	if err := saveSession(w.config, workerID, authToken, w.protocol, w.masterFingerprint); err != nil {
+		w.logger.Warn("failed to save worker session", zap.Error(err))
+	}
 }
 
 // GetCredentials returns the worker ID and auth token
@@ -522,6 +522,9 @@ type VolumeChunk struct {
 	Checksum      string                 `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"` // SHA-256 of data
 	TotalSize     int64                  `protobuf:"varint,5,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
 	IsFinal       bool                   `protobuf:"varint,6,opt,name=is_final,json=isFinal,proto3" json:"is_final,omitempty"`
+	Delta         bool                   `protobuf:"varint,7,opt,name=delta,proto3" json:"delta,omitempty"`                                  // Data is a tar of changed files to merge into the existing volume
+	DeletedPaths  []string               `protobuf:"bytes,8,rep,name=deleted_paths,json=deletedPaths,proto3" json:"deleted_paths,omitempty"` // Delta only, on the final chunk: paths to remove before merging
+	Patch         bool                   `protobuf:"varint,9,opt,name=patch,proto3" json:"patch,omitempty"`                                  // Delta only: the tar holds file ranges to write in place rather than whole files
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -598,6 +601,1516 @@ func (x *VolumeChunk) GetIsFinal() bool {
 	return false
 }
 
+func (x *VolumeChunk) GetDelta() bool {
+	if x != nil {
+		return x.Delta
+	}
+	return false
+}
+
+func (x *VolumeChunk) GetDeletedPaths() []string {
+	if x != nil {
+		return x.DeletedPaths
+	}
+	return nil
+}
+
+func (x *VolumeChunk) GetPatch() bool {
+	if x != nil {
+		return x.Patch
+	}
+	return false
+}
+
+// VolumeIndexRequest asks for the file index of a volume
+type VolumeIndexRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	RangeSize     int64                  `protobuf:"varint,2,opt,name=range_size,json=rangeSize,proto3" json:"range_size,omitempty"` // If set, each file is also hashed in ranges of this many bytes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VolumeIndexRequest) Reset() {
+	*x = VolumeIndexRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VolumeIndexRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VolumeIndexRequest) ProtoMessage() {}
+
+func (x *VolumeIndexRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VolumeIndexRequest.ProtoReflect.Descriptor instead.
+func (*VolumeIndexRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VolumeIndexRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *VolumeIndexRequest) GetRangeSize() int64 {
+	if x != nil {
+		return x.RangeSize
+	}
+	return 0
+}
+
+// StartCheckRequest lists what a container needs free on the target to start
+type StartCheckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContainerName string                 `protobuf:"bytes,1,opt,name=container_name,json=containerName,proto3" json:"container_name,omitempty"`
+	Ports         []*HostPort            `protobuf:"bytes,2,rep,name=ports,proto3" json:"ports,omitempty"`
+	Volumes       []string               `protobuf:"bytes,3,rep,name=volumes,proto3" json:"volumes,omitempty"`                         // Named volumes it mounts
+	BindPaths     []string               `protobuf:"bytes,4,rep,name=bind_paths,json=bindPaths,proto3" json:"bind_paths,omitempty"`    // Host paths it bind-mounts
+	SharedWith    []string               `protobuf:"bytes,5,rep,name=shared_with,json=sharedWith,proto3" json:"shared_with,omitempty"` // Containers of the same migration, which may share its volumes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartCheckRequest) Reset() {
+	*x = StartCheckRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCheckRequest) ProtoMessage() {}
+
+func (x *StartCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCheckRequest.ProtoReflect.Descriptor instead.
+func (*StartCheckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StartCheckRequest) GetContainerName() string {
+	if x != nil {
+		return x.ContainerName
+	}
+	return ""
+}
+
+func (x *StartCheckRequest) GetPorts() []*HostPort {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *StartCheckRequest) GetVolumes() []string {
+	if x != nil {
+		return x.Volumes
+	}
+	return nil
+}
+
+func (x *StartCheckRequest) GetBindPaths() []string {
+	if x != nil {
+		return x.BindPaths
+	}
+	return nil
+}
+
+func (x *StartCheckRequest) GetSharedWith() []string {
+	if x != nil {
+		return x.SharedWith
+	}
+	return nil
+}
+
+// HostPort is a port published on the host
+type HostPort struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	HostIp        string                 `protobuf:"bytes,1,opt,name=host_ip,json=hostIp,proto3" json:"host_ip,omitempty"` // Empty for all addresses
+	HostPort      int32                  `protobuf:"varint,2,opt,name=host_port,json=hostPort,proto3" json:"host_port,omitempty"`
+	Protocol      string                 `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"` // tcp or udp
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostPort) Reset() {
+	*x = HostPort{}
+	mi := &file_proto_migrate_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostPort) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostPort) ProtoMessage() {}
+
+func (x *HostPort) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostPort.ProtoReflect.Descriptor instead.
+func (*HostPort) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HostPort) GetHostIp() string {
+	if x != nil {
+		return x.HostIp
+	}
+	return ""
+}
+
+func (x *HostPort) GetHostPort() int32 {
+	if x != nil {
+		return x.HostPort
+	}
+	return 0
+}
+
+func (x *HostPort) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+// StartConflict is one thing in the way of starting a container
+type StartConflict struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"` // name, port, volume or bind
+	Resource      string                 `protobuf:"bytes,2,opt,name=resource,proto3" json:"resource,omitempty"`
+	Detail        string                 `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartConflict) Reset() {
+	*x = StartConflict{}
+	mi := &file_proto_migrate_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartConflict) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartConflict) ProtoMessage() {}
+
+func (x *StartConflict) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartConflict.ProtoReflect.Descriptor instead.
+func (*StartConflict) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *StartConflict) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *StartConflict) GetResource() string {
+	if x != nil {
+		return x.Resource
+	}
+	return ""
+}
+
+func (x *StartConflict) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+// StartCheckResult lists the conflicts found; none means the container can start
+type StartCheckResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Conflicts     []*StartConflict       `protobuf:"bytes,1,rep,name=conflicts,proto3" json:"conflicts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartCheckResult) Reset() {
+	*x = StartCheckResult{}
+	mi := &file_proto_migrate_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartCheckResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartCheckResult) ProtoMessage() {}
+
+func (x *StartCheckResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartCheckResult.ProtoReflect.Descriptor instead.
+func (*StartCheckResult) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StartCheckResult) GetConflicts() []*StartConflict {
+	if x != nil {
+		return x.Conflicts
+	}
+	return nil
+}
+
+// VolumeFile describes one directory or regular file in a volume
+type VolumeFile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"` // Relative to the volume root
+	Mode          uint32                 `protobuf:"varint,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	ModTime       int64                  `protobuf:"varint,4,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"` // Unix nanoseconds
+	Hash          string                 `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`                       // SHA-256 of the contents; empty for directories
+	Dir           bool                   `protobuf:"varint,6,opt,name=dir,proto3" json:"dir,omitempty"`
+	Ranges        []string               `protobuf:"bytes,7,rep,name=ranges,proto3" json:"ranges,omitempty"` // SHA-256 of each range_size piece, when asked for
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VolumeFile) Reset() {
+	*x = VolumeFile{}
+	mi := &file_proto_migrate_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VolumeFile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VolumeFile) ProtoMessage() {}
+
+func (x *VolumeFile) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VolumeFile.ProtoReflect.Descriptor instead.
+func (*VolumeFile) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *VolumeFile) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *VolumeFile) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *VolumeFile) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *VolumeFile) GetModTime() int64 {
+	if x != nil {
+		return x.ModTime
+	}
+	return 0
+}
+
+func (x *VolumeFile) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *VolumeFile) GetDir() bool {
+	if x != nil {
+		return x.Dir
+	}
+	return false
+}
+
+func (x *VolumeFile) GetRanges() []string {
+	if x != nil {
+		return x.Ranges
+	}
+	return nil
+}
+
+// VolumeIndex carries part of a volume's file list; exists is false if the volume is missing
+type VolumeIndex struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	Files         []*VolumeFile          `protobuf:"bytes,2,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VolumeIndex) Reset() {
+	*x = VolumeIndex{}
+	mi := &file_proto_migrate_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VolumeIndex) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VolumeIndex) ProtoMessage() {}
+
+func (x *VolumeIndex) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VolumeIndex.ProtoReflect.Descriptor instead.
+func (*VolumeIndex) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *VolumeIndex) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *VolumeIndex) GetFiles() []*VolumeFile {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+// VolumeSpec describes a volume to create on the peer
+type VolumeSpec struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Driver        string                 `protobuf:"bytes,2,opt,name=driver,proto3" json:"driver,omitempty"`
+	DriverOpts    map[string]string      `protobuf:"bytes,3,rep,name=driver_opts,json=driverOpts,proto3" json:"driver_opts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Labels        map[string]string      `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VolumeSpec) Reset() {
+	*x = VolumeSpec{}
+	mi := &file_proto_migrate_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VolumeSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VolumeSpec) ProtoMessage() {}
+
+func (x *VolumeSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VolumeSpec.ProtoReflect.Descriptor instead.
+func (*VolumeSpec) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *VolumeSpec) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *VolumeSpec) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *VolumeSpec) GetDriverOpts() map[string]string {
+	if x != nil {
+		return x.DriverOpts
+	}
+	return nil
+}
+
+func (x *VolumeSpec) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// VolumeSampleRequest asks for the checksums of some of a volume's files
+type VolumeSampleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Volume        string                 `protobuf:"bytes,1,opt,name=volume,proto3" json:"volume,omitempty"`
+	Paths         []string               `protobuf:"bytes,2,rep,name=paths,proto3" json:"paths,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VolumeSampleRequest) Reset() {
+	*x = VolumeSampleRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VolumeSampleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VolumeSampleRequest) ProtoMessage() {}
+
+func (x *VolumeSampleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VolumeSampleRequest.ProtoReflect.Descriptor instead.
+func (*VolumeSampleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *VolumeSampleRequest) GetVolume() string {
+	if x != nil {
+		return x.Volume
+	}
+	return ""
+}
+
+func (x *VolumeSampleRequest) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+// VolumeSample is the peer's copy of a volume: its file count and size, and
+// the sampled files hashed afresh; exists is false if the volume is missing
+type VolumeSample struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	Files         int64                  `protobuf:"varint,2,opt,name=files,proto3" json:"files,omitempty"`
+	Bytes         int64                  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Sample        []*VolumeFile          `protobuf:"bytes,4,rep,name=sample,proto3" json:"sample,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VolumeSample) Reset() {
+	*x = VolumeSample{}
+	mi := &file_proto_migrate_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VolumeSample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VolumeSample) ProtoMessage() {}
+
+func (x *VolumeSample) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VolumeSample.ProtoReflect.Descriptor instead.
+func (*VolumeSample) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *VolumeSample) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *VolumeSample) GetFiles() int64 {
+	if x != nil {
+		return x.Files
+	}
+	return 0
+}
+
+func (x *VolumeSample) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *VolumeSample) GetSample() []*VolumeFile {
+	if x != nil {
+		return x.Sample
+	}
+	return nil
+}
+
+// ImageInspectRequest names an image by ID, name or name:tag
+type ImageInspectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reference     string                 `protobuf:"bytes,1,opt,name=reference,proto3" json:"reference,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageInspectRequest) Reset() {
+	*x = ImageInspectRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageInspectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageInspectRequest) ProtoMessage() {}
+
+func (x *ImageInspectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageInspectRequest.ProtoReflect.Descriptor instead.
+func (*ImageInspectRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ImageInspectRequest) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+// ImageInspect is what the peer knows of an image
+type ImageInspect struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageInspect) Reset() {
+	*x = ImageInspect{}
+	mi := &file_proto_migrate_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageInspect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageInspect) ProtoMessage() {}
+
+func (x *ImageInspect) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageInspect.ProtoReflect.Descriptor instead.
+func (*ImageInspect) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ImageInspect) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *ImageInspect) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// HostInspect is the peer's host as migration provenance labels name it
+type HostInspect struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hostname      string                 `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostInspect) Reset() {
+	*x = HostInspect{}
+	mi := &file_proto_migrate_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostInspect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostInspect) ProtoMessage() {}
+
+func (x *HostInspect) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostInspect.ProtoReflect.Descriptor instead.
+func (*HostInspect) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HostInspect) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+// ContainerInspectRequest names a container by ID or name
+type ContainerInspectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reference     string                 `protobuf:"bytes,1,opt,name=reference,proto3" json:"reference,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainerInspectRequest) Reset() {
+	*x = ContainerInspectRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerInspectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerInspectRequest) ProtoMessage() {}
+
+func (x *ContainerInspectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerInspectRequest.ProtoReflect.Descriptor instead.
+func (*ContainerInspectRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ContainerInspectRequest) GetReference() string {
+	if x != nil {
+		return x.Reference
+	}
+	return ""
+}
+
+// ContainerInspect is a container's state as Docker reports it
+type ContainerInspect struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"` // created, running, exited...
+	Running       bool                   `protobuf:"varint,5,opt,name=running,proto3" json:"running,omitempty"`
+	Restarting    bool                   `protobuf:"varint,6,opt,name=restarting,proto3" json:"restarting,omitempty"`
+	Health        string                 `protobuf:"bytes,7,opt,name=health,proto3" json:"health,omitempty"` // Healthcheck status; empty without a healthcheck
+	ExitCode      int32                  `protobuf:"varint,8,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	Error         string                 `protobuf:"bytes,9,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainerInspect) Reset() {
+	*x = ContainerInspect{}
+	mi := &file_proto_migrate_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerInspect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerInspect) ProtoMessage() {}
+
+func (x *ContainerInspect) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerInspect.ProtoReflect.Descriptor instead.
+func (*ContainerInspect) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ContainerInspect) GetExists() bool {
+	if x != nil {
+		return x.Exists
+	}
+	return false
+}
+
+func (x *ContainerInspect) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ContainerInspect) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ContainerInspect) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ContainerInspect) GetRunning() bool {
+	if x != nil {
+		return x.Running
+	}
+	return false
+}
+
+func (x *ContainerInspect) GetRestarting() bool {
+	if x != nil {
+		return x.Restarting
+	}
+	return false
+}
+
+func (x *ContainerInspect) GetHealth() string {
+	if x != nil {
+		return x.Health
+	}
+	return ""
+}
+
+func (x *ContainerInspect) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *ContainerInspect) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ContainerCreateRequest carries a container's exported state and the name to create it under
+type ContainerCreateRequest struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	StateData           []byte                 `protobuf:"bytes,1,opt,name=state_data,json=stateData,proto3" json:"state_data,omitempty"` // JSON-encoded ContainerState
+	Name                string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	AllowDefaultRuntime bool                   `protobuf:"varint,3,opt,name=allow_default_runtime,json=allowDefaultRuntime,proto3" json:"allow_default_runtime,omitempty"` // Use the default runtime if the requested one is not installed
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *ContainerCreateRequest) Reset() {
+	*x = ContainerCreateRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerCreateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerCreateRequest) ProtoMessage() {}
+
+func (x *ContainerCreateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerCreateRequest.ProtoReflect.Descriptor instead.
+func (*ContainerCreateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ContainerCreateRequest) GetStateData() []byte {
+	if x != nil {
+		return x.StateData
+	}
+	return nil
+}
+
+func (x *ContainerCreateRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ContainerCreateRequest) GetAllowDefaultRuntime() bool {
+	if x != nil {
+		return x.AllowDefaultRuntime
+	}
+	return false
+}
+
+// ContainerCreateResult is the created container and what it lost
+type ContainerCreateResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Report        *CompatibilityReport   `protobuf:"bytes,2,opt,name=report,proto3" json:"report,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainerCreateResult) Reset() {
+	*x = ContainerCreateResult{}
+	mi := &file_proto_migrate_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerCreateResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerCreateResult) ProtoMessage() {}
+
+func (x *ContainerCreateResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerCreateResult.ProtoReflect.Descriptor instead.
+func (*ContainerCreateResult) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ContainerCreateResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ContainerCreateResult) GetReport() *CompatibilityReport {
+	if x != nil {
+		return x.Report
+	}
+	return nil
+}
+
+// CompatibilityReport lists the settings a recreated container did not get as requested
+type CompatibilityReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Container     string                 `protobuf:"bytes,1,opt,name=container,proto3" json:"container,omitempty"`
+	ContainerId   string                 `protobuf:"bytes,2,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
+	Skipped       []*SkippedField        `protobuf:"bytes,3,rep,name=skipped,proto3" json:"skipped,omitempty"`
+	Warnings      []string               `protobuf:"bytes,4,rep,name=warnings,proto3" json:"warnings,omitempty"` // Limit checks and daemon warnings
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompatibilityReport) Reset() {
+	*x = CompatibilityReport{}
+	mi := &file_proto_migrate_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompatibilityReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompatibilityReport) ProtoMessage() {}
+
+func (x *CompatibilityReport) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompatibilityReport.ProtoReflect.Descriptor instead.
+func (*CompatibilityReport) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CompatibilityReport) GetContainer() string {
+	if x != nil {
+		return x.Container
+	}
+	return ""
+}
+
+func (x *CompatibilityReport) GetContainerId() string {
+	if x != nil {
+		return x.ContainerId
+	}
+	return ""
+}
+
+func (x *CompatibilityReport) GetSkipped() []*SkippedField {
+	if x != nil {
+		return x.Skipped
+	}
+	return nil
+}
+
+func (x *CompatibilityReport) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+// SkippedField is a container setting that was not applied as requested
+type SkippedField struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"` // e.g. "HostConfig.Sysctls"
+	Requested     string                 `protobuf:"bytes,2,opt,name=requested,proto3" json:"requested,omitempty"`
+	Applied       string                 `protobuf:"bytes,3,opt,name=applied,proto3" json:"applied,omitempty"`
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SkippedField) Reset() {
+	*x = SkippedField{}
+	mi := &file_proto_migrate_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SkippedField) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SkippedField) ProtoMessage() {}
+
+func (x *SkippedField) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SkippedField.ProtoReflect.Descriptor instead.
+func (*SkippedField) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *SkippedField) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *SkippedField) GetRequested() string {
+	if x != nil {
+		return x.Requested
+	}
+	return ""
+}
+
+func (x *SkippedField) GetApplied() string {
+	if x != nil {
+		return x.Applied
+	}
+	return ""
+}
+
+func (x *SkippedField) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// CheckpointSupportResult is why the peer cannot restore checkpoints, or empty if it can
+type CheckpointSupportResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Unsupported   string                 `protobuf:"bytes,1,opt,name=unsupported,proto3" json:"unsupported,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckpointSupportResult) Reset() {
+	*x = CheckpointSupportResult{}
+	mi := &file_proto_migrate_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckpointSupportResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckpointSupportResult) ProtoMessage() {}
+
+func (x *CheckpointSupportResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckpointSupportResult.ProtoReflect.Descriptor instead.
+func (*CheckpointSupportResult) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CheckpointSupportResult) GetUnsupported() string {
+	if x != nil {
+		return x.Unsupported
+	}
+	return ""
+}
+
+// ContainerRestoreRequest starts a created container from a checkpoint held
+// in a volume sent by the source. The volume is removed once the restore
+// has been attempted.
+type ContainerRestoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContainerId   string                 `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
+	CheckpointId  string                 `protobuf:"bytes,2,opt,name=checkpoint_id,json=checkpointId,proto3" json:"checkpoint_id,omitempty"`
+	Volume        string                 `protobuf:"bytes,3,opt,name=volume,proto3" json:"volume,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainerRestoreRequest) Reset() {
+	*x = ContainerRestoreRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerRestoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerRestoreRequest) ProtoMessage() {}
+
+func (x *ContainerRestoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerRestoreRequest.ProtoReflect.Descriptor instead.
+func (*ContainerRestoreRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ContainerRestoreRequest) GetContainerId() string {
+	if x != nil {
+		return x.ContainerId
+	}
+	return ""
+}
+
+func (x *ContainerRestoreRequest) GetCheckpointId() string {
+	if x != nil {
+		return x.CheckpointId
+	}
+	return ""
+}
+
+func (x *ContainerRestoreRequest) GetVolume() string {
+	if x != nil {
+		return x.Volume
+	}
+	return ""
+}
+
+// ContainerStartRequest starts a created container
+type ContainerStartRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContainerId   string                 `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3" json:"container_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ContainerStartRequest) Reset() {
+	*x = ContainerStartRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ContainerStartRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContainerStartRequest) ProtoMessage() {}
+
+func (x *ContainerStartRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContainerStartRequest.ProtoReflect.Descriptor instead.
+func (*ContainerStartRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ContainerStartRequest) GetContainerId() string {
+	if x != nil {
+		return x.ContainerId
+	}
+	return ""
+}
+
+// ComposeBundle is a compose stack's bundle as exported on the source
+type ComposeBundle struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stack         string                 `protobuf:"bytes,1,opt,name=stack,proto3" json:"stack,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"` // The bundle's tar
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComposeBundle) Reset() {
+	*x = ComposeBundle{}
+	mi := &file_proto_migrate_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComposeBundle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComposeBundle) ProtoMessage() {}
+
+func (x *ComposeBundle) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComposeBundle.ProtoReflect.Descriptor instead.
+func (*ComposeBundle) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ComposeBundle) GetStack() string {
+	if x != nil {
+		return x.Stack
+	}
+	return ""
+}
+
+func (x *ComposeBundle) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// ComposeBundleReceipt tells the source where the peer kept the bundle
+type ComposeBundleReceipt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComposeBundleReceipt) Reset() {
+	*x = ComposeBundleReceipt{}
+	mi := &file_proto_migrate_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComposeBundleReceipt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComposeBundleReceipt) ProtoMessage() {}
+
+func (x *ComposeBundleReceipt) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComposeBundleReceipt.ProtoReflect.Descriptor instead.
+func (*ComposeBundleReceipt) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ComposeBundleReceipt) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+// RemoveResourcesRequest lists what migration job_id left on the peer. Only
+// resources carrying the job's label are removed.
+type RemoveResourcesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Resources     *ResourceList          `protobuf:"bytes,2,opt,name=resources,proto3" json:"resources,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveResourcesRequest) Reset() {
+	*x = RemoveResourcesRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveResourcesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveResourcesRequest) ProtoMessage() {}
+
+func (x *RemoveResourcesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveResourcesRequest.ProtoReflect.Descriptor instead.
+func (*RemoveResourcesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *RemoveResourcesRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *RemoveResourcesRequest) GetResources() *ResourceList {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
 // LayerBlob represents an image layer
 type LayerBlob struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -614,7 +2127,7 @@ type LayerBlob struct {
 
 func (x *LayerBlob) Reset() {
 	*x = LayerBlob{}
-	mi := &file_proto_migrate_proto_msgTypes[1]
+	mi := &file_proto_migrate_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -626,7 +2139,7 @@ func (x *LayerBlob) String() string {
 func (*LayerBlob) ProtoMessage() {}
 
 func (x *LayerBlob) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[1]
+	mi := &file_proto_migrate_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -639,7 +2152,7 @@ func (x *LayerBlob) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use LayerBlob.ProtoReflect.Descriptor instead.
 func (*LayerBlob) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{1}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{26}
 }
 
 func (x *LayerBlob) GetImageId() string {
@@ -670,25 +2183,193 @@ func (x *LayerBlob) GetData() []byte {
 	return nil
 }
 
-func (x *LayerBlob) GetChecksum() string {
+func (x *LayerBlob) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *LayerBlob) GetLayerSize() int64 {
+	if x != nil {
+		return x.LayerSize
+	}
+	return 0
+}
+
+func (x *LayerBlob) GetIsFinal() bool {
+	if x != nil {
+		return x.IsFinal
+	}
+	return false
+}
+
+// LayerQuery lists an image's layers by diff ID, base layer first
+type LayerQuery struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ImageId       string                 `protobuf:"bytes,1,opt,name=image_id,json=imageId,proto3" json:"image_id,omitempty"`
+	DiffIds       []string               `protobuf:"bytes,2,rep,name=diff_ids,json=diffIds,proto3" json:"diff_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LayerQuery) Reset() {
+	*x = LayerQuery{}
+	mi := &file_proto_migrate_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LayerQuery) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LayerQuery) ProtoMessage() {}
+
+func (x *LayerQuery) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LayerQuery.ProtoReflect.Descriptor instead.
+func (*LayerQuery) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *LayerQuery) GetImageId() string {
+	if x != nil {
+		return x.ImageId
+	}
+	return ""
+}
+
+func (x *LayerQuery) GetDiffIds() []string {
+	if x != nil {
+		return x.DiffIds
+	}
+	return nil
+}
+
+// LayerQueryResult lists the queried layers the peer has. A layer only counts
+// if every layer below it matches too, so this is always a prefix of the query.
+type LayerQueryResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Present       []string               `protobuf:"bytes,1,rep,name=present,proto3" json:"present,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LayerQueryResult) Reset() {
+	*x = LayerQueryResult{}
+	mi := &file_proto_migrate_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LayerQueryResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LayerQueryResult) ProtoMessage() {}
+
+func (x *LayerQueryResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LayerQueryResult.ProtoReflect.Descriptor instead.
+func (*LayerQueryResult) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *LayerQueryResult) GetPresent() []string {
+	if x != nil {
+		return x.Present
+	}
+	return nil
+}
+
+// ImagePullRequest asks a peer to pull reference and tag it as tag
+type ImagePullRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ImageId       string                 `protobuf:"bytes,1,opt,name=image_id,json=imageId,proto3" json:"image_id,omitempty"`
+	Reference     string                 `protobuf:"bytes,2,opt,name=reference,proto3" json:"reference,omitempty"`
+	Tag           string                 `protobuf:"bytes,3,opt,name=tag,proto3" json:"tag,omitempty"`                                       // Original name to tag the image with; empty keeps only reference
+	RegistryAuth  string                 `protobuf:"bytes,4,opt,name=registry_auth,json=registryAuth,proto3" json:"registry_auth,omitempty"` // Base64 Docker registry credentials; empty for an open registry
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImagePullRequest) Reset() {
+	*x = ImagePullRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImagePullRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImagePullRequest) ProtoMessage() {}
+
+func (x *ImagePullRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImagePullRequest.ProtoReflect.Descriptor instead.
+func (*ImagePullRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ImagePullRequest) GetImageId() string {
+	if x != nil {
+		return x.ImageId
+	}
+	return ""
+}
+
+func (x *ImagePullRequest) GetReference() string {
 	if x != nil {
-		return x.Checksum
+		return x.Reference
 	}
 	return ""
 }
 
-func (x *LayerBlob) GetLayerSize() int64 {
+func (x *ImagePullRequest) GetTag() string {
 	if x != nil {
-		return x.LayerSize
+		return x.Tag
 	}
-	return 0
+	return ""
 }
 
-func (x *LayerBlob) GetIsFinal() bool {
+func (x *ImagePullRequest) GetRegistryAuth() string {
 	if x != nil {
-		return x.IsFinal
+		return x.RegistryAuth
 	}
-	return false
+	return ""
 }
 
 // ContainerChunk represents container state data
@@ -705,7 +2386,7 @@ type ContainerChunk struct {
 
 func (x *ContainerChunk) Reset() {
 	*x = ContainerChunk{}
-	mi := &file_proto_migrate_proto_msgTypes[2]
+	mi := &file_proto_migrate_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -717,7 +2398,7 @@ func (x *ContainerChunk) String() string {
 func (*ContainerChunk) ProtoMessage() {}
 
 func (x *ContainerChunk) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[2]
+	mi := &file_proto_migrate_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -730,7 +2411,7 @@ func (x *ContainerChunk) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ContainerChunk.ProtoReflect.Descriptor instead.
 func (*ContainerChunk) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{2}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{30}
 }
 
 func (x *ContainerChunk) GetContainerId() string {
@@ -781,7 +2462,7 @@ type NetworkConfig struct {
 
 func (x *NetworkConfig) Reset() {
 	*x = NetworkConfig{}
-	mi := &file_proto_migrate_proto_msgTypes[3]
+	mi := &file_proto_migrate_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -793,7 +2474,7 @@ func (x *NetworkConfig) String() string {
 func (*NetworkConfig) ProtoMessage() {}
 
 func (x *NetworkConfig) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[3]
+	mi := &file_proto_migrate_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -806,7 +2487,7 @@ func (x *NetworkConfig) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NetworkConfig.ProtoReflect.Descriptor instead.
 func (*NetworkConfig) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{3}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{31}
 }
 
 func (x *NetworkConfig) GetNetworkId() string {
@@ -843,14 +2524,15 @@ type TransferAck struct {
 	Offset        int64                  `protobuf:"varint,1,opt,name=offset,proto3" json:"offset,omitempty"`
 	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
 	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
-	Progress      float32                `protobuf:"fixed32,4,opt,name=progress,proto3" json:"progress,omitempty"` // 0.0 to 1.0
+	Progress      float32                `protobuf:"fixed32,4,opt,name=progress,proto3" json:"progress,omitempty"`    // 0.0 to 1.0
+	Retransmit    bool                   `protobuf:"varint,5,opt,name=retransmit,proto3" json:"retransmit,omitempty"` // Chunk at offset failed verification; send it again
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TransferAck) Reset() {
 	*x = TransferAck{}
-	mi := &file_proto_migrate_proto_msgTypes[4]
+	mi := &file_proto_migrate_proto_msgTypes[32]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -862,7 +2544,7 @@ func (x *TransferAck) String() string {
 func (*TransferAck) ProtoMessage() {}
 
 func (x *TransferAck) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[4]
+	mi := &file_proto_migrate_proto_msgTypes[32]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -875,7 +2557,7 @@ func (x *TransferAck) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TransferAck.ProtoReflect.Descriptor instead.
 func (*TransferAck) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{4}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{32}
 }
 
 func (x *TransferAck) GetOffset() int64 {
@@ -906,6 +2588,13 @@ func (x *TransferAck) GetProgress() float32 {
 	return 0
 }
 
+func (x *TransferAck) GetRetransmit() bool {
+	if x != nil {
+		return x.Retransmit
+	}
+	return false
+}
+
 // TransferResult reports the final result of a transfer
 type TransferResult struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
@@ -920,7 +2609,7 @@ type TransferResult struct {
 
 func (x *TransferResult) Reset() {
 	*x = TransferResult{}
-	mi := &file_proto_migrate_proto_msgTypes[5]
+	mi := &file_proto_migrate_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -932,7 +2621,7 @@ func (x *TransferResult) String() string {
 func (*TransferResult) ProtoMessage() {}
 
 func (x *TransferResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[5]
+	mi := &file_proto_migrate_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -945,7 +2634,7 @@ func (x *TransferResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TransferResult.ProtoReflect.Descriptor instead.
 func (*TransferResult) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{5}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{33}
 }
 
 func (x *TransferResult) GetSuccess() bool {
@@ -994,7 +2683,7 @@ type ResourceRequest struct {
 
 func (x *ResourceRequest) Reset() {
 	*x = ResourceRequest{}
-	mi := &file_proto_migrate_proto_msgTypes[6]
+	mi := &file_proto_migrate_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1006,7 +2695,7 @@ func (x *ResourceRequest) String() string {
 func (*ResourceRequest) ProtoMessage() {}
 
 func (x *ResourceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[6]
+	mi := &file_proto_migrate_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1019,7 +2708,7 @@ func (x *ResourceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResourceRequest.ProtoReflect.Descriptor instead.
 func (*ResourceRequest) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{6}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{34}
 }
 
 func (x *ResourceRequest) GetType() ResourceType {
@@ -1049,7 +2738,7 @@ type ResourceList struct {
 
 func (x *ResourceList) Reset() {
 	*x = ResourceList{}
-	mi := &file_proto_migrate_proto_msgTypes[7]
+	mi := &file_proto_migrate_proto_msgTypes[35]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1061,7 +2750,7 @@ func (x *ResourceList) String() string {
 func (*ResourceList) ProtoMessage() {}
 
 func (x *ResourceList) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[7]
+	mi := &file_proto_migrate_proto_msgTypes[35]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1074,7 +2763,7 @@ func (x *ResourceList) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResourceList.ProtoReflect.Descriptor instead.
 func (*ResourceList) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{7}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{35}
 }
 
 func (x *ResourceList) GetContainers() []*ContainerResource {
@@ -1114,13 +2803,14 @@ type ContainerResource struct {
 	State         string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
 	Created       int64                  `protobuf:"varint,5,opt,name=created,proto3" json:"created,omitempty"`
 	Labels        map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SizeRw        int64                  `protobuf:"varint,7,opt,name=size_rw,json=sizeRw,proto3" json:"size_rw,omitempty"` // Writable layer size in bytes
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ContainerResource) Reset() {
 	*x = ContainerResource{}
-	mi := &file_proto_migrate_proto_msgTypes[8]
+	mi := &file_proto_migrate_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1132,7 +2822,7 @@ func (x *ContainerResource) String() string {
 func (*ContainerResource) ProtoMessage() {}
 
 func (x *ContainerResource) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[8]
+	mi := &file_proto_migrate_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1145,7 +2835,7 @@ func (x *ContainerResource) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ContainerResource.ProtoReflect.Descriptor instead.
 func (*ContainerResource) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{8}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{36}
 }
 
 func (x *ContainerResource) GetId() string {
@@ -1190,6 +2880,13 @@ func (x *ContainerResource) GetLabels() map[string]string {
 	return nil
 }
 
+func (x *ContainerResource) GetSizeRw() int64 {
+	if x != nil {
+		return x.SizeRw
+	}
+	return 0
+}
+
 // ImageResource represents an image
 type ImageResource struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1204,7 +2901,7 @@ type ImageResource struct {
 
 func (x *ImageResource) Reset() {
 	*x = ImageResource{}
-	mi := &file_proto_migrate_proto_msgTypes[9]
+	mi := &file_proto_migrate_proto_msgTypes[37]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1216,7 +2913,7 @@ func (x *ImageResource) String() string {
 func (*ImageResource) ProtoMessage() {}
 
 func (x *ImageResource) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[9]
+	mi := &file_proto_migrate_proto_msgTypes[37]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1229,7 +2926,7 @@ func (x *ImageResource) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ImageResource.ProtoReflect.Descriptor instead.
 func (*ImageResource) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{9}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{37}
 }
 
 func (x *ImageResource) GetId() string {
@@ -1281,7 +2978,7 @@ type VolumeResource struct {
 
 func (x *VolumeResource) Reset() {
 	*x = VolumeResource{}
-	mi := &file_proto_migrate_proto_msgTypes[10]
+	mi := &file_proto_migrate_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1293,7 +2990,7 @@ func (x *VolumeResource) String() string {
 func (*VolumeResource) ProtoMessage() {}
 
 func (x *VolumeResource) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[10]
+	mi := &file_proto_migrate_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1306,7 +3003,7 @@ func (x *VolumeResource) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VolumeResource.ProtoReflect.Descriptor instead.
 func (*VolumeResource) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{10}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{38}
 }
 
 func (x *VolumeResource) GetName() string {
@@ -1353,13 +3050,14 @@ type NetworkResource struct {
 	Scope          string                 `protobuf:"bytes,4,opt,name=scope,proto3" json:"scope,omitempty"`
 	Internal       bool                   `protobuf:"varint,5,opt,name=internal,proto3" json:"internal,omitempty"`
 	ContainerCount int32                  `protobuf:"varint,6,opt,name=container_count,json=containerCount,proto3" json:"container_count,omitempty"`
+	Subnets        []string               `protobuf:"bytes,7,rep,name=subnets,proto3" json:"subnets,omitempty"` // IPAM subnets, so the master can avoid them
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
 func (x *NetworkResource) Reset() {
 	*x = NetworkResource{}
-	mi := &file_proto_migrate_proto_msgTypes[11]
+	mi := &file_proto_migrate_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1371,7 +3069,7 @@ func (x *NetworkResource) String() string {
 func (*NetworkResource) ProtoMessage() {}
 
 func (x *NetworkResource) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[11]
+	mi := &file_proto_migrate_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1384,7 +3082,7 @@ func (x *NetworkResource) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NetworkResource.ProtoReflect.Descriptor instead.
 func (*NetworkResource) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{11}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{39}
 }
 
 func (x *NetworkResource) GetId() string {
@@ -1429,6 +3127,13 @@ func (x *NetworkResource) GetContainerCount() int32 {
 	return 0
 }
 
+func (x *NetworkResource) GetSubnets() []string {
+	if x != nil {
+		return x.Subnets
+	}
+	return nil
+}
+
 // Empty message for requests with no parameters
 type Empty struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1438,7 +3143,7 @@ type Empty struct {
 
 func (x *Empty) Reset() {
 	*x = Empty{}
-	mi := &file_proto_migrate_proto_msgTypes[12]
+	mi := &file_proto_migrate_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1450,7 +3155,7 @@ func (x *Empty) String() string {
 func (*Empty) ProtoMessage() {}
 
 func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[12]
+	mi := &file_proto_migrate_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1463,7 +3168,161 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Empty.ProtoReflect.Descriptor instead.
 func (*Empty) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{12}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{40}
+}
+
+// DiskUsageCategory summarises one kind of Docker object
+type DiskUsageCategory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Active        int32                  `protobuf:"varint,2,opt,name=active,proto3" json:"active,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`               // Bytes used
+	Reclaimable   int64                  `protobuf:"varint,4,opt,name=reclaimable,proto3" json:"reclaimable,omitempty"` // Bytes a prune could free
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiskUsageCategory) Reset() {
+	*x = DiskUsageCategory{}
+	mi := &file_proto_migrate_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiskUsageCategory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiskUsageCategory) ProtoMessage() {}
+
+func (x *DiskUsageCategory) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiskUsageCategory.ProtoReflect.Descriptor instead.
+func (*DiskUsageCategory) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *DiskUsageCategory) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *DiskUsageCategory) GetActive() int32 {
+	if x != nil {
+		return x.Active
+	}
+	return 0
+}
+
+func (x *DiskUsageCategory) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *DiskUsageCategory) GetReclaimable() int64 {
+	if x != nil {
+		return x.Reclaimable
+	}
+	return 0
+}
+
+// DiskUsageReport is the equivalent of `docker system df` plus free space
+type DiskUsageReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Images        *DiskUsageCategory     `protobuf:"bytes,1,opt,name=images,proto3" json:"images,omitempty"`
+	Containers    *DiskUsageCategory     `protobuf:"bytes,2,opt,name=containers,proto3" json:"containers,omitempty"`
+	Volumes       *DiskUsageCategory     `protobuf:"bytes,3,opt,name=volumes,proto3" json:"volumes,omitempty"`
+	BuildCache    *DiskUsageCategory     `protobuf:"bytes,4,opt,name=build_cache,json=buildCache,proto3" json:"build_cache,omitempty"`
+	DiskTotal     int64                  `protobuf:"varint,5,opt,name=disk_total,json=diskTotal,proto3" json:"disk_total,omitempty"`             // Bytes on the Docker data root filesystem
+	DiskAvailable int64                  `protobuf:"varint,6,opt,name=disk_available,json=diskAvailable,proto3" json:"disk_available,omitempty"` // Free bytes on the Docker data root filesystem
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiskUsageReport) Reset() {
+	*x = DiskUsageReport{}
+	mi := &file_proto_migrate_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiskUsageReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiskUsageReport) ProtoMessage() {}
+
+func (x *DiskUsageReport) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiskUsageReport.ProtoReflect.Descriptor instead.
+func (*DiskUsageReport) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *DiskUsageReport) GetImages() *DiskUsageCategory {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+func (x *DiskUsageReport) GetContainers() *DiskUsageCategory {
+	if x != nil {
+		return x.Containers
+	}
+	return nil
+}
+
+func (x *DiskUsageReport) GetVolumes() *DiskUsageCategory {
+	if x != nil {
+		return x.Volumes
+	}
+	return nil
+}
+
+func (x *DiskUsageReport) GetBuildCache() *DiskUsageCategory {
+	if x != nil {
+		return x.BuildCache
+	}
+	return nil
+}
+
+func (x *DiskUsageReport) GetDiskTotal() int64 {
+	if x != nil {
+		return x.DiskTotal
+	}
+	return 0
+}
+
+func (x *DiskUsageReport) GetDiskAvailable() int64 {
+	if x != nil {
+		return x.DiskAvailable
+	}
+	return 0
 }
 
 // Pong response for ping
@@ -1472,25 +3331,104 @@ type Pong struct {
 	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
 	Timestamp     int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	VolumeDrivers []string               `protobuf:"bytes,4,rep,name=volume_drivers,json=volumeDrivers,proto3" json:"volume_drivers,omitempty"`
+	TimestampMs   int64                  `protobuf:"varint,5,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"` // Responder's clock, Unix milliseconds, for skew estimation
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pong) Reset() {
+	*x = Pong{}
+	mi := &file_proto_migrate_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pong) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pong) ProtoMessage() {}
+
+func (x *Pong) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pong.ProtoReflect.Descriptor instead.
+func (*Pong) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *Pong) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *Pong) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Pong) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Pong) GetVolumeDrivers() []string {
+	if x != nil {
+		return x.VolumeDrivers
+	}
+	return nil
+}
+
+func (x *Pong) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+// PairingExchange carries one side of a pairing exchange. The certificate
+// must be the one presented in the TLS handshake.
+type PairingExchange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey     []byte                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`          // Ephemeral X25519 public key
+	CodeVerifier  []byte                 `protobuf:"bytes,2,opt,name=code_verifier,json=codeVerifier,proto3" json:"code_verifier,omitempty"` // Proves knowledge of the pairing code
+	Certificate   []byte                 `protobuf:"bytes,3,opt,name=certificate,proto3" json:"certificate,omitempty"`                       // PEM encoded certificate
+	GrpcPort      int32                  `protobuf:"varint,4,opt,name=grpc_port,json=grpcPort,proto3" json:"grpc_port,omitempty"`            // Port the sender's gRPC server listens on
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Pong) Reset() {
-	*x = Pong{}
-	mi := &file_proto_migrate_proto_msgTypes[13]
+func (x *PairingExchange) Reset() {
+	*x = PairingExchange{}
+	mi := &file_proto_migrate_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Pong) String() string {
+func (x *PairingExchange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Pong) ProtoMessage() {}
+func (*PairingExchange) ProtoMessage() {}
 
-func (x *Pong) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[13]
+func (x *PairingExchange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1501,49 +3439,60 @@ func (x *Pong) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Pong.ProtoReflect.Descriptor instead.
-func (*Pong) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{13}
+// Deprecated: Use PairingExchange.ProtoReflect.Descriptor instead.
+func (*PairingExchange) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{44}
 }
 
-func (x *Pong) GetPeerId() string {
+func (x *PairingExchange) GetPublicKey() []byte {
 	if x != nil {
-		return x.PeerId
+		return x.PublicKey
 	}
-	return ""
+	return nil
 }
 
-func (x *Pong) GetTimestamp() int64 {
+func (x *PairingExchange) GetCodeVerifier() []byte {
 	if x != nil {
-		return x.Timestamp
+		return x.CodeVerifier
 	}
-	return 0
+	return nil
 }
 
-func (x *Pong) GetVersion() string {
+func (x *PairingExchange) GetCertificate() []byte {
 	if x != nil {
-		return x.Version
+		return x.Certificate
 	}
-	return ""
+	return nil
+}
+
+func (x *PairingExchange) GetGrpcPort() int32 {
+	if x != nil {
+		return x.GrpcPort
+	}
+	return 0
 }
 
 // WorkerRegistration is sent by worker to register with master
 type WorkerRegistration struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	EnrollmentToken string                 `protobuf:"bytes,1,opt,name=enrollment_token,json=enrollmentToken,proto3" json:"enrollment_token,omitempty"`                                  // Token provided by master for enrollment
-	WorkerName      string                 `protobuf:"bytes,2,opt,name=worker_name,json=workerName,proto3" json:"worker_name,omitempty"`                                                 // Human-readable worker name
-	Hostname        string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`                                                                       // Worker's hostname
-	GrpcAddress     string                 `protobuf:"bytes,4,opt,name=grpc_address,json=grpcAddress,proto3" json:"grpc_address,omitempty"`                                              // Address where worker's gRPC server listens
-	TlsFingerprint  string                 `protobuf:"bytes,5,opt,name=tls_fingerprint,json=tlsFingerprint,proto3" json:"tls_fingerprint,omitempty"`                                     // Worker's TLS certificate fingerprint
-	Labels          map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Worker labels for filtering
-	Version         string                 `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`                                                                         // docker-migrate version
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	EnrollmentToken    string                 `protobuf:"bytes,1,opt,name=enrollment_token,json=enrollmentToken,proto3" json:"enrollment_token,omitempty"`                                  // Token provided by master for enrollment
+	WorkerName         string                 `protobuf:"bytes,2,opt,name=worker_name,json=workerName,proto3" json:"worker_name,omitempty"`                                                 // Human-readable worker name
+	Hostname           string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`                                                                       // Worker's hostname
+	GrpcAddress        string                 `protobuf:"bytes,4,opt,name=grpc_address,json=grpcAddress,proto3" json:"grpc_address,omitempty"`                                              // Address where worker's gRPC server listens
+	TlsFingerprint     string                 `protobuf:"bytes,5,opt,name=tls_fingerprint,json=tlsFingerprint,proto3" json:"tls_fingerprint,omitempty"`                                     // Worker's TLS certificate fingerprint
+	Labels             map[string]string      `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Worker labels for filtering
+	Version            string                 `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`                                                                         // docker-migrate version
+	OutboundOnly       bool                   `protobuf:"varint,8,opt,name=outbound_only,json=outboundOnly,proto3" json:"outbound_only,omitempty"`                                          // Worker accepts no inbound connections; transfers go via the master proxy
+	ProtocolVersion    int32                  `protobuf:"varint,9,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`                                 // Highest protocol version the worker speaks (0 = 1, pre-versioning)
+	MinProtocolVersion int32                  `protobuf:"varint,10,opt,name=min_protocol_version,json=minProtocolVersion,proto3" json:"min_protocol_version,omitempty"`                     // Lowest protocol version the worker accepts
+	TimestampMs        int64                  `protobuf:"varint,11,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"`                                            // Worker's clock when sending, Unix milliseconds, for skew estimation
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *WorkerRegistration) Reset() {
 	*x = WorkerRegistration{}
-	mi := &file_proto_migrate_proto_msgTypes[14]
+	mi := &file_proto_migrate_proto_msgTypes[45]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1555,7 +3504,7 @@ func (x *WorkerRegistration) String() string {
 func (*WorkerRegistration) ProtoMessage() {}
 
 func (x *WorkerRegistration) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[14]
+	mi := &file_proto_migrate_proto_msgTypes[45]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1568,7 +3517,7 @@ func (x *WorkerRegistration) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WorkerRegistration.ProtoReflect.Descriptor instead.
 func (*WorkerRegistration) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{14}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{45}
 }
 
 func (x *WorkerRegistration) GetEnrollmentToken() string {
@@ -1620,6 +3569,34 @@ func (x *WorkerRegistration) GetVersion() string {
 	return ""
 }
 
+func (x *WorkerRegistration) GetOutboundOnly() bool {
+	if x != nil {
+		return x.OutboundOnly
+	}
+	return false
+}
+
+func (x *WorkerRegistration) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *WorkerRegistration) GetMinProtocolVersion() int32 {
+	if x != nil {
+		return x.MinProtocolVersion
+	}
+	return 0
+}
+
+func (x *WorkerRegistration) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
 // RegistrationResponse confirms worker registration
 type RegistrationResponse struct {
 	state               protoimpl.MessageState `protogen:"open.v1"`
@@ -1629,13 +3606,15 @@ type RegistrationResponse struct {
 	AuthToken           string                 `protobuf:"bytes,4,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`                                  // Token for subsequent authentication
 	HeartbeatIntervalMs int64                  `protobuf:"varint,5,opt,name=heartbeat_interval_ms,json=heartbeatIntervalMs,proto3" json:"heartbeat_interval_ms,omitempty"` // How often worker should heartbeat
 	InventoryIntervalMs int64                  `protobuf:"varint,6,opt,name=inventory_interval_ms,json=inventoryIntervalMs,proto3" json:"inventory_interval_ms,omitempty"` // How often to report inventory
+	ProtocolVersion     int32                  `protobuf:"varint,7,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`               // Negotiated protocol version (0 = 1, pre-versioning master)
+	MasterFingerprint   string                 `protobuf:"bytes,8,opt,name=master_fingerprint,json=masterFingerprint,proto3" json:"master_fingerprint,omitempty"`          // SHA-256 fingerprint of the master's TLS certificate, for the worker to pin
 	unknownFields       protoimpl.UnknownFields
 	sizeCache           protoimpl.SizeCache
 }
 
 func (x *RegistrationResponse) Reset() {
 	*x = RegistrationResponse{}
-	mi := &file_proto_migrate_proto_msgTypes[15]
+	mi := &file_proto_migrate_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1647,7 +3626,7 @@ func (x *RegistrationResponse) String() string {
 func (*RegistrationResponse) ProtoMessage() {}
 
 func (x *RegistrationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[15]
+	mi := &file_proto_migrate_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1660,7 +3639,7 @@ func (x *RegistrationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RegistrationResponse.ProtoReflect.Descriptor instead.
 func (*RegistrationResponse) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{15}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{46}
 }
 
 func (x *RegistrationResponse) GetSuccess() bool {
@@ -1705,6 +3684,20 @@ func (x *RegistrationResponse) GetInventoryIntervalMs() int64 {
 	return 0
 }
 
+func (x *RegistrationResponse) GetProtocolVersion() int32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *RegistrationResponse) GetMasterFingerprint() string {
+	if x != nil {
+		return x.MasterFingerprint
+	}
+	return ""
+}
+
 // WorkerMessage is sent from worker to master on the stream
 type WorkerMessage struct {
 	state     protoimpl.MessageState `protogen:"open.v1"`
@@ -1716,6 +3709,7 @@ type WorkerMessage struct {
 	//	*WorkerMessage_MigrationProgress
 	//	*WorkerMessage_MigrationComplete
 	//	*WorkerMessage_WorkerError
+	//	*WorkerMessage_TransferFallback
 	Payload       isWorkerMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1723,7 +3717,7 @@ type WorkerMessage struct {
 
 func (x *WorkerMessage) Reset() {
 	*x = WorkerMessage{}
-	mi := &file_proto_migrate_proto_msgTypes[16]
+	mi := &file_proto_migrate_proto_msgTypes[47]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1735,7 +3729,7 @@ func (x *WorkerMessage) String() string {
 func (*WorkerMessage) ProtoMessage() {}
 
 func (x *WorkerMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[16]
+	mi := &file_proto_migrate_proto_msgTypes[47]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1748,7 +3742,7 @@ func (x *WorkerMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WorkerMessage.ProtoReflect.Descriptor instead.
 func (*WorkerMessage) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{16}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{47}
 }
 
 func (x *WorkerMessage) GetWorkerId() string {
@@ -1808,6 +3802,15 @@ func (x *WorkerMessage) GetWorkerError() *WorkerError {
 	return nil
 }
 
+func (x *WorkerMessage) GetTransferFallback() *TransferFallback {
+	if x != nil {
+		if x, ok := x.Payload.(*WorkerMessage_TransferFallback); ok {
+			return x.TransferFallback
+		}
+	}
+	return nil
+}
+
 type isWorkerMessage_Payload interface {
 	isWorkerMessage_Payload()
 }
@@ -1828,6 +3831,10 @@ type WorkerMessage_WorkerError struct {
 	WorkerError *WorkerError `protobuf:"bytes,6,opt,name=worker_error,json=workerError,proto3,oneof"`
 }
 
+type WorkerMessage_TransferFallback struct {
+	TransferFallback *TransferFallback `protobuf:"bytes,7,opt,name=transfer_fallback,json=transferFallback,proto3,oneof"`
+}
+
 func (*WorkerMessage_Heartbeat) isWorkerMessage_Payload() {}
 
 func (*WorkerMessage_MigrationProgress) isWorkerMessage_Payload() {}
@@ -1836,6 +3843,8 @@ func (*WorkerMessage_MigrationComplete) isWorkerMessage_Payload() {}
 
 func (*WorkerMessage_WorkerError) isWorkerMessage_Payload() {}
 
+func (*WorkerMessage_TransferFallback) isWorkerMessage_Payload() {}
+
 // MasterCommand is sent from master to worker on the stream
 type MasterCommand struct {
 	state     protoimpl.MessageState `protogen:"open.v1"`
@@ -1847,6 +3856,8 @@ type MasterCommand struct {
 	//	*MasterCommand_CancelMigration
 	//	*MasterCommand_UpdateConfig
 	//	*MasterCommand_Shutdown
+	//	*MasterCommand_RotateAuthToken
+	//	*MasterCommand_ProxyFallback
 	Payload       isMasterCommand_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -1854,7 +3865,7 @@ type MasterCommand struct {
 
 func (x *MasterCommand) Reset() {
 	*x = MasterCommand{}
-	mi := &file_proto_migrate_proto_msgTypes[17]
+	mi := &file_proto_migrate_proto_msgTypes[48]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1866,7 +3877,7 @@ func (x *MasterCommand) String() string {
 func (*MasterCommand) ProtoMessage() {}
 
 func (x *MasterCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[17]
+	mi := &file_proto_migrate_proto_msgTypes[48]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1879,7 +3890,7 @@ func (x *MasterCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MasterCommand.ProtoReflect.Descriptor instead.
 func (*MasterCommand) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{17}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{48}
 }
 
 func (x *MasterCommand) GetCommandId() string {
@@ -1941,6 +3952,24 @@ func (x *MasterCommand) GetShutdown() *ShutdownCommand {
 	return nil
 }
 
+func (x *MasterCommand) GetRotateAuthToken() *RotateAuthTokenCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*MasterCommand_RotateAuthToken); ok {
+			return x.RotateAuthToken
+		}
+	}
+	return nil
+}
+
+func (x *MasterCommand) GetProxyFallback() *ProxyFallbackCommand {
+	if x != nil {
+		if x, ok := x.Payload.(*MasterCommand_ProxyFallback); ok {
+			return x.ProxyFallback
+		}
+	}
+	return nil
+}
+
 type isMasterCommand_Payload interface {
 	isMasterCommand_Payload()
 }
@@ -1965,6 +3994,14 @@ type MasterCommand_Shutdown struct {
 	Shutdown *ShutdownCommand `protobuf:"bytes,6,opt,name=shutdown,proto3,oneof"`
 }
 
+type MasterCommand_RotateAuthToken struct {
+	RotateAuthToken *RotateAuthTokenCommand `protobuf:"bytes,7,opt,name=rotate_auth_token,json=rotateAuthToken,proto3,oneof"`
+}
+
+type MasterCommand_ProxyFallback struct {
+	ProxyFallback *ProxyFallbackCommand `protobuf:"bytes,8,opt,name=proxy_fallback,json=proxyFallback,proto3,oneof"`
+}
+
 func (*MasterCommand_HeartbeatAck) isMasterCommand_Payload() {}
 
 func (*MasterCommand_StartMigration) isMasterCommand_Payload() {}
@@ -1975,6 +4012,10 @@ func (*MasterCommand_UpdateConfig) isMasterCommand_Payload() {}
 
 func (*MasterCommand_Shutdown) isMasterCommand_Payload() {}
 
+func (*MasterCommand_RotateAuthToken) isMasterCommand_Payload() {}
+
+func (*MasterCommand_ProxyFallback) isMasterCommand_Payload() {}
+
 // Heartbeat sent periodically by worker
 type Heartbeat struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
@@ -1988,7 +4029,7 @@ type Heartbeat struct {
 
 func (x *Heartbeat) Reset() {
 	*x = Heartbeat{}
-	mi := &file_proto_migrate_proto_msgTypes[18]
+	mi := &file_proto_migrate_proto_msgTypes[49]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2000,7 +4041,7 @@ func (x *Heartbeat) String() string {
 func (*Heartbeat) ProtoMessage() {}
 
 func (x *Heartbeat) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[18]
+	mi := &file_proto_migrate_proto_msgTypes[49]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2013,7 +4054,7 @@ func (x *Heartbeat) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Heartbeat.ProtoReflect.Descriptor instead.
 func (*Heartbeat) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{18}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{49}
 }
 
 func (x *Heartbeat) GetTimestamp() int64 {
@@ -2055,7 +4096,7 @@ type HeartbeatAck struct {
 
 func (x *HeartbeatAck) Reset() {
 	*x = HeartbeatAck{}
-	mi := &file_proto_migrate_proto_msgTypes[19]
+	mi := &file_proto_migrate_proto_msgTypes[50]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2067,7 +4108,7 @@ func (x *HeartbeatAck) String() string {
 func (*HeartbeatAck) ProtoMessage() {}
 
 func (x *HeartbeatAck) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[19]
+	mi := &file_proto_migrate_proto_msgTypes[50]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2080,7 +4121,7 @@ func (x *HeartbeatAck) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HeartbeatAck.ProtoReflect.Descriptor instead.
 func (*HeartbeatAck) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{19}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{50}
 }
 
 func (x *HeartbeatAck) GetTimestamp() int64 {
@@ -2113,7 +4154,7 @@ type SystemResources struct {
 
 func (x *SystemResources) Reset() {
 	*x = SystemResources{}
-	mi := &file_proto_migrate_proto_msgTypes[20]
+	mi := &file_proto_migrate_proto_msgTypes[51]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2125,7 +4166,7 @@ func (x *SystemResources) String() string {
 func (*SystemResources) ProtoMessage() {}
 
 func (x *SystemResources) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[20]
+	mi := &file_proto_migrate_proto_msgTypes[51]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2138,7 +4179,7 @@ func (x *SystemResources) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SystemResources.ProtoReflect.Descriptor instead.
 func (*SystemResources) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{20}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{51}
 }
 
 func (x *SystemResources) GetCpuPercent() int64 {
@@ -2200,13 +4241,14 @@ type ResourceInventory struct {
 	Images        []*ImageResource       `protobuf:"bytes,5,rep,name=images,proto3" json:"images,omitempty"`
 	Volumes       []*VolumeResource      `protobuf:"bytes,6,rep,name=volumes,proto3" json:"volumes,omitempty"`
 	Networks      []*NetworkResource     `protobuf:"bytes,7,rep,name=networks,proto3" json:"networks,omitempty"`
+	DiskUsage     *DiskUsageReport       `protobuf:"bytes,8,opt,name=disk_usage,json=diskUsage,proto3" json:"disk_usage,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ResourceInventory) Reset() {
 	*x = ResourceInventory{}
-	mi := &file_proto_migrate_proto_msgTypes[21]
+	mi := &file_proto_migrate_proto_msgTypes[52]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2218,7 +4260,7 @@ func (x *ResourceInventory) String() string {
 func (*ResourceInventory) ProtoMessage() {}
 
 func (x *ResourceInventory) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[21]
+	mi := &file_proto_migrate_proto_msgTypes[52]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2231,7 +4273,7 @@ func (x *ResourceInventory) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ResourceInventory.ProtoReflect.Descriptor instead.
 func (*ResourceInventory) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{21}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{52}
 }
 
 func (x *ResourceInventory) GetWorkerId() string {
@@ -2252,35 +4294,220 @@ func (x *ResourceInventory) GetTimestamp() int64 {
 	if x != nil {
 		return x.Timestamp
 	}
-	return 0
+	return 0
+}
+
+func (x *ResourceInventory) GetContainers() []*ContainerResource {
+	if x != nil {
+		return x.Containers
+	}
+	return nil
+}
+
+func (x *ResourceInventory) GetImages() []*ImageResource {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+func (x *ResourceInventory) GetVolumes() []*VolumeResource {
+	if x != nil {
+		return x.Volumes
+	}
+	return nil
+}
+
+func (x *ResourceInventory) GetNetworks() []*NetworkResource {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
+func (x *ResourceInventory) GetDiskUsage() *DiskUsageReport {
+	if x != nil {
+		return x.DiskUsage
+	}
+	return nil
+}
+
+// WorkerMigrationRequest is a worker-initiated request to migrate its resources
+type WorkerMigrationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WorkerId      string                 `protobuf:"bytes,1,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	AuthToken     string                 `protobuf:"bytes,2,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	TargetWorker  string                 `protobuf:"bytes,3,opt,name=target_worker,json=targetWorker,proto3" json:"target_worker,omitempty"` // Target worker ID or name
+	ContainerIds  []string               `protobuf:"bytes,4,rep,name=container_ids,json=containerIds,proto3" json:"container_ids,omitempty"`
+	ImageIds      []string               `protobuf:"bytes,5,rep,name=image_ids,json=imageIds,proto3" json:"image_ids,omitempty"`
+	VolumeNames   []string               `protobuf:"bytes,6,rep,name=volume_names,json=volumeNames,proto3" json:"volume_names,omitempty"`
+	NetworkIds    []string               `protobuf:"bytes,7,rep,name=network_ids,json=networkIds,proto3" json:"network_ids,omitempty"`
+	Mode          MigrationMode          `protobuf:"varint,8,opt,name=mode,proto3,enum=migrate.MigrationMode" json:"mode,omitempty"`
+	Strategy      MigrationStrategy      `protobuf:"varint,9,opt,name=strategy,proto3,enum=migrate.MigrationStrategy" json:"strategy,omitempty"`
+	Note          string                 `protobuf:"bytes,10,opt,name=note,proto3" json:"note,omitempty"` // Shown to the approving admin
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WorkerMigrationRequest) Reset() {
+	*x = WorkerMigrationRequest{}
+	mi := &file_proto_migrate_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WorkerMigrationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkerMigrationRequest) ProtoMessage() {}
+
+func (x *WorkerMigrationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WorkerMigrationRequest.ProtoReflect.Descriptor instead.
+func (*WorkerMigrationRequest) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *WorkerMigrationRequest) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *WorkerMigrationRequest) GetAuthToken() string {
+	if x != nil {
+		return x.AuthToken
+	}
+	return ""
+}
+
+func (x *WorkerMigrationRequest) GetTargetWorker() string {
+	if x != nil {
+		return x.TargetWorker
+	}
+	return ""
+}
+
+func (x *WorkerMigrationRequest) GetContainerIds() []string {
+	if x != nil {
+		return x.ContainerIds
+	}
+	return nil
+}
+
+func (x *WorkerMigrationRequest) GetImageIds() []string {
+	if x != nil {
+		return x.ImageIds
+	}
+	return nil
+}
+
+func (x *WorkerMigrationRequest) GetVolumeNames() []string {
+	if x != nil {
+		return x.VolumeNames
+	}
+	return nil
+}
+
+func (x *WorkerMigrationRequest) GetNetworkIds() []string {
+	if x != nil {
+		return x.NetworkIds
+	}
+	return nil
+}
+
+func (x *WorkerMigrationRequest) GetMode() MigrationMode {
+	if x != nil {
+		return x.Mode
+	}
+	return MigrationMode_MIGRATION_MODE_COLD
+}
+
+func (x *WorkerMigrationRequest) GetStrategy() MigrationStrategy {
+	if x != nil {
+		return x.Strategy
+	}
+	return MigrationStrategy_MIGRATION_STRATEGY_FULL
+}
+
+func (x *WorkerMigrationRequest) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+// WorkerMigrationRequestResponse returns the pending migration's ID
+type WorkerMigrationRequestResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	MigrationId   string                 `protobuf:"bytes,3,opt,name=migration_id,json=migrationId,proto3" json:"migration_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WorkerMigrationRequestResponse) Reset() {
+	*x = WorkerMigrationRequestResponse{}
+	mi := &file_proto_migrate_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WorkerMigrationRequestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WorkerMigrationRequestResponse) ProtoMessage() {}
+
+func (x *WorkerMigrationRequestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
-func (x *ResourceInventory) GetContainers() []*ContainerResource {
-	if x != nil {
-		return x.Containers
-	}
-	return nil
+// Deprecated: Use WorkerMigrationRequestResponse.ProtoReflect.Descriptor instead.
+func (*WorkerMigrationRequestResponse) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{54}
 }
 
-func (x *ResourceInventory) GetImages() []*ImageResource {
+func (x *WorkerMigrationRequestResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Images
+		return x.Success
 	}
-	return nil
+	return false
 }
 
-func (x *ResourceInventory) GetVolumes() []*VolumeResource {
+func (x *WorkerMigrationRequestResponse) GetError() string {
 	if x != nil {
-		return x.Volumes
+		return x.Error
 	}
-	return nil
+	return ""
 }
 
-func (x *ResourceInventory) GetNetworks() []*NetworkResource {
+func (x *WorkerMigrationRequestResponse) GetMigrationId() string {
 	if x != nil {
-		return x.Networks
+		return x.MigrationId
 	}
-	return nil
+	return ""
 }
 
 // AckResponse is a simple acknowledgment
@@ -2294,7 +4521,7 @@ type AckResponse struct {
 
 func (x *AckResponse) Reset() {
 	*x = AckResponse{}
-	mi := &file_proto_migrate_proto_msgTypes[22]
+	mi := &file_proto_migrate_proto_msgTypes[55]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2306,7 +4533,7 @@ func (x *AckResponse) String() string {
 func (*AckResponse) ProtoMessage() {}
 
 func (x *AckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[22]
+	mi := &file_proto_migrate_proto_msgTypes[55]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2319,7 +4546,7 @@ func (x *AckResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AckResponse.ProtoReflect.Descriptor instead.
 func (*AckResponse) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{22}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{55}
 }
 
 func (x *AckResponse) GetSuccess() bool {
@@ -2351,13 +4578,15 @@ type MigrationRequest struct {
 	Strategy          MigrationStrategy      `protobuf:"varint,10,opt,name=strategy,proto3,enum=migrate.MigrationStrategy" json:"strategy,omitempty"`
 	TransferMode      TransferMode           `protobuf:"varint,11,opt,name=transfer_mode,json=transferMode,proto3,enum=migrate.TransferMode" json:"transfer_mode,omitempty"` // How to transfer data
 	ProxyAddress      string                 `protobuf:"bytes,12,opt,name=proxy_address,json=proxyAddress,proto3" json:"proxy_address,omitempty"`                            // Master's proxy address (for proxy mode)
+	ProxyNonce        string                 `protobuf:"bytes,13,opt,name=proxy_nonce,json=proxyNonce,proto3" json:"proxy_nonce,omitempty"`                                  // One-time nonce for the proxy handshake
+	ImageRegistry     *ImageRegistry         `protobuf:"bytes,14,opt,name=image_registry,json=imageRegistry,proto3" json:"image_registry,omitempty"`                         // Set to send images through an intermediary registry
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
 
 func (x *MigrationRequest) Reset() {
 	*x = MigrationRequest{}
-	mi := &file_proto_migrate_proto_msgTypes[23]
+	mi := &file_proto_migrate_proto_msgTypes[56]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2369,7 +4598,7 @@ func (x *MigrationRequest) String() string {
 func (*MigrationRequest) ProtoMessage() {}
 
 func (x *MigrationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[23]
+	mi := &file_proto_migrate_proto_msgTypes[56]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2382,7 +4611,7 @@ func (x *MigrationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MigrationRequest.ProtoReflect.Descriptor instead.
 func (*MigrationRequest) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{23}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{56}
 }
 
 func (x *MigrationRequest) GetMigrationId() string {
@@ -2469,6 +4698,73 @@ func (x *MigrationRequest) GetProxyAddress() string {
 	return ""
 }
 
+func (x *MigrationRequest) GetProxyNonce() string {
+	if x != nil {
+		return x.ProxyNonce
+	}
+	return ""
+}
+
+func (x *MigrationRequest) GetImageRegistry() *ImageRegistry {
+	if x != nil {
+		return x.ImageRegistry
+	}
+	return nil
+}
+
+// ImageRegistry is an intermediary registry images are pushed to and pulled from
+type ImageRegistry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`                               // Registry and repository prefix, e.g. registry.internal:5000/migrate
+	RegistryAuth  string                 `protobuf:"bytes,2,opt,name=registry_auth,json=registryAuth,proto3" json:"registry_auth,omitempty"` // Base64 Docker registry credentials; empty for an open registry
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImageRegistry) Reset() {
+	*x = ImageRegistry{}
+	mi := &file_proto_migrate_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImageRegistry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImageRegistry) ProtoMessage() {}
+
+func (x *ImageRegistry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImageRegistry.ProtoReflect.Descriptor instead.
+func (*ImageRegistry) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *ImageRegistry) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *ImageRegistry) GetRegistryAuth() string {
+	if x != nil {
+		return x.RegistryAuth
+	}
+	return ""
+}
+
 // MigrationResponse acknowledges migration request
 type MigrationResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -2481,7 +4777,7 @@ type MigrationResponse struct {
 
 func (x *MigrationResponse) Reset() {
 	*x = MigrationResponse{}
-	mi := &file_proto_migrate_proto_msgTypes[24]
+	mi := &file_proto_migrate_proto_msgTypes[58]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2493,7 +4789,7 @@ func (x *MigrationResponse) String() string {
 func (*MigrationResponse) ProtoMessage() {}
 
 func (x *MigrationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[24]
+	mi := &file_proto_migrate_proto_msgTypes[58]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2506,7 +4802,7 @@ func (x *MigrationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MigrationResponse.ProtoReflect.Descriptor instead.
 func (*MigrationResponse) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{24}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{58}
 }
 
 func (x *MigrationResponse) GetAccepted() bool {
@@ -2543,13 +4839,15 @@ type AcceptMigrationRequest struct {
 	NetworkIds        []string               `protobuf:"bytes,8,rep,name=network_ids,json=networkIds,proto3" json:"network_ids,omitempty"`
 	TransferMode      TransferMode           `protobuf:"varint,9,opt,name=transfer_mode,json=transferMode,proto3,enum=migrate.TransferMode" json:"transfer_mode,omitempty"` // How to transfer data
 	ProxyAddress      string                 `protobuf:"bytes,10,opt,name=proxy_address,json=proxyAddress,proto3" json:"proxy_address,omitempty"`                           // Master's proxy address (for proxy mode)
+	ProxyNonce        string                 `protobuf:"bytes,11,opt,name=proxy_nonce,json=proxyNonce,proto3" json:"proxy_nonce,omitempty"`                                 // One-time nonce for the proxy handshake
+	Networks          []*NetworkSpec         `protobuf:"bytes,12,rep,name=networks,proto3" json:"networks,omitempty"`                                                       // Networks to create before receiving data
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
 
 func (x *AcceptMigrationRequest) Reset() {
 	*x = AcceptMigrationRequest{}
-	mi := &file_proto_migrate_proto_msgTypes[25]
+	mi := &file_proto_migrate_proto_msgTypes[59]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2561,7 +4859,7 @@ func (x *AcceptMigrationRequest) String() string {
 func (*AcceptMigrationRequest) ProtoMessage() {}
 
 func (x *AcceptMigrationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[25]
+	mi := &file_proto_migrate_proto_msgTypes[59]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2574,7 +4872,7 @@ func (x *AcceptMigrationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AcceptMigrationRequest.ProtoReflect.Descriptor instead.
 func (*AcceptMigrationRequest) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{25}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{59}
 }
 
 func (x *AcceptMigrationRequest) GetMigrationId() string {
@@ -2647,6 +4945,105 @@ func (x *AcceptMigrationRequest) GetProxyAddress() string {
 	return ""
 }
 
+func (x *AcceptMigrationRequest) GetProxyNonce() string {
+	if x != nil {
+		return x.ProxyNonce
+	}
+	return ""
+}
+
+func (x *AcceptMigrationRequest) GetNetworks() []*NetworkSpec {
+	if x != nil {
+		return x.Networks
+	}
+	return nil
+}
+
+// NetworkSpec is a network the target creates, with a subnet chosen by the master
+type NetworkSpec struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SourceId      string                 `protobuf:"bytes,1,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Driver        string                 `protobuf:"bytes,3,opt,name=driver,proto3" json:"driver,omitempty"`
+	Internal      bool                   `protobuf:"varint,4,opt,name=internal,proto3" json:"internal,omitempty"`
+	Subnet        string                 `protobuf:"bytes,5,opt,name=subnet,proto3" json:"subnet,omitempty"` // Empty lets Docker pick one
+	Gateway       string                 `protobuf:"bytes,6,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NetworkSpec) Reset() {
+	*x = NetworkSpec{}
+	mi := &file_proto_migrate_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NetworkSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkSpec) ProtoMessage() {}
+
+func (x *NetworkSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkSpec.ProtoReflect.Descriptor instead.
+func (*NetworkSpec) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *NetworkSpec) GetSourceId() string {
+	if x != nil {
+		return x.SourceId
+	}
+	return ""
+}
+
+func (x *NetworkSpec) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NetworkSpec) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+func (x *NetworkSpec) GetInternal() bool {
+	if x != nil {
+		return x.Internal
+	}
+	return false
+}
+
+func (x *NetworkSpec) GetSubnet() string {
+	if x != nil {
+		return x.Subnet
+	}
+	return ""
+}
+
+func (x *NetworkSpec) GetGateway() string {
+	if x != nil {
+		return x.Gateway
+	}
+	return ""
+}
+
 // AcceptMigrationResponse confirms worker is ready to receive
 type AcceptMigrationResponse struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
@@ -2659,7 +5056,7 @@ type AcceptMigrationResponse struct {
 
 func (x *AcceptMigrationResponse) Reset() {
 	*x = AcceptMigrationResponse{}
-	mi := &file_proto_migrate_proto_msgTypes[26]
+	mi := &file_proto_migrate_proto_msgTypes[61]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2671,7 +5068,7 @@ func (x *AcceptMigrationResponse) String() string {
 func (*AcceptMigrationResponse) ProtoMessage() {}
 
 func (x *AcceptMigrationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[26]
+	mi := &file_proto_migrate_proto_msgTypes[61]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2684,7 +5081,7 @@ func (x *AcceptMigrationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AcceptMigrationResponse.ProtoReflect.Descriptor instead.
 func (*AcceptMigrationResponse) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{26}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{61}
 }
 
 func (x *AcceptMigrationResponse) GetAccepted() bool {
@@ -2722,7 +5119,7 @@ type HealthResponse struct {
 
 func (x *HealthResponse) Reset() {
 	*x = HealthResponse{}
-	mi := &file_proto_migrate_proto_msgTypes[27]
+	mi := &file_proto_migrate_proto_msgTypes[62]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2734,7 +5131,7 @@ func (x *HealthResponse) String() string {
 func (*HealthResponse) ProtoMessage() {}
 
 func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[27]
+	mi := &file_proto_migrate_proto_msgTypes[62]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2747,7 +5144,7 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
 func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{27}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{62}
 }
 
 func (x *HealthResponse) GetHealthy() bool {
@@ -2798,7 +5195,7 @@ type StartMigrationCommand struct {
 
 func (x *StartMigrationCommand) Reset() {
 	*x = StartMigrationCommand{}
-	mi := &file_proto_migrate_proto_msgTypes[28]
+	mi := &file_proto_migrate_proto_msgTypes[63]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2810,7 +5207,7 @@ func (x *StartMigrationCommand) String() string {
 func (*StartMigrationCommand) ProtoMessage() {}
 
 func (x *StartMigrationCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[28]
+	mi := &file_proto_migrate_proto_msgTypes[63]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2823,7 +5220,7 @@ func (x *StartMigrationCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StartMigrationCommand.ProtoReflect.Descriptor instead.
 func (*StartMigrationCommand) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{28}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{63}
 }
 
 func (x *StartMigrationCommand) GetRole() MigrationRole {
@@ -2865,7 +5262,7 @@ type CancelMigrationCommand struct {
 
 func (x *CancelMigrationCommand) Reset() {
 	*x = CancelMigrationCommand{}
-	mi := &file_proto_migrate_proto_msgTypes[29]
+	mi := &file_proto_migrate_proto_msgTypes[64]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2877,7 +5274,7 @@ func (x *CancelMigrationCommand) String() string {
 func (*CancelMigrationCommand) ProtoMessage() {}
 
 func (x *CancelMigrationCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[29]
+	mi := &file_proto_migrate_proto_msgTypes[64]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2890,7 +5287,7 @@ func (x *CancelMigrationCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelMigrationCommand.ProtoReflect.Descriptor instead.
 func (*CancelMigrationCommand) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{29}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{64}
 }
 
 func (x *CancelMigrationCommand) GetMigrationId() string {
@@ -2907,6 +5304,76 @@ func (x *CancelMigrationCommand) GetReason() string {
 	return ""
 }
 
+// ProxyFallbackCommand moves a running auto-mode migration onto the master's
+// proxy, sent to both workers after the source's direct connection failed
+type ProxyFallbackCommand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MigrationId   string                 `protobuf:"bytes,1,opt,name=migration_id,json=migrationId,proto3" json:"migration_id,omitempty"`
+	ProxyAddress  string                 `protobuf:"bytes,2,opt,name=proxy_address,json=proxyAddress,proto3" json:"proxy_address,omitempty"`
+	ProxyNonce    string                 `protobuf:"bytes,3,opt,name=proxy_nonce,json=proxyNonce,proto3" json:"proxy_nonce,omitempty"` // One-time nonce for this worker's proxy handshake
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                             // Set when the master will not relay; the source gives up
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProxyFallbackCommand) Reset() {
+	*x = ProxyFallbackCommand{}
+	mi := &file_proto_migrate_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProxyFallbackCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProxyFallbackCommand) ProtoMessage() {}
+
+func (x *ProxyFallbackCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProxyFallbackCommand.ProtoReflect.Descriptor instead.
+func (*ProxyFallbackCommand) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *ProxyFallbackCommand) GetMigrationId() string {
+	if x != nil {
+		return x.MigrationId
+	}
+	return ""
+}
+
+func (x *ProxyFallbackCommand) GetProxyAddress() string {
+	if x != nil {
+		return x.ProxyAddress
+	}
+	return ""
+}
+
+func (x *ProxyFallbackCommand) GetProxyNonce() string {
+	if x != nil {
+		return x.ProxyNonce
+	}
+	return ""
+}
+
+func (x *ProxyFallbackCommand) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 // CancelMigrationRequest for direct RPC
 type CancelMigrationRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -2918,7 +5385,7 @@ type CancelMigrationRequest struct {
 
 func (x *CancelMigrationRequest) Reset() {
 	*x = CancelMigrationRequest{}
-	mi := &file_proto_migrate_proto_msgTypes[30]
+	mi := &file_proto_migrate_proto_msgTypes[66]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2930,7 +5397,7 @@ func (x *CancelMigrationRequest) String() string {
 func (*CancelMigrationRequest) ProtoMessage() {}
 
 func (x *CancelMigrationRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[30]
+	mi := &file_proto_migrate_proto_msgTypes[66]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2943,7 +5410,7 @@ func (x *CancelMigrationRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelMigrationRequest.ProtoReflect.Descriptor instead.
 func (*CancelMigrationRequest) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{30}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{66}
 }
 
 func (x *CancelMigrationRequest) GetMigrationId() string {
@@ -2971,7 +5438,7 @@ type CancelMigrationResponse struct {
 
 func (x *CancelMigrationResponse) Reset() {
 	*x = CancelMigrationResponse{}
-	mi := &file_proto_migrate_proto_msgTypes[31]
+	mi := &file_proto_migrate_proto_msgTypes[67]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2983,7 +5450,7 @@ func (x *CancelMigrationResponse) String() string {
 func (*CancelMigrationResponse) ProtoMessage() {}
 
 func (x *CancelMigrationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[31]
+	mi := &file_proto_migrate_proto_msgTypes[67]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2996,7 +5463,7 @@ func (x *CancelMigrationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelMigrationResponse.ProtoReflect.Descriptor instead.
 func (*CancelMigrationResponse) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{31}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{67}
 }
 
 func (x *CancelMigrationResponse) GetSuccess() bool {
@@ -3025,7 +5492,7 @@ type UpdateConfigCommand struct {
 
 func (x *UpdateConfigCommand) Reset() {
 	*x = UpdateConfigCommand{}
-	mi := &file_proto_migrate_proto_msgTypes[32]
+	mi := &file_proto_migrate_proto_msgTypes[68]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3037,7 +5504,7 @@ func (x *UpdateConfigCommand) String() string {
 func (*UpdateConfigCommand) ProtoMessage() {}
 
 func (x *UpdateConfigCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[32]
+	mi := &file_proto_migrate_proto_msgTypes[68]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3050,7 +5517,7 @@ func (x *UpdateConfigCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateConfigCommand.ProtoReflect.Descriptor instead.
 func (*UpdateConfigCommand) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{32}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{68}
 }
 
 func (x *UpdateConfigCommand) GetHeartbeatIntervalMs() int64 {
@@ -3085,7 +5552,7 @@ type ShutdownCommand struct {
 
 func (x *ShutdownCommand) Reset() {
 	*x = ShutdownCommand{}
-	mi := &file_proto_migrate_proto_msgTypes[33]
+	mi := &file_proto_migrate_proto_msgTypes[69]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3097,7 +5564,7 @@ func (x *ShutdownCommand) String() string {
 func (*ShutdownCommand) ProtoMessage() {}
 
 func (x *ShutdownCommand) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[33]
+	mi := &file_proto_migrate_proto_msgTypes[69]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3110,7 +5577,7 @@ func (x *ShutdownCommand) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ShutdownCommand.ProtoReflect.Descriptor instead.
 func (*ShutdownCommand) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{33}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{69}
 }
 
 func (x *ShutdownCommand) GetReason() string {
@@ -3122,9 +5589,63 @@ func (x *ShutdownCommand) GetReason() string {
 
 func (x *ShutdownCommand) GetForce() bool {
 	if x != nil {
-		return x.Force
+		return x.Force
+	}
+	return false
+}
+
+// RotateAuthTokenCommand replaces the worker's auth token. The master keeps
+// accepting the old one until previous_valid_until.
+type RotateAuthTokenCommand struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	AuthToken          string                 `protobuf:"bytes,1,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`
+	PreviousValidUntil int64                  `protobuf:"varint,2,opt,name=previous_valid_until,json=previousValidUntil,proto3" json:"previous_valid_until,omitempty"` // Unix seconds
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *RotateAuthTokenCommand) Reset() {
+	*x = RotateAuthTokenCommand{}
+	mi := &file_proto_migrate_proto_msgTypes[70]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateAuthTokenCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateAuthTokenCommand) ProtoMessage() {}
+
+func (x *RotateAuthTokenCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[70]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateAuthTokenCommand.ProtoReflect.Descriptor instead.
+func (*RotateAuthTokenCommand) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{70}
+}
+
+func (x *RotateAuthTokenCommand) GetAuthToken() string {
+	if x != nil {
+		return x.AuthToken
+	}
+	return ""
+}
+
+func (x *RotateAuthTokenCommand) GetPreviousValidUntil() int64 {
+	if x != nil {
+		return x.PreviousValidUntil
 	}
-	return false
+	return 0
 }
 
 // MigrationProgress reports progress during migration
@@ -3137,13 +5658,14 @@ type MigrationProgress struct {
 	TotalBytes       int64                  `protobuf:"varint,5,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
 	CurrentResource  string                 `protobuf:"bytes,6,opt,name=current_resource,json=currentResource,proto3" json:"current_resource,omitempty"` // Resource currently being transferred
 	Message          string                 `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
+	TimestampMs      int64                  `protobuf:"varint,8,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"` // Worker's clock, Unix milliseconds
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
 
 func (x *MigrationProgress) Reset() {
 	*x = MigrationProgress{}
-	mi := &file_proto_migrate_proto_msgTypes[34]
+	mi := &file_proto_migrate_proto_msgTypes[71]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3155,7 +5677,7 @@ func (x *MigrationProgress) String() string {
 func (*MigrationProgress) ProtoMessage() {}
 
 func (x *MigrationProgress) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[34]
+	mi := &file_proto_migrate_proto_msgTypes[71]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3168,7 +5690,7 @@ func (x *MigrationProgress) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MigrationProgress.ProtoReflect.Descriptor instead.
 func (*MigrationProgress) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{34}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{71}
 }
 
 func (x *MigrationProgress) GetMigrationId() string {
@@ -3220,6 +5742,13 @@ func (x *MigrationProgress) GetMessage() string {
 	return ""
 }
 
+func (x *MigrationProgress) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
 // MigrationComplete reports migration completion
 type MigrationComplete struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
@@ -3229,13 +5758,14 @@ type MigrationComplete struct {
 	DurationMs        int64                  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
 	BytesTransferred  int64                  `protobuf:"varint,5,opt,name=bytes_transferred,json=bytesTransferred,proto3" json:"bytes_transferred,omitempty"`
 	ResourcesMigrated []string               `protobuf:"bytes,6,rep,name=resources_migrated,json=resourcesMigrated,proto3" json:"resources_migrated,omitempty"`
+	TimestampMs       int64                  `protobuf:"varint,7,opt,name=timestamp_ms,json=timestampMs,proto3" json:"timestamp_ms,omitempty"` // Worker's clock, Unix milliseconds
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
 
 func (x *MigrationComplete) Reset() {
 	*x = MigrationComplete{}
-	mi := &file_proto_migrate_proto_msgTypes[35]
+	mi := &file_proto_migrate_proto_msgTypes[72]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3247,7 +5777,7 @@ func (x *MigrationComplete) String() string {
 func (*MigrationComplete) ProtoMessage() {}
 
 func (x *MigrationComplete) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[35]
+	mi := &file_proto_migrate_proto_msgTypes[72]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3260,7 +5790,7 @@ func (x *MigrationComplete) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MigrationComplete.ProtoReflect.Descriptor instead.
 func (*MigrationComplete) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{35}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{72}
 }
 
 func (x *MigrationComplete) GetMigrationId() string {
@@ -3305,6 +5835,67 @@ func (x *MigrationComplete) GetResourcesMigrated() []string {
 	return nil
 }
 
+func (x *MigrationComplete) GetTimestampMs() int64 {
+	if x != nil {
+		return x.TimestampMs
+	}
+	return 0
+}
+
+// TransferFallback asks the master to relay an auto-mode migration whose
+// direct connection to the target failed
+type TransferFallback struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MigrationId   string                 `protobuf:"bytes,1,opt,name=migration_id,json=migrationId,proto3" json:"migration_id,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransferFallback) Reset() {
+	*x = TransferFallback{}
+	mi := &file_proto_migrate_proto_msgTypes[73]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransferFallback) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransferFallback) ProtoMessage() {}
+
+func (x *TransferFallback) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[73]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransferFallback.ProtoReflect.Descriptor instead.
+func (*TransferFallback) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{73}
+}
+
+func (x *TransferFallback) GetMigrationId() string {
+	if x != nil {
+		return x.MigrationId
+	}
+	return ""
+}
+
+func (x *TransferFallback) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
 // WorkerError reports an error from worker
 type WorkerError struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -3318,7 +5909,7 @@ type WorkerError struct {
 
 func (x *WorkerError) Reset() {
 	*x = WorkerError{}
-	mi := &file_proto_migrate_proto_msgTypes[36]
+	mi := &file_proto_migrate_proto_msgTypes[74]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3330,7 +5921,7 @@ func (x *WorkerError) String() string {
 func (*WorkerError) ProtoMessage() {}
 
 func (x *WorkerError) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[36]
+	mi := &file_proto_migrate_proto_msgTypes[74]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3343,7 +5934,7 @@ func (x *WorkerError) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WorkerError.ProtoReflect.Descriptor instead.
 func (*WorkerError) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{36}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{74}
 }
 
 func (x *WorkerError) GetErrorCode() string {
@@ -3395,7 +5986,7 @@ type ProxyData struct {
 
 func (x *ProxyData) Reset() {
 	*x = ProxyData{}
-	mi := &file_proto_migrate_proto_msgTypes[37]
+	mi := &file_proto_migrate_proto_msgTypes[75]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3407,7 +5998,7 @@ func (x *ProxyData) String() string {
 func (*ProxyData) ProtoMessage() {}
 
 func (x *ProxyData) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[37]
+	mi := &file_proto_migrate_proto_msgTypes[75]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3420,7 +6011,7 @@ func (x *ProxyData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProxyData.ProtoReflect.Descriptor instead.
 func (*ProxyData) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{37}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{75}
 }
 
 func (x *ProxyData) GetMigrationId() string {
@@ -3551,13 +6142,14 @@ type ProxyHandshake struct {
 	Role           ProxyRole              `protobuf:"varint,1,opt,name=role,proto3,enum=migrate.ProxyRole" json:"role,omitempty"`
 	TargetWorkerId string                 `protobuf:"bytes,2,opt,name=target_worker_id,json=targetWorkerId,proto3" json:"target_worker_id,omitempty"` // For source: which worker to relay to
 	AuthToken      string                 `protobuf:"bytes,3,opt,name=auth_token,json=authToken,proto3" json:"auth_token,omitempty"`                  // Worker's auth token for verification
+	Nonce          string                 `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`                                           // Master-issued, bound to the migration and role; valid once
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
 func (x *ProxyHandshake) Reset() {
 	*x = ProxyHandshake{}
-	mi := &file_proto_migrate_proto_msgTypes[38]
+	mi := &file_proto_migrate_proto_msgTypes[76]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3569,7 +6161,7 @@ func (x *ProxyHandshake) String() string {
 func (*ProxyHandshake) ProtoMessage() {}
 
 func (x *ProxyHandshake) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[38]
+	mi := &file_proto_migrate_proto_msgTypes[76]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3582,7 +6174,7 @@ func (x *ProxyHandshake) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProxyHandshake.ProtoReflect.Descriptor instead.
 func (*ProxyHandshake) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{38}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{76}
 }
 
 func (x *ProxyHandshake) GetRole() ProxyRole {
@@ -3606,6 +6198,13 @@ func (x *ProxyHandshake) GetAuthToken() string {
 	return ""
 }
 
+func (x *ProxyHandshake) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
 // ProxyClose signals the end of the proxy session
 type ProxyClose struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -3617,7 +6216,7 @@ type ProxyClose struct {
 
 func (x *ProxyClose) Reset() {
 	*x = ProxyClose{}
-	mi := &file_proto_migrate_proto_msgTypes[39]
+	mi := &file_proto_migrate_proto_msgTypes[77]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3629,7 +6228,7 @@ func (x *ProxyClose) String() string {
 func (*ProxyClose) ProtoMessage() {}
 
 func (x *ProxyClose) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_migrate_proto_msgTypes[39]
+	mi := &file_proto_migrate_proto_msgTypes[77]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3642,7 +6241,7 @@ func (x *ProxyClose) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProxyClose.ProtoReflect.Descriptor instead.
 func (*ProxyClose) Descriptor() ([]byte, []int) {
-	return file_proto_migrate_proto_rawDescGZIP(), []int{39}
+	return file_proto_migrate_proto_rawDescGZIP(), []int{77}
 }
 
 func (x *ProxyClose) GetSuccess() bool {
@@ -3659,11 +6258,231 @@ func (x *ProxyClose) GetError() string {
 	return ""
 }
 
+// RendezvousListen starts listening for offers
+type RendezvousListen struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RendezvousListen) Reset() {
+	*x = RendezvousListen{}
+	mi := &file_proto_migrate_proto_msgTypes[78]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RendezvousListen) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RendezvousListen) ProtoMessage() {}
+
+func (x *RendezvousListen) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[78]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RendezvousListen.ProtoReflect.Descriptor instead.
+func (*RendezvousListen) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{78}
+}
+
+// RendezvousOffer asks a listening peer to punch a hole towards the caller
+type RendezvousOffer struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	SessionId         string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	TargetFingerprint string                 `protobuf:"bytes,2,opt,name=target_fingerprint,json=targetFingerprint,proto3" json:"target_fingerprint,omitempty"` // Set by the caller
+	SourceFingerprint string                 `protobuf:"bytes,3,opt,name=source_fingerprint,json=sourceFingerprint,proto3" json:"source_fingerprint,omitempty"` // Set by the master from the caller's certificate
+	Candidates        []string               `protobuf:"bytes,4,rep,name=candidates,proto3" json:"candidates,omitempty"`                                        // UDP host:port pairs the caller may be reached at
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *RendezvousOffer) Reset() {
+	*x = RendezvousOffer{}
+	mi := &file_proto_migrate_proto_msgTypes[79]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RendezvousOffer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RendezvousOffer) ProtoMessage() {}
+
+func (x *RendezvousOffer) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[79]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RendezvousOffer.ProtoReflect.Descriptor instead.
+func (*RendezvousOffer) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{79}
+}
+
+func (x *RendezvousOffer) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *RendezvousOffer) GetTargetFingerprint() string {
+	if x != nil {
+		return x.TargetFingerprint
+	}
+	return ""
+}
+
+func (x *RendezvousOffer) GetSourceFingerprint() string {
+	if x != nil {
+		return x.SourceFingerprint
+	}
+	return ""
+}
+
+func (x *RendezvousOffer) GetCandidates() []string {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+// RendezvousAnswer is the listening peer's reply to an offer
+type RendezvousAnswer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Candidates    []string               `protobuf:"bytes,2,rep,name=candidates,proto3" json:"candidates,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RendezvousAnswer) Reset() {
+	*x = RendezvousAnswer{}
+	mi := &file_proto_migrate_proto_msgTypes[80]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RendezvousAnswer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RendezvousAnswer) ProtoMessage() {}
+
+func (x *RendezvousAnswer) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[80]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RendezvousAnswer.ProtoReflect.Descriptor instead.
+func (*RendezvousAnswer) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{80}
+}
+
+func (x *RendezvousAnswer) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *RendezvousAnswer) GetCandidates() []string {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *RendezvousAnswer) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// RelayFrame carries relayed bytes; the first frame of a stream names the session
+type RelayFrame struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelayFrame) Reset() {
+	*x = RelayFrame{}
+	mi := &file_proto_migrate_proto_msgTypes[81]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelayFrame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelayFrame) ProtoMessage() {}
+
+func (x *RelayFrame) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_migrate_proto_msgTypes[81]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelayFrame.ProtoReflect.Descriptor instead.
+func (*RelayFrame) Descriptor() ([]byte, []int) {
+	return file_proto_migrate_proto_rawDescGZIP(), []int{81}
+}
+
+func (x *RelayFrame) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *RelayFrame) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 var File_proto_migrate_proto protoreflect.FileDescriptor
 
 const file_proto_migrate_proto_rawDesc = "" +
 	"\n" +
-	"\x13proto/migrate.proto\x12\amigrate\"\xac\x01\n" +
+	"\x13proto/migrate.proto\x12\amigrate\"\xfd\x01\n" +
 	"\vVolumeChunk\x12\x1b\n" +
 	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12\x16\n" +
 	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
@@ -3671,7 +6490,120 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\bchecksum\x18\x04 \x01(\tR\bchecksum\x12\x1d\n" +
 	"\n" +
 	"total_size\x18\x05 \x01(\x03R\ttotalSize\x12\x19\n" +
-	"\bis_final\x18\x06 \x01(\bR\aisFinal\"\xcb\x01\n" +
+	"\bis_final\x18\x06 \x01(\bR\aisFinal\x12\x14\n" +
+	"\x05delta\x18\a \x01(\bR\x05delta\x12#\n" +
+	"\rdeleted_paths\x18\b \x03(\tR\fdeletedPaths\x12\x14\n" +
+	"\x05patch\x18\t \x01(\bR\x05patch\"P\n" +
+	"\x12VolumeIndexRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12\x1d\n" +
+	"\n" +
+	"range_size\x18\x02 \x01(\x03R\trangeSize\"\xbd\x01\n" +
+	"\x11StartCheckRequest\x12%\n" +
+	"\x0econtainer_name\x18\x01 \x01(\tR\rcontainerName\x12'\n" +
+	"\x05ports\x18\x02 \x03(\v2\x11.migrate.HostPortR\x05ports\x12\x18\n" +
+	"\avolumes\x18\x03 \x03(\tR\avolumes\x12\x1d\n" +
+	"\n" +
+	"bind_paths\x18\x04 \x03(\tR\tbindPaths\x12\x1f\n" +
+	"\vshared_with\x18\x05 \x03(\tR\n" +
+	"sharedWith\"\\\n" +
+	"\bHostPort\x12\x17\n" +
+	"\ahost_ip\x18\x01 \x01(\tR\x06hostIp\x12\x1b\n" +
+	"\thost_port\x18\x02 \x01(\x05R\bhostPort\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\tR\bprotocol\"W\n" +
+	"\rStartConflict\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x1a\n" +
+	"\bresource\x18\x02 \x01(\tR\bresource\x12\x16\n" +
+	"\x06detail\x18\x03 \x01(\tR\x06detail\"H\n" +
+	"\x10StartCheckResult\x124\n" +
+	"\tconflicts\x18\x01 \x03(\v2\x16.migrate.StartConflictR\tconflicts\"\xa1\x01\n" +
+	"\n" +
+	"VolumeFile\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\rR\x04mode\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x19\n" +
+	"\bmod_time\x18\x04 \x01(\x03R\amodTime\x12\x12\n" +
+	"\x04hash\x18\x05 \x01(\tR\x04hash\x12\x10\n" +
+	"\x03dir\x18\x06 \x01(\bR\x03dir\x12\x16\n" +
+	"\x06ranges\x18\a \x03(\tR\x06ranges\"P\n" +
+	"\vVolumeIndex\x12\x16\n" +
+	"\x06exists\x18\x01 \x01(\bR\x06exists\x12)\n" +
+	"\x05files\x18\x02 \x03(\v2\x13.migrate.VolumeFileR\x05files\"\xb1\x02\n" +
+	"\n" +
+	"VolumeSpec\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06driver\x18\x02 \x01(\tR\x06driver\x12D\n" +
+	"\vdriver_opts\x18\x03 \x03(\v2#.migrate.VolumeSpec.DriverOptsEntryR\n" +
+	"driverOpts\x127\n" +
+	"\x06labels\x18\x04 \x03(\v2\x1f.migrate.VolumeSpec.LabelsEntryR\x06labels\x1a=\n" +
+	"\x0fDriverOptsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"C\n" +
+	"\x13VolumeSampleRequest\x12\x16\n" +
+	"\x06volume\x18\x01 \x01(\tR\x06volume\x12\x14\n" +
+	"\x05paths\x18\x02 \x03(\tR\x05paths\"\x7f\n" +
+	"\fVolumeSample\x12\x16\n" +
+	"\x06exists\x18\x01 \x01(\bR\x06exists\x12\x14\n" +
+	"\x05files\x18\x02 \x01(\x03R\x05files\x12\x14\n" +
+	"\x05bytes\x18\x03 \x01(\x03R\x05bytes\x12+\n" +
+	"\x06sample\x18\x04 \x03(\v2\x13.migrate.VolumeFileR\x06sample\"3\n" +
+	"\x13ImageInspectRequest\x12\x1c\n" +
+	"\treference\x18\x01 \x01(\tR\treference\"6\n" +
+	"\fImageInspect\x12\x16\n" +
+	"\x06exists\x18\x01 \x01(\bR\x06exists\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\")\n" +
+	"\vHostInspect\x12\x1a\n" +
+	"\bhostname\x18\x01 \x01(\tR\bhostname\"7\n" +
+	"\x17ContainerInspectRequest\x12\x1c\n" +
+	"\treference\x18\x01 \x01(\tR\treference\"\xeb\x01\n" +
+	"\x10ContainerInspect\x12\x16\n" +
+	"\x06exists\x18\x01 \x01(\bR\x06exists\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x18\n" +
+	"\arunning\x18\x05 \x01(\bR\arunning\x12\x1e\n" +
+	"\n" +
+	"restarting\x18\x06 \x01(\bR\n" +
+	"restarting\x12\x16\n" +
+	"\x06health\x18\a \x01(\tR\x06health\x12\x1b\n" +
+	"\texit_code\x18\b \x01(\x05R\bexitCode\x12\x14\n" +
+	"\x05error\x18\t \x01(\tR\x05error\"\x7f\n" +
+	"\x16ContainerCreateRequest\x12\x1d\n" +
+	"\n" +
+	"state_data\x18\x01 \x01(\fR\tstateData\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x122\n" +
+	"\x15allow_default_runtime\x18\x03 \x01(\bR\x13allowDefaultRuntime\"]\n" +
+	"\x15ContainerCreateResult\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x124\n" +
+	"\x06report\x18\x02 \x01(\v2\x1c.migrate.CompatibilityReportR\x06report\"\xa3\x01\n" +
+	"\x13CompatibilityReport\x12\x1c\n" +
+	"\tcontainer\x18\x01 \x01(\tR\tcontainer\x12!\n" +
+	"\fcontainer_id\x18\x02 \x01(\tR\vcontainerId\x12/\n" +
+	"\askipped\x18\x03 \x03(\v2\x15.migrate.SkippedFieldR\askipped\x12\x1a\n" +
+	"\bwarnings\x18\x04 \x03(\tR\bwarnings\"t\n" +
+	"\fSkippedField\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x1c\n" +
+	"\trequested\x18\x02 \x01(\tR\trequested\x12\x18\n" +
+	"\aapplied\x18\x03 \x01(\tR\aapplied\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\";\n" +
+	"\x17CheckpointSupportResult\x12 \n" +
+	"\vunsupported\x18\x01 \x01(\tR\vunsupported\"y\n" +
+	"\x17ContainerRestoreRequest\x12!\n" +
+	"\fcontainer_id\x18\x01 \x01(\tR\vcontainerId\x12#\n" +
+	"\rcheckpoint_id\x18\x02 \x01(\tR\fcheckpointId\x12\x16\n" +
+	"\x06volume\x18\x03 \x01(\tR\x06volume\":\n" +
+	"\x15ContainerStartRequest\x12!\n" +
+	"\fcontainer_id\x18\x01 \x01(\tR\vcontainerId\"9\n" +
+	"\rComposeBundle\x12\x14\n" +
+	"\x05stack\x18\x01 \x01(\tR\x05stack\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"*\n" +
+	"\x14ComposeBundleReceipt\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"d\n" +
+	"\x16RemoveResourcesRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x123\n" +
+	"\tresources\x18\x02 \x01(\v2\x15.migrate.ResourceListR\tresources\"\xcb\x01\n" +
 	"\tLayerBlob\x12\x19\n" +
 	"\bimage_id\x18\x01 \x01(\tR\aimageId\x12!\n" +
 	"\flayer_digest\x18\x02 \x01(\tR\vlayerDigest\x12\x16\n" +
@@ -3680,7 +6612,18 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\bchecksum\x18\x05 \x01(\tR\bchecksum\x12\x1d\n" +
 	"\n" +
 	"layer_size\x18\x06 \x01(\x03R\tlayerSize\x12\x19\n" +
-	"\bis_final\x18\a \x01(\bR\aisFinal\"\xb0\x01\n" +
+	"\bis_final\x18\a \x01(\bR\aisFinal\"B\n" +
+	"\n" +
+	"LayerQuery\x12\x19\n" +
+	"\bimage_id\x18\x01 \x01(\tR\aimageId\x12\x19\n" +
+	"\bdiff_ids\x18\x02 \x03(\tR\adiffIds\",\n" +
+	"\x10LayerQueryResult\x12\x18\n" +
+	"\apresent\x18\x01 \x03(\tR\apresent\"\x82\x01\n" +
+	"\x10ImagePullRequest\x12\x19\n" +
+	"\bimage_id\x18\x01 \x01(\tR\aimageId\x12\x1c\n" +
+	"\treference\x18\x02 \x01(\tR\treference\x12\x10\n" +
+	"\x03tag\x18\x03 \x01(\tR\x03tag\x12#\n" +
+	"\rregistry_auth\x18\x04 \x01(\tR\fregistryAuth\"\xb0\x01\n" +
 	"\x0eContainerChunk\x12!\n" +
 	"\fcontainer_id\x18\x01 \x01(\tR\vcontainerId\x12%\n" +
 	"\x0econtainer_name\x18\x02 \x01(\tR\rcontainerName\x12\x1d\n" +
@@ -3694,12 +6637,15 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1f\n" +
 	"\vconfig_data\x18\x03 \x01(\fR\n" +
 	"configData\x12\x1a\n" +
-	"\bchecksum\x18\x04 \x01(\tR\bchecksum\"q\n" +
+	"\bchecksum\x18\x04 \x01(\tR\bchecksum\"\x91\x01\n" +
 	"\vTransferAck\x12\x16\n" +
 	"\x06offset\x18\x01 \x01(\x03R\x06offset\x12\x18\n" +
 	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1a\n" +
-	"\bprogress\x18\x04 \x01(\x02R\bprogress\"\xaf\x01\n" +
+	"\bprogress\x18\x04 \x01(\x02R\bprogress\x12\x1e\n" +
+	"\n" +
+	"retransmit\x18\x05 \x01(\bR\n" +
+	"retransmit\"\xaf\x01\n" +
 	"\x0eTransferResult\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1f\n" +
@@ -3717,14 +6663,15 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"containers\x12.\n" +
 	"\x06images\x18\x02 \x03(\v2\x16.migrate.ImageResourceR\x06images\x121\n" +
 	"\avolumes\x18\x03 \x03(\v2\x17.migrate.VolumeResourceR\avolumes\x124\n" +
-	"\bnetworks\x18\x04 \x03(\v2\x18.migrate.NetworkResourceR\bnetworks\"\xf8\x01\n" +
+	"\bnetworks\x18\x04 \x03(\v2\x18.migrate.NetworkResourceR\bnetworks\"\x91\x02\n" +
 	"\x11ContainerResource\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
 	"\x05image\x18\x03 \x01(\tR\x05image\x12\x14\n" +
 	"\x05state\x18\x04 \x01(\tR\x05state\x12\x18\n" +
 	"\acreated\x18\x05 \x01(\x03R\acreated\x12>\n" +
-	"\x06labels\x18\x06 \x03(\v2&.migrate.ContainerResource.LabelsEntryR\x06labels\x1a9\n" +
+	"\x06labels\x18\x06 \x03(\v2&.migrate.ContainerResource.LabelsEntryR\x06labels\x12\x17\n" +
+	"\asize_rw\x18\a \x01(\x03R\x06sizeRw\x1a9\n" +
 	"\vLabelsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x82\x01\n" +
@@ -3745,19 +6692,44 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\x06labels\x18\x05 \x03(\v2#.migrate.VolumeResource.LabelsEntryR\x06labels\x1a9\n" +
 	"\vLabelsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa8\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc2\x01\n" +
 	"\x0fNetworkResource\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
 	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
 	"\x06driver\x18\x03 \x01(\tR\x06driver\x12\x14\n" +
 	"\x05scope\x18\x04 \x01(\tR\x05scope\x12\x1a\n" +
 	"\binternal\x18\x05 \x01(\bR\binternal\x12'\n" +
-	"\x0fcontainer_count\x18\x06 \x01(\x05R\x0econtainerCount\"\a\n" +
-	"\x05Empty\"W\n" +
+	"\x0fcontainer_count\x18\x06 \x01(\x05R\x0econtainerCount\x12\x18\n" +
+	"\asubnets\x18\a \x03(\tR\asubnets\"\a\n" +
+	"\x05Empty\"w\n" +
+	"\x11DiskUsageCategory\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x12\x16\n" +
+	"\x06active\x18\x02 \x01(\x05R\x06active\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12 \n" +
+	"\vreclaimable\x18\x04 \x01(\x03R\vreclaimable\"\xba\x02\n" +
+	"\x0fDiskUsageReport\x122\n" +
+	"\x06images\x18\x01 \x01(\v2\x1a.migrate.DiskUsageCategoryR\x06images\x12:\n" +
+	"\n" +
+	"containers\x18\x02 \x01(\v2\x1a.migrate.DiskUsageCategoryR\n" +
+	"containers\x124\n" +
+	"\avolumes\x18\x03 \x01(\v2\x1a.migrate.DiskUsageCategoryR\avolumes\x12;\n" +
+	"\vbuild_cache\x18\x04 \x01(\v2\x1a.migrate.DiskUsageCategoryR\n" +
+	"buildCache\x12\x1d\n" +
+	"\n" +
+	"disk_total\x18\x05 \x01(\x03R\tdiskTotal\x12%\n" +
+	"\x0edisk_available\x18\x06 \x01(\x03R\rdiskAvailable\"\xa1\x01\n" +
 	"\x04Pong\x12\x17\n" +
 	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x1c\n" +
 	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12\x18\n" +
-	"\aversion\x18\x03 \x01(\tR\aversion\"\xde\x02\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12%\n" +
+	"\x0evolume_drivers\x18\x04 \x03(\tR\rvolumeDrivers\x12!\n" +
+	"\ftimestamp_ms\x18\x05 \x01(\x03R\vtimestampMs\"\x94\x01\n" +
+	"\x0fPairingExchange\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\fR\tpublicKey\x12#\n" +
+	"\rcode_verifier\x18\x02 \x01(\fR\fcodeVerifier\x12 \n" +
+	"\vcertificate\x18\x03 \x01(\fR\vcertificate\x12\x1b\n" +
+	"\tgrpc_port\x18\x04 \x01(\x05R\bgrpcPort\"\x83\x04\n" +
 	"\x12WorkerRegistration\x12)\n" +
 	"\x10enrollment_token\x18\x01 \x01(\tR\x0fenrollmentToken\x12\x1f\n" +
 	"\vworker_name\x18\x02 \x01(\tR\n" +
@@ -3766,10 +6738,15 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\fgrpc_address\x18\x04 \x01(\tR\vgrpcAddress\x12'\n" +
 	"\x0ftls_fingerprint\x18\x05 \x01(\tR\x0etlsFingerprint\x12?\n" +
 	"\x06labels\x18\x06 \x03(\v2'.migrate.WorkerRegistration.LabelsEntryR\x06labels\x12\x18\n" +
-	"\aversion\x18\a \x01(\tR\aversion\x1a9\n" +
+	"\aversion\x18\a \x01(\tR\aversion\x12#\n" +
+	"\routbound_only\x18\b \x01(\bR\foutboundOnly\x12)\n" +
+	"\x10protocol_version\x18\t \x01(\x05R\x0fprotocolVersion\x120\n" +
+	"\x14min_protocol_version\x18\n" +
+	" \x01(\x05R\x12minProtocolVersion\x12!\n" +
+	"\ftimestamp_ms\x18\v \x01(\x03R\vtimestampMs\x1a9\n" +
 	"\vLabelsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xea\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xc4\x02\n" +
 	"\x14RegistrationResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12\x1b\n" +
@@ -3777,7 +6754,9 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\n" +
 	"auth_token\x18\x04 \x01(\tR\tauthToken\x122\n" +
 	"\x15heartbeat_interval_ms\x18\x05 \x01(\x03R\x13heartbeatIntervalMs\x122\n" +
-	"\x15inventory_interval_ms\x18\x06 \x01(\x03R\x13inventoryIntervalMs\"\xdf\x02\n" +
+	"\x15inventory_interval_ms\x18\x06 \x01(\x03R\x13inventoryIntervalMs\x12)\n" +
+	"\x10protocol_version\x18\a \x01(\x05R\x0fprotocolVersion\x12-\n" +
+	"\x12master_fingerprint\x18\b \x01(\tR\x11masterFingerprint\"\xa9\x03\n" +
 	"\rWorkerMessage\x12\x1b\n" +
 	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x1d\n" +
 	"\n" +
@@ -3785,8 +6764,9 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\theartbeat\x18\x03 \x01(\v2\x12.migrate.HeartbeatH\x00R\theartbeat\x12K\n" +
 	"\x12migration_progress\x18\x04 \x01(\v2\x1a.migrate.MigrationProgressH\x00R\x11migrationProgress\x12K\n" +
 	"\x12migration_complete\x18\x05 \x01(\v2\x1a.migrate.MigrationCompleteH\x00R\x11migrationComplete\x129\n" +
-	"\fworker_error\x18\x06 \x01(\v2\x14.migrate.WorkerErrorH\x00R\vworkerErrorB\t\n" +
-	"\apayload\"\x8d\x03\n" +
+	"\fworker_error\x18\x06 \x01(\v2\x14.migrate.WorkerErrorH\x00R\vworkerError\x12H\n" +
+	"\x11transfer_fallback\x18\a \x01(\v2\x19.migrate.TransferFallbackH\x00R\x10transferFallbackB\t\n" +
+	"\apayload\"\xa4\x04\n" +
 	"\rMasterCommand\x12\x1d\n" +
 	"\n" +
 	"command_id\x18\x01 \x01(\tR\tcommandId\x12<\n" +
@@ -3794,7 +6774,9 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\x0fstart_migration\x18\x03 \x01(\v2\x1e.migrate.StartMigrationCommandH\x00R\x0estartMigration\x12L\n" +
 	"\x10cancel_migration\x18\x04 \x01(\v2\x1f.migrate.CancelMigrationCommandH\x00R\x0fcancelMigration\x12C\n" +
 	"\rupdate_config\x18\x05 \x01(\v2\x1c.migrate.UpdateConfigCommandH\x00R\fupdateConfig\x126\n" +
-	"\bshutdown\x18\x06 \x01(\v2\x18.migrate.ShutdownCommandH\x00R\bshutdownB\t\n" +
+	"\bshutdown\x18\x06 \x01(\v2\x18.migrate.ShutdownCommandH\x00R\bshutdown\x12M\n" +
+	"\x11rotate_auth_token\x18\a \x01(\v2\x1f.migrate.RotateAuthTokenCommandH\x00R\x0frotateAuthToken\x12F\n" +
+	"\x0eproxy_fallback\x18\b \x01(\v2\x1d.migrate.ProxyFallbackCommandH\x00R\rproxyFallbackB\t\n" +
 	"\apayload\"\xca\x01\n" +
 	"\tHeartbeat\x12\x1c\n" +
 	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12-\n" +
@@ -3813,7 +6795,7 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"disk_total\x18\x04 \x01(\x03R\tdiskTotal\x12%\n" +
 	"\x0edisk_available\x18\x05 \x01(\x03R\rdiskAvailable\x12(\n" +
 	"\x10network_rx_bytes\x18\x06 \x01(\x03R\x0enetworkRxBytes\x12(\n" +
-	"\x10network_tx_bytes\x18\a \x01(\x03R\x0enetworkTxBytes\"\xc2\x02\n" +
+	"\x10network_tx_bytes\x18\a \x01(\x03R\x0enetworkTxBytes\"\xfb\x02\n" +
 	"\x11ResourceInventory\x12\x1b\n" +
 	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x1d\n" +
 	"\n" +
@@ -3824,10 +6806,30 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"containers\x12.\n" +
 	"\x06images\x18\x05 \x03(\v2\x16.migrate.ImageResourceR\x06images\x121\n" +
 	"\avolumes\x18\x06 \x03(\v2\x17.migrate.VolumeResourceR\avolumes\x124\n" +
-	"\bnetworks\x18\a \x03(\v2\x18.migrate.NetworkResourceR\bnetworks\"=\n" +
+	"\bnetworks\x18\a \x03(\v2\x18.migrate.NetworkResourceR\bnetworks\x127\n" +
+	"\n" +
+	"disk_usage\x18\b \x01(\v2\x18.migrate.DiskUsageReportR\tdiskUsage\"\xf7\x02\n" +
+	"\x16WorkerMigrationRequest\x12\x1b\n" +
+	"\tworker_id\x18\x01 \x01(\tR\bworkerId\x12\x1d\n" +
+	"\n" +
+	"auth_token\x18\x02 \x01(\tR\tauthToken\x12#\n" +
+	"\rtarget_worker\x18\x03 \x01(\tR\ftargetWorker\x12#\n" +
+	"\rcontainer_ids\x18\x04 \x03(\tR\fcontainerIds\x12\x1b\n" +
+	"\timage_ids\x18\x05 \x03(\tR\bimageIds\x12!\n" +
+	"\fvolume_names\x18\x06 \x03(\tR\vvolumeNames\x12\x1f\n" +
+	"\vnetwork_ids\x18\a \x03(\tR\n" +
+	"networkIds\x12*\n" +
+	"\x04mode\x18\b \x01(\x0e2\x16.migrate.MigrationModeR\x04mode\x126\n" +
+	"\bstrategy\x18\t \x01(\x0e2\x1a.migrate.MigrationStrategyR\bstrategy\x12\x12\n" +
+	"\x04note\x18\n" +
+	" \x01(\tR\x04note\"s\n" +
+	"\x1eWorkerMigrationRequestResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12!\n" +
+	"\fmigration_id\x18\x03 \x01(\tR\vmigrationId\"=\n" +
 	"\vAckResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error\"\x80\x04\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xe0\x04\n" +
 	"\x10MigrationRequest\x12!\n" +
 	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12(\n" +
 	"\x10target_worker_id\x18\x02 \x01(\tR\x0etargetWorkerId\x12%\n" +
@@ -3842,11 +6844,17 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\bstrategy\x18\n" +
 	" \x01(\x0e2\x1a.migrate.MigrationStrategyR\bstrategy\x12:\n" +
 	"\rtransfer_mode\x18\v \x01(\x0e2\x15.migrate.TransferModeR\ftransferMode\x12#\n" +
-	"\rproxy_address\x18\f \x01(\tR\fproxyAddress\"h\n" +
+	"\rproxy_address\x18\f \x01(\tR\fproxyAddress\x12\x1f\n" +
+	"\vproxy_nonce\x18\r \x01(\tR\n" +
+	"proxyNonce\x12=\n" +
+	"\x0eimage_registry\x18\x0e \x01(\v2\x16.migrate.ImageRegistryR\rimageRegistry\"N\n" +
+	"\rImageRegistry\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12#\n" +
+	"\rregistry_auth\x18\x02 \x01(\tR\fregistryAuth\"h\n" +
 	"\x11MigrationResponse\x12\x1a\n" +
 	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12!\n" +
-	"\fmigration_id\x18\x03 \x01(\tR\vmigrationId\"\xa2\x03\n" +
+	"\fmigration_id\x18\x03 \x01(\tR\vmigrationId\"\xf5\x03\n" +
 	"\x16AcceptMigrationRequest\x12!\n" +
 	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12(\n" +
 	"\x10source_worker_id\x18\x02 \x01(\tR\x0esourceWorkerId\x12%\n" +
@@ -3859,7 +6867,17 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"networkIds\x12:\n" +
 	"\rtransfer_mode\x18\t \x01(\x0e2\x15.migrate.TransferModeR\ftransferMode\x12#\n" +
 	"\rproxy_address\x18\n" +
-	" \x01(\tR\fproxyAddress\"t\n" +
+	" \x01(\tR\fproxyAddress\x12\x1f\n" +
+	"\vproxy_nonce\x18\v \x01(\tR\n" +
+	"proxyNonce\x120\n" +
+	"\bnetworks\x18\f \x03(\v2\x14.migrate.NetworkSpecR\bnetworks\"\xa4\x01\n" +
+	"\vNetworkSpec\x12\x1b\n" +
+	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x16\n" +
+	"\x06driver\x18\x03 \x01(\tR\x06driver\x12\x1a\n" +
+	"\binternal\x18\x04 \x01(\bR\binternal\x12\x16\n" +
+	"\x06subnet\x18\x05 \x01(\tR\x06subnet\x12\x18\n" +
+	"\agateway\x18\x06 \x01(\tR\agateway\"t\n" +
 	"\x17AcceptMigrationResponse\x12\x1a\n" +
 	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\x12'\n" +
@@ -3880,7 +6898,13 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\rtransfer_mode\x18\x04 \x01(\x0e2\x15.migrate.TransferModeR\ftransferMode\"S\n" +
 	"\x16CancelMigrationCommand\x12!\n" +
 	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12\x16\n" +
-	"\x06reason\x18\x02 \x01(\tR\x06reason\"S\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\x95\x01\n" +
+	"\x14ProxyFallbackCommand\x12!\n" +
+	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12#\n" +
+	"\rproxy_address\x18\x02 \x01(\tR\fproxyAddress\x12\x1f\n" +
+	"\vproxy_nonce\x18\x03 \x01(\tR\n" +
+	"proxyNonce\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"S\n" +
 	"\x16CancelMigrationRequest\x12!\n" +
 	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12\x16\n" +
 	"\x06reason\x18\x02 \x01(\tR\x06reason\"I\n" +
@@ -3896,7 +6920,11 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"?\n" +
 	"\x0fShutdownCommand\x12\x16\n" +
 	"\x06reason\x18\x01 \x01(\tR\x06reason\x12\x14\n" +
-	"\x05force\x18\x02 \x01(\bR\x05force\"\x94\x02\n" +
+	"\x05force\x18\x02 \x01(\bR\x05force\"i\n" +
+	"\x16RotateAuthTokenCommand\x12\x1d\n" +
+	"\n" +
+	"auth_token\x18\x01 \x01(\tR\tauthToken\x120\n" +
+	"\x14previous_valid_until\x18\x02 \x01(\x03R\x12previousValidUntil\"\xb7\x02\n" +
 	"\x11MigrationProgress\x12!\n" +
 	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12-\n" +
 	"\x05phase\x18\x02 \x01(\x0e2\x17.migrate.MigrationPhaseR\x05phase\x12\x1a\n" +
@@ -3905,7 +6933,8 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\vtotal_bytes\x18\x05 \x01(\x03R\n" +
 	"totalBytes\x12)\n" +
 	"\x10current_resource\x18\x06 \x01(\tR\x0fcurrentResource\x12\x18\n" +
-	"\amessage\x18\a \x01(\tR\amessage\"\xe3\x01\n" +
+	"\amessage\x18\a \x01(\tR\amessage\x12!\n" +
+	"\ftimestamp_ms\x18\b \x01(\x03R\vtimestampMs\"\x86\x02\n" +
 	"\x11MigrationComplete\x12!\n" +
 	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12\x18\n" +
 	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x14\n" +
@@ -3913,7 +6942,11 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\vduration_ms\x18\x04 \x01(\x03R\n" +
 	"durationMs\x12+\n" +
 	"\x11bytes_transferred\x18\x05 \x01(\x03R\x10bytesTransferred\x12-\n" +
-	"\x12resources_migrated\x18\x06 \x03(\tR\x11resourcesMigrated\"v\n" +
+	"\x12resources_migrated\x18\x06 \x03(\tR\x11resourcesMigrated\x12!\n" +
+	"\ftimestamp_ms\x18\a \x01(\x03R\vtimestampMs\"M\n" +
+	"\x10TransferFallback\x12!\n" +
+	"\fmigration_id\x18\x01 \x01(\tR\vmigrationId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"v\n" +
 	"\vWorkerError\x12\x1d\n" +
 	"\n" +
 	"error_code\x18\x01 \x01(\tR\terrorCode\x12\x18\n" +
@@ -3931,16 +6964,38 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\x03ack\x18\a \x01(\v2\x14.migrate.TransferAckH\x00R\x03ack\x127\n" +
 	"\thandshake\x18\b \x01(\v2\x17.migrate.ProxyHandshakeH\x00R\thandshake\x12+\n" +
 	"\x05close\x18\t \x01(\v2\x13.migrate.ProxyCloseH\x00R\x05closeB\t\n" +
-	"\apayload\"\x81\x01\n" +
+	"\apayload\"\x97\x01\n" +
 	"\x0eProxyHandshake\x12&\n" +
 	"\x04role\x18\x01 \x01(\x0e2\x12.migrate.ProxyRoleR\x04role\x12(\n" +
 	"\x10target_worker_id\x18\x02 \x01(\tR\x0etargetWorkerId\x12\x1d\n" +
 	"\n" +
-	"auth_token\x18\x03 \x01(\tR\tauthToken\"<\n" +
+	"auth_token\x18\x03 \x01(\tR\tauthToken\x12\x14\n" +
+	"\x05nonce\x18\x04 \x01(\tR\x05nonce\"<\n" +
 	"\n" +
 	"ProxyClose\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05error*N\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\x12\n" +
+	"\x10RendezvousListen\"\xae\x01\n" +
+	"\x0fRendezvousOffer\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12-\n" +
+	"\x12target_fingerprint\x18\x02 \x01(\tR\x11targetFingerprint\x12-\n" +
+	"\x12source_fingerprint\x18\x03 \x01(\tR\x11sourceFingerprint\x12\x1e\n" +
+	"\n" +
+	"candidates\x18\x04 \x03(\tR\n" +
+	"candidates\"g\n" +
+	"\x10RendezvousAnswer\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1e\n" +
+	"\n" +
+	"candidates\x18\x02 \x03(\tR\n" +
+	"candidates\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"?\n" +
+	"\n" +
+	"RelayFrame\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data*N\n" +
 	"\fResourceType\x12\a\n" +
 	"\x03ALL\x10\x00\x12\x0e\n" +
 	"\n" +
@@ -3992,25 +7047,48 @@ const file_proto_migrate_proto_rawDesc = "" +
 	"\x10PROXY_DATA_CLOSE\x10\x05*9\n" +
 	"\tProxyRole\x12\x15\n" +
 	"\x11PROXY_ROLE_SOURCE\x10\x00\x12\x15\n" +
-	"\x11PROXY_ROLE_TARGET\x10\x012\x90\x03\n" +
+	"\x11PROXY_ROLE_TARGET\x10\x012\x9b\f\n" +
 	"\x10MigrationService\x12@\n" +
 	"\x0eTransferVolume\x12\x14.migrate.VolumeChunk\x1a\x14.migrate.TransferAck(\x010\x01\x12C\n" +
-	"\x13TransferImageLayers\x12\x12.migrate.LayerBlob\x1a\x14.migrate.TransferAck(\x010\x01\x12B\n" +
+	"\x13TransferImageLayers\x12\x12.migrate.LayerBlob\x1a\x14.migrate.TransferAck(\x010\x01\x12=\n" +
+	"\vQueryLayers\x12\x13.migrate.LayerQuery\x1a\x19.migrate.LayerQueryResult\x12?\n" +
+	"\tPullImage\x12\x19.migrate.ImagePullRequest\x1a\x17.migrate.TransferResult\x12B\n" +
 	"\x0fGetResourceList\x12\x18.migrate.ResourceRequest\x1a\x15.migrate.ResourceList\x12%\n" +
 	"\x04Ping\x12\x0e.migrate.Empty\x1a\r.migrate.Pong\x12F\n" +
 	"\x11TransferContainer\x12\x17.migrate.ContainerChunk\x1a\x14.migrate.TransferAck(\x010\x01\x12B\n" +
-	"\x0fTransferNetwork\x12\x16.migrate.NetworkConfig\x1a\x17.migrate.TransferResult2\xe6\x01\n" +
+	"\x0fTransferNetwork\x12\x16.migrate.NetworkConfig\x1a\x17.migrate.TransferResult\x128\n" +
+	"\fGetDiskUsage\x12\x0e.migrate.Empty\x1a\x18.migrate.DiskUsageReport\x12:\n" +
+	"\x04Pair\x12\x18.migrate.PairingExchange\x1a\x18.migrate.PairingExchange\x12E\n" +
+	"\x0eGetVolumeIndex\x12\x1b.migrate.VolumeIndexRequest\x1a\x14.migrate.VolumeIndex0\x01\x12L\n" +
+	"\x13CheckStartConflicts\x12\x1a.migrate.StartCheckRequest\x1a\x19.migrate.StartCheckResult\x128\n" +
+	"\fCreateVolume\x12\x13.migrate.VolumeSpec\x1a\x13.migrate.VolumeSpec\x12C\n" +
+	"\fSampleVolume\x12\x1c.migrate.VolumeSampleRequest\x1a\x15.migrate.VolumeSample\x12C\n" +
+	"\fInspectImage\x12\x1c.migrate.ImageInspectRequest\x1a\x15.migrate.ImageInspect\x123\n" +
+	"\vInspectHost\x12\x0e.migrate.Empty\x1a\x14.migrate.HostInspect\x12O\n" +
+	"\x10InspectContainer\x12 .migrate.ContainerInspectRequest\x1a\x19.migrate.ContainerInspect\x12R\n" +
+	"\x0fCreateContainer\x12\x1f.migrate.ContainerCreateRequest\x1a\x1e.migrate.ContainerCreateResult\x12E\n" +
+	"\x11CheckpointSupport\x12\x0e.migrate.Empty\x1a .migrate.CheckpointSupportResult\x12D\n" +
+	"\x10RestoreContainer\x12 .migrate.ContainerRestoreRequest\x1a\x0e.migrate.Empty\x12@\n" +
+	"\x0eStartContainer\x12\x1e.migrate.ContainerStartRequest\x1a\x0e.migrate.Empty\x12F\n" +
+	"\rReceiveBundle\x12\x16.migrate.ComposeBundle\x1a\x1d.migrate.ComposeBundleReceipt\x12I\n" +
+	"\x0fRemoveResources\x12\x1f.migrate.RemoveResourcesRequest\x1a\x15.migrate.ResourceList2\xc4\x02\n" +
 	"\rMasterService\x12L\n" +
 	"\x0eRegisterWorker\x12\x1b.migrate.WorkerRegistration\x1a\x1d.migrate.RegistrationResponse\x12B\n" +
 	"\fWorkerStream\x12\x16.migrate.WorkerMessage\x1a\x16.migrate.MasterCommand(\x010\x01\x12C\n" +
-	"\x0fReportResources\x12\x1a.migrate.ResourceInventory\x1a\x14.migrate.AckResponse2\xbf\x02\n" +
+	"\x0fReportResources\x12\x1a.migrate.ResourceInventory\x1a\x14.migrate.AckResponse\x12\\\n" +
+	"\x10RequestMigration\x12\x1f.migrate.WorkerMigrationRequest\x1a'.migrate.WorkerMigrationRequestResponse2\xbf\x02\n" +
 	"\rWorkerService\x12J\n" +
 	"\x11InitiateMigration\x12\x19.migrate.MigrationRequest\x1a\x1a.migrate.MigrationResponse\x12T\n" +
 	"\x0fAcceptMigration\x12\x1f.migrate.AcceptMigrationRequest\x1a .migrate.AcceptMigrationResponse\x126\n" +
 	"\vHealthCheck\x12\x0e.migrate.Empty\x1a\x17.migrate.HealthResponse\x12T\n" +
 	"\x0fCancelMigration\x12\x1f.migrate.CancelMigrationRequest\x1a .migrate.CancelMigrationResponse2N\n" +
 	"\fProxyService\x12>\n" +
-	"\x10OpenProxyChannel\x12\x12.migrate.ProxyData\x1a\x12.migrate.ProxyData(\x010\x01B1Z/github.com/artemis/docker-migrate/proto;migrateb\x06proto3"
+	"\x10OpenProxyChannel\x12\x12.migrate.ProxyData\x1a\x12.migrate.ProxyData(\x010\x012\xfe\x01\n" +
+	"\x11RendezvousService\x12?\n" +
+	"\x06Listen\x12\x19.migrate.RendezvousListen\x1a\x18.migrate.RendezvousOffer0\x01\x12<\n" +
+	"\x05Offer\x12\x18.migrate.RendezvousOffer\x1a\x19.migrate.RendezvousAnswer\x123\n" +
+	"\x06Answer\x12\x19.migrate.RendezvousAnswer\x1a\x0e.migrate.Empty\x125\n" +
+	"\x05Relay\x12\x13.migrate.RelayFrame\x1a\x13.migrate.RelayFrame(\x010\x01B1Z/github.com/artemis/docker-migrate/proto;migrateb\x06proto3"
 
 var (
 	file_proto_migrate_proto_rawDescOnce sync.Once
@@ -4025,140 +7103,249 @@ func file_proto_migrate_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_migrate_proto_enumTypes = make([]protoimpl.EnumInfo, 9)
-var file_proto_migrate_proto_msgTypes = make([]protoimpl.MessageInfo, 45)
+var file_proto_migrate_proto_msgTypes = make([]protoimpl.MessageInfo, 89)
 var file_proto_migrate_proto_goTypes = []any{
-	(ResourceType)(0),               // 0: migrate.ResourceType
-	(TransferMode)(0),               // 1: migrate.TransferMode
-	(WorkerStatus)(0),               // 2: migrate.WorkerStatus
-	(MigrationRole)(0),              // 3: migrate.MigrationRole
-	(MigrationMode)(0),              // 4: migrate.MigrationMode
-	(MigrationStrategy)(0),          // 5: migrate.MigrationStrategy
-	(MigrationPhase)(0),             // 6: migrate.MigrationPhase
-	(ProxyDataType)(0),              // 7: migrate.ProxyDataType
-	(ProxyRole)(0),                  // 8: migrate.ProxyRole
-	(*VolumeChunk)(nil),             // 9: migrate.VolumeChunk
-	(*LayerBlob)(nil),               // 10: migrate.LayerBlob
-	(*ContainerChunk)(nil),          // 11: migrate.ContainerChunk
-	(*NetworkConfig)(nil),           // 12: migrate.NetworkConfig
-	(*TransferAck)(nil),             // 13: migrate.TransferAck
-	(*TransferResult)(nil),          // 14: migrate.TransferResult
-	(*ResourceRequest)(nil),         // 15: migrate.ResourceRequest
-	(*ResourceList)(nil),            // 16: migrate.ResourceList
-	(*ContainerResource)(nil),       // 17: migrate.ContainerResource
-	(*ImageResource)(nil),           // 18: migrate.ImageResource
-	(*VolumeResource)(nil),          // 19: migrate.VolumeResource
-	(*NetworkResource)(nil),         // 20: migrate.NetworkResource
-	(*Empty)(nil),                   // 21: migrate.Empty
-	(*Pong)(nil),                    // 22: migrate.Pong
-	(*WorkerRegistration)(nil),      // 23: migrate.WorkerRegistration
-	(*RegistrationResponse)(nil),    // 24: migrate.RegistrationResponse
-	(*WorkerMessage)(nil),           // 25: migrate.WorkerMessage
-	(*MasterCommand)(nil),           // 26: migrate.MasterCommand
-	(*Heartbeat)(nil),               // 27: migrate.Heartbeat
-	(*HeartbeatAck)(nil),            // 28: migrate.HeartbeatAck
-	(*SystemResources)(nil),         // 29: migrate.SystemResources
-	(*ResourceInventory)(nil),       // 30: migrate.ResourceInventory
-	(*AckResponse)(nil),             // 31: migrate.AckResponse
-	(*MigrationRequest)(nil),        // 32: migrate.MigrationRequest
-	(*MigrationResponse)(nil),       // 33: migrate.MigrationResponse
-	(*AcceptMigrationRequest)(nil),  // 34: migrate.AcceptMigrationRequest
-	(*AcceptMigrationResponse)(nil), // 35: migrate.AcceptMigrationResponse
-	(*HealthResponse)(nil),          // 36: migrate.HealthResponse
-	(*StartMigrationCommand)(nil),   // 37: migrate.StartMigrationCommand
-	(*CancelMigrationCommand)(nil),  // 38: migrate.CancelMigrationCommand
-	(*CancelMigrationRequest)(nil),  // 39: migrate.CancelMigrationRequest
-	(*CancelMigrationResponse)(nil), // 40: migrate.CancelMigrationResponse
-	(*UpdateConfigCommand)(nil),     // 41: migrate.UpdateConfigCommand
-	(*ShutdownCommand)(nil),         // 42: migrate.ShutdownCommand
-	(*MigrationProgress)(nil),       // 43: migrate.MigrationProgress
-	(*MigrationComplete)(nil),       // 44: migrate.MigrationComplete
-	(*WorkerError)(nil),             // 45: migrate.WorkerError
-	(*ProxyData)(nil),               // 46: migrate.ProxyData
-	(*ProxyHandshake)(nil),          // 47: migrate.ProxyHandshake
-	(*ProxyClose)(nil),              // 48: migrate.ProxyClose
-	nil,                             // 49: migrate.ContainerResource.LabelsEntry
-	nil,                             // 50: migrate.VolumeResource.LabelsEntry
-	nil,                             // 51: migrate.WorkerRegistration.LabelsEntry
-	nil,                             // 52: migrate.HealthResponse.ChecksEntry
-	nil,                             // 53: migrate.UpdateConfigCommand.LabelsEntry
+	(ResourceType)(0),                      // 0: migrate.ResourceType
+	(TransferMode)(0),                      // 1: migrate.TransferMode
+	(WorkerStatus)(0),                      // 2: migrate.WorkerStatus
+	(MigrationRole)(0),                     // 3: migrate.MigrationRole
+	(MigrationMode)(0),                     // 4: migrate.MigrationMode
+	(MigrationStrategy)(0),                 // 5: migrate.MigrationStrategy
+	(MigrationPhase)(0),                    // 6: migrate.MigrationPhase
+	(ProxyDataType)(0),                     // 7: migrate.ProxyDataType
+	(ProxyRole)(0),                         // 8: migrate.ProxyRole
+	(*VolumeChunk)(nil),                    // 9: migrate.VolumeChunk
+	(*VolumeIndexRequest)(nil),             // 10: migrate.VolumeIndexRequest
+	(*StartCheckRequest)(nil),              // 11: migrate.StartCheckRequest
+	(*HostPort)(nil),                       // 12: migrate.HostPort
+	(*StartConflict)(nil),                  // 13: migrate.StartConflict
+	(*StartCheckResult)(nil),               // 14: migrate.StartCheckResult
+	(*VolumeFile)(nil),                     // 15: migrate.VolumeFile
+	(*VolumeIndex)(nil),                    // 16: migrate.VolumeIndex
+	(*VolumeSpec)(nil),                     // 17: migrate.VolumeSpec
+	(*VolumeSampleRequest)(nil),            // 18: migrate.VolumeSampleRequest
+	(*VolumeSample)(nil),                   // 19: migrate.VolumeSample
+	(*ImageInspectRequest)(nil),            // 20: migrate.ImageInspectRequest
+	(*ImageInspect)(nil),                   // 21: migrate.ImageInspect
+	(*HostInspect)(nil),                    // 22: migrate.HostInspect
+	(*ContainerInspectRequest)(nil),        // 23: migrate.ContainerInspectRequest
+	(*ContainerInspect)(nil),               // 24: migrate.ContainerInspect
+	(*ContainerCreateRequest)(nil),         // 25: migrate.ContainerCreateRequest
+	(*ContainerCreateResult)(nil),          // 26: migrate.ContainerCreateResult
+	(*CompatibilityReport)(nil),            // 27: migrate.CompatibilityReport
+	(*SkippedField)(nil),                   // 28: migrate.SkippedField
+	(*CheckpointSupportResult)(nil),        // 29: migrate.CheckpointSupportResult
+	(*ContainerRestoreRequest)(nil),        // 30: migrate.ContainerRestoreRequest
+	(*ContainerStartRequest)(nil),          // 31: migrate.ContainerStartRequest
+	(*ComposeBundle)(nil),                  // 32: migrate.ComposeBundle
+	(*ComposeBundleReceipt)(nil),           // 33: migrate.ComposeBundleReceipt
+	(*RemoveResourcesRequest)(nil),         // 34: migrate.RemoveResourcesRequest
+	(*LayerBlob)(nil),                      // 35: migrate.LayerBlob
+	(*LayerQuery)(nil),                     // 36: migrate.LayerQuery
+	(*LayerQueryResult)(nil),               // 37: migrate.LayerQueryResult
+	(*ImagePullRequest)(nil),               // 38: migrate.ImagePullRequest
+	(*ContainerChunk)(nil),                 // 39: migrate.ContainerChunk
+	(*NetworkConfig)(nil),                  // 40: migrate.NetworkConfig
+	(*TransferAck)(nil),                    // 41: migrate.TransferAck
+	(*TransferResult)(nil),                 // 42: migrate.TransferResult
+	(*ResourceRequest)(nil),                // 43: migrate.ResourceRequest
+	(*ResourceList)(nil),                   // 44: migrate.ResourceList
+	(*ContainerResource)(nil),              // 45: migrate.ContainerResource
+	(*ImageResource)(nil),                  // 46: migrate.ImageResource
+	(*VolumeResource)(nil),                 // 47: migrate.VolumeResource
+	(*NetworkResource)(nil),                // 48: migrate.NetworkResource
+	(*Empty)(nil),                          // 49: migrate.Empty
+	(*DiskUsageCategory)(nil),              // 50: migrate.DiskUsageCategory
+	(*DiskUsageReport)(nil),                // 51: migrate.DiskUsageReport
+	(*Pong)(nil),                           // 52: migrate.Pong
+	(*PairingExchange)(nil),                // 53: migrate.PairingExchange
+	(*WorkerRegistration)(nil),             // 54: migrate.WorkerRegistration
+	(*RegistrationResponse)(nil),           // 55: migrate.RegistrationResponse
+	(*WorkerMessage)(nil),                  // 56: migrate.WorkerMessage
+	(*MasterCommand)(nil),                  // 57: migrate.MasterCommand
+	(*Heartbeat)(nil),                      // 58: migrate.Heartbeat
+	(*HeartbeatAck)(nil),                   // 59: migrate.HeartbeatAck
+	(*SystemResources)(nil),                // 60: migrate.SystemResources
+	(*ResourceInventory)(nil),              // 61: migrate.ResourceInventory
+	(*WorkerMigrationRequest)(nil),         // 62: migrate.WorkerMigrationRequest
+	(*WorkerMigrationRequestResponse)(nil), // 63: migrate.WorkerMigrationRequestResponse
+	(*AckResponse)(nil),                    // 64: migrate.AckResponse
+	(*MigrationRequest)(nil),               // 65: migrate.MigrationRequest
+	(*ImageRegistry)(nil),                  // 66: migrate.ImageRegistry
+	(*MigrationResponse)(nil),              // 67: migrate.MigrationResponse
+	(*AcceptMigrationRequest)(nil),         // 68: migrate.AcceptMigrationRequest
+	(*NetworkSpec)(nil),                    // 69: migrate.NetworkSpec
+	(*AcceptMigrationResponse)(nil),        // 70: migrate.AcceptMigrationResponse
+	(*HealthResponse)(nil),                 // 71: migrate.HealthResponse
+	(*StartMigrationCommand)(nil),          // 72: migrate.StartMigrationCommand
+	(*CancelMigrationCommand)(nil),         // 73: migrate.CancelMigrationCommand
+	(*ProxyFallbackCommand)(nil),           // 74: migrate.ProxyFallbackCommand
+	(*CancelMigrationRequest)(nil),         // 75: migrate.CancelMigrationRequest
+	(*CancelMigrationResponse)(nil),        // 76: migrate.CancelMigrationResponse
+	(*UpdateConfigCommand)(nil),            // 77: migrate.UpdateConfigCommand
+	(*ShutdownCommand)(nil),                // 78: migrate.ShutdownCommand
+	(*RotateAuthTokenCommand)(nil),         // 79: migrate.RotateAuthTokenCommand
+	(*MigrationProgress)(nil),              // 80: migrate.MigrationProgress
+	(*MigrationComplete)(nil),              // 81: migrate.MigrationComplete
+	(*TransferFallback)(nil),               // 82: migrate.TransferFallback
+	(*WorkerError)(nil),                    // 83: migrate.WorkerError
+	(*ProxyData)(nil),                      // 84: migrate.ProxyData
+	(*ProxyHandshake)(nil),                 // 85: migrate.ProxyHandshake
+	(*ProxyClose)(nil),                     // 86: migrate.ProxyClose
+	(*RendezvousListen)(nil),               // 87: migrate.RendezvousListen
+	(*RendezvousOffer)(nil),                // 88: migrate.RendezvousOffer
+	(*RendezvousAnswer)(nil),               // 89: migrate.RendezvousAnswer
+	(*RelayFrame)(nil),                     // 90: migrate.RelayFrame
+	nil,                                    // 91: migrate.VolumeSpec.DriverOptsEntry
+	nil,                                    // 92: migrate.VolumeSpec.LabelsEntry
+	nil,                                    // 93: migrate.ContainerResource.LabelsEntry
+	nil,                                    // 94: migrate.VolumeResource.LabelsEntry
+	nil,                                    // 95: migrate.WorkerRegistration.LabelsEntry
+	nil,                                    // 96: migrate.HealthResponse.ChecksEntry
+	nil,                                    // 97: migrate.UpdateConfigCommand.LabelsEntry
 }
 var file_proto_migrate_proto_depIdxs = []int32{
-	0,  // 0: migrate.ResourceRequest.type:type_name -> migrate.ResourceType
-	17, // 1: migrate.ResourceList.containers:type_name -> migrate.ContainerResource
-	18, // 2: migrate.ResourceList.images:type_name -> migrate.ImageResource
-	19, // 3: migrate.ResourceList.volumes:type_name -> migrate.VolumeResource
-	20, // 4: migrate.ResourceList.networks:type_name -> migrate.NetworkResource
-	49, // 5: migrate.ContainerResource.labels:type_name -> migrate.ContainerResource.LabelsEntry
-	50, // 6: migrate.VolumeResource.labels:type_name -> migrate.VolumeResource.LabelsEntry
-	51, // 7: migrate.WorkerRegistration.labels:type_name -> migrate.WorkerRegistration.LabelsEntry
-	27, // 8: migrate.WorkerMessage.heartbeat:type_name -> migrate.Heartbeat
-	43, // 9: migrate.WorkerMessage.migration_progress:type_name -> migrate.MigrationProgress
-	44, // 10: migrate.WorkerMessage.migration_complete:type_name -> migrate.MigrationComplete
-	45, // 11: migrate.WorkerMessage.worker_error:type_name -> migrate.WorkerError
-	28, // 12: migrate.MasterCommand.heartbeat_ack:type_name -> migrate.HeartbeatAck
-	37, // 13: migrate.MasterCommand.start_migration:type_name -> migrate.StartMigrationCommand
-	38, // 14: migrate.MasterCommand.cancel_migration:type_name -> migrate.CancelMigrationCommand
-	41, // 15: migrate.MasterCommand.update_config:type_name -> migrate.UpdateConfigCommand
-	42, // 16: migrate.MasterCommand.shutdown:type_name -> migrate.ShutdownCommand
-	2,  // 17: migrate.Heartbeat.status:type_name -> migrate.WorkerStatus
-	29, // 18: migrate.Heartbeat.system_resources:type_name -> migrate.SystemResources
-	17, // 19: migrate.ResourceInventory.containers:type_name -> migrate.ContainerResource
-	18, // 20: migrate.ResourceInventory.images:type_name -> migrate.ImageResource
-	19, // 21: migrate.ResourceInventory.volumes:type_name -> migrate.VolumeResource
-	20, // 22: migrate.ResourceInventory.networks:type_name -> migrate.NetworkResource
-	4,  // 23: migrate.MigrationRequest.mode:type_name -> migrate.MigrationMode
-	5,  // 24: migrate.MigrationRequest.strategy:type_name -> migrate.MigrationStrategy
-	1,  // 25: migrate.MigrationRequest.transfer_mode:type_name -> migrate.TransferMode
-	1,  // 26: migrate.AcceptMigrationRequest.transfer_mode:type_name -> migrate.TransferMode
-	2,  // 27: migrate.HealthResponse.status:type_name -> migrate.WorkerStatus
-	52, // 28: migrate.HealthResponse.checks:type_name -> migrate.HealthResponse.ChecksEntry
-	3,  // 29: migrate.StartMigrationCommand.role:type_name -> migrate.MigrationRole
-	32, // 30: migrate.StartMigrationCommand.request:type_name -> migrate.MigrationRequest
-	34, // 31: migrate.StartMigrationCommand.accept_request:type_name -> migrate.AcceptMigrationRequest
-	1,  // 32: migrate.StartMigrationCommand.transfer_mode:type_name -> migrate.TransferMode
-	53, // 33: migrate.UpdateConfigCommand.labels:type_name -> migrate.UpdateConfigCommand.LabelsEntry
-	6,  // 34: migrate.MigrationProgress.phase:type_name -> migrate.MigrationPhase
-	7,  // 35: migrate.ProxyData.type:type_name -> migrate.ProxyDataType
-	9,  // 36: migrate.ProxyData.volume_chunk:type_name -> migrate.VolumeChunk
-	10, // 37: migrate.ProxyData.layer_blob:type_name -> migrate.LayerBlob
-	11, // 38: migrate.ProxyData.container_chunk:type_name -> migrate.ContainerChunk
-	13, // 39: migrate.ProxyData.ack:type_name -> migrate.TransferAck
-	47, // 40: migrate.ProxyData.handshake:type_name -> migrate.ProxyHandshake
-	48, // 41: migrate.ProxyData.close:type_name -> migrate.ProxyClose
-	8,  // 42: migrate.ProxyHandshake.role:type_name -> migrate.ProxyRole
-	9,  // 43: migrate.MigrationService.TransferVolume:input_type -> migrate.VolumeChunk
-	10, // 44: migrate.MigrationService.TransferImageLayers:input_type -> migrate.LayerBlob
-	15, // 45: migrate.MigrationService.GetResourceList:input_type -> migrate.ResourceRequest
-	21, // 46: migrate.MigrationService.Ping:input_type -> migrate.Empty
-	11, // 47: migrate.MigrationService.TransferContainer:input_type -> migrate.ContainerChunk
-	12, // 48: migrate.MigrationService.TransferNetwork:input_type -> migrate.NetworkConfig
-	23, // 49: migrate.MasterService.RegisterWorker:input_type -> migrate.WorkerRegistration
-	25, // 50: migrate.MasterService.WorkerStream:input_type -> migrate.WorkerMessage
-	30, // 51: migrate.MasterService.ReportResources:input_type -> migrate.ResourceInventory
-	32, // 52: migrate.WorkerService.InitiateMigration:input_type -> migrate.MigrationRequest
-	34, // 53: migrate.WorkerService.AcceptMigration:input_type -> migrate.AcceptMigrationRequest
-	21, // 54: migrate.WorkerService.HealthCheck:input_type -> migrate.Empty
-	39, // 55: migrate.WorkerService.CancelMigration:input_type -> migrate.CancelMigrationRequest
-	46, // 56: migrate.ProxyService.OpenProxyChannel:input_type -> migrate.ProxyData
-	13, // 57: migrate.MigrationService.TransferVolume:output_type -> migrate.TransferAck
-	13, // 58: migrate.MigrationService.TransferImageLayers:output_type -> migrate.TransferAck
-	16, // 59: migrate.MigrationService.GetResourceList:output_type -> migrate.ResourceList
-	22, // 60: migrate.MigrationService.Ping:output_type -> migrate.Pong
-	13, // 61: migrate.MigrationService.TransferContainer:output_type -> migrate.TransferAck
-	14, // 62: migrate.MigrationService.TransferNetwork:output_type -> migrate.TransferResult
-	24, // 63: migrate.MasterService.RegisterWorker:output_type -> migrate.RegistrationResponse
-	26, // 64: migrate.MasterService.WorkerStream:output_type -> migrate.MasterCommand
-	31, // 65: migrate.MasterService.ReportResources:output_type -> migrate.AckResponse
-	33, // 66: migrate.WorkerService.InitiateMigration:output_type -> migrate.MigrationResponse
-	35, // 67: migrate.WorkerService.AcceptMigration:output_type -> migrate.AcceptMigrationResponse
-	36, // 68: migrate.WorkerService.HealthCheck:output_type -> migrate.HealthResponse
-	40, // 69: migrate.WorkerService.CancelMigration:output_type -> migrate.CancelMigrationResponse
-	46, // 70: migrate.ProxyService.OpenProxyChannel:output_type -> migrate.ProxyData
-	57, // [57:71] is the sub-list for method output_type
-	43, // [43:57] is the sub-list for method input_type
-	43, // [43:43] is the sub-list for extension type_name
-	43, // [43:43] is the sub-list for extension extendee
-	0,  // [0:43] is the sub-list for field type_name
+	12,  // 0: migrate.StartCheckRequest.ports:type_name -> migrate.HostPort
+	13,  // 1: migrate.StartCheckResult.conflicts:type_name -> migrate.StartConflict
+	15,  // 2: migrate.VolumeIndex.files:type_name -> migrate.VolumeFile
+	91,  // 3: migrate.VolumeSpec.driver_opts:type_name -> migrate.VolumeSpec.DriverOptsEntry
+	92,  // 4: migrate.VolumeSpec.labels:type_name -> migrate.VolumeSpec.LabelsEntry
+	15,  // 5: migrate.VolumeSample.sample:type_name -> migrate.VolumeFile
+	27,  // 6: migrate.ContainerCreateResult.report:type_name -> migrate.CompatibilityReport
+	28,  // 7: migrate.CompatibilityReport.skipped:type_name -> migrate.SkippedField
+	44,  // 8: migrate.RemoveResourcesRequest.resources:type_name -> migrate.ResourceList
+	0,   // 9: migrate.ResourceRequest.type:type_name -> migrate.ResourceType
+	45,  // 10: migrate.ResourceList.containers:type_name -> migrate.ContainerResource
+	46,  // 11: migrate.ResourceList.images:type_name -> migrate.ImageResource
+	47,  // 12: migrate.ResourceList.volumes:type_name -> migrate.VolumeResource
+	48,  // 13: migrate.ResourceList.networks:type_name -> migrate.NetworkResource
+	93,  // 14: migrate.ContainerResource.labels:type_name -> migrate.ContainerResource.LabelsEntry
+	94,  // 15: migrate.VolumeResource.labels:type_name -> migrate.VolumeResource.LabelsEntry
+	50,  // 16: migrate.DiskUsageReport.images:type_name -> migrate.DiskUsageCategory
+	50,  // 17: migrate.DiskUsageReport.containers:type_name -> migrate.DiskUsageCategory
+	50,  // 18: migrate.DiskUsageReport.volumes:type_name -> migrate.DiskUsageCategory
+	50,  // 19: migrate.DiskUsageReport.build_cache:type_name -> migrate.DiskUsageCategory
+	95,  // 20: migrate.WorkerRegistration.labels:type_name -> migrate.WorkerRegistration.LabelsEntry
+	58,  // 21: migrate.WorkerMessage.heartbeat:type_name -> migrate.Heartbeat
+	80,  // 22: migrate.WorkerMessage.migration_progress:type_name -> migrate.MigrationProgress
+	81,  // 23: migrate.WorkerMessage.migration_complete:type_name -> migrate.MigrationComplete
+	83,  // 24: migrate.WorkerMessage.worker_error:type_name -> migrate.WorkerError
+	82,  // 25: migrate.WorkerMessage.transfer_fallback:type_name -> migrate.TransferFallback
+	59,  // 26: migrate.MasterCommand.heartbeat_ack:type_name -> migrate.HeartbeatAck
+	72,  // 27: migrate.MasterCommand.start_migration:type_name -> migrate.StartMigrationCommand
+	73,  // 28: migrate.MasterCommand.cancel_migration:type_name -> migrate.CancelMigrationCommand
+	77,  // 29: migrate.MasterCommand.update_config:type_name -> migrate.UpdateConfigCommand
+	78,  // 30: migrate.MasterCommand.shutdown:type_name -> migrate.ShutdownCommand
+	79,  // 31: migrate.MasterCommand.rotate_auth_token:type_name -> migrate.RotateAuthTokenCommand
+	74,  // 32: migrate.MasterCommand.proxy_fallback:type_name -> migrate.ProxyFallbackCommand
+	2,   // 33: migrate.Heartbeat.status:type_name -> migrate.WorkerStatus
+	60,  // 34: migrate.Heartbeat.system_resources:type_name -> migrate.SystemResources
+	45,  // 35: migrate.ResourceInventory.containers:type_name -> migrate.ContainerResource
+	46,  // 36: migrate.ResourceInventory.images:type_name -> migrate.ImageResource
+	47,  // 37: migrate.ResourceInventory.volumes:type_name -> migrate.VolumeResource
+	48,  // 38: migrate.ResourceInventory.networks:type_name -> migrate.NetworkResource
+	51,  // 39: migrate.ResourceInventory.disk_usage:type_name -> migrate.DiskUsageReport
+	4,   // 40: migrate.WorkerMigrationRequest.mode:type_name -> migrate.MigrationMode
+	5,   // 41: migrate.WorkerMigrationRequest.strategy:type_name -> migrate.MigrationStrategy
+	4,   // 42: migrate.MigrationRequest.mode:type_name -> migrate.MigrationMode
+	5,   // 43: migrate.MigrationRequest.strategy:type_name -> migrate.MigrationStrategy
+	1,   // 44: migrate.MigrationRequest.transfer_mode:type_name -> migrate.TransferMode
+	66,  // 45: migrate.MigrationRequest.image_registry:type_name -> migrate.ImageRegistry
+	1,   // 46: migrate.AcceptMigrationRequest.transfer_mode:type_name -> migrate.TransferMode
+	69,  // 47: migrate.AcceptMigrationRequest.networks:type_name -> migrate.NetworkSpec
+	2,   // 48: migrate.HealthResponse.status:type_name -> migrate.WorkerStatus
+	96,  // 49: migrate.HealthResponse.checks:type_name -> migrate.HealthResponse.ChecksEntry
+	3,   // 50: migrate.StartMigrationCommand.role:type_name -> migrate.MigrationRole
+	65,  // 51: migrate.StartMigrationCommand.request:type_name -> migrate.MigrationRequest
+	68,  // 52: migrate.StartMigrationCommand.accept_request:type_name -> migrate.AcceptMigrationRequest
+	1,   // 53: migrate.StartMigrationCommand.transfer_mode:type_name -> migrate.TransferMode
+	97,  // 54: migrate.UpdateConfigCommand.labels:type_name -> migrate.UpdateConfigCommand.LabelsEntry
+	6,   // 55: migrate.MigrationProgress.phase:type_name -> migrate.MigrationPhase
+	7,   // 56: migrate.ProxyData.type:type_name -> migrate.ProxyDataType
+	9,   // 57: migrate.ProxyData.volume_chunk:type_name -> migrate.VolumeChunk
+	35,  // 58: migrate.ProxyData.layer_blob:type_name -> migrate.LayerBlob
+	39,  // 59: migrate.ProxyData.container_chunk:type_name -> migrate.ContainerChunk
+	41,  // 60: migrate.ProxyData.ack:type_name -> migrate.TransferAck
+	85,  // 61: migrate.ProxyData.handshake:type_name -> migrate.ProxyHandshake
+	86,  // 62: migrate.ProxyData.close:type_name -> migrate.ProxyClose
+	8,   // 63: migrate.ProxyHandshake.role:type_name -> migrate.ProxyRole
+	9,   // 64: migrate.MigrationService.TransferVolume:input_type -> migrate.VolumeChunk
+	35,  // 65: migrate.MigrationService.TransferImageLayers:input_type -> migrate.LayerBlob
+	36,  // 66: migrate.MigrationService.QueryLayers:input_type -> migrate.LayerQuery
+	38,  // 67: migrate.MigrationService.PullImage:input_type -> migrate.ImagePullRequest
+	43,  // 68: migrate.MigrationService.GetResourceList:input_type -> migrate.ResourceRequest
+	49,  // 69: migrate.MigrationService.Ping:input_type -> migrate.Empty
+	39,  // 70: migrate.MigrationService.TransferContainer:input_type -> migrate.ContainerChunk
+	40,  // 71: migrate.MigrationService.TransferNetwork:input_type -> migrate.NetworkConfig
+	49,  // 72: migrate.MigrationService.GetDiskUsage:input_type -> migrate.Empty
+	53,  // 73: migrate.MigrationService.Pair:input_type -> migrate.PairingExchange
+	10,  // 74: migrate.MigrationService.GetVolumeIndex:input_type -> migrate.VolumeIndexRequest
+	11,  // 75: migrate.MigrationService.CheckStartConflicts:input_type -> migrate.StartCheckRequest
+	17,  // 76: migrate.MigrationService.CreateVolume:input_type -> migrate.VolumeSpec
+	18,  // 77: migrate.MigrationService.SampleVolume:input_type -> migrate.VolumeSampleRequest
+	20,  // 78: migrate.MigrationService.InspectImage:input_type -> migrate.ImageInspectRequest
+	49,  // 79: migrate.MigrationService.InspectHost:input_type -> migrate.Empty
+	23,  // 80: migrate.MigrationService.InspectContainer:input_type -> migrate.ContainerInspectRequest
+	25,  // 81: migrate.MigrationService.CreateContainer:input_type -> migrate.ContainerCreateRequest
+	49,  // 82: migrate.MigrationService.CheckpointSupport:input_type -> migrate.Empty
+	30,  // 83: migrate.MigrationService.RestoreContainer:input_type -> migrate.ContainerRestoreRequest
+	31,  // 84: migrate.MigrationService.StartContainer:input_type -> migrate.ContainerStartRequest
+	32,  // 85: migrate.MigrationService.ReceiveBundle:input_type -> migrate.ComposeBundle
+	34,  // 86: migrate.MigrationService.RemoveResources:input_type -> migrate.RemoveResourcesRequest
+	54,  // 87: migrate.MasterService.RegisterWorker:input_type -> migrate.WorkerRegistration
+	56,  // 88: migrate.MasterService.WorkerStream:input_type -> migrate.WorkerMessage
+	61,  // 89: migrate.MasterService.ReportResources:input_type -> migrate.ResourceInventory
+	62,  // 90: migrate.MasterService.RequestMigration:input_type -> migrate.WorkerMigrationRequest
+	65,  // 91: migrate.WorkerService.InitiateMigration:input_type -> migrate.MigrationRequest
+	68,  // 92: migrate.WorkerService.AcceptMigration:input_type -> migrate.AcceptMigrationRequest
+	49,  // 93: migrate.WorkerService.HealthCheck:input_type -> migrate.Empty
+	75,  // 94: migrate.WorkerService.CancelMigration:input_type -> migrate.CancelMigrationRequest
+	84,  // 95: migrate.ProxyService.OpenProxyChannel:input_type -> migrate.ProxyData
+	87,  // 96: migrate.RendezvousService.Listen:input_type -> migrate.RendezvousListen
+	88,  // 97: migrate.RendezvousService.Offer:input_type -> migrate.RendezvousOffer
+	89,  // 98: migrate.RendezvousService.Answer:input_type -> migrate.RendezvousAnswer
+	90,  // 99: migrate.RendezvousService.Relay:input_type -> migrate.RelayFrame
+	41,  // 100: migrate.MigrationService.TransferVolume:output_type -> migrate.TransferAck
+	41,  // 101: migrate.MigrationService.TransferImageLayers:output_type -> migrate.TransferAck
+	37,  // 102: migrate.MigrationService.QueryLayers:output_type -> migrate.LayerQueryResult
+	42,  // 103: migrate.MigrationService.PullImage:output_type -> migrate.TransferResult
+	44,  // 104: migrate.MigrationService.GetResourceList:output_type -> migrate.ResourceList
+	52,  // 105: migrate.MigrationService.Ping:output_type -> migrate.Pong
+	41,  // 106: migrate.MigrationService.TransferContainer:output_type -> migrate.TransferAck
+	42,  // 107: migrate.MigrationService.TransferNetwork:output_type -> migrate.TransferResult
+	51,  // 108: migrate.MigrationService.GetDiskUsage:output_type -> migrate.DiskUsageReport
+	53,  // 109: migrate.MigrationService.Pair:output_type -> migrate.PairingExchange
+	16,  // 110: migrate.MigrationService.GetVolumeIndex:output_type -> migrate.VolumeIndex
+	14,  // 111: migrate.MigrationService.CheckStartConflicts:output_type -> migrate.StartCheckResult
+	17,  // 112: migrate.MigrationService.CreateVolume:output_type -> migrate.VolumeSpec
+	19,  // 113: migrate.MigrationService.SampleVolume:output_type -> migrate.VolumeSample
+	21,  // 114: migrate.MigrationService.InspectImage:output_type -> migrate.ImageInspect
+	22,  // 115: migrate.MigrationService.InspectHost:output_type -> migrate.HostInspect
+	24,  // 116: migrate.MigrationService.InspectContainer:output_type -> migrate.ContainerInspect
+	26,  // 117: migrate.MigrationService.CreateContainer:output_type -> migrate.ContainerCreateResult
+	29,  // 118: migrate.MigrationService.CheckpointSupport:output_type -> migrate.CheckpointSupportResult
+	49,  // 119: migrate.MigrationService.RestoreContainer:output_type -> migrate.Empty
+	49,  // 120: migrate.MigrationService.StartContainer:output_type -> migrate.Empty
+	33,  // 121: migrate.MigrationService.ReceiveBundle:output_type -> migrate.ComposeBundleReceipt
+	44,  // 122: migrate.MigrationService.RemoveResources:output_type -> migrate.ResourceList
+	55,  // 123: migrate.MasterService.RegisterWorker:output_type -> migrate.RegistrationResponse
+	57,  // 124: migrate.MasterService.WorkerStream:output_type -> migrate.MasterCommand
+	64,  // 125: migrate.MasterService.ReportResources:output_type -> migrate.AckResponse
+	63,  // 126: migrate.MasterService.RequestMigration:output_type -> migrate.WorkerMigrationRequestResponse
+	67,  // 127: migrate.WorkerService.InitiateMigration:output_type -> migrate.MigrationResponse
+	70,  // 128: migrate.WorkerService.AcceptMigration:output_type -> migrate.AcceptMigrationResponse
+	71,  // 129: migrate.WorkerService.HealthCheck:output_type -> migrate.HealthResponse
+	76,  // 130: migrate.WorkerService.CancelMigration:output_type -> migrate.CancelMigrationResponse
+	84,  // 131: migrate.ProxyService.OpenProxyChannel:output_type -> migrate.ProxyData
+	88,  // 132: migrate.RendezvousService.Listen:output_type -> migrate.RendezvousOffer
+	89,  // 133: migrate.RendezvousService.Offer:output_type -> migrate.RendezvousAnswer
+	49,  // 134: migrate.RendezvousService.Answer:output_type -> migrate.Empty
+	90,  // 135: migrate.RendezvousService.Relay:output_type -> migrate.RelayFrame
+	100, // [100:136] is the sub-list for method output_type
+	64,  // [64:100] is the sub-list for method input_type
+	64,  // [64:64] is the sub-list for extension type_name
+	64,  // [64:64] is the sub-list for extension extendee
+	0,   // [0:64] is the sub-list for field type_name
 }
 
 func init() { file_proto_migrate_proto_init() }
@@ -4166,20 +7353,23 @@ func file_proto_migrate_proto_init() {
 	if File_proto_migrate_proto != nil {
 		return
 	}
-	file_proto_migrate_proto_msgTypes[16].OneofWrappers = []any{
+	file_proto_migrate_proto_msgTypes[47].OneofWrappers = []any{
 		(*WorkerMessage_Heartbeat)(nil),
 		(*WorkerMessage_MigrationProgress)(nil),
 		(*WorkerMessage_MigrationComplete)(nil),
 		(*WorkerMessage_WorkerError)(nil),
+		(*WorkerMessage_TransferFallback)(nil),
 	}
-	file_proto_migrate_proto_msgTypes[17].OneofWrappers = []any{
+	file_proto_migrate_proto_msgTypes[48].OneofWrappers = []any{
 		(*MasterCommand_HeartbeatAck)(nil),
 		(*MasterCommand_StartMigration)(nil),
 		(*MasterCommand_CancelMigration)(nil),
 		(*MasterCommand_UpdateConfig)(nil),
 		(*MasterCommand_Shutdown)(nil),
+		(*MasterCommand_RotateAuthToken)(nil),
+		(*MasterCommand_ProxyFallback)(nil),
 	}
-	file_proto_migrate_proto_msgTypes[37].OneofWrappers = []any{
+	file_proto_migrate_proto_msgTypes[75].OneofWrappers = []any{
 		(*ProxyData_VolumeChunk)(nil),
 		(*ProxyData_LayerBlob)(nil),
 		(*ProxyData_ContainerChunk)(nil),
@@ -4193,9 +7383,9 @@ func file_proto_migrate_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_migrate_proto_rawDesc), len(file_proto_migrate_proto_rawDesc)),
 			NumEnums:      9,
-			NumMessages:   45,
+			NumMessages:   89,
 			NumExtensions: 0,
-			NumServices:   4,
+			NumServices:   5,
 		},
 		GoTypes:           file_proto_migrate_proto_goTypes,
 		DependencyIndexes: file_proto_migrate_proto_depIdxs,
@@ -21,10 +21,27 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	MigrationService_TransferVolume_FullMethodName      = "/migrate.MigrationService/TransferVolume"
 	MigrationService_TransferImageLayers_FullMethodName = "/migrate.MigrationService/TransferImageLayers"
+	MigrationService_QueryLayers_FullMethodName         = "/migrate.MigrationService/QueryLayers"
+	MigrationService_PullImage_FullMethodName           = "/migrate.MigrationService/PullImage"
 	MigrationService_GetResourceList_FullMethodName     = "/migrate.MigrationService/GetResourceList"
 	MigrationService_Ping_FullMethodName                = "/migrate.MigrationService/Ping"
 	MigrationService_TransferContainer_FullMethodName   = "/migrate.MigrationService/TransferContainer"
 	MigrationService_TransferNetwork_FullMethodName     = "/migrate.MigrationService/TransferNetwork"
+	MigrationService_GetDiskUsage_FullMethodName        = "/migrate.MigrationService/GetDiskUsage"
+	MigrationService_Pair_FullMethodName                = "/migrate.MigrationService/Pair"
+	MigrationService_GetVolumeIndex_FullMethodName      = "/migrate.MigrationService/GetVolumeIndex"
+	MigrationService_CheckStartConflicts_FullMethodName = "/migrate.MigrationService/CheckStartConflicts"
+	MigrationService_CreateVolume_FullMethodName        = "/migrate.MigrationService/CreateVolume"
+	MigrationService_SampleVolume_FullMethodName        = "/migrate.MigrationService/SampleVolume"
+	MigrationService_InspectImage_FullMethodName        = "/migrate.MigrationService/InspectImage"
+	MigrationService_InspectHost_FullMethodName         = "/migrate.MigrationService/InspectHost"
+	MigrationService_InspectContainer_FullMethodName    = "/migrate.MigrationService/InspectContainer"
+	MigrationService_CreateContainer_FullMethodName     = "/migrate.MigrationService/CreateContainer"
+	MigrationService_CheckpointSupport_FullMethodName   = "/migrate.MigrationService/CheckpointSupport"
+	MigrationService_RestoreContainer_FullMethodName    = "/migrate.MigrationService/RestoreContainer"
+	MigrationService_StartContainer_FullMethodName      = "/migrate.MigrationService/StartContainer"
+	MigrationService_ReceiveBundle_FullMethodName       = "/migrate.MigrationService/ReceiveBundle"
+	MigrationService_RemoveResources_FullMethodName     = "/migrate.MigrationService/RemoveResources"
 )
 
 // MigrationServiceClient is the client API for MigrationService service.
@@ -37,6 +54,12 @@ type MigrationServiceClient interface {
 	TransferVolume(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[VolumeChunk, TransferAck], error)
 	// TransferImageLayers transfers image layers as blobs
 	TransferImageLayers(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[LayerBlob, TransferAck], error)
+	// QueryLayers reports which of an image's layers the peer already has, so
+	// the sender can leave them out of the transfer
+	QueryLayers(ctx context.Context, in *LayerQuery, opts ...grpc.CallOption) (*LayerQueryResult, error)
+	// PullImage has the peer pull an image the sender pushed to an
+	// intermediary registry, instead of receiving it over the stream
+	PullImage(ctx context.Context, in *ImagePullRequest, opts ...grpc.CallOption) (*TransferResult, error)
 	// GetResourceList retrieves list of available resources on peer
 	GetResourceList(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*ResourceList, error)
 	// Ping checks peer connectivity and latency
@@ -45,6 +68,45 @@ type MigrationServiceClient interface {
 	TransferContainer(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ContainerChunk, TransferAck], error)
 	// TransferNetwork transfers network configuration
 	TransferNetwork(ctx context.Context, in *NetworkConfig, opts ...grpc.CallOption) (*TransferResult, error)
+	// GetDiskUsage reports the peer's Docker disk usage and free space
+	GetDiskUsage(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DiskUsageReport, error)
+	// Pair completes a pairing exchange with the host that generated the code.
+	// It is the only call open to untrusted clients.
+	Pair(ctx context.Context, in *PairingExchange, opts ...grpc.CallOption) (*PairingExchange, error)
+	// GetVolumeIndex lists a volume's files with size, mtime and hash so the
+	// sender can work out which ones a warm sync needs to send. Large indexes
+	// arrive over several messages.
+	GetVolumeIndex(ctx context.Context, in *VolumeIndexRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[VolumeIndex], error)
+	// CheckStartConflicts reports whether a container's name, host ports and
+	// volumes are still free on the peer, just before it is started there
+	CheckStartConflicts(ctx context.Context, in *StartCheckRequest, opts ...grpc.CallOption) (*StartCheckResult, error)
+	// CreateVolume creates a volume with a driver, its options and labels,
+	// before any files are sent to it. An existing volume with the same driver
+	// is left as it is.
+	CreateVolume(ctx context.Context, in *VolumeSpec, opts ...grpc.CallOption) (*VolumeSpec, error)
+	// SampleVolume counts a volume's files and hashes the requested ones,
+	// read back from the volume rather than from any cached index
+	SampleVolume(ctx context.Context, in *VolumeSampleRequest, opts ...grpc.CallOption) (*VolumeSample, error)
+	// InspectImage reports whether the peer has an image, and its ID
+	InspectImage(ctx context.Context, in *ImageInspectRequest, opts ...grpc.CallOption) (*ImageInspect, error)
+	// InspectHost reports the hostname the peer records as a migration source
+	InspectHost(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*HostInspect, error)
+	// InspectContainer reports a container's state on the peer
+	InspectContainer(ctx context.Context, in *ContainerInspectRequest, opts ...grpc.CallOption) (*ContainerInspect, error)
+	// CreateContainer recreates a container from its exported state and
+	// reports the settings the peer could not apply. It is not started.
+	CreateContainer(ctx context.Context, in *ContainerCreateRequest, opts ...grpc.CallOption) (*ContainerCreateResult, error)
+	// CheckpointSupport reports why the peer cannot restore checkpoints
+	CheckpointSupport(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CheckpointSupportResult, error)
+	// RestoreContainer starts a created container from a checkpoint
+	RestoreContainer(ctx context.Context, in *ContainerRestoreRequest, opts ...grpc.CallOption) (*Empty, error)
+	// StartContainer starts a created container without a checkpoint
+	StartContainer(ctx context.Context, in *ContainerStartRequest, opts ...grpc.CallOption) (*Empty, error)
+	// ReceiveBundle stores a compose stack's bundle on the peer
+	ReceiveBundle(ctx context.Context, in *ComposeBundle, opts ...grpc.CallOption) (*ComposeBundleReceipt, error)
+	// RemoveResources deletes what a failed migration left on the peer and
+	// returns what it removed
+	RemoveResources(ctx context.Context, in *RemoveResourcesRequest, opts ...grpc.CallOption) (*ResourceList, error)
 }
 
 type migrationServiceClient struct {
@@ -81,6 +143,26 @@ func (c *migrationServiceClient) TransferImageLayers(ctx context.Context, opts .
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MigrationService_TransferImageLayersClient = grpc.BidiStreamingClient[LayerBlob, TransferAck]
 
+func (c *migrationServiceClient) QueryLayers(ctx context.Context, in *LayerQuery, opts ...grpc.CallOption) (*LayerQueryResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LayerQueryResult)
+	err := c.cc.Invoke(ctx, MigrationService_QueryLayers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) PullImage(ctx context.Context, in *ImagePullRequest, opts ...grpc.CallOption) (*TransferResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransferResult)
+	err := c.cc.Invoke(ctx, MigrationService_PullImage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *migrationServiceClient) GetResourceList(ctx context.Context, in *ResourceRequest, opts ...grpc.CallOption) (*ResourceList, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ResourceList)
@@ -124,6 +206,165 @@ func (c *migrationServiceClient) TransferNetwork(ctx context.Context, in *Networ
 	return out, nil
 }
 
+func (c *migrationServiceClient) GetDiskUsage(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DiskUsageReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DiskUsageReport)
+	err := c.cc.Invoke(ctx, MigrationService_GetDiskUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) Pair(ctx context.Context, in *PairingExchange, opts ...grpc.CallOption) (*PairingExchange, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PairingExchange)
+	err := c.cc.Invoke(ctx, MigrationService_Pair_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) GetVolumeIndex(ctx context.Context, in *VolumeIndexRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[VolumeIndex], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MigrationService_ServiceDesc.Streams[3], MigrationService_GetVolumeIndex_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[VolumeIndexRequest, VolumeIndex]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MigrationService_GetVolumeIndexClient = grpc.ServerStreamingClient[VolumeIndex]
+
+func (c *migrationServiceClient) CheckStartConflicts(ctx context.Context, in *StartCheckRequest, opts ...grpc.CallOption) (*StartCheckResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartCheckResult)
+	err := c.cc.Invoke(ctx, MigrationService_CheckStartConflicts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) CreateVolume(ctx context.Context, in *VolumeSpec, opts ...grpc.CallOption) (*VolumeSpec, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VolumeSpec)
+	err := c.cc.Invoke(ctx, MigrationService_CreateVolume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) SampleVolume(ctx context.Context, in *VolumeSampleRequest, opts ...grpc.CallOption) (*VolumeSample, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VolumeSample)
+	err := c.cc.Invoke(ctx, MigrationService_SampleVolume_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) InspectImage(ctx context.Context, in *ImageInspectRequest, opts ...grpc.CallOption) (*ImageInspect, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImageInspect)
+	err := c.cc.Invoke(ctx, MigrationService_InspectImage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) InspectHost(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*HostInspect, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HostInspect)
+	err := c.cc.Invoke(ctx, MigrationService_InspectHost_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) InspectContainer(ctx context.Context, in *ContainerInspectRequest, opts ...grpc.CallOption) (*ContainerInspect, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContainerInspect)
+	err := c.cc.Invoke(ctx, MigrationService_InspectContainer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) CreateContainer(ctx context.Context, in *ContainerCreateRequest, opts ...grpc.CallOption) (*ContainerCreateResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ContainerCreateResult)
+	err := c.cc.Invoke(ctx, MigrationService_CreateContainer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) CheckpointSupport(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CheckpointSupportResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CheckpointSupportResult)
+	err := c.cc.Invoke(ctx, MigrationService_CheckpointSupport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) RestoreContainer(ctx context.Context, in *ContainerRestoreRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, MigrationService_RestoreContainer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) StartContainer(ctx context.Context, in *ContainerStartRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, MigrationService_StartContainer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) ReceiveBundle(ctx context.Context, in *ComposeBundle, opts ...grpc.CallOption) (*ComposeBundleReceipt, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ComposeBundleReceipt)
+	err := c.cc.Invoke(ctx, MigrationService_ReceiveBundle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *migrationServiceClient) RemoveResources(ctx context.Context, in *RemoveResourcesRequest, opts ...grpc.CallOption) (*ResourceList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResourceList)
+	err := c.cc.Invoke(ctx, MigrationService_RemoveResources_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MigrationServiceServer is the server API for MigrationService service.
 // All implementations must embed UnimplementedMigrationServiceServer
 // for forward compatibility.
@@ -134,6 +375,12 @@ type MigrationServiceServer interface {
 	TransferVolume(grpc.BidiStreamingServer[VolumeChunk, TransferAck]) error
 	// TransferImageLayers transfers image layers as blobs
 	TransferImageLayers(grpc.BidiStreamingServer[LayerBlob, TransferAck]) error
+	// QueryLayers reports which of an image's layers the peer already has, so
+	// the sender can leave them out of the transfer
+	QueryLayers(context.Context, *LayerQuery) (*LayerQueryResult, error)
+	// PullImage has the peer pull an image the sender pushed to an
+	// intermediary registry, instead of receiving it over the stream
+	PullImage(context.Context, *ImagePullRequest) (*TransferResult, error)
 	// GetResourceList retrieves list of available resources on peer
 	GetResourceList(context.Context, *ResourceRequest) (*ResourceList, error)
 	// Ping checks peer connectivity and latency
@@ -142,6 +389,45 @@ type MigrationServiceServer interface {
 	TransferContainer(grpc.BidiStreamingServer[ContainerChunk, TransferAck]) error
 	// TransferNetwork transfers network configuration
 	TransferNetwork(context.Context, *NetworkConfig) (*TransferResult, error)
+	// GetDiskUsage reports the peer's Docker disk usage and free space
+	GetDiskUsage(context.Context, *Empty) (*DiskUsageReport, error)
+	// Pair completes a pairing exchange with the host that generated the code.
+	// It is the only call open to untrusted clients.
+	Pair(context.Context, *PairingExchange) (*PairingExchange, error)
+	// GetVolumeIndex lists a volume's files with size, mtime and hash so the
+	// sender can work out which ones a warm sync needs to send. Large indexes
+	// arrive over several messages.
+	GetVolumeIndex(*VolumeIndexRequest, grpc.ServerStreamingServer[VolumeIndex]) error
+	// CheckStartConflicts reports whether a container's name, host ports and
+	// volumes are still free on the peer, just before it is started there
+	CheckStartConflicts(context.Context, *StartCheckRequest) (*StartCheckResult, error)
+	// CreateVolume creates a volume with a driver, its options and labels,
+	// before any files are sent to it. An existing volume with the same driver
+	// is left as it is.
+	CreateVolume(context.Context, *VolumeSpec) (*VolumeSpec, error)
+	// SampleVolume counts a volume's files and hashes the requested ones,
+	// read back from the volume rather than from any cached index
+	SampleVolume(context.Context, *VolumeSampleRequest) (*VolumeSample, error)
+	// InspectImage reports whether the peer has an image, and its ID
+	InspectImage(context.Context, *ImageInspectRequest) (*ImageInspect, error)
+	// InspectHost reports the hostname the peer records as a migration source
+	InspectHost(context.Context, *Empty) (*HostInspect, error)
+	// InspectContainer reports a container's state on the peer
+	InspectContainer(context.Context, *ContainerInspectRequest) (*ContainerInspect, error)
+	// CreateContainer recreates a container from its exported state and
+	// reports the settings the peer could not apply. It is not started.
+	CreateContainer(context.Context, *ContainerCreateRequest) (*ContainerCreateResult, error)
+	// CheckpointSupport reports why the peer cannot restore checkpoints
+	CheckpointSupport(context.Context, *Empty) (*CheckpointSupportResult, error)
+	// RestoreContainer starts a created container from a checkpoint
+	RestoreContainer(context.Context, *ContainerRestoreRequest) (*Empty, error)
+	// StartContainer starts a created container without a checkpoint
+	StartContainer(context.Context, *ContainerStartRequest) (*Empty, error)
+	// ReceiveBundle stores a compose stack's bundle on the peer
+	ReceiveBundle(context.Context, *ComposeBundle) (*ComposeBundleReceipt, error)
+	// RemoveResources deletes what a failed migration left on the peer and
+	// returns what it removed
+	RemoveResources(context.Context, *RemoveResourcesRequest) (*ResourceList, error)
 	mustEmbedUnimplementedMigrationServiceServer()
 }
 
@@ -158,6 +444,12 @@ func (UnimplementedMigrationServiceServer) TransferVolume(grpc.BidiStreamingServ
 func (UnimplementedMigrationServiceServer) TransferImageLayers(grpc.BidiStreamingServer[LayerBlob, TransferAck]) error {
 	return status.Error(codes.Unimplemented, "method TransferImageLayers not implemented")
 }
+func (UnimplementedMigrationServiceServer) QueryLayers(context.Context, *LayerQuery) (*LayerQueryResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method QueryLayers not implemented")
+}
+func (UnimplementedMigrationServiceServer) PullImage(context.Context, *ImagePullRequest) (*TransferResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method PullImage not implemented")
+}
 func (UnimplementedMigrationServiceServer) GetResourceList(context.Context, *ResourceRequest) (*ResourceList, error) {
 	return nil, status.Error(codes.Unimplemented, "method GetResourceList not implemented")
 }
@@ -170,6 +462,51 @@ func (UnimplementedMigrationServiceServer) TransferContainer(grpc.BidiStreamingS
 func (UnimplementedMigrationServiceServer) TransferNetwork(context.Context, *NetworkConfig) (*TransferResult, error) {
 	return nil, status.Error(codes.Unimplemented, "method TransferNetwork not implemented")
 }
+func (UnimplementedMigrationServiceServer) GetDiskUsage(context.Context, *Empty) (*DiskUsageReport, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetDiskUsage not implemented")
+}
+func (UnimplementedMigrationServiceServer) Pair(context.Context, *PairingExchange) (*PairingExchange, error) {
+	return nil, status.Error(codes.Unimplemented, "method Pair not implemented")
+}
+func (UnimplementedMigrationServiceServer) GetVolumeIndex(*VolumeIndexRequest, grpc.ServerStreamingServer[VolumeIndex]) error {
+	return status.Error(codes.Unimplemented, "method GetVolumeIndex not implemented")
+}
+func (UnimplementedMigrationServiceServer) CheckStartConflicts(context.Context, *StartCheckRequest) (*StartCheckResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method CheckStartConflicts not implemented")
+}
+func (UnimplementedMigrationServiceServer) CreateVolume(context.Context, *VolumeSpec) (*VolumeSpec, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateVolume not implemented")
+}
+func (UnimplementedMigrationServiceServer) SampleVolume(context.Context, *VolumeSampleRequest) (*VolumeSample, error) {
+	return nil, status.Error(codes.Unimplemented, "method SampleVolume not implemented")
+}
+func (UnimplementedMigrationServiceServer) InspectImage(context.Context, *ImageInspectRequest) (*ImageInspect, error) {
+	return nil, status.Error(codes.Unimplemented, "method InspectImage not implemented")
+}
+func (UnimplementedMigrationServiceServer) InspectHost(context.Context, *Empty) (*HostInspect, error) {
+	return nil, status.Error(codes.Unimplemented, "method InspectHost not implemented")
+}
+func (UnimplementedMigrationServiceServer) InspectContainer(context.Context, *ContainerInspectRequest) (*ContainerInspect, error) {
+	return nil, status.Error(codes.Unimplemented, "method InspectContainer not implemented")
+}
+func (UnimplementedMigrationServiceServer) CreateContainer(context.Context, *ContainerCreateRequest) (*ContainerCreateResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateContainer not implemented")
+}
+func (UnimplementedMigrationServiceServer) CheckpointSupport(context.Context, *Empty) (*CheckpointSupportResult, error) {
+	return nil, status.Error(codes.Unimplemented, "method CheckpointSupport not implemented")
+}
+func (UnimplementedMigrationServiceServer) RestoreContainer(context.Context, *ContainerRestoreRequest) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method RestoreContainer not implemented")
+}
+func (UnimplementedMigrationServiceServer) StartContainer(context.Context, *ContainerStartRequest) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method StartContainer not implemented")
+}
+func (UnimplementedMigrationServiceServer) ReceiveBundle(context.Context, *ComposeBundle) (*ComposeBundleReceipt, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReceiveBundle not implemented")
+}
+func (UnimplementedMigrationServiceServer) RemoveResources(context.Context, *RemoveResourcesRequest) (*ResourceList, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveResources not implemented")
+}
 func (UnimplementedMigrationServiceServer) mustEmbedUnimplementedMigrationServiceServer() {}
 func (UnimplementedMigrationServiceServer) testEmbeddedByValue()                          {}
 
@@ -188,80 +525,379 @@ func RegisterMigrationServiceServer(s grpc.ServiceRegistrar, srv MigrationServic
 	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
 		t.testEmbeddedByValue()
 	}
-	s.RegisterService(&MigrationService_ServiceDesc, srv)
+	s.RegisterService(&MigrationService_ServiceDesc, srv)
+}
+
+func _MigrationService_TransferVolume_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MigrationServiceServer).TransferVolume(&grpc.GenericServerStream[VolumeChunk, TransferAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MigrationService_TransferVolumeServer = grpc.BidiStreamingServer[VolumeChunk, TransferAck]
+
+func _MigrationService_TransferImageLayers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MigrationServiceServer).TransferImageLayers(&grpc.GenericServerStream[LayerBlob, TransferAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MigrationService_TransferImageLayersServer = grpc.BidiStreamingServer[LayerBlob, TransferAck]
+
+func _MigrationService_QueryLayers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LayerQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).QueryLayers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_QueryLayers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).QueryLayers(ctx, req.(*LayerQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_PullImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImagePullRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).PullImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_PullImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).PullImage(ctx, req.(*ImagePullRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_GetResourceList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResourceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).GetResourceList(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_GetResourceList_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).GetResourceList(ctx, req.(*ResourceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_Ping_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).Ping(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_TransferContainer_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MigrationServiceServer).TransferContainer(&grpc.GenericServerStream[ContainerChunk, TransferAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MigrationService_TransferContainerServer = grpc.BidiStreamingServer[ContainerChunk, TransferAck]
+
+func _MigrationService_TransferNetwork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NetworkConfig)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).TransferNetwork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_TransferNetwork_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).TransferNetwork(ctx, req.(*NetworkConfig))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_GetDiskUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).GetDiskUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_GetDiskUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).GetDiskUsage(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_Pair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PairingExchange)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).Pair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_Pair_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).Pair(ctx, req.(*PairingExchange))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_GetVolumeIndex_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VolumeIndexRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MigrationServiceServer).GetVolumeIndex(m, &grpc.GenericServerStream[VolumeIndexRequest, VolumeIndex]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MigrationService_GetVolumeIndexServer = grpc.ServerStreamingServer[VolumeIndex]
+
+func _MigrationService_CheckStartConflicts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).CheckStartConflicts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_CheckStartConflicts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).CheckStartConflicts(ctx, req.(*StartCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_CreateVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VolumeSpec)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).CreateVolume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_CreateVolume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).CreateVolume(ctx, req.(*VolumeSpec))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_SampleVolume_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VolumeSampleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).SampleVolume(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_SampleVolume_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).SampleVolume(ctx, req.(*VolumeSampleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_InspectImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImageInspectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).InspectImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_InspectImage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).InspectImage(ctx, req.(*ImageInspectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_InspectHost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).InspectHost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_InspectHost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).InspectHost(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MigrationService_InspectContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContainerInspectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).InspectContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_InspectContainer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).InspectContainer(ctx, req.(*ContainerInspectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _MigrationService_TransferVolume_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(MigrationServiceServer).TransferVolume(&grpc.GenericServerStream[VolumeChunk, TransferAck]{ServerStream: stream})
+func _MigrationService_CreateContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContainerCreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).CreateContainer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_CreateContainer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).CreateContainer(ctx, req.(*ContainerCreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type MigrationService_TransferVolumeServer = grpc.BidiStreamingServer[VolumeChunk, TransferAck]
-
-func _MigrationService_TransferImageLayers_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(MigrationServiceServer).TransferImageLayers(&grpc.GenericServerStream[LayerBlob, TransferAck]{ServerStream: stream})
+func _MigrationService_CheckpointSupport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).CheckpointSupport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_CheckpointSupport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).CheckpointSupport(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type MigrationService_TransferImageLayersServer = grpc.BidiStreamingServer[LayerBlob, TransferAck]
-
-func _MigrationService_GetResourceList_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ResourceRequest)
+func _MigrationService_RestoreContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContainerRestoreRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MigrationServiceServer).GetResourceList(ctx, in)
+		return srv.(MigrationServiceServer).RestoreContainer(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MigrationService_GetResourceList_FullMethodName,
+		FullMethod: MigrationService_RestoreContainer_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MigrationServiceServer).GetResourceList(ctx, req.(*ResourceRequest))
+		return srv.(MigrationServiceServer).RestoreContainer(ctx, req.(*ContainerRestoreRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MigrationService_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+func _MigrationService_StartContainer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContainerStartRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MigrationServiceServer).Ping(ctx, in)
+		return srv.(MigrationServiceServer).StartContainer(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MigrationService_Ping_FullMethodName,
+		FullMethod: MigrationService_StartContainer_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MigrationServiceServer).Ping(ctx, req.(*Empty))
+		return srv.(MigrationServiceServer).StartContainer(ctx, req.(*ContainerStartRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MigrationService_TransferContainer_Handler(srv interface{}, stream grpc.ServerStream) error {
-	return srv.(MigrationServiceServer).TransferContainer(&grpc.GenericServerStream[ContainerChunk, TransferAck]{ServerStream: stream})
+func _MigrationService_ReceiveBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ComposeBundle)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MigrationServiceServer).ReceiveBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MigrationService_ReceiveBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MigrationServiceServer).ReceiveBundle(ctx, req.(*ComposeBundle))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
-type MigrationService_TransferContainerServer = grpc.BidiStreamingServer[ContainerChunk, TransferAck]
-
-func _MigrationService_TransferNetwork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(NetworkConfig)
+func _MigrationService_RemoveResources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveResourcesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MigrationServiceServer).TransferNetwork(ctx, in)
+		return srv.(MigrationServiceServer).RemoveResources(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MigrationService_TransferNetwork_FullMethodName,
+		FullMethod: MigrationService_RemoveResources_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MigrationServiceServer).TransferNetwork(ctx, req.(*NetworkConfig))
+		return srv.(MigrationServiceServer).RemoveResources(ctx, req.(*RemoveResourcesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -273,6 +909,14 @@ var MigrationService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "migrate.MigrationService",
 	HandlerType: (*MigrationServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "QueryLayers",
+			Handler:    _MigrationService_QueryLayers_Handler,
+		},
+		{
+			MethodName: "PullImage",
+			Handler:    _MigrationService_PullImage_Handler,
+		},
 		{
 			MethodName: "GetResourceList",
 			Handler:    _MigrationService_GetResourceList_Handler,
@@ -285,6 +929,62 @@ var MigrationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "TransferNetwork",
 			Handler:    _MigrationService_TransferNetwork_Handler,
 		},
+		{
+			MethodName: "GetDiskUsage",
+			Handler:    _MigrationService_GetDiskUsage_Handler,
+		},
+		{
+			MethodName: "Pair",
+			Handler:    _MigrationService_Pair_Handler,
+		},
+		{
+			MethodName: "CheckStartConflicts",
+			Handler:    _MigrationService_CheckStartConflicts_Handler,
+		},
+		{
+			MethodName: "CreateVolume",
+			Handler:    _MigrationService_CreateVolume_Handler,
+		},
+		{
+			MethodName: "SampleVolume",
+			Handler:    _MigrationService_SampleVolume_Handler,
+		},
+		{
+			MethodName: "InspectImage",
+			Handler:    _MigrationService_InspectImage_Handler,
+		},
+		{
+			MethodName: "InspectHost",
+			Handler:    _MigrationService_InspectHost_Handler,
+		},
+		{
+			MethodName: "InspectContainer",
+			Handler:    _MigrationService_InspectContainer_Handler,
+		},
+		{
+			MethodName: "CreateContainer",
+			Handler:    _MigrationService_CreateContainer_Handler,
+		},
+		{
+			MethodName: "CheckpointSupport",
+			Handler:    _MigrationService_CheckpointSupport_Handler,
+		},
+		{
+			MethodName: "RestoreContainer",
+			Handler:    _MigrationService_RestoreContainer_Handler,
+		},
+		{
+			MethodName: "StartContainer",
+			Handler:    _MigrationService_StartContainer_Handler,
+		},
+		{
+			MethodName: "ReceiveBundle",
+			Handler:    _MigrationService_ReceiveBundle_Handler,
+		},
+		{
+			MethodName: "RemoveResources",
+			Handler:    _MigrationService_RemoveResources_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -305,14 +1005,20 @@ var MigrationService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "GetVolumeIndex",
+			Handler:       _MigrationService_GetVolumeIndex_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "proto/migrate.proto",
 }
 
 const (
-	MasterService_RegisterWorker_FullMethodName  = "/migrate.MasterService/RegisterWorker"
-	MasterService_WorkerStream_FullMethodName    = "/migrate.MasterService/WorkerStream"
-	MasterService_ReportResources_FullMethodName = "/migrate.MasterService/ReportResources"
+	MasterService_RegisterWorker_FullMethodName   = "/migrate.MasterService/RegisterWorker"
+	MasterService_WorkerStream_FullMethodName     = "/migrate.MasterService/WorkerStream"
+	MasterService_ReportResources_FullMethodName  = "/migrate.MasterService/ReportResources"
+	MasterService_RequestMigration_FullMethodName = "/migrate.MasterService/RequestMigration"
 )
 
 // MasterServiceClient is the client API for MasterService service.
@@ -327,6 +1033,9 @@ type MasterServiceClient interface {
 	WorkerStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[WorkerMessage, MasterCommand], error)
 	// ReportResources reports worker's Docker resource inventory
 	ReportResources(ctx context.Context, in *ResourceInventory, opts ...grpc.CallOption) (*AckResponse, error)
+	// RequestMigration asks the master to migrate resources off the calling
+	// worker; the job waits for an admin to approve it
+	RequestMigration(ctx context.Context, in *WorkerMigrationRequest, opts ...grpc.CallOption) (*WorkerMigrationRequestResponse, error)
 }
 
 type masterServiceClient struct {
@@ -370,6 +1079,16 @@ func (c *masterServiceClient) ReportResources(ctx context.Context, in *ResourceI
 	return out, nil
 }
 
+func (c *masterServiceClient) RequestMigration(ctx context.Context, in *WorkerMigrationRequest, opts ...grpc.CallOption) (*WorkerMigrationRequestResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WorkerMigrationRequestResponse)
+	err := c.cc.Invoke(ctx, MasterService_RequestMigration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MasterServiceServer is the server API for MasterService service.
 // All implementations must embed UnimplementedMasterServiceServer
 // for forward compatibility.
@@ -382,6 +1101,9 @@ type MasterServiceServer interface {
 	WorkerStream(grpc.BidiStreamingServer[WorkerMessage, MasterCommand]) error
 	// ReportResources reports worker's Docker resource inventory
 	ReportResources(context.Context, *ResourceInventory) (*AckResponse, error)
+	// RequestMigration asks the master to migrate resources off the calling
+	// worker; the job waits for an admin to approve it
+	RequestMigration(context.Context, *WorkerMigrationRequest) (*WorkerMigrationRequestResponse, error)
 	mustEmbedUnimplementedMasterServiceServer()
 }
 
@@ -401,6 +1123,9 @@ func (UnimplementedMasterServiceServer) WorkerStream(grpc.BidiStreamingServer[Wo
 func (UnimplementedMasterServiceServer) ReportResources(context.Context, *ResourceInventory) (*AckResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method ReportResources not implemented")
 }
+func (UnimplementedMasterServiceServer) RequestMigration(context.Context, *WorkerMigrationRequest) (*WorkerMigrationRequestResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RequestMigration not implemented")
+}
 func (UnimplementedMasterServiceServer) mustEmbedUnimplementedMasterServiceServer() {}
 func (UnimplementedMasterServiceServer) testEmbeddedByValue()                       {}
 
@@ -465,6 +1190,24 @@ func _MasterService_ReportResources_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MasterService_RequestMigration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WorkerMigrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MasterServiceServer).RequestMigration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MasterService_RequestMigration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MasterServiceServer).RequestMigration(ctx, req.(*WorkerMigrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MasterService_ServiceDesc is the grpc.ServiceDesc for MasterService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -480,6 +1223,10 @@ var MasterService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReportResources",
 			Handler:    _MasterService_ReportResources_Handler,
 		},
+		{
+			MethodName: "RequestMigration",
+			Handler:    _MasterService_RequestMigration_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -823,3 +1570,233 @@ var ProxyService_ServiceDesc = grpc.ServiceDesc{
 	},
 	Metadata: "proto/migrate.proto",
 }
+
+const (
+	RendezvousService_Listen_FullMethodName = "/migrate.RendezvousService/Listen"
+	RendezvousService_Offer_FullMethodName  = "/migrate.RendezvousService/Offer"
+	RendezvousService_Answer_FullMethodName = "/migrate.RendezvousService/Answer"
+	RendezvousService_Relay_FullMethodName  = "/migrate.RendezvousService/Relay"
+)
+
+// RendezvousServiceClient is the client API for RendezvousService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RendezvousService lets P2P nodes that cannot reach each other exchange
+// candidate addresses for UDP hole punching, and relays their traffic when no
+// hole can be punched. Callers are identified by their TLS certificate.
+type RendezvousServiceClient interface {
+	// Listen delivers offers from peers that want to connect to the caller
+	Listen(ctx context.Context, in *RendezvousListen, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RendezvousOffer], error)
+	// Offer sends the caller's candidates to a listening peer and returns its answer
+	Offer(ctx context.Context, in *RendezvousOffer, opts ...grpc.CallOption) (*RendezvousAnswer, error)
+	// Answer replies to an offer received through Listen
+	Answer(ctx context.Context, in *RendezvousAnswer, opts ...grpc.CallOption) (*Empty, error)
+	// Relay pipes bytes between the two sides of an offered session
+	Relay(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RelayFrame, RelayFrame], error)
+}
+
+type rendezvousServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRendezvousServiceClient(cc grpc.ClientConnInterface) RendezvousServiceClient {
+	return &rendezvousServiceClient{cc}
+}
+
+func (c *rendezvousServiceClient) Listen(ctx context.Context, in *RendezvousListen, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RendezvousOffer], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RendezvousService_ServiceDesc.Streams[0], RendezvousService_Listen_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RendezvousListen, RendezvousOffer]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RendezvousService_ListenClient = grpc.ServerStreamingClient[RendezvousOffer]
+
+func (c *rendezvousServiceClient) Offer(ctx context.Context, in *RendezvousOffer, opts ...grpc.CallOption) (*RendezvousAnswer, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RendezvousAnswer)
+	err := c.cc.Invoke(ctx, RendezvousService_Offer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rendezvousServiceClient) Answer(ctx context.Context, in *RendezvousAnswer, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, RendezvousService_Answer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rendezvousServiceClient) Relay(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[RelayFrame, RelayFrame], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RendezvousService_ServiceDesc.Streams[1], RendezvousService_Relay_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RelayFrame, RelayFrame]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RendezvousService_RelayClient = grpc.BidiStreamingClient[RelayFrame, RelayFrame]
+
+// RendezvousServiceServer is the server API for RendezvousService service.
+// All implementations must embed UnimplementedRendezvousServiceServer
+// for forward compatibility.
+//
+// RendezvousService lets P2P nodes that cannot reach each other exchange
+// candidate addresses for UDP hole punching, and relays their traffic when no
+// hole can be punched. Callers are identified by their TLS certificate.
+type RendezvousServiceServer interface {
+	// Listen delivers offers from peers that want to connect to the caller
+	Listen(*RendezvousListen, grpc.ServerStreamingServer[RendezvousOffer]) error
+	// Offer sends the caller's candidates to a listening peer and returns its answer
+	Offer(context.Context, *RendezvousOffer) (*RendezvousAnswer, error)
+	// Answer replies to an offer received through Listen
+	Answer(context.Context, *RendezvousAnswer) (*Empty, error)
+	// Relay pipes bytes between the two sides of an offered session
+	Relay(grpc.BidiStreamingServer[RelayFrame, RelayFrame]) error
+	mustEmbedUnimplementedRendezvousServiceServer()
+}
+
+// UnimplementedRendezvousServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRendezvousServiceServer struct{}
+
+func (UnimplementedRendezvousServiceServer) Listen(*RendezvousListen, grpc.ServerStreamingServer[RendezvousOffer]) error {
+	return status.Error(codes.Unimplemented, "method Listen not implemented")
+}
+func (UnimplementedRendezvousServiceServer) Offer(context.Context, *RendezvousOffer) (*RendezvousAnswer, error) {
+	return nil, status.Error(codes.Unimplemented, "method Offer not implemented")
+}
+func (UnimplementedRendezvousServiceServer) Answer(context.Context, *RendezvousAnswer) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Answer not implemented")
+}
+func (UnimplementedRendezvousServiceServer) Relay(grpc.BidiStreamingServer[RelayFrame, RelayFrame]) error {
+	return status.Error(codes.Unimplemented, "method Relay not implemented")
+}
+func (UnimplementedRendezvousServiceServer) mustEmbedUnimplementedRendezvousServiceServer() {}
+func (UnimplementedRendezvousServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeRendezvousServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RendezvousServiceServer will
+// result in compilation errors.
+type UnsafeRendezvousServiceServer interface {
+	mustEmbedUnimplementedRendezvousServiceServer()
+}
+
+func RegisterRendezvousServiceServer(s grpc.ServiceRegistrar, srv RendezvousServiceServer) {
+	// If the following call panics, it indicates UnimplementedRendezvousServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RendezvousService_ServiceDesc, srv)
+}
+
+func _RendezvousService_Listen_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RendezvousListen)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RendezvousServiceServer).Listen(m, &grpc.GenericServerStream[RendezvousListen, RendezvousOffer]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RendezvousService_ListenServer = grpc.ServerStreamingServer[RendezvousOffer]
+
+func _RendezvousService_Offer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RendezvousOffer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RendezvousServiceServer).Offer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RendezvousService_Offer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RendezvousServiceServer).Offer(ctx, req.(*RendezvousOffer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RendezvousService_Answer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RendezvousAnswer)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RendezvousServiceServer).Answer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RendezvousService_Answer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RendezvousServiceServer).Answer(ctx, req.(*RendezvousAnswer))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RendezvousService_Relay_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RendezvousServiceServer).Relay(&grpc.GenericServerStream[RelayFrame, RelayFrame]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RendezvousService_RelayServer = grpc.BidiStreamingServer[RelayFrame, RelayFrame]
+
+// RendezvousService_ServiceDesc is the grpc.ServiceDesc for RendezvousService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RendezvousService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "migrate.RendezvousService",
+	HandlerType: (*RendezvousServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Offer",
+			Handler:    _RendezvousService_Offer_Handler,
+		},
+		{
+			MethodName: "Answer",
+			Handler:    _RendezvousService_Answer_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Listen",
+			Handler:       _RendezvousService_Listen_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Relay",
+			Handler:       _RendezvousService_Relay_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/migrate.proto",
+}
@@ -8,6 +8,7 @@ set -e
 # Default values
 MASTER_URL=""
 TOKEN=""
+MASTER_FINGERPRINT=""
 WORKER_NAME=$(hostname)
 INSTALL_DIR="/usr/local/bin"
 CONFIG_DIR="/etc/docker-migrate"
@@ -43,6 +44,10 @@ while [[ $# -gt 0 ]]; do
             TOKEN="$2"
             shift 2
             ;;
+        --master-fingerprint)
+            MASTER_FINGERPRINT="$2"
+            shift 2
+            ;;
         --name)
             WORKER_NAME="$2"
             shift 2
@@ -56,11 +61,12 @@ while [[ $# -gt 0 ]]; do
             shift
             ;;
         --help)
-            echo "Usage: $0 --master-url URL --token TOKEN [--name NAME]"
+            echo "Usage: $0 --master-url URL --token TOKEN [--master-fingerprint FP] [--name NAME]"
             echo ""
             echo "Options:"
             echo "  --master-url        Master gRPC URL (required)"
             echo "  --token             Enrollment token from master (required)"
+            echo "  --master-fingerprint Master certificate fingerprint (required)"
             echo "  --name              Worker name (default: hostname)"
             echo "  --install-dir       Binary installation directory (default: /usr/local/bin)"
             echo "  --skip-docker-check Skip Docker installation check"
@@ -84,6 +90,12 @@ if [ -z "$TOKEN" ]; then
     exit 1
 fi
 
+if [ -z "$MASTER_FINGERPRINT" ]; then
+    log_error "--master-fingerprint is required (the master logs it at startup as master_fingerprint)"
+    exit 1
+fi
+FINGERPRINT_FLAG=" --master-fingerprint ${MASTER_FINGERPRINT}"
+
 # Detect OS and architecture
 OS=$(uname -s | tr '[:upper:]' '[:lower:]')
 ARCH=$(uname -m)
@@ -107,7 +119,7 @@ log_info "Detected OS: $OS, Architecture: $ARCH"
 if [ "$OS" != "linux" ]; then
     log_error "Service installation is only supported on Linux"
     log_info "For other platforms, download the binary manually and run:"
-    log_info "  docker-migrate worker --master-url $MASTER_URL --token $TOKEN"
+    log_info "  docker-migrate worker --master-url $MASTER_URL --token $TOKEN --master-fingerprint $MASTER_FINGERPRINT"
     exit 1
 fi
 
@@ -349,7 +361,7 @@ ${DOCKER_REQUIRES}
 [Service]
 Type=simple
 User=${SERVICE_USER}
-ExecStart=${BINARY_PATH} worker --master-url ${MASTER_URL} --token ${TOKEN} --name ${WORKER_NAME} --config ${CONFIG_FILE}
+ExecStart=${BINARY_PATH} worker --master-url ${MASTER_URL} --token ${TOKEN}${FINGERPRINT_FLAG} --name ${WORKER_NAME} --config ${CONFIG_FILE}
 Restart=always
 RestartSec=10
 StartLimitInterval=60
@@ -6,11 +6,14 @@ import { ToastContainer } from './components/common/Toast';
 import { ConnectionStatus } from './components/common/ConnectionStatus';
 import { ResourceCard } from './components/Dashboard/ResourceCard';
 import { PeerList } from './components/Dashboard/PeerList';
+import { PendingPeers } from './components/Dashboard/PendingPeers';
+import { NearbyPeers } from './components/Dashboard/NearbyPeers';
 import { WorkerList } from './components/Dashboard/WorkerList';
 import { QuickActions } from './components/Dashboard/QuickActions';
 import { MasterQuickActions } from './components/Dashboard/MasterQuickActions';
 import { GenerateCode } from './components/Pairing/GenerateCode';
 import { EnterCode } from './components/Pairing/EnterCode';
+import { VerifyPairing } from './components/Pairing/VerifyPairing';
 import { PreFlightChecks } from './components/Migration/PreFlightChecks';
 import { MigrationProgress } from './components/Migration/MigrationProgress';
 import { MigrationComplete } from './components/Migration/MigrationComplete';
@@ -22,6 +25,7 @@ import type {
   Peer,
   Worker,
   PairingCode,
+  PairedPeer,
   ResourceCounts,
   MigrationState,
   WSMessage,
@@ -39,7 +43,7 @@ const queryClient = new QueryClient({
   },
 });
 
-type View = 'dashboard' | 'generate-code' | 'enter-code' | 'migration' | 'migration-wizard' | 'worker-resources' | 'containers' | 'images' | 'volumes' | 'networks' | 'compose';
+type View = 'dashboard' | 'generate-code' | 'enter-code' | 'verify-pairing' | 'migration' | 'migration-wizard' | 'worker-resources' | 'containers' | 'images' | 'volumes' | 'networks' | 'compose';
 
 function App() {
   const [currentView, setCurrentView] = useState<View>('dashboard');
@@ -58,6 +62,8 @@ function App() {
     totalVolumeSize: 0,
   });
   const [pairingCode, setPairingCode] = useState<PairingCode | null>(null);
+  const [pairedPeer, setPairedPeer] = useState<PairedPeer | null>(null);
+  const [pairAddress, setPairAddress] = useState('');
   const [activeMigration, setActiveMigration] = useState<MigrationState | null>(null);
   const [selectedWorkerForResources, setSelectedWorkerForResources] = useState<Worker | null>(null);
   const [preselectedSourceWorker, setPreselectedSourceWorker] = useState<Worker | null>(null);
@@ -86,6 +92,19 @@ function App() {
       case 'worker_update':
         loadWorkers();
         break;
+      case 'peer_update':
+        loadPeers();
+        // Someone used our code: show the symbols to compare with them before trusting them
+        if (currentView === 'generate-code' && message.data?.paired && message.data?.verification) {
+          setPairedPeer({
+            id: message.data.peer_id,
+            name: message.data.name,
+            verification: message.data.verification,
+          });
+          setPairingCode(null);
+          setCurrentView('verify-pairing');
+        }
+        break;
       case 'migration_progress':
         if (activeMigration) {
           setActiveMigration({
@@ -204,16 +223,12 @@ function App() {
     }
   }
 
-  async function handleConnectWithCode(code: string) {
-    const response = await api.pairing.connect(code);
+  async function handleConnectWithCode(code: string, peerAddress: string) {
+    const response = await api.pairing.connect(code, peerAddress);
     if (response.success && response.data) {
-      addToast({
-        type: 'success',
-        title: 'Connected',
-        message: `Successfully paired with ${response.data.name}`,
-      });
       await loadPeers();
-      setCurrentView('dashboard');
+      setPairedPeer(response.data);
+      setCurrentView('verify-pairing');
     } else {
       addToast({
         type: 'error',
@@ -317,25 +332,60 @@ function App() {
                       }}
                     />
                   ) : (
-                    <PeerList
-                      peers={peers}
-                      onMigrate={handleStartMigration}
-                      onDisconnect={async (peer) => {
-                        await api.peers.disconnect(peer.id);
-                        await loadPeers();
-                        addToast({
-                          type: 'info',
-                          title: 'Disconnected',
-                          message: `Disconnected from ${peer.name}`,
-                        });
-                      }}
-                    />
+                    <div className="space-y-6">
+                      <PendingPeers
+                        onTrusted={async (peer) => {
+                          await loadPeers();
+                          addToast({
+                            type: 'success',
+                            title: 'Peer Trusted',
+                            message: `Trusted ${peer.name || peer.address} on first use`,
+                          });
+                        }}
+                        onError={(message) => addToast({ type: 'error', title: 'Trust on First Use', message })}
+                      />
+                      <NearbyPeers
+                        onPair={(address) => {
+                          setPairAddress(address);
+                          setCurrentView('enter-code');
+                        }}
+                      />
+                      <PeerList
+                        peers={peers}
+                        onMigrate={handleStartMigration}
+                        onDisconnect={async (peer) => {
+                          if (
+                            !confirm(
+                              `Remove ${peer.name} from trusted peers? Any migrations or transfers with this peer will be cancelled, and it must be paired again to reconnect.`
+                            )
+                          ) {
+                            return;
+                          }
+                          const response = await api.peers.remove(peer.id);
+                          await loadPeers();
+                          if (!response.success) {
+                            addToast({
+                              type: 'error',
+                              title: 'Remove Failed',
+                              message: response.error || `Could not remove ${peer.name}`,
+                            });
+                            return;
+                          }
+                          addToast({
+                            type: 'info',
+                            title: 'Peer Removed',
+                            message: `Removed ${peer.name} from trusted peers`,
+                          });
+                        }}
+                      />
+                    </div>
                   )}
                 </div>
                 <div>
                   {isMasterMode ? (
                     <MasterQuickActions
                       enrollmentToken={configInfo?.enrollment_token}
+                      masterFingerprint={configInfo?.master_fingerprint}
                       workerCount={workers.length}
                       onStartMigration={() => {
                         setPreselectedSourceWorker(null);
@@ -346,7 +396,10 @@ function App() {
                     <QuickActions
                       hasPeers={peers.length > 0}
                       onPairDevice={handleGeneratePairingCode}
-                      onScanCode={() => setCurrentView('enter-code')}
+                      onScanCode={() => {
+                        setPairAddress('');
+                        setCurrentView('enter-code');
+                      }}
                       onStartMigration={() =>
                         addToast({ type: 'info', title: 'Select a peer to migrate to' })
                       }
@@ -370,9 +423,50 @@ function App() {
             </div>
           )}
 
+          {currentView === 'verify-pairing' && pairedPeer && (
+            <div className="max-w-2xl mx-auto">
+              <VerifyPairing
+                peer={pairedPeer}
+                onMatch={async () => {
+                  const response = await api.pairing.confirm(pairedPeer.id);
+                  if (!response.success) {
+                    addToast({
+                      type: 'error',
+                      title: 'Pairing not confirmed',
+                      message: response.error || 'The pairing expired, pair again',
+                    });
+                    setPairedPeer(null);
+                    setCurrentView('dashboard');
+                    return;
+                  }
+                  await loadPeers();
+                  addToast({
+                    type: 'success',
+                    title: 'Connected',
+                    message: `Successfully paired with ${pairedPeer.name}`,
+                  });
+                  setPairedPeer(null);
+                  setCurrentView('dashboard');
+                }}
+                onMismatch={async (peer) => {
+                  await api.pairing.reject(peer.id);
+                  await loadPeers();
+                  addToast({
+                    type: 'error',
+                    title: 'Pairing removed',
+                    message: `The symbols did not match, so ${peer.name || 'the peer'} was not trusted`,
+                  });
+                  setPairedPeer(null);
+                  setCurrentView('dashboard');
+                }}
+              />
+            </div>
+          )}
+
           {currentView === 'enter-code' && (
             <div className="max-w-2xl mx-auto">
               <EnterCode
+                initialAddress={pairAddress}
                 onConnect={handleConnectWithCode}
                 onCancel={() => setCurrentView('dashboard')}
               />
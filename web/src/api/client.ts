@@ -16,23 +16,64 @@ import type {
   ConfigInfo,
   WorkerResources,
   StartMigrationRequest,
+  EstimateMigrationRequest,
+  MigrationEstimate,
   MigrationJob,
+  PendingPeer,
+  PendingPeersResponse,
+  NearbyPeersResponse,
+  PairedPeer,
 } from '../types';
 
 const API_BASE = import.meta.env.VITE_API_BASE || '/api';
 
-async function fetchJSON<T>(url: string, options?: RequestInit): Promise<APIResponse<T>> {
+const TOKEN_KEY = 'docker-migrate.apiToken';
+
+// withAccessToken appends the stored API token to a WebSocket URL, since
+// browsers cannot set headers on WebSocket handshakes
+export function withAccessToken(url: string): string {
+  const token = localStorage.getItem(TOKEN_KEY);
+  if (!token) return url;
+  return `${url}${url.includes('?') ? '&' : '?'}access_token=${encodeURIComponent(token)}`;
+}
+
+// Master mode may require an API token, and a TOTP code for destructive
+// operations; prompt for whichever the server asks for and retry once
+async function fetchJSON<T>(url: string, options?: RequestInit, retried = false): Promise<APIResponse<T>> {
   try {
+    const token = localStorage.getItem(TOKEN_KEY);
     const response = await fetch(`${API_BASE}${url}`, {
       ...options,
       headers: {
         'Content-Type': 'application/json',
+        ...(token ? { Authorization: `Bearer ${token}` } : {}),
         ...options?.headers,
       },
     });
 
     const data = await response.json();
 
+    // With OIDC configured the server sends browsers to its sign-in page
+    if (response.status === 401 && data.login_url) {
+      window.location.href = data.login_url;
+      return { success: false, error: data.error };
+    }
+
+    if (!retried && response.status === 401) {
+      const entered = window.prompt('API token required');
+      if (entered) {
+        localStorage.setItem(TOKEN_KEY, entered.trim());
+        return fetchJSON<T>(url, options, true);
+      }
+    }
+
+    if (!retried && response.status === 403 && data.totp_required) {
+      const code = window.prompt('Enter your TOTP code to confirm');
+      if (code) {
+        return fetchJSON<T>(url, { ...options, headers: { ...options?.headers, 'X-TOTP-Code': code.trim() } }, true);
+      }
+    }
+
     if (!response.ok) {
       return {
         success: false,
@@ -122,6 +163,19 @@ export const api = {
     list: () => fetchJSON<Peer[]>('/peers'),
     get: (id: string) => fetchJSON<Peer>(`/peers/${id}`),
     disconnect: (id: string) => fetchJSON<void>(`/peers/${id}/disconnect`, { method: 'POST' }),
+    remove: (id: string) =>
+      fetchJSON<{ status: string; cancelled_migrations: number }>(`/peers/${id}`, { method: 'DELETE' }),
+    pending: () => fetchJSON<PendingPeersResponse>('/peers/pending'),
+    nearby: () => fetchJSON<NearbyPeersResponse>('/peers/nearby'),
+    probe: (address: string) =>
+      fetchJSON<PendingPeer>('/peers/probe', {
+        method: 'POST',
+        body: JSON.stringify({ address }),
+      }),
+    confirmPending: (fingerprint: string) =>
+      fetchJSON<Peer>(`/peers/pending/${fingerprint}/confirm`, { method: 'POST' }),
+    rejectPending: (fingerprint: string) =>
+      fetchJSON<void>(`/peers/pending/${fingerprint}`, { method: 'DELETE' }),
   },
 
   // Workers (master-worker mode)
@@ -134,7 +188,8 @@ export const api = {
       return { success: false, error: response.error };
     },
     get: (id: string) => fetchJSON<Worker>(`/workers/${id}`),
-    resources: (id: string) => fetchJSON<WorkerResources>(`/workers/${id}/resources`),
+    resources: (id: string) =>
+      fetchJSON<WorkerResources>(`/workers/${id}/resources?group_by=stack`),
     remove: (id: string) => fetchJSON<void>(`/workers/${id}`, { method: 'DELETE' }),
   },
 
@@ -145,6 +200,11 @@ export const api = {
         method: 'POST',
         body: JSON.stringify(request),
       }),
+    estimate: (request: EstimateMigrationRequest) =>
+      fetchJSON<MigrationEstimate>('/migrations/estimate', {
+        method: 'POST',
+        body: JSON.stringify(request),
+      }),
     list: async (): Promise<APIResponse<MigrationJob[]>> => {
       const response = await fetchJSON<{ migrations: MigrationJob[] }>('/migrations');
       if (response.success && response.data) {
@@ -154,6 +214,12 @@ export const api = {
     },
     get: (id: string) => fetchJSON<MigrationJob>(`/migrations/${id}`),
     cancel: (id: string) => fetchJSON<void>(`/migrations/${id}/cancel`, { method: 'POST' }),
+    approve: (id: string) => fetchJSON<MigrationJob>(`/migrations/${id}/approve`, { method: 'POST' }),
+    reject: (id: string, reason?: string) =>
+      fetchJSON<void>(`/migrations/${id}/reject`, {
+        method: 'POST',
+        body: JSON.stringify({ reason }),
+      }),
   },
 
   // Config
@@ -165,16 +231,20 @@ export const api = {
   // Pairing
   pairing: {
     generate: () => fetchJSON<PairingCode>('/pair/generate', { method: 'POST' }),
-    connect: (code: string) =>
-      fetchJSON<Peer>('/pair/connect', {
+    connect: (code: string, peerAddress: string) =>
+      fetchJSON<PairedPeer>('/pair/connect', {
         method: 'POST',
-        body: JSON.stringify({ code }),
+        body: JSON.stringify({ code, peer_address: peerAddress }),
       }),
     cancel: (code: string) =>
       fetchJSON<void>('/pair/cancel', {
         method: 'POST',
         body: JSON.stringify({ code }),
       }),
+    confirm: (peerId: string) =>
+      fetchJSON<void>(`/pair/${peerId}/confirm`, { method: 'POST' }),
+    reject: (peerId: string) =>
+      fetchJSON<void>(`/pair/${peerId}`, { method: 'DELETE' }),
   },
 
   // Migration
@@ -206,6 +276,12 @@ export const api = {
       fetchJSON<void>(`/migrate/${id}/retry`, { method: 'POST' }),
 
     list: () => fetchJSON<MigrationState[]>('/migrate'),
+
+    logsStream: (id: string) => {
+      const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+      const host = import.meta.env.VITE_WS_HOST || window.location.host;
+      return withAccessToken(`${protocol}//${host}/ws/migrate/${id}/logs`);
+    },
   },
 };
 
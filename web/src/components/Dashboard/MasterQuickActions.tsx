@@ -5,15 +5,16 @@ import { Button } from '../ui/Button';
 
 interface MasterQuickActionsProps {
   enrollmentToken?: string;
+  masterFingerprint?: string;
   workerCount: number;
   onStartMigration?: () => void;
 }
 
-export function MasterQuickActions({ enrollmentToken, workerCount, onStartMigration }: MasterQuickActionsProps) {
+export function MasterQuickActions({ enrollmentToken, masterFingerprint, workerCount, onStartMigration }: MasterQuickActionsProps) {
   const [copied, setCopied] = useState(false);
 
-  const installCommand = enrollmentToken
-    ? `curl -sSL https://raw.githubusercontent.com/Altacee/dockation/main/scripts/install-worker.sh | sudo bash -s -- --master-url <MASTER_IP>:9090 --token ${enrollmentToken}`
+  const installCommand = enrollmentToken && masterFingerprint
+    ? `curl -sSL https://raw.githubusercontent.com/Altacee/dockation/main/scripts/install-worker.sh | sudo bash -s -- --master-url <MASTER_IP>:9090 --token ${enrollmentToken} --master-fingerprint ${masterFingerprint}`
     : '';
 
   const copyToClipboard = async () => {
@@ -47,7 +48,7 @@ export function MasterQuickActions({ enrollmentToken, workerCount, onStartMigrat
               variant="ghost"
               className="absolute top-2 right-2 text-gray-400 hover:text-white"
               onClick={copyToClipboard}
-              disabled={!enrollmentToken}
+              disabled={!installCommand}
             >
               {copied ? <Check className="h-4 w-4" /> : <Copy className="h-4 w-4" />}
             </Button>
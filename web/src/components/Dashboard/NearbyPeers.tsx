@@ -0,0 +1,82 @@
+import { useEffect, useState } from 'react';
+import { Radar, Link } from 'lucide-react';
+import type { NearbyPeer } from '../../types';
+import { Card, CardContent, CardHeader, CardTitle } from '../ui/Card';
+import { Button } from '../ui/Button';
+import { formatRelativeTime } from '../../lib/utils';
+import api from '../../api/client';
+
+interface NearbyPeersProps {
+  onPair?: (address: string) => void;
+  className?: string;
+}
+
+// NearbyPeers lists unpaired hosts advertising themselves over mDNS. It
+// renders nothing unless the daemon runs with "mdns" enabled.
+export function NearbyPeers({ onPair, className }: NearbyPeersProps) {
+  const [enabled, setEnabled] = useState(false);
+  const [peers, setPeers] = useState<NearbyPeer[]>([]);
+
+  async function load() {
+    const response = await api.peers.nearby();
+    if (response.success && response.data) {
+      setEnabled(response.data.mdns);
+      setPeers((response.data.peers || []).filter((peer) => !peer.paired));
+    }
+  }
+
+  useEffect(() => {
+    load();
+    const interval = setInterval(load, 10000);
+    return () => clearInterval(interval);
+  }, []);
+
+  if (!enabled) {
+    return null;
+  }
+
+  return (
+    <Card className={className}>
+      <CardHeader>
+        <CardTitle className="text-lg flex items-center gap-2">
+          <Radar className="h-5 w-5 text-blue-600" aria-hidden="true" />
+          Nearby Hosts
+        </CardTitle>
+      </CardHeader>
+      <CardContent>
+        {peers.length === 0 ? (
+          <p className="text-sm text-gray-500">No unpaired hosts found on the local network</p>
+        ) : (
+          <div className="space-y-3" role="list" aria-label="Hosts on the local network">
+            {peers.map((peer) => (
+              <div
+                key={peer.fingerprint}
+                className="flex items-center gap-4 p-3 rounded-lg border bg-white"
+                role="listitem"
+              >
+                <div className="flex-1 min-w-0">
+                  <h4 className="text-sm font-semibold text-gray-900 truncate">
+                    {peer.name}
+                    {peer.addresses.length > 0 && (
+                      <span className="ml-2 font-normal text-gray-500">{peer.addresses[0]}</span>
+                    )}
+                  </h4>
+                  <p className="text-xs font-mono text-gray-600 break-all">{peer.fingerprint}</p>
+                  <p className="text-xs text-gray-500">Seen {formatRelativeTime(peer.last_seen)}</p>
+                </div>
+                <Button
+                  size="sm"
+                  onClick={() => onPair?.(peer.addresses[0] || '')}
+                  aria-label={`Pair with ${peer.name}`}
+                >
+                  <Link className="h-4 w-4 mr-1" aria-hidden="true" />
+                  Pair
+                </Button>
+              </div>
+            ))}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
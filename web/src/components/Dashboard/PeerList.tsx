@@ -1,4 +1,4 @@
-import { Server, Wifi, WifiOff, MoreVertical, ArrowRight } from 'lucide-react';
+import { Server, Wifi, WifiOff, Trash2, ArrowRight } from 'lucide-react';
 import type { Peer } from '../../types';
 import { Card, CardContent, CardHeader, CardTitle } from '../ui/Card';
 import { Badge } from '../ui/Badge';
@@ -104,31 +104,29 @@ function PeerItem({ peer, onMigrate, onDisconnect }: PeerItemProps) {
       </div>
 
       {/* Actions */}
-      {isOnline && (
-        <div className="flex items-center gap-2">
-          {onMigrate && (
-            <Button
-              size="sm"
-              onClick={() => onMigrate(peer)}
-              className="bg-blue-600 hover:bg-blue-700"
-              aria-label={`Migrate to ${peer.name}`}
-            >
-              <ArrowRight className="h-4 w-4 mr-1" aria-hidden="true" />
-              Migrate
-            </Button>
-          )}
-          {onDisconnect && (
-            <Button
-              size="sm"
-              variant="ghost"
-              onClick={() => onDisconnect(peer)}
-              aria-label={`Disconnect from ${peer.name}`}
-            >
-              <MoreVertical className="h-4 w-4" />
-            </Button>
-          )}
-        </div>
-      )}
+      <div className="flex items-center gap-2">
+        {isOnline && onMigrate && (
+          <Button
+            size="sm"
+            onClick={() => onMigrate(peer)}
+            className="bg-blue-600 hover:bg-blue-700"
+            aria-label={`Migrate to ${peer.name}`}
+          >
+            <ArrowRight className="h-4 w-4 mr-1" aria-hidden="true" />
+            Migrate
+          </Button>
+        )}
+        {onDisconnect && (
+          <Button
+            size="sm"
+            variant="ghost"
+            onClick={() => onDisconnect(peer)}
+            aria-label={`Remove ${peer.name} from trusted peers`}
+          >
+            <Trash2 className="h-4 w-4" aria-hidden="true" />
+          </Button>
+        )}
+      </div>
     </div>
   );
 }
@@ -0,0 +1,146 @@
+import { useEffect, useState } from 'react';
+import { ShieldAlert, Check, X } from 'lucide-react';
+import type { PendingPeer } from '../../types';
+import { Card, CardContent, CardHeader, CardTitle } from '../ui/Card';
+import { Button } from '../ui/Button';
+import { Input } from '../ui/Input';
+import { formatRelativeTime } from '../../lib/utils';
+import api from '../../api/client';
+
+interface PendingPeersProps {
+  onTrusted?: (peer: PendingPeer) => void;
+  onError?: (message: string) => void;
+  className?: string;
+}
+
+// PendingPeers lists hosts recorded by trust-on-first-use. It renders nothing
+// unless the daemon runs with "tofu" enabled.
+export function PendingPeers({ onTrusted, onError, className }: PendingPeersProps) {
+  const [enabled, setEnabled] = useState(false);
+  const [pending, setPending] = useState<PendingPeer[]>([]);
+  const [address, setAddress] = useState('');
+  const [probing, setProbing] = useState(false);
+
+  async function load() {
+    const response = await api.peers.pending();
+    if (response.success && response.data) {
+      setEnabled(response.data.tofu);
+      setPending(response.data.pending || []);
+    }
+  }
+
+  useEffect(() => {
+    load();
+    const interval = setInterval(load, 10000);
+    return () => clearInterval(interval);
+  }, []);
+
+  if (!enabled) {
+    return null;
+  }
+
+  async function probe() {
+    if (!address) return;
+    setProbing(true);
+    const response = await api.peers.probe(address);
+    setProbing(false);
+    if (!response.success) {
+      onError?.(response.error || `Could not reach ${address}`);
+      return;
+    }
+    setAddress('');
+    await load();
+  }
+
+  async function confirmPeer(peer: PendingPeer) {
+    if (
+      !confirm(
+        `Trust ${peer.name || peer.address || 'this host'} without pairing?\n\nOnly continue if this fingerprint matches the one shown on the other host:\n\n${peer.fingerprint}`
+      )
+    ) {
+      return;
+    }
+    const response = await api.peers.confirmPending(peer.fingerprint);
+    if (!response.success) {
+      onError?.(response.error || 'Could not trust peer');
+    } else {
+      onTrusted?.(peer);
+    }
+    await load();
+  }
+
+  async function rejectPeer(peer: PendingPeer) {
+    await api.peers.rejectPending(peer.fingerprint);
+    await load();
+  }
+
+  return (
+    <Card className={className}>
+      <CardHeader>
+        <CardTitle className="text-lg flex items-center gap-2">
+          <ShieldAlert className="h-5 w-5 text-amber-600" aria-hidden="true" />
+          Trust on First Use
+        </CardTitle>
+      </CardHeader>
+      <CardContent>
+        <p className="text-xs text-amber-700 bg-amber-50 border border-amber-200 rounded-md p-3 mb-4">
+          Trust on first use is enabled. Any host that connects is listed here; trusting one
+          skips pairing entirely. Compare fingerprints with the other host before confirming and
+          only use this on networks you control.
+        </p>
+
+        <div className="flex gap-2 mb-4">
+          <Input
+            value={address}
+            onChange={(e) => setAddress(e.target.value)}
+            placeholder="host:9090"
+            aria-label="Peer address to contact"
+          />
+          <Button size="sm" onClick={probe} disabled={probing || !address}>
+            {probing ? 'Contacting...' : 'Contact'}
+          </Button>
+        </div>
+
+        {pending.length === 0 ? (
+          <p className="text-sm text-gray-500">No hosts waiting for confirmation</p>
+        ) : (
+          <div className="space-y-3" role="list" aria-label="Hosts awaiting trust">
+            {pending.map((peer) => (
+              <div
+                key={peer.fingerprint}
+                className="flex items-center gap-4 p-3 rounded-lg border bg-white"
+                role="listitem"
+              >
+                <div className="flex-1 min-w-0">
+                  <h4 className="text-sm font-semibold text-gray-900 truncate">
+                    {peer.name || 'unknown host'}
+                    {peer.address && <span className="ml-2 font-normal text-gray-500">{peer.address}</span>}
+                  </h4>
+                  <p className="text-xs font-mono text-gray-600 break-all">{peer.fingerprint}</p>
+                  <p className="text-xs text-gray-500">
+                    First seen {formatRelativeTime(peer.first_seen)} • {peer.attempts} connection
+                    {peer.attempts === 1 ? '' : 's'}
+                  </p>
+                </div>
+                <div className="flex items-center gap-2">
+                  <Button size="sm" onClick={() => confirmPeer(peer)} aria-label={`Trust ${peer.name}`}>
+                    <Check className="h-4 w-4 mr-1" aria-hidden="true" />
+                    Trust
+                  </Button>
+                  <Button
+                    size="sm"
+                    variant="ghost"
+                    onClick={() => rejectPeer(peer)}
+                    aria-label={`Reject ${peer.name}`}
+                  >
+                    <X className="h-4 w-4" aria-hidden="true" />
+                  </Button>
+                </div>
+              </div>
+            ))}
+          </div>
+        )}
+      </CardContent>
+    </Card>
+  );
+}
@@ -6,6 +6,7 @@ import type {
   WorkerImage,
   WorkerVolume,
   WorkerNetwork,
+  StackResources,
 } from '../../types';
 
 export type MigrationMode = 'cold' | 'warm' | 'live';
@@ -43,6 +44,7 @@ type MigrationAction =
   | { type: 'TOGGLE_IMAGE'; image: WorkerImage }
   | { type: 'TOGGLE_VOLUME'; volume: WorkerVolume }
   | { type: 'TOGGLE_NETWORK'; network: WorkerNetwork }
+  | { type: 'SET_STACK_SELECTED'; stack: StackResources; selected: boolean }
   | { type: 'SELECT_ALL_CONTAINERS' }
   | { type: 'DESELECT_ALL_CONTAINERS' }
   | { type: 'SELECT_ALL_IMAGES' }
@@ -154,6 +156,35 @@ function migrationReducer(
       };
     }
 
+    case 'SET_STACK_SELECTED': {
+      // Select or clear every container, volume and network of the stack
+      const { stack, selected } = action;
+      const containerIds = new Set(stack.containers || []);
+      const volumeNames = new Set(stack.volumes || []);
+      const networkIds = new Set(stack.networks || []);
+      const current = state.selectedResources;
+      const withStack = <T,>(chosen: T[], all: T[] | null | undefined, inStack: (r: T) => boolean) =>
+        selected
+          ? [...chosen.filter((r) => !inStack(r)), ...(all || []).filter(inStack)]
+          : chosen.filter((r) => !inStack(r));
+
+      return {
+        ...state,
+        selectedResources: {
+          ...current,
+          containers: withStack(current.containers, state.sourceResources?.containers, (c) =>
+            containerIds.has(c.id)
+          ),
+          volumes: withStack(current.volumes, state.sourceResources?.volumes, (v) =>
+            volumeNames.has(v.name)
+          ),
+          networks: withStack(current.networks, state.sourceResources?.networks, (n) =>
+            networkIds.has(n.id)
+          ),
+        },
+      };
+    }
+
     case 'SELECT_ALL_CONTAINERS':
       return {
         ...state,
@@ -260,6 +291,7 @@ interface MigrationContextValue {
   toggleImage: (image: WorkerImage) => void;
   toggleVolume: (volume: WorkerVolume) => void;
   toggleNetwork: (network: WorkerNetwork) => void;
+  setStackSelected: (stack: StackResources, selected: boolean) => void;
   selectAllContainers: () => void;
   deselectAllContainers: () => void;
   selectAllImages: () => void;
@@ -292,6 +324,7 @@ export function MigrationProvider({ children }: { children: ReactNode }) {
     toggleImage: (image) => dispatch({ type: 'TOGGLE_IMAGE', image }),
     toggleVolume: (volume) => dispatch({ type: 'TOGGLE_VOLUME', volume }),
     toggleNetwork: (network) => dispatch({ type: 'TOGGLE_NETWORK', network }),
+    setStackSelected: (stack, selected) => dispatch({ type: 'SET_STACK_SELECTED', stack, selected }),
     selectAllContainers: () => dispatch({ type: 'SELECT_ALL_CONTAINERS' }),
     deselectAllContainers: () => dispatch({ type: 'DESELECT_ALL_CONTAINERS' }),
     selectAllImages: () => dispatch({ type: 'SELECT_ALL_IMAGES' }),
@@ -4,6 +4,7 @@ import {
   Database,
   HardDrive,
   Network,
+  Layers,
   Loader2,
   AlertCircle,
   CheckSquare,
@@ -14,6 +15,7 @@ import { Badge } from '../ui/Badge';
 import { cn, formatBytes } from '../../lib/utils';
 import { useMigrationContext } from './MigrationContext';
 import api from '../../api/client';
+import type { StackResources } from '../../types';
 
 type TabType = 'containers' | 'images' | 'volumes' | 'networks';
 
@@ -32,6 +34,7 @@ export function StepResourceSelect() {
     toggleImage,
     toggleVolume,
     toggleNetwork,
+    setStackSelected,
     selectAllContainers,
     deselectAllContainers,
     selectAllImages,
@@ -75,6 +78,18 @@ export function StepResourceSelect() {
   const resources = state.sourceResources;
   const selected = state.selectedResources;
 
+  // A stack counts as selected once all of its resources are
+  const isStackSelected = (stack: StackResources) => {
+    const containers = stack.containers || [];
+    const volumes = stack.volumes || [];
+    const networks = stack.networks || [];
+    return (
+      containers.every((id) => selected.containers.some((c) => c.id === id)) &&
+      volumes.every((name) => selected.volumes.some((v) => v.name === name)) &&
+      networks.every((id) => selected.networks.some((n) => n.id === id))
+    );
+  };
+
   const getTabCount = (tab: TabType) => {
     switch (tab) {
       case 'containers':
@@ -207,6 +222,50 @@ export function StepResourceSelect() {
         </div>
       </div>
 
+      {/* Compose stacks */}
+      {(resources.stacks?.length || 0) > 0 && (
+        <div className="space-y-2">
+          <span className="text-sm font-medium text-gray-700">Stacks</span>
+          <div className="flex flex-wrap gap-2" role="list" aria-label="Compose stacks">
+            {resources.stacks!.map((stack) => {
+              const isSelected = isStackSelected(stack);
+              const count =
+                (stack.containers?.length || 0) +
+                (stack.volumes?.length || 0) +
+                (stack.networks?.length || 0);
+
+              return (
+                <button
+                  key={stack.name}
+                  role="listitem"
+                  onClick={() => setStackSelected(stack, !isSelected)}
+                  aria-pressed={isSelected}
+                  className={cn(
+                    'flex items-center gap-2 px-3 py-2 text-sm border rounded-lg transition-colors',
+                    isSelected
+                      ? 'border-blue-500 bg-blue-50 text-blue-700'
+                      : 'border-gray-200 text-gray-700 hover:bg-gray-50'
+                  )}
+                >
+                  {isSelected ? (
+                    <CheckSquare className="h-4 w-4 text-blue-600" />
+                  ) : (
+                    <Layers className="h-4 w-4 text-gray-400" />
+                  )}
+                  <span className="font-medium">{stack.name}</span>
+                  {stack.managed_by !== 'compose' && (
+                    <Badge variant="outline" className="bg-gray-100 text-gray-600">
+                      {stack.managed_by}
+                    </Badge>
+                  )}
+                  <span className="text-xs text-gray-500">{count} resources</span>
+                </button>
+              );
+            })}
+          </div>
+        </div>
+      )}
+
       {/* Tabs */}
       <div className="border-b border-gray-200">
         <nav className="flex -mb-px" aria-label="Resource types">
@@ -1,4 +1,4 @@
-import { useState } from 'react';
+import { useEffect, useState } from 'react';
 import {
   ArrowRight,
   Box,
@@ -14,6 +14,7 @@ import { Badge } from '../ui/Badge';
 import { formatBytes } from '../../lib/utils';
 import { useMigrationContext } from './MigrationContext';
 import api from '../../api/client';
+import type { MigrationEstimate } from '../../types';
 
 interface StepReviewProps {
   onComplete: (migrationId: string) => void;
@@ -27,9 +28,36 @@ export function StepReview({ onComplete, onCancel }: StepReviewProps) {
 
   const { sourceWorker, targetWorker, selectedResources, options } = state;
 
+  const [estimate, setEstimate] = useState<MigrationEstimate | null>(null);
+
   // Calculate total size of selected images
   const totalImageSize = selectedResources.images.reduce((acc, img) => acc + img.size, 0);
 
+  // Size everything selected from the source worker's inventory
+  useEffect(() => {
+    if (!sourceWorker) return;
+    let cancelled = false;
+    api.migrations
+      .estimate({
+        source_worker_id: sourceWorker.id,
+        container_ids: selectedResources.containers.map((c) => c.id),
+        image_ids: selectedResources.images.map((i) => i.id),
+        volume_names: selectedResources.volumes.map((v) => v.name),
+        network_ids: selectedResources.networks.map((n) => n.id),
+      })
+      .then((response) => {
+        if (!cancelled && response.success && response.data) {
+          setEstimate(response.data);
+        }
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, [sourceWorker, selectedResources]);
+
+  const estimatedSize = (type: string, id: string) =>
+    estimate?.resources.find((r) => r.type === type && r.id === id)?.size_bytes;
+
   const handleStartMigration = async () => {
     if (!sourceWorker || !targetWorker) return;
 
@@ -144,10 +172,18 @@ export function StepReview({ onComplete, onCancel }: StepReviewProps) {
               </span>
             </div>
           </div>
-          {totalImageSize > 0 && (
+          {estimate ? (
             <p className="text-xs text-gray-500 mt-3">
-              Total image size: {formatBytes(totalImageSize)}
+              Estimated transfer: {formatBytes(estimate.total_bytes)}
+              {estimate.estimated_duration > 0 &&
+                ` (about ${Math.max(1, Math.round(estimate.estimated_duration / 60e9))} min at 100 Mbps)`}
             </p>
+          ) : (
+            totalImageSize > 0 && (
+              <p className="text-xs text-gray-500 mt-3">
+                Total image size: {formatBytes(totalImageSize)}
+              </p>
+            )
           )}
         </div>
 
@@ -228,9 +264,14 @@ export function StepReview({ onComplete, onCancel }: StepReviewProps) {
             </div>
             <div className="divide-y max-h-32 overflow-y-auto">
               {selectedResources.volumes.map((volume) => (
-                <div key={volume.name} className="px-4 py-2 text-sm">
-                  <span className="font-medium">{volume.name}</span>
-                  <span className="text-gray-500 ml-2">({volume.driver})</span>
+                <div key={volume.name} className="px-4 py-2 text-sm flex justify-between">
+                  <span>
+                    <span className="font-medium">{volume.name}</span>
+                    <span className="text-gray-500 ml-2">({volume.driver})</span>
+                  </span>
+                  {estimatedSize('volume', volume.name) !== undefined && (
+                    <span className="text-gray-500">{formatBytes(estimatedSize('volume', volume.name)!)}</span>
+                  )}
                 </div>
               ))}
             </div>
@@ -6,7 +6,8 @@ import { Loader2 } from 'lucide-react';
 import { validatePairingCode } from '../../lib/utils';
 
 interface EnterCodeProps {
-  onConnect?: (code: string) => void;
+  initialAddress?: string;
+  onConnect?: (code: string, peerAddress: string) => void;
   onCancel?: () => void;
   isConnecting?: boolean;
   error?: string;
@@ -14,6 +15,7 @@ interface EnterCodeProps {
 }
 
 export function EnterCode({
+  initialAddress = '',
   onConnect,
   onCancel,
   isConnecting = false,
@@ -21,6 +23,7 @@ export function EnterCode({
   className,
 }: EnterCodeProps) {
   const [code, setCode] = useState('');
+  const [peerAddress, setPeerAddress] = useState(initialAddress);
   const [validationError, setValidationError] = useState('');
 
   const handleCodeChange = (value: string) => {
@@ -38,10 +41,15 @@ export function EnterCode({
       return;
     }
 
-    onConnect?.(code);
+    if (!peerAddress.trim()) {
+      setValidationError("Please enter the other device's address");
+      return;
+    }
+
+    onConnect?.(code, peerAddress.trim());
   };
 
-  const isValid = validatePairingCode(code);
+  const isValid = validatePairingCode(code) && peerAddress.trim() !== '';
 
   return (
     <Card className={className}>
@@ -54,6 +62,27 @@ export function EnterCode({
 
       <CardContent>
         <form onSubmit={handleSubmit} className="space-y-6">
+          {/* Peer address */}
+          <div className="space-y-2">
+            <label htmlFor="peer-address" className="text-sm font-medium text-gray-700">
+              Device Address
+            </label>
+            <Input
+              id="peer-address"
+              type="text"
+              placeholder="192.168.1.20:9090"
+              value={peerAddress}
+              onChange={(e) => {
+                setPeerAddress(e.target.value);
+                setValidationError('');
+              }}
+              disabled={isConnecting}
+            />
+            <p className="text-xs text-gray-500">
+              The other device's gRPC address. Pairing only needs this port, not its web UI.
+            </p>
+          </div>
+
           {/* Code input */}
           <div className="space-y-2">
             <label htmlFor="pairing-code" className="text-sm font-medium text-gray-700">
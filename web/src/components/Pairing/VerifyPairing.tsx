@@ -0,0 +1,73 @@
+import { ShieldCheck } from 'lucide-react';
+import type { PairedPeer } from '../../types';
+import { Card, CardContent, CardHeader, CardTitle } from '../ui/Card';
+import { Button } from '../ui/Button';
+
+interface VerifyPairingProps {
+  peer: PairedPeer;
+  onMatch?: () => void;
+  onMismatch?: (peer: PairedPeer) => void;
+  className?: string;
+}
+
+// VerifyPairing shows the symbols derived from both hosts' certificates.
+// The other host shows the same sequence unless something intercepted the
+// pairing, so the user compares them before the peer is trusted.
+export function VerifyPairing({ peer, onMatch, onMismatch, className }: VerifyPairingProps) {
+  return (
+    <Card className={className}>
+      <CardHeader>
+        <CardTitle className="text-xl flex items-center gap-2">
+          <ShieldCheck className="h-5 w-5 text-blue-600" aria-hidden="true" />
+          Verify {peer.name || 'peer'}
+        </CardTitle>
+        <p className="text-sm text-gray-600 mt-1">
+          Check that the other device shows the same symbols, in the same order
+        </p>
+      </CardHeader>
+
+      <CardContent>
+        <div className="space-y-6">
+          <ol className="grid grid-cols-4 sm:grid-cols-7 gap-3" aria-label="Verification symbols">
+            {peer.verification.map((symbol, i) => (
+              <li
+                key={i}
+                className="flex flex-col items-center gap-1 p-2 rounded-lg border bg-gray-50"
+              >
+                <span className="text-3xl" aria-hidden="true">
+                  {symbol.emoji}
+                </span>
+                <span className="text-xs font-medium text-gray-700 text-center">{symbol.word}</span>
+              </li>
+            ))}
+          </ol>
+
+          <div className="p-4 bg-gray-50 border border-gray-200 rounded-lg">
+            <p className="text-sm text-gray-700">
+              Compare them in person or over a call. The peer is only trusted once you confirm
+              they match. If they differ, another machine may be sitting between the two devices.
+            </p>
+          </div>
+
+          <div className="flex items-center gap-3 pt-4 border-t">
+            <Button
+              type="button"
+              variant="outline"
+              onClick={() => onMismatch?.(peer)}
+              className="flex-1"
+            >
+              They don't match
+            </Button>
+            <Button
+              type="button"
+              onClick={onMatch}
+              className="flex-1 bg-blue-600 hover:bg-blue-700"
+            >
+              They match
+            </Button>
+          </div>
+        </div>
+      </CardContent>
+    </Card>
+  );
+}
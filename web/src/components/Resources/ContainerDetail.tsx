@@ -13,7 +13,7 @@ import {
 } from 'lucide-react';
 import { Card, CardContent, CardHeader, CardTitle } from '../ui/Card';
 import { Button } from '../ui/Button';
-import api from '../../api/client';
+import api, { withAccessToken } from '../../api/client';
 import { cn } from '../../lib/utils';
 
 interface ContainerDetailProps {
@@ -49,7 +49,7 @@ export function ContainerDetail({ containerId, onBack, onRefresh }: ContainerDet
     const wsHost = import.meta.env.VITE_WS_HOST || window.location.host;
     const wsUrl = `${wsProtocol}//${wsHost}/ws/containers/${containerId}/logs`;
 
-    const ws = new WebSocket(wsUrl);
+    const ws = new WebSocket(withAccessToken(wsUrl));
     wsRef.current = ws;
 
     ws.onopen = () => {
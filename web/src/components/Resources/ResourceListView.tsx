@@ -531,10 +531,19 @@ export function ResourceListView({ type, onBack }: ResourceListViewProps) {
                 <tbody className="divide-y divide-gray-200">
                   {containers.map((container: any) => {
                     const isRunning = container.State?.toLowerCase() === 'running';
+                    const migratedFrom = container.Labels?.['docker-migrate.source'];
                     return (
                       <tr key={container.Id} className="text-sm">
                         <td className="py-3 pr-4 font-medium text-gray-900">
                           {container.Names?.[0]?.replace(/^\//, '') || container.Id?.substring(0, 12)}
+                          {migratedFrom && (
+                            <span
+                              className="ml-2 inline-flex items-center px-2 py-0.5 rounded-full text-xs font-medium bg-purple-100 text-purple-800"
+                              title={`Migrated from ${migratedFrom} by job ${container.Labels?.['docker-migrate.job'] || 'unknown'}`}
+                            >
+                              migrated
+                            </span>
+                          )}
                         </td>
                         <td className="py-3 pr-4 text-gray-600 font-mono text-xs">
                           {container.Image?.substring(0, 40)}
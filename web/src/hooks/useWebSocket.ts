@@ -1,5 +1,6 @@
 import { useEffect, useRef, useState, useCallback } from 'react';
 import type { WSMessage, ConnectionStatus } from '../types';
+import { withAccessToken } from '../api/client';
 
 interface UseWebSocketOptions {
   url: string;
@@ -44,7 +45,7 @@ export function useWebSocket({
     setStatus('connecting');
 
     try {
-      const ws = new WebSocket(url);
+      const ws = new WebSocket(withAccessToken(url));
       wsRef.current = ws;
 
       ws.onopen = () => {
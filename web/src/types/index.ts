@@ -6,6 +6,16 @@ export interface WorkerResources {
   volumes: WorkerVolume[] | null;
   networks: WorkerNetwork[] | null;
   updated_at: string;
+  stacks?: StackResources[]; // with group_by=stack
+}
+
+// The resources of one compose project or Swarm stack
+export interface StackResources {
+  name: string;
+  managed_by: 'compose' | 'portainer' | 'komodo' | 'swarm';
+  containers?: string[]; // IDs
+  volumes?: string[]; // names
+  networks?: string[]; // IDs
 }
 
 export interface WorkerContainer {
@@ -48,6 +58,28 @@ export interface StartMigrationRequest {
   transfer_mode?: TransferMode;
 }
 
+export interface EstimateMigrationRequest {
+  source_worker_id: string;
+  container_ids: string[];
+  image_ids: string[];
+  volume_names: string[];
+  network_ids: string[];
+}
+
+export interface ResourceSize {
+  type: 'container' | 'image' | 'volume' | 'network';
+  id: string;
+  name: string;
+  size_bytes: number;
+  error?: string;
+}
+
+export interface MigrationEstimate {
+  resources: ResourceSize[];
+  total_bytes: number;
+  estimated_duration: number; // nanoseconds
+}
+
 export interface MigrationJob {
   id: string;
   source_worker_id: string;
@@ -60,6 +92,21 @@ export interface MigrationJob {
   started_at: string;
   error?: string;
   transfer_mode?: TransferMode;
+  transfer_fallback?: string; // why an auto-mode job moved to the proxy
+  image_mode?: 'stream' | 'registry';
+  requested_by?: string;
+  reviewed_by?: string; // admin who approved or rejected a requested migration
+  reviewed_at?: string;
+  issued_by?: string;
+  namespace?: string;
+  note?: string;
+  attempt?: number;
+  max_attempts?: number;
+  next_retry_at?: string;
+  completed_resources?: string[];
+  completed_at?: string;
+  last_progress_at?: string; // worker report time, on the master's clock
+  clock_skew_ms?: number; // source clock minus target clock
 }
 
 // Core Docker resource types
@@ -154,6 +201,36 @@ export interface Peer {
   availableSpace: number;
 }
 
+// An unknown peer recorded by trust-on-first-use, awaiting confirmation
+export interface PendingPeer {
+  fingerprint: string;
+  name: string;
+  address?: string;
+  first_seen: string;
+  last_seen: string;
+  attempts: number;
+}
+
+export interface PendingPeersResponse {
+  tofu: boolean;
+  pending: PendingPeer[];
+}
+
+// A host advertising itself over mDNS on the local network
+export interface NearbyPeer {
+  instance: string;
+  name: string;
+  fingerprint: string;
+  addresses: string[];
+  paired: boolean;
+  last_seen: string;
+}
+
+export interface NearbyPeersResponse {
+  mdns: boolean;
+  peers: NearbyPeer[];
+}
+
 // Worker types (master-worker mode)
 export interface Worker {
   id: string;
@@ -162,10 +239,13 @@ export interface Worker {
   grpc_address: string;
   labels: Record<string, string>;
   version: string;
+  namespace?: string;
   status: string;
   online: boolean;
   registered_at: string;
   last_heartbeat: string;
+  clock_skew_ms?: number; // worker clock minus master clock
+  clock_skew_warning?: boolean;
   container_count: number;
   image_count: number;
   volume_count: number;
@@ -175,7 +255,10 @@ export interface Worker {
 // Config info
 export interface ConfigInfo {
   role: 'master' | 'worker' | 'p2p' | '';
-  enrollment_token?: string;
+  enrollment_token?: string; // only for unscoped admin callers
+  master_fingerprint?: string; // workers pin this with --master-fingerprint
+  api_role?: 'viewer' | 'operator' | 'admin';
+  namespace?: string; // set when the caller is confined to a namespace
 }
 
 export interface PairingCode {
@@ -184,6 +267,21 @@ export interface PairingCode {
   peerId?: string;
 }
 
+// One symbol of the sequence both hosts show after pairing
+export interface VerificationSymbol {
+  emoji: string;
+  word: string;
+}
+
+// A peer that just paired, with the symbols to compare on both hosts
+export interface PairedPeer {
+  id: string;
+  name: string;
+  fingerprint?: string;
+  address?: string;
+  verification: VerificationSymbol[];
+}
+
 // Migration types
 export type MigrationPhase =
   | 'idle'
@@ -234,7 +332,7 @@ export interface MigrationError {
 }
 
 export type MigrationMode = 'copy' | 'move';
-export type MigrationStrategy = 'cold' | 'warm' | 'snapshot';
+export type MigrationStrategy = 'cold' | 'warm' | 'snapshot' | 'live';
 export type ConflictResolution = 'error' | 'skip' | 'overwrite' | 'rename';
 
 export interface PathMapping {
@@ -304,12 +402,14 @@ export type WSMessageType =
   | 'migration_progress'
   | 'migration_error'
   | 'migration_complete'
-  | 'preflight_update';
+  | 'preflight_update'
+  | 'peer_update';
 
 export interface WSMessage {
   type: WSMessageType;
   timestamp: string;
   payload: any;
+  data?: any; // Events from the server's event bus carry their body here
 }
 
 // API response types